@@ -0,0 +1,93 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/spf13/pflag"
+)
+
+// Affected is the set of modules, files, and plugins affected by a change.
+//
+// This is the JSON output of buf beta affected, and is read by commands with
+// the affected flag to restrict their work.
+type Affected struct {
+	// Modules are the names of the affected modules, sorted.
+	Modules []string `json:"modules"`
+	// Files are the paths of the affected files relative to the current directory, sorted.
+	Files []string `json:"files"`
+	// Plugins are the names of the affected plugins of the buf.gen.yaml template, in
+	// template order.
+	Plugins []string `json:"plugins"`
+}
+
+// BindAffected binds the affected flag.
+func BindAffected(flagSet *pflag.FlagSet, addr *string, flagName string) {
+	flagSet.StringVar(
+		addr,
+		flagName,
+		"",
+		`Limit to the files in the JSON output of "buf beta affected", read from the given path or stdin if "-"
+The output must be produced from the same directory`,
+	)
+}
+
+// ReadAffected reads the JSON output of buf beta affected from the path, or from
+// stdin if the path is "-".
+func ReadAffected(container app.StdinContainer, path string, flagName string) (*Affected, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(container.Stdin())
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	affected := &Affected{}
+	if err := json.Unmarshal(data, affected); err != nil {
+		return nil, appcmd.NewInvalidArgumentErrorf("invalid JSON for --%s: %v", flagName, err)
+	}
+	return affected, nil
+}
+
+// SplitFiles splits the affected files into the files that exist in the current
+// directory and the files that were deleted.
+//
+// Deleted files cannot be targeted on the input, but are still affected on the
+// inputs the change is compared against.
+func (a *Affected) SplitFiles() ([]string, []string, error) {
+	var existingFiles []string
+	var deletedFiles []string
+	for _, file := range a.Files {
+		if _, err := os.Stat(file); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, nil, err
+			}
+			deletedFiles = append(deletedFiles, file)
+			continue
+		}
+		existingFiles = append(existingFiles, file)
+	}
+	return existingFiles, deletedFiles, nil
+}
@@ -23,11 +23,8 @@ import (
 
 	"github.com/bufbuild/buf/private/buf/bufwkt/bufwktstore"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
-	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
-	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapimodule"
-	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapiowner"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/filelock"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
@@ -113,37 +110,34 @@ var (
 // NewModuleDataProvider returns a new ModuleDataProvider while creating the
 // required cache directories.
 func NewModuleDataProvider(container appext.Container) (bufmodule.ModuleDataProvider, error) {
-	clientConfig, err := NewConnectClientConfig(container)
+	moduleProviders, err := newModuleProviders(container)
 	if err != nil {
 		return nil, err
 	}
-	return newModuleDataProvider(
-		container,
-		bufregistryapimodule.NewClientProvider(
-			clientConfig,
-		),
-		bufregistryapiowner.NewClientProvider(
-			clientConfig,
-		),
-	)
+	return newModuleDataProvider(container, moduleProviders)
 }
 
 // NewCommitProvider returns a new CommitProvider while creating the
 // required cache directories.
 func NewCommitProvider(container appext.Container) (bufmodule.CommitProvider, error) {
-	clientConfig, err := NewConnectClientConfig(container)
+	moduleProviders, err := newModuleProviders(container)
 	if err != nil {
 		return nil, err
 	}
-	return newCommitProvider(
-		container,
-		bufregistryapimodule.NewClientProvider(
-			clientConfig,
-		),
-		bufregistryapiowner.NewClientProvider(
-			clientConfig,
-		),
-	)
+	return newCommitProvider(container, moduleProviders)
+}
+
+// NewSignatureCommitProvider returns a new CommitProvider for Commits whose Signatures
+// are verified, while creating the required cache directories.
+//
+// Commits without Signatures are read again instead of from the cache, as Commits cached
+// by older versions never have Signatures.
+func NewSignatureCommitProvider(container appext.Container) (bufmodule.CommitProvider, error) {
+	moduleProviders, err := newModuleProviders(container)
+	if err != nil {
+		return nil, err
+	}
+	return newCommitProvider(container, moduleProviders, bufmodulecache.CommitProviderWithRefreshUnsigned())
 }
 
 // CreateWasmRuntimeCacheDir creates the cache directory for the Wasm runtime.
@@ -177,20 +171,13 @@ func NewWKTStore(container appext.Container) (bufwktstore.Store, error) {
 	), nil
 }
 
-func newModuleDataProvider(
-	container appext.Container,
-	moduleClientProvider bufregistryapimodule.ClientProvider,
-	ownerClientProvider bufregistryapiowner.ClientProvider,
-) (bufmodule.ModuleDataProvider, error) {
+// NewModuleDataStore returns a new bufmodulestore.ModuleDataStore for the module cache
+// while creating the required cache directories.
+func NewModuleDataStore(container appext.Container) (bufmodulestore.ModuleDataStore, error) {
 	if err := createCacheDir(container.CacheDirPath(), v3CacheModuleRelDirPath); err != nil {
 		return nil, err
 	}
 	fullCacheDirPath := normalpath.Join(container.CacheDirPath(), v3CacheModuleRelDirPath)
-	delegateModuleDataProvider := bufmoduleapi.NewModuleDataProvider(
-		container.Logger(),
-		moduleClientProvider,
-		newGraphProvider(container, moduleClientProvider, ownerClientProvider),
-	)
 	// No symlinks.
 	storageosProvider := storageos.NewProvider()
 	cacheBucket, err := storageosProvider.NewReadWriteBucket(fullCacheDirPath)
@@ -204,40 +191,64 @@ func newModuleDataProvider(
 	if err != nil {
 		return nil, err
 	}
-	return bufmodulecache.NewModuleDataProvider(
+	return bufmodulestore.NewModuleDataStore(
 		container.Logger(),
-		delegateModuleDataProvider,
-		bufmodulestore.NewModuleDataStore(
-			container.Logger(),
-			cacheBucket,
-			filelocker,
-		),
+		cacheBucket,
+		filelocker,
 	), nil
 }
 
-func newCommitProvider(
-	container appext.Container,
-	moduleClientProvider bufregistryapimodule.ClientProvider,
-	ownerClientProvider bufregistryapiowner.ClientProvider,
-) (bufmodule.CommitProvider, error) {
+// NewCommitStore returns a new bufmodulestore.CommitStore for the commit cache
+// while creating the required cache directories.
+func NewCommitStore(container appext.Container) (bufmodulestore.CommitStore, error) {
 	if err := createCacheDir(container.CacheDirPath(), v3CacheCommitsRelDirPath); err != nil {
 		return nil, err
 	}
 	fullCacheDirPath := normalpath.Join(container.CacheDirPath(), v3CacheCommitsRelDirPath)
-	delegateReader := bufmoduleapi.NewCommitProvider(container.Logger(), moduleClientProvider, ownerClientProvider)
 	// No symlinks.
 	storageosProvider := storageos.NewProvider()
 	cacheBucket, err := storageosProvider.NewReadWriteBucket(fullCacheDirPath)
 	if err != nil {
 		return nil, err
 	}
+	return bufmodulestore.NewCommitStore(
+		container.Logger(),
+		cacheBucket,
+	), nil
+}
+
+// newModuleDataProvider returns a new ModuleDataProvider that caches the ModuleDatas
+// from the delegate.
+func newModuleDataProvider(
+	container appext.Container,
+	delegateModuleDataProvider bufmodule.ModuleDataProvider,
+) (bufmodule.ModuleDataProvider, error) {
+	moduleDataStore, err := NewModuleDataStore(container)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodulecache.NewModuleDataProvider(
+		container.Logger(),
+		delegateModuleDataProvider,
+		moduleDataStore,
+	), nil
+}
+
+// newCommitProvider returns a new CommitProvider that caches the Commits from the delegate.
+func newCommitProvider(
+	container appext.Container,
+	delegateCommitProvider bufmodule.CommitProvider,
+	options ...bufmodulecache.CommitProviderOption,
+) (bufmodule.CommitProvider, error) {
+	commitStore, err := NewCommitStore(container)
+	if err != nil {
+		return nil, err
+	}
 	return bufmodulecache.NewCommitProvider(
 		container.Logger(),
-		delegateReader,
-		bufmodulestore.NewCommitStore(
-			container.Logger(),
-			cacheBucket,
-		),
+		delegateCommitProvider,
+		commitStore,
+		options...,
 	), nil
 }
 
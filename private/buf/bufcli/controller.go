@@ -16,9 +16,7 @@ package bufcli
 
 import (
 	"github.com/bufbuild/buf/private/buf/bufctl"
-	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
-	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapimodule"
-	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapiowner"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 )
 
@@ -33,20 +31,30 @@ func NewController(
 			bufctl.WithCopyToInMemory(),
 		)
 	}
-	clientConfig, err := NewConnectClientConfig(container)
+	moduleProviders, err := newModuleProviders(container)
 	if err != nil {
 		return nil, err
 	}
-	moduleClientProvider := bufregistryapimodule.NewClientProvider(clientConfig)
-	ownerClientProvider := bufregistryapiowner.NewClientProvider(clientConfig)
-	moduleDataProvider, err := newModuleDataProvider(container, moduleClientProvider, ownerClientProvider)
+	options = append(
+		options,
+		bufctl.WithBufYAMLFileFunc(moduleProviders.selectForBufYAMLFile),
+	)
+	moduleDataProvider, err := newModuleDataProvider(container, moduleProviders)
 	if err != nil {
 		return nil, err
 	}
-	commitProvider, err := newCommitProvider(container, moduleClientProvider, ownerClientProvider)
+	commitProvider, err := newCommitProvider(container, moduleProviders)
 	if err != nil {
 		return nil, err
 	}
+	signatureCommitProvider, err := newCommitProvider(container, moduleProviders, bufmodulecache.CommitProviderWithRefreshUnsigned())
+	if err != nil {
+		return nil, err
+	}
+	options = append(
+		options,
+		bufctl.WithSignatureCommitProvider(signatureCommitProvider),
+	)
 	wktStore, err := NewWKTStore(container)
 	if err != nil {
 		return nil, err
@@ -54,8 +62,8 @@ func NewController(
 	return bufctl.NewController(
 		container.Logger(),
 		container,
-		newGraphProvider(container, moduleClientProvider, ownerClientProvider),
-		bufmoduleapi.NewModuleKeyProvider(container.Logger(), moduleClientProvider),
+		moduleProviders,
+		moduleProviders,
 		moduleDataProvider,
 		commitProvider,
 		wktStore,
@@ -38,6 +38,13 @@ const (
 	// at a per-file level.
 	copyToInMemoryEnvKey = "BUF_BETA_COPY_FILES_TO_MEMORY"
 
+	// moduleRegistryEnvKey selects a module registry to use instead of the BSR.
+	//
+	// The value is either file://<dir> for a directory, or oci://<repository-prefix>
+	// for an OCI registry. oci+http://<repository-prefix> talks to the OCI registry
+	// over plain HTTP. If set, this takes precedence over the registry key of buf.yaml.
+	moduleRegistryEnvKey = "BUF_MODULE_REGISTRY"
+
 	// This should only be used for testing. This is not part of Buf's API, and should
 	// never be documented or part of Buf's contract.
 	legacyFederationRegistryEnvKey = "BUF_TESTING_LEGACY_FEDERATION_REGISTRY"
@@ -16,37 +16,14 @@ package bufcli
 
 import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
-	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
-	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapimodule"
-	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapiowner"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 )
 
 // NewGraphProvider returns a new GraphProvider.
 func NewGraphProvider(container appext.Container) (bufmodule.GraphProvider, error) {
-	clientConfig, err := NewConnectClientConfig(container)
+	moduleProviders, err := newModuleProviders(container)
 	if err != nil {
 		return nil, err
 	}
-	return newGraphProvider(
-		container,
-		bufregistryapimodule.NewClientProvider(clientConfig),
-		bufregistryapiowner.NewClientProvider(clientConfig),
-	), nil
-}
-
-func newGraphProvider(
-	container appext.Container,
-	moduleClientProvider bufregistryapimodule.ClientProvider,
-	ownerClientProvider bufregistryapiowner.ClientProvider,
-) bufmodule.GraphProvider {
-	return bufmoduleapi.NewGraphProvider(
-		container.Logger(),
-		moduleClientProvider,
-		ownerClientProvider,
-		// OK if empty
-		bufmoduleapi.GraphProviderWithLegacyFederationRegistry(container.Env(legacyFederationRegistryEnvKey)),
-		// OK if empty
-		bufmoduleapi.GraphProviderWithPublicRegistry(container.Env(publicRegistryEnvKey)),
-	)
+	return moduleProviders, nil
 }
@@ -16,21 +16,14 @@ package bufcli
 
 import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
-	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
-	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapimodule"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 )
 
 // NewModuleKeyProvider returns a new ModuleKeyProvider.
 func NewModuleKeyProvider(container appext.Container) (bufmodule.ModuleKeyProvider, error) {
-	clientConfig, err := NewConnectClientConfig(container)
+	moduleProviders, err := newModuleProviders(container)
 	if err != nil {
 		return nil, err
 	}
-	return bufmoduleapi.NewModuleKeyProvider(
-		container.Logger(),
-		bufregistryapimodule.NewClientProvider(
-			clientConfig,
-		),
-	), nil
+	return moduleProviders, nil
 }
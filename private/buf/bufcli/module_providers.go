@@ -0,0 +1,292 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleregistry"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapimodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapiowner"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/dag"
+)
+
+// NewModuleProvidersInterceptor returns a new Interceptor that shares the providers that
+// modules are resolved from, and the Uploader that modules are pushed with, between all
+// Controllers and providers created for a command.
+//
+// This makes sure that the module registry is selected once per command. Without this
+// Interceptor, each Controller and provider selects the module registry on its own.
+func NewModuleProvidersInterceptor() appext.Interceptor {
+	return func(next func(context.Context, appext.Container) error) func(context.Context, appext.Container) error {
+		return func(ctx context.Context, container appext.Container) error {
+			return next(
+				ctx,
+				&moduleProvidersContainer{
+					Container:       container,
+					moduleProviders: &moduleProviders{container: container},
+				},
+			)
+		}
+	}
+}
+
+// *** PRIVATE ***
+
+// moduleProvidersContainer is a Container that carries the moduleProviders of a command.
+type moduleProvidersContainer struct {
+	appext.Container
+
+	moduleProviders *moduleProviders
+}
+
+// moduleProviders are the uncached providers that modules are resolved from, and the
+// Uploader that modules are pushed with.
+//
+// The providers are backed by either a module registry or the BSR, which is selected once:
+//
+//   - If moduleRegistryEnvKey is set, it selects the module registry.
+//   - Otherwise, the registry key of the v2 buf.yaml of the first workspace read that sets
+//     one selects the module registry, see selectForBufYAMLFile.
+//   - If no module registry is selected by the time the providers are first used, the
+//     providers are backed by the BSR.
+type moduleProviders struct {
+	container appext.Container
+
+	lock sync.Mutex
+	// nil until selected.
+	delegate *moduleProvidersDelegate
+	// The value that selected the module registry, empty if the BSR is used.
+	value string
+}
+
+// newModuleProviders returns the moduleProviders for the container.
+//
+// If the container was created by NewModuleProvidersInterceptor, the moduleProviders of
+// the command are returned.
+func newModuleProviders(container appext.Container) (*moduleProviders, error) {
+	moduleProviders := &moduleProviders{container: container}
+	if moduleProvidersContainer, ok := container.(*moduleProvidersContainer); ok {
+		moduleProviders = moduleProvidersContainer.moduleProviders
+	}
+	// The environment is checked up front so that misconfiguration is reported before
+	// any module is resolved.
+	if err := moduleProviders.selectForEnv(); err != nil {
+		return nil, err
+	}
+	return moduleProviders, nil
+}
+
+func (m *moduleProviders) GetGraphForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) (*dag.Graph[bufmodule.RegistryCommitID, bufmodule.ModuleKey], error) {
+	delegate, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.graphProvider.GetGraphForModuleKeys(ctx, moduleKeys)
+}
+
+func (m *moduleProviders) GetModuleKeysForModuleRefs(
+	ctx context.Context,
+	moduleRefs []bufparse.Ref,
+	digestType bufmodule.DigestType,
+) ([]bufmodule.ModuleKey, error) {
+	delegate, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.moduleKeyProvider.GetModuleKeysForModuleRefs(ctx, moduleRefs, digestType)
+}
+
+func (m *moduleProviders) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	delegate, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+}
+
+func (m *moduleProviders) GetCommitsForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.Commit, error) {
+	delegate, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.commitProvider.GetCommitsForModuleKeys(ctx, moduleKeys)
+}
+
+func (m *moduleProviders) GetCommitsForCommitKeys(
+	ctx context.Context,
+	commitKeys []bufmodule.CommitKey,
+) ([]bufmodule.Commit, error) {
+	delegate, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.commitProvider.GetCommitsForCommitKeys(ctx, commitKeys)
+}
+
+func (m *moduleProviders) Upload(
+	ctx context.Context,
+	moduleSet bufmodule.ModuleSet,
+	options ...bufmodule.UploadOption,
+) ([]bufmodule.Commit, error) {
+	delegate, err := m.getDelegate()
+	if err != nil {
+		return nil, err
+	}
+	return delegate.uploader.Upload(ctx, moduleSet, options...)
+}
+
+// selectForBufYAMLFile selects the module registry named by the registry key of the v2
+// buf.yaml of a workspace. This is a bufworkspace.BufYAMLFileFunc.
+//
+// This is a no-op if moduleRegistryEnvKey is set, or if the buf.yaml does not set a
+// registry. If a different module registry, or the BSR, is already in use, this is an error.
+func (m *moduleProviders) selectForBufYAMLFile(
+	_ context.Context,
+	bufYAMLFile bufconfig.BufYAMLFile,
+	dirPath string,
+) error {
+	value := bufYAMLFile.ModuleRegistry()
+	if value == "" || m.container.Env(moduleRegistryEnvKey) != "" {
+		return nil
+	}
+	source := "registry in " + bufconfig.DefaultBufYAMLFileName
+	if dirPath != "" {
+		source = "registry in " + filepath.Join(dirPath, bufconfig.DefaultBufYAMLFileName)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.delegate != nil {
+		if m.value == value {
+			return nil
+		}
+		inUse := "the BSR"
+		if m.value != "" {
+			inUse = fmt.Sprintf("%q", m.value)
+		}
+		return fmt.Errorf("%s is %q, but modules are already being resolved from %s", source, value, inUse)
+	}
+	if dirPath == "" && isRelativeFileModuleRegistry(value) {
+		return fmt.Errorf("%s: relative directories in %q can only be used in a buf.yaml on disk", source, value)
+	}
+	moduleRegistry, err := newModuleRegistry(m.container.Logger(), value, source, dirPath)
+	if err != nil {
+		return err
+	}
+	m.delegate = newModuleProvidersDelegateForModuleRegistry(moduleRegistry)
+	m.value = value
+	return nil
+}
+
+// selectForEnv selects the module registry named by moduleRegistryEnvKey, if set.
+func (m *moduleProviders) selectForEnv() error {
+	value := m.container.Env(moduleRegistryEnvKey)
+	if value == "" {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.delegate != nil {
+		return nil
+	}
+	moduleRegistry, err := newModuleRegistry(m.container.Logger(), value, moduleRegistryEnvKey, "")
+	if err != nil {
+		return err
+	}
+	m.delegate = newModuleProvidersDelegateForModuleRegistry(moduleRegistry)
+	m.value = value
+	return nil
+}
+
+// getDelegate returns the selected moduleProvidersDelegate, selecting the BSR if
+// nothing is selected yet.
+func (m *moduleProviders) getDelegate() (*moduleProvidersDelegate, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.delegate == nil {
+		delegate, err := newModuleProvidersDelegateForBSR(m.container)
+		if err != nil {
+			return nil, err
+		}
+		m.delegate = delegate
+	}
+	return m.delegate, nil
+}
+
+// moduleProvidersDelegate is a selected set of providers and Uploader.
+type moduleProvidersDelegate struct {
+	graphProvider      bufmodule.GraphProvider
+	moduleKeyProvider  bufmodule.ModuleKeyProvider
+	moduleDataProvider bufmodule.ModuleDataProvider
+	commitProvider     bufmodule.CommitProvider
+	uploader           bufmodule.Uploader
+}
+
+func newModuleProvidersDelegateForModuleRegistry(moduleRegistry bufmoduleregistry.Registry) *moduleProvidersDelegate {
+	return &moduleProvidersDelegate{
+		graphProvider:      moduleRegistry,
+		moduleKeyProvider:  moduleRegistry,
+		moduleDataProvider: moduleRegistry,
+		commitProvider:     moduleRegistry,
+		uploader:           moduleRegistry,
+	}
+}
+
+func newModuleProvidersDelegateForBSR(container appext.Container) (*moduleProvidersDelegate, error) {
+	clientConfig, err := NewConnectClientConfig(container)
+	if err != nil {
+		return nil, err
+	}
+	moduleClientProvider := bufregistryapimodule.NewClientProvider(clientConfig)
+	ownerClientProvider := bufregistryapiowner.NewClientProvider(clientConfig)
+	graphProvider := bufmoduleapi.NewGraphProvider(
+		container.Logger(),
+		moduleClientProvider,
+		ownerClientProvider,
+		// OK if empty
+		bufmoduleapi.GraphProviderWithLegacyFederationRegistry(container.Env(legacyFederationRegistryEnvKey)),
+		// OK if empty
+		bufmoduleapi.GraphProviderWithPublicRegistry(container.Env(publicRegistryEnvKey)),
+	)
+	return &moduleProvidersDelegate{
+		graphProvider:      graphProvider,
+		moduleKeyProvider:  bufmoduleapi.NewModuleKeyProvider(container.Logger(), moduleClientProvider),
+		moduleDataProvider: bufmoduleapi.NewModuleDataProvider(container.Logger(), moduleClientProvider, graphProvider),
+		commitProvider:     bufmoduleapi.NewCommitProvider(container.Logger(), moduleClientProvider, ownerClientProvider),
+		uploader: bufmoduleapi.NewUploader(
+			container.Logger(),
+			moduleClientProvider,
+			// OK if empty
+			bufmoduleapi.UploaderWithPublicRegistry(container.Env(publicRegistryEnvKey)),
+		),
+	}, nil
+}
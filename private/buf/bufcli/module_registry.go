@@ -0,0 +1,90 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleregistry"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+)
+
+const (
+	moduleRegistryFilePrefix    = "file://"
+	moduleRegistryOCIPrefix     = "oci://"
+	moduleRegistryOCIHTTPPrefix = "oci+http://"
+)
+
+// newModuleRegistry returns the module registry selected by the value.
+//
+// The source describes where the value came from for errors. Relative file paths in the
+// value are relative to dirPath, or to the current directory if dirPath is empty.
+func newModuleRegistry(
+	logger *slog.Logger,
+	value string,
+	source string,
+	dirPath string,
+) (bufmoduleregistry.Registry, error) {
+	switch {
+	case strings.HasPrefix(value, moduleRegistryFilePrefix):
+		registryDirPath := strings.TrimPrefix(value, moduleRegistryFilePrefix)
+		if registryDirPath == "" {
+			return nil, fmt.Errorf("%s: no directory given in %q", source, value)
+		}
+		if dirPath != "" && !filepath.IsAbs(registryDirPath) {
+			registryDirPath = filepath.Join(dirPath, registryDirPath)
+		}
+		if err := os.MkdirAll(registryDirPath, 0755); err != nil {
+			return nil, err
+		}
+		// No symlinks.
+		bucket, err := storageos.NewProvider().NewReadWriteBucket(registryDirPath)
+		if err != nil {
+			return nil, err
+		}
+		return bufmoduleregistry.NewBucketRegistry(logger, bucket), nil
+	case strings.HasPrefix(value, moduleRegistryOCIPrefix):
+		return bufmoduleregistry.NewOCIRegistry(
+			logger,
+			strings.TrimPrefix(value, moduleRegistryOCIPrefix),
+		)
+	case strings.HasPrefix(value, moduleRegistryOCIHTTPPrefix):
+		return bufmoduleregistry.NewOCIRegistry(
+			logger,
+			strings.TrimPrefix(value, moduleRegistryOCIHTTPPrefix),
+			bufmoduleregistry.OCIRegistryWithInsecure(),
+		)
+	default:
+		return nil, fmt.Errorf(
+			"%s must start with one of %s, %s, or %s, got %q",
+			source,
+			moduleRegistryFilePrefix,
+			moduleRegistryOCIPrefix,
+			moduleRegistryOCIHTTPPrefix,
+			value,
+		)
+	}
+}
+
+// isRelativeFileModuleRegistry returns true if the value selects a module registry in
+// a relative directory.
+func isRelativeFileModuleRegistry(value string) bool {
+	registryDirPath, ok := strings.CutPrefix(value, moduleRegistryFilePrefix)
+	return ok && registryDirPath != "" && !filepath.IsAbs(registryDirPath)
+}
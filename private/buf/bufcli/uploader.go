@@ -16,28 +16,14 @@ package bufcli
 
 import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
-	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleapi"
-	"github.com/bufbuild/buf/private/bufpkg/bufregistryapi/bufregistryapimodule"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 )
 
 // NewUploader returns a new Uploader.
 func NewUploader(container appext.Container) (bufmodule.Uploader, error) {
-	clientConfig, err := NewConnectClientConfig(container)
+	moduleProviders, err := newModuleProviders(container)
 	if err != nil {
 		return nil, err
 	}
-	return newUploader(container, bufregistryapimodule.NewClientProvider(clientConfig)), nil
-}
-
-func newUploader(
-	container appext.Container,
-	clientProvider bufregistryapimodule.ClientProvider,
-) bufmodule.Uploader {
-	return bufmoduleapi.NewUploader(
-		container.Logger(),
-		clientProvider,
-		// OK if empty
-		bufmoduleapi.UploaderWithPublicRegistry(container.Env(publicRegistryEnvKey)),
-	)
+	return moduleProviders, nil
 }
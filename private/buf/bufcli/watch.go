@@ -0,0 +1,274 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/filewatch"
+	"github.com/bufbuild/buf/private/pkg/interrupt"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// clearScreen moves the cursor to the top left and clears the screen.
+const clearScreen = "\033[H\033[2J"
+
+// watchConfigFileNames are the names of the configuration files that are watched in
+// addition to .proto files.
+var watchConfigFileNames = []string{
+	bufconfig.DefaultBufYAMLFileName,
+	bufconfig.DefaultBufLockFileName,
+	bufconfig.DefaultBufWorkYAMLFileName,
+}
+
+// BindWatch binds the watch flag.
+func BindWatch(flagSet *pflag.FlagSet, addr *bool, flagName string) {
+	flagSet.BoolVar(
+		addr,
+		flagName,
+		false,
+		`Run again each time a .proto file or configuration file of the workspace changes, until interrupted
+The input must be a local directory, and --timeout is ignored`,
+	)
+}
+
+// Watch runs f, and then runs f again each time a relevant file of the workspace of the
+// input changes, until interrupted.
+//
+// The input must be a local directory. The directories of the local modules of the
+// workspace and the configuration files of the input directory are watched, along with
+// the extra file paths, such as a buf.gen.yaml template. The watched directories are
+// determined again before each run, so that modules added to the workspace are watched.
+//
+// The files are read before f runs, so that a change made while f runs starts another
+// run. The output paths are the files and directories that f writes to, such as the
+// output directories of generation, and are not watched, so that f does not trigger
+// itself.
+//
+// The screen is cleared before each run if stdout is a terminal. Errors returned by f are
+// printed, and watching continues. The controller should be created with
+// bufctl.WithBuildCache so that unchanged files are not parsed again on each run.
+func Watch(
+	ctx context.Context,
+	container app.Container,
+	controller bufctl.Controller,
+	input string,
+	extraFilePaths []string,
+	outputPaths []string,
+	f func(context.Context) error,
+) error {
+	if fileInfo, err := os.Stat(input); err != nil || !fileInfo.IsDir() {
+		return appcmd.NewInvalidArgumentErrorf("input %q must be a local directory in watch mode", input)
+	}
+	// The --timeout deadline of the context applies to a single run of the command, and
+	// would otherwise stop watching, so the context is only cancelled on interrupt.
+	ctx, cancel := signal.NotifyContext(context.WithoutCancel(ctx), interrupt.Signals...)
+	defer cancel()
+	return watch(
+		ctx,
+		container,
+		func(ctx context.Context) ([]string, error) {
+			return getWatchPaths(ctx, controller, input, extraFilePaths)
+		},
+		extraFilePaths,
+		outputPaths,
+		f,
+	)
+}
+
+// watch runs f until the context is done, each time after the watched files within the
+// paths returned by getWatchPaths change.
+func watch(
+	ctx context.Context,
+	container app.StdioContainer,
+	getWatchPaths func(context.Context) ([]string, error),
+	extraFilePaths []string,
+	outputPaths []string,
+	f func(context.Context) error,
+	options ...filewatch.WaitForChangeOption,
+) error {
+	stdoutIsTerminal := isTerminal(container.Stdout())
+	for {
+		watchPaths, err := getWatchPaths(ctx)
+		if err != nil {
+			return err
+		}
+		include, err := newWatchInclude(watchPaths, extraFilePaths, outputPaths)
+		if err != nil {
+			return err
+		}
+		snapshot, err := filewatch.NewSnapshot(watchPaths, include)
+		if err != nil {
+			return err
+		}
+		if stdoutIsTerminal {
+			if _, err := fmt.Fprint(container.Stdout(), clearScreen); err != nil {
+				return err
+			}
+		}
+		if err := f(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// File annotations were already printed.
+			if errString := err.Error(); errString != "" && !errors.Is(err, bufctl.ErrFileAnnotation) {
+				if _, err := fmt.Fprintf(container.Stderr(), "Failure: %s\n", errString); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := fmt.Fprintln(container.Stderr(), "Watching for changes..."); err != nil {
+			return err
+		}
+		changedPaths, _, err := filewatch.WaitForChange(ctx, snapshot, options...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if !stdoutIsTerminal {
+			if _, err := fmt.Fprintf(container.Stderr(), "Changed: %s\n", strings.Join(changedPaths, ", ")); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// newWatchInclude returns the function that determines if a file within the watch paths
+// is watched.
+//
+// The .proto files, configuration files, and extra file paths are watched, unless they
+// are equal to or contained within one of the output paths. An output path that is equal
+// to or contains a watch path, such as an output directory of ".", cannot exclude its
+// files, as it would exclude the files that are watched.
+func newWatchInclude(
+	watchPaths []string,
+	extraFilePaths []string,
+	outputPaths []string,
+) (func(string) bool, error) {
+	absWatchPaths, err := getAbsNormalPaths(watchPaths)
+	if err != nil {
+		return nil, err
+	}
+	absOutputPaths, err := getAbsNormalPaths(outputPaths)
+	if err != nil {
+		return nil, err
+	}
+	absOutputPaths = slicesext.Filter(
+		absOutputPaths,
+		func(absOutputPath string) bool {
+			for _, absWatchPath := range absWatchPaths {
+				if normalpath.EqualsOrContainsPath(absOutputPath, absWatchPath, normalpath.Absolute) {
+					return false
+				}
+			}
+			return true
+		},
+	)
+	return func(path string) bool {
+		if !(filepath.Ext(path) == ".proto" ||
+			slices.Contains(watchConfigFileNames, filepath.Base(path)) ||
+			slices.Contains(extraFilePaths, path)) {
+			return false
+		}
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return true
+		}
+		absPath = normalpath.Normalize(absPath)
+		for _, absOutputPath := range absOutputPaths {
+			if normalpath.EqualsOrContainsPath(absOutputPath, absPath, normalpath.Absolute) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func getAbsNormalPaths(paths []string) ([]string, error) {
+	absPaths := make([]string, len(paths))
+	for i, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		absPaths[i] = normalpath.Normalize(absPath)
+	}
+	return absPaths, nil
+}
+
+// getWatchPaths returns the paths to watch for the input directory.
+//
+// If the workspace cannot be read, such as when a buf.yaml is invalid, the entire input
+// directory is watched, so that a fix is picked up.
+func getWatchPaths(
+	ctx context.Context,
+	controller bufctl.Controller,
+	input string,
+	extraFilePaths []string,
+) ([]string, error) {
+	watchPaths := slices.Clone(extraFilePaths)
+	for _, configFileName := range watchConfigFileNames {
+		watchPaths = append(watchPaths, filepath.Join(input, configFileName))
+	}
+	workspace, err := controller.GetWorkspace(ctx, input)
+	if err != nil {
+		return append(watchPaths, input), nil
+	}
+	moduleDirPaths := make(map[string]struct{})
+	if err := bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(workspace).WalkFileInfos(
+		ctx,
+		func(fileInfo bufmodule.FileInfo) error {
+			if !fileInfo.Module().IsLocal() || fileInfo.LocalPath() == "" {
+				return nil
+			}
+			// The directory of the module is the local path without the path within the module.
+			localPath := normalpath.Normalize(fileInfo.LocalPath())
+			moduleDirPath := normalpath.Normalize(strings.TrimSuffix(localPath, fileInfo.Path()))
+			moduleDirPaths[normalpath.Unnormalize(moduleDirPath)] = struct{}{}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	if len(moduleDirPaths) == 0 {
+		return append(watchPaths, input), nil
+	}
+	for moduleDirPath := range moduleDirPaths {
+		watchPaths = append(watchPaths, moduleDirPath)
+	}
+	return watchPaths, nil
+}
+
+func isTerminal(value any) bool {
+	file, ok := value.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
@@ -0,0 +1,100 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/filewatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchChangeDuringRun(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	protoFilePath := filepath.Join(dirPath, "a.proto")
+	outputDirPath := filepath.Join(dirPath, "gen")
+	testWriteFile(t, protoFilePath, "a")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stderr := bytes.NewBuffer(nil)
+	var runs int
+	err := watch(
+		ctx,
+		app.NewContainer(nil, nil, bytes.NewBuffer(nil), stderr),
+		func(context.Context) ([]string, error) {
+			return []string{dirPath}, nil
+		},
+		nil,
+		[]string{outputDirPath},
+		func(context.Context) error {
+			runs++
+			// The output is a .proto file within the watched directory, but is not watched.
+			testWriteFile(t, filepath.Join(outputDirPath, "a.proto"), "output")
+			switch runs {
+			case 1:
+				// A change made while the command runs starts another run.
+				testWriteFile(t, protoFilePath, "aa")
+			case 2:
+				go func() {
+					time.Sleep(500 * time.Millisecond)
+					cancel()
+				}()
+			}
+			return nil
+		},
+		filewatch.WaitForChangeWithPollInterval(10*time.Millisecond),
+		filewatch.WaitForChangeWithDebounce(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, runs)
+	assert.Equal(
+		t,
+		"Watching for changes...\nChanged: "+protoFilePath+"\nWatching for changes...\n",
+		stderr.String(),
+	)
+}
+
+func TestNewWatchInclude(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	templateFilePath := filepath.Join(dirPath, "buf.gen.yaml")
+	include, err := newWatchInclude(
+		[]string{dirPath, templateFilePath},
+		[]string{templateFilePath},
+		[]string{filepath.Join(dirPath, "gen"), dirPath},
+	)
+	require.NoError(t, err)
+	assert.True(t, include(filepath.Join(dirPath, "a.proto")))
+	assert.True(t, include(filepath.Join(dirPath, "buf.yaml")))
+	assert.True(t, include(templateFilePath))
+	assert.False(t, include(filepath.Join(dirPath, "a.txt")))
+	assert.False(t, include(filepath.Join(dirPath, "gen", "a.proto")))
+	assert.False(t, include(filepath.Join(dirPath, "gen", "b", "buf.yaml")))
+	// The output directory that contains the watched directory does not exclude it.
+	assert.True(t, include(filepath.Join(dirPath, "b", "a.proto")))
+}
+
+func testWriteFile(t *testing.T, path string, data string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+}
@@ -176,6 +176,9 @@ type controller struct {
 	fileAnnotationErrorFormat string
 	fileAnnotationsToStdout   bool
 	copyToInMemory            bool
+	buildCache                bufimage.BuildCache
+	bufYAMLFileFunc           bufworkspace.BufYAMLFileFunc
+	signatureCommitProvider   bufmodule.CommitProvider
 
 	storageosProvider           storageos.Provider
 	buffetchRefParser           buffetch.RefParser
@@ -227,14 +230,34 @@ func newController(
 		moduleKeyProvider,
 	)
 	controller.buffetchWriter = buffetch.NewWriter(logger)
+	var workspaceProviderOptions []bufworkspace.WorkspaceProviderOption
+	var workspaceDepManagerProviderOptions []bufworkspace.WorkspaceDepManagerProviderOption
+	if controller.bufYAMLFileFunc != nil {
+		workspaceProviderOptions = append(
+			workspaceProviderOptions,
+			bufworkspace.WorkspaceProviderWithBufYAMLFileFunc(controller.bufYAMLFileFunc),
+		)
+		workspaceDepManagerProviderOptions = append(
+			workspaceDepManagerProviderOptions,
+			bufworkspace.WorkspaceDepManagerProviderWithBufYAMLFileFunc(controller.bufYAMLFileFunc),
+		)
+	}
+	if controller.signatureCommitProvider != nil {
+		workspaceProviderOptions = append(
+			workspaceProviderOptions,
+			bufworkspace.WorkspaceProviderWithSignatureCommitProvider(controller.signatureCommitProvider),
+		)
+	}
 	controller.workspaceProvider = bufworkspace.NewWorkspaceProvider(
 		logger,
 		graphProvider,
 		moduleDataProvider,
 		commitProvider,
+		workspaceProviderOptions...,
 	)
 	controller.workspaceDepManagerProvider = bufworkspace.NewWorkspaceDepManagerProvider(
 		logger,
+		workspaceDepManagerProviderOptions...,
 	)
 	return controller, nil
 }
@@ -816,6 +839,12 @@ func (c *controller) getWorkspaceForProtoFileRef(
 			bufworkspace.WithIgnoreAndDisallowV1BufWorkYAMLs(),
 		)
 	}
+	if functionOptions.hasBufLockDepModuleKeys {
+		options = append(
+			options,
+			bufworkspace.WithBufLockDepModuleKeys(functionOptions.bufLockDepModuleKeys),
+		)
+	}
 	return c.workspaceProvider.GetWorkspaceForBucket(
 		ctx,
 		readBucketCloser,
@@ -852,6 +881,12 @@ func (c *controller) getWorkspaceForSourceRef(
 			bufworkspace.WithIgnoreAndDisallowV1BufWorkYAMLs(),
 		)
 	}
+	if functionOptions.hasBufLockDepModuleKeys {
+		options = append(
+			options,
+			bufworkspace.WithBufLockDepModuleKeys(functionOptions.bufLockDepModuleKeys),
+		)
+	}
 	return c.workspaceProvider.GetWorkspaceForBucket(
 		ctx,
 		readBucketCloser,
@@ -990,6 +1025,9 @@ func (c *controller) buildImage(
 	if functionOptions.imageExcludeSourceInfo {
 		options = append(options, bufimage.WithExcludeSourceCodeInfo())
 	}
+	if c.buildCache != nil {
+		options = append(options, bufimage.WithBuildCache(c.buildCache))
+	}
 	image, err := bufimage.BuildImage(
 		ctx,
 		c.logger,
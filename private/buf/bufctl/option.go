@@ -16,6 +16,9 @@ package bufctl
 
 import (
 	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufworkspace"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 )
 
 type ControllerOption func(*controller)
@@ -44,6 +47,32 @@ func WithCopyToInMemory() ControllerOption {
 	}
 }
 
+// WithBuildCache reuses the parsed files of previous builds with the same BuildCache
+// when building images.
+func WithBuildCache(buildCache bufimage.BuildCache) ControllerOption {
+	return func(controller *controller) {
+		controller.buildCache = buildCache
+	}
+}
+
+// WithBufYAMLFileFunc calls the BufYAMLFileFunc with the v2 buf.yaml of each workspace
+// read by the Controller, before any dependencies of the workspace are read.
+func WithBufYAMLFileFunc(bufYAMLFileFunc bufworkspace.BufYAMLFileFunc) ControllerOption {
+	return func(controller *controller) {
+		controller.bufYAMLFileFunc = bufYAMLFileFunc
+	}
+}
+
+// WithSignatureCommitProvider reads the Commits whose Signatures are verified against the
+// trusted signing keys of a workspace from the CommitProvider.
+//
+// If not set, the CommitProvider of the Controller is used.
+func WithSignatureCommitProvider(signatureCommitProvider bufmodule.CommitProvider) ControllerOption {
+	return func(controller *controller) {
+		controller.signatureCommitProvider = signatureCommitProvider
+	}
+}
+
 // TODO FUTURE: split up to per-function.
 type FunctionOption func(*functionOptions)
 
@@ -113,6 +142,18 @@ func WithIgnoreAndDisallowV1BufWorkYAMLs() FunctionOption {
 	}
 }
 
+// WithBufLockDepModuleKeys returns a new FunctionOption that says to use the given
+// ModuleKeys as the dependencies of the Workspace, in place of the dependencies in the
+// buf.lock files.
+//
+// See bufworkspace.WithBufLockDepModuleKeys for more details.
+func WithBufLockDepModuleKeys(depModuleKeys []bufmodule.ModuleKey) FunctionOption {
+	return func(functionOptions *functionOptions) {
+		functionOptions.bufLockDepModuleKeys = depModuleKeys
+		functionOptions.hasBufLockDepModuleKeys = true
+	}
+}
+
 // WithMessageValidation returns a new FunctionOption that says to validate the
 // message as it is being read.
 //
@@ -137,6 +178,8 @@ type functionOptions struct {
 	imageAsFileDescriptorSet        bool
 	configOverride                  string
 	ignoreAndDisallowV1BufWorkYAMLs bool
+	bufLockDepModuleKeys            []bufmodule.ModuleKey
+	hasBufLockDepModuleKeys         bool
 	messageValidation               bool
 }
 
@@ -22,8 +22,10 @@ import (
 	"net/http"
 
 	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"google.golang.org/protobuf/reflect/protodesc"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 // Invoker provides the ability to invoke RPCs dynamically.
@@ -63,3 +65,32 @@ func ResolveServiceDescriptor(res protoencoding.Resolver, service string) (proto
 	}
 	return serviceDescriptor, nil
 }
+
+// ResolveFileDescriptorProtos uses the given resolver to find the files that
+// define the given services, and returns them along with all of their transitive
+// imports. The files are returned in topological order, with every file after
+// its imports.
+func ResolveFileDescriptorProtos(res protoencoding.Resolver, services []protoreflect.FullName) ([]*descriptorpb.FileDescriptorProto, error) {
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]struct{})
+	var addFile func(protoreflect.FileDescriptor)
+	addFile = func(fileDescriptor protoreflect.FileDescriptor) {
+		if _, ok := seen[fileDescriptor.Path()]; ok {
+			return
+		}
+		seen[fileDescriptor.Path()] = struct{}{}
+		imports := fileDescriptor.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			addFile(imports.Get(i).FileDescriptor)
+		}
+		fileDescriptorProtos = append(fileDescriptorProtos, protodesc.ToFileDescriptorProto(fileDescriptor))
+	}
+	for _, service := range services {
+		serviceDescriptor, err := ResolveServiceDescriptor(res, string(service))
+		if err != nil {
+			return nil, err
+		}
+		addFile(serviceDescriptor.ParentFile())
+	}
+	return fileDescriptorProtos, nil
+}
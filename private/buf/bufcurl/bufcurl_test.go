@@ -0,0 +1,86 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/protocompile"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestResolveFileDescriptorProtos(t *testing.T) {
+	t.Parallel()
+	descriptors, err := (&protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(
+			&protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(
+					map[string]string{
+						"a.proto": `syntax = "proto3";
+package a;
+import "b.proto";
+import "c.proto";
+service FooService {
+  rpc Foo(b.Request) returns (c.Response);
+}
+service BarService {
+  rpc Bar(b.Request) returns (b.Request);
+}
+`,
+						"b.proto": `syntax = "proto3";
+package b;
+import "c.proto";
+message Request {
+  c.Response response = 1;
+}
+`,
+						"c.proto": `syntax = "proto3";
+package c;
+import "google/protobuf/timestamp.proto";
+message Response {
+  google.protobuf.Timestamp time = 1;
+}
+service BazService {}
+`,
+					},
+				),
+			},
+		),
+	}).Compile(context.Background(), "google/protobuf/timestamp.proto", "c.proto", "b.proto", "a.proto")
+	require.NoError(t, err)
+	compiledFileDescriptorProtos := make([]*descriptorpb.FileDescriptorProto, len(descriptors))
+	for i, descriptor := range descriptors {
+		compiledFileDescriptorProtos[i] = protodesc.ToFileDescriptorProto(descriptor)
+	}
+	resolver, err := protoencoding.NewResolver(compiledFileDescriptorProtos...)
+	require.NoError(t, err)
+	fileDescriptorProtos, err := ResolveFileDescriptorProtos(
+		resolver,
+		[]protoreflect.FullName{"a.FooService", "a.BarService", "c.BazService"},
+	)
+	require.NoError(t, err)
+	paths := make([]string, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		paths[i] = fileDescriptorProto.GetName()
+	}
+	require.Equal(t, []string{"google/protobuf/timestamp.proto", "c.proto", "b.proto", "a.proto"}, paths)
+	_, err = ResolveFileDescriptorProtos(resolver, []protoreflect.FullName{"a.Missing"})
+	require.EqualError(t, err, `failed to find service named "a.Missing" in schema`)
+}
@@ -0,0 +1,319 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"fmt"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// Protocol/transport flags
+	ProtocolFlagName            = "protocol"
+	UnixSocketFlagName          = "unix-socket"
+	HTTP2PriorKnowledgeFlagName = "http2-prior-knowledge"
+	HTTP3FlagName               = "http3"
+
+	// TLS flags
+	KeyFlagName           = "key"
+	CertFlagName          = "cert"
+	CertFlagShortName     = "E"
+	CACertFlagName        = "cacert"
+	ServerNameFlagName    = "servername"
+	InsecureFlagName      = "insecure"
+	InsecureFlagShortName = "k"
+
+	// Timeout flags
+	NoKeepAliveFlagName    = "no-keepalive"
+	KeepAliveFlagName      = "keepalive-time"
+	ConnectTimeoutFlagName = "connect-timeout"
+
+	// Header flags
+	UserAgentFlagName      = "user-agent"
+	UserAgentFlagShortName = "A"
+	HeaderFlagName         = "header"
+	HeaderFlagShortName    = "H"
+)
+
+// ConnectionFlags are the flags that configure the connection to a server, shared
+// by the commands that connect to a server.
+type ConnectionFlags struct {
+	// Protocol details
+	Protocol            string
+	UnixSocket          string
+	HTTP2PriorKnowledge bool
+	HTTP3               bool
+
+	// TLS
+	Key, Cert, CACert, ServerName string
+	Insecure                      bool
+	// TODO: CRLFile, CertStatus
+
+	// Timeouts
+	NoKeepAlive           bool
+	KeepAliveTimeSeconds  float64
+	ConnectTimeoutSeconds float64
+
+	// Headers
+	UserAgent string
+	Headers   []string
+
+	defaultProtocol string
+	headersUsage    string
+	// so we can inquire about which flags present on command-line
+	flagSet *pflag.FlagSet
+}
+
+// NewConnectionFlags returns a new ConnectionFlags.
+//
+// The RPC protocol defaults to defaultProtocol. The usage of the header flag is
+// given by the command, as the headers may be read along with other inputs.
+func NewConnectionFlags(defaultProtocol string, headersUsage string) *ConnectionFlags {
+	return &ConnectionFlags{
+		defaultProtocol: defaultProtocol,
+		headersUsage:    headersUsage,
+	}
+}
+
+// Bind binds the flags to the flag set.
+func (f *ConnectionFlags) Bind(flagSet *pflag.FlagSet) {
+	f.flagSet = flagSet
+
+	flagSet.StringVar(
+		&f.Protocol,
+		ProtocolFlagName,
+		f.defaultProtocol,
+		`The RPC protocol to use. This can be one of "grpc", "grpcweb", or "connect"`,
+	)
+	flagSet.StringVar(
+		&f.UnixSocket,
+		UnixSocketFlagName,
+		"",
+		`The path to a unix socket that will be used instead of opening a TCP socket to the host
+and port indicated in the URL`,
+	)
+	flagSet.BoolVar(
+		&f.HTTP2PriorKnowledge,
+		HTTP2PriorKnowledgeFlagName,
+		false,
+		`This flag can be used to indicate that HTTP/2 should be used. Without this, HTTP 1.1
+will be used with URLs with an http scheme, and protocol negotiation will be used to
+choose either HTTP 1.1 or HTTP/2 for URLs with an https scheme. With this flag set,
+HTTP/2 is always used, even over plain-text.`,
+	)
+
+	flagSet.BoolVar(
+		&f.HTTP3,
+		HTTP3FlagName,
+		false,
+		`This flag can be used to indicate that HTTP/3 should be used. Without this, HTTP 1.1
+will be used with URLs with an http scheme, and protocol negotiation will be used to
+choose either HTTP 1.1 or HTTP/2 for URLs with an https scheme. With this flag set,
+HTTP/3 is always used.`,
+	)
+
+	flagSet.BoolVar(
+		&f.NoKeepAlive,
+		NoKeepAliveFlagName,
+		false,
+		`By default, connections are created using TCP keepalive. If this flag is present, they
+will be disabled`,
+	)
+	flagSet.Float64Var(
+		&f.KeepAliveTimeSeconds,
+		KeepAliveFlagName,
+		60,
+		`The duration, in seconds, between TCP keepalive transmissions`,
+	)
+	flagSet.Float64Var(
+		&f.ConnectTimeoutSeconds,
+		ConnectTimeoutFlagName,
+		0,
+		`The time limit, in seconds, for a connection to be established with the server. There is
+no limit if this flag is not present`,
+	)
+
+	flagSet.StringVar(
+		&f.Key,
+		KeyFlagName,
+		"",
+		fmt.Sprintf(`Path to a PEM-encoded X509 private key file, for using client certificates with TLS. This
+option is only valid when the URL uses the https scheme. A --%s or -%s flag must also be
+present to provide the certificate and public key that corresponds to the given
+private key`,
+			CertFlagName, CertFlagShortName,
+		),
+	)
+	flagSet.StringVarP(
+		&f.Cert,
+		CertFlagName,
+		CertFlagShortName,
+		"",
+		fmt.Sprintf(`Path to a PEM-encoded X509 certificate file, for using client certificates with TLS. This
+option is only valid when the URL uses the https scheme. A --%s flag must also be
+present to provide the private key that corresponds to the given certificate`,
+			KeyFlagName,
+		),
+	)
+	flagSet.StringVar(
+		&f.CACert,
+		CACertFlagName,
+		"",
+		fmt.Sprintf(`Path to a PEM-encoded X509 certificate pool file that contains the set of trusted
+certificate authorities/issuers. If omitted, the system's default set of trusted
+certificates are used to verify the server's certificate. This option is only valid
+when the URL uses the https scheme. It is not applicable if --%s or -%s flag is used`,
+			InsecureFlagName, InsecureFlagShortName,
+		),
+	)
+	flagSet.BoolVarP(
+		&f.Insecure,
+		InsecureFlagName,
+		InsecureFlagShortName,
+		false,
+		`If set, the TLS connection will be insecure and the server's certificate will NOT be
+verified. This is generally discouraged. This option is only valid when the URL uses
+the https scheme`,
+	)
+	flagSet.StringVar(
+		&f.ServerName,
+		ServerNameFlagName,
+		"",
+		`The server name to use in TLS handshakes (for SNI) if the URL scheme is https. If not
+specified, the default is the origin host in the URL or the value in a "Host" header if
+one is provided`,
+	)
+
+	flagSet.StringVarP(
+		&f.UserAgent,
+		UserAgentFlagName,
+		UserAgentFlagShortName,
+		"",
+		fmt.Sprintf(`The user agent string to send. This is ignored if a --%s or -%s flag is provided
+that sets a header named 'User-Agent'.`,
+			HeaderFlagName, HeaderFlagShortName,
+		),
+	)
+	flagSet.StringSliceVarP(
+		&f.Headers,
+		HeaderFlagName,
+		HeaderFlagShortName,
+		nil,
+		f.headersUsage,
+	)
+}
+
+// Validate validates the flags, given whether the URL of the server is secure (https).
+//
+// The flags must have been bound.
+func (f *ConnectionFlags) Validate(isSecure bool) error {
+	if (f.Key != "" || f.Cert != "" || f.CACert != "" || f.ServerName != "" || f.flagSet.Changed(InsecureFlagName)) &&
+		!isSecure {
+		return fmt.Errorf(
+			"TLS flags (--%s, --%s, --%s, --%s, --%s) should not be used unless URL is secure (https)",
+			KeyFlagName, CertFlagName, CACertFlagName, InsecureFlagName, ServerNameFlagName)
+	}
+	if (f.Key != "") != (f.Cert != "") {
+		return fmt.Errorf("if one of --%s or --%s flags is used, both should be used (mutual TLS with a client certificate requires both)", KeyFlagName, CertFlagName)
+	}
+	if f.Insecure && f.CACert != "" {
+		return fmt.Errorf("if --%s is set, --%s should not be set as it is unused", InsecureFlagName, CACertFlagName)
+	}
+
+	if !isSecure && !f.HTTP2PriorKnowledge && f.Protocol == connect.ProtocolGRPC {
+		return fmt.Errorf("grpc protocol cannot be used with plain-text URLs (http) unless --%s flag is set", HTTP2PriorKnowledgeFlagName)
+	}
+
+	if !isSecure && f.HTTP3 {
+		return fmt.Errorf("--%s cannot be used with plain-text URLs (http)", HTTP3FlagName)
+	}
+
+	if f.UnixSocket != "" && f.HTTP3 {
+		return fmt.Errorf("--%s cannot be used with --%s", UnixSocketFlagName, HTTP3FlagName)
+	}
+
+	switch f.Protocol {
+	case connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb:
+	default:
+		return fmt.Errorf(
+			"--%s value must be one of %q, %q, or %q",
+			ProtocolFlagName, connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb)
+	}
+
+	if f.NoKeepAlive && f.flagSet.Changed(KeepAliveFlagName) {
+		return fmt.Errorf("--%s should not be specified if keepalive is disabled", KeepAliveFlagName)
+	}
+	if f.KeepAliveTimeSeconds <= 0 {
+		return fmt.Errorf("--%s value must be positive", KeepAliveFlagName)
+	}
+	// these two default to zero (which means no timeout in effect)
+	if f.ConnectTimeoutSeconds < 0 || (f.ConnectTimeoutSeconds == 0 && f.flagSet.Changed(ConnectTimeoutFlagName)) {
+		return fmt.Errorf("--%s value must be positive", ConnectTimeoutFlagName)
+	}
+	return nil
+}
+
+// ClientOptions returns the options for the clients used to connect to the server,
+// according to the RPC protocol.
+func (f *ConnectionFlags) ClientOptions() []connect.ClientOption {
+	switch f.Protocol {
+	case connect.ProtocolGRPC:
+		return []connect.ClientOption{connect.WithGRPC()}
+	case connect.ProtocolGRPCWeb:
+		return []connect.ClientOption{connect.WithGRPCWeb()}
+	default:
+		return nil
+	}
+}
+
+// SetUserAgent sets the User-Agent header to the user agent of the flags, or to the
+// default user agent for the given version, unless the headers already have one.
+func (f *ConnectionFlags) SetUserAgent(headers http.Header, version string) {
+	if len(headers.Values("user-agent")) != 0 {
+		return
+	}
+	userAgent := f.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent(f.Protocol, version)
+	}
+	headers.Set("user-agent", userAgent)
+}
+
+// TransportSettings returns the TransportSettings for the flags.
+func (f *ConnectionFlags) TransportSettings() *TransportSettings {
+	settings := &TransportSettings{
+		TLS: &TLSSettings{
+			KeyFile:             f.Key,
+			CertFile:            f.Cert,
+			CACertFile:          f.CACert,
+			ServerName:          f.ServerName,
+			Insecure:            f.Insecure,
+			HTTP2PriorKnowledge: f.HTTP2PriorKnowledge,
+			HTTP3:               f.HTTP3,
+		},
+		UnixSocket:  f.UnixSocket,
+		NoKeepAlive: f.NoKeepAlive,
+	}
+	if f.ConnectTimeoutSeconds != 0 {
+		settings.ConnectTimeout = secondsToDuration(f.ConnectTimeoutSeconds)
+	}
+	if !f.NoKeepAlive {
+		settings.KeepAliveTime = secondsToDuration(f.KeepAliveTimeSeconds)
+	}
+	return settings
+}
@@ -0,0 +1,187 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+)
+
+// TransportSettings contains settings related to creating an HTTP transport.
+type TransportSettings struct {
+	// The TLS settings, used if the URL is secure.
+	TLS *TLSSettings
+	// The path to a unix socket to dial instead of the host and port of the URL.
+	UnixSocket string
+	// The time limit for a connection to be established. Zero means no limit.
+	ConnectTimeout time.Duration
+	// If true, TCP keepalive is disabled.
+	NoKeepAlive bool
+	// The duration between TCP keepalive transmissions.
+	KeepAliveTime time.Duration
+}
+
+// NewHTTPRoundTripper constructs an http.RoundTripper that logs information
+// to the given printer as connections are established.
+//
+// The protocol used is determined by the HTTP2PriorKnowledge and HTTP3 TLS settings,
+// which are also used if the URL is not secure.
+func NewHTTPRoundTripper(
+	settings *TransportSettings,
+	isSecure bool,
+	authority string,
+	printer verbose.Printer,
+) (http.RoundTripper, error) {
+	if settings.TLS.HTTP3 {
+		return newHTTP3RoundTripper(settings, authority, printer)
+	}
+	var dialer net.Dialer
+	if settings.ConnectTimeout != 0 {
+		dialer.Timeout = settings.ConnectTimeout
+	}
+	if settings.NoKeepAlive {
+		dialer.KeepAlive = -1
+	} else {
+		dialer.KeepAlive = settings.KeepAliveTime
+	}
+
+	var dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+	if settings.UnixSocket != "" {
+		dialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			printer.Printf("* Dialing unix socket %s...", settings.UnixSocket)
+			return dialer.DialContext(ctx, "unix", settings.UnixSocket)
+		}
+	} else {
+		dialFunc = func(ctx context.Context, network, address string) (net.Conn, error) {
+			printer.Printf("* Dialing (%s) %s...", network, address)
+			conn, err := dialer.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			printer.Printf("* Connected to %s", conn.RemoteAddr().String())
+			return conn, err
+		}
+	}
+
+	var dialTLSFunc func(ctx context.Context, network, address string) (net.Conn, error)
+	if isSecure {
+		tlsConfig, err := MakeVerboseTLSConfig(settings.TLS, authority, printer)
+		if err != nil {
+			return nil, err
+		}
+		dialTLSFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialFunc(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			printer.Printf("* ALPN: offering %s", strings.Join(tlsConfig.NextProtos, ","))
+			tlsConn := tls.Client(conn, tlsConfig)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return nil, err
+			}
+			return tlsConn, nil
+		}
+	}
+
+	var transport http.RoundTripper
+	switch {
+	case settings.TLS.HTTP2PriorKnowledge && isSecure:
+		transport = &http2.Transport{
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialTLSFunc(ctx, network, addr)
+			},
+		}
+	case settings.TLS.HTTP2PriorKnowledge:
+		transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialFunc(ctx, network, addr)
+			},
+		}
+	default:
+		transport = &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			DialContext:       dialFunc,
+			DialTLSContext:    dialTLSFunc,
+			ForceAttemptHTTP2: true,
+			MaxIdleConns:      1,
+		}
+	}
+	return transport, nil
+}
+
+// secondsToDuration converts the seconds of a flag value to a time.Duration.
+func secondsToDuration(secs float64) time.Duration {
+	return time.Duration(float64(time.Second) * secs)
+}
+
+func newHTTP3RoundTripper(
+	settings *TransportSettings,
+	authority string,
+	printer verbose.Printer,
+) (http.RoundTripper, error) {
+	quicCfg := &quic.Config{
+		KeepAlivePeriod: -1,
+	}
+	if settings.ConnectTimeout != 0 {
+		quicCfg.HandshakeIdleTimeout = settings.ConnectTimeout
+	}
+	if !settings.NoKeepAlive {
+		quicCfg.KeepAlivePeriod = settings.KeepAliveTime
+	}
+
+	tlsConfig, err := MakeVerboseTLSConfig(settings.TLS, authority, printer)
+	if err != nil {
+		return nil, err
+	}
+
+	udpConn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		return nil, err
+	}
+	transport := &quic.Transport{Conn: udpConn}
+	roundTripper := &http3.Transport{
+		TLSClientConfig: tlsConfig,
+		QUICConfig:      quicCfg,
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			printer.Printf("* Dialing (udp) %s...", addr)
+			udpAddr, err := net.ResolveUDPAddr("udp", addr)
+			if err != nil {
+				return nil, err
+			}
+			printer.Printf("* ALPN: offering %s", strings.Join(tlsCfg.NextProtos, ","))
+			conn, err := transport.DialEarly(ctx, udpAddr, tlsCfg, cfg)
+			if err != nil {
+				return nil, err
+			}
+			printer.Printf("* Connected to %s", conn.RemoteAddr().String())
+			return conn, err
+		},
+		EnableDatagrams:        false,
+		AdditionalSettings:     map[uint64]uint64{},
+		MaxResponseHeaderBytes: 0,
+		DisableCompression:     false,
+	}
+	return roundTripper, nil
+}
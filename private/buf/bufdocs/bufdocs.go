@@ -0,0 +1,124 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufdocs renders API reference documentation for Protobuf files.
+package bufdocs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufprotosource"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+)
+
+const (
+	// FormatMarkdown is the Markdown format.
+	FormatMarkdown Format = 1
+	// FormatHTML is the HTML format.
+	FormatHTML Format = 2
+)
+
+var (
+	// AllFormatsString is the string representation of all Formats.
+	AllFormatsString = stringutil.SliceToString([]string{FormatMarkdown.String(), FormatHTML.String()})
+)
+
+// Format is a format to render documentation in.
+type Format int
+
+// ParseFormat parses the format.
+//
+// If the empty string is provided, this is interpreted as FormatMarkdown.
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "", "markdown":
+		return FormatMarkdown, nil
+	case "html":
+		return FormatHTML, nil
+	default:
+		return 0, fmt.Errorf("unknown format: %s", s)
+	}
+}
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	switch f {
+	case FormatMarkdown:
+		return "markdown"
+	case FormatHTML:
+		return "html"
+	default:
+		return fmt.Sprintf("%d", f)
+	}
+}
+
+// Write renders the documentation of the files that are not imports, and writes
+// it to the bucket.
+//
+// One page is written for every package, named by the package and the extension
+// of the format, such as "acme.v1.md". Files without a package are on the page
+// named "default". An index page named "index" lists all packages.
+//
+// Every page documents the services, messages, enums, and extensions of the package
+// with their comments, and references to types of documented packages are links.
+// Import files are only used to resolve types.
+func Write(
+	ctx context.Context,
+	writeBucket storage.WriteBucket,
+	files []bufprotosource.File,
+	format Format,
+) error {
+	var renderer renderer
+	switch format {
+	case FormatMarkdown:
+		renderer = newMarkdownRenderer()
+	case FormatHTML:
+		renderer = newHTMLRenderer()
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+	packages, err := newDocPackages(files)
+	if err != nil {
+		return err
+	}
+	indexData, err := renderer.RenderIndex(packages)
+	if err != nil {
+		return err
+	}
+	if err := storage.PutPath(ctx, writeBucket, "index"+renderer.Extension(), indexData); err != nil {
+		return err
+	}
+	for _, docPackage := range packages {
+		data, err := renderer.RenderPackage(docPackage)
+		if err != nil {
+			return err
+		}
+		if err := storage.PutPath(ctx, writeBucket, docPackage.PageName+renderer.Extension(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// *** PRIVATE ***
+
+// renderer renders pages in a format.
+type renderer interface {
+	// Extension returns the file extension of the pages, such as ".md".
+	Extension() string
+	RenderIndex(packages []*docPackage) ([]byte, error)
+	RenderPackage(docPackage *docPackage) ([]byte, error)
+}
@@ -0,0 +1,260 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdocs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/bufpkg/bufprotosource"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/require"
+)
+
+var testPathToData = map[string][]byte{
+	"acme/v1/user.proto": []byte(`syntax = "proto3";
+
+package acme.v1;
+
+import "acme/common/v1/money.proto";
+import "google/protobuf/timestamp.proto";
+
+// Manages users.
+service UserService {
+  // Gets a user.
+  rpc GetUser(GetUserRequest) returns (GetUserResponse);
+  // Watches users.
+  rpc WatchUsers(GetUserRequest) returns (stream GetUserResponse) {
+    option deprecated = true;
+  }
+}
+
+message GetUserRequest {
+  // The ID of the user | or their email.
+  string id = 1;
+}
+
+message GetUserResponse {
+  User user = 1;
+}
+
+// A user.
+//
+// Users are created on sign up.
+message User {
+  message Address {
+    string city = 1;
+  }
+  string id = 1; // The ID.
+  repeated Address addresses = 2;
+  map<string, acme.common.v1.Money> balances = 3;
+  optional google.protobuf.Timestamp create_time = 4;
+  Status status = 5 [deprecated = true];
+  oneof contact {
+    string email = 6;
+    string phone = 7;
+  }
+}
+
+// The status of a user.
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_ACTIVE = 1 [deprecated = true];
+}
+`),
+	"acme/common/v1/money.proto": []byte(`syntax = "proto3";
+
+package acme.common.v1;
+
+message Money {}
+`),
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	t.Parallel()
+	readBucket := testWrite(t, FormatMarkdown)
+	testRequireFile(
+		t,
+		readBucket,
+		"index.md",
+		`# API Reference
+
+| Package | Files |
+| --- | --- |
+| [`+"`acme.common.v1`"+`](acme.common.v1.md) | 1 |
+| [`+"`acme.v1`"+`](acme.v1.md) | 1 |
+`,
+	)
+	testRequireFile(
+		t,
+		readBucket,
+		"acme.v1.md",
+		"# `acme.v1`\n"+`
+[Index](index.md)
+
+Files:
+
+- `+"`acme/v1/user.proto`"+`
+
+## Services
+
+<a id="acme.v1.UserService"></a>
+
+### `+"`UserService`"+`
+
+Manages users.
+
+| Method | Request | Response | Description |
+| --- | --- | --- | --- |
+| `+"`GetUser` | [`acme.v1.GetUserRequest`](#acme.v1.GetUserRequest) | [`acme.v1.GetUserResponse`](#acme.v1.GetUserResponse)"+` | Gets a user. |
+| `+"`WatchUsers` | [`acme.v1.GetUserRequest`](#acme.v1.GetUserRequest) | stream [`acme.v1.GetUserResponse`](#acme.v1.GetUserResponse)"+` | **Deprecated.** Watches users. |
+
+## Messages
+
+<a id="acme.v1.GetUserRequest"></a>
+
+### `+"`GetUserRequest`"+`
+
+| Field | Number | Type | Label | Description |
+| --- | --- | --- | --- | --- |
+| `+"`id` | 1 | `string`"+` |  | The ID of the user \| or their email. |
+
+<a id="acme.v1.GetUserResponse"></a>
+
+### `+"`GetUserResponse`"+`
+
+| Field | Number | Type | Label | Description |
+| --- | --- | --- | --- | --- |
+| `+"`user` | 1 | [`acme.v1.User`](#acme.v1.User)"+` |  |  |
+
+<a id="acme.v1.User"></a>
+
+### `+"`User`"+`
+
+A user.
+
+Users are created on sign up.
+
+| Field | Number | Type | Label | Description |
+| --- | --- | --- | --- | --- |
+| `+"`id` | 1 | `string`"+` |  | The ID. |
+| `+"`addresses` | 2 | [`acme.v1.User.Address`](#acme.v1.User.Address)"+` | repeated |  |
+| `+"`balances` | 3 | [`map<string, acme.common.v1.Money>`](acme.common.v1.md#acme.common.v1.Money)"+` |  |  |
+| `+"`create_time` | 4 | `google.protobuf.Timestamp`"+` | optional |  |
+| `+"`status` | 5 | [`acme.v1.Status`](#acme.v1.Status)"+` |  | **Deprecated.** |
+| `+"`email` | 6 | `string`"+` |  | Part of oneof `+"`contact`"+`. |
+| `+"`phone` | 7 | `string`"+` |  | Part of oneof `+"`contact`"+`. |
+
+<a id="acme.v1.User.Address"></a>
+
+### `+"`User.Address`"+`
+
+| Field | Number | Type | Label | Description |
+| --- | --- | --- | --- | --- |
+| `+"`city` | 1 | `string`"+` |  |  |
+
+## Enums
+
+<a id="acme.v1.Status"></a>
+
+### `+"`Status`"+`
+
+The status of a user.
+
+| Value | Number | Description |
+| --- | --- | --- |
+| `+"`STATUS_UNSPECIFIED`"+` | 0 |  |
+| `+"`STATUS_ACTIVE`"+` | 1 | **Deprecated.** |
+`,
+	)
+	testRequireFile(
+		t,
+		readBucket,
+		"acme.common.v1.md",
+		"# `acme.common.v1`\n"+`
+[Index](index.md)
+
+Files:
+
+- `+"`acme/common/v1/money.proto`"+`
+
+## Messages
+
+<a id="acme.common.v1.Money"></a>
+
+### `+"`Money`"+`
+
+This message has no fields.
+`,
+	)
+}
+
+func TestWriteHTML(t *testing.T) {
+	t.Parallel()
+	readBucket := testWrite(t, FormatHTML)
+	data, err := storage.ReadPath(context.Background(), readBucket, "index.html")
+	require.NoError(t, err)
+	require.Contains(t, string(data), `<a href="acme.v1.html">acme.v1</a>`)
+	data, err = storage.ReadPath(context.Background(), readBucket, "acme.v1.html")
+	require.NoError(t, err)
+	html := string(data)
+	require.Contains(t, html, `<h3 id="acme.v1.User"><code>User</code></h3>`)
+	require.Contains(
+		t,
+		html,
+		`<a href="acme.common.v1.html#acme.common.v1.Money"><code>map&lt;string, acme.common.v1.Money&gt;</code></a>`,
+	)
+	require.Contains(t, html, `<code>google.protobuf.Timestamp</code>`)
+	require.Contains(t, html, `<span class="deprecated">Deprecated.</span> <span class="description">Watches users.</span>`)
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+	for _, format := range []Format{FormatMarkdown, FormatHTML} {
+		parsedFormat, err := ParseFormat(format.String())
+		require.NoError(t, err)
+		require.Equal(t, format, parsedFormat)
+	}
+	_, err := ParseFormat("pdf")
+	require.EqualError(t, err, "unknown format: pdf")
+}
+
+func testWrite(t *testing.T, format Format) storage.ReadBucket {
+	ctx := context.Background()
+	moduleSet, err := bufmoduletesting.NewModuleSetForPathToData(testPathToData)
+	require.NoError(t, err)
+	image, err := bufimage.BuildImage(
+		ctx,
+		slogtestext.NewLogger(t),
+		bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(moduleSet),
+	)
+	require.NoError(t, err)
+	files, err := bufprotosource.NewFiles(ctx, image.Files(), image.Resolver())
+	require.NoError(t, err)
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, Write(ctx, readWriteBucket, files, format))
+	return readWriteBucket
+}
+
+func testRequireFile(t *testing.T, readBucket storage.ReadBucket, path string, expected string) {
+	data, err := storage.ReadPath(context.Background(), readBucket, path)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(data))
+}
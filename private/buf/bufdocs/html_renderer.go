@@ -0,0 +1,192 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdocs
+
+import (
+	"bytes"
+	"html/template"
+)
+
+const htmlTemplates = `
+{{- define "head" -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ . }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 64em; padding: 0 1em; }
+table { border-collapse: collapse; margin-bottom: 1em; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 0.4em; text-align: left; vertical-align: top; }
+.deprecated { color: #b00; font-weight: bold; }
+.description { white-space: pre-line; }
+</style>
+</head>
+<body>
+{{ end -}}
+
+{{- define "description" -}}
+{{ if .Deprecated }}<span class="deprecated">Deprecated.</span> {{ end -}}
+{{ if .Description }}<span class="description">{{ .Description }}</span>{{ end }}
+{{- end -}}
+
+{{- define "type" -}}
+{{ if .Href }}<a href="{{ .Href }}"><code>{{ .Name }}</code></a>{{ else }}<code>{{ .Name }}</code>{{ end }}
+{{- end -}}
+
+{{- define "index" -}}
+{{ template "head" "API Reference" -}}
+<h1>API Reference</h1>
+<table>
+<tr><th>Package</th><th>Files</th></tr>
+{{ range . -}}
+<tr><td><a href="{{ .PageName }}.html">{{ packageTitle . }}</a></td><td>{{ len .FilePaths }}</td></tr>
+{{ end -}}
+</table>
+</body>
+</html>
+{{ end -}}
+
+{{- define "package" -}}
+{{ $package := . -}}
+{{ template "head" (packageTitle .) -}}
+<h1>{{ packageTitle . }}</h1>
+<p><a href="index.html">Index</a></p>
+<p>Files:</p>
+<ul>
+{{ range .FilePaths -}}
+<li><code>{{ . }}</code></li>
+{{ end -}}
+</ul>
+{{ if .Services -}}
+<h2>Services</h2>
+{{ range .Services -}}
+<h3 id="{{ .Anchor }}"><code>{{ .Name }}</code></h3>
+{{ if or .Deprecated .Description }}<p>{{ template "description" . }}</p>{{ end }}
+<table>
+<tr><th>Method</th><th>Request</th><th>Response</th><th>Description</th></tr>
+{{ range .Methods -}}
+<tr><td><code>{{ .Name }}</code></td><td>{{ if .ClientStreaming }}stream {{ end }}{{ template "type" (typeLink $package .Request) }}</td><td>{{ if .ServerStreaming }}stream {{ end }}{{ template "type" (typeLink $package .Response) }}</td><td>{{ template "description" . }}</td></tr>
+{{ end -}}
+</table>
+{{ end -}}
+{{ end -}}
+{{ if .Messages -}}
+<h2>Messages</h2>
+{{ range .Messages -}}
+<h3 id="{{ .Anchor }}"><code>{{ .Name }}</code></h3>
+{{ if or .Deprecated .Description }}<p>{{ template "description" . }}</p>{{ end }}
+{{ if .Fields -}}
+<table>
+<tr><th>Field</th><th>Number</th><th>Type</th><th>Label</th><th>Description</th></tr>
+{{ range .Fields -}}
+<tr><td><code>{{ .Name }}</code></td><td>{{ .Number }}</td><td>{{ template "type" (typeLink $package .Type) }}</td><td>{{ .Label }}</td><td>{{ template "description" . }}</td></tr>
+{{ end -}}
+</table>
+{{ else -}}
+<p>This message has no fields.</p>
+{{ end -}}
+{{ end -}}
+{{ end -}}
+{{ if .Enums -}}
+<h2>Enums</h2>
+{{ range .Enums -}}
+<h3 id="{{ .Anchor }}"><code>{{ .Name }}</code></h3>
+{{ if or .Deprecated .Description }}<p>{{ template "description" . }}</p>{{ end }}
+<table>
+<tr><th>Value</th><th>Number</th><th>Description</th></tr>
+{{ range .Values -}}
+<tr><td><code>{{ .Name }}</code></td><td>{{ .Number }}</td><td>{{ template "description" . }}</td></tr>
+{{ end -}}
+</table>
+{{ end -}}
+{{ end -}}
+{{ if .Extensions -}}
+<h2>Extensions</h2>
+<table>
+<tr><th>Extension</th><th>Extendee</th><th>Number</th><th>Type</th><th>Label</th><th>Description</th></tr>
+{{ range .Extensions -}}
+<tr><td><code>{{ .Name }}</code></td><td>{{ template "type" (typeLink $package .Extendee) }}</td><td>{{ .Number }}</td><td>{{ template "type" (typeLink $package .Type) }}</td><td>{{ .Label }}</td><td>{{ template "description" . }}</td></tr>
+{{ end -}}
+</table>
+{{ end -}}
+</body>
+</html>
+{{ end -}}
+`
+
+type htmlRenderer struct {
+	template *template.Template
+}
+
+func newHTMLRenderer() *htmlRenderer {
+	return &htmlRenderer{
+		template: template.Must(
+			template.New("").Funcs(
+				template.FuncMap{
+					"packageTitle": getHTMLPackageTitle,
+					"typeLink":     getHTMLTypeLink,
+				},
+			).Parse(htmlTemplates),
+		),
+	}
+}
+
+func (*htmlRenderer) Extension() string {
+	return ".html"
+}
+
+func (r *htmlRenderer) RenderIndex(packages []*docPackage) ([]byte, error) {
+	return r.execute("index", packages)
+}
+
+func (r *htmlRenderer) RenderPackage(docPackage *docPackage) ([]byte, error) {
+	return r.execute("package", docPackage)
+}
+
+func (r *htmlRenderer) execute(name string, data any) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := r.template.ExecuteTemplate(buffer, name, data); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// htmlTypeLink is a type with the link to its documentation, if any.
+type htmlTypeLink struct {
+	Name string
+	Href string
+}
+
+func getHTMLTypeLink(docPackage *docPackage, docType *docType) htmlTypeLink {
+	htmlTypeLink := htmlTypeLink{
+		Name: docType.Name,
+	}
+	switch docType.LinkPageName {
+	case "":
+	case docPackage.PageName:
+		htmlTypeLink.Href = "#" + docType.LinkAnchor
+	default:
+		htmlTypeLink.Href = docType.LinkPageName + ".html#" + docType.LinkAnchor
+	}
+	return htmlTypeLink
+}
+
+func getHTMLPackageTitle(docPackage *docPackage) string {
+	if docPackage.Name == "" {
+		return "Default package"
+	}
+	return docPackage.Name
+}
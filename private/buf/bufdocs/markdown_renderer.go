@@ -0,0 +1,185 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdocs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const markdownDeprecated = "**Deprecated.**"
+
+type markdownRenderer struct{}
+
+func newMarkdownRenderer() *markdownRenderer {
+	return &markdownRenderer{}
+}
+
+func (*markdownRenderer) Extension() string {
+	return ".md"
+}
+
+func (r *markdownRenderer) RenderIndex(packages []*docPackage) ([]byte, error) {
+	builder := &strings.Builder{}
+	builder.WriteString("# API Reference\n\n")
+	builder.WriteString("| Package | Files |\n| --- | --- |\n")
+	for _, docPackage := range packages {
+		writeMarkdownRow(
+			builder,
+			fmt.Sprintf("[%s](%s%s)", getMarkdownPackageTitle(docPackage), docPackage.PageName, r.Extension()),
+			strconv.Itoa(len(docPackage.FilePaths)),
+		)
+	}
+	return []byte(builder.String()), nil
+}
+
+func (r *markdownRenderer) RenderPackage(docPackage *docPackage) ([]byte, error) {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "# %s\n\n", getMarkdownPackageTitle(docPackage))
+	builder.WriteString("[Index](index" + r.Extension() + ")\n\nFiles:\n\n")
+	for _, filePath := range docPackage.FilePaths {
+		fmt.Fprintf(builder, "- `%s`\n", filePath)
+	}
+	if len(docPackage.Services) > 0 {
+		builder.WriteString("\n## Services\n")
+		for _, docService := range docPackage.Services {
+			writeMarkdownHeading(builder, docService.Anchor, docService.Name, docService.Description, docService.Deprecated)
+			builder.WriteString("| Method | Request | Response | Description |\n| --- | --- | --- | --- |\n")
+			for _, docMethod := range docService.Methods {
+				writeMarkdownRow(
+					builder,
+					"`"+docMethod.Name+"`",
+					r.getStreamPrefix(docMethod.ClientStreaming)+r.getTypeLink(docPackage, docMethod.Request),
+					r.getStreamPrefix(docMethod.ServerStreaming)+r.getTypeLink(docPackage, docMethod.Response),
+					getMarkdownDescription(docMethod.Description, docMethod.Deprecated),
+				)
+			}
+		}
+	}
+	if len(docPackage.Messages) > 0 {
+		builder.WriteString("\n## Messages\n")
+		for _, docMessage := range docPackage.Messages {
+			writeMarkdownHeading(builder, docMessage.Anchor, docMessage.Name, docMessage.Description, docMessage.Deprecated)
+			if len(docMessage.Fields) == 0 {
+				builder.WriteString("This message has no fields.\n")
+				continue
+			}
+			builder.WriteString("| Field | Number | Type | Label | Description |\n| --- | --- | --- | --- | --- |\n")
+			for _, docField := range docMessage.Fields {
+				writeMarkdownRow(
+					builder,
+					"`"+docField.Name+"`",
+					strconv.Itoa(docField.Number),
+					r.getTypeLink(docPackage, docField.Type),
+					docField.Label,
+					getMarkdownDescription(docField.Description, docField.Deprecated),
+				)
+			}
+		}
+	}
+	if len(docPackage.Enums) > 0 {
+		builder.WriteString("\n## Enums\n")
+		for _, docEnum := range docPackage.Enums {
+			writeMarkdownHeading(builder, docEnum.Anchor, docEnum.Name, docEnum.Description, docEnum.Deprecated)
+			builder.WriteString("| Value | Number | Description |\n| --- | --- | --- |\n")
+			for _, docEnumValue := range docEnum.Values {
+				writeMarkdownRow(
+					builder,
+					"`"+docEnumValue.Name+"`",
+					strconv.Itoa(docEnumValue.Number),
+					getMarkdownDescription(docEnumValue.Description, docEnumValue.Deprecated),
+				)
+			}
+		}
+	}
+	if len(docPackage.Extensions) > 0 {
+		builder.WriteString("\n## Extensions\n\n")
+		builder.WriteString("| Extension | Extendee | Number | Type | Label | Description |\n| --- | --- | --- | --- | --- | --- |\n")
+		for _, docField := range docPackage.Extensions {
+			writeMarkdownRow(
+				builder,
+				"`"+docField.Name+"`",
+				r.getTypeLink(docPackage, docField.Extendee),
+				strconv.Itoa(docField.Number),
+				r.getTypeLink(docPackage, docField.Type),
+				docField.Label,
+				getMarkdownDescription(docField.Description, docField.Deprecated),
+			)
+		}
+	}
+	return []byte(builder.String()), nil
+}
+
+func (r *markdownRenderer) getTypeLink(docPackage *docPackage, docType *docType) string {
+	code := "`" + docType.Name + "`"
+	if docType.LinkPageName == "" {
+		return code
+	}
+	if docType.LinkPageName == docPackage.PageName {
+		return fmt.Sprintf("[%s](#%s)", code, docType.LinkAnchor)
+	}
+	return fmt.Sprintf("[%s](%s%s#%s)", code, docType.LinkPageName, r.Extension(), docType.LinkAnchor)
+}
+
+func (*markdownRenderer) getStreamPrefix(streaming bool) string {
+	if streaming {
+		return "stream "
+	}
+	return ""
+}
+
+// writeMarkdownHeading writes the heading of a service, message, or enum, with an
+// explicit anchor so that links do not depend on how headings are turned into anchors.
+func writeMarkdownHeading(
+	builder *strings.Builder,
+	anchor string,
+	name string,
+	description string,
+	deprecated bool,
+) {
+	fmt.Fprintf(builder, "\n<a id=\"%s\"></a>\n\n### `%s`\n\n", anchor, name)
+	if deprecated {
+		builder.WriteString(markdownDeprecated + "\n\n")
+	}
+	if description != "" {
+		builder.WriteString(description + "\n\n")
+	}
+}
+
+func writeMarkdownRow(builder *strings.Builder, cells ...string) {
+	for i, cell := range cells {
+		cells[i] = strings.ReplaceAll(
+			strings.ReplaceAll(cell, "|", `\|`),
+			"\n",
+			"<br>",
+		)
+	}
+	builder.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+}
+
+func getMarkdownDescription(description string, deprecated bool) string {
+	if deprecated {
+		return strings.TrimSpace(markdownDeprecated + " " + description)
+	}
+	return description
+}
+
+func getMarkdownPackageTitle(docPackage *docPackage) string {
+	if docPackage.Name == "" {
+		return "Default package"
+	}
+	return "`" + docPackage.Name + "`"
+}
@@ -0,0 +1,397 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdocs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufprotosource"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// defaultPageName is the name of the page of files without a package.
+const defaultPageName = "default"
+
+type docPackage struct {
+	Name       string
+	PageName   string
+	FilePaths  []string
+	Services   []*docService
+	Messages   []*docMessage
+	Enums      []*docEnum
+	Extensions []*docField
+}
+
+type docService struct {
+	Anchor      string
+	Name        string
+	Description string
+	Deprecated  bool
+	Methods     []*docMethod
+}
+
+type docMethod struct {
+	Name            string
+	Description     string
+	Deprecated      bool
+	Request         *docType
+	Response        *docType
+	ClientStreaming bool
+	ServerStreaming bool
+}
+
+type docMessage struct {
+	Anchor      string
+	Name        string
+	Description string
+	Deprecated  bool
+	Fields      []*docField
+}
+
+type docField struct {
+	Name        string
+	Number      int
+	Label       string
+	Type        *docType
+	Extendee    *docType
+	Description string
+	Deprecated  bool
+}
+
+type docEnum struct {
+	Anchor      string
+	Name        string
+	Description string
+	Deprecated  bool
+	Values      []*docEnumValue
+}
+
+type docEnumValue struct {
+	Name        string
+	Number      int
+	Description string
+	Deprecated  bool
+}
+
+// docType is a reference to a type.
+type docType struct {
+	// Name is the name to display, such as "string", "acme.v1.User", or
+	// "map<string, acme.v1.User>".
+	Name string
+	// LinkPageName is the name of the page that documents the type, or
+	// empty if the type is not documented.
+	LinkPageName string
+	// LinkAnchor is the anchor of the type on the page.
+	LinkAnchor string
+}
+
+// typeResolver resolves the type names of fields and methods.
+type typeResolver struct {
+	// fullNameToMessage contains the messages of all files, including imports,
+	// to find map entries.
+	fullNameToMessage map[string]bufprotosource.Message
+	// fullNameToPageName contains the messages and enums of documented files.
+	fullNameToPageName map[string]string
+}
+
+func newDocPackages(files []bufprotosource.File) ([]*docPackage, error) {
+	fullNameToMessage, err := bufprotosource.FullNameToMessage(files...)
+	if err != nil {
+		return nil, err
+	}
+	var targetFiles []bufprotosource.File
+	for _, file := range files {
+		if !file.IsImport() {
+			targetFiles = append(targetFiles, file)
+		}
+	}
+	typeResolver := &typeResolver{
+		fullNameToMessage:  fullNameToMessage,
+		fullNameToPageName: make(map[string]string),
+	}
+	for _, file := range targetFiles {
+		pageName := getPageName(file.Package())
+		if err := bufprotosource.ForEachMessage(
+			func(message bufprotosource.Message) error {
+				typeResolver.fullNameToPageName[message.FullName()] = pageName
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+		if err := bufprotosource.ForEachEnum(
+			func(enum bufprotosource.Enum) error {
+				typeResolver.fullNameToPageName[enum.FullName()] = pageName
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+	}
+	packageToFiles, err := bufprotosource.PackageToFiles(targetFiles...)
+	if err != nil {
+		return nil, err
+	}
+	packages := make([]*docPackage, 0, len(packageToFiles))
+	for packageName, packageFiles := range packageToFiles {
+		docPackage, err := newDocPackage(typeResolver, packageName, packageFiles)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, docPackage)
+	}
+	sort.Slice(
+		packages,
+		func(i int, j int) bool {
+			return packages[i].PageName < packages[j].PageName
+		},
+	)
+	return packages, nil
+}
+
+func newDocPackage(
+	typeResolver *typeResolver,
+	packageName string,
+	files []bufprotosource.File,
+) (*docPackage, error) {
+	docPackage := &docPackage{
+		Name:     packageName,
+		PageName: getPageName(packageName),
+	}
+	for _, file := range files {
+		docPackage.FilePaths = append(docPackage.FilePaths, file.Path())
+		for _, service := range file.Services() {
+			docPackage.Services = append(docPackage.Services, newDocService(typeResolver, service))
+		}
+		if err := bufprotosource.ForEachMessage(
+			func(message bufprotosource.Message) error {
+				if message.IsMapEntry() {
+					return nil
+				}
+				docMessage, err := newDocMessage(typeResolver, message)
+				if err != nil {
+					return err
+				}
+				docPackage.Messages = append(docPackage.Messages, docMessage)
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+		if err := bufprotosource.ForEachEnum(
+			func(enum bufprotosource.Enum) error {
+				docPackage.Enums = append(docPackage.Enums, newDocEnum(enum))
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+		if err := bufprotosource.ForEachExtension(
+			func(extension bufprotosource.Field) error {
+				docField, err := newDocField(typeResolver, extension)
+				if err != nil {
+					return err
+				}
+				docField.Name = extension.NestedName()
+				docField.Extendee = typeResolver.getNamedType(extension.Extendee())
+				docPackage.Extensions = append(docPackage.Extensions, docField)
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return docPackage, nil
+}
+
+func newDocService(typeResolver *typeResolver, service bufprotosource.Service) *docService {
+	docService := &docService{
+		Anchor:      service.FullName(),
+		Name:        service.Name(),
+		Description: getDescription(service),
+		Deprecated:  service.Deprecated(),
+	}
+	for _, method := range service.Methods() {
+		docService.Methods = append(
+			docService.Methods,
+			&docMethod{
+				Name:            method.Name(),
+				Description:     getDescription(method),
+				Deprecated:      method.Deprecated(),
+				Request:         typeResolver.getNamedType(method.InputTypeName()),
+				Response:        typeResolver.getNamedType(method.OutputTypeName()),
+				ClientStreaming: method.ClientStreaming(),
+				ServerStreaming: method.ServerStreaming(),
+			},
+		)
+	}
+	return docService
+}
+
+func newDocMessage(typeResolver *typeResolver, message bufprotosource.Message) (*docMessage, error) {
+	docMessage := &docMessage{
+		Anchor:      message.FullName(),
+		Name:        message.NestedName(),
+		Description: getDescription(message),
+		Deprecated:  message.Deprecated(),
+	}
+	for _, field := range message.Fields() {
+		docField, err := newDocField(typeResolver, field)
+		if err != nil {
+			return nil, err
+		}
+		docMessage.Fields = append(docMessage.Fields, docField)
+	}
+	return docMessage, nil
+}
+
+func newDocField(typeResolver *typeResolver, field bufprotosource.Field) (*docField, error) {
+	docType, isMap, err := typeResolver.getFieldType(field)
+	if err != nil {
+		return nil, err
+	}
+	description := getDescription(field)
+	// Synthetic oneofs of proto3 optional fields are not worth mentioning.
+	if oneof := field.Oneof(); oneof != nil && !field.Proto3Optional() {
+		description = strings.TrimSpace(fmt.Sprintf("Part of oneof `%s`. %s", oneof.Name(), description))
+	}
+	return &docField{
+		Name:        field.Name(),
+		Number:      field.Number(),
+		Label:       getLabel(field, isMap),
+		Type:        docType,
+		Description: description,
+		Deprecated:  field.Deprecated(),
+	}, nil
+}
+
+func newDocEnum(enum bufprotosource.Enum) *docEnum {
+	docEnum := &docEnum{
+		Anchor:      enum.FullName(),
+		Name:        enum.NestedName(),
+		Description: getDescription(enum),
+		Deprecated:  enum.Deprecated(),
+	}
+	for _, value := range enum.Values() {
+		docEnum.Values = append(
+			docEnum.Values,
+			&docEnumValue{
+				Name:        value.Name(),
+				Number:      value.Number(),
+				Description: getDescription(value),
+				Deprecated:  value.Deprecated(),
+			},
+		)
+	}
+	return docEnum
+}
+
+// getFieldType returns the type of the field, and whether the field is a map.
+func (t *typeResolver) getFieldType(field bufprotosource.Field) (*docType, bool, error) {
+	switch field.Type() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		fullName := strings.TrimPrefix(field.TypeName(), ".")
+		message, ok := t.fullNameToMessage[fullName]
+		if !ok || !message.IsMapEntry() {
+			return t.getNamedType(fullName), false, nil
+		}
+		fields := message.Fields()
+		if len(fields) != 2 {
+			return nil, false, fmt.Errorf("map entry %s does not have two fields", fullName)
+		}
+		keyType, _, err := t.getFieldType(fields[0])
+		if err != nil {
+			return nil, false, err
+		}
+		valueType, _, err := t.getFieldType(fields[1])
+		if err != nil {
+			return nil, false, err
+		}
+		// The map links to its value type, as keys are never messages or enums.
+		valueType.Name = fmt.Sprintf("map<%s, %s>", keyType.Name, valueType.Name)
+		return valueType, true, nil
+	case descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		return t.getNamedType(field.TypeName()), false, nil
+	default:
+		return &docType{
+			Name: strings.ToLower(strings.TrimPrefix(field.Type().String(), "TYPE_")),
+		}, false, nil
+	}
+}
+
+// getNamedType returns the type of the message or enum with the given type name,
+// which may have a leading dot.
+func (t *typeResolver) getNamedType(typeName string) *docType {
+	fullName := strings.TrimPrefix(typeName, ".")
+	return &docType{
+		Name:         fullName,
+		LinkPageName: t.fullNameToPageName[fullName],
+		LinkAnchor:   fullName,
+	}
+}
+
+func getLabel(field bufprotosource.Field, isMap bool) string {
+	switch {
+	case isMap:
+		return ""
+	case field.Label() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+		return "repeated"
+	case field.Label() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED:
+		return "required"
+	case field.Proto3Optional():
+		return "optional"
+	default:
+		return ""
+	}
+}
+
+// getDescription returns the leading and trailing comments of the descriptor.
+func getDescription(namedDescriptor bufprotosource.NamedDescriptor) string {
+	location := namedDescriptor.Location()
+	if location == nil {
+		return ""
+	}
+	var paragraphs []string
+	for _, comments := range []string{location.LeadingComments(), location.TrailingComments()} {
+		if comment := trimComment(comments); comment != "" {
+			paragraphs = append(paragraphs, comment)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// trimComment removes the leading space of the lines of a comment, and the leading
+// and trailing blank lines.
+func trimComment(comment string) string {
+	lines := strings.Split(comment, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(strings.TrimRight(line, " \t"), " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+func getPageName(packageName string) string {
+	if packageName == "" {
+		return defaultPageName
+	}
+	return packageName
+}
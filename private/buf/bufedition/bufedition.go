@@ -0,0 +1,55 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufedition migrates proto2 and proto3 files to Protobuf Editions.
+package bufedition
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ParseEdition parses the edition string, such as "2023", to an Edition that
+// files can be migrated to.
+func ParseEdition(value string) (descriptorpb.Edition, error) {
+	edition, ok := supportedEditionStringToEdition[value]
+	if !ok {
+		return 0, fmt.Errorf("unsupported edition %q, must be one of %q", value, supportedEditionStrings)
+	}
+	return edition, nil
+}
+
+// MigrateBucket migrates the .proto files at the given paths to the given Edition,
+// and returns a new bucket with the migrated files.
+//
+// The bucket must contain the files at the given paths, as well as all of their
+// imports that are not well-known types.
+//
+// Feature options are added as needed so that the semantics of the migrated files
+// are identical to the semantics of the original files. The original and migrated
+// files are compiled and compared, and an error is returned if there are any
+// differences between them. Files that already use Editions are returned unchanged.
+//
+// Groups are not supported, and an error is returned if any of the files contain a group.
+func MigrateBucket(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	paths []string,
+	edition descriptorpb.Edition,
+) (storage.ReadBucket, error) {
+	return migrateBucket(ctx, bucket, paths, edition)
+}
@@ -0,0 +1,183 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufedition
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/diff"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestMigrateBucket(t *testing.T) {
+	t.Parallel()
+	testMigrateBucket(t, "testdata/editions")
+	testMigrateBucket(t, "testdata/proto2")
+	testMigrateBucket(t, "testdata/proto3")
+}
+
+func TestMigrateBucketGroup(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket, err := storageos.NewProvider().NewReadWriteBucket("testdata/group")
+	require.NoError(t, err)
+	_, err = MigrateBucket(ctx, bucket, []string{"a.proto"}, descriptorpb.Edition_EDITION_2023)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `group "a.v1.Foo.bar" cannot be migrated`)
+}
+
+func TestParseEdition(t *testing.T) {
+	t.Parallel()
+	edition, err := ParseEdition("2023")
+	require.NoError(t, err)
+	assert.Equal(t, descriptorpb.Edition_EDITION_2023, edition)
+	_, err = ParseEdition("2024")
+	assert.Error(t, err)
+}
+
+func TestGetDifferences(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	originalBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto3";
+message Foo {
+  int32 one = 1;
+  repeated int32 two = 2;
+}
+`),
+		},
+	)
+	require.NoError(t, err)
+	// A naive migration that only changes the syntax declaration.
+	migratedBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a.proto": []byte(`edition = "2023";
+message Foo {
+  int32 one = 1;
+  repeated int32 two = 2;
+}
+`),
+		},
+	)
+	require.NoError(t, err)
+	originalFiles, err := compileFiles(ctx, originalBucket, []string{"a.proto"})
+	require.NoError(t, err)
+	migratedFiles, err := compileFiles(ctx, migratedBucket, []string{"a.proto"})
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			`a.proto: Foo.one has_presence was "false" but is "true"`,
+		},
+		getDifferences(originalFiles[0], migratedFiles[0]),
+	)
+}
+
+func TestGetDifferencesDeclarations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	originalBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a.proto": []byte(`syntax = "proto2";
+option java_package = "com.foo";
+message Foo {
+  option deprecated = true;
+  extensions 100 to 200;
+  reserved 5 to 6;
+  reserved "old";
+  optional int32 one = 1 [deprecated = true];
+  repeated int32 two = 2 [packed = true];
+}
+enum Bar {
+  reserved 3;
+  BAR_ZERO = 0 [deprecated = true];
+}
+`),
+		},
+	)
+	require.NoError(t, err)
+	// A migration that loses everything but the resolved semantics of the fields and enums.
+	migratedBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a.proto": []byte(`edition = "2023";
+option features.enum_type = CLOSED;
+option features.json_format = LEGACY_BEST_EFFORT;
+message Foo {
+  int32 one = 1;
+  repeated int32 two = 2;
+}
+enum Bar {
+  BAR_ZERO = 0;
+}
+`),
+		},
+	)
+	require.NoError(t, err)
+	originalFiles, err := compileFiles(ctx, originalBucket, []string{"a.proto"})
+	require.NoError(t, err)
+	migratedFiles, err := compileFiles(ctx, migratedBucket, []string{"a.proto"})
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]string{
+			`a.proto: BAR_ZERO options was "deprecated:true" but is ""`,
+			`a.proto: Bar reserved_ranges was "3" but is ""`,
+			`a.proto: Foo extension_range 100 options was "" but is missing`,
+			`a.proto: Foo extension_ranges was "100-200" but is ""`,
+			`a.proto: Foo options was "deprecated:true" but is ""`,
+			`a.proto: Foo reserved_names was "old" but is ""`,
+			`a.proto: Foo reserved_ranges was "5-6" but is ""`,
+			`a.proto: Foo.one options was "deprecated:true" but is ""`,
+			`a.proto: options was "java_package:\"com.foo\"" but is ""`,
+		},
+		getDifferences(originalFiles[0], migratedFiles[0]),
+	)
+}
+
+func testMigrateBucket(t *testing.T, dirPath string) {
+	t.Run(dirPath, func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		bucket, err := storageos.NewProvider().NewReadWriteBucket(dirPath)
+		require.NoError(t, err)
+		paths, err := storage.AllPaths(ctx, storage.FilterReadBucket(bucket, storage.MatchPathExt(".proto")), "")
+		require.NoError(t, err)
+		migratedBucket, err := MigrateBucket(ctx, bucket, paths, descriptorpb.Edition_EDITION_2023)
+		require.NoError(t, err)
+		for _, path := range paths {
+			t.Run(path, func(t *testing.T) {
+				migratedData, err := storage.ReadPath(ctx, migratedBucket, path)
+				require.NoError(t, err)
+				goldenPath := path + ".golden"
+				goldenFile, err := bucket.Get(ctx, goldenPath)
+				require.NoError(t, err)
+				goldenData, err := io.ReadAll(goldenFile)
+				require.NoError(t, err)
+				require.NoError(t, goldenFile.Close())
+				fileDiff, err := diff.Diff(ctx, goldenData, migratedData, goldenPath, path+" (migrated)")
+				require.NoError(t, err)
+				require.Empty(t, string(fileDiff))
+			})
+		}
+	})
+}
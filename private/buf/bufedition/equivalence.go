@@ -0,0 +1,233 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufedition
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/protocompile/protoutil"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// getDifferences returns the semantic differences between the original file and the migrated file.
+//
+// Both files are summarized into a map from element name and property to value, where the values
+// are resolved semantics such as field presence and packing, as opposed to how these were declared.
+// Options, reserved ranges and names, and extension ranges are compared as declared, except for
+// features and the packed option, which are compared as resolved semantics.
+func getDifferences(original protoreflect.FileDescriptor, migrated protoreflect.FileDescriptor) []string {
+	originalSummary := getSummary(original)
+	migratedSummary := getSummary(migrated)
+	var differences []string
+	for _, key := range slicesext.MapKeysToSortedSlice(originalSummary) {
+		originalValue := originalSummary[key]
+		migratedValue, ok := migratedSummary[key]
+		if !ok {
+			differences = append(differences, fmt.Sprintf("%s: %s was %q but is missing", original.Path(), key, originalValue))
+			continue
+		}
+		if originalValue != migratedValue {
+			differences = append(differences, fmt.Sprintf("%s: %s was %q but is %q", original.Path(), key, originalValue, migratedValue))
+		}
+	}
+	for _, key := range slicesext.MapKeysToSortedSlice(migratedSummary) {
+		if _, ok := originalSummary[key]; !ok {
+			differences = append(differences, fmt.Sprintf("%s: %s was added as %q", original.Path(), key, migratedSummary[key]))
+		}
+	}
+	return differences
+}
+
+func getSummary(file protoreflect.FileDescriptor) map[string]string {
+	summary := make(map[string]string)
+	summary["package"] = string(file.Package())
+	summary["options"] = getOptionsString(file.Options())
+	for i := 0; i < file.Enums().Len(); i++ {
+		addEnumToSummary(summary, file.Enums().Get(i))
+	}
+	for i := 0; i < file.Extensions().Len(); i++ {
+		addFieldToSummary(summary, file.Extensions().Get(i))
+	}
+	forEachMessage(file.Messages(), func(message protoreflect.MessageDescriptor) {
+		prefix := string(message.FullName())
+		summary[prefix+" map_entry"] = strconv.FormatBool(message.IsMapEntry())
+		summary[prefix+" json_format"] = resolveFeatureString(message, "json_format")
+		summary[prefix+" options"] = getOptionsString(message.Options())
+		summary[prefix+" reserved_ranges"] = getFieldRangesString(message.ReservedRanges())
+		summary[prefix+" reserved_names"] = getNamesString(message.ReservedNames())
+		summary[prefix+" extension_ranges"] = getFieldRangesString(message.ExtensionRanges())
+		for i := 0; i < message.ExtensionRanges().Len(); i++ {
+			extensionRange := message.ExtensionRanges().Get(i)
+			summary[fmt.Sprintf("%s extension_range %d options", prefix, extensionRange[0])] = getOptionsString(message.ExtensionRangeOptions(i))
+		}
+		for i := 0; i < message.Oneofs().Len(); i++ {
+			// Synthetic oneofs for proto3 optional fields are replaced by explicit field presence.
+			if oneof := message.Oneofs().Get(i); !oneof.IsSynthetic() {
+				summary[string(oneof.FullName())+" options"] = getOptionsString(oneof.Options())
+			}
+		}
+		for i := 0; i < message.Enums().Len(); i++ {
+			addEnumToSummary(summary, message.Enums().Get(i))
+		}
+		for i := 0; i < message.Fields().Len(); i++ {
+			addFieldToSummary(summary, message.Fields().Get(i))
+		}
+		for i := 0; i < message.Extensions().Len(); i++ {
+			addFieldToSummary(summary, message.Extensions().Get(i))
+		}
+	})
+	for i := 0; i < file.Services().Len(); i++ {
+		service := file.Services().Get(i)
+		summary[string(service.FullName())+" options"] = getOptionsString(service.Options())
+		for j := 0; j < service.Methods().Len(); j++ {
+			method := service.Methods().Get(j)
+			prefix := string(method.FullName())
+			summary[prefix+" options"] = getOptionsString(method.Options())
+			summary[prefix+" input"] = string(method.Input().FullName())
+			summary[prefix+" output"] = string(method.Output().FullName())
+			summary[prefix+" client_streaming"] = strconv.FormatBool(method.IsStreamingClient())
+			summary[prefix+" server_streaming"] = strconv.FormatBool(method.IsStreamingServer())
+		}
+	}
+	return summary
+}
+
+func addEnumToSummary(summary map[string]string, enum protoreflect.EnumDescriptor) {
+	prefix := string(enum.FullName())
+	summary[prefix+" closed"] = strconv.FormatBool(enum.IsClosed())
+	summary[prefix+" json_format"] = resolveFeatureString(enum, "json_format")
+	summary[prefix+" options"] = getOptionsString(enum.Options())
+	summary[prefix+" reserved_ranges"] = getEnumRangesString(enum.ReservedRanges())
+	summary[prefix+" reserved_names"] = getNamesString(enum.ReservedNames())
+	for i := 0; i < enum.Values().Len(); i++ {
+		value := enum.Values().Get(i)
+		summary[string(value.FullName())+" number"] = strconv.Itoa(int(value.Number()))
+		summary[string(value.FullName())+" options"] = getOptionsString(value.Options())
+	}
+}
+
+func addFieldToSummary(summary map[string]string, field protoreflect.FieldDescriptor) {
+	prefix := string(field.FullName())
+	summary[prefix+" number"] = strconv.Itoa(int(field.Number()))
+	summary[prefix+" kind"] = field.Kind().String()
+	summary[prefix+" cardinality"] = field.Cardinality().String()
+	summary[prefix+" has_presence"] = strconv.FormatBool(field.HasPresence())
+	summary[prefix+" packed"] = strconv.FormatBool(field.IsPacked())
+	summary[prefix+" json_name"] = field.JSONName()
+	summary[prefix+" options"] = getOptionsString(field.Options(), "packed")
+	if field.HasDefault() {
+		summary[prefix+" default"] = fmt.Sprint(field.Default().Interface())
+	}
+	if message := field.Message(); message != nil {
+		summary[prefix+" type"] = string(message.FullName())
+	}
+	if enum := field.Enum(); enum != nil {
+		summary[prefix+" type"] = string(enum.FullName())
+	}
+	if oneof := field.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		summary[prefix+" oneof"] = string(oneof.FullName())
+	}
+	if field.IsExtension() {
+		summary[prefix+" extendee"] = string(field.ContainingMessage().FullName())
+	}
+	if field.Kind() == protoreflect.StringKind {
+		summary[prefix+" utf8_validation"] = resolveFeatureString(field, "utf8_validation")
+	}
+}
+
+// resolveFeatureString resolves the named feature for the descriptor and returns it as a string.
+//
+// For proto2 and proto3 files, this resolves to the defaults that match the semantics of the syntax.
+func resolveFeatureString(descriptor protoreflect.Descriptor, name protoreflect.Name) string {
+	featureField := (*descriptorpb.FeatureSet)(nil).ProtoReflect().Descriptor().Fields().ByName(name)
+	if featureField == nil {
+		return ""
+	}
+	value, err := protoutil.ResolveFeature(descriptor, featureField)
+	if err != nil {
+		return err.Error()
+	}
+	if enumValue := featureField.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+		return string(enumValue.Name())
+	}
+	return value.String()
+}
+
+// getOptionsString returns the options as text, excluding features and the given fields.
+//
+// Features and the excluded fields are compared as resolved semantics instead.
+func getOptionsString(options proto.Message, excludeFieldNames ...protoreflect.Name) string {
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return ""
+	}
+	options = proto.Clone(options)
+	optionsMessage := options.ProtoReflect()
+	for _, name := range append(excludeFieldNames, "features") {
+		if field := optionsMessage.Descriptor().Fields().ByName(name); field != nil {
+			optionsMessage.Clear(field)
+		}
+	}
+	data, err := prototext.MarshalOptions{}.Marshal(options)
+	if err != nil {
+		return err.Error()
+	}
+	// Custom options that could not be resolved are unknown fields, which are not
+	// printed as text.
+	if unknown := optionsMessage.GetUnknown(); len(unknown) > 0 {
+		return string(data) + " unknown:" + strconv.Quote(string(unknown))
+	}
+	return string(data)
+}
+
+func getFieldRangesString(fieldRanges protoreflect.FieldRanges) string {
+	rangeStrings := make([]string, fieldRanges.Len())
+	for i := 0; i < fieldRanges.Len(); i++ {
+		fieldRange := fieldRanges.Get(i)
+		// Field ranges are exclusive of the end.
+		rangeStrings[i] = getRangeString(int(fieldRange[0]), int(fieldRange[1])-1)
+	}
+	return strings.Join(rangeStrings, ",")
+}
+
+func getEnumRangesString(enumRanges protoreflect.EnumRanges) string {
+	rangeStrings := make([]string, enumRanges.Len())
+	for i := 0; i < enumRanges.Len(); i++ {
+		enumRange := enumRanges.Get(i)
+		// Enum ranges are inclusive of the end.
+		rangeStrings[i] = getRangeString(int(enumRange[0]), int(enumRange[1]))
+	}
+	return strings.Join(rangeStrings, ",")
+}
+
+func getRangeString(start int, end int) string {
+	if start == end {
+		return strconv.Itoa(start)
+	}
+	return strconv.Itoa(start) + "-" + strconv.Itoa(end)
+}
+
+func getNamesString(names protoreflect.Names) string {
+	nameStrings := make([]string, names.Len())
+	for i := 0; i < names.Len(); i++ {
+		nameStrings[i] = string(names.Get(i))
+	}
+	return strings.Join(nameStrings, ",")
+}
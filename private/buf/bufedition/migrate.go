@@ -0,0 +1,495 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufedition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/ast"
+	"github.com/bufbuild/protocompile/linker"
+	"github.com/bufbuild/protocompile/protoutil"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	featureFieldPresenceImplicit         = "features.field_presence = IMPLICIT"
+	featureFieldPresenceExplicit         = "features.field_presence = EXPLICIT"
+	featureFieldPresenceLegacyRequired   = "features.field_presence = LEGACY_REQUIRED"
+	featureEnumTypeClosed                = "features.enum_type = CLOSED"
+	featureRepeatedFieldEncodingPacked   = "features.repeated_field_encoding = PACKED"
+	featureRepeatedFieldEncodingExpanded = "features.repeated_field_encoding = EXPANDED"
+	featureUTF8ValidationNone            = "features.utf8_validation = NONE"
+	featureJSONFormatLegacyBestEffort    = "features.json_format = LEGACY_BEST_EFFORT"
+)
+
+var (
+	supportedEditionStrings         = []string{"2023"}
+	supportedEditionStringToEdition = map[string]descriptorpb.Edition{
+		"2023": descriptorpb.Edition_EDITION_2023,
+	}
+	supportedEditionToString = map[descriptorpb.Edition]string{
+		descriptorpb.Edition_EDITION_2023: "2023",
+	}
+)
+
+func migrateBucket(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	paths []string,
+	edition descriptorpb.Edition,
+) (storage.ReadBucket, error) {
+	editionString, ok := supportedEditionToString[edition]
+	if !ok {
+		return nil, fmt.Errorf("unsupported edition %v", edition)
+	}
+	originalFiles, err := compileFiles(ctx, bucket, paths)
+	if err != nil {
+		return nil, err
+	}
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	for i, path := range paths {
+		if err := migrateFileToBucket(ctx, bucket, readWriteBucket, path, originalFiles[i], editionString); err != nil {
+			return nil, err
+		}
+	}
+	migratedFiles, err := compileFiles(ctx, newOverlayReadBucket(readWriteBucket, bucket), paths)
+	if err != nil {
+		return nil, fmt.Errorf("migrated files failed to compile: %w", err)
+	}
+	var differences []string
+	for i := range paths {
+		differences = append(differences, getDifferences(originalFiles[i], migratedFiles[i])...)
+	}
+	if len(differences) > 0 {
+		return nil, fmt.Errorf("migrated files are not equivalent to the original files:\n  %s", strings.Join(differences, "\n  "))
+	}
+	return readWriteBucket, nil
+}
+
+func migrateFileToBucket(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	writeBucket storage.WriteBucket,
+	path string,
+	file linker.File,
+	editionString string,
+) (retErr error) {
+	readObjectCloser, err := bucket.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, readObjectCloser.Close())
+	}()
+	data, err := io.ReadAll(readObjectCloser)
+	if err != nil {
+		return err
+	}
+	result, ok := file.(linker.Result)
+	if !ok {
+		return syserror.Newf("expected a linker.Result for %q but got %T", path, file)
+	}
+	migratedData, err := newMigrator(result, data, editionString).migrate()
+	if err != nil {
+		return fmt.Errorf("%s: %w", readObjectCloser.ExternalPath(), err)
+	}
+	writeObjectCloser, err := writeBucket.Put(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, writeObjectCloser.Close())
+	}()
+	if _, err := writeObjectCloser.Write(migratedData); err != nil {
+		return err
+	}
+	return writeObjectCloser.SetExternalPath(readObjectCloser.ExternalPath())
+}
+
+// compileFiles compiles the given paths, retaining their ASTs.
+//
+// The returned files are in the same order as the paths.
+func compileFiles(ctx context.Context, bucket storage.ReadBucket, paths []string) (linker.Files, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(
+			&protocompile.SourceResolver{
+				Accessor: func(path string) (io.ReadCloser, error) {
+					return bucket.Get(ctx, path)
+				},
+			},
+		),
+		RetainASTs: true,
+	}
+	return compiler.Compile(ctx, paths...)
+}
+
+type migrator struct {
+	result        linker.Result
+	fileNode      *ast.FileNode
+	data          []byte
+	editionString string
+	edits         []*edit
+}
+
+func newMigrator(result linker.Result, data []byte, editionString string) *migrator {
+	return &migrator{
+		result:        result,
+		fileNode:      result.AST(),
+		data:          data,
+		editionString: editionString,
+	}
+}
+
+func (m *migrator) migrate() ([]byte, error) {
+	syntax := m.result.Syntax()
+	if syntax == protoreflect.Editions {
+		return m.data, nil
+	}
+	fields := getAllFields(m.result)
+	for _, field := range fields {
+		if field.Kind() == protoreflect.GroupKind {
+			return nil, fmt.Errorf("group %q cannot be migrated, replace it with a message field first", field.FullName())
+		}
+	}
+	if syntaxNode := m.fileNode.Syntax; syntaxNode != nil {
+		m.replaceNode(syntaxNode, fmt.Sprintf("edition = %q;", m.editionString))
+	} else {
+		// The syntax is unspecified, which means proto2.
+		offset := m.getFirstDeclOffset()
+		m.addEdit(offset, offset, fmt.Sprintf("edition = %q;\n\n", m.editionString))
+	}
+	switch syntax {
+	case protoreflect.Proto2:
+		m.addFileOptions(getProto2FileFeatures(m.result, fields))
+	case protoreflect.Proto3:
+		m.addFileOptions(getProto3FileFeatures(fields))
+	default:
+		return nil, syserror.Newf("unknown syntax: %v", syntax)
+	}
+	proto3Implicit := syntax == protoreflect.Proto3 && len(getProto3FileFeatures(fields)) > 0
+	for _, field := range fields {
+		if err := m.migrateField(field, syntax, proto3Implicit); err != nil {
+			return nil, err
+		}
+	}
+	return applyEdits(m.data, m.edits)
+}
+
+func (m *migrator) migrateField(
+	field protoreflect.FieldDescriptor,
+	syntax protoreflect.Syntax,
+	proto3Implicit bool,
+) error {
+	fieldDescriptorProtoWrapper, ok := field.(protoutil.DescriptorProtoWrapper)
+	if !ok {
+		return syserror.Newf("expected a protoutil.DescriptorProtoWrapper for %q but got %T", field.FullName(), field)
+	}
+	fieldDescriptorProto, ok := fieldDescriptorProtoWrapper.AsProto().(*descriptorpb.FieldDescriptorProto)
+	if !ok {
+		return syserror.Newf("expected a *descriptorpb.FieldDescriptorProto for %q", field.FullName())
+	}
+	var fieldDeclNode ast.FieldDeclNode
+	switch node := m.result.FieldNode(fieldDescriptorProto).(type) {
+	case *ast.FieldNode:
+		fieldDeclNode = node
+	case *ast.MapFieldNode:
+		fieldDeclNode = node
+	default:
+		// Synthetic fields, such as the fields of map entries, have nothing to migrate.
+		return nil
+	}
+	if labelNode := fieldDeclNode.FieldLabel(); labelNode != nil && field.Cardinality() != protoreflect.Repeated {
+		m.addEdit(m.startOffset(labelNode), m.startOffset(fieldDeclNode.FieldType()), "")
+	}
+	var addOptions []string
+	switch {
+	case syntax == protoreflect.Proto2 && field.Cardinality() == protoreflect.Required:
+		addOptions = append(addOptions, featureFieldPresenceLegacyRequired)
+	case proto3Implicit && field.HasOptionalKeyword() && field.Message() == nil && !field.IsExtension():
+		// Message fields and extensions always have explicit presence.
+		addOptions = append(addOptions, featureFieldPresenceExplicit)
+	}
+	var packedReplacement string
+	if fieldOptions := fieldDescriptorProto.GetOptions(); fieldOptions != nil && fieldOptions.Packed != nil {
+		switch packed := fieldOptions.GetPacked(); {
+		case syntax == protoreflect.Proto2 && packed:
+			packedReplacement = featureRepeatedFieldEncodingPacked
+		case syntax == protoreflect.Proto3 && !packed:
+			packedReplacement = featureRepeatedFieldEncodingExpanded
+		}
+		return m.rewriteFieldOptions(fieldDeclNode, packedReplacement, addOptions)
+	}
+	if len(addOptions) == 0 {
+		return nil
+	}
+	if compactOptionsNode := fieldDeclNode.GetOptions(); compactOptionsNode != nil {
+		lastOptionNode := compactOptionsNode.Options[len(compactOptionsNode.Options)-1]
+		m.addEdit(m.endOffset(lastOptionNode), m.endOffset(lastOptionNode), ", "+strings.Join(addOptions, ", "))
+		return nil
+	}
+	// The semicolon is the last token of the field.
+	semicolonOffset := m.endOffset(fieldDeclNode) - 1
+	m.addEdit(semicolonOffset, semicolonOffset, " ["+strings.Join(addOptions, ", ")+"]")
+	return nil
+}
+
+// rewriteFieldOptions rewrites the compact options of a field that has the packed option set.
+//
+// The packed option is replaced with packedReplacement if it is not empty, and removed otherwise.
+func (m *migrator) rewriteFieldOptions(fieldDeclNode ast.FieldDeclNode, packedReplacement string, addOptions []string) error {
+	compactOptionsNode := fieldDeclNode.GetOptions()
+	if compactOptionsNode == nil {
+		return syserror.New("packed option set without compact options")
+	}
+	var options []string
+	for _, optionNode := range compactOptionsNode.Options {
+		if isPackedOptionNode(optionNode) {
+			if packedReplacement != "" {
+				options = append(options, packedReplacement)
+			}
+			continue
+		}
+		options = append(options, string(m.data[m.startOffset(optionNode):m.endOffset(optionNode)]))
+	}
+	options = append(options, addOptions...)
+	if len(options) == 0 {
+		m.addEdit(m.endOffset(fieldDeclNode.FieldTag()), m.endOffset(compactOptionsNode), "")
+		return nil
+	}
+	m.replaceNode(compactOptionsNode, "["+strings.Join(options, ", ")+"]")
+	return nil
+}
+
+// addFileOptions adds the file options after the last package, import, or option declaration
+// that precedes any other declarations, or after the syntax declaration if there are none.
+//
+// If there is no syntax declaration either, the file options are added before the first declaration.
+func (m *migrator) addFileOptions(fileOptions []string) {
+	if len(fileOptions) == 0 {
+		return
+	}
+	var anchorNode ast.Node
+	anchorIsOption := false
+	if m.fileNode.Syntax != nil {
+		anchorNode = m.fileNode.Syntax
+	}
+DECLS:
+	for _, decl := range m.fileNode.Decls {
+		switch decl := decl.(type) {
+		case *ast.EmptyDeclNode:
+		case *ast.PackageNode, *ast.ImportNode:
+			anchorNode = decl
+			anchorIsOption = false
+		case *ast.OptionNode:
+			anchorNode = decl
+			anchorIsOption = true
+		default:
+			break DECLS
+		}
+	}
+	var text strings.Builder
+	for _, fileOption := range fileOptions {
+		text.WriteString("\noption " + fileOption + ";")
+	}
+	if anchorNode == nil {
+		offset := m.getFirstDeclOffset()
+		m.addEdit(offset, offset, strings.TrimPrefix(text.String(), "\n")+"\n\n")
+		return
+	}
+	optionsText := text.String()
+	if !anchorIsOption {
+		optionsText = "\n" + optionsText
+	}
+	offset := m.endOffset(anchorNode)
+	m.addEdit(offset, offset, optionsText)
+}
+
+// getFirstDeclOffset returns the offset of the first declaration in the file, or the
+// end of the file if there are no declarations.
+func (m *migrator) getFirstDeclOffset() int {
+	if len(m.fileNode.Decls) > 0 {
+		return m.startOffset(m.fileNode.Decls[0])
+	}
+	return len(m.data)
+}
+
+func (m *migrator) replaceNode(node ast.Node, text string) {
+	m.addEdit(m.startOffset(node), m.endOffset(node), text)
+}
+
+func (m *migrator) addEdit(start int, end int, text string) {
+	m.edits = append(m.edits, &edit{start: start, end: end, text: text})
+}
+
+func (m *migrator) startOffset(node ast.Node) int {
+	return m.fileNode.NodeInfo(node).Start().Offset
+}
+
+// endOffset returns the offset after the last character of the node.
+func (m *migrator) endOffset(node ast.Node) int {
+	// NodeInfo.End returns the position of the last character, with the column
+	// adjusted to be exclusive, but the offset is not adjusted.
+	return m.fileNode.NodeInfo(node).End().Offset + 1
+}
+
+type edit struct {
+	start int
+	end   int
+	text  string
+}
+
+func applyEdits(data []byte, edits []*edit) ([]byte, error) {
+	sort.SliceStable(
+		edits,
+		func(i int, j int) bool {
+			return edits[i].start < edits[j].start
+		},
+	)
+	var builder strings.Builder
+	offset := 0
+	for _, edit := range edits {
+		if edit.start < offset || edit.end < edit.start || edit.end > len(data) {
+			return nil, syserror.Newf("invalid edit [%d:%d] at offset %d", edit.start, edit.end, offset)
+		}
+		builder.Write(data[offset:edit.start])
+		builder.WriteString(edit.text)
+		offset = edit.end
+	}
+	builder.Write(data[offset:])
+	return []byte(builder.String()), nil
+}
+
+// getProto2FileFeatures returns the file features needed to keep the semantics of a
+// proto2 file that has the given fields.
+func getProto2FileFeatures(file protoreflect.FileDescriptor, fields []protoreflect.FieldDescriptor) []string {
+	var fileFeatures []string
+	if hasEnums(file) {
+		fileFeatures = append(fileFeatures, featureEnumTypeClosed)
+	}
+	for _, field := range fields {
+		if field.Cardinality() == protoreflect.Repeated && isPackableKind(field.Kind()) && !field.IsMap() {
+			fileFeatures = append(fileFeatures, featureRepeatedFieldEncodingExpanded)
+			break
+		}
+	}
+	for _, field := range fields {
+		if field.Kind() == protoreflect.StringKind {
+			fileFeatures = append(fileFeatures, featureUTF8ValidationNone)
+			break
+		}
+	}
+	if file.Messages().Len() > 0 || file.Enums().Len() > 0 {
+		fileFeatures = append(fileFeatures, featureJSONFormatLegacyBestEffort)
+	}
+	return fileFeatures
+}
+
+// getProto3FileFeatures returns the file features needed to keep the semantics of a
+// proto3 file that has the given fields.
+func getProto3FileFeatures(fields []protoreflect.FieldDescriptor) []string {
+	for _, field := range fields {
+		if !field.HasPresence() && field.Cardinality() != protoreflect.Repeated {
+			return []string{featureFieldPresenceImplicit}
+		}
+	}
+	return nil
+}
+
+func isPackedOptionNode(optionNode *ast.OptionNode) bool {
+	parts := optionNode.Name.Parts
+	return len(parts) == 1 && !parts[0].IsExtension() && string(parts[0].Name.AsIdentifier()) == "packed"
+}
+
+func isPackableKind(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind, protoreflect.GroupKind:
+		return false
+	default:
+		return true
+	}
+}
+
+func hasEnums(file protoreflect.FileDescriptor) bool {
+	if file.Enums().Len() > 0 {
+		return true
+	}
+	found := false
+	forEachMessage(file.Messages(), func(message protoreflect.MessageDescriptor) {
+		if message.Enums().Len() > 0 {
+			found = true
+		}
+	})
+	return found
+}
+
+// getAllFields returns all fields and extensions declared in the file, including those of map entries.
+func getAllFields(file protoreflect.FileDescriptor) []protoreflect.FieldDescriptor {
+	var fields []protoreflect.FieldDescriptor
+	for i := 0; i < file.Extensions().Len(); i++ {
+		fields = append(fields, file.Extensions().Get(i))
+	}
+	forEachMessage(file.Messages(), func(message protoreflect.MessageDescriptor) {
+		for i := 0; i < message.Fields().Len(); i++ {
+			fields = append(fields, message.Fields().Get(i))
+		}
+		for i := 0; i < message.Extensions().Len(); i++ {
+			fields = append(fields, message.Extensions().Get(i))
+		}
+	})
+	return fields
+}
+
+func forEachMessage(messages protoreflect.MessageDescriptors, f func(protoreflect.MessageDescriptor)) {
+	for i := 0; i < messages.Len(); i++ {
+		message := messages.Get(i)
+		f(message)
+		forEachMessage(message.Messages(), f)
+	}
+}
+
+// overlayReadBucket reads from the overlay bucket first, and falls back to the base bucket.
+type overlayReadBucket struct {
+	storage.ReadBucket
+
+	overlay storage.ReadBucket
+}
+
+func newOverlayReadBucket(overlay storage.ReadBucket, base storage.ReadBucket) *overlayReadBucket {
+	return &overlayReadBucket{
+		ReadBucket: base,
+		overlay:    overlay,
+	}
+}
+
+func (o *overlayReadBucket) Get(ctx context.Context, path string) (storage.ReadObjectCloser, error) {
+	readObjectCloser, err := o.overlay.Get(ctx, path)
+	if err == nil {
+		return readObjectCloser, nil
+	}
+	if !storage.IsNotExist(err) {
+		return nil, err
+	}
+	return o.ReadBucket.Get(ctx, path)
+}
@@ -15,10 +15,13 @@
 package bufformat
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
@@ -26,6 +29,7 @@ import (
 	"github.com/bufbuild/protocompile/ast"
 	"github.com/bufbuild/protocompile/parser"
 	"github.com/bufbuild/protocompile/reporter"
+	"github.com/jhump/protoreflect/v2/protoprint"
 )
 
 // FormatModuleSet formats and writes the target files into a read bucket.
@@ -81,6 +85,30 @@ func FormatBucket(ctx context.Context, bucket storage.ReadBucket) (_ storage.Rea
 	return readWriteBucket, nil
 }
 
+// FormatImage reconstructs the .proto files of the image from their descriptors, and returns a
+// new bucket with the formatted files.
+//
+// Comments are reconstructed from the source code info of the files, if present. Building
+// the reconstructed files results in descriptors equivalent to those of the image.
+func FormatImage(ctx context.Context, image bufimage.Image) (storage.ReadBucket, error) {
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	printer := &protoprint.Printer{}
+	for _, imageFile := range image.Files() {
+		fileDescriptor, err := image.Resolver().FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		buffer := bytes.NewBuffer(nil)
+		if err := printer.PrintProtoFile(fileDescriptor, buffer); err != nil {
+			return nil, fmt.Errorf("could not reconstruct %s: %w", imageFile.Path(), err)
+		}
+		if err := storage.PutPath(ctx, readWriteBucket, imageFile.Path(), buffer.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	return FormatBucket(ctx, readWriteBucket)
+}
+
 // FormatFileNode formats the given file node and writ the result to dest.
 func FormatFileNode(dest io.Writer, fileNode *ast.FileNode) error {
 	formatter := newFormatter(dest, fileNode)
@@ -0,0 +1,170 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufformat
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestFormatImage(t *testing.T) {
+	t.Parallel()
+	testFormatImageRoundTripForFile(t, "testdata/customoptions/options.golden.proto")
+	testFormatImageRoundTripForFile(t, "testdata/proto2/message/v1/message_group.golden.proto")
+	testFormatImageRoundTripForFile(t, "testdata/proto2/message/v1/message_extensions.golden.proto")
+	testFormatImageRoundTrip(
+		t,
+		"editions",
+		map[string][]byte{
+			"a/features.proto": []byte(`edition = "2023";
+package a;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.FeatureSet {
+  Features a = 9995;
+}
+message Features {
+  bool flag = 1 [
+    targets = TARGET_TYPE_FILE,
+    targets = TARGET_TYPE_FIELD,
+    feature_support = { edition_introduced: EDITION_2023 },
+    edition_defaults = { edition: EDITION_LEGACY, value: "false" }
+  ];
+}
+`),
+			"a/a.proto": []byte(`edition = "2023";
+package a;
+import "a/features.proto";
+option features.field_presence = IMPLICIT;
+option features.(a).flag = true;
+// Foo is a message.
+message Foo {
+  string bar = 1 [features.field_presence = EXPLICIT];
+  repeated int32 baz = 2 [features.repeated_field_encoding = EXPANDED];
+  Foo child = 3 [features.message_encoding = DELIMITED];
+}
+enum Status {
+  option features.enum_type = CLOSED;
+  STATUS_UNSPECIFIED = 0;
+}
+`),
+		},
+	)
+}
+
+func TestFormatImageComments(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	image := testBuildImage(
+		t,
+		testNewReadBucket(
+			t,
+			map[string][]byte{
+				"a.proto": []byte(`syntax = "proto3";
+package a;
+// Foo is a message.
+message Foo {
+  string bar = 1; // The bar.
+}
+`),
+			},
+		),
+	)
+	readBucket, err := FormatImage(ctx, image)
+	require.NoError(t, err)
+	data, err := storage.ReadPath(ctx, readBucket, "a.proto")
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`syntax = "proto3";
+
+package a;
+
+// Foo is a message.
+message Foo {
+  string bar = 1; // The bar.
+}
+`,
+		string(data),
+	)
+}
+
+func testFormatImageRoundTripForFile(t *testing.T, path string) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	testFormatImageRoundTrip(t, path, map[string][]byte{normalpath.Base(path): data})
+}
+
+// testFormatImageRoundTrip checks that building the files reconstructed from an image
+// results in the same descriptors, other than source code info.
+func testFormatImageRoundTrip(t *testing.T, name string, pathToData map[string][]byte) {
+	t.Run(name, func(t *testing.T) {
+		t.Parallel()
+		ctx := context.Background()
+		image := testBuildImage(t, testNewReadBucket(t, pathToData))
+		readBucket, err := FormatImage(ctx, bufimage.ImageWithoutImports(image))
+		require.NoError(t, err)
+		reconstructedImage := testBuildImage(t, readBucket)
+		require.Empty(
+			t,
+			cmp.Diff(
+				testGetFileDescriptorProtos(bufimage.ImageWithoutImports(image)),
+				testGetFileDescriptorProtos(bufimage.ImageWithoutImports(reconstructedImage)),
+				protocmp.Transform(),
+			),
+		)
+	})
+}
+
+func testBuildImage(t *testing.T, bucket storage.ReadBucket) bufimage.Image {
+	moduleSet, err := bufmoduletesting.NewModuleSetForBucket(bucket)
+	require.NoError(t, err)
+	image, err := bufimage.BuildImage(
+		context.Background(),
+		slogtestext.NewLogger(t),
+		bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(moduleSet),
+	)
+	require.NoError(t, err)
+	return image
+}
+
+func testNewReadBucket(t *testing.T, pathToData map[string][]byte) storage.ReadBucket {
+	readBucket, err := storagemem.NewReadBucket(pathToData)
+	require.NoError(t, err)
+	return readBucket
+}
+
+func testGetFileDescriptorProtos(image bufimage.Image) []*descriptorpb.FileDescriptorProto {
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	for _, imageFile := range image.Files() {
+		fileDescriptorProto := proto.Clone(imageFile.FileDescriptorProto()).(*descriptorpb.FileDescriptorProto)
+		fileDescriptorProto.SourceCodeInfo = nil
+		fileDescriptorProtos = append(fileDescriptorProtos, fileDescriptorProto)
+	}
+	return fileDescriptorProtos
+}
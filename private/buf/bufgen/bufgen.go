@@ -20,8 +20,10 @@ package bufgen
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"strconv"
+	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
@@ -112,8 +114,27 @@ func GenerateWithBaseOutDirPath(baseOutDirPath string) GenerateOption {
 	}
 }
 
+// GenerateWithCheck returns a new GenerateOption that results in the generated
+// files being compared with the files that exist on disk, instead of being written.
+// A unified diff of every file that would be changed, added, or removed is written
+// to the given Writer, and nothing is written if the output is up to date.
+//
+// Nothing on disk is modified, including files that would be deleted by
+// GenerateWithDeleteOuts. Instead, if outputs would be deleted, files that would be
+// deleted are reported as removed.
+func GenerateWithCheck(writer io.Writer) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.checkWriter = writer
+	}
+}
+
 // GenerateWithDeleteOuts returns a new GenerateOption that results in the
-// output directories, zip files, or jar files being deleted before generation is run.
+// zip files or jar files being deleted before generation is run, and the stale
+// files in the output directories being deleted after generation is run.
+//
+// Output directories are never deleted as a whole. Only the files in the manifest of
+// an output directory that were previously generated and are no longer generated are
+// deleted. Files that were modified after they were generated are never deleted this way.
 func GenerateWithDeleteOuts(deleteOuts bool) GenerateOption {
 	return func(generateOptions *generateOptions) {
 		generateOptions.deleteOuts = &deleteOuts
@@ -147,3 +168,24 @@ func GenerateWithIncludeWellKnownTypesOverride(includeWellKnownTypes bool) Gener
 		generateOptions.includeWellKnownTypesOverride = &includeWellKnownTypes
 	}
 }
+
+// GenerateWithPluginTimeout returns a new GenerateOption that limits the duration
+// of the execution of each plugin that does not have its own timeout.
+//
+// The default is to have no timeout.
+func GenerateWithPluginTimeout(pluginTimeout time.Duration) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.pluginTimeout = pluginTimeout
+	}
+}
+
+// GenerateWithMaxParallelism returns a new GenerateOption that limits the number
+// of plugins that are executed at once. The number of invocations of each plugin
+// that are executed at once is limited separately by the MaxParallelism of the plugin.
+//
+// The default is to use thread.Parallelism(). A value of <1 has no meaning.
+func GenerateWithMaxParallelism(maxParallelism int) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.maxParallelism = maxParallelism
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	connect "connectrpc.com/connect"
+)
+
+// remoteRetryInitialDelay is the delay before the first retry of a remote
+// plugin. The delay doubles after every retry.
+const remoteRetryInitialDelay = time.Second
+
+// execWithTimeout calls f with a context that expires after the timeout.
+//
+// If the timeout is zero, f is called with the given context. If f fails after
+// the timeout expires, an error naming the plugins that timed out is returned.
+func execWithTimeout(
+	ctx context.Context,
+	pluginNames []string,
+	timeout time.Duration,
+	f func(context.Context) error,
+) error {
+	if timeout == 0 {
+		return f(ctx)
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := f(timeoutCtx); err != nil {
+		// Only report a timeout if the parent context is still valid, otherwise
+		// the timeout is not the cause of the failure.
+		if ctx.Err() == nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("%s timed out after %v", getPluginsDescription(pluginNames), timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// execWithRetries calls f, and calls f again up to the given number of retries
+// if f fails with a transient error. The delay between calls starts at the
+// initial delay, and doubles after every retry.
+func execWithRetries(
+	ctx context.Context,
+	logger *slog.Logger,
+	pluginNames []string,
+	retries int,
+	initialDelay time.Duration,
+	f func(context.Context) error,
+) error {
+	delay := initialDelay
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil || attempt > retries || !isTransientError(err) {
+			return err
+		}
+		logger.Warn(
+			fmt.Sprintf(
+				"%s failed with a transient error, retrying in %v (%d/%d): %v",
+				getPluginsDescription(pluginNames),
+				delay,
+				attempt,
+				retries,
+				err,
+			),
+		)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// isTransientError returns true if the error is a connect error that may not occur
+// if the request is retried.
+func isTransientError(err error) bool {
+	switch connect.CodeOf(err) {
+	case connect.CodeUnavailable, connect.CodeResourceExhausted, connect.CodeAborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func getPluginsDescription(pluginNames []string) string {
+	if len(pluginNames) == 1 {
+		return "plugin " + pluginNames[0]
+	}
+	return "plugins " + strings.Join(pluginNames, ", ")
+}
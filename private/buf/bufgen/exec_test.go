@@ -0,0 +1,107 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	connect "connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecWithTimeout(t *testing.T) {
+	t.Parallel()
+	err := execWithTimeout(
+		context.Background(),
+		[]string{"buf.build/acme/a", "buf.build/acme/b"},
+		10*time.Millisecond,
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	)
+	require.EqualError(t, err, "plugins buf.build/acme/a, buf.build/acme/b timed out after 10ms")
+	// Errors that are not caused by the timeout are returned as is.
+	expectedErr := errors.New("failure")
+	err = execWithTimeout(
+		context.Background(),
+		[]string{"protoc-gen-a"},
+		time.Minute,
+		func(context.Context) error {
+			return expectedErr
+		},
+	)
+	require.ErrorIs(t, err, expectedErr)
+	// Cancellation of the parent context is not reported as a timeout.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = execWithTimeout(
+		ctx,
+		[]string{"protoc-gen-a"},
+		time.Minute,
+		func(ctx context.Context) error {
+			return ctx.Err()
+		},
+	)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExecWithRetries(t *testing.T) {
+	t.Parallel()
+	logger := slogtestext.NewLogger(t)
+	testExecWithRetries := func(
+		t *testing.T,
+		retries int,
+		errs []error,
+		expectedCalls int,
+	) error {
+		t.Helper()
+		var calls int
+		err := execWithRetries(
+			context.Background(),
+			logger,
+			[]string{"buf.build/acme/a"},
+			retries,
+			time.Millisecond,
+			func(context.Context) error {
+				calls++
+				if calls > len(errs) {
+					return nil
+				}
+				return errs[calls-1]
+			},
+		)
+		require.Equal(t, expectedCalls, calls)
+		return err
+	}
+	unavailableErr := connect.NewError(connect.CodeUnavailable, errors.New("unavailable"))
+	invalidArgumentErr := connect.NewError(connect.CodeInvalidArgument, errors.New("invalid"))
+	// Succeeds after retrying transient errors.
+	err := testExecWithRetries(t, 2, []error{unavailableErr, unavailableErr}, 3)
+	require.NoError(t, err)
+	// Fails once the retries are exhausted.
+	err = testExecWithRetries(t, 1, []error{unavailableErr, unavailableErr}, 2)
+	require.ErrorIs(t, err, unavailableErr)
+	// Errors that are not transient are not retried.
+	err = testExecWithRetries(t, 2, []error{invalidArgumentErr}, 1)
+	require.ErrorIs(t, err, invalidArgumentErr)
+	// No retries by default.
+	err = testExecWithRetries(t, 0, []error{unavailableErr}, 1)
+	require.ErrorIs(t, err, unavailableErr)
+}
@@ -18,14 +18,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"time"
 
 	connect "connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufprotopluginexec"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagemodify"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
 	"github.com/bufbuild/buf/private/bufpkg/bufprotoplugin"
 	"github.com/bufbuild/buf/private/bufpkg/bufprotoplugin/bufprotopluginos"
 	"github.com/bufbuild/buf/private/bufpkg/bufremoteplugin"
@@ -34,6 +39,7 @@ import (
 	registryv1alpha1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/registry/v1alpha1"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/thread"
@@ -77,6 +83,19 @@ func newGenerator(
 //
 // This behavior is equivalent to protoc, which only writes out the content
 // for each of the plugins if all of the plugins are successful.
+//
+// If GenerateWithCheck is set, the output directories are never deleted, and
+// the bufprotopluginos.ResponseWriter compares the generated content with the
+// content on disk instead of writing it. A single ResponseWriter is used for all
+// images in this case, so that files generated for one image are not reported
+// as removed when checking the output of another.
+//
+// Every output directory gets a manifest of the files generated to it, and the
+// manifests are compared with the manifests that would be written when checking.
+// Output directories are never deleted as a whole. If the outputs are to be
+// deleted, only the files in the previous manifest that are no longer generated
+// are deleted once all images have been generated, and a warning is logged for
+// the output directories that do not have a manifest yet.
 func (g *generator) Generate(
 	ctx context.Context,
 	container app.EnvStdioContainer,
@@ -102,9 +121,53 @@ func (g *generator) Generate(
 	if generateOptions.deleteOuts != nil {
 		shouldDeleteOuts = *generateOptions.deleteOuts
 	}
+	manifests, err := bufprotopluginos.NewManifests(
+		ctx,
+		g.logger,
+		g.storageosProvider,
+		getPluginOuts(generateOptions.baseOutDirPath, config.GeneratePluginConfigs()),
+	)
+	if err != nil {
+		return err
+	}
+	if generateOptions.checkWriter != nil {
+		responseWriterOptions := []bufprotopluginos.ResponseWriterOption{
+			bufprotopluginos.ResponseWriterWithCheck(generateOptions.checkWriter),
+			bufprotopluginos.ResponseWriterWithManifests(manifests),
+		}
+		if shouldDeleteOuts {
+			responseWriterOptions = append(responseWriterOptions, bufprotopluginos.ResponseWriterWithCheckRemovedFiles())
+		}
+		responseWriter := bufprotopluginos.NewResponseWriter(
+			g.logger,
+			g.storageosProvider,
+			responseWriterOptions...,
+		)
+		for _, image := range images {
+			if err := g.generateCode(
+				ctx,
+				container,
+				responseWriter,
+				image,
+				generateOptions.baseOutDirPath,
+				config.GeneratePluginConfigs(),
+				generateOptions.includeImportsOverride,
+				generateOptions.includeWellKnownTypesOverride,
+				generateOptions.pluginTimeout,
+				generateOptions.maxParallelism,
+			); err != nil {
+				return err
+			}
+		}
+		if err := responseWriter.Close(); err != nil {
+			return err
+		}
+		return manifests.CheckManifests(ctx, generateOptions.checkWriter, shouldDeleteOuts)
+	}
 	if shouldDeleteOuts {
 		if err := g.deleteOuts(
 			ctx,
+			manifests,
 			generateOptions.baseOutDirPath,
 			config.GeneratePluginConfigs(),
 		); err != nil {
@@ -112,49 +175,86 @@ func (g *generator) Generate(
 		}
 	}
 	for _, image := range images {
+		responseWriter := bufprotopluginos.NewResponseWriter(
+			g.logger,
+			g.storageosProvider,
+			bufprotopluginos.ResponseWriterWithCreateOutDirIfNotExists(),
+			bufprotopluginos.ResponseWriterWithManifests(manifests),
+		)
 		if err := g.generateCode(
 			ctx,
 			container,
+			responseWriter,
 			image,
 			generateOptions.baseOutDirPath,
 			config.GeneratePluginConfigs(),
 			generateOptions.includeImportsOverride,
 			generateOptions.includeWellKnownTypesOverride,
+			generateOptions.pluginTimeout,
+			generateOptions.maxParallelism,
 		); err != nil {
 			return err
 		}
+		if err := responseWriter.Close(); err != nil {
+			return err
+		}
 	}
-	return nil
+	// Stale files in output directories were not deleted by deleteOuts,
+	// and are deleted here instead.
+	return manifests.WriteManifests(ctx, shouldDeleteOuts)
 }
 
+// deleteOuts deletes the zip and jar files that the plugins write to, as they are
+// always replaced as a whole. Output directories are not deleted, as they may contain
+// files that were not generated. Instead, their stale files are deleted after generation
+// using their manifest. If an existing output directory has no manifest, a warning is
+// logged, as none of its files can be deleted until a manifest has been written to it.
 func (g *generator) deleteOuts(
 	ctx context.Context,
+	manifests bufprotopluginos.Manifests,
 	baseOutDir string,
 	pluginConfigs []bufconfig.GeneratePluginConfig,
 ) error {
-	return bufprotopluginos.NewCleaner(g.storageosProvider).DeleteOuts(
-		ctx,
-		slicesext.Map(
-			pluginConfigs,
-			func(pluginConfig bufconfig.GeneratePluginConfig) string {
-				out := pluginConfig.Out()
-				if baseOutDir != "" && baseOutDir != "." {
-					return filepath.Join(baseOutDir, out)
-				}
-				return out
-			},
-		),
-	)
+	var archivePluginOuts []string
+	for _, pluginOut := range slicesext.ToUniqueSorted(getPluginOuts(baseOutDir, pluginConfigs)) {
+		switch filepath.Ext(pluginOut) {
+		case ".jar", ".zip":
+			archivePluginOuts = append(archivePluginOuts, pluginOut)
+			continue
+		}
+		if manifests.HasManifest(pluginOut) {
+			continue
+		}
+		// OK to use os.Stat instead of os.Lstat here.
+		if _, err := os.Stat(normalpath.Unnormalize(pluginOut)); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		g.logger.Warn(
+			fmt.Sprintf(
+				"%s has no generation manifest, so no files were deleted from it; a manifest has been written, and stale files will be deleted on the next clean",
+				normalpath.Unnormalize(pluginOut),
+			),
+		)
+	}
+	return bufprotopluginos.NewCleaner(g.storageosProvider).DeleteOuts(ctx, archivePluginOuts)
 }
 
+// generateCode executes the plugins for the image and adds the responses to the
+// responseWriter. The caller is responsible for closing the responseWriter.
 func (g *generator) generateCode(
 	ctx context.Context,
 	container app.EnvStdioContainer,
+	responseWriter bufprotopluginos.ResponseWriter,
 	inputImage bufimage.Image,
 	baseOutDir string,
 	pluginConfigs []bufconfig.GeneratePluginConfig,
 	includeImportsOverride *bool,
 	includeWellKnownTypesOverride *bool,
+	pluginTimeout time.Duration,
+	maxParallelism int,
 ) error {
 	responses, err := g.execPlugins(
 		ctx,
@@ -163,16 +263,13 @@ func (g *generator) generateCode(
 		inputImage,
 		includeImportsOverride,
 		includeWellKnownTypesOverride,
+		pluginTimeout,
+		maxParallelism,
 	)
 	if err != nil {
 		return err
 	}
 	// Apply the CodeGeneratorResponses in the order they were specified.
-	responseWriter := bufprotopluginos.NewResponseWriter(
-		g.logger,
-		g.storageosProvider,
-		bufprotopluginos.ResponseWriterWithCreateOutDirIfNotExists(),
-	)
 	for i, pluginConfig := range pluginConfigs {
 		out := pluginConfig.Out()
 		if baseOutDir != "" && baseOutDir != "." {
@@ -182,6 +279,18 @@ func (g *generator) generateCode(
 		if response == nil {
 			return fmt.Errorf("failed to get plugin response for %s", pluginConfig.Name())
 		}
+		// Post-processing is applied before the response is added, so that checks
+		// and manifests see the post-processed files.
+		response, err = postProcessResponse(
+			ctx,
+			container,
+			g.storageosProvider,
+			response,
+			pluginConfig.PostProcess(),
+		)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %w", pluginConfig.Name(), err)
+		}
 		if err := responseWriter.AddResponse(
 			ctx,
 			response,
@@ -190,9 +299,6 @@ func (g *generator) generateCode(
 			return fmt.Errorf("plugin %s: %v", pluginConfig.Name(), err)
 		}
 	}
-	if err := responseWriter.Close(); err != nil {
-		return err
-	}
 	return nil
 }
 
@@ -203,6 +309,8 @@ func (g *generator) execPlugins(
 	image bufimage.Image,
 	includeImportsOverride *bool,
 	includeWellKnownTypesOverride *bool,
+	pluginTimeout time.Duration,
+	maxParallelism int,
 ) ([]*pluginpb.CodeGeneratorResponse, error) {
 	imageProvider := newImageProvider(image)
 	// Collect all of the plugin jobs so that they can be executed in parallel.
@@ -213,41 +321,76 @@ func (g *generator) execPlugins(
 	for i, pluginConfig := range pluginConfigs {
 		index := i
 		currentPluginConfig := pluginConfig
+		// Plugins that filter the types or paths that they generate for are
+		// given their own image, and are never batched with other plugins.
+		pluginImage := image
+		pluginImageProvider := imageProvider
+		if hasPluginImageFilters(currentPluginConfig) {
+			var err error
+			pluginImage, err = getFilteredPluginImage(image, currentPluginConfig)
+			if err != nil {
+				return nil, err
+			}
+			pluginImageProvider = newImageProvider(pluginImage)
+		}
 		// We're using this as a proxy for Type() == PluginConfigTypeRemote.
 		//
 		// We should be using the enum here.
 		remote := currentPluginConfig.RemoteHost()
 		if remote != "" {
-			remotePluginConfigTable[remote] = append(
-				remotePluginConfigTable[remote],
-				&remotePluginExecArgs{
-					Index:        index,
-					PluginConfig: currentPluginConfig,
-				},
-			)
-		} else {
+			indexedPluginConfig := &remotePluginExecArgs{
+				Index:        index,
+				PluginConfig: currentPluginConfig,
+			}
+			// Plugins with their own timeout are also never batched, as the
+			// timeout applies to the execution of the batch.
+			if !hasPluginImageFilters(currentPluginConfig) && currentPluginConfig.Timeout() == 0 {
+				remotePluginConfigTable[remote] = append(remotePluginConfigTable[remote], indexedPluginConfig)
+				continue
+			}
 			jobs = append(jobs, func(ctx context.Context) error {
-				includeImports := currentPluginConfig.IncludeImports()
-				if includeImportsOverride != nil {
-					includeImports = *includeImportsOverride
-				}
-				includeWellKnownTypes := currentPluginConfig.IncludeWKT()
-				if includeWellKnownTypesOverride != nil {
-					includeWellKnownTypes = *includeWellKnownTypesOverride
-				}
-				response, err := g.execLocalPlugin(
+				return g.execRemotePluginsV2IntoResponses(
 					ctx,
 					container,
-					imageProvider,
-					currentPluginConfig,
-					includeImports,
-					includeWellKnownTypes,
+					pluginImage,
+					remote,
+					[]*remotePluginExecArgs{indexedPluginConfig},
+					includeImportsOverride,
+					includeWellKnownTypesOverride,
+					getPluginTimeout(currentPluginConfig, pluginTimeout),
+					responses,
+				)
+			})
+		} else {
+			jobs = append(jobs, func(ctx context.Context) error {
+				return execWithTimeout(
+					ctx,
+					[]string{currentPluginConfig.Name()},
+					getPluginTimeout(currentPluginConfig, pluginTimeout),
+					func(ctx context.Context) error {
+						includeImports := currentPluginConfig.IncludeImports()
+						if includeImportsOverride != nil {
+							includeImports = *includeImportsOverride
+						}
+						includeWellKnownTypes := currentPluginConfig.IncludeWKT()
+						if includeWellKnownTypesOverride != nil {
+							includeWellKnownTypes = *includeWellKnownTypesOverride
+						}
+						response, err := g.execLocalPlugin(
+							ctx,
+							container,
+							pluginImageProvider,
+							currentPluginConfig,
+							includeImports,
+							includeWellKnownTypes,
+						)
+						if err != nil {
+							return err
+						}
+						responses[index] = response
+						return nil
+					},
 				)
-				if err != nil {
-					return err
-				}
-				responses[index] = response
-				return nil
 			})
 		}
 	}
@@ -257,7 +400,7 @@ func (g *generator) execPlugins(
 		indexedPluginConfigs := indexedPluginConfigs
 		if len(indexedPluginConfigs) > 0 {
 			jobs = append(jobs, func(ctx context.Context) error {
-				results, err := g.execRemotePluginsV2(
+				return g.execRemotePluginsV2IntoResponses(
 					ctx,
 					container,
 					image,
@@ -265,14 +408,9 @@ func (g *generator) execPlugins(
 					indexedPluginConfigs,
 					includeImportsOverride,
 					includeWellKnownTypesOverride,
+					pluginTimeout,
+					responses,
 				)
-				if err != nil {
-					return err
-				}
-				for _, result := range results {
-					responses[result.Index] = result.CodeGeneratorResponse
-				}
-				return nil
 			})
 		}
 	}
@@ -292,6 +430,7 @@ func (g *generator) execPlugins(
 		ctx,
 		jobs,
 		thread.ParallelizeWithCancelOnFailure(),
+		thread.ParallelizeWithParallelism(maxParallelism),
 	); err != nil {
 		return nil, err
 	}
@@ -333,6 +472,7 @@ func (g *generator) execLocalPlugin(
 		requests,
 		bufprotopluginexec.GenerateWithPluginPath(pluginConfig.Path()...),
 		bufprotopluginexec.GenerateWithProtocPath(pluginConfig.ProtocPath()...),
+		bufprotopluginexec.GenerateWithMaxParallelism(pluginConfig.MaxParallelism()),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("plugin %s: %v", pluginConfig.Name(), err)
@@ -350,6 +490,68 @@ type remotePluginExecutionResult struct {
 	Index                 int
 }
 
+// execRemotePluginsV2IntoResponses executes the remote plugins and sets the
+// responses at the indexes of the plugins.
+//
+// The timeout applies to the execution of all of the plugins, including retries.
+// The plugins are retried up to the maximum number of retries of the plugins.
+func (g *generator) execRemotePluginsV2IntoResponses(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	image bufimage.Image,
+	remote string,
+	pluginConfigs []*remotePluginExecArgs,
+	includeImportsOverride *bool,
+	includeWellKnownTypesOverride *bool,
+	timeout time.Duration,
+	responses []*pluginpb.CodeGeneratorResponse,
+) error {
+	pluginNames := slicesext.Map(
+		pluginConfigs,
+		func(pluginConfig *remotePluginExecArgs) string {
+			return pluginConfig.PluginConfig.Name()
+		},
+	)
+	var retries int
+	for _, pluginConfig := range pluginConfigs {
+		retries = max(retries, pluginConfig.PluginConfig.Retries())
+	}
+	var results []*remotePluginExecutionResult
+	if err := execWithTimeout(
+		ctx,
+		pluginNames,
+		timeout,
+		func(ctx context.Context) error {
+			return execWithRetries(
+				ctx,
+				g.logger,
+				pluginNames,
+				retries,
+				remoteRetryInitialDelay,
+				func(ctx context.Context) error {
+					var err error
+					results, err = g.execRemotePluginsV2(
+						ctx,
+						container,
+						image,
+						remote,
+						pluginConfigs,
+						includeImportsOverride,
+						includeWellKnownTypesOverride,
+					)
+					return err
+				},
+			)
+		},
+	); err != nil {
+		return err
+	}
+	for _, result := range results {
+		responses[result.Index] = result.CodeGeneratorResponse
+	}
+	return nil
+}
+
 func (g *generator) execRemotePluginsV2(
 	ctx context.Context,
 	container app.EnvStdioContainer,
@@ -443,6 +645,49 @@ func getPluginGenerationRequest(
 	}, nil
 }
 
+// hasPluginImageFilters returns true if the plugin filters the types or paths
+// that it generates for.
+func hasPluginImageFilters(pluginConfig bufconfig.GeneratePluginConfig) bool {
+	return len(pluginConfig.Types()) > 0 ||
+		len(pluginConfig.ExcludeTypes()) > 0 ||
+		len(pluginConfig.Paths()) > 0 ||
+		len(pluginConfig.ExcludePaths()) > 0
+}
+
+// getFilteredPluginImage returns a copy of the image that only contains the
+// files and types that the plugin generates for.
+func getFilteredPluginImage(
+	image bufimage.Image,
+	pluginConfig bufconfig.GeneratePluginConfig,
+) (bufimage.Image, error) {
+	// Filtering by types mutates the image, and the image is shared by all plugins.
+	pluginImage, err := bufimage.CloneImage(image)
+	if err != nil {
+		return nil, err
+	}
+	if len(pluginConfig.Paths()) > 0 || len(pluginConfig.ExcludePaths()) > 0 {
+		pluginImage, err = bufimage.ImageWithOnlyPaths(
+			pluginImage,
+			pluginConfig.Paths(),
+			pluginConfig.ExcludePaths(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", pluginConfig.Name(), err)
+		}
+	}
+	if len(pluginConfig.Types()) > 0 || len(pluginConfig.ExcludeTypes()) > 0 {
+		pluginImage, err = bufimageutil.ImageFilteredByTypesWithOptions(
+			pluginImage,
+			pluginConfig.Types(),
+			bufimageutil.WithExcludeTypes(pluginConfig.ExcludeTypes()...),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", pluginConfig.Name(), err)
+		}
+	}
+	return pluginImage, nil
+}
+
 // validateResponses verifies that a response is set for each of the
 // pluginConfigs, and that each generated file is generated by a single
 // plugin.
@@ -474,8 +719,33 @@ func validateResponses(
 	return nil
 }
 
+// getPluginTimeout returns the timeout of the plugin, or the default timeout
+// if the plugin does not have a timeout.
+func getPluginTimeout(pluginConfig bufconfig.GeneratePluginConfig, defaultTimeout time.Duration) time.Duration {
+	if timeout := pluginConfig.Timeout(); timeout != 0 {
+		return timeout
+	}
+	return defaultTimeout
+}
+
+func getPluginOuts(baseOutDir string, pluginConfigs []bufconfig.GeneratePluginConfig) []string {
+	return slicesext.Map(
+		pluginConfigs,
+		func(pluginConfig bufconfig.GeneratePluginConfig) string {
+			out := pluginConfig.Out()
+			if baseOutDir != "" && baseOutDir != "." {
+				return filepath.Join(baseOutDir, out)
+			}
+			return out
+		},
+	)
+}
+
 type generateOptions struct {
 	baseOutDirPath                string
+	pluginTimeout                 time.Duration
+	maxParallelism                int
+	checkWriter                   io.Writer
 	deleteOuts                    *bool
 	includeImportsOverride        *bool
 	includeWellKnownTypesOverride *bool
@@ -0,0 +1,233 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/execext"
+	"github.com/bufbuild/buf/private/pkg/licenseheader"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/tmp"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// postProcessResponse applies the post-processing steps of the plugin to the files
+// in the response, in order, and returns the resulting response.
+//
+// Files with insertion points are not post-processed, and are kept after the other
+// files of the response. The input response is not modified.
+func postProcessResponse(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	storageosProvider storageos.Provider,
+	response *pluginpb.CodeGeneratorResponse,
+	postProcessConfigs []bufconfig.GeneratePostProcessConfig,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	if len(postProcessConfigs) == 0 {
+		return response, nil
+	}
+	var files []*pluginpb.CodeGeneratorResponse_File
+	var insertionPointFiles []*pluginpb.CodeGeneratorResponse_File
+	for _, file := range response.GetFile() {
+		if file.GetInsertionPoint() != "" || file.GetName() == "" {
+			insertionPointFiles = append(insertionPointFiles, file)
+			continue
+		}
+		files = append(files, proto.Clone(file).(*pluginpb.CodeGeneratorResponse_File))
+	}
+	var err error
+	for _, postProcessConfig := range postProcessConfigs {
+		switch postProcessConfig.Type() {
+		case bufconfig.GeneratePostProcessTypeLicenseHeader:
+			err = postProcessLicenseHeader(files, postProcessConfig)
+		case bufconfig.GeneratePostProcessTypeRewritePath:
+			err = postProcessRewritePath(files, postProcessConfig)
+		case bufconfig.GeneratePostProcessTypeRun:
+			files, err = postProcessRun(ctx, container, storageosProvider, files, postProcessConfig)
+		default:
+			err = fmt.Errorf("unknown post_process type: %v", postProcessConfig.Type())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("post_process %s: %w", postProcessConfig.Type().String(), err)
+		}
+	}
+	postProcessedResponse := proto.Clone(response).(*pluginpb.CodeGeneratorResponse)
+	postProcessedResponse.File = append(files, insertionPointFiles...)
+	return postProcessedResponse, nil
+}
+
+// postProcessLicenseHeader adds or replaces the license header of the files.
+//
+// Files with an extension that licenseheader does not recognize are left as is.
+func postProcessLicenseHeader(
+	files []*pluginpb.CodeGeneratorResponse_File,
+	postProcessConfig bufconfig.GeneratePostProcessConfig,
+) error {
+	for _, file := range files {
+		data, err := licenseheader.Modify(
+			postProcessConfig.LicenseType(),
+			postProcessConfig.CopyrightHolder(),
+			postProcessConfig.YearRange(),
+			file.GetName(),
+			[]byte(file.GetContent()),
+		)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.GetName(), err)
+		}
+		if content := string(data); content != file.GetContent() {
+			file.Content = proto.String(content)
+			// The annotations refer to offsets in the original content.
+			file.GeneratedCodeInfo = nil
+		}
+	}
+	return nil
+}
+
+// postProcessRewritePath rewrites the paths of the files that match.
+func postProcessRewritePath(
+	files []*pluginpb.CodeGeneratorResponse_File,
+	postProcessConfig bufconfig.GeneratePostProcessConfig,
+) error {
+	match := postProcessConfig.Match()
+	pathToOriginalPath := make(map[string]string, len(files))
+	for _, file := range files {
+		path := file.GetName()
+		if match.MatchString(path) {
+			rewrittenPath, err := normalpath.NormalizeAndValidate(
+				match.ReplaceAllString(path, postProcessConfig.Replace()),
+			)
+			if err != nil {
+				return fmt.Errorf("invalid rewritten path for %s: %w", file.GetName(), err)
+			}
+			if rewrittenPath == "." {
+				return fmt.Errorf("invalid rewritten path for %s: must not be empty", file.GetName())
+			}
+			path = rewrittenPath
+		}
+		if originalPath, ok := pathToOriginalPath[path]; ok {
+			return fmt.Errorf("%s and %s are both rewritten to %s", originalPath, file.GetName(), path)
+		}
+		pathToOriginalPath[path] = file.GetName()
+		file.Name = proto.String(path)
+	}
+	return nil
+}
+
+// postProcessRun writes the files to a temporary directory, runs the command in
+// that directory with the paths of the files as additional arguments, and returns
+// the files in the directory after the command completes.
+//
+// Files may be modified, added or removed by the command.
+func postProcessRun(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	storageosProvider storageos.Provider,
+	files []*pluginpb.CodeGeneratorResponse_File,
+	postProcessConfig bufconfig.GeneratePostProcessConfig,
+) (_ []*pluginpb.CodeGeneratorResponse_File, retErr error) {
+	tmpDir, err := tmp.NewDir(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, tmpDir.Close())
+	}()
+	readWriteBucket, err := storageosProvider.NewReadWriteBucket(tmpDir.Path())
+	if err != nil {
+		return nil, err
+	}
+	pathToFile := make(map[string]*pluginpb.CodeGeneratorResponse_File, len(files))
+	for _, file := range files {
+		if err := storage.PutPath(ctx, readWriteBucket, file.GetName(), []byte(file.GetContent())); err != nil {
+			return nil, err
+		}
+		pathToFile[file.GetName()] = file
+	}
+	run := postProcessConfig.Run()
+	command := run[0]
+	// The command runs in the temporary directory, so relative paths to the command
+	// are resolved relative to the current working directory first.
+	if filepath.Base(command) != command {
+		command, err = filepath.Abs(command)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := execext.Run(
+		ctx,
+		command,
+		execext.WithArgs(
+			slicesext.Concat(
+				run[1:],
+				slicesext.Map(
+					files,
+					func(file *pluginpb.CodeGeneratorResponse_File) string {
+						return normalpath.Unnormalize(file.GetName())
+					},
+				),
+			)...,
+		),
+		execext.WithEnv(app.Environ(container)),
+		execext.WithStdout(container.Stderr()),
+		execext.WithStderr(container.Stderr()),
+		execext.WithDir(tmpDir.Path()),
+	); err != nil {
+		return nil, err
+	}
+	var postProcessedFiles []*pluginpb.CodeGeneratorResponse_File
+	if err := storage.WalkReadObjects(
+		ctx,
+		readWriteBucket,
+		"",
+		func(readObject storage.ReadObject) error {
+			data, err := io.ReadAll(readObject)
+			if err != nil {
+				return err
+			}
+			content := string(data)
+			postProcessedFile := &pluginpb.CodeGeneratorResponse_File{
+				Name:    proto.String(readObject.Path()),
+				Content: proto.String(content),
+			}
+			// The annotations still apply if the content is unchanged.
+			if file, ok := pathToFile[readObject.Path()]; ok && file.GetContent() == content {
+				postProcessedFile.GeneratedCodeInfo = file.GetGeneratedCodeInfo()
+			}
+			postProcessedFiles = append(postProcessedFiles, postProcessedFile)
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	sort.Slice(
+		postProcessedFiles,
+		func(i int, j int) bool {
+			return postProcessedFiles[i].GetName() < postProcessedFiles[j].GetName()
+		},
+	)
+	return postProcessedFiles, nil
+}
@@ -0,0 +1,216 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/licenseheader"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestPostProcessResponse(t *testing.T) {
+	t.Parallel()
+	response := &pluginpb.CodeGeneratorResponse{
+		SupportedFeatures: proto.Uint64(uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)),
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:              proto.String("foo/v1/foo.pb.go"),
+				Content:           proto.String("package foov1\n"),
+				GeneratedCodeInfo: &descriptorpb.GeneratedCodeInfo{},
+			},
+			{
+				Name:           proto.String("bar/v1/bar.pb.go"),
+				InsertionPoint: proto.String("imports"),
+				Content:        proto.String("import \"fmt\"\n"),
+			},
+			{
+				Name:    proto.String("foo/v1/foo.txt"),
+				Content: proto.String("foo\n"),
+			},
+		},
+	}
+	postProcessedResponse, err := postProcessResponse(
+		context.Background(),
+		newTestPostProcessContainer(),
+		storageos.NewProvider(),
+		response,
+		[]bufconfig.GeneratePostProcessConfig{
+			newTestRewritePathPostProcessConfig(t, `^foo/v1/(.*)\.pb\.go$`, "foov1/$1.go"),
+			newTestLicenseHeaderPostProcessConfig(t),
+		},
+	)
+	require.NoError(t, err)
+	diff := cmp.Diff(
+		[]*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("foov1/foo.go"),
+				Content: proto.String("// Copyright 2024 Acme, Inc.\n//\n// All rights reserved.\n\npackage foov1\n"),
+			},
+			{
+				Name:    proto.String("foo/v1/foo.txt"),
+				Content: proto.String("foo\n"),
+			},
+			{
+				Name:           proto.String("bar/v1/bar.pb.go"),
+				InsertionPoint: proto.String("imports"),
+				Content:        proto.String("import \"fmt\"\n"),
+			},
+		},
+		postProcessedResponse.GetFile(),
+		protocmp.Transform(),
+	)
+	require.Empty(t, diff)
+	require.Equal(t, response.GetSupportedFeatures(), postProcessedResponse.GetSupportedFeatures())
+	// The input response is not modified.
+	require.Equal(t, "foo/v1/foo.pb.go", response.GetFile()[0].GetName())
+	require.Equal(t, "package foov1\n", response.GetFile()[0].GetContent())
+}
+
+func TestPostProcessResponseRewritePathErrors(t *testing.T) {
+	t.Parallel()
+	response := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("foo/v1/foo.pb.go"),
+				Content: proto.String("package foov1\n"),
+			},
+			{
+				Name:    proto.String("foo/v2/foo.pb.go"),
+				Content: proto.String("package foov2\n"),
+			},
+		},
+	}
+	_, err := postProcessResponse(
+		context.Background(),
+		newTestPostProcessContainer(),
+		storageos.NewProvider(),
+		response,
+		[]bufconfig.GeneratePostProcessConfig{
+			newTestRewritePathPostProcessConfig(t, `^foo/v[0-9]/`, "foo/"),
+		},
+	)
+	require.ErrorContains(t, err, "foo/v1/foo.pb.go and foo/v2/foo.pb.go are both rewritten to foo/foo.pb.go")
+	_, err = postProcessResponse(
+		context.Background(),
+		newTestPostProcessContainer(),
+		storageos.NewProvider(),
+		response,
+		[]bufconfig.GeneratePostProcessConfig{
+			newTestRewritePathPostProcessConfig(t, `^foo/`, "../"),
+		},
+	)
+	require.ErrorContains(t, err, "invalid rewritten path for foo/v1/foo.pb.go")
+}
+
+func TestPostProcessResponseRun(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("touch is not available on windows")
+	}
+	response := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:              proto.String("foo/v1/foo.pb.go"),
+				Content:           proto.String("package foov1\n"),
+				GeneratedCodeInfo: &descriptorpb.GeneratedCodeInfo{},
+			},
+		},
+	}
+	runPostProcessConfig, err := bufconfig.NewGenerateRunPostProcessConfig([]string{"touch", "extra.txt"})
+	require.NoError(t, err)
+	postProcessedResponse, err := postProcessResponse(
+		context.Background(),
+		newTestPostProcessContainer(),
+		storageos.NewProvider(),
+		response,
+		[]bufconfig.GeneratePostProcessConfig{
+			runPostProcessConfig,
+		},
+	)
+	require.NoError(t, err)
+	diff := cmp.Diff(
+		[]*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("extra.txt"),
+				Content: proto.String(""),
+			},
+			{
+				Name:    proto.String("foo/v1/foo.pb.go"),
+				Content: proto.String("package foov1\n"),
+				// The content is unchanged, so the annotations are kept.
+				GeneratedCodeInfo: &descriptorpb.GeneratedCodeInfo{},
+			},
+		},
+		postProcessedResponse.GetFile(),
+		protocmp.Transform(),
+	)
+	require.Empty(t, diff)
+	failPostProcessConfig, err := bufconfig.NewGenerateRunPostProcessConfig([]string{"false"})
+	require.NoError(t, err)
+	_, err = postProcessResponse(
+		context.Background(),
+		newTestPostProcessContainer(),
+		storageos.NewProvider(),
+		response,
+		[]bufconfig.GeneratePostProcessConfig{
+			failPostProcessConfig,
+		},
+	)
+	require.ErrorContains(t, err, "post_process run")
+}
+
+func newTestPostProcessContainer() app.Container {
+	return app.NewContainer(
+		map[string]string{
+			"PATH": os.Getenv("PATH"),
+		},
+		nil,
+		bytes.NewBuffer(nil),
+		bytes.NewBuffer(nil),
+	)
+}
+
+func newTestRewritePathPostProcessConfig(
+	t *testing.T,
+	match string,
+	replace string,
+) bufconfig.GeneratePostProcessConfig {
+	postProcessConfig, err := bufconfig.NewGenerateRewritePathPostProcessConfig(match, replace)
+	require.NoError(t, err)
+	return postProcessConfig
+}
+
+func newTestLicenseHeaderPostProcessConfig(t *testing.T) bufconfig.GeneratePostProcessConfig {
+	postProcessConfig, err := bufconfig.NewGenerateLicenseHeaderPostProcessConfig(
+		licenseheader.LicenseTypeProprietary,
+		"Acme, Inc.",
+		"2024",
+	)
+	require.NoError(t, err)
+	return postProcessConfig
+}
@@ -196,6 +196,7 @@ func (m *migrateBuilder) addModule(ctx context.Context, moduleDirPath string) (r
 				false,
 				"",
 				false,
+				false,
 			),
 			bufconfig.NewBreakingConfig(
 				bufconfig.NewEnabledCheckConfigForUseIDsAndCategories(
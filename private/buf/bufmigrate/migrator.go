@@ -660,6 +660,7 @@ func equivalentLintConfigInV2(
 		lintConfig.RPCAllowGoogleProtobufEmptyResponses(),
 		lintConfig.ServiceSuffix(),
 		lintConfig.AllowCommentIgnores(),
+		lintConfig.RequireCommentIgnoreReason(),
 	), nil
 }
 
@@ -26,6 +26,8 @@ import (
 	"os/exec"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufjsonschema"
+	"github.com/bufbuild/buf/private/bufpkg/bufopenapi"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/protoplugin"
@@ -100,6 +102,16 @@ func GenerateWithProtocPath(protocPath ...string) GenerateOption {
 	}
 }
 
+// GenerateWithMaxParallelism returns a new GenerateOption that limits the number
+// of requests that are executed at once by the plugin.
+//
+// The default is to use thread.Parallelism(). A value of <1 has no meaning.
+func GenerateWithMaxParallelism(maxParallelism int) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.maxParallelism = maxParallelism
+	}
+}
+
 // NewHandler returns a new Handler based on the plugin name and optional path.
 //
 // protocPath and pluginPath are optional.
@@ -107,6 +119,7 @@ func GenerateWithProtocPath(protocPath ...string) GenerateOption {
 //   - If the plugin path is set, this returns a new binary handler for that path.
 //   - If the plugin path is unset, this does exec.LookPath for a binary named protoc-gen-pluginName,
 //     and if one is found, a new binary handler is returned for this.
+//   - Else, if the name is in BuiltinPluginNames, this returns the handler built into buf.
 //   - Else, if the name is in ProtocProxyPluginNames, this returns a new protoc proxy handler.
 //   - Else, this returns error.
 func NewHandler(
@@ -131,6 +144,14 @@ func NewHandler(
 		return handler, nil
 	}
 
+	// Initialize buf builtin plugin handler. These do not require protoc.
+	switch pluginName {
+	case "buf-jsonschema":
+		return bufjsonschema.NewHandler(), nil
+	case "buf-openapiv3":
+		return bufopenapi.NewHandler(), nil
+	}
+
 	// Initialize builtin protoc plugin handler. We always look for protoc-gen-X first,
 	// but if not, check the builtins.
 	if _, ok := bufconfig.ProtocProxyPluginNames[pluginName]; ok {
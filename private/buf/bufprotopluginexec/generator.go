@@ -70,12 +70,14 @@ func (g *generator) Generate(
 		ctx,
 		container,
 		requests,
+		bufprotoplugin.GenerateWithMaxParallelism(generateOptions.maxParallelism),
 	)
 }
 
 type generateOptions struct {
-	pluginPath []string
-	protocPath []string
+	pluginPath     []string
+	protocPath     []string
+	maxParallelism int
 }
 
 func newGenerateOptions() *generateOptions {
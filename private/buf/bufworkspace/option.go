@@ -15,6 +15,7 @@
 package bufworkspace
 
 import (
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 )
@@ -71,6 +72,22 @@ func WithIgnoreAndDisallowV1BufWorkYAMLs() WorkspaceBucketOption {
 	return &workspaceIgnoreAndDisallowV1BufWorkYAMLsOption{}
 }
 
+// WithBufLockDepModuleKeys returns a new WorkspaceBucketOption that says to use the given
+// ModuleKeys as the dependencies of the Workspace, in place of the dependencies in the
+// buf.lock files. The buf.lock files are not read.
+//
+// The ModuleKeys are expected to have all transitive dependencies, as in a buf.lock. For
+// v1 workspaces, the ModuleKeys are used for every Module, so this should only be used
+// with WithIgnoreAndDisallowV1BufWorkYAMLs.
+//
+// This is used to build a Workspace against updated dependencies without writing the
+// buf.lock, with buf dep update --preview.
+func WithBufLockDepModuleKeys(depModuleKeys []bufmodule.ModuleKey) WorkspaceBucketOption {
+	return &workspaceBufLockDepModuleKeysOption{
+		depModuleKeys: depModuleKeys,
+	}
+}
+
 // Note these paths need to have the path/to/module stripped, and then each new path
 // filtered to the specific module it applies to. If some modules do not have any
 // target paths, but we specified WorkspaceWithTargetPaths, then those modules
@@ -148,11 +165,22 @@ func (c *workspaceIgnoreAndDisallowV1BufWorkYAMLsOption) applyToWorkspaceBucketC
 	config.ignoreAndDisallowV1BufWorkYAMLs = true
 }
 
+type workspaceBufLockDepModuleKeysOption struct {
+	depModuleKeys []bufmodule.ModuleKey
+}
+
+func (b *workspaceBufLockDepModuleKeysOption) applyToWorkspaceBucketConfig(config *workspaceBucketConfig) {
+	config.bufLockDepModuleKeys = b.depModuleKeys
+	config.hasBufLockDepModuleKeys = true
+}
+
 type workspaceBucketConfig struct {
 	protoFileTargetPath             string
 	includePackageFiles             bool
 	configOverride                  string
 	ignoreAndDisallowV1BufWorkYAMLs bool
+	bufLockDepModuleKeys            []bufmodule.ModuleKey
+	hasBufLockDepModuleKeys         bool
 }
 
 func newWorkspaceBucketConfig(options []WorkspaceBucketOption) (*workspaceBucketConfig, error) {
@@ -56,6 +56,11 @@ type WorkspaceDepManager interface {
 	//
 	// Sorted.
 	ConfiguredDepModuleRefs(ctx context.Context) ([]bufparse.Ref, error)
+	// TrustedSigningKeys returns the PublicKeys that the Signatures of dependencies are
+	// verified with.
+	//
+	// These come from the buf.yaml file. If empty, Signatures should not be verified.
+	TrustedSigningKeys(ctx context.Context) ([]bufmodule.PublicKey, error)
 
 	isWorkspaceDepManager()
 }
@@ -94,30 +99,27 @@ func newWorkspaceDepManager(
 }
 
 func (w *workspaceDepManager) ConfiguredDepModuleRefs(ctx context.Context) ([]bufparse.Ref, error) {
-	bufYAMLFile, err := bufconfig.GetBufYAMLFileForPrefix(ctx, w.bucket, w.targetSubDirPath)
+	bufYAMLFile, err := w.getBufYAMLFile(ctx)
 	if err != nil {
-		if !errors.Is(err, fs.ErrNotExist) {
-			return nil, err
-		}
+		return nil, err
 	}
 	if bufYAMLFile == nil {
 		return nil, nil
 	}
-	switch fileVersion := bufYAMLFile.FileVersion(); fileVersion {
-	case bufconfig.FileVersionV1Beta1, bufconfig.FileVersionV1:
-		if w.isV2 {
-			return nil, syserror.Newf("buf.yaml at %q did had version %v but expected v1beta1, v1", w.targetSubDirPath, fileVersion)
-		}
-	case bufconfig.FileVersionV2:
-		if !w.isV2 {
-			return nil, syserror.Newf("buf.yaml at %q did had version %v but expected v12", w.targetSubDirPath, fileVersion)
-		}
-	default:
-		return nil, syserror.Newf("unknown FileVersion: %v", fileVersion)
-	}
 	return bufYAMLFile.ConfiguredDepModuleRefs(), nil
 }
 
+func (w *workspaceDepManager) TrustedSigningKeys(ctx context.Context) ([]bufmodule.PublicKey, error) {
+	bufYAMLFile, err := w.getBufYAMLFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if bufYAMLFile == nil {
+		return nil, nil
+	}
+	return bufYAMLFile.TrustedSigningKeys(), nil
+}
+
 func (w *workspaceDepManager) BufLockFileDigestType() bufmodule.DigestType {
 	if w.isV2 {
 		return bufmodule.DigestTypeB5
@@ -163,3 +165,28 @@ func (w *workspaceDepManager) UpdateBufLockFile(ctx context.Context, depModuleKe
 }
 
 func (*workspaceDepManager) isWorkspaceDepManager() {}
+
+// getBufYAMLFile returns the buf.yaml file at the target directory, or nil if it
+// does not exist.
+func (w *workspaceDepManager) getBufYAMLFile(ctx context.Context) (bufconfig.BufYAMLFile, error) {
+	bufYAMLFile, err := bufconfig.GetBufYAMLFileForPrefix(ctx, w.bucket, w.targetSubDirPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	switch fileVersion := bufYAMLFile.FileVersion(); fileVersion {
+	case bufconfig.FileVersionV1Beta1, bufconfig.FileVersionV1:
+		if w.isV2 {
+			return nil, syserror.Newf("buf.yaml at %q did had version %v but expected v1beta1, v1", w.targetSubDirPath, fileVersion)
+		}
+	case bufconfig.FileVersionV2:
+		if !w.isV2 {
+			return nil, syserror.Newf("buf.yaml at %q did had version %v but expected v12", w.targetSubDirPath, fileVersion)
+		}
+	default:
+		return nil, syserror.Newf("unknown FileVersion: %v", fileVersion)
+	}
+	return bufYAMLFile, nil
+}
@@ -49,24 +49,43 @@ type WorkspaceDepManagerProvider interface {
 // NewWorkspaceDepManagerProvider returns a new WorkspaceDepManagerProvider.
 func NewWorkspaceDepManagerProvider(
 	logger *slog.Logger,
+	options ...WorkspaceDepManagerProviderOption,
 ) WorkspaceDepManagerProvider {
 	return newWorkspaceDepManagerProvider(
 		logger,
+		options...,
 	)
 }
 
+// WorkspaceDepManagerProviderOption is an option for a new WorkspaceDepManagerProvider.
+type WorkspaceDepManagerProviderOption func(*workspaceDepManagerProvider)
+
+// WorkspaceDepManagerProviderWithBufYAMLFileFunc returns a new WorkspaceDepManagerProviderOption
+// that calls the BufYAMLFileFunc with the v2 buf.yaml of each WorkspaceDepManager.
+func WorkspaceDepManagerProviderWithBufYAMLFileFunc(bufYAMLFileFunc BufYAMLFileFunc) WorkspaceDepManagerProviderOption {
+	return func(workspaceDepManagerProvider *workspaceDepManagerProvider) {
+		workspaceDepManagerProvider.bufYAMLFileFunc = bufYAMLFileFunc
+	}
+}
+
 // *** PRIVATE ***
 
 type workspaceDepManagerProvider struct {
-	logger *slog.Logger
+	logger          *slog.Logger
+	bufYAMLFileFunc BufYAMLFileFunc
 }
 
 func newWorkspaceDepManagerProvider(
 	logger *slog.Logger,
+	options ...WorkspaceDepManagerProviderOption,
 ) *workspaceDepManagerProvider {
-	return &workspaceDepManagerProvider{
+	workspaceDepManagerProvider := &workspaceDepManagerProvider{
 		logger: logger,
 	}
+	for _, option := range options {
+		option(workspaceDepManagerProvider)
+	}
+	return workspaceDepManagerProvider
 }
 
 func (w *workspaceDepManagerProvider) GetWorkspaceDepManager(
@@ -79,6 +98,15 @@ func (w *workspaceDepManagerProvider) GetWorkspaceDepManager(
 		// A v2 workspace was found, but we make sure
 		bufYAMLFile := controllingWorkspace.BufYAMLFile()
 		if bufYAMLFile.FileVersion() == bufconfig.FileVersionV2 {
+			if w.bufYAMLFileFunc != nil {
+				bufYAMLFileDirPath, err := getBufYAMLFileLocalDirPath(ctx, bucket, controllingWorkspace.Path())
+				if err != nil {
+					return nil, err
+				}
+				if err := w.bufYAMLFileFunc(ctx, bufYAMLFile, bufYAMLFileDirPath); err != nil {
+					return nil, err
+				}
+			}
 			return newWorkspaceDepManager(bucket, controllingWorkspace.Path(), true), nil
 		}
 	}
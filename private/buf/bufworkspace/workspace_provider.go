@@ -20,10 +20,12 @@ import (
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"path/filepath"
 
 	"github.com/bufbuild/buf/private/buf/buftarget"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulevendor"
 	"github.com/bufbuild/buf/private/bufpkg/bufparse"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
@@ -77,22 +79,56 @@ func NewWorkspaceProvider(
 	graphProvider bufmodule.GraphProvider,
 	moduleDataProvider bufmodule.ModuleDataProvider,
 	commitProvider bufmodule.CommitProvider,
+	options ...WorkspaceProviderOption,
 ) WorkspaceProvider {
 	return newWorkspaceProvider(
 		logger,
 		graphProvider,
 		moduleDataProvider,
 		commitProvider,
+		options...,
 	)
 }
 
+// BufYAMLFileFunc is called with the v2 buf.yaml of a workspace before any dependencies
+// of the workspace are read.
+//
+// The dirPath is the directory of the buf.yaml on disk, or empty if the buf.yaml is not
+// on disk, for example if it was read from a remote input or a config override.
+type BufYAMLFileFunc func(ctx context.Context, bufYAMLFile bufconfig.BufYAMLFile, dirPath string) error
+
+// WorkspaceProviderOption is an option for a new WorkspaceProvider.
+type WorkspaceProviderOption func(*workspaceProvider)
+
+// WorkspaceProviderWithBufYAMLFileFunc returns a new WorkspaceProviderOption that calls
+// the BufYAMLFileFunc with the v2 buf.yaml of each Workspace built for a Bucket.
+func WorkspaceProviderWithBufYAMLFileFunc(bufYAMLFileFunc BufYAMLFileFunc) WorkspaceProviderOption {
+	return func(workspaceProvider *workspaceProvider) {
+		workspaceProvider.bufYAMLFileFunc = bufYAMLFileFunc
+	}
+}
+
+// WorkspaceProviderWithSignatureCommitProvider returns a new WorkspaceProviderOption that
+// reads the Commits whose Signatures are verified against the trusted signing keys of a
+// workspace from the CommitProvider.
+//
+// If not set, the CommitProvider of the WorkspaceProvider is used. Vendored Commits are
+// always read from the vendor directory first.
+func WorkspaceProviderWithSignatureCommitProvider(signatureCommitProvider bufmodule.CommitProvider) WorkspaceProviderOption {
+	return func(workspaceProvider *workspaceProvider) {
+		workspaceProvider.signatureCommitProvider = signatureCommitProvider
+	}
+}
+
 // *** PRIVATE ***
 
 type workspaceProvider struct {
-	logger             *slog.Logger
-	graphProvider      bufmodule.GraphProvider
-	moduleDataProvider bufmodule.ModuleDataProvider
-	commitProvider     bufmodule.CommitProvider
+	logger                  *slog.Logger
+	graphProvider           bufmodule.GraphProvider
+	moduleDataProvider      bufmodule.ModuleDataProvider
+	commitProvider          bufmodule.CommitProvider
+	signatureCommitProvider bufmodule.CommitProvider
+	bufYAMLFileFunc         BufYAMLFileFunc
 }
 
 func newWorkspaceProvider(
@@ -100,13 +136,21 @@ func newWorkspaceProvider(
 	graphProvider bufmodule.GraphProvider,
 	moduleDataProvider bufmodule.ModuleDataProvider,
 	commitProvider bufmodule.CommitProvider,
+	options ...WorkspaceProviderOption,
 ) *workspaceProvider {
-	return &workspaceProvider{
+	workspaceProvider := &workspaceProvider{
 		logger:             logger,
 		graphProvider:      graphProvider,
 		moduleDataProvider: moduleDataProvider,
 		commitProvider:     commitProvider,
 	}
+	for _, option := range options {
+		option(workspaceProvider)
+	}
+	if workspaceProvider.signatureCommitProvider == nil {
+		workspaceProvider.signatureCommitProvider = commitProvider
+	}
+	return workspaceProvider
 }
 
 func (w *workspaceProvider) GetWorkspaceForModuleKey(
@@ -245,15 +289,29 @@ func (w *workspaceProvider) GetWorkspaceForBucket(
 		return nil, err
 	}
 	if workspaceTargeting.v2 != nil {
+		if w.bufYAMLFileFunc != nil {
+			var bufYAMLFileDirPath string
+			if controllingWorkspace := bucketTargeting.ControllingWorkspace(); overrideBufYAMLFile == nil && controllingWorkspace != nil {
+				bufYAMLFileDirPath, err = getBufYAMLFileLocalDirPath(ctx, bucket, controllingWorkspace.Path())
+				if err != nil {
+					return nil, err
+				}
+			}
+			if err := w.bufYAMLFileFunc(ctx, workspaceTargeting.v2.bufYAMLFile, bufYAMLFileDirPath); err != nil {
+				return nil, err
+			}
+		}
 		return w.getWorkspaceForBucketBufYAMLV2(
 			ctx,
 			bucket,
+			config,
 			workspaceTargeting.v2,
 		)
 	}
 	return w.getWorkspaceForBucketAndModuleDirPathsV1Beta1OrV1(
 		ctx,
 		bucket,
+		config,
 		workspaceTargeting.v1,
 	)
 }
@@ -261,50 +319,28 @@ func (w *workspaceProvider) GetWorkspaceForBucket(
 func (w *workspaceProvider) getWorkspaceForBucketAndModuleDirPathsV1Beta1OrV1(
 	ctx context.Context,
 	bucket storage.ReadBucket,
+	config *workspaceBucketConfig,
 	v1WorkspaceTargeting *v1Targeting,
 ) (*workspace, error) {
 	moduleSetBuilder := bufmodule.NewModuleSetBuilder(ctx, w.logger, w.moduleDataProvider, w.commitProvider)
 	for _, moduleBucketAndTargeting := range v1WorkspaceTargeting.moduleBucketsAndTargeting {
 		mappedModuleBucket := moduleBucketAndTargeting.bucket
 		moduleTargeting := moduleBucketAndTargeting.moduleTargeting
-		bufLockFile, err := bufconfig.GetBufLockFileForPrefix(
-			ctx,
-			bucket, // Need to use the non-mapped bucket since the mapped bucket excludes the buf.lock
-			moduleTargeting.moduleDirPath,
-			bufconfig.BufLockFileWithDigestResolver(
-				func(ctx context.Context, remote string, commitID uuid.UUID) (bufmodule.Digest, error) {
-					commitKey, err := bufmodule.NewCommitKey(remote, commitID, bufmodule.DigestTypeB4)
-					if err != nil {
-						return nil, err
-					}
-					commits, err := w.commitProvider.GetCommitsForCommitKeys(ctx, []bufmodule.CommitKey{commitKey})
-					if err != nil {
-						return nil, err
-					}
-					return commits[0].ModuleKey().Digest()
-				},
-			),
-		)
-		if err != nil {
-			if !errors.Is(err, fs.ErrNotExist) {
+		depModuleKeys := config.bufLockDepModuleKeys
+		if !config.hasBufLockDepModuleKeys {
+			var err error
+			depModuleKeys, err = w.getBufLockFileDepModuleKeysV1Beta1OrV1(ctx, bucket, moduleTargeting.moduleDirPath)
+			if err != nil {
 				return nil, err
 			}
-		} else {
-			switch fileVersion := bufLockFile.FileVersion(); fileVersion {
-			case bufconfig.FileVersionV1Beta1, bufconfig.FileVersionV1:
-			case bufconfig.FileVersionV2:
-				return nil, errors.New("got a v2 buf.lock file for a v1 buf.yaml - this is not allowed, run buf mod update to update your buf.lock file")
-			default:
-				return nil, syserror.Newf("unknown FileVersion: %v", fileVersion)
-			}
-			for _, depModuleKey := range bufLockFile.DepModuleKeys() {
-				// DepModuleKeys from a BufLockFile is expected to have all transitive dependencies,
-				// and we can rely on this property.
-				moduleSetBuilder.AddRemoteModule(
-					depModuleKey,
-					false,
-				)
-			}
+		}
+		for _, depModuleKey := range depModuleKeys {
+			// DepModuleKeys from a BufLockFile is expected to have all transitive dependencies,
+			// and we can rely on this property.
+			moduleSetBuilder.AddRemoteModule(
+				depModuleKey,
+				false,
+			)
 		}
 		v1BufYAMLObjectData, err := bufconfig.GetBufYAMLV1Beta1OrV1ObjectDataForPrefix(ctx, bucket, moduleTargeting.moduleDirPath)
 		if err != nil {
@@ -367,37 +403,42 @@ func (w *workspaceProvider) getWorkspaceForBucketAndModuleDirPathsV1Beta1OrV1(
 func (w *workspaceProvider) getWorkspaceForBucketBufYAMLV2(
 	ctx context.Context,
 	bucket storage.ReadBucket,
+	config *workspaceBucketConfig,
 	v2Targeting *v2Targeting,
 ) (*workspace, error) {
-	moduleSetBuilder := bufmodule.NewModuleSetBuilder(ctx, w.logger, w.moduleDataProvider, w.commitProvider)
-	bufLockFile, err := bufconfig.GetBufLockFileForPrefix(
-		ctx,
-		bucket,
-		// buf.lock files live next to the buf.yaml
-		".",
-		// We are not passing BufLockFileWithDigestResolver here because a buf.lock
-		// v2 is expected to have digests
-	)
+	moduleDataProvider := w.moduleDataProvider
+	signatureCommitProvider := w.signatureCommitProvider
+	// Vendored dependencies live next to the buf.lock, and are used in place of the
+	// cache and the BSR when present.
+	//
+	// Only v2 workspaces read vendored dependencies, and buf dep vendor rejects v1 workspaces.
+	vendorBucket := storage.MapReadBucket(bucket, storage.MapOnPrefix(bufmodulevendor.DirPath))
+	hasVendor, err := bufmodulevendor.Exists(ctx, vendorBucket)
 	if err != nil {
-		if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if hasVendor {
+		moduleDataProvider = bufmodulevendor.NewModuleDataProvider(w.logger, vendorBucket, moduleDataProvider)
+		signatureCommitProvider = bufmodulevendor.NewCommitProvider(w.logger, vendorBucket, signatureCommitProvider)
+	}
+	moduleSetBuilder := bufmodule.NewModuleSetBuilder(ctx, w.logger, moduleDataProvider, w.commitProvider)
+	depModuleKeys := config.bufLockDepModuleKeys
+	if !config.hasBufLockDepModuleKeys {
+		depModuleKeys, err = getBufLockFileDepModuleKeysV2(ctx, bucket)
+		if err != nil {
 			return nil, err
 		}
-	} else {
-		switch fileVersion := bufLockFile.FileVersion(); fileVersion {
-		case bufconfig.FileVersionV1Beta1, bufconfig.FileVersionV1:
-			return nil, fmt.Errorf("got a %s buf.lock file for a v2 buf.yaml", bufLockFile.FileVersion().String())
-		case bufconfig.FileVersionV2:
-		default:
-			return nil, syserror.Newf("unknown FileVersion: %v", fileVersion)
-		}
-		for _, depModuleKey := range bufLockFile.DepModuleKeys() {
-			// DepModuleKeys from a BufLockFile is expected to have all transitive dependencies,
-			// and we can rely on this property.
-			moduleSetBuilder.AddRemoteModule(
-				depModuleKey,
-				false,
-			)
-		}
+	}
+	if err := verifyDepSignatures(ctx, signatureCommitProvider, v2Targeting.bufYAMLFile.TrustedSigningKeys(), depModuleKeys); err != nil {
+		return nil, err
+	}
+	for _, depModuleKey := range depModuleKeys {
+		// DepModuleKeys from a BufLockFile is expected to have all transitive dependencies,
+		// and we can rely on this property.
+		moduleSetBuilder.AddRemoteModule(
+			depModuleKey,
+			false,
+		)
 	}
 	// Only check for duplicate module description in v2, which would be an user error, i.e.
 	// This is not a system error:
@@ -431,6 +472,17 @@ func (w *workspaceProvider) getWorkspaceForBucketBufYAMLV2(
 			return nil, fmt.Errorf("multiple module configs found with the same description: %s", moduleDescription)
 		}
 		seenModuleDescriptions[moduleDescription] = struct{}{}
+		if hasVendor && normalpath.ContainsPath(moduleConfig.DirPath(), bufmodulevendor.DirPath, normalpath.Relative) {
+			// The vendored dependencies are never part of a local Module.
+			vendorRelDirPath, err := normalpath.Rel(moduleConfig.DirPath(), bufmodulevendor.DirPath)
+			if err != nil {
+				return nil, err
+			}
+			mappedModuleBucket = storage.FilterReadBucket(
+				mappedModuleBucket,
+				storage.MatchNot(storage.MatchPathContained(vendorRelDirPath)),
+			)
+		}
 		moduleSetBuilder.AddLocalModule(
 			mappedModuleBucket,
 			moduleBucketAndTargeting.bucketID,
@@ -460,6 +512,102 @@ func (w *workspaceProvider) getWorkspaceForBucketBufYAMLV2(
 	)
 }
 
+// verifyDepSignatures verifies that each of the dependencies is signed by one of the
+// trusted signing keys.
+//
+// The Commits are read for the Digests of the dependencies, so a dependency whose Digest
+// does not match its Commit is also an error. If there are no trusted signing keys, this
+// is a no-op.
+func verifyDepSignatures(
+	ctx context.Context,
+	signatureCommitProvider bufmodule.CommitProvider,
+	trustedSigningKeys []bufmodule.PublicKey,
+	depModuleKeys []bufmodule.ModuleKey,
+) error {
+	if len(trustedSigningKeys) == 0 || len(depModuleKeys) == 0 {
+		return nil
+	}
+	commits, err := signatureCommitProvider.GetCommitsForModuleKeys(ctx, depModuleKeys)
+	if err != nil {
+		return err
+	}
+	if err := bufmodule.VerifyCommitSignatures(commits, trustedSigningKeys); err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+	return nil
+}
+
+// getBufLockFileDepModuleKeysV1Beta1OrV1 returns the dependencies in the v1beta1 or v1
+// buf.lock file of the module at the directory, or nil if there is no buf.lock file.
+func (w *workspaceProvider) getBufLockFileDepModuleKeysV1Beta1OrV1(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	moduleDirPath string,
+) ([]bufmodule.ModuleKey, error) {
+	bufLockFile, err := bufconfig.GetBufLockFileForPrefix(
+		ctx,
+		bucket, // Need to use the non-mapped bucket since the mapped bucket excludes the buf.lock
+		moduleDirPath,
+		bufconfig.BufLockFileWithDigestResolver(
+			func(ctx context.Context, remote string, commitID uuid.UUID) (bufmodule.Digest, error) {
+				commitKey, err := bufmodule.NewCommitKey(remote, commitID, bufmodule.DigestTypeB4)
+				if err != nil {
+					return nil, err
+				}
+				commits, err := w.commitProvider.GetCommitsForCommitKeys(ctx, []bufmodule.CommitKey{commitKey})
+				if err != nil {
+					return nil, err
+				}
+				return commits[0].ModuleKey().Digest()
+			},
+		),
+	)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	switch fileVersion := bufLockFile.FileVersion(); fileVersion {
+	case bufconfig.FileVersionV1Beta1, bufconfig.FileVersionV1:
+	case bufconfig.FileVersionV2:
+		return nil, errors.New("got a v2 buf.lock file for a v1 buf.yaml - this is not allowed, run buf mod update to update your buf.lock file")
+	default:
+		return nil, syserror.Newf("unknown FileVersion: %v", fileVersion)
+	}
+	return bufLockFile.DepModuleKeys(), nil
+}
+
+// getBufLockFileDepModuleKeysV2 returns the dependencies in the v2 buf.lock file, or nil
+// if there is no buf.lock file.
+func getBufLockFileDepModuleKeysV2(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+) ([]bufmodule.ModuleKey, error) {
+	bufLockFile, err := bufconfig.GetBufLockFileForPrefix(
+		ctx,
+		bucket,
+		// buf.lock files live next to the buf.yaml
+		".",
+		// We are not passing BufLockFileWithDigestResolver here because a buf.lock
+		// v2 is expected to have digests
+	)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	switch fileVersion := bufLockFile.FileVersion(); fileVersion {
+	case bufconfig.FileVersionV1Beta1, bufconfig.FileVersionV1:
+		return nil, fmt.Errorf("got a %s buf.lock file for a v2 buf.yaml", bufLockFile.FileVersion().String())
+	case bufconfig.FileVersionV2:
+	default:
+		return nil, syserror.Newf("unknown FileVersion: %v", fileVersion)
+	}
+	return bufLockFile.DepModuleKeys(), nil
+}
+
 // only use for workspaces created from buckets
 func (w *workspaceProvider) getWorkspaceForBucketModuleSet(
 	moduleSet bufmodule.ModuleSet,
@@ -531,3 +679,19 @@ func getLocalModuleDescription(pathDescription string, moduleConfig bufconfig.Mo
 	}
 	return description
 }
+
+// getBufYAMLFileLocalDirPath returns the directory on disk of the buf.yaml in the
+// directory of the bucket, or empty if the buf.yaml is not on disk.
+func getBufYAMLFileLocalDirPath(ctx context.Context, bucket storage.ReadBucket, dirPath string) (string, error) {
+	objectInfo, err := bucket.Stat(ctx, normalpath.Join(dirPath, bufconfig.DefaultBufYAMLFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	if localPath := objectInfo.LocalPath(); localPath != "" {
+		return filepath.Dir(localPath), nil
+	}
+	return "", nil
+}
@@ -30,16 +30,21 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/registry/token/tokendelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/registry/token/tokenget"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/registry/token/tokenlist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/affected"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/bufpluginv1"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/bufpluginv1beta1"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/bufpluginv2"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/docs"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/lsp"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/price"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/reflectexport"
 	betaplugindelete "github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/plugin/plugindelete"
 	betapluginpush "github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/plugin/pluginpush"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhookcreate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhookdelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhooklist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/signingkey/signingkeygenerate"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/signingkey/signingkeypublickey"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/stats"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/studioagent"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/breaking"
@@ -52,17 +57,25 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/convert"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/curl"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depgraph"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depoutdated"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depprune"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depsbom"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depupdate"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depvendor"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/dep/depwhy"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/export"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/format"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/generate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/lint"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/lsfiles"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/migrateedition"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modlsbreakingrules"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modlslintrules"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modopen"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/push"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/cache/cachels"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/cache/cacheprune"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/cache/cacheverify"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/module/modulecommit/modulecommitaddlabel"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/module/modulecommit/modulecommitinfo"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/module/modulecommit/modulecommitlist"
@@ -114,6 +127,7 @@ func NewRootCommand(name string) *appcmd.Command {
 		name,
 		appext.BuilderWithTimeout(120*time.Second),
 		appext.BuilderWithInterceptor(newErrorInterceptor()),
+		appext.BuilderWithInterceptor(bufcli.NewModuleProvidersInterceptor()),
 		appext.BuilderWithLoggerProvider(slogapp.LoggerProvider),
 	)
 	return &appcmd.Command{
@@ -126,6 +140,7 @@ func NewRootCommand(name string) *appcmd.Command {
 			build.NewCommand("build", builder),
 			export.NewCommand("export", builder),
 			format.NewCommand("format", builder),
+			migrateedition.NewCommand("migrate-edition", builder),
 			lint.NewCommand("lint", builder),
 			breaking.NewCommand("breaking", builder),
 			generate.NewCommand("generate", builder),
@@ -138,8 +153,12 @@ func NewRootCommand(name string) *appcmd.Command {
 				Short: "Work with dependencies",
 				SubCommands: []*appcmd.Command{
 					depgraph.NewCommand("graph", builder),
+					depoutdated.NewCommand("outdated", builder),
 					depprune.NewCommand("prune", builder, ``, false),
+					depsbom.NewCommand("sbom", builder),
 					depupdate.NewCommand("update", builder, ``, false),
+					depvendor.NewCommand("vendor", builder),
+					depwhy.NewCommand("why", builder),
 				},
 			},
 			{
@@ -183,6 +202,15 @@ func NewRootCommand(name string) *appcmd.Command {
 					registrylogout.NewCommand("logout", builder),
 					whoami.NewCommand("whoami", builder),
 					registrycc.NewCommand("cc", builder, ``, false),
+					{
+						Use:   "cache",
+						Short: "Manage the local cache of modules downloaded from the registry",
+						SubCommands: []*appcmd.Command{
+							cachels.NewCommand("ls", builder),
+							cacheprune.NewCommand("prune", builder),
+							cacheverify.NewCommand("verify", builder),
+						},
+					},
 					{
 						Use:        "commit",
 						Short:      `Manage a module's commits, all commands are deprecated and have moved to the "buf registry module commit" subcommands`,
@@ -272,9 +300,20 @@ func NewRootCommand(name string) *appcmd.Command {
 				Use:   "beta",
 				Short: "Beta commands. Unstable and likely to change",
 				SubCommands: []*appcmd.Command{
+					affected.NewCommand("affected", builder),
+					docs.NewCommand("docs", builder),
 					lsp.NewCommand("lsp", builder),
 					price.NewCommand("price", builder),
+					reflectexport.NewCommand("reflect-export", builder),
 					stats.NewCommand("stats", builder),
+					{
+						Use:   "signing-key",
+						Short: "Manage keys for signing pushed modules",
+						SubCommands: []*appcmd.Command{
+							signingkeygenerate.NewCommand("generate", builder),
+							signingkeypublickey.NewCommand("public-key", builder),
+						},
+					},
 					bufpluginv1beta1.NewCommand("buf-plugin-v1beta1", builder),
 					bufpluginv1.NewCommand("buf-plugin-v1", builder),
 					bufpluginv2.NewCommand("buf-plugin-v2", builder),
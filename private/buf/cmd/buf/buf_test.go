@@ -98,6 +98,8 @@ var (
 		{ID: "COMMENT_SERVICE", Categories: []string{"COMMENTS"}, Default: false, Purpose: "Checks that services have non-empty comments."},
 		{ID: "RPC_NO_CLIENT_STREAMING", Categories: []string{"UNARY_RPC"}, Default: false, Purpose: "Checks that RPCs are not client streaming."},
 		{ID: "RPC_NO_SERVER_STREAMING", Categories: []string{"UNARY_RPC"}, Default: false, Purpose: "Checks that RPCs are not server streaming."},
+		{ID: "FEATURE_NO_LEGACY_VALUE", Categories: []string{}, Default: false, Purpose: "Checks that features are not set to values that only exist for compatibility with proto2 and proto3."},
+		{ID: "FEATURE_NO_REDUNDANT_OVERRIDE", Categories: []string{}, Default: false, Purpose: "Checks that features are not set to the value they would already inherit."},
 		{ID: "STABLE_PACKAGE_NO_IMPORT_UNSTABLE", Categories: []string{}, Default: false, Purpose: "Checks that all files that have stable versioned packages do not import packages with unstable version packages."},
 	}
 	// ordered, contains non-default
@@ -166,6 +168,18 @@ var (
 		{ID: "FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED", Categories: []string{"WIRE_JSON", "WIRE"}, Default: false, Purpose: "Checks that fields are not deleted from a given message unless the number is reserved."},
 		{ID: "FIELD_WIRE_COMPATIBLE_CARDINALITY", Categories: []string{"WIRE"}, Default: false, Purpose: "Checks that fields have wire-compatible cardinalities in a given message."},
 		{ID: "FIELD_WIRE_COMPATIBLE_TYPE", Categories: []string{"WIRE"}, Default: false, Purpose: "Checks that fields have wire-compatible types in a given message."},
+		{ID: "FIELD_PROTOVALIDATE_NO_ADD_CEL", Categories: []string{"PROTOVALIDATE_CONSTRAINTS"}, Default: false, Purpose: "Checks that fields do not have protovalidate CEL constraints added or changed."},
+		{ID: "FIELD_PROTOVALIDATE_NO_ADD_REQUIRED", Categories: []string{"PROTOVALIDATE_CONSTRAINTS"}, Default: false, Purpose: "Checks that fields do not become required with protovalidate."},
+		{ID: "FIELD_PROTOVALIDATE_NO_NARROWED_RULES", Categories: []string{"PROTOVALIDATE_CONSTRAINTS"}, Default: false, Purpose: "Checks that fields do not have protovalidate rules that narrow the accepted values."},
+		{ID: "MESSAGE_PROTOVALIDATE_NO_ADD_CEL", Categories: []string{"PROTOVALIDATE_CONSTRAINTS"}, Default: false, Purpose: "Checks that messages do not have protovalidate CEL constraints added or changed."},
+		{ID: "MESSAGE_PROTOVALIDATE_NO_REMOVE_DISABLED", Categories: []string{"PROTOVALIDATE_CONSTRAINTS"}, Default: false, Purpose: "Checks that messages do not have protovalidate validation re-enabled by removing the disabled option."},
+		{ID: "ONEOF_PROTOVALIDATE_NO_ADD_REQUIRED", Categories: []string{"PROTOVALIDATE_CONSTRAINTS"}, Default: false, Purpose: "Checks that oneofs do not become required with protovalidate."},
+		{ID: "ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED", Categories: []string{}, Default: false, Purpose: "Checks that enum values are not deleted from a given enum unless they were deprecated."},
+		{ID: "ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED", Categories: []string{}, Default: false, Purpose: "Checks that enum values are not deleted from a given enum unless they were deprecated and the number and name are reserved."},
+		{ID: "FIELD_NO_DELETE_UNLESS_DEPRECATED", Categories: []string{}, Default: false, Purpose: "Checks that fields are not deleted from a given message unless they were deprecated."},
+		{ID: "FIELD_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED", Categories: []string{}, Default: false, Purpose: "Checks that fields are not deleted from a given message unless they were deprecated and the number and name are reserved."},
+		{ID: "MESSAGE_NO_DELETE_UNLESS_DEPRECATED", Categories: []string{}, Default: false, Purpose: "Checks that messages are not deleted from a given file unless they were deprecated."},
+		{ID: "RPC_NO_DELETE_UNLESS_DEPRECATED", Categories: []string{}, Default: false, Purpose: "Checks that rpcs are not deleted from a given service unless they were deprecated."},
 	}
 )
 
@@ -663,6 +677,36 @@ func TestFailCheckBreaking5(t *testing.T) {
 	)
 }
 
+func TestFailCheckBreakingMultipleAgainst(t *testing.T) {
+	t.Parallel()
+	againstFile := filepath.Join("testdata", "protofileref", "breaking", "b", "foo.proto")
+	againstDir := filepath.Join("testdata", "protofileref", "breaking", "b")
+	testRunStdout(
+		t,
+		nil,
+		bufctl.ExitCodeFileAnnotation,
+		fmt.Sprintf(
+			`
+			<input>:1:1:Previously present file "bar.proto" was deleted. (against %q)
+			%s:7:3:Field "2" with name "world" on message "Foo" changed type from "int32" to "string". (against %q, %q)
+			`,
+			againstDir,
+			filepath.Join("testdata", "protofileref", "breaking", "a", "foo.proto"),
+			againstFile,
+			againstDir,
+		),
+		"breaking",
+		filepath.Join("testdata", "protofileref", "breaking", "a", "foo.proto"),
+		"--against",
+		againstFile,
+		"--against",
+		againstDir,
+		// Duplicate baselines are only checked once.
+		"--against",
+		againstFile,
+	)
+}
+
 func TestCheckLsLintRulesModAll(t *testing.T) {
 	t.Parallel()
 	expectedStdout := `
@@ -964,6 +1008,8 @@ COMMENT_RPC                        COMMENTS                           Checks tha
 COMMENT_SERVICE                    COMMENTS                           Checks that services have non-empty comments.
 RPC_NO_CLIENT_STREAMING            UNARY_RPC                          Checks that RPCs are not client streaming.
 RPC_NO_SERVER_STREAMING            UNARY_RPC                          Checks that RPCs are not server streaming.
+FEATURE_NO_LEGACY_VALUE                                               Checks that features are not set to values that only exist for compatibility with proto2 and proto3.
+FEATURE_NO_REDUNDANT_OVERRIDE                                         Checks that features are not set to the value they would already inherit.
 STABLE_PACKAGE_NO_IMPORT_UNSTABLE                                     Checks that all files that have stable versioned packages do not import packages with unstable version packages.
 		`
 	testRunStdout(
@@ -1135,71 +1181,83 @@ FIELD_WIRE_COMPATIBLE_CARDINALITY               WIRE
 func TestCheckLsBreakingRulesV2(t *testing.T) {
 	t.Parallel()
 	expectedStdout := `
-ID                                              CATEGORIES                      DEFAULT  PURPOSE
-ENUM_NO_DELETE                                  FILE                            *        Checks that enums are not deleted from a given file.
-EXTENSION_NO_DELETE                             FILE                            *        Checks that extensions are not deleted from a given file.
-FILE_NO_DELETE                                  FILE                            *        Checks that files are not deleted.
-MESSAGE_NO_DELETE                               FILE                            *        Checks that messages are not deleted from a given file.
-SERVICE_NO_DELETE                               FILE                            *        Checks that services are not deleted from a given file.
-ENUM_SAME_TYPE                                  FILE, PACKAGE                   *        Checks that enums have the same type (open vs closed).
-ENUM_VALUE_NO_DELETE                            FILE, PACKAGE                   *        Checks that enum values are not deleted from a given enum.
-EXTENSION_MESSAGE_NO_DELETE                     FILE, PACKAGE                   *        Checks that extension ranges are not deleted from a given message.
-FIELD_NO_DELETE                                 FILE, PACKAGE                   *        Checks that fields are not deleted from a given message.
-FIELD_SAME_CARDINALITY                          FILE, PACKAGE                   *        Checks that fields have the same cardinalities in a given message.
-FIELD_SAME_CPP_STRING_TYPE                      FILE, PACKAGE                   *        Checks that fields have the same C++ string type, based on ctype field option or (pb.cpp).string_type feature.
-FIELD_SAME_JAVA_UTF8_VALIDATION                 FILE, PACKAGE                   *        Checks that fields have the same Java string UTF8 validation, based on java_string_check_utf8 file option or (pb.java).utf8_validation feature.
-FIELD_SAME_JSTYPE                               FILE, PACKAGE                   *        Checks that fields have the same value for the jstype option.
-FIELD_SAME_TYPE                                 FILE, PACKAGE                   *        Checks that fields have the same types in a given message.
-FIELD_SAME_UTF8_VALIDATION                      FILE, PACKAGE                   *        Checks that string fields have the same UTF8 validation mode.
-FILE_SAME_CC_ENABLE_ARENAS                      FILE, PACKAGE                   *        Checks that files have the same value for the cc_enable_arenas option.
-FILE_SAME_CC_GENERIC_SERVICES                   FILE, PACKAGE                   *        Checks that files have the same value for the cc_generic_services option.
-FILE_SAME_CSHARP_NAMESPACE                      FILE, PACKAGE                   *        Checks that files have the same value for the csharp_namespace option.
-FILE_SAME_GO_PACKAGE                            FILE, PACKAGE                   *        Checks that files have the same value for the go_package option.
-FILE_SAME_JAVA_GENERIC_SERVICES                 FILE, PACKAGE                   *        Checks that files have the same value for the java_generic_services option.
-FILE_SAME_JAVA_MULTIPLE_FILES                   FILE, PACKAGE                   *        Checks that files have the same value for the java_multiple_files option.
-FILE_SAME_JAVA_OUTER_CLASSNAME                  FILE, PACKAGE                   *        Checks that files have the same value for the java_outer_classname option.
-FILE_SAME_JAVA_PACKAGE                          FILE, PACKAGE                   *        Checks that files have the same value for the java_package option.
-FILE_SAME_OBJC_CLASS_PREFIX                     FILE, PACKAGE                   *        Checks that files have the same value for the objc_class_prefix option.
-FILE_SAME_OPTIMIZE_FOR                          FILE, PACKAGE                   *        Checks that files have the same value for the optimize_for option.
-FILE_SAME_PHP_CLASS_PREFIX                      FILE, PACKAGE                   *        Checks that files have the same value for the php_class_prefix option.
-FILE_SAME_PHP_METADATA_NAMESPACE                FILE, PACKAGE                   *        Checks that files have the same value for the php_metadata_namespace option.
-FILE_SAME_PHP_NAMESPACE                         FILE, PACKAGE                   *        Checks that files have the same value for the php_namespace option.
-FILE_SAME_PY_GENERIC_SERVICES                   FILE, PACKAGE                   *        Checks that files have the same value for the py_generic_services option.
-FILE_SAME_RUBY_PACKAGE                          FILE, PACKAGE                   *        Checks that files have the same value for the ruby_package option.
-FILE_SAME_SWIFT_PREFIX                          FILE, PACKAGE                   *        Checks that files have the same value for the swift_prefix option.
-FILE_SAME_SYNTAX                                FILE, PACKAGE                   *        Checks that files have the same syntax.
-MESSAGE_NO_REMOVE_STANDARD_DESCRIPTOR_ACCESSOR  FILE, PACKAGE                   *        Checks that messages do not change the no_standard_descriptor_accessor option from false or unset to true.
-ONEOF_NO_DELETE                                 FILE, PACKAGE                   *        Checks that oneofs are not deleted from a given message.
-RPC_NO_DELETE                                   FILE, PACKAGE                   *        Checks that rpcs are not deleted from a given service.
-ENUM_SAME_JSON_FORMAT                           FILE, PACKAGE, WIRE_JSON        *        Checks that enums have the same JSON format support.
-ENUM_VALUE_SAME_NAME                            FILE, PACKAGE, WIRE_JSON        *        Checks that enum values have the same name.
-FIELD_SAME_JSON_NAME                            FILE, PACKAGE, WIRE_JSON        *        Checks that fields have the same value for the json_name option.
-FIELD_SAME_NAME                                 FILE, PACKAGE, WIRE_JSON        *        Checks that fields have the same names in a given message.
-MESSAGE_SAME_JSON_FORMAT                        FILE, PACKAGE, WIRE_JSON        *        Checks that messages have the same JSON format support.
-FIELD_SAME_DEFAULT                              FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that fields have the same default value, if a default is specified.
-FIELD_SAME_ONEOF                                FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that fields have the same oneofs in a given message.
-FILE_SAME_PACKAGE                               FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that files have the same package.
-MESSAGE_SAME_REQUIRED_FIELDS                    FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that messages have no added or deleted required fields.
-RESERVED_ENUM_NO_DELETE                         FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that reserved ranges and names are not deleted from a given enum.
-RESERVED_MESSAGE_NO_DELETE                      FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that reserved ranges and names are not deleted from a given message.
-RPC_SAME_CLIENT_STREAMING                       FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs have the same client streaming value.
-RPC_SAME_IDEMPOTENCY_LEVEL                      FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs have the same value for the idempotency_level option.
-RPC_SAME_REQUEST_TYPE                           FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs are have the same request type.
-RPC_SAME_RESPONSE_TYPE                          FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs are have the same response type.
-RPC_SAME_SERVER_STREAMING                       FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs have the same server streaming value.
-PACKAGE_ENUM_NO_DELETE                          PACKAGE                                  Checks that enums are not deleted from a given package.
-PACKAGE_EXTENSION_NO_DELETE                     PACKAGE                                  Checks that extensions are not deleted from a given package.
-PACKAGE_MESSAGE_NO_DELETE                       PACKAGE                                  Checks that messages are not deleted from a given package.
-PACKAGE_NO_DELETE                               PACKAGE                                  Checks that packages are not deleted.
-PACKAGE_SERVICE_NO_DELETE                       PACKAGE                                  Checks that services are not deleted from a given package.
-ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED       WIRE_JSON                                Checks that enum values are not deleted from a given enum unless the name is reserved.
-FIELD_NO_DELETE_UNLESS_NAME_RESERVED            WIRE_JSON                                Checks that fields are not deleted from a given message unless the name is reserved.
-FIELD_WIRE_JSON_COMPATIBLE_CARDINALITY          WIRE_JSON                                Checks that fields have wire and JSON compatible cardinalities in a given message.
-FIELD_WIRE_JSON_COMPATIBLE_TYPE                 WIRE_JSON                                Checks that fields have wire and JSON compatible types in a given message.
-ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED     WIRE_JSON, WIRE                          Checks that enum values are not deleted from a given enum unless the number is reserved.
-FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED          WIRE_JSON, WIRE                          Checks that fields are not deleted from a given message unless the number is reserved.
-FIELD_WIRE_COMPATIBLE_CARDINALITY               WIRE                                     Checks that fields have wire-compatible cardinalities in a given message.
-FIELD_WIRE_COMPATIBLE_TYPE                      WIRE                                     Checks that fields have wire-compatible types in a given message.
+ID                                                   CATEGORIES                      DEFAULT  PURPOSE
+ENUM_NO_DELETE                                       FILE                            *        Checks that enums are not deleted from a given file.
+EXTENSION_NO_DELETE                                  FILE                            *        Checks that extensions are not deleted from a given file.
+FILE_NO_DELETE                                       FILE                            *        Checks that files are not deleted.
+MESSAGE_NO_DELETE                                    FILE                            *        Checks that messages are not deleted from a given file.
+SERVICE_NO_DELETE                                    FILE                            *        Checks that services are not deleted from a given file.
+ENUM_SAME_TYPE                                       FILE, PACKAGE                   *        Checks that enums have the same type (open vs closed).
+ENUM_VALUE_NO_DELETE                                 FILE, PACKAGE                   *        Checks that enum values are not deleted from a given enum.
+EXTENSION_MESSAGE_NO_DELETE                          FILE, PACKAGE                   *        Checks that extension ranges are not deleted from a given message.
+FIELD_NO_DELETE                                      FILE, PACKAGE                   *        Checks that fields are not deleted from a given message.
+FIELD_SAME_CARDINALITY                               FILE, PACKAGE                   *        Checks that fields have the same cardinalities in a given message.
+FIELD_SAME_CPP_STRING_TYPE                           FILE, PACKAGE                   *        Checks that fields have the same C++ string type, based on ctype field option or (pb.cpp).string_type feature.
+FIELD_SAME_JAVA_UTF8_VALIDATION                      FILE, PACKAGE                   *        Checks that fields have the same Java string UTF8 validation, based on java_string_check_utf8 file option or (pb.java).utf8_validation feature.
+FIELD_SAME_JSTYPE                                    FILE, PACKAGE                   *        Checks that fields have the same value for the jstype option.
+FIELD_SAME_TYPE                                      FILE, PACKAGE                   *        Checks that fields have the same types in a given message.
+FIELD_SAME_UTF8_VALIDATION                           FILE, PACKAGE                   *        Checks that string fields have the same UTF8 validation mode.
+FILE_SAME_CC_ENABLE_ARENAS                           FILE, PACKAGE                   *        Checks that files have the same value for the cc_enable_arenas option.
+FILE_SAME_CC_GENERIC_SERVICES                        FILE, PACKAGE                   *        Checks that files have the same value for the cc_generic_services option.
+FILE_SAME_CSHARP_NAMESPACE                           FILE, PACKAGE                   *        Checks that files have the same value for the csharp_namespace option.
+FILE_SAME_GO_PACKAGE                                 FILE, PACKAGE                   *        Checks that files have the same value for the go_package option.
+FILE_SAME_JAVA_GENERIC_SERVICES                      FILE, PACKAGE                   *        Checks that files have the same value for the java_generic_services option.
+FILE_SAME_JAVA_MULTIPLE_FILES                        FILE, PACKAGE                   *        Checks that files have the same value for the java_multiple_files option.
+FILE_SAME_JAVA_OUTER_CLASSNAME                       FILE, PACKAGE                   *        Checks that files have the same value for the java_outer_classname option.
+FILE_SAME_JAVA_PACKAGE                               FILE, PACKAGE                   *        Checks that files have the same value for the java_package option.
+FILE_SAME_OBJC_CLASS_PREFIX                          FILE, PACKAGE                   *        Checks that files have the same value for the objc_class_prefix option.
+FILE_SAME_OPTIMIZE_FOR                               FILE, PACKAGE                   *        Checks that files have the same value for the optimize_for option.
+FILE_SAME_PHP_CLASS_PREFIX                           FILE, PACKAGE                   *        Checks that files have the same value for the php_class_prefix option.
+FILE_SAME_PHP_METADATA_NAMESPACE                     FILE, PACKAGE                   *        Checks that files have the same value for the php_metadata_namespace option.
+FILE_SAME_PHP_NAMESPACE                              FILE, PACKAGE                   *        Checks that files have the same value for the php_namespace option.
+FILE_SAME_PY_GENERIC_SERVICES                        FILE, PACKAGE                   *        Checks that files have the same value for the py_generic_services option.
+FILE_SAME_RUBY_PACKAGE                               FILE, PACKAGE                   *        Checks that files have the same value for the ruby_package option.
+FILE_SAME_SWIFT_PREFIX                               FILE, PACKAGE                   *        Checks that files have the same value for the swift_prefix option.
+FILE_SAME_SYNTAX                                     FILE, PACKAGE                   *        Checks that files have the same syntax.
+MESSAGE_NO_REMOVE_STANDARD_DESCRIPTOR_ACCESSOR       FILE, PACKAGE                   *        Checks that messages do not change the no_standard_descriptor_accessor option from false or unset to true.
+ONEOF_NO_DELETE                                      FILE, PACKAGE                   *        Checks that oneofs are not deleted from a given message.
+RPC_NO_DELETE                                        FILE, PACKAGE                   *        Checks that rpcs are not deleted from a given service.
+ENUM_SAME_JSON_FORMAT                                FILE, PACKAGE, WIRE_JSON        *        Checks that enums have the same JSON format support.
+ENUM_VALUE_SAME_NAME                                 FILE, PACKAGE, WIRE_JSON        *        Checks that enum values have the same name.
+FIELD_SAME_JSON_NAME                                 FILE, PACKAGE, WIRE_JSON        *        Checks that fields have the same value for the json_name option.
+FIELD_SAME_NAME                                      FILE, PACKAGE, WIRE_JSON        *        Checks that fields have the same names in a given message.
+MESSAGE_SAME_JSON_FORMAT                             FILE, PACKAGE, WIRE_JSON        *        Checks that messages have the same JSON format support.
+FIELD_SAME_DEFAULT                                   FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that fields have the same default value, if a default is specified.
+FIELD_SAME_ONEOF                                     FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that fields have the same oneofs in a given message.
+FILE_SAME_PACKAGE                                    FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that files have the same package.
+MESSAGE_SAME_REQUIRED_FIELDS                         FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that messages have no added or deleted required fields.
+RESERVED_ENUM_NO_DELETE                              FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that reserved ranges and names are not deleted from a given enum.
+RESERVED_MESSAGE_NO_DELETE                           FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that reserved ranges and names are not deleted from a given message.
+RPC_SAME_CLIENT_STREAMING                            FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs have the same client streaming value.
+RPC_SAME_IDEMPOTENCY_LEVEL                           FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs have the same value for the idempotency_level option.
+RPC_SAME_REQUEST_TYPE                                FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs are have the same request type.
+RPC_SAME_RESPONSE_TYPE                               FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs are have the same response type.
+RPC_SAME_SERVER_STREAMING                            FILE, PACKAGE, WIRE_JSON, WIRE  *        Checks that rpcs have the same server streaming value.
+PACKAGE_ENUM_NO_DELETE                               PACKAGE                                  Checks that enums are not deleted from a given package.
+PACKAGE_EXTENSION_NO_DELETE                          PACKAGE                                  Checks that extensions are not deleted from a given package.
+PACKAGE_MESSAGE_NO_DELETE                            PACKAGE                                  Checks that messages are not deleted from a given package.
+PACKAGE_NO_DELETE                                    PACKAGE                                  Checks that packages are not deleted.
+PACKAGE_SERVICE_NO_DELETE                            PACKAGE                                  Checks that services are not deleted from a given package.
+ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED            WIRE_JSON                                Checks that enum values are not deleted from a given enum unless the name is reserved.
+FIELD_NO_DELETE_UNLESS_NAME_RESERVED                 WIRE_JSON                                Checks that fields are not deleted from a given message unless the name is reserved.
+FIELD_WIRE_JSON_COMPATIBLE_CARDINALITY               WIRE_JSON                                Checks that fields have wire and JSON compatible cardinalities in a given message.
+FIELD_WIRE_JSON_COMPATIBLE_TYPE                      WIRE_JSON                                Checks that fields have wire and JSON compatible types in a given message.
+ENUM_VALUE_NO_DELETE_UNLESS_NUMBER_RESERVED          WIRE_JSON, WIRE                          Checks that enum values are not deleted from a given enum unless the number is reserved.
+FIELD_NO_DELETE_UNLESS_NUMBER_RESERVED               WIRE_JSON, WIRE                          Checks that fields are not deleted from a given message unless the number is reserved.
+FIELD_WIRE_COMPATIBLE_CARDINALITY                    WIRE                                     Checks that fields have wire-compatible cardinalities in a given message.
+FIELD_WIRE_COMPATIBLE_TYPE                           WIRE                                     Checks that fields have wire-compatible types in a given message.
+FIELD_PROTOVALIDATE_NO_ADD_CEL                       PROTOVALIDATE_CONSTRAINTS                Checks that fields do not have protovalidate CEL constraints added or changed.
+FIELD_PROTOVALIDATE_NO_ADD_REQUIRED                  PROTOVALIDATE_CONSTRAINTS                Checks that fields do not become required with protovalidate.
+FIELD_PROTOVALIDATE_NO_NARROWED_RULES                PROTOVALIDATE_CONSTRAINTS                Checks that fields do not have protovalidate rules that narrow the accepted values.
+MESSAGE_PROTOVALIDATE_NO_ADD_CEL                     PROTOVALIDATE_CONSTRAINTS                Checks that messages do not have protovalidate CEL constraints added or changed.
+MESSAGE_PROTOVALIDATE_NO_REMOVE_DISABLED             PROTOVALIDATE_CONSTRAINTS                Checks that messages do not have protovalidate validation re-enabled by removing the disabled option.
+ONEOF_PROTOVALIDATE_NO_ADD_REQUIRED                  PROTOVALIDATE_CONSTRAINTS                Checks that oneofs do not become required with protovalidate.
+ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED                                                        Checks that enum values are not deleted from a given enum unless they were deprecated.
+ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED                                           Checks that enum values are not deleted from a given enum unless they were deprecated and the number and name are reserved.
+FIELD_NO_DELETE_UNLESS_DEPRECATED                                                             Checks that fields are not deleted from a given message unless they were deprecated.
+FIELD_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED                                                Checks that fields are not deleted from a given message unless they were deprecated and the number and name are reserved.
+MESSAGE_NO_DELETE_UNLESS_DEPRECATED                                                           Checks that messages are not deleted from a given file unless they were deprecated.
+RPC_NO_DELETE_UNLESS_DEPRECATED                                                               Checks that rpcs are not deleted from a given service unless they were deprecated.
 		`
 	testRunStdout(
 		t,
@@ -2660,6 +2718,79 @@ func TestExportExcludeImports(t *testing.T) {
 	)
 }
 
+func TestExportImage(t *testing.T) {
+	t.Parallel()
+	imagePath := filepath.Join(t.TempDir(), "image.binpb")
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"build",
+		"-o",
+		imagePath,
+		filepath.Join("testdata", "export", "proto"),
+	)
+	tempDir := t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"export",
+		"-o",
+		tempDir,
+		imagePath,
+	)
+	readWriteBucket, err := storageos.NewProvider().NewReadWriteBucket(tempDir)
+	require.NoError(t, err)
+	storagetesting.AssertPaths(
+		t,
+		readWriteBucket,
+		"",
+		"request.proto",
+		"rpc.proto",
+	)
+	// Images do not contain the original files, so the files are reconstructed and formatted.
+	data, err := os.ReadFile(filepath.Join(tempDir, "rpc.proto"))
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`syntax = "proto3";
+
+package example;
+
+import "request.proto";
+
+message RPC {
+  request.Request req = 1;
+}
+`,
+		string(data),
+	)
+
+	tempDir = t.TempDir()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"export",
+		"--exclude-imports",
+		"-o",
+		tempDir,
+		imagePath,
+	)
+	readWriteBucket, err = storageos.NewProvider().NewReadWriteBucket(tempDir)
+	require.NoError(t, err)
+	storagetesting.AssertPaths(
+		t,
+		readWriteBucket,
+		"",
+		"rpc.proto",
+	)
+}
+
 func TestExportPaths(t *testing.T) {
 	t.Parallel()
 	tempDir := t.TempDir()
@@ -4055,6 +4186,81 @@ func TestFormatExitCode(t *testing.T) {
 
 // Tests if the image produced by the formatted result is
 // equivalent to the original result.
+func TestMigrateEdition(t *testing.T) {
+	t.Parallel()
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`edition = "2023";
+
+package a.v1;
+
+option features.field_presence = IMPLICIT;
+
+message Foo {
+  int32 one = 1;
+  int32 two = 2 [features.field_presence = EXPLICIT];
+  repeated int32 three = 3 [features.repeated_field_encoding = EXPANDED];
+}`,
+		"migrate-edition",
+		filepath.Join("testdata", "migrateedition", "proto3"),
+	)
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		bufctl.ExitCodeFileAnnotation,
+		nil,
+		stdout,
+		"migrate-edition",
+		filepath.Join("testdata", "migrateedition", "proto3"),
+		"-d",
+		"--exit-code",
+	)
+	assert.Contains(t, stdout.String(), `+edition = "2023";`)
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{`group "a.v1.Foo.bar" cannot be migrated, replace it with a message field first`},
+		"migrate-edition",
+		filepath.Join("testdata", "migrateedition", "group"),
+	)
+}
+
+func TestMigrateEditionWrite(t *testing.T) {
+	t.Parallel()
+	tempDir := t.TempDir()
+	for _, fileName := range []string{"buf.yaml", "a.proto"} {
+		data, err := os.ReadFile(filepath.Join("testdata", "migrateedition", "proto3", fileName))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, fileName), data, 0600))
+	}
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"migrate-edition",
+		tempDir,
+		"-w",
+	)
+	data, err := os.ReadFile(filepath.Join(tempDir, "a.proto"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `edition = "2023";`)
+	// Files that already use Editions are left unchanged.
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"migrate-edition",
+		tempDir,
+		"-d",
+		"--exit-code",
+	)
+}
+
 func TestFormatEquivalence(t *testing.T) {
 	t.Parallel()
 	tempDir := t.TempDir()
@@ -0,0 +1,407 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package affected
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/git"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	againstFlagName         = "against"
+	templateFlagName        = "template"
+	formatFlagName          = "format"
+	errorFormatFlagName     = "error-format"
+	disableSymlinksFlagName = "disable-symlinks"
+
+	defaultTemplatePath = "buf.gen.yaml"
+)
+
+// configFileNames are the names of the configuration files that affect all files of the
+// modules in or below their directory.
+var configFileNames = []string{
+	bufconfig.DefaultBufYAMLFileName,
+	bufconfig.DefaultBufLockFileName,
+	bufconfig.DefaultBufWorkYAMLFileName,
+}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <source>",
+		Short: "Print the modules, files, and plugins affected by changes since a git ref",
+		Long: `The files that changed since the git ref given by --` + againstFlagName + ` are listed with git
+from the current directory, including uncommitted and untracked files. Deleted files are listed
+as affected if they were within a module, so that buf breaking checks them. A change to a buf.yaml,
+buf.lock, or buf.work.yaml file affects every file of the modules in or below its directory.
+The affected files are then expanded to every file of the workspace that transitively imports
+an affected file.
+
+The plugins of the buf.gen.yaml template are affected if any file is affected. If the template
+itself changed, every file of the workspace is affected.
+
+The files are printed relative to the current directory. The JSON output can be passed to
+buf lint, buf breaking, and buf generate with --affected to restrict them to the affected files:
+
+    $ buf beta affected --against main --format json > affected.json
+    $ buf lint --affected affected.json
+    $ buf breaking --against '.git#branch=main' --affected affected.json
+    $ buf generate --affected affected.json
+
+The first argument is the source to check, which must be a local directory.
+If no argument is specified, defaults to ".".`,
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Against         string
+	Template        string
+	Format          string
+	ErrorFormat     string
+	DisableSymlinks bool
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.Against,
+		againstFlagName,
+		"",
+		`The git ref to compare against, such as "main" or "HEAD~1". Required`,
+	)
+	flagSet.StringVar(
+		&f.Template,
+		templateFlagName,
+		"",
+		fmt.Sprintf(
+			`The buf.gen.yaml template to list the affected plugins of. Defaults to %q if it exists`,
+			defaultTemplatePath,
+		),
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	if flags.Against == "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s is required", againstFlagName)
+	}
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	templatePath := flags.Template
+	if templatePath == "" {
+		if _, err := os.Stat(defaultTemplatePath); err == nil {
+			templatePath = defaultTemplatePath
+		}
+	}
+	var pluginNames []string
+	if templatePath != "" {
+		pluginNames, err = getPluginNames(templatePath)
+		if err != nil {
+			return err
+		}
+	}
+	changedPaths, err := git.NewLister().ListChangedFiles(ctx, container, flags.Against)
+	if err != nil {
+		return err
+	}
+	controller, err := bufcli.NewController(
+		container,
+		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
+		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
+	)
+	if err != nil {
+		return err
+	}
+	workspace, err := controller.GetWorkspace(ctx, input)
+	if err != nil {
+		return err
+	}
+	affectedFiles, err := getAffectedFiles(
+		ctx,
+		workspace,
+		slicesext.Map(changedPaths, normalpath.Normalize),
+		templatePath,
+	)
+	if err != nil {
+		return err
+	}
+	affected := &bufcli.Affected{
+		Modules: []string{},
+		Files:   []string{},
+		Plugins: []string{},
+	}
+	moduleNames := make(map[string]struct{})
+	for _, affectedFile := range affectedFiles {
+		moduleNames[moduleToString(affectedFile.module)] = struct{}{}
+		affected.Files = append(affected.Files, affectedFile.localPath)
+	}
+	affected.Modules = slicesext.MapKeysToSortedSlice(moduleNames)
+	slices.Sort(affected.Files)
+	if len(affected.Files) > 0 {
+		affected.Plugins = append(affected.Plugins, pluginNames...)
+	}
+	switch format {
+	case bufprint.FormatText:
+		var lines []string
+		for _, module := range affected.Modules {
+			lines = append(lines, "module "+module)
+		}
+		for _, file := range affected.Files {
+			lines = append(lines, "file "+file)
+		}
+		for _, plugin := range affected.Plugins {
+			lines = append(lines, "plugin "+plugin)
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		_, err := fmt.Fprintln(container.Stdout(), strings.Join(lines, "\n"))
+		return err
+	case bufprint.FormatJSON:
+		return json.NewEncoder(container.Stdout()).Encode(affected)
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+type affectedFile struct {
+	module    bufmodule.Module
+	path      string
+	localPath string
+}
+
+// getAffectedFiles returns the files of the local Modules of the ModuleSet that are
+// affected by the changed paths.
+//
+// The changed paths are normalized and relative to the current directory.
+func getAffectedFiles(
+	ctx context.Context,
+	moduleSet bufmodule.ModuleSet,
+	changedPaths []string,
+	templatePath string,
+) ([]*affectedFile, error) {
+	var localFiles []*affectedFile
+	localPathToFile := make(map[string]*affectedFile)
+	pathToLocalFile := make(map[string]*affectedFile)
+	importPathToImporters := make(map[string][]*affectedFile)
+	if err := bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(moduleSet).WalkFileInfos(
+		ctx,
+		func(fileInfo bufmodule.FileInfo) error {
+			if !fileInfo.Module().IsLocal() || fileInfo.LocalPath() == "" {
+				return nil
+			}
+			localPath, err := getRelativeLocalPath(fileInfo.LocalPath())
+			if err != nil {
+				return err
+			}
+			file := &affectedFile{
+				module:    fileInfo.Module(),
+				path:      fileInfo.Path(),
+				localPath: localPath,
+			}
+			localFiles = append(localFiles, file)
+			localPathToFile[localPath] = file
+			pathToLocalFile[file.path] = file
+			importPaths, err := fileInfo.ProtoFileImports()
+			if err != nil {
+				return err
+			}
+			for _, importPath := range importPaths {
+				importPathToImporters[importPath] = append(importPathToImporters[importPath], file)
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	moduleDirPathToModule := make(map[string]bufmodule.Module)
+	for _, file := range localFiles {
+		moduleDirPathToModule[getModuleDirPath(file)] = file.module
+	}
+	moduleDirPaths := slicesext.MapKeysToSortedSlice(moduleDirPathToModule)
+	affectedLocalPaths := make(map[string]struct{})
+	var queue []*affectedFile
+	addAffected := func(file *affectedFile) {
+		if _, ok := affectedLocalPaths[file.localPath]; !ok {
+			affectedLocalPaths[file.localPath] = struct{}{}
+			queue = append(queue, file)
+		}
+	}
+	normalizedTemplatePath := normalpath.Normalize(templatePath)
+	for _, changedPath := range changedPaths {
+		if file, ok := localPathToFile[changedPath]; ok {
+			addAffected(file)
+			continue
+		}
+		if templatePath != "" && changedPath == normalizedTemplatePath {
+			for _, file := range localFiles {
+				addAffected(file)
+			}
+			continue
+		}
+		if normalpath.Ext(changedPath) == ".proto" {
+			// The file was deleted. It is affected if it was within a local Module, and
+			// its importers are affected as well. The most nested Module directory wins.
+			for i := len(moduleDirPaths) - 1; i >= 0; i-- {
+				moduleDirPath := moduleDirPaths[i]
+				if !normalpath.ContainsPath(moduleDirPath, changedPath, normalpath.Relative) {
+					continue
+				}
+				path, err := normalpath.Rel(moduleDirPath, changedPath)
+				if err != nil {
+					return nil, err
+				}
+				addAffected(
+					&affectedFile{
+						module:    moduleDirPathToModule[moduleDirPath],
+						path:      path,
+						localPath: changedPath,
+					},
+				)
+				break
+			}
+			continue
+		}
+		if slices.Contains(configFileNames, normalpath.Base(changedPath)) {
+			configDirPath := normalpath.Dir(changedPath)
+			for _, file := range localFiles {
+				if normalpath.EqualsOrContainsPath(configDirPath, getModuleDirPath(file), normalpath.Relative) {
+					addAffected(file)
+				}
+			}
+		}
+	}
+	var affectedFiles []*affectedFile
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		affectedFiles = append(affectedFiles, file)
+		for _, importer := range importPathToImporters[file.path] {
+			addAffected(importer)
+		}
+	}
+	return affectedFiles, nil
+}
+
+// getPluginNames returns the names of the plugins of the buf.gen.yaml template at the path.
+func getPluginNames(templatePath string) (_ []string, retErr error) {
+	file, err := os.Open(templatePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, appcmd.NewInvalidArgumentErrorf("--%s: %s does not exist", templateFlagName, templatePath)
+		}
+		return nil, err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, file.Close())
+	}()
+	bufGenYAMLFile, err := bufconfig.ReadBufGenYAMLFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return slicesext.Map(
+		bufGenYAMLFile.GenerateConfig().GeneratePluginConfigs(),
+		bufconfig.GeneratePluginConfig.Name,
+	), nil
+}
+
+// getRelativeLocalPath returns the normalized local path, relative to the current
+// directory if it is absolute.
+func getRelativeLocalPath(localPath string) (string, error) {
+	localPath = normalpath.Normalize(localPath)
+	if !strings.HasPrefix(localPath, "/") {
+		return localPath, nil
+	}
+	currentDirPath, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return normalpath.Rel(normalpath.Normalize(currentDirPath), localPath)
+}
+
+// getModuleDirPath returns the directory of the Module of the file, relative to the
+// current directory.
+func getModuleDirPath(file *affectedFile) string {
+	return normalpath.Normalize(strings.TrimSuffix(file.localPath, file.path))
+}
+
+func moduleToString(module bufmodule.Module) string {
+	if moduleFullName := module.FullName(); moduleFullName != nil {
+		return moduleFullName.String()
+	}
+	return module.OpaqueID()
+}
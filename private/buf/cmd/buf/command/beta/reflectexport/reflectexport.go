@@ -0,0 +1,349 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufcurl"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufformat"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/gen/data/datawkt"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/google/uuid"
+	"github.com/spf13/pflag"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	outputFlagName          = "output"
+	outputFlagShortName     = "o"
+	reflectProtocolFlagName = "reflect-protocol"
+	verboseFlagName         = "verbose"
+	verboseFlagShortName    = "v"
+)
+
+// reflectionServiceNames are the services of the reflection protocols, which are
+// not part of the schema of the server.
+var reflectionServiceNames = map[protoreflect.FullName]struct{}{
+	"grpc.reflection.v1.ServerReflection":      {},
+	"grpc.reflection.v1alpha.ServerReflection": {},
+}
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <url>",
+		Short: "Export the schema of a server using server reflection",
+		Long: `Export the schema of a running server using gRPC server reflection, so that it can be
+linted, diffed, and checked for breaking changes against a local module.
+
+The only positional argument is the base URL of the server. The files that define every service
+listed by the server are downloaded, along with all of their transitive imports. The services of
+the reflection protocols themselves are not exported.
+
+The connection is configured with the same flags as buf curl. Server reflection relies on
+bidirectional streaming, so it does not work with HTTP 1.1: the URL must use https, or the
+--http2-prior-knowledge flag must be set.
+
+If the output is an image, such as "schema.binpb" or "schema.json", the image is written to it.
+Otherwise, the output is a directory to which the .proto files are written, reconstructed from
+the descriptors and formatted. A buf.yaml file is written to the directory if it does not already
+contain one. Well-known types are not written to the directory, as they are always available.
+
+Examples:
+
+Export the schema of a server to an image:
+
+    $ buf beta reflect-export https://demo.connectrpc.com -o schema.binpb
+
+Export the schema of a plain-text gRPC server to a module, and check it for breaking changes
+against the module in the current directory:
+
+    $ buf beta reflect-export http://localhost:8080 --http2-prior-knowledge -o deployed
+    $ buf breaking . --against deployed
+`,
+		Args: appcmd.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Output          string
+	ReflectProtocol string
+	// Protocol, TLS, timeout, and header flags, shared with buf curl
+	Connection *bufcurl.ConnectionFlags
+	Verbose    bool
+}
+
+func newFlags() *flags {
+	return &flags{
+		Connection: bufcurl.NewConnectionFlags(
+			connect.ProtocolGRPC,
+			`Request headers to include with reflection requests. This flag may be specified more than once.
+Each flag value should have the form "name: value". A special value of '@<path>' means to read
+headers from the file at <path>. If the path is "-" then headers are read from stdin`,
+		),
+	}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	f.Connection.Bind(flagSet)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"",
+		`The output image or directory for the schema`,
+	)
+	_ = appcmd.MarkFlagRequired(flagSet, outputFlagName)
+	flagSet.StringVar(
+		&f.ReflectProtocol,
+		reflectProtocolFlagName,
+		"",
+		fmt.Sprintf(
+			`The reflection protocol to use. By default, all known protocols are tried from newest to oldest. Must be one of %s`,
+			stringutil.SliceToString(bufcurl.AllKnownReflectProtocolStrings),
+		),
+	)
+	flagSet.BoolVarP(
+		&f.Verbose,
+		verboseFlagName,
+		verboseFlagShortName,
+		false,
+		"Turn on verbose mode",
+	)
+}
+
+func (f *flags) validate(isSecure bool) error {
+	if !isSecure && !f.Connection.HTTP2PriorKnowledge {
+		return fmt.Errorf("server reflection cannot be used with plain-text URLs (http) unless --%s flag is set", bufcurl.HTTP2PriorKnowledgeFlagName)
+	}
+	return f.Connection.Validate(isSecure)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	baseURL := container.Arg(0)
+	endpointURL, err := url.Parse(baseURL)
+	if err != nil {
+		return appcmd.NewInvalidArgumentErrorf("%q is not a valid URL: %v", baseURL, err)
+	}
+	if endpointURL.Scheme != "http" && endpointURL.Scheme != "https" {
+		return appcmd.NewInvalidArgumentErrorf("invalid URL: scheme %q is not supported", endpointURL.Scheme)
+	}
+	isSecure := endpointURL.Scheme == "https"
+	if err := flags.validate(isSecure); err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	reflectProtocol, err := bufcurl.ParseReflectProtocol(flags.ReflectProtocol)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	outputRef, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, flags.Output)
+	if err != nil {
+		return err
+	}
+	var verbosePrinter verbose.Printer = verbose.NopPrinter
+	if flags.Verbose {
+		verbosePrinter = verbose.NewPrinter(container.Stderr(), container.AppName())
+	}
+	headers, _, err := bufcurl.LoadHeaders(flags.Connection.Headers, "", nil)
+	if err != nil {
+		return err
+	}
+	flags.Connection.SetUserAgent(headers, bufcli.Version)
+	roundTripper, err := bufcurl.NewHTTPRoundTripper(
+		flags.Connection.TransportSettings(),
+		isSecure,
+		bufcurl.GetAuthority(endpointURL.Host, headers),
+		verbosePrinter,
+	)
+	if err != nil {
+		return err
+	}
+	resolver, closeResolver := bufcurl.NewServerReflectionResolver(
+		ctx,
+		bufcurl.NewVerboseHTTPClient(roundTripper, verbosePrinter),
+		flags.Connection.ClientOptions(),
+		baseURL,
+		reflectProtocol,
+		headers,
+		verbosePrinter,
+	)
+	defer closeResolver()
+	image, err := getImage(resolver)
+	if err != nil {
+		return err
+	}
+	switch t := outputRef.(type) {
+	case buffetch.MessageRef:
+		controller, err := bufcli.NewController(container)
+		if err != nil {
+			return err
+		}
+		return controller.PutImage(ctx, flags.Output, image)
+	case buffetch.DirRef:
+		return writeModule(ctx, t.DirPath(), image)
+	default:
+		return appcmd.NewInvalidArgumentErrorf("--%s must be an image or a directory: %q", outputFlagName, flags.Output)
+	}
+}
+
+// getImage downloads the files that define the services of the server and their imports,
+// and returns them as an image. Well-known types are imports of the image.
+func getImage(resolver bufcurl.Resolver) (bufimage.Image, error) {
+	serviceNames, err := resolver.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	var exportServiceNames []protoreflect.FullName
+	for _, serviceName := range serviceNames {
+		if _, ok := reflectionServiceNames[serviceName]; !ok {
+			exportServiceNames = append(exportServiceNames, serviceName)
+		}
+	}
+	if len(exportServiceNames) == 0 {
+		return nil, errors.New("server did not list any services other than server reflection")
+	}
+	fileDescriptorProtos, err := bufcurl.ResolveFileDescriptorProtos(resolver, exportServiceNames)
+	if err != nil {
+		return nil, err
+	}
+	// Custom options are unrecognized fields of the downloaded descriptors
+	// until they are reparsed with the downloaded extensions.
+	protoencodingResolver := protoencoding.NewLazyResolver(fileDescriptorProtos...)
+	imageFiles := make([]bufimage.ImageFile, len(fileDescriptorProtos))
+	for i, fileDescriptorProto := range fileDescriptorProtos {
+		if err := protoencoding.ReparseExtensions(protoencodingResolver, fileDescriptorProto.ProtoReflect()); err != nil {
+			return nil, err
+		}
+		imageFile, err := bufimage.NewImageFile(
+			fileDescriptorProto,
+			nil,
+			uuid.Nil,
+			"",
+			"",
+			datawkt.Exists(fileDescriptorProto.GetName()),
+			false,
+			nil,
+		)
+		if err != nil {
+			return nil, err
+		}
+		imageFiles[i] = imageFile
+	}
+	return bufimage.NewImage(imageFiles)
+}
+
+// writeModule writes the reconstructed files of the image that are not imports to
+// the directory, along with a buf.yaml if the directory does not contain one.
+func writeModule(ctx context.Context, dirPath string, image bufimage.Image) error {
+	readBucket, err := bufformat.FormatImage(ctx, bufimage.ImageWithoutImports(image))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dirPath, 0755); err != nil {
+		return err
+	}
+	readWriteBucket, err := storageos.NewProvider().NewReadWriteBucket(dirPath)
+	if err != nil {
+		return err
+	}
+	if _, err := storage.Copy(ctx, readBucket, readWriteBucket); err != nil {
+		return err
+	}
+	exists, err := bufcli.BufYAMLFileExistsForDirPath(ctx, dirPath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	moduleConfig, err := bufconfig.NewModuleConfig(
+		".",
+		nil,
+		map[string][]string{
+			".": {},
+		},
+		map[string][]string{
+			".": {},
+		},
+		bufconfig.NewLintConfig(
+			bufconfig.NewEnabledCheckConfigForUseIDsAndCategories(
+				bufconfig.FileVersionV2,
+				[]string{"STANDARD"},
+				false,
+			),
+			"",
+			false,
+			false,
+			false,
+			"",
+			true,
+			false,
+		),
+		bufconfig.NewBreakingConfig(
+			bufconfig.NewEnabledCheckConfigForUseIDsAndCategories(
+				bufconfig.FileVersionV2,
+				[]string{"FILE"},
+				false,
+			),
+			false,
+		),
+	)
+	if err != nil {
+		return err
+	}
+	bufYAMLFile, err := bufconfig.NewBufYAMLFile(
+		bufconfig.FileVersionV2,
+		[]bufconfig.ModuleConfig{
+			moduleConfig,
+		},
+		nil,
+		nil,
+		bufconfig.BufYAMLFileWithIncludeDocsLink(),
+	)
+	if err != nil {
+		return err
+	}
+	return bufcli.PutBufYAMLFileForDirPath(ctx, dirPath, bufYAMLFile)
+}
@@ -0,0 +1,259 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reflectexport
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/internal/internaltesting"
+	reflectionv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/reflection/v1"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestReflectExport(t *testing.T) {
+	t.Parallel()
+	serverURL := newTestReflectionServer(t)
+	outDirPath := t.TempDir()
+
+	appcmdtesting.RunCommandSuccess(
+		t,
+		testNewCommand,
+		internaltesting.NewEnvFunc(t),
+		nil,
+		nil,
+		serverURL,
+		"--insecure",
+		"--output",
+		outDirPath,
+	)
+	data, err := os.ReadFile(filepath.Join(outDirPath, "foo", "v1", "foo.proto"))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		`syntax = "proto3";
+
+package foo.v1;
+
+import "google/protobuf/empty.proto";
+
+message Foo {
+  string name = 1;
+}
+
+service FooService {
+  rpc GetFoo(google.protobuf.Empty) returns (Foo);
+}
+`,
+		string(data),
+	)
+	_, err = os.Stat(filepath.Join(outDirPath, "buf.yaml"))
+	require.NoError(t, err)
+	// Well-known types and the reflection services are not exported.
+	_, err = os.Stat(filepath.Join(outDirPath, "google"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(outDirPath, "grpc"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	imagePath := filepath.Join(t.TempDir(), "image.binpb")
+	appcmdtesting.RunCommandSuccess(
+		t,
+		testNewCommand,
+		internaltesting.NewEnvFunc(t),
+		nil,
+		nil,
+		serverURL,
+		"--insecure",
+		"--output",
+		imagePath,
+	)
+	data, err = os.ReadFile(imagePath)
+	require.NoError(t, err)
+	fileDescriptorSet := &descriptorpb.FileDescriptorSet{}
+	require.NoError(t, proto.Unmarshal(data, fileDescriptorSet))
+	var fileNames []string
+	for _, fileDescriptorProto := range fileDescriptorSet.GetFile() {
+		fileNames = append(fileNames, fileDescriptorProto.GetName())
+	}
+	assert.Equal(t, []string{"google/protobuf/empty.proto", "foo/v1/foo.proto"}, fileNames)
+}
+
+func TestReflectExportInvalidFlags(t *testing.T) {
+	t.Parallel()
+	appcmdtesting.RunCommandExitCodeStderrContains(
+		t,
+		testNewCommand,
+		1,
+		[]string{`server reflection cannot be used with plain-text URLs (http) unless --http2-prior-knowledge flag is set`},
+		internaltesting.NewEnvFunc(t),
+		nil,
+		"http://localhost:8080",
+		"--output",
+		t.TempDir(),
+	)
+	appcmdtesting.RunCommandExitCodeStderrContains(
+		t,
+		testNewCommand,
+		1,
+		[]string{`TLS flags (--key, --cert, --cacert, --insecure, --servername) should not be used unless URL is secure (https)`},
+		internaltesting.NewEnvFunc(t),
+		nil,
+		"http://localhost:8080",
+		"--http2-prior-knowledge",
+		"--insecure",
+		"--output",
+		t.TempDir(),
+	)
+}
+
+// newTestReflectionServer starts an HTTP/2 server with TLS that implements the
+// grpc.reflection.v1.ServerReflection service for a schema with a single service,
+// and returns its URL.
+func newTestReflectionServer(t *testing.T) string {
+	fooFileDescriptorProto := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("foo/v1/foo.proto"),
+		Package:    proto.String("foo.v1"),
+		Dependency: []string{"google/protobuf/empty.proto"},
+		Syntax:     proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Foo"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("name"),
+						JsonName: proto.String("name"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("FooService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("GetFoo"),
+						InputType:  proto.String(".google.protobuf.Empty"),
+						OutputType: proto.String(".foo.v1.Foo"),
+					},
+				},
+			},
+		},
+	}
+	fileNameToData := make(map[string][]byte)
+	for _, fileDescriptorProto := range []*descriptorpb.FileDescriptorProto{
+		fooFileDescriptorProto,
+		protodesc.ToFileDescriptorProto(emptypb.File_google_protobuf_empty_proto),
+	} {
+		data, err := proto.Marshal(fileDescriptorProto)
+		require.NoError(t, err)
+		fileNameToData[fileDescriptorProto.GetName()] = data
+	}
+	symbolToFileName := map[string]string{
+		"foo.v1.Foo":        "foo/v1/foo.proto",
+		"foo.v1.FooService": "foo/v1/foo.proto",
+	}
+	getFileDescriptorResponse := func(fileName string) *reflectionv1.ServerReflectionResponse {
+		data, ok := fileNameToData[fileName]
+		if !ok {
+			return &reflectionv1.ServerReflectionResponse{
+				MessageResponse: &reflectionv1.ServerReflectionResponse_ErrorResponse{
+					ErrorResponse: &reflectionv1.ErrorResponse{
+						ErrorCode:    int32(connect.CodeNotFound),
+						ErrorMessage: "not found",
+					},
+				},
+			}
+		}
+		return &reflectionv1.ServerReflectionResponse{
+			MessageResponse: &reflectionv1.ServerReflectionResponse_FileDescriptorResponse{
+				FileDescriptorResponse: &reflectionv1.FileDescriptorResponse{
+					FileDescriptorProto: [][]byte{data},
+				},
+			},
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle(
+		"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+		connect.NewBidiStreamHandler(
+			"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+			func(
+				ctx context.Context,
+				stream *connect.BidiStream[reflectionv1.ServerReflectionRequest, reflectionv1.ServerReflectionResponse],
+			) error {
+				for {
+					request, err := stream.Receive()
+					if err != nil {
+						if errors.Is(err, io.EOF) {
+							return nil
+						}
+						return err
+					}
+					var response *reflectionv1.ServerReflectionResponse
+					switch messageRequest := request.GetMessageRequest().(type) {
+					case *reflectionv1.ServerReflectionRequest_ListServices:
+						response = &reflectionv1.ServerReflectionResponse{
+							MessageResponse: &reflectionv1.ServerReflectionResponse_ListServicesResponse{
+								ListServicesResponse: &reflectionv1.ListServiceResponse{
+									Service: []*reflectionv1.ServiceResponse{
+										{Name: "foo.v1.FooService"},
+										{Name: "grpc.reflection.v1.ServerReflection"},
+									},
+								},
+							},
+						}
+					case *reflectionv1.ServerReflectionRequest_FileByFilename:
+						response = getFileDescriptorResponse(messageRequest.FileByFilename)
+					case *reflectionv1.ServerReflectionRequest_FileContainingSymbol:
+						response = getFileDescriptorResponse(symbolToFileName[messageRequest.FileContainingSymbol])
+					default:
+						return connect.NewError(connect.CodeUnimplemented, errors.New("unsupported request"))
+					}
+					response.OriginalRequest = request
+					if err := stream.Send(response); err != nil {
+						return err
+					}
+				}
+			},
+		),
+	)
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func testNewCommand(use string) *appcmd.Command {
+	return NewCommand(use, appext.NewBuilder(use))
+}
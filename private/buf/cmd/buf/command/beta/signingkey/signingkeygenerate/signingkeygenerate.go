@@ -0,0 +1,81 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signingkeygenerate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   name + " <path>",
+		Short: "Generate a signing key for signing pushed modules",
+		Long: `An ed25519 private key is written to <path> as a PEM-encoded PKCS #8 key, and its public
+key is printed to stdout.
+
+The private key is used with buf push --signing-key. The public key is added to the
+trusted_signing_keys of the buf.yaml of modules that depend on the pushed modules, so that
+their signatures are verified whenever the dependencies are resolved.
+
+The file at <path> must not exist.`,
+		Args: appcmd.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container)
+			},
+		),
+	}
+}
+
+func run(
+	_ context.Context,
+	container appext.Container,
+) (retErr error) {
+	signingKey, err := bufmodule.NewSigningKey()
+	if err != nil {
+		return err
+	}
+	data, err := bufmodule.SigningKeyToPEM(signingKey)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(container.Arg(0), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("%s already exists", container.Arg(0))
+		}
+		return err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, file.Close())
+	}()
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(container.Stdout(), signingKey.PublicKey().String())
+	return err
+}
@@ -0,0 +1,62 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signingkeypublickey
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   name + " <path>",
+		Short: "Print the public key of a signing key",
+		Long: `The signing key at <path> must be a PEM-encoded PKCS #8 ed25519 private key, such as
+one written by buf beta signing-key generate or by "openssl genpkey -algorithm ed25519".
+
+The public key is printed in the form used by trusted_signing_keys in buf.yaml.`,
+		Args: appcmd.ExactArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container)
+			},
+		),
+	}
+}
+
+func run(
+	_ context.Context,
+	container appext.Container,
+) error {
+	data, err := os.ReadFile(container.Arg(0))
+	if err != nil {
+		return err
+	}
+	signingKey, err := bufmodule.ParseSigningKeyPEM(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(container.Stdout(), signingKey.PublicKey().String())
+	return err
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
@@ -25,8 +26,10 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/git"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/wasm"
@@ -40,9 +43,13 @@ const (
 	limitToInputFilesFlagName = "limit-to-input-files"
 	configFlagName            = "config"
 	againstFlagName           = "against"
+	againstGitRefsFlagName    = "against-git-refs"
 	againstConfigFlagName     = "against-config"
 	excludePathsFlagName      = "exclude-path"
 	disableSymlinksFlagName   = "disable-symlinks"
+	affectedFlagName          = "affected"
+
+	defaultAgainstGitInput = ".git"
 )
 
 // NewCommand returns a new Command.
@@ -56,6 +63,17 @@ func NewCommand(
 		Short: "Verify no breaking changes have been made",
 		Long: `This command makes sure that the <input> location has no breaking changes compared to the <against-input> location.
 
+The --against flag may be specified multiple times to check against multiple baselines, such as every
+release that is still deployed. Alternatively, --against-git-refs checks against every tag matching a
+glob, or every commit in a revision range, of the git repository of the current directory:
+
+    $ buf breaking --against-git-refs 'v1.*'
+    $ buf breaking --against '.git#subdir=proto' --against-git-refs 'v1.0.0..main'
+
+Each baseline image is built once. Violations that are found against more than one baseline are only
+printed once, and when checking against multiple baselines, each violation is labeled with the
+baselines it was found against.
+
 ` +
 			bufcli.GetInputLong(`the source, module, or image to check for breaking changes`),
 		Args: appcmd.MaximumNArgs(1),
@@ -74,10 +92,12 @@ type flags struct {
 	LimitToInputFiles bool
 	Paths             []string
 	Config            string
-	Against           string
+	Against           []string
+	AgainstGitRefs    string
 	AgainstConfig     string
 	ExcludePaths      []string
 	DisableSymlinks   bool
+	Affected          string
 	// special
 	InputHashtag string
 }
@@ -91,6 +111,7 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
 	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	bufcli.BindAffected(flagSet, &f.Affected, affectedFlagName)
 	flagSet.StringVar(
 		&f.ErrorFormat,
 		errorFormatFlagName,
@@ -123,15 +144,28 @@ Overrides --%s`,
 		"",
 		`The buf.yaml file or data to use for configuration`,
 	)
-	flagSet.StringVar(
+	flagSet.StringArrayVar(
 		&f.Against,
 		againstFlagName,
-		"",
+		nil,
 		fmt.Sprintf(
-			`Required. The source, module, or image to check against. Must be one of format %s`,
+			`Required unless --%s is set. The source, module, or image to check against. Must be one of format %s
+May be specified multiple times to check against multiple baselines`,
+			againstGitRefsFlagName,
 			buffetch.AllFormatsString,
 		),
 	)
+	flagSet.StringVar(
+		&f.AgainstGitRefs,
+		againstGitRefsFlagName,
+		"",
+		fmt.Sprintf(
+			`Check against every tag matching the given glob, such as "v1.*", or every commit in the given revision range, such as "v1.0.0..main"
+Refs are listed from the git repository of the current directory
+Each --%s input must be a git input without a branch, tag, or ref, and is checked at each ref. Defaults to ".git"`,
+			againstFlagName,
+		),
+	)
 	flagSet.StringVar(
 		&f.AgainstConfig,
 		againstConfigFlagName,
@@ -145,13 +179,39 @@ func run(
 	container appext.Container,
 	flags *flags,
 ) (retErr error) {
-	if err := bufcli.ValidateRequiredFlag(againstFlagName, flags.Against); err != nil {
-		return err
+	if len(flags.Against) == 0 && flags.AgainstGitRefs == "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s or --%s is required", againstFlagName, againstGitRefsFlagName)
 	}
 	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 	if err != nil {
 		return err
 	}
+	paths := flags.Paths
+	againstPaths := flags.Paths
+	if flags.Affected != "" {
+		if len(flags.Paths) > 0 {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", affectedFlagName, pathsFlagName)
+		}
+		affected, err := bufcli.ReadAffected(container, flags.Affected, affectedFlagName)
+		if err != nil {
+			return err
+		}
+		if len(affected.Files) == 0 {
+			return nil
+		}
+		existingFiles, deletedFiles, err := affected.SplitFiles()
+		if err != nil {
+			return err
+		}
+		// Deleted files only exist on the against side, so the input is limited to the
+		// existing files, and the against side to all affected files. If every affected
+		// file was deleted, the whole input is checked, as the deletions are what break.
+		paths = existingFiles
+		againstPaths = affected.Files
+		if len(deletedFiles) > 0 && len(existingFiles) == 0 {
+			paths = nil
+		}
+	}
 	controller, err := bufcli.NewController(
 		container,
 		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
@@ -166,7 +226,7 @@ func run(
 	imageWithConfigs, err := controller.GetTargetImageWithConfigs(
 		ctx,
 		input,
-		bufctl.WithTargetPaths(flags.Paths, flags.ExcludePaths),
+		bufctl.WithTargetPaths(paths, flags.ExcludePaths),
 		bufctl.WithConfigOverride(flags.Config),
 	)
 	if err != nil {
@@ -174,36 +234,60 @@ func run(
 	}
 	// TODO: this doesn't actually work because we're using the same file paths for both sides
 	// of the roots change, then we're torched
-	externalPaths := flags.Paths
+	externalPaths := againstPaths
 	if flags.LimitToInputFiles {
 		externalPaths, err = getExternalPathsForImages(imageWithConfigs)
 		if err != nil {
 			return err
 		}
 	}
-	// Do not exclude imports here. bufcheck's Client requires all imports.
-	// Use bufcheck's BreakingWithExcludeImports.
-	againstImageWithConfigs, err := controller.GetTargetImageWithConfigs(
-		ctx,
-		flags.Against,
-		bufctl.WithTargetPaths(externalPaths, flags.ExcludePaths),
-		bufctl.WithConfigOverride(flags.AgainstConfig),
-	)
+	againstInputs, err := getAgainstInputs(ctx, container, flags.Against, flags.AgainstGitRefs)
 	if err != nil {
 		return err
 	}
-	if len(imageWithConfigs) != len(againstImageWithConfigs) {
-		// If workspaces are being used as input, the number
-		// of images MUST match. Otherwise the results will
-		// be meaningless and yield false positives.
-		//
-		// And similar to the note above, if the roots change,
-		// we're torched.
-		return fmt.Errorf(
-			"input contained %d images, whereas against contained %d images",
-			len(imageWithConfigs),
-			len(againstImageWithConfigs),
+	// Build each baseline image once up front, so that we fail early on
+	// a baseline that does not build, and so that the images can be
+	// checked against every input image.
+	againstImageWithConfigsList := make([][]bufctl.ImageWithConfig, len(againstInputs))
+	for i, againstInput := range againstInputs {
+		// Do not exclude imports here. bufcheck's Client requires all imports.
+		// Use bufcheck's BreakingWithExcludeImports.
+		againstImageWithConfigs, err := controller.GetTargetImageWithConfigs(
+			ctx,
+			againstInput,
+			bufctl.WithTargetPaths(externalPaths, flags.ExcludePaths),
+			bufctl.WithConfigOverride(flags.AgainstConfig),
 		)
+		if err != nil {
+			if flags.Affected != "" && errors.Is(err, bufmodule.ErrNoTargetProtoFiles) {
+				// Every affected file was added since this baseline, so there
+				// is nothing to break.
+				continue
+			}
+			return err
+		}
+		if len(imageWithConfigs) != len(againstImageWithConfigs) {
+			// If workspaces are being used as input, the number
+			// of images MUST match. Otherwise the results will
+			// be meaningless and yield false positives.
+			//
+			// And similar to the note above, if the roots change,
+			// we're torched.
+			if len(againstInputs) == 1 {
+				return fmt.Errorf(
+					"input contained %d images, whereas against contained %d images",
+					len(imageWithConfigs),
+					len(againstImageWithConfigs),
+				)
+			}
+			return fmt.Errorf(
+				"input contained %d images, whereas against %q contained %d images",
+				len(imageWithConfigs),
+				againstInput,
+				len(againstImageWithConfigs),
+			)
+		}
+		againstImageWithConfigsList[i] = againstImageWithConfigs
 	}
 	wasmRuntimeCacheDir, err := bufcli.CreateWasmRuntimeCacheDir(container)
 	if err != nil {
@@ -216,37 +300,45 @@ func run(
 	defer func() {
 		retErr = errors.Join(retErr, wasmRuntime.Close(ctx))
 	}()
-	var allFileAnnotations []bufanalysis.FileAnnotation
-	for i, imageWithConfig := range imageWithConfigs {
-		client, err := bufcheck.NewClient(
-			container.Logger(),
-			bufcheck.NewRunnerProvider(wasmRuntime),
-			bufcheck.ClientWithStderr(container.Stderr()),
-		)
-		if err != nil {
-			return err
-		}
-		breakingOptions := []bufcheck.BreakingOption{
-			bufcheck.WithPluginConfigs(imageWithConfig.PluginConfigs()...),
+	// Only label the annotations with their baselines if there is more than one,
+	// so that the output for a single --against is unchanged.
+	baselineFileAnnotationLabeler := newBaselineFileAnnotationLabeler(len(againstInputs) > 1)
+	for i, againstInput := range againstInputs {
+		if againstImageWithConfigsList[i] == nil {
+			continue
 		}
-		if flags.ExcludeImports {
-			breakingOptions = append(breakingOptions, bufcheck.BreakingWithExcludeImports())
-		}
-		if err := client.Breaking(
-			ctx,
-			imageWithConfig.BreakingConfig(),
-			imageWithConfig,
-			againstImageWithConfigs[i],
-			breakingOptions...,
-		); err != nil {
-			var fileAnnotationSet bufanalysis.FileAnnotationSet
-			if errors.As(err, &fileAnnotationSet) {
-				allFileAnnotations = append(allFileAnnotations, fileAnnotationSet.FileAnnotations()...)
-			} else {
+		for j, imageWithConfig := range imageWithConfigs {
+			client, err := bufcheck.NewClient(
+				container.Logger(),
+				bufcheck.NewRunnerProvider(wasmRuntime),
+				bufcheck.ClientWithStderr(container.Stderr()),
+			)
+			if err != nil {
 				return err
 			}
+			breakingOptions := []bufcheck.BreakingOption{
+				bufcheck.WithPluginConfigs(imageWithConfig.PluginConfigs()...),
+			}
+			if flags.ExcludeImports {
+				breakingOptions = append(breakingOptions, bufcheck.BreakingWithExcludeImports())
+			}
+			if err := client.Breaking(
+				ctx,
+				imageWithConfig.BreakingConfig(),
+				imageWithConfig,
+				againstImageWithConfigsList[i][j],
+				breakingOptions...,
+			); err != nil {
+				var fileAnnotationSet bufanalysis.FileAnnotationSet
+				if errors.As(err, &fileAnnotationSet) {
+					baselineFileAnnotationLabeler.add(againstInput, fileAnnotationSet.FileAnnotations()...)
+				} else {
+					return err
+				}
+			}
 		}
 	}
+	allFileAnnotations := baselineFileAnnotationLabeler.fileAnnotations()
 	if len(allFileAnnotations) > 0 {
 		allFileAnnotationSet := bufanalysis.NewFileAnnotationSet(allFileAnnotations...)
 		if err := bufanalysis.PrintFileAnnotationSet(
@@ -261,6 +353,64 @@ func run(
 	return nil
 }
 
+// getAgainstInputs returns the deduplicated against inputs to check against.
+//
+// If againstGitRefs is set, each against input is expanded to an input for each of
+// the matching git refs.
+func getAgainstInputs(
+	ctx context.Context,
+	container appext.Container,
+	againsts []string,
+	againstGitRefs string,
+) ([]string, error) {
+	if againstGitRefs == "" {
+		return slicesext.Deduplicate(againsts), nil
+	}
+	gitRefs, err := listGitRefs(ctx, container, againstGitRefs)
+	if err != nil {
+		return nil, err
+	}
+	if len(gitRefs) == 0 {
+		return nil, appcmd.NewInvalidArgumentErrorf("--%s: no git refs matched %q", againstGitRefsFlagName, againstGitRefs)
+	}
+	if len(againsts) == 0 {
+		againsts = []string{defaultAgainstGitInput}
+	}
+	againstInputs := make([]string, 0, len(againsts)*len(gitRefs))
+	for _, against := range againsts {
+		for _, gitRef := range gitRefs {
+			againstInputs = append(againstInputs, getAgainstInputForGitRef(against, gitRef))
+		}
+	}
+	return slicesext.Deduplicate(againstInputs), nil
+}
+
+// listGitRefs lists the git refs for the value of --against-git-refs.
+//
+// The value is a revision range if it contains "..", otherwise it is a tag glob.
+func listGitRefs(
+	ctx context.Context,
+	container appext.Container,
+	againstGitRefs string,
+) ([]string, error) {
+	lister := git.NewLister()
+	if strings.Contains(againstGitRefs, "..") {
+		return lister.ListCommits(ctx, container, againstGitRefs)
+	}
+	return lister.ListTags(ctx, container, againstGitRefs)
+}
+
+// getAgainstInputForGitRef adds the ref option to the git input.
+//
+// If the git input already has a branch, tag, or ref, building the
+// resulting input will fail, which is what we want.
+func getAgainstInputForGitRef(againstGitInput string, gitRef string) string {
+	if strings.Contains(againstGitInput, "#") {
+		return againstGitInput + ",ref=" + gitRef
+	}
+	return againstGitInput + "#ref=" + gitRef
+}
+
 func getExternalPathsForImages[I bufimage.Image, S ~[]I](images S) ([]string, error) {
 	externalPaths := make(map[string]struct{})
 	for _, image := range images {
@@ -270,3 +420,76 @@ func getExternalPathsForImages[I bufimage.Image, S ~[]I](images S) ([]string, er
 	}
 	return slicesext.MapKeysToSlice(externalPaths), nil
 }
+
+// baselineFileAnnotationLabeler deduplicates FileAnnotations found against
+// multiple baselines, and labels each with the baselines it was found against.
+type baselineFileAnnotationLabeler struct {
+	label               bool
+	keys                []string
+	keyToFileAnnotation map[string]bufanalysis.FileAnnotation
+	keyToBaselines      map[string][]string
+}
+
+func newBaselineFileAnnotationLabeler(label bool) *baselineFileAnnotationLabeler {
+	return &baselineFileAnnotationLabeler{
+		label:               label,
+		keyToFileAnnotation: make(map[string]bufanalysis.FileAnnotation),
+		keyToBaselines:      make(map[string][]string),
+	}
+}
+
+func (b *baselineFileAnnotationLabeler) add(baseline string, fileAnnotations ...bufanalysis.FileAnnotation) {
+	for _, fileAnnotation := range fileAnnotations {
+		key := getFileAnnotationKey(fileAnnotation)
+		if _, ok := b.keyToFileAnnotation[key]; !ok {
+			b.keys = append(b.keys, key)
+			b.keyToFileAnnotation[key] = fileAnnotation
+		}
+		if baselines := b.keyToBaselines[key]; len(baselines) == 0 || baselines[len(baselines)-1] != baseline {
+			b.keyToBaselines[key] = append(baselines, baseline)
+		}
+	}
+}
+
+func (b *baselineFileAnnotationLabeler) fileAnnotations() []bufanalysis.FileAnnotation {
+	fileAnnotations := make([]bufanalysis.FileAnnotation, 0, len(b.keys))
+	for _, key := range b.keys {
+		fileAnnotation := b.keyToFileAnnotation[key]
+		if b.label {
+			fileAnnotation = bufanalysis.NewFileAnnotation(
+				fileAnnotation.FileInfo(),
+				fileAnnotation.StartLine(),
+				fileAnnotation.StartColumn(),
+				fileAnnotation.EndLine(),
+				fileAnnotation.EndColumn(),
+				fileAnnotation.Type(),
+				fmt.Sprintf(
+					"%s (against %s)",
+					fileAnnotation.Message(),
+					stringutil.JoinSliceQuoted(b.keyToBaselines[key], ", "),
+				),
+				fileAnnotation.PluginName(),
+			)
+		}
+		fileAnnotations = append(fileAnnotations, fileAnnotation)
+	}
+	return fileAnnotations
+}
+
+func getFileAnnotationKey(fileAnnotation bufanalysis.FileAnnotation) string {
+	var path string
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		path = fileInfo.ExternalPath()
+	}
+	return fmt.Sprintf(
+		"%s:%d:%d:%d:%d:%s:%s:%s",
+		path,
+		fileAnnotation.StartLine(),
+		fileAnnotation.StartColumn(),
+		fileAnnotation.EndLine(),
+		fileAnnotation.EndColumn(),
+		fileAnnotation.Type(),
+		fileAnnotation.PluginName(),
+		fileAnnotation.Message(),
+	)
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
@@ -43,6 +44,7 @@ const (
 	excludePathsFlagName                  = "exclude-path"
 	disableSymlinksFlagName               = "disable-symlinks"
 	typeFlagName                          = "type"
+	watchFlagName                         = "watch"
 )
 
 // NewCommand returns a new Command.
@@ -77,6 +79,7 @@ type flags struct {
 	ExcludePaths                  []string
 	DisableSymlinks               bool
 	Types                         []string
+	Watch                         bool
 	// special
 	InputHashtag string
 }
@@ -93,6 +96,7 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
 	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	bufcli.BindWatch(flagSet, &f.Watch, watchFlagName)
 	flagSet.BoolVar(
 		&f.ExcludeSourceRetentionOptions,
 		excludeSourceRetentionOptionsFlagName,
@@ -144,14 +148,39 @@ func run(
 	if err != nil {
 		return err
 	}
-	controller, err := bufcli.NewController(
-		container,
+	controllerOptions := []bufctl.ControllerOption{
 		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
 		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
-	)
+	}
+	if flags.Watch {
+		controllerOptions = append(controllerOptions, bufctl.WithBuildCache(bufimage.NewBuildCache()))
+	}
+	controller, err := bufcli.NewController(container, controllerOptions...)
 	if err != nil {
 		return err
 	}
+	if flags.Watch {
+		return bufcli.Watch(
+			ctx,
+			container,
+			controller,
+			input,
+			nil,
+			getWatchOutputPaths(ctx, container, flags.Output),
+			func(ctx context.Context) error {
+				return build(ctx, controller, input, flags)
+			},
+		)
+	}
+	return build(ctx, controller, input, flags)
+}
+
+func build(
+	ctx context.Context,
+	controller bufctl.Controller,
+	input string,
+	flags *flags,
+) error {
 	image, err := controller.GetImage(
 		ctx,
 		input,
@@ -177,3 +206,12 @@ func run(
 		bufctl.WithImageAsFileDescriptorSet(flags.AsFileDescriptorSet),
 	)
 }
+
+// getWatchOutputPaths returns the path of the output image, so that it is not watched.
+func getWatchOutputPaths(ctx context.Context, container appext.Container, output string) []string {
+	messageRef, err := buffetch.NewMessageRefParser(container.Logger()).GetMessageRef(ctx, output)
+	if err != nil || messageRef.IsNull() || messageRef.Path() == "" || messageRef.Path() == "-" {
+		return nil
+	}
+	return []string{messageRef.Path()}
+}
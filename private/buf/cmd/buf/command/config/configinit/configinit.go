@@ -155,6 +155,7 @@ func run(
 			"",
 			// We actually want comment ignores enabled by default
 			true,
+			false,
 		),
 		bufconfig.NewBreakingConfig(
 			bufconfig.NewEnabledCheckConfigForUseIDsAndCategories(
@@ -17,20 +17,16 @@ package curl
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
-	"net"
-	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
-	"time"
 
 	"connectrpc.com/connect"
 	"github.com/bufbuild/buf/private/buf/bufcli"
@@ -41,10 +37,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/netrc"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/bufbuild/buf/private/pkg/verbose"
-	"github.com/quic-go/quic-go"
-	"github.com/quic-go/quic-go/http3"
 	"github.com/spf13/pflag"
-	"golang.org/x/net/http2"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
@@ -57,42 +50,18 @@ const (
 	reflectHeaderFlagName   = "reflect-header"
 	reflectProtocolFlagName = "reflect-protocol"
 
-	// Protocol/transport flags
-	protocolFlagName            = "protocol"
-	unixSocketFlagName          = "unix-socket"
-	http2PriorKnowledgeFlagName = "http2-prior-knowledge"
-	http3FlagName               = "http3"
-
-	// TLS flags
-	keyFlagName           = "key"
-	certFlagName          = "cert"
-	certFlagShortName     = "E"
-	caCertFlagName        = "cacert"
-	serverNameFlagName    = "servername"
-	insecureFlagName      = "insecure"
-	insecureFlagShortName = "k"
-
 	// Action flags
 	listServicesFlagName = "list-services"
 	listMethodsFlagName  = "list-methods"
 
-	// Timeout flags
-	noKeepAliveFlagName    = "no-keepalive"
-	keepAliveFlagName      = "keepalive-time"
-	connectTimeoutFlagName = "connect-timeout"
-
-	// Header and request body flags
-	userAgentFlagName      = "user-agent"
-	userAgentFlagShortName = "A"
-	userFlagName           = "user"
-	userFlagShortName      = "u"
-	netrcFlagName          = "netrc"
-	netrcFlagShortName     = "n"
-	netrcFileFlagName      = "netrc-file"
-	headerFlagName         = "header"
-	headerFlagShortName    = "H"
-	dataFlagName           = "data"
-	dataFlagShortName      = "d"
+	// Credentials and request body flags
+	userFlagName       = "user"
+	userFlagShortName  = "u"
+	netrcFlagName      = "netrc"
+	netrcFlagShortName = "n"
+	netrcFileFlagName  = "netrc-file"
+	dataFlagName       = "data"
+	dataFlagShortName  = "d"
 
 	// Output flags
 	outputFlagName       = "output"
@@ -208,31 +177,16 @@ type flags struct {
 	ReflectHeaders  []string
 	ReflectProtocol string
 
-	// Protocol details
-	Protocol            string
-	UnixSocket          string
-	HTTP2PriorKnowledge bool
-	HTTP3               bool
-
-	// TLS
-	Key, Cert, CACert, ServerName string
-	Insecure                      bool
-	// TODO: CRLFile, CertStatus
+	// Protocol, TLS, timeout, and header flags
+	Connection *bufcurl.ConnectionFlags
 
 	// Actions
 	ListServices, ListMethods bool
 
-	// Timeouts
-	NoKeepAlive           bool
-	KeepAliveTimeSeconds  float64
-	ConnectTimeoutSeconds float64
-
 	// Handling request and response data and metadata
-	UserAgent string
 	User      string
 	Netrc     bool
 	NetrcFile string
-	Headers   []string
 	Data      string
 
 	// Output options
@@ -248,11 +202,25 @@ type flags struct {
 }
 
 func newFlags() *flags {
-	return &flags{}
+	return &flags{
+		Connection: bufcurl.NewConnectionFlags(
+			connect.ProtocolConnect,
+			fmt.Sprintf(`Request headers to include with the RPC invocation. This flag may be specified more
+than once to indicate multiple headers. Each flag value should have the form "name: value".
+A special value of '@<path>' means to read headers from the file at <path>. If the path
+is "-" then headers are read from stdin. If the same file is indicated as used with the
+request data flag (--%s or -%s), the file must contain all headers, then a blank line,
+and then the request body. It is not allowed to indicate stdin if the schema is expected
+to be provided via stdin as a file descriptor set or image`,
+				dataFlagName, dataFlagShortName,
+			),
+		),
+	}
 }
 
 func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	f.flagSet = flagSet
+	f.Connection.Bind(flagSet)
 
 	flagSet.StringSliceVar(
 		&f.Schemas,
@@ -291,7 +259,7 @@ means to read headers from the file at <path>. If the path is "-" then headers a
 read from stdin. It is not allowed to indicate a file with the same path as used with
 the request data flag (--%s or -%s). Furthermore, it is not allowed to indicate stdin
 if the schema is expected to be provided via stdin as a file descriptor set or image`,
-			reflectFlagName, headerFlagName, headerFlagShortName, dataFlagName, dataFlagShortName,
+			reflectFlagName, bufcurl.HeaderFlagName, bufcurl.HeaderFlagShortName, dataFlagName, dataFlagShortName,
 		),
 	)
 	flagSet.StringVar(
@@ -309,111 +277,6 @@ named "grpc.reflection.v1.ServerReflection" and "grpc.reflection.v1alpha.ServerR
 respectively`,
 	)
 
-	flagSet.StringVar(
-		&f.Protocol,
-		protocolFlagName,
-		connect.ProtocolConnect,
-		`The RPC protocol to use. This can be one of "grpc", "grpcweb", or "connect"`,
-	)
-	flagSet.StringVar(
-		&f.UnixSocket,
-		unixSocketFlagName,
-		"",
-		`The path to a unix socket that will be used instead of opening a TCP socket to the host
-and port indicated in the URL`,
-	)
-	flagSet.BoolVar(
-		&f.HTTP2PriorKnowledge,
-		http2PriorKnowledgeFlagName,
-		false,
-		`This flag can be used to indicate that HTTP/2 should be used. Without this, HTTP 1.1
-will be used with URLs with an http scheme, and protocol negotiation will be used to
-choose either HTTP 1.1 or HTTP/2 for URLs with an https scheme. With this flag set,
-HTTP/2 is always used, even over plain-text.`,
-	)
-
-	flagSet.BoolVar(
-		&f.HTTP3,
-		http3FlagName,
-		false,
-		`This flag can be used to indicate that HTTP/3 should be used. Without this, HTTP 1.1
-will be used with URLs with an http scheme, and protocol negotiation will be used to
-choose either HTTP 1.1 or HTTP/2 for URLs with an https scheme. With this flag set,
-HTTP/3 is always used.`,
-	)
-
-	flagSet.BoolVar(
-		&f.NoKeepAlive,
-		noKeepAliveFlagName,
-		false,
-		`By default, connections are created using TCP keepalive. If this flag is present, they
-will be disabled`,
-	)
-	flagSet.Float64Var(
-		&f.KeepAliveTimeSeconds,
-		keepAliveFlagName,
-		60,
-		`The duration, in seconds, between TCP keepalive transmissions`,
-	)
-	flagSet.Float64Var(
-		&f.ConnectTimeoutSeconds,
-		connectTimeoutFlagName,
-		0,
-		`The time limit, in seconds, for a connection to be established with the server. There is
-no limit if this flag is not present`,
-	)
-
-	flagSet.StringVar(
-		&f.Key,
-		keyFlagName,
-		"",
-		fmt.Sprintf(`Path to a PEM-encoded X509 private key file, for using client certificates with TLS. This
-option is only valid when the URL uses the https scheme. A --%s or -%s flag must also be
-present to provide the certificate and public key that corresponds to the given
-private key`,
-			certFlagName, certFlagShortName,
-		),
-	)
-	flagSet.StringVarP(
-		&f.Cert,
-		certFlagName,
-		certFlagShortName,
-		"",
-		fmt.Sprintf(`Path to a PEM-encoded X509 certificate file, for using client certificates with TLS. This
-option is only valid when the URL uses the https scheme. A --%s flag must also be
-present to provide the private key that corresponds to the given certificate`,
-			keyFlagName,
-		),
-	)
-	flagSet.StringVar(
-		&f.CACert,
-		caCertFlagName,
-		"",
-		fmt.Sprintf(`Path to a PEM-encoded X509 certificate pool file that contains the set of trusted
-certificate authorities/issuers. If omitted, the system's default set of trusted
-certificates are used to verify the server's certificate. This option is only valid
-when the URL uses the https scheme. It is not applicable if --%s or -%s flag is used`,
-			insecureFlagName, insecureFlagShortName,
-		),
-	)
-	flagSet.BoolVarP(
-		&f.Insecure,
-		insecureFlagName,
-		insecureFlagShortName,
-		false,
-		`If set, the TLS connection will be insecure and the server's certificate will NOT be
-verified. This is generally discouraged. This option is only valid when the URL uses
-the https scheme`,
-	)
-	flagSet.StringVar(
-		&f.ServerName,
-		serverNameFlagName,
-		"",
-		`The server name to use in TLS handshakes (for SNI) if the URL scheme is https. If not
-specified, the default is the origin host in the URL or the value in a "Host" header if
-one is provided`,
-	)
-
 	flagSet.BoolVar(
 		&f.ListServices,
 		listServicesFlagName,
@@ -433,16 +296,6 @@ or method name. If the schema source is not server reflection, the URL is not us
 may be omitted.`,
 	)
 
-	flagSet.StringVarP(
-		&f.UserAgent,
-		userAgentFlagName,
-		userAgentFlagShortName,
-		"",
-		fmt.Sprintf(`The user agent string to send. This is ignored if a --%s or -%s flag is provided
-that sets a header named 'User-Agent'.`,
-			headerFlagName, headerFlagShortName,
-		),
-	)
 	flagSet.StringVarP(
 		&f.User,
 		userFlagName,
@@ -453,7 +306,7 @@ in the format "username:password". If the value has no colon, it is assumed to j
 the username, in which case you will be prompted to enter a password. This overrides
 the use of a .netrc file. This is ignored if a --%s or -%s flag is provided that sets
 a header named 'Authorization'.`,
-			headerFlagName, headerFlagShortName,
+			bufcurl.HeaderFlagName, bufcurl.HeaderFlagShortName,
 		),
 	)
 	flagSet.BoolVarP(
@@ -466,7 +319,7 @@ credentials for the request. The credentials will be sent via a basic authorizat
 The command will fail if the file does not have an entry for the hostname in the URL. This
 flag is ignored if a --%s or -%s flag is present. This is ignored if a --%s or -%s flag
 is provided that sets a header named 'Authorization'.`,
-			userFlagName, userFlagShortName, headerFlagName, headerFlagShortName,
+			userFlagName, userFlagShortName, bufcurl.HeaderFlagName, bufcurl.HeaderFlagShortName,
 		),
 	)
 	flagSet.StringVar(
@@ -477,22 +330,7 @@ is provided that sets a header named 'Authorization'.`,
 a file named .netrc in the user's home directory. This flag cannot be used with the --%s
 or -%s flag. This is ignored if a --%s or -%s flag is provided that sets a header named
 'Authorization'.`,
-			netrcFlagName, netrcFlagShortName, netrcFlagName, netrcFlagShortName, headerFlagName, headerFlagShortName,
-		),
-	)
-	flagSet.StringSliceVarP(
-		&f.Headers,
-		headerFlagName,
-		headerFlagShortName,
-		nil,
-		fmt.Sprintf(`Request headers to include with the RPC invocation. This flag may be specified more
-than once to indicate multiple headers. Each flag value should have the form "name: value".
-A special value of '@<path>' means to read headers from the file at <path>. If the path
-is "-" then headers are read from stdin. If the same file is indicated as used with the
-request data flag (--%s or -%s), the file must contain all headers, then a blank line,
-and then the request body. It is not allowed to indicate stdin if the schema is expected
-to be provided via stdin as a file descriptor set or image`,
-			dataFlagName, dataFlagShortName,
+			netrcFlagName, netrcFlagShortName, netrcFlagName, netrcFlagShortName, bufcurl.HeaderFlagName, bufcurl.HeaderFlagShortName,
 		),
 	)
 	flagSet.StringVarP(
@@ -507,7 +345,7 @@ request data is read from stdin. If the same file is indicated as used with the
 headers flags (--%s or -%s), the file must contain all headers, then a blank line, and
 then the request body. It is not allowed to indicate stdin if the schema is expected to be
 provided via stdin as a file descriptor set or image`,
-			headerFlagName, headerFlagShortName,
+			bufcurl.HeaderFlagName, bufcurl.HeaderFlagShortName,
 		),
 	)
 	flagSet.StringVarP(
@@ -553,29 +391,8 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 		return fmt.Errorf("flags --%s and --%s are mutually exclusive", listServicesFlagName, listMethodsFlagName)
 	}
 
-	if (f.Key != "" || f.Cert != "" || f.CACert != "" || f.ServerName != "" || f.flagSet.Changed(insecureFlagName)) &&
-		!isSecure {
-		return fmt.Errorf(
-			"TLS flags (--%s, --%s, --%s, --%s, --%s) should not be used unless URL is secure (https)",
-			keyFlagName, certFlagName, caCertFlagName, insecureFlagName, serverNameFlagName)
-	}
-	if (f.Key != "") != (f.Cert != "") {
-		return fmt.Errorf("if one of --%s or --%s flags is used, both should be used (mutual TLS with a client certificate requires both)", keyFlagName, certFlagName)
-	}
-	if f.Insecure && f.CACert != "" {
-		return fmt.Errorf("if --%s is set, --%s should not be set as it is unused", insecureFlagName, caCertFlagName)
-	}
-
-	if !isSecure && !f.HTTP2PriorKnowledge && f.Protocol == connect.ProtocolGRPC {
-		return fmt.Errorf("grpc protocol cannot be used with plain-text URLs (http) unless --%s flag is set", http2PriorKnowledgeFlagName)
-	}
-
-	if !isSecure && f.HTTP3 {
-		return fmt.Errorf("--%s cannot be used with plain-text URLs (http)", http3FlagName)
-	}
-
-	if f.UnixSocket != "" && f.HTTP3 {
-		return fmt.Errorf("--%s cannot be used with --%s", unixSocketFlagName, http3FlagName)
+	if err := f.Connection.Validate(isSecure); err != nil {
+		return err
 	}
 
 	if f.Netrc && f.NetrcFile != "" {
@@ -599,8 +416,8 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 			reflectHeaderFlagName, reflectProtocolFlagName, reflectFlagName)
 	}
 	if f.Reflect {
-		if !isSecure && !f.HTTP2PriorKnowledge {
-			return fmt.Errorf("--%s cannot be used with plain-text URLs (http) unless --%s flag is set", reflectFlagName, http2PriorKnowledgeFlagName)
+		if !isSecure && !f.Connection.HTTP2PriorKnowledge {
+			return fmt.Errorf("--%s cannot be used with plain-text URLs (http) unless --%s flag is set", reflectFlagName, bufcurl.HTTP2PriorKnowledgeFlagName)
 		}
 		if _, err := bufcurl.ParseReflectProtocol(f.ReflectProtocol); err != nil {
 			return fmt.Errorf(
@@ -611,25 +428,6 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 		}
 	}
 
-	switch f.Protocol {
-	case connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb:
-	default:
-		return fmt.Errorf(
-			"--%s value must be one of %q, %q, or %q",
-			protocolFlagName, connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb)
-	}
-
-	if f.NoKeepAlive && f.flagSet.Changed(keepAliveFlagName) {
-		return fmt.Errorf("--%s should not be specified if keepalive is disabled", keepAliveFlagName)
-	}
-	if f.KeepAliveTimeSeconds <= 0 {
-		return fmt.Errorf("--%s value must be positive", keepAliveFlagName)
-	}
-	// these two default to zero (which means no timeout in effect)
-	if f.ConnectTimeoutSeconds < 0 || (f.ConnectTimeoutSeconds == 0 && f.flagSet.Changed(connectTimeoutFlagName)) {
-		return fmt.Errorf("--%s value must be positive", connectTimeoutFlagName)
-	}
-
 	var dataFile string
 	if strings.HasPrefix(f.Data, "@") {
 		dataFile = strings.TrimPrefix(f.Data, "@")
@@ -642,7 +440,7 @@ func (f *flags) validate(hasURL, isSecure bool) error {
 	}
 
 	headerFiles := map[string]struct{}{}
-	if err := validateHeaders(f.Headers, headerFlagName, schemaIsStdin, false, headerFiles); err != nil {
+	if err := validateHeaders(f.Connection.Headers, bufcurl.HeaderFlagName, schemaIsStdin, false, headerFiles); err != nil {
 		return err
 	}
 	reflectHeaderFiles := map[string]struct{}{}
@@ -723,18 +521,6 @@ func (f *flags) determineCredentials(
 	return basicAuth(username, password), nil
 }
 
-func (f *flags) getTLSConfig(authority string, printer verbose.Printer) (*tls.Config, error) {
-	return bufcurl.MakeVerboseTLSConfig(&bufcurl.TLSSettings{
-		KeyFile:             f.Key,
-		CertFile:            f.Cert,
-		CACertFile:          f.CACert,
-		ServerName:          f.ServerName,
-		Insecure:            f.Insecure,
-		HTTP2PriorKnowledge: f.HTTP2PriorKnowledge,
-		HTTP3:               f.HTTP3,
-	}, authority, printer)
-}
-
 func promptForPassword(ctx context.Context, container app.Container, prompt string) (string, error) {
 	// NB: The comments below and the mechanism of handling I/O async was
 	// copied from the "registry login" command.
@@ -883,14 +669,8 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 		verbosePrinter = verbose.NewPrinter(container.Stderr(), container.AppName())
 	}
 
-	var clientOptions []connect.ClientOption
-	switch f.Protocol {
-	case connect.ProtocolGRPC:
-		clientOptions = []connect.ClientOption{connect.WithGRPC()}
-	case connect.ProtocolGRPCWeb:
-		clientOptions = []connect.ClientOption{connect.WithGRPCWeb()}
-	}
-	if f.Protocol != connect.ProtocolGRPC {
+	clientOptions := f.Connection.ClientOptions()
+	if f.Connection.Protocol != connect.ProtocolGRPC {
 		// The transport will log trailers to the verbose printer. But if
 		// we're not using standard grpc protocol, trailers are actually encoded
 		// in an end-of-stream message for streaming calls. So this interceptor
@@ -912,17 +692,11 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 			}
 		}
 	}
-	requestHeaders, dataReader, err := bufcurl.LoadHeaders(f.Headers, dataFileReference, nil)
+	requestHeaders, dataReader, err := bufcurl.LoadHeaders(f.Connection.Headers, dataFileReference, nil)
 	if err != nil {
 		return err
 	}
-	userAgent := f.UserAgent
-	if userAgent == "" {
-		userAgent = bufcurl.DefaultUserAgent(f.Protocol, bufcli.Version)
-	}
-	if len(requestHeaders.Values("user-agent")) == 0 {
-		requestHeaders.Set("user-agent", userAgent)
-	}
+	f.Connection.SetUserAgent(requestHeaders, bufcli.Version)
 	var basicCreds *string
 	if len(requestHeaders.Values("authorization")) == 0 {
 		creds, err := f.determineCredentials(ctx, container, verbosePrinter, host)
@@ -965,7 +739,7 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 			// This shouldn't be possible since we check in flags.validate, but just in case
 			return nil, errors.New("URL positional argument is missing")
 		}
-		roundTripper, err := makeHTTPRoundTripper(f, isSecure, bufcurl.GetAuthority(host, requestHeaders), verbosePrinter)
+		roundTripper, err := bufcurl.NewHTTPRoundTripper(f.Connection.TransportSettings(), isSecure, bufcurl.GetAuthority(host, requestHeaders), verbosePrinter)
 		if err != nil {
 			return nil, err
 		}
@@ -999,9 +773,7 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 				reflectHeaders.Set("authorization", creds)
 			}
 		}
-		if len(reflectHeaders.Values("user-agent")) == 0 {
-			reflectHeaders.Set("user-agent", userAgent)
-		}
+		f.Connection.SetUserAgent(reflectHeaders, bufcli.Version)
 		reflectProtocol, err := bufcurl.ParseReflectProtocol(f.ReflectProtocol)
 		if err != nil {
 			return err
@@ -1083,132 +855,3 @@ func run(ctx context.Context, container appext.Container, f *flags) (err error)
 		return invoker.Invoke(ctx, dataSource, dataReader, requestHeaders)
 	}
 }
-
-func makeHTTPRoundTripper(f *flags, isSecure bool, authority string, printer verbose.Printer) (http.RoundTripper, error) {
-	if f.HTTP3 {
-		return makeHTTP3RoundTripper(f, authority, printer)
-	}
-	var dialer net.Dialer
-	if f.ConnectTimeoutSeconds != 0 {
-		dialer.Timeout = secondsToDuration(f.ConnectTimeoutSeconds)
-	}
-	if f.NoKeepAlive {
-		dialer.KeepAlive = -1
-	} else {
-		dialer.KeepAlive = secondsToDuration(f.KeepAliveTimeSeconds)
-	}
-
-	var dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
-	if f.UnixSocket != "" {
-		dialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
-			printer.Printf("* Dialing unix socket %s...", f.UnixSocket)
-			return dialer.DialContext(ctx, "unix", f.UnixSocket)
-		}
-	} else {
-		dialFunc = func(ctx context.Context, network, address string) (net.Conn, error) {
-			printer.Printf("* Dialing (%s) %s...", network, address)
-			conn, err := dialer.DialContext(ctx, network, address)
-			if err != nil {
-				return nil, err
-			}
-			printer.Printf("* Connected to %s", conn.RemoteAddr().String())
-			return conn, err
-		}
-	}
-
-	var dialTLSFunc func(ctx context.Context, network, address string) (net.Conn, error)
-	if isSecure {
-		tlsConfig, err := f.getTLSConfig(authority, printer)
-		if err != nil {
-			return nil, err
-		}
-		dialTLSFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
-			conn, err := dialFunc(ctx, network, addr)
-			if err != nil {
-				return nil, err
-			}
-			printer.Printf("* ALPN: offering %s", strings.Join(tlsConfig.NextProtos, ","))
-			tlsConn := tls.Client(conn, tlsConfig)
-			if err := tlsConn.HandshakeContext(ctx); err != nil {
-				return nil, err
-			}
-			return tlsConn, nil
-		}
-	}
-
-	var transport http.RoundTripper
-	switch {
-	case f.HTTP2PriorKnowledge && isSecure:
-		transport = &http2.Transport{
-			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
-				return dialTLSFunc(ctx, network, addr)
-			},
-		}
-	case f.HTTP2PriorKnowledge:
-		transport = &http2.Transport{
-			AllowHTTP: true,
-			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
-				return dialFunc(ctx, network, addr)
-			},
-		}
-	default:
-		transport = &http.Transport{
-			Proxy:             http.ProxyFromEnvironment,
-			DialContext:       dialFunc,
-			DialTLSContext:    dialTLSFunc,
-			ForceAttemptHTTP2: true,
-			MaxIdleConns:      1,
-		}
-	}
-	return transport, nil
-}
-
-func makeHTTP3RoundTripper(f *flags, authority string, printer verbose.Printer) (http.RoundTripper, error) {
-	quicCfg := &quic.Config{
-		KeepAlivePeriod: -1,
-	}
-	if f.ConnectTimeoutSeconds != 0 {
-		quicCfg.HandshakeIdleTimeout = secondsToDuration(f.ConnectTimeoutSeconds)
-	}
-	if !f.NoKeepAlive {
-		quicCfg.KeepAlivePeriod = secondsToDuration(f.KeepAliveTimeSeconds)
-	}
-
-	tlsConfig, err := f.getTLSConfig(authority, printer)
-	if err != nil {
-		return nil, err
-	}
-
-	udpConn, err := net.ListenUDP("udp", nil)
-	if err != nil {
-		return nil, err
-	}
-	transport := &quic.Transport{Conn: udpConn}
-	roundTripper := &http3.Transport{
-		TLSClientConfig: tlsConfig,
-		QUICConfig:      quicCfg,
-		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
-			printer.Printf("* Dialing (udp) %s...", addr)
-			udpAddr, err := net.ResolveUDPAddr("udp", addr)
-			if err != nil {
-				return nil, err
-			}
-			printer.Printf("* ALPN: offering %s", strings.Join(tlsCfg.NextProtos, ","))
-			conn, err := transport.DialEarly(ctx, udpAddr, tlsCfg, cfg)
-			if err != nil {
-				return nil, err
-			}
-			printer.Printf("* Connected to %s", conn.RemoteAddr().String())
-			return conn, err
-		},
-		EnableDatagrams:        false,
-		AdditionalSettings:     map[uint64]uint64{},
-		MaxResponseHeaderBytes: 0,
-		DisableCompression:     false,
-	}
-	return roundTripper, nil
-}
-
-func secondsToDuration(secs float64) time.Duration {
-	return time.Duration(float64(time.Second) * secs)
-}
@@ -17,18 +17,22 @@ package depgraph
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/dag"
 	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/syserror"
 	"github.com/bufbuild/buf/private/pkg/uuidutil"
 	"github.com/google/uuid"
 	"github.com/spf13/pflag"
@@ -38,15 +42,29 @@ const (
 	errorFormatFlagName     = "error-format"
 	disableSymlinksFlagName = "disable-symlinks"
 	formatFlagName          = "format"
+	levelFlagName           = "level"
+	fromFlagName            = "from"
+	toFlagName              = "to"
 
-	dotFormatString  = "dot"
-	jsonFormatString = "json"
+	dotFormatString     = "dot"
+	jsonFormatString    = "json"
+	mermaidFormatString = "mermaid"
+	graphMLFormatString = "graphml"
+
+	moduleLevelString = "module"
+	fileLevelString   = "file"
 )
 
 var (
 	allGraphFormatStrings = []string{
 		dotFormatString,
 		jsonFormatString,
+		mermaidFormatString,
+		graphMLFormatString,
+	}
+	allLevelStrings = []string{
+		moduleLevelString,
+		fileLevelString,
 	}
 )
 
@@ -82,6 +100,16 @@ brew install graphviz
 You can easily visualize a dependency graph using the dot tool:
 
 buf dep graph | dot -Tpng >| graph.png && open graph.png
+
+With --level file, the graph of imports between the .proto files of the workspace and its
+dependencies is printed instead. Imports that are unused, as found by the IMPORT_USED lint
+rule, are dashed in DOT output and dotted in Mermaid output.
+
+Nodes and edges that are part of a cycle are colored red in DOT and Mermaid output.
+
+With --from, only the nodes reachable from the given module or file are printed. With --to,
+only the nodes from which the given module or file is reachable are printed. At the file level,
+a module selects all of its files.
 ` + bufcli.GetSourceOrModuleLong(`the source or module to print the dependency graph for`),
 		Args: appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -99,6 +127,9 @@ type flags struct {
 	// special
 	InputHashtag string
 	Format       string
+	Level        string
+	From         string
+	To           string
 }
 
 func newFlags() *flags {
@@ -126,6 +157,27 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 			stringutil.SliceToString(allGraphFormatStrings),
 		),
 	)
+	flagSet.StringVar(
+		&f.Level,
+		levelFlagName,
+		moduleLevelString,
+		fmt.Sprintf(
+			"The level of the graph. Must be one of %s",
+			stringutil.SliceToString(allLevelStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.From,
+		fromFlagName,
+		"",
+		"Only print the nodes reachable from this module or file",
+	)
+	flagSet.StringVar(
+		&f.To,
+		toFlagName,
+		"",
+		"Only print the nodes from which this module or file is reachable",
+	)
 }
 
 func run(
@@ -137,6 +189,12 @@ func run(
 	if err != nil {
 		return err
 	}
+	if !slices.Contains(allGraphFormatStrings, flags.Format) {
+		return appcmd.NewInvalidArgumentErrorf("invalid value for --%s: %s", formatFlagName, flags.Format)
+	}
+	if !slices.Contains(allLevelStrings, flags.Level) {
+		return appcmd.NewInvalidArgumentErrorf("invalid value for --%s: %s", levelFlagName, flags.Level)
+	}
 	controller, err := bufcli.NewController(
 		container,
 		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
@@ -149,60 +207,273 @@ func run(
 	if err != nil {
 		return err
 	}
-	graph, err := bufmodule.ModuleSetToDAG(workspace)
+	moduleDAG, err := bufmodule.ModuleSetToDAG(workspace)
+	if err != nil {
+		return err
+	}
+	var graph *graph
+	switch flags.Level {
+	case moduleLevelString:
+		graph, err = newModuleGraph(workspace, moduleDAG)
+	case fileLevelString:
+		graph, err = newFileGraph(ctx, container, workspace)
+	default:
+		return syserror.Newf("unknown level: %s", flags.Level)
+	}
 	if err != nil {
 		return err
 	}
+	graph.markCycles()
+	graph, err = graph.filter(flags.From, flags.To)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
 	var graphString string
 	switch flags.Format {
 	case dotFormatString:
-		dotString, err := graph.DOTString(moduleToString)
+		graphString, err = graph.dotString()
 		if err != nil {
 			return err
 		}
-		graphString = dotString
-	case jsonFormatString:
-		// We traverse each module (node) in the graph and populate the deps (outbound nodes).
-		// We keep track of every module we have seen so we can update their d
-		moduleFullNameOrOpaqueIDToExternalModule := make(map[string]externalModule)
-		if err := graph.WalkNodes(
-			func(module bufmodule.Module, _ []bufmodule.Module, deps []bufmodule.Module) error {
-				moduleFullNameOrOpaqueID := moduleFullNameOrOpaqueID(module)
-				// We have already populated this node through deps, we can skip module.
-				if _, ok := moduleFullNameOrOpaqueIDToExternalModule[moduleFullNameOrOpaqueID]; ok {
-					return nil
-				}
-				// We first scaffold a module with no deps populated yet.
-				externalModule, err := externalModuleNoDepsForModule(module)
-				if err != nil {
-					return err
-				}
-				if err := externalModule.addDeps(deps, graph, moduleFullNameOrOpaqueIDToExternalModule, flags); err != nil {
-					return err
-				}
-				// Sort the deps alphabetically before adding our external module.
-				sortExternalModules(externalModule.Deps)
-				moduleFullNameOrOpaqueIDToExternalModule[moduleFullNameOrOpaqueID] = externalModule
-				return nil
-			},
-		); err != nil {
+	case mermaidFormatString:
+		graphString = graph.mermaidString()
+	case graphMLFormatString:
+		graphString, err = graph.graphMLString()
+		if err != nil {
 			return err
 		}
-		externalModules := slicesext.MapValuesToSlice(moduleFullNameOrOpaqueIDToExternalModule)
-		// Sort all modules alphabetically.
-		sortExternalModules(externalModules)
-		data, err := json.Marshal(externalModules)
+	case jsonFormatString:
+		if flags.Level == fileLevelString {
+			graphString, err = fileJSONString(graph)
+		} else {
+			graphString, err = moduleJSONString(moduleDAG, graph)
+		}
 		if err != nil {
 			return err
 		}
-		graphString = string(data)
 	default:
-		return appcmd.NewInvalidArgumentErrorf("invalid value for --%s: %s", formatFlagName, flags.Format)
+		return syserror.Newf("unknown format: %s", flags.Format)
 	}
 	_, err = fmt.Fprintln(container.Stdout(), graphString)
 	return err
 }
 
+// newModuleGraph returns a new graph of the Modules in the ModuleSet.
+//
+// The ModuleSet's dag.Graph is only used for its edges, as walking it errors on cycles.
+func newModuleGraph(moduleSet bufmodule.ModuleSet, moduleDAG *dag.Graph[string, bufmodule.Module]) (*graph, error) {
+	// Only the Modules in the DAG are included, which are those reachable from the target Modules.
+	modules := slicesext.Filter(
+		moduleSet.Modules(),
+		func(module bufmodule.Module) bool {
+			return moduleDAG.ContainsNode(module.OpaqueID())
+		},
+	)
+	graph := newGraph()
+	for _, module := range modules {
+		graph.addNode(
+			&graphNode{
+				id:     moduleToString(module),
+				module: moduleFullNameOrOpaqueID(module),
+				local:  module.IsLocal(),
+			},
+		)
+	}
+	for _, module := range modules {
+		deps, err := moduleDAG.OutboundNodes(module.OpaqueID())
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			graph.addEdge(
+				&graphEdge{
+					from: moduleToString(module),
+					to:   moduleToString(dep),
+				},
+			)
+		}
+	}
+	return graph, nil
+}
+
+// newFileGraph returns a new graph of the imports between the .proto files of the ModuleSet.
+//
+// The imports are parsed from each file, so that the graph can be printed even if the
+// ModuleSet does not build, for example due to an import cycle. The ModuleSet is then
+// built to find unused imports. If the build fails, unused imports are not marked.
+func newFileGraph(
+	ctx context.Context,
+	container appext.Container,
+	moduleSet bufmodule.ModuleSet,
+) (*graph, error) {
+	var fileInfos []bufmodule.FileInfo
+	if err := bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(moduleSet).WalkFileInfos(
+		ctx,
+		func(fileInfo bufmodule.FileInfo) error {
+			fileInfos = append(fileInfos, fileInfo)
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(
+		fileInfos,
+		func(a bufmodule.FileInfo, b bufmodule.FileInfo) int {
+			return strings.Compare(a.Path(), b.Path())
+		},
+	)
+	pathToUnusedImportPaths, err := getPathToUnusedImportPaths(ctx, container, moduleSet)
+	if err != nil {
+		return nil, err
+	}
+	graph := newGraph()
+	for _, fileInfo := range fileInfos {
+		graph.addNode(
+			&graphNode{
+				id:     fileInfo.Path(),
+				module: moduleFullNameOrOpaqueID(fileInfo.Module()),
+				local:  fileInfo.Module().IsLocal(),
+			},
+		)
+	}
+	for _, fileInfo := range fileInfos {
+		importPaths, err := fileInfo.ProtoFileImports()
+		if err != nil {
+			return nil, err
+		}
+		for _, importPath := range importPaths {
+			// Imports of files that are not in any Module, such as the Well-Known Types
+			// when not provided by a Module, have no module.
+			graph.addNode(&graphNode{id: importPath})
+			_, unused := pathToUnusedImportPaths[fileInfo.Path()][importPath]
+			graph.addEdge(
+				&graphEdge{
+					from:   fileInfo.Path(),
+					to:     importPath,
+					unused: unused,
+				},
+			)
+		}
+	}
+	return graph, nil
+}
+
+// getPathToUnusedImportPaths builds the ModuleSet and returns a map from the path of each
+// built file to the paths of its unused imports.
+//
+// Returns an empty map if the ModuleSet does not build.
+func getPathToUnusedImportPaths(
+	ctx context.Context,
+	container appext.Container,
+	moduleSet bufmodule.ModuleSet,
+) (map[string]map[string]struct{}, error) {
+	pathToUnusedImportPaths := make(map[string]map[string]struct{})
+	image, err := bufimage.BuildImage(
+		ctx,
+		container.Logger(),
+		bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(moduleSet),
+		bufimage.WithExcludeSourceCodeInfo(),
+	)
+	if err != nil {
+		var fileAnnotationSet bufanalysis.FileAnnotationSet
+		if errors.As(err, &fileAnnotationSet) {
+			container.Logger().Warn("Unused imports are not shown as the input does not build: " + err.Error())
+			return pathToUnusedImportPaths, nil
+		}
+		return nil, err
+	}
+	for _, imageFile := range image.Files() {
+		dependencies := imageFile.FileDescriptorProto().GetDependency()
+		for _, unusedDependencyIndex := range imageFile.UnusedDependencyIndexes() {
+			unusedImportPaths, ok := pathToUnusedImportPaths[imageFile.Path()]
+			if !ok {
+				unusedImportPaths = make(map[string]struct{})
+				pathToUnusedImportPaths[imageFile.Path()] = unusedImportPaths
+			}
+			unusedImportPaths[dependencies[unusedDependencyIndex]] = struct{}{}
+		}
+	}
+	return pathToUnusedImportPaths, nil
+}
+
+// moduleJSONString returns the JSON representation of the Modules in the graph, with
+// their deps nested.
+func moduleJSONString(moduleDAG *dag.Graph[string, bufmodule.Module], graph *graph) (string, error) {
+	include := func(module bufmodule.Module) bool {
+		_, ok := graph.idToNode[moduleToString(module)]
+		return ok
+	}
+	// We traverse each module (node) in the graph and populate the deps (outbound nodes).
+	// We keep track of every module we have seen so we can update their d
+	moduleFullNameOrOpaqueIDToExternalModule := make(map[string]externalModule)
+	if err := moduleDAG.WalkNodes(
+		func(module bufmodule.Module, _ []bufmodule.Module, deps []bufmodule.Module) error {
+			if !include(module) {
+				return nil
+			}
+			moduleFullNameOrOpaqueID := moduleFullNameOrOpaqueID(module)
+			// We have already populated this node through deps, we can skip module.
+			if _, ok := moduleFullNameOrOpaqueIDToExternalModule[moduleFullNameOrOpaqueID]; ok {
+				return nil
+			}
+			// We first scaffold a module with no deps populated yet.
+			externalModule, err := externalModuleNoDepsForModule(module)
+			if err != nil {
+				return err
+			}
+			if err := externalModule.addDeps(slicesext.Filter(deps, include), moduleDAG, moduleFullNameOrOpaqueIDToExternalModule, include); err != nil {
+				return err
+			}
+			// Sort the deps alphabetically before adding our external module.
+			sortExternalModules(externalModule.Deps)
+			moduleFullNameOrOpaqueIDToExternalModule[moduleFullNameOrOpaqueID] = externalModule
+			return nil
+		},
+	); err != nil {
+		return "", err
+	}
+	externalModules := slicesext.MapValuesToSlice(moduleFullNameOrOpaqueIDToExternalModule)
+	// Sort all modules alphabetically.
+	sortExternalModules(externalModules)
+	data, err := json.Marshal(externalModules)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// fileJSONString returns the JSON representation of the files in the graph.
+func fileJSONString(graph *graph) (string, error) {
+	pathToExternalFile := make(map[string]*externalFile, len(graph.nodes))
+	externalFiles := make([]*externalFile, len(graph.nodes))
+	for i, node := range graph.nodes {
+		externalFile := &externalFile{
+			Path:   node.id,
+			Module: node.module,
+			Local:  node.local,
+			Cycle:  node.inCycle,
+		}
+		pathToExternalFile[node.id] = externalFile
+		externalFiles[i] = externalFile
+	}
+	for _, edge := range graph.edges {
+		externalFile := pathToExternalFile[edge.from]
+		externalFile.Imports = append(
+			externalFile.Imports,
+			externalImport{
+				Path:   edge.to,
+				Unused: edge.unused,
+				Cycle:  edge.inCycle,
+			},
+		)
+	}
+	data, err := json.Marshal(externalFiles)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 func moduleToString(module bufmodule.Module) string {
 	if moduleFullName := module.FullName(); moduleFullName != nil {
 		commitID := dashlessCommitIDStringForModule(module)
@@ -246,7 +517,7 @@ func (e *externalModule) addDeps(
 	deps []bufmodule.Module,
 	graph *dag.Graph[string, bufmodule.Module],
 	moduleFullNameOrOpaqueIDToExternalModule map[string]externalModule,
-	flags *flags,
+	include func(bufmodule.Module) bool,
 ) error {
 	for _, dep := range deps {
 		depFullNameOrOpaqueID := moduleFullNameOrOpaqueID(dep)
@@ -268,7 +539,7 @@ func (e *externalModule) addDeps(
 		if err != nil {
 			return err
 		}
-		if err := depExternalModule.addDeps(transitiveDeps, graph, moduleFullNameOrOpaqueIDToExternalModule, flags); err != nil {
+		if err := depExternalModule.addDeps(slicesext.Filter(transitiveDeps, include), graph, moduleFullNameOrOpaqueIDToExternalModule, include); err != nil {
 			return err
 		}
 		moduleFullNameOrOpaqueIDToExternalModule[depFullNameOrOpaqueID] = depExternalModule
@@ -295,6 +566,20 @@ func externalModuleNoDepsForModule(module bufmodule.Module) (externalModule, err
 	}, nil
 }
 
+type externalFile struct {
+	Path    string           `json:"path,omitempty"`
+	Module  string           `json:"module,omitempty"`
+	Imports []externalImport `json:"imports,omitempty"`
+	Local   bool             `json:"local,omitempty"`
+	Cycle   bool             `json:"cycle,omitempty"`
+}
+
+type externalImport struct {
+	Path   string `json:"path,omitempty"`
+	Unused bool   `json:"unused,omitempty"`
+	Cycle  bool   `json:"cycle,omitempty"`
+}
+
 func sortExternalModules(externalModules []externalModule) {
 	slices.SortFunc(
 		externalModules,
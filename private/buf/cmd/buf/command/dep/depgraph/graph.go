@@ -0,0 +1,425 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depgraph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// graph is a directed graph of modules or files that is used for printing.
+//
+// Unlike dag.Graph, a graph may contain cycles, which are marked instead of
+// resulting in an error.
+type graph struct {
+	nodes    []*graphNode
+	idToNode map[string]*graphNode
+	edges    []*graphEdge
+	edgeKeys map[[2]string]struct{}
+}
+
+type graphNode struct {
+	// id is the module string for modules, and the path for files.
+	id string
+	// module is the FullName or OpaqueID of the module, for both modules and files.
+	module  string
+	local   bool
+	inCycle bool
+}
+
+type graphEdge struct {
+	from    string
+	to      string
+	unused  bool
+	inCycle bool
+}
+
+func newGraph() *graph {
+	return &graph{
+		idToNode: make(map[string]*graphNode),
+		edgeKeys: make(map[[2]string]struct{}),
+	}
+}
+
+// addNode adds the node if a node with the same id was not already added.
+func (g *graph) addNode(node *graphNode) {
+	if _, ok := g.idToNode[node.id]; ok {
+		return
+	}
+	g.idToNode[node.id] = node
+	g.nodes = append(g.nodes, node)
+}
+
+// addEdge adds the edge if an edge between the same nodes was not already added.
+//
+// Both nodes must have already been added.
+func (g *graph) addEdge(edge *graphEdge) {
+	key := [2]string{edge.from, edge.to}
+	if _, ok := g.edgeKeys[key]; ok {
+		return
+	}
+	g.edgeKeys[key] = struct{}{}
+	g.edges = append(g.edges, edge)
+}
+
+// markCycles marks all nodes and edges that are part of a cycle.
+//
+// This uses Tarjan's strongly connected components algorithm. A node is part of a cycle
+// if its component has more than one node, or if it has an edge to itself.
+func (g *graph) markCycles() {
+	idToOutboundIDs := make(map[string][]string)
+	for _, edge := range g.edges {
+		idToOutboundIDs[edge.from] = append(idToOutboundIDs[edge.from], edge.to)
+	}
+	idToIndex := make(map[string]int)
+	idToLowLink := make(map[string]int)
+	idToComponent := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var numComponents int
+	var visit func(string)
+	visit = func(id string) {
+		idToIndex[id] = len(idToIndex)
+		idToLowLink[id] = idToIndex[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, outboundID := range idToOutboundIDs[id] {
+			if _, ok := idToIndex[outboundID]; !ok {
+				visit(outboundID)
+				idToLowLink[id] = min(idToLowLink[id], idToLowLink[outboundID])
+			} else if onStack[outboundID] {
+				idToLowLink[id] = min(idToLowLink[id], idToIndex[outboundID])
+			}
+		}
+		if idToLowLink[id] != idToIndex[id] {
+			return
+		}
+		var componentIDs []string
+		for {
+			componentID := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[componentID] = false
+			idToComponent[componentID] = numComponents
+			componentIDs = append(componentIDs, componentID)
+			if componentID == id {
+				break
+			}
+		}
+		if len(componentIDs) > 1 {
+			for _, componentID := range componentIDs {
+				g.idToNode[componentID].inCycle = true
+			}
+		}
+		numComponents++
+	}
+	for _, node := range g.nodes {
+		if _, ok := idToIndex[node.id]; !ok {
+			visit(node.id)
+		}
+	}
+	for _, edge := range g.edges {
+		if edge.from == edge.to {
+			g.idToNode[edge.from].inCycle = true
+		}
+		if idToComponent[edge.from] == idToComponent[edge.to] && g.idToNode[edge.from].inCycle {
+			edge.inCycle = true
+		}
+	}
+}
+
+// filter returns the subgraph of the nodes reachable from any node matching from,
+// and from which any node matching to is reachable.
+//
+// A node matches a value if either its id or its module is equal to the value. Empty
+// values are ignored.
+func (g *graph) filter(from string, to string) (*graph, error) {
+	include := make(map[string]bool, len(g.nodes))
+	for _, node := range g.nodes {
+		include[node.id] = true
+	}
+	if from != "" {
+		reachable, err := g.reachable(from, func(edge *graphEdge) (string, string) { return edge.from, edge.to })
+		if err != nil {
+			return nil, err
+		}
+		for id := range include {
+			include[id] = include[id] && reachable[id]
+		}
+	}
+	if to != "" {
+		reachable, err := g.reachable(to, func(edge *graphEdge) (string, string) { return edge.to, edge.from })
+		if err != nil {
+			return nil, err
+		}
+		for id := range include {
+			include[id] = include[id] && reachable[id]
+		}
+	}
+	filtered := newGraph()
+	for _, node := range g.nodes {
+		if include[node.id] {
+			filtered.addNode(node)
+		}
+	}
+	for _, edge := range g.edges {
+		if include[edge.from] && include[edge.to] {
+			filtered.addEdge(edge)
+		}
+	}
+	return filtered, nil
+}
+
+// reachable returns the ids of the nodes reachable from the nodes that match value,
+// including the matching nodes themselves.
+//
+// direction returns the start and end of an edge in the direction of traversal.
+func (g *graph) reachable(value string, direction func(*graphEdge) (string, string)) (map[string]bool, error) {
+	idToNextIDs := make(map[string][]string)
+	for _, edge := range g.edges {
+		start, end := direction(edge)
+		idToNextIDs[start] = append(idToNextIDs[start], end)
+	}
+	reachable := make(map[string]bool)
+	var queue []string
+	for _, node := range g.nodes {
+		if node.id == value || node.module == value {
+			reachable[node.id] = true
+			queue = append(queue, node.id)
+		}
+	}
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("%q does not match any node in the graph", value)
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, nextID := range idToNextIDs[id] {
+			if !reachable[nextID] {
+				reachable[nextID] = true
+				queue = append(queue, nextID)
+			}
+		}
+	}
+	return reachable, nil
+}
+
+// dotString returns a DOT representation of the graph.
+//
+// Nodes and edges that are part of a cycle are colored red, and unused imports are dashed.
+//
+// https://graphviz.org/doc/info/lang.html
+func (g *graph) dotString() (string, error) {
+	var lines []string
+	seenIDs := make(map[string]struct{})
+	for _, edge := range g.edges {
+		seenIDs[edge.from] = struct{}{}
+		seenIDs[edge.to] = struct{}{}
+		fromName, err := xmlEscape(edge.from)
+		if err != nil {
+			return "", err
+		}
+		toName, err := xmlEscape(edge.to)
+		if err != nil {
+			return "", err
+		}
+		var attributes []string
+		if edge.inCycle {
+			attributes = append(attributes, "color=red")
+		}
+		if edge.unused {
+			attributes = append(attributes, "style=dashed")
+		}
+		lines = append(lines, fmt.Sprintf("%q -> %q%s", fromName, toName, dotAttributesString(attributes)))
+	}
+	for _, node := range g.nodes {
+		_, seen := seenIDs[node.id]
+		if seen && !node.inCycle {
+			continue
+		}
+		name, err := xmlEscape(node.id)
+		if err != nil {
+			return "", err
+		}
+		var attributes []string
+		if node.inCycle {
+			attributes = append(attributes, "color=red")
+		}
+		lines = append(lines, fmt.Sprintf("%q%s", name, dotAttributesString(attributes)))
+	}
+	if len(lines) == 0 {
+		return "digraph {}", nil
+	}
+	return "digraph {\n\n  " + strings.Join(lines, "\n  ") + "\n\n}", nil
+}
+
+// mermaidString returns a Mermaid flowchart representation of the graph.
+//
+// Nodes and edges that are part of a cycle are colored red, and unused imports are dotted.
+//
+// https://mermaid.js.org/syntax/flowchart.html
+func (g *graph) mermaidString() string {
+	idToMermaidID := make(map[string]string, len(g.nodes))
+	lines := []string{"flowchart LR"}
+	var cycleMermaidIDs []string
+	for i, node := range g.nodes {
+		mermaidID := "n" + strconv.Itoa(i)
+		idToMermaidID[node.id] = mermaidID
+		lines = append(lines, fmt.Sprintf(`  %s["%s"]`, mermaidID, mermaidEscape(node.id)))
+		if node.inCycle {
+			cycleMermaidIDs = append(cycleMermaidIDs, mermaidID)
+		}
+	}
+	var cycleEdgeIndexes []string
+	for i, edge := range g.edges {
+		arrow := "-->"
+		if edge.unused {
+			arrow = "-. unused .->"
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", idToMermaidID[edge.from], arrow, idToMermaidID[edge.to]))
+		if edge.inCycle {
+			cycleEdgeIndexes = append(cycleEdgeIndexes, strconv.Itoa(i))
+		}
+	}
+	if len(cycleMermaidIDs) > 0 {
+		lines = append(
+			lines,
+			"  classDef cycle stroke:#f00,color:#f00",
+			"  class "+strings.Join(cycleMermaidIDs, ",")+" cycle",
+		)
+	}
+	if len(cycleEdgeIndexes) > 0 {
+		lines = append(lines, "  linkStyle "+strings.Join(cycleEdgeIndexes, ",")+" stroke:#f00")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// graphMLString returns a GraphML representation of the graph.
+//
+// http://graphml.graphdrawing.org
+func (g *graph) graphMLString() (string, error) {
+	idToGraphMLID := make(map[string]string, len(g.nodes))
+	externalGraph := externalGraphMLGraph{
+		ID:          "G",
+		EdgeDefault: "directed",
+	}
+	for i, node := range g.nodes {
+		graphMLID := "n" + strconv.Itoa(i)
+		idToGraphMLID[node.id] = graphMLID
+		externalGraph.Nodes = append(
+			externalGraph.Nodes,
+			externalGraphMLNode{
+				ID: graphMLID,
+				Data: []externalGraphMLData{
+					{Key: "label", Value: node.id},
+					{Key: "module", Value: node.module},
+					{Key: "local", Value: strconv.FormatBool(node.local)},
+					{Key: "cycle", Value: strconv.FormatBool(node.inCycle)},
+				},
+			},
+		)
+	}
+	for _, edge := range g.edges {
+		externalGraph.Edges = append(
+			externalGraph.Edges,
+			externalGraphMLEdge{
+				Source: idToGraphMLID[edge.from],
+				Target: idToGraphMLID[edge.to],
+				Data: []externalGraphMLData{
+					{Key: "unused", Value: strconv.FormatBool(edge.unused)},
+					{Key: "cycle", Value: strconv.FormatBool(edge.inCycle)},
+				},
+			},
+		)
+	}
+	data, err := xml.MarshalIndent(
+		externalGraphML{
+			XMLNS: "http://graphml.graphdrawing.org/xmlns",
+			Keys: []externalGraphMLKey{
+				{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+				{ID: "module", For: "node", AttrName: "module", AttrType: "string"},
+				{ID: "local", For: "node", AttrName: "local", AttrType: "boolean"},
+				{ID: "unused", For: "edge", AttrName: "unused", AttrType: "boolean"},
+				{ID: "cycle", For: "all", AttrName: "cycle", AttrType: "boolean"},
+			},
+			Graph: externalGraph,
+		},
+		"",
+		"  ",
+	)
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(data), nil
+}
+
+type externalGraphML struct {
+	XMLName xml.Name             `xml:"graphml"`
+	XMLNS   string               `xml:"xmlns,attr"`
+	Keys    []externalGraphMLKey `xml:"key"`
+	Graph   externalGraphMLGraph `xml:"graph"`
+}
+
+type externalGraphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type externalGraphMLGraph struct {
+	ID          string                `xml:"id,attr"`
+	EdgeDefault string                `xml:"edgedefault,attr"`
+	Nodes       []externalGraphMLNode `xml:"node"`
+	Edges       []externalGraphMLEdge `xml:"edge"`
+}
+
+type externalGraphMLNode struct {
+	ID   string                `xml:"id,attr"`
+	Data []externalGraphMLData `xml:"data"`
+}
+
+type externalGraphMLEdge struct {
+	Source string                `xml:"source,attr"`
+	Target string                `xml:"target,attr"`
+	Data   []externalGraphMLData `xml:"data"`
+}
+
+type externalGraphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func dotAttributesString(attributes []string) string {
+	if len(attributes) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(attributes, ", ") + "]"
+}
+
+func xmlEscape(s string) (string, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := xml.EscapeText(buffer, []byte(s)); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+// mermaidEscape escapes double quotes, which cannot appear in quoted Mermaid labels.
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
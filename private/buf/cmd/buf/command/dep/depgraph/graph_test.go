@@ -0,0 +1,139 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depgraph
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphMarkCycles(t *testing.T) {
+	t.Parallel()
+	graph := testNewGraph(
+		[][2]string{
+			{"a", "b"},
+			{"b", "c"},
+			{"c", "b"},
+			{"c", "d"},
+			{"e", "e"},
+		},
+	)
+	graph.markCycles()
+	require.Equal(t, []string{"b", "c", "e"}, testCycleNodeIDs(graph))
+	require.Equal(t, []string{"b->c", "c->b", "e->e"}, testCycleEdgeStrings(graph))
+}
+
+func TestGraphFilter(t *testing.T) {
+	t.Parallel()
+	graph := testNewGraph(
+		[][2]string{
+			{"a", "b"},
+			{"b", "c"},
+			{"a", "d"},
+			{"e", "c"},
+		},
+	)
+	filtered, err := graph.filter("a", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c", "d"}, testNodeIDs(filtered))
+	filtered, err = graph.filter("", "c")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c", "e"}, testNodeIDs(filtered))
+	filtered, err = graph.filter("a", "c")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, testNodeIDs(filtered))
+	require.Len(t, filtered.edges, 2)
+	// Nodes also match by module.
+	filtered, err = graph.filter("module-e", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"c", "e"}, testNodeIDs(filtered))
+	_, err = graph.filter("f", "")
+	require.Error(t, err)
+}
+
+func TestGraphDOTString(t *testing.T) {
+	t.Parallel()
+	graph := testNewGraph(
+		[][2]string{
+			{"a", "b"},
+			{"b", "a"},
+		},
+	)
+	graph.addNode(&graphNode{id: "c"})
+	graph.addNode(&graphNode{id: "d"})
+	graph.addEdge(&graphEdge{from: "c", to: "d", unused: true})
+	graph.markCycles()
+	dotString, err := graph.dotString()
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`digraph {
+
+  "a" -> "b" [color=red]
+  "b" -> "a" [color=red]
+  "c" -> "d" [style=dashed]
+  "a" [color=red]
+  "b" [color=red]
+
+}`,
+		dotString,
+	)
+	dotString, err = newGraph().dotString()
+	require.NoError(t, err)
+	require.Equal(t, "digraph {}", dotString)
+}
+
+func testNewGraph(edges [][2]string) *graph {
+	graph := newGraph()
+	for _, edge := range edges {
+		graph.addNode(&graphNode{id: edge[0], module: "module-" + edge[0]})
+		graph.addNode(&graphNode{id: edge[1], module: "module-" + edge[1]})
+		graph.addEdge(&graphEdge{from: edge[0], to: edge[1]})
+	}
+	return graph
+}
+
+func testNodeIDs(graph *graph) []string {
+	var ids []string
+	for _, node := range graph.nodes {
+		ids = append(ids, node.id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func testCycleNodeIDs(graph *graph) []string {
+	var ids []string
+	for _, node := range graph.nodes {
+		if node.inCycle {
+			ids = append(ids, node.id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func testCycleEdgeStrings(graph *graph) []string {
+	var edgeStrings []string
+	for _, edge := range graph.edges {
+		if edge.inCycle {
+			edgeStrings = append(edgeStrings, edge.from+"->"+edge.to)
+		}
+	}
+	slices.Sort(edgeStrings)
+	return edgeStrings
+}
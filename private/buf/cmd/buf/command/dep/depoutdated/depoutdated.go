@@ -0,0 +1,256 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depoutdated
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	formatFlagName = "format"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <directory>",
+		Short: "Print the dependencies in a buf.lock that are behind their latest commit",
+		Long: `The first argument is the directory of your buf.yaml configuration file.
+Defaults to "." if no argument is specified.
+
+Each pinned dependency in the buf.lock is compared with the latest commit for the ref of the
+dependency in your buf.yaml deps, or the default label if the dependency is not declared in
+your buf.yaml deps. How far behind a pin is is the time between the creation of the pinned
+commit and the latest commit. The buf.lock is not modified, use "buf dep update" to update it.`,
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Format string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	dirPath := "."
+	if container.NumArgs() > 0 {
+		dirPath = container.Arg(0)
+	}
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	controller, err := bufcli.NewController(container)
+	if err != nil {
+		return err
+	}
+	workspaceDepManager, err := controller.GetWorkspaceDepManager(ctx, dirPath)
+	if err != nil {
+		return err
+	}
+	configuredDepModuleRefs, err := workspaceDepManager.ConfiguredDepModuleRefs(ctx)
+	if err != nil {
+		return err
+	}
+	fullNameStringToConfiguredDepModuleRef := make(map[string]bufparse.Ref, len(configuredDepModuleRefs))
+	for _, configuredDepModuleRef := range configuredDepModuleRefs {
+		fullNameStringToConfiguredDepModuleRef[configuredDepModuleRef.FullName().String()] = configuredDepModuleRef
+	}
+	pinnedModuleKeys, err := workspaceDepManager.ExistingBufLockFileDepModuleKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pinnedModuleKeys) == 0 {
+		_, err := fmt.Fprintln(container.Stderr(), "no dependencies are pinned in the buf.lock")
+		return err
+	}
+	moduleRefs := make([]bufparse.Ref, len(pinnedModuleKeys))
+	for i, pinnedModuleKey := range pinnedModuleKeys {
+		moduleRef, ok := fullNameStringToConfiguredDepModuleRef[pinnedModuleKey.FullName().String()]
+		if !ok {
+			// Transitive dependencies that are not declared in buf.yaml are compared
+			// with the default label.
+			moduleRef, err = bufparse.NewRef(
+				pinnedModuleKey.FullName().Registry(),
+				pinnedModuleKey.FullName().Owner(),
+				pinnedModuleKey.FullName().Name(),
+				"",
+			)
+			if err != nil {
+				return err
+			}
+		}
+		moduleRefs[i] = moduleRef
+	}
+	moduleKeyProvider, err := bufcli.NewModuleKeyProvider(container)
+	if err != nil {
+		return err
+	}
+	latestModuleKeys, err := moduleKeyProvider.GetModuleKeysForModuleRefs(
+		ctx,
+		moduleRefs,
+		workspaceDepManager.BufLockFileDigestType(),
+	)
+	if err != nil {
+		return err
+	}
+	commitProvider, err := bufcli.NewCommitProvider(container)
+	if err != nil {
+		return err
+	}
+	pinnedCommits, err := commitProvider.GetCommitsForModuleKeys(ctx, pinnedModuleKeys)
+	if err != nil {
+		return err
+	}
+	latestCommits, err := commitProvider.GetCommitsForModuleKeys(ctx, latestModuleKeys)
+	if err != nil {
+		return err
+	}
+	externalDeps := make([]externalDep, len(pinnedModuleKeys))
+	for i := range pinnedModuleKeys {
+		externalDep, err := newExternalDep(moduleRefs[i], pinnedCommits[i], latestCommits[i])
+		if err != nil {
+			return err
+		}
+		externalDeps[i] = externalDep
+	}
+	switch format {
+	case bufprint.FormatText:
+		return bufprint.WithTabWriter(
+			container.Stdout(),
+			[]string{
+				"Module",
+				"Ref",
+				"Pinned",
+				"Latest",
+				"Behind",
+			},
+			func(tabWriter bufprint.TabWriter) error {
+				for _, externalDep := range externalDeps {
+					if err := tabWriter.Write(
+						externalDep.Module,
+						externalDep.Ref,
+						externalDep.PinnedCommit,
+						externalDep.LatestCommit,
+						behindString(externalDep),
+					); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		)
+	case bufprint.FormatJSON:
+		encoder := json.NewEncoder(container.Stdout())
+		for _, externalDep := range externalDeps {
+			if err := encoder.Encode(externalDep); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+func newExternalDep(
+	moduleRef bufparse.Ref,
+	pinnedCommit bufmodule.Commit,
+	latestCommit bufmodule.Commit,
+) (externalDep, error) {
+	pinnedCreateTime, err := pinnedCommit.CreateTime()
+	if err != nil {
+		return externalDep{}, err
+	}
+	latestCreateTime, err := latestCommit.CreateTime()
+	if err != nil {
+		return externalDep{}, err
+	}
+	externalDep := externalDep{
+		Module:       moduleRef.FullName().String(),
+		Ref:          moduleRef.Ref(),
+		PinnedCommit: uuidutil.ToDashless(pinnedCommit.ModuleKey().CommitID()),
+		LatestCommit: uuidutil.ToDashless(latestCommit.ModuleKey().CommitID()),
+		UpToDate:     pinnedCommit.ModuleKey().CommitID() == latestCommit.ModuleKey().CommitID(),
+	}
+	if !externalDep.UpToDate && latestCreateTime.After(pinnedCreateTime) {
+		externalDep.BehindSeconds = int64(latestCreateTime.Sub(pinnedCreateTime) / time.Second)
+	}
+	return externalDep, nil
+}
+
+func behindString(externalDep externalDep) string {
+	if externalDep.UpToDate {
+		return "up to date"
+	}
+	behind := time.Duration(externalDep.BehindSeconds) * time.Second
+	switch {
+	case behind >= 48*time.Hour:
+		return fmt.Sprintf("%d days", behind/(24*time.Hour))
+	case behind >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", behind/time.Hour)
+	default:
+		return "less than 2 hours"
+	}
+}
+
+type externalDep struct {
+	Module       string `json:"module,omitempty"`
+	Ref          string `json:"ref,omitempty"`
+	PinnedCommit string `json:"pinned_commit,omitempty"`
+	LatestCommit string `json:"latest_commit,omitempty"`
+	UpToDate     bool   `json:"up_to_date"`
+	// BehindSeconds is the number of seconds between the creation of the pinned
+	// commit and the latest commit.
+	BehindSeconds int64 `json:"behind_seconds"`
+}
@@ -75,6 +75,9 @@ func run(
 	if err != nil {
 		return err
 	}
+	if err := internal.VerifyDepSignatures(ctx, container, workspaceDepManager, configuredDepModuleKeys); err != nil {
+		return err
+	}
 	return internal.Prune(
 		ctx,
 		container.Logger(),
@@ -0,0 +1,202 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depsbom
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"buf.build/go/spdx"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufsbom"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	disableSymlinksFlagName = "disable-symlinks"
+	formatFlagName          = "format"
+	allowedLicensesFlagName = "allowed-licenses"
+
+	cycloneDXFormatString = "cyclonedx"
+	spdxFormatString      = "spdx"
+)
+
+var (
+	allSBOMFormatStrings = []string{
+		cycloneDXFormatString,
+		spdxFormatString,
+	}
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Print a software bill of materials for the modules and their dependencies",
+		Long: `The target modules of the input and all their dependencies are listed with their
+full name, commit, digest, and license.
+
+The license of each module is detected from the LICENSE file of the module. An
+SPDX-License-Identifier line in the file takes precedence, otherwise the text is matched against
+commonly used licenses.
+
+If --` + allowedLicensesFlagName + ` is set, the command fails if the license of any dependency
+is not one of the given SPDX license IDs, or could not be detected. Local modules are not checked.
+` + bufcli.GetSourceOrModuleLong(`the source or module to print the software bill of materials for`),
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	DisableSymlinks bool
+	Format          string
+	AllowedLicenses []string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		cycloneDXFormatString,
+		fmt.Sprintf(
+			"The format to print the software bill of materials as. Must be one of %s",
+			stringutil.SliceToString(allSBOMFormatStrings),
+		),
+	)
+	flagSet.StringSliceVar(
+		&f.AllowedLicenses,
+		allowedLicensesFlagName,
+		nil,
+		`The SPDX license IDs that dependencies are allowed to have, such as "Apache-2.0". May be passed multiple times`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(allSBOMFormatStrings, flags.Format) {
+		return appcmd.NewInvalidArgumentErrorf("invalid value for --%s: %s", formatFlagName, flags.Format)
+	}
+	allowedLicenseIDs := make(map[string]struct{}, len(flags.AllowedLicenses))
+	for _, allowedLicense := range flags.AllowedLicenses {
+		license, ok := spdx.LicenseForID(allowedLicense)
+		if !ok {
+			return appcmd.NewInvalidArgumentErrorf("--%s: unknown SPDX license ID %q", allowedLicensesFlagName, allowedLicense)
+		}
+		allowedLicenseIDs[license.ID] = struct{}{}
+	}
+	controller, err := bufcli.NewController(
+		container,
+		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
+		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
+	)
+	if err != nil {
+		return err
+	}
+	workspace, err := controller.GetWorkspace(ctx, input)
+	if err != nil {
+		return err
+	}
+	modules, err := bufsbom.ModulesForModuleSet(ctx, workspace)
+	if err != nil {
+		return err
+	}
+	if len(allowedLicenseIDs) > 0 {
+		var disallowedModuleStrings []string
+		for _, module := range modules {
+			if module.Local {
+				continue
+			}
+			if _, ok := allowedLicenseIDs[module.LicenseID]; ok {
+				continue
+			}
+			licenseID := module.LicenseID
+			if licenseID == "" {
+				licenseID = "unknown"
+			}
+			disallowedModuleStrings = append(disallowedModuleStrings, fmt.Sprintf("%s (%s)", module.Name, licenseID))
+		}
+		if len(disallowedModuleStrings) > 0 {
+			return fmt.Errorf(
+				"dependencies with licenses that are not allowed: %s",
+				strings.Join(disallowedModuleStrings, ", "),
+			)
+		}
+	}
+	documentID, err := uuidutil.New()
+	if err != nil {
+		return err
+	}
+	documentInfo := bufsbom.DocumentInfo{
+		Name:        input,
+		ID:          documentID,
+		Time:        time.Now(),
+		ToolName:    "buf",
+		ToolVersion: bufcli.Version,
+	}
+	switch flags.Format {
+	case cycloneDXFormatString:
+		return bufsbom.WriteCycloneDX(container.Stdout(), documentInfo, modules)
+	case spdxFormatString:
+		return bufsbom.WriteSPDX(container.Stdout(), documentInfo, modules)
+	default:
+		return syserror.Newf("unknown format: %s", flags.Format)
+	}
+}
@@ -32,7 +32,8 @@ import (
 )
 
 const (
-	onlyFlagName = "only"
+	onlyFlagName    = "only"
+	previewFlagName = "preview"
 )
 
 // NewCommand returns a new update Command.
@@ -50,7 +51,11 @@ func NewCommand(
 and write them and their transitive dependencies to buf.lock.
 
 The first argument is the directory of the local module to update.
-Defaults to "." if no argument is specified.`,
+Defaults to "." if no argument is specified.
+
+If --preview is set, the buf.lock is not modified. Instead, a summary of the changes to each
+dependency is printed. The types of each updated dependency that are referenced by your modules
+are checked for breaking changes and new deprecations between the pinned and the latest commit.`,
 		Args:       appcmd.MaximumNArgs(1),
 		Deprecated: deprecated,
 		Hidden:     hidden,
@@ -64,7 +69,8 @@ Defaults to "." if no argument is specified.`,
 }
 
 type flags struct {
-	Only []string
+	Only    []string
+	Preview bool
 }
 
 func newFlags() *flags {
@@ -80,6 +86,12 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	)
 	// TODO FUTURE: implement
 	_ = flagSet.MarkHidden(onlyFlagName)
+	flagSet.BoolVar(
+		&f.Preview,
+		previewFlagName,
+		false,
+		"Print a summary of the changes to each dependency, including breaking changes to referenced types, without modifying the buf.lock",
+	)
 }
 
 // run update the buf.lock file for a specific module.
@@ -119,6 +131,9 @@ func run(
 	if err != nil {
 		return err
 	}
+	if err := internal.VerifyDepSignatures(ctx, container, workspaceDepManager, configuredDepModuleKeys); err != nil {
+		return err
+	}
 	logger.DebugContext(
 		ctx,
 		"all deps",
@@ -139,6 +154,16 @@ func run(
 		logger.Warn(fmt.Sprintf("No configured dependencies were found to update in %q.", dirPath))
 		return nil
 	}
+	if flags.Preview {
+		return runPreview(
+			ctx,
+			container,
+			controller,
+			dirPath,
+			existingDepModuleKeys,
+			configuredDepModuleKeys,
+		)
+	}
 
 	// We're about to edit the buf.lock file on disk. If we have a subsequent error,
 	// attempt to revert the buf.lock file.
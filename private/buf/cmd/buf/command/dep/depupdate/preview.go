@@ -0,0 +1,416 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/bufworkspace"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/bufbuild/buf/private/pkg/wasm"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// runPreview prints a summary of the changes to the dependencies that would be made by
+// updating the buf.lock from existingDepModuleKeys to configuredDepModuleKeys.
+//
+// The workspace is built against the new dependencies in memory, and the buf.lock is never
+// written.
+func runPreview(
+	ctx context.Context,
+	container appext.Container,
+	controller bufctl.Controller,
+	dirPath string,
+	existingDepModuleKeys []bufmodule.ModuleKey,
+	configuredDepModuleKeys []bufmodule.ModuleKey,
+) (retErr error) {
+	var oldWorkspace bufworkspace.Workspace
+	referencedTypeNames := make(map[string]struct{})
+	if len(existingDepModuleKeys) > 0 {
+		var err error
+		oldWorkspace, err = controller.GetWorkspace(ctx, dirPath, bufctl.WithIgnoreAndDisallowV1BufWorkYAMLs())
+		if err != nil {
+			return err
+		}
+		oldImage, err := controller.GetImageForWorkspace(
+			ctx,
+			oldWorkspace,
+			bufctl.WithImageExcludeSourceInfo(true),
+		)
+		if err != nil {
+			return err
+		}
+		for _, imageFile := range oldImage.Files() {
+			if !imageFile.IsImport() {
+				addReferencedTypeNames(referencedTypeNames, imageFile.FileDescriptorProto())
+			}
+		}
+	}
+	newWorkspace, err := controller.GetWorkspace(
+		ctx,
+		dirPath,
+		bufctl.WithIgnoreAndDisallowV1BufWorkYAMLs(),
+		bufctl.WithBufLockDepModuleKeys(configuredDepModuleKeys),
+	)
+	if err != nil {
+		return err
+	}
+	wasmRuntimeCacheDir, err := bufcli.CreateWasmRuntimeCacheDir(container)
+	if err != nil {
+		return err
+	}
+	wasmRuntime, err := wasm.NewRuntime(ctx, wasm.WithLocalCacheDir(wasmRuntimeCacheDir))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, wasmRuntime.Close(ctx))
+	}()
+	client, err := bufcheck.NewClient(
+		container.Logger(),
+		bufcheck.NewRunnerProvider(wasmRuntime),
+		bufcheck.ClientWithStderr(container.Stderr()),
+	)
+	if err != nil {
+		return err
+	}
+	fullNameStringToExistingDepModuleKey := make(map[string]bufmodule.ModuleKey, len(existingDepModuleKeys))
+	for _, existingDepModuleKey := range existingDepModuleKeys {
+		fullNameStringToExistingDepModuleKey[existingDepModuleKey.FullName().String()] = existingDepModuleKey
+	}
+	fullNameStringToConfiguredDepModuleKey := make(map[string]bufmodule.ModuleKey, len(configuredDepModuleKeys))
+	for _, configuredDepModuleKey := range configuredDepModuleKeys {
+		fullNameStringToConfiguredDepModuleKey[configuredDepModuleKey.FullName().String()] = configuredDepModuleKey
+	}
+	var depPreviews []*depPreview
+	for _, existingDepModuleKey := range existingDepModuleKeys {
+		if _, ok := fullNameStringToConfiguredDepModuleKey[existingDepModuleKey.FullName().String()]; !ok {
+			depPreviews = append(
+				depPreviews,
+				&depPreview{
+					fullNameString: existingDepModuleKey.FullName().String(),
+					summary:        "removed " + uuidutil.ToDashless(existingDepModuleKey.CommitID()),
+				},
+			)
+		}
+	}
+	for _, configuredDepModuleKey := range configuredDepModuleKeys {
+		fullNameString := configuredDepModuleKey.FullName().String()
+		existingDepModuleKey, ok := fullNameStringToExistingDepModuleKey[fullNameString]
+		if !ok {
+			depPreviews = append(
+				depPreviews,
+				&depPreview{
+					fullNameString: fullNameString,
+					summary:        "added " + uuidutil.ToDashless(configuredDepModuleKey.CommitID()),
+				},
+			)
+			continue
+		}
+		if existingDepModuleKey.CommitID() == configuredDepModuleKey.CommitID() {
+			continue
+		}
+		depPreview, err := getDepPreview(
+			ctx,
+			container,
+			client,
+			oldWorkspace,
+			newWorkspace,
+			referencedTypeNames,
+			existingDepModuleKey,
+			configuredDepModuleKey,
+		)
+		if err != nil {
+			return err
+		}
+		depPreviews = append(depPreviews, depPreview)
+	}
+	if len(depPreviews) == 0 {
+		_, err := fmt.Fprintln(container.Stdout(), "All dependencies are up to date.")
+		return err
+	}
+	sort.Slice(
+		depPreviews,
+		func(i int, j int) bool {
+			return depPreviews[i].fullNameString < depPreviews[j].fullNameString
+		},
+	)
+	for _, depPreview := range depPreviews {
+		if err := depPreview.print(container.Stdout()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getDepPreview runs breaking change detection on the types of the dependency that are
+// referenced by the target modules, between the existing and the configured commit.
+func getDepPreview(
+	ctx context.Context,
+	container appext.Container,
+	client bufcheck.Client,
+	oldWorkspace bufworkspace.Workspace,
+	newWorkspace bufworkspace.Workspace,
+	referencedTypeNames map[string]struct{},
+	existingDepModuleKey bufmodule.ModuleKey,
+	configuredDepModuleKey bufmodule.ModuleKey,
+) (*depPreview, error) {
+	depPreview := &depPreview{
+		fullNameString: configuredDepModuleKey.FullName().String(),
+		summary: fmt.Sprintf(
+			"%s -> %s",
+			uuidutil.ToDashless(existingDepModuleKey.CommitID()),
+			uuidutil.ToDashless(configuredDepModuleKey.CommitID()),
+		),
+	}
+	oldImage, err := buildImageForDep(ctx, container, oldWorkspace, depPreview.fullNameString)
+	if err != nil {
+		return nil, err
+	}
+	newImage, err := buildImageForDep(ctx, container, newWorkspace, depPreview.fullNameString)
+	if err != nil {
+		return nil, err
+	}
+	oldTypeNames := getTypeNames(oldImage)
+	newTypeNames := getTypeNames(newImage)
+	// Only the referenced types that still exist are checked for breaking changes, the
+	// referenced types that no longer exist are reported as removed.
+	var typeNames []string
+	for referencedTypeName := range referencedTypeNames {
+		if _, ok := oldTypeNames[referencedTypeName]; !ok {
+			continue
+		}
+		if _, ok := newTypeNames[referencedTypeName]; !ok {
+			depPreview.changes = append(depPreview.changes, "removed type "+referencedTypeName)
+			continue
+		}
+		typeNames = append(typeNames, referencedTypeName)
+	}
+	sort.Strings(depPreview.changes)
+	if len(typeNames) == 0 {
+		if len(depPreview.changes) == 0 {
+			depPreview.summary += " (no referenced types)"
+		}
+		return depPreview, nil
+	}
+	sort.Strings(typeNames)
+	oldImage, err = bufimageutil.ImageFilteredByTypes(oldImage, typeNames...)
+	if err != nil {
+		return nil, err
+	}
+	newImage, err = bufimageutil.ImageFilteredByTypes(newImage, typeNames...)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Breaking(
+		ctx,
+		bufconfig.DefaultBreakingConfigV2,
+		newImage,
+		oldImage,
+		bufcheck.BreakingWithExcludeImports(),
+	); err != nil {
+		var fileAnnotationSet bufanalysis.FileAnnotationSet
+		if !errors.As(err, &fileAnnotationSet) {
+			return nil, err
+		}
+		for _, fileAnnotation := range fileAnnotationSet.FileAnnotations() {
+			depPreview.changes = append(depPreview.changes, fileAnnotation.String())
+		}
+	}
+	oldDeprecatedNames := getDeprecatedNames(oldImage)
+	var newDeprecatedNames []string
+	for deprecatedName := range getDeprecatedNames(newImage) {
+		if _, ok := oldDeprecatedNames[deprecatedName]; !ok {
+			newDeprecatedNames = append(newDeprecatedNames, deprecatedName)
+		}
+	}
+	sort.Strings(newDeprecatedNames)
+	for _, newDeprecatedName := range newDeprecatedNames {
+		depPreview.changes = append(depPreview.changes, "deprecated "+newDeprecatedName)
+	}
+	return depPreview, nil
+}
+
+// buildImageForDep builds an Image with the files of the dependency as the non-imports.
+func buildImageForDep(
+	ctx context.Context,
+	container appext.Container,
+	workspace bufworkspace.Workspace,
+	fullNameString string,
+) (bufimage.Image, error) {
+	var depModule bufmodule.Module
+	for _, module := range workspace.Modules() {
+		if moduleFullName := module.FullName(); moduleFullName != nil && moduleFullName.String() == fullNameString {
+			depModule = module
+			break
+		}
+	}
+	if depModule == nil {
+		return nil, fmt.Errorf("dependency %s not found in workspace", fullNameString)
+	}
+	moduleSet, err := workspace.WithTargetOpaqueIDs(depModule.OpaqueID())
+	if err != nil {
+		return nil, err
+	}
+	return bufimage.BuildImage(
+		ctx,
+		container.Logger(),
+		bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(moduleSet),
+	)
+}
+
+type depPreview struct {
+	fullNameString string
+	summary        string
+	changes        []string
+}
+
+func (d *depPreview) print(writer io.Writer) error {
+	lines := []string{d.fullNameString + ": " + d.summary}
+	for _, change := range d.changes {
+		lines = append(lines, "  "+change)
+	}
+	_, err := fmt.Fprintln(writer, strings.Join(lines, "\n"))
+	return err
+}
+
+// addReferencedTypeNames adds the fully-qualified names of all message and enum types
+// referenced by the file to referencedTypeNames.
+func addReferencedTypeNames(referencedTypeNames map[string]struct{}, fileDescriptorProto *descriptorpb.FileDescriptorProto) {
+	addTypeName := func(typeName string) {
+		if typeName != "" {
+			referencedTypeNames[strings.TrimPrefix(typeName, ".")] = struct{}{}
+		}
+	}
+	addFields := func(fieldDescriptorProtos []*descriptorpb.FieldDescriptorProto) {
+		for _, fieldDescriptorProto := range fieldDescriptorProtos {
+			addTypeName(fieldDescriptorProto.GetTypeName())
+			addTypeName(fieldDescriptorProto.GetExtendee())
+		}
+	}
+	var addMessages func([]*descriptorpb.DescriptorProto)
+	addMessages = func(descriptorProtos []*descriptorpb.DescriptorProto) {
+		for _, descriptorProto := range descriptorProtos {
+			addFields(descriptorProto.GetField())
+			addFields(descriptorProto.GetExtension())
+			addMessages(descriptorProto.GetNestedType())
+		}
+	}
+	addMessages(fileDescriptorProto.GetMessageType())
+	addFields(fileDescriptorProto.GetExtension())
+	for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+		for _, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+			addTypeName(methodDescriptorProto.GetInputType())
+			addTypeName(methodDescriptorProto.GetOutputType())
+		}
+	}
+}
+
+// getTypeNames returns the fully-qualified names of all message and enum types declared
+// in the non-import files of the Image.
+func getTypeNames(image bufimage.Image) map[string]struct{} {
+	typeNames := make(map[string]struct{})
+	walkNonImportFiles(
+		image,
+		func(name string, _ bool, isType bool) {
+			if isType {
+				typeNames[name] = struct{}{}
+			}
+		},
+	)
+	return typeNames
+}
+
+// getDeprecatedNames returns the fully-qualified names of all deprecated elements declared
+// in the non-import files of the Image.
+func getDeprecatedNames(image bufimage.Image) map[string]struct{} {
+	deprecatedNames := make(map[string]struct{})
+	walkNonImportFiles(
+		image,
+		func(name string, deprecated bool, _ bool) {
+			if deprecated {
+				deprecatedNames[name] = struct{}{}
+			}
+		},
+	)
+	return deprecatedNames
+}
+
+// walkNonImportFiles calls f for every named element declared in the non-import files of
+// the Image. isType is true for messages and enums.
+func walkNonImportFiles(image bufimage.Image, f func(name string, deprecated bool, isType bool)) {
+	join := func(prefix string, name string) string {
+		if prefix == "" {
+			return name
+		}
+		return prefix + "." + name
+	}
+	walkFields := func(prefix string, fieldDescriptorProtos []*descriptorpb.FieldDescriptorProto) {
+		for _, fieldDescriptorProto := range fieldDescriptorProtos {
+			f(join(prefix, fieldDescriptorProto.GetName()), fieldDescriptorProto.GetOptions().GetDeprecated(), false)
+		}
+	}
+	walkEnums := func(prefix string, enumDescriptorProtos []*descriptorpb.EnumDescriptorProto) {
+		for _, enumDescriptorProto := range enumDescriptorProtos {
+			enumName := join(prefix, enumDescriptorProto.GetName())
+			f(enumName, enumDescriptorProto.GetOptions().GetDeprecated(), true)
+			for _, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+				f(join(enumName, enumValueDescriptorProto.GetName()), enumValueDescriptorProto.GetOptions().GetDeprecated(), false)
+			}
+		}
+	}
+	var walkMessages func(string, []*descriptorpb.DescriptorProto)
+	walkMessages = func(prefix string, descriptorProtos []*descriptorpb.DescriptorProto) {
+		for _, descriptorProto := range descriptorProtos {
+			messageName := join(prefix, descriptorProto.GetName())
+			f(messageName, descriptorProto.GetOptions().GetDeprecated(), true)
+			walkFields(messageName, descriptorProto.GetField())
+			walkFields(messageName, descriptorProto.GetExtension())
+			walkEnums(messageName, descriptorProto.GetEnumType())
+			walkMessages(messageName, descriptorProto.GetNestedType())
+		}
+	}
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptorProto := imageFile.FileDescriptorProto()
+		prefix := fileDescriptorProto.GetPackage()
+		walkMessages(prefix, fileDescriptorProto.GetMessageType())
+		walkEnums(prefix, fileDescriptorProto.GetEnumType())
+		walkFields(prefix, fileDescriptorProto.GetExtension())
+		for _, serviceDescriptorProto := range fileDescriptorProto.GetService() {
+			serviceName := join(prefix, serviceDescriptorProto.GetName())
+			f(serviceName, serviceDescriptorProto.GetOptions().GetDeprecated(), false)
+			for _, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+				f(join(serviceName, methodDescriptorProto.GetName()), methodDescriptorProto.GetOptions().GetDeprecated(), false)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depvendor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulevendor"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/spf13/pflag"
+)
+
+const (
+	checkFlagName = "check"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <directory>",
+		Short: "Vendor the dependencies in a buf.lock into the workspace",
+		Long: `The first argument is the directory of your buf.yaml configuration file.
+Defaults to "." if no argument is specified.
+
+Every dependency pinned in buf.lock is written to the "` + bufmodulevendor.DirPath + `" directory next to
+the buf.lock, along with a manifest of its files and the signatures of its commit. Any existing
+content of the directory is replaced.
+
+When the vendor directory is present, commands that build the workspace read dependencies from
+it instead of the cache or the BSR, so no network access is needed. Vendoring is only supported
+for v2 buf.yaml files. Workspaces with a v1 buf.yaml or a buf.work.yaml never read the vendor
+directory, so they must be migrated with "buf config migrate" first.
+
+Use --` + checkFlagName + ` to verify that the vendor directory still matches buf.lock without writing anything.`,
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Check bool
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.BoolVar(
+		&f.Check,
+		checkFlagName,
+		false,
+		"Check that the vendored dependencies match buf.lock, and exit with a non-zero exit code if they do not",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	dirPath := "."
+	if container.NumArgs() > 0 {
+		dirPath = container.Arg(0)
+	}
+	controller, err := bufcli.NewController(container)
+	if err != nil {
+		return err
+	}
+	workspaceDepManager, err := controller.GetWorkspaceDepManager(ctx, dirPath)
+	if err != nil {
+		return err
+	}
+	if workspaceDepManager.BufLockFileDigestType() != bufmodule.DigestTypeB5 {
+		return errors.New("dependencies can only be vendored for v2 buf.yaml files, as v1 buf.yaml and buf.work.yaml workspaces do not read vendored dependencies, run buf config migrate first")
+	}
+	depModuleKeys, err := workspaceDepManager.ExistingBufLockFileDepModuleKeys(ctx)
+	if err != nil {
+		return err
+	}
+	vendorDirPath := filepath.Join(dirPath, bufmodulevendor.DirPath)
+	if flags.Check {
+		if _, err := os.Stat(vendorDirPath); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("no vendored dependencies found at %s, run buf dep vendor", vendorDirPath)
+			}
+			return err
+		}
+		vendorBucket, err := storageos.NewProvider().NewReadWriteBucket(vendorDirPath)
+		if err != nil {
+			return err
+		}
+		return bufmodulevendor.Check(ctx, vendorBucket, depModuleKeys)
+	}
+	moduleDataProvider, err := bufcli.NewModuleDataProvider(container)
+	if err != nil {
+		return err
+	}
+	moduleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, depModuleKeys)
+	if err != nil {
+		return err
+	}
+	// The Commits are vendored so that their Signatures can be verified against the
+	// trusted_signing_keys of the buf.yaml without the cache or network access.
+	commitProvider, err := bufcli.NewSignatureCommitProvider(container)
+	if err != nil {
+		return err
+	}
+	commits, err := commitProvider.GetCommitsForModuleKeys(ctx, depModuleKeys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(vendorDirPath, 0755); err != nil {
+		return err
+	}
+	vendorBucket, err := storageos.NewProvider().NewReadWriteBucket(vendorDirPath)
+	if err != nil {
+		return err
+	}
+	return bufmodulevendor.Vendor(ctx, vendorBucket, moduleDatas, commits)
+}
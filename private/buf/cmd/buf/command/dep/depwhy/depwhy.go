@@ -0,0 +1,280 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package depwhy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/buf/bufworkspace"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	disableSymlinksFlagName = "disable-symlinks"
+	formatFlagName          = "format"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <module> <input>",
+		Short: "Print why a module is a dependency",
+		Long: `The first argument is the name of the dependency, such as "buf.build/acme/weather".
+The second argument is the source or module to inspect, which defaults to "." if not specified.
+
+Every path in the dependency graph from the target modules of the input to the dependency is
+printed, followed by every import of a file of the dependency. Imports that are not used by
+the importing file are marked as unused.`,
+		Args: appcmd.RangeArgs(1, 2),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	DisableSymlinks bool
+	Format          string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	depFullName, err := bufparse.ParseFullName(container.Arg(0))
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	input := "."
+	if container.NumArgs() > 1 {
+		input = container.Arg(1)
+	}
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	controller, err := bufcli.NewController(
+		container,
+		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
+		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
+	)
+	if err != nil {
+		return err
+	}
+	workspace, err := controller.GetWorkspace(ctx, input)
+	if err != nil {
+		return err
+	}
+	depModule := workspace.GetModuleForFullName(depFullName)
+	if depModule == nil {
+		return fmt.Errorf("%s is not a dependency of %s", depFullName.String(), input)
+	}
+	graph, err := bufmodule.ModuleSetToDAG(workspace)
+	if err != nil {
+		return err
+	}
+	var paths [][]string
+	for _, module := range workspace.Modules() {
+		if !module.IsTarget() || module.OpaqueID() == depModule.OpaqueID() {
+			continue
+		}
+		modulePaths, err := graph.AllPaths(module.OpaqueID(), depModule.OpaqueID())
+		if err != nil {
+			return err
+		}
+		for _, modulePath := range modulePaths {
+			paths = append(paths, slicesext.Map(modulePath, moduleToString))
+		}
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("%s is not a dependency of the target modules of %s", depFullName.String(), input)
+	}
+	externalImports, err := getExternalImports(ctx, controller, workspace, depModule)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case bufprint.FormatText:
+		var lines []string
+		lines = append(lines, "Paths:")
+		for _, path := range paths {
+			lines = append(lines, "  "+strings.Join(path, " -> "))
+		}
+		lines = append(lines, "", "Imports:")
+		if len(externalImports) == 0 {
+			lines = append(lines, "  (none)")
+		}
+		for _, externalImport := range externalImports {
+			line := fmt.Sprintf("  %s: %s imports %s", externalImport.Module, externalImport.File, externalImport.Import)
+			if !externalImport.Used {
+				line += " (unused)"
+			}
+			lines = append(lines, line)
+		}
+		_, err := fmt.Fprintln(container.Stdout(), strings.Join(lines, "\n"))
+		return err
+	case bufprint.FormatJSON:
+		return json.NewEncoder(container.Stdout()).Encode(
+			externalWhy{
+				Module:  moduleToString(depModule),
+				Paths:   paths,
+				Imports: externalImports,
+			},
+		)
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+// getExternalImports returns every import of a file of the dependency Module by a file
+// of another Module.
+func getExternalImports(
+	ctx context.Context,
+	controller bufctl.Controller,
+	workspace bufworkspace.Workspace,
+	depModule bufmodule.Module,
+) ([]externalImport, error) {
+	image, err := controller.GetImageForWorkspace(ctx, workspace)
+	if err != nil {
+		return nil, err
+	}
+	moduleReadBucket := bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(workspace)
+	// Cache lookups, as the same files are imported many times.
+	pathToModule := make(map[string]bufmodule.Module)
+	getModuleForPath := func(path string) (bufmodule.Module, error) {
+		if module, ok := pathToModule[path]; ok {
+			return module, nil
+		}
+		var module bufmodule.Module
+		fileInfo, err := moduleReadBucket.StatFileInfo(ctx, path)
+		if err != nil {
+			// Files that are not in any Module, such as the Well-Known Types, are ignored.
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+		} else {
+			module = fileInfo.Module()
+		}
+		pathToModule[path] = module
+		return module, nil
+	}
+	var externalImports []externalImport
+	for _, imageFile := range image.Files() {
+		module, err := getModuleForPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		if module == nil || module.OpaqueID() == depModule.OpaqueID() {
+			continue
+		}
+		unusedIndexes := make(map[int32]struct{})
+		for _, unusedIndex := range imageFile.UnusedDependencyIndexes() {
+			unusedIndexes[unusedIndex] = struct{}{}
+		}
+		for i, importPath := range imageFile.FileDescriptorProto().GetDependency() {
+			importModule, err := getModuleForPath(importPath)
+			if err != nil {
+				return nil, err
+			}
+			if importModule == nil || importModule.OpaqueID() != depModule.OpaqueID() {
+				continue
+			}
+			_, unused := unusedIndexes[int32(i)]
+			externalImports = append(
+				externalImports,
+				externalImport{
+					Module: moduleToString(module),
+					File:   imageFile.Path(),
+					Import: importPath,
+					Used:   !unused,
+				},
+			)
+		}
+	}
+	return externalImports, nil
+}
+
+func moduleToString(module bufmodule.Module) string {
+	if moduleFullName := module.FullName(); moduleFullName != nil {
+		if commitID := module.CommitID(); commitID != uuid.Nil {
+			return moduleFullName.String() + ":" + uuidutil.ToDashless(commitID)
+		}
+		return moduleFullName.String()
+	}
+	return module.OpaqueID()
+}
+
+type externalWhy struct {
+	Module  string           `json:"module,omitempty"`
+	Paths   [][]string       `json:"paths,omitempty"`
+	Imports []externalImport `json:"imports,omitempty"`
+}
+
+type externalImport struct {
+	Module string `json:"module,omitempty"`
+	File   string `json:"file,omitempty"`
+	Import string `json:"import,omitempty"`
+	Used   bool   `json:"used"`
+}
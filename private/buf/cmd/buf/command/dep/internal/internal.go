@@ -52,6 +52,37 @@ func ModuleKeysAndTransitiveDepModuleKeysForModuleRefs(
 	return moduleKeysAndTransitiveDepModuleKeysForModuleKeys(ctx, container, moduleKeys)
 }
 
+// VerifyDepSignatures verifies that each of the dependency ModuleKeys is signed by one of
+// the trusted signing keys of the buf.yaml.
+//
+// If the buf.yaml has no trusted signing keys, this is a no-op.
+func VerifyDepSignatures(
+	ctx context.Context,
+	container appext.Container,
+	workspaceDepManager bufworkspace.WorkspaceDepManager,
+	depModuleKeys []bufmodule.ModuleKey,
+) error {
+	trustedSigningKeys, err := workspaceDepManager.TrustedSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(trustedSigningKeys) == 0 || len(depModuleKeys) == 0 {
+		return nil
+	}
+	commitProvider, err := bufcli.NewSignatureCommitProvider(container)
+	if err != nil {
+		return err
+	}
+	commits, err := commitProvider.GetCommitsForModuleKeys(ctx, depModuleKeys)
+	if err != nil {
+		return err
+	}
+	if err := bufmodule.VerifyCommitSignatures(commits, trustedSigningKeys); err != nil {
+		return fmt.Errorf("signature verification failed: %w", err)
+	}
+	return nil
+}
+
 // Prune prunes the buf.lock.
 //
 // Used by dep/mod prune.
@@ -22,6 +22,8 @@ import (
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufformat"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/gen/data/datawkt"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
@@ -49,9 +51,13 @@ func NewCommand(
 ) *appcmd.Command {
 	flags := newFlags()
 	return &appcmd.Command{
-		Use:   name + " <source>",
+		Use:   name + " <input>",
 		Short: "Export proto files from one location to another",
-		Long: bufcli.GetSourceOrModuleLong(`the source or module to export`) + `
+		Long: bufcli.GetInputLong(`the source, module, or image to export`) + `
+
+If the input is an image, the .proto files are reconstructed from the descriptors of the
+image, and formatted. Comments are reconstructed from source code info if the image contains
+it. Building the reconstructed files results in an image equivalent to the input.
 
 Examples:
 
@@ -74,6 +80,10 @@ Export a specific version of a remote module to a local directory.
 Export a git repo to a local directory.
 
     $ buf export https://github.com/owner/repository.git --output=<output-dir>
+
+Reconstruct proto files from an image to a local directory.
+
+    $ buf export image.binpb --output=<output-dir>
 `,
 		Args: appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -139,6 +149,13 @@ func run(
 	if err != nil {
 		return err
 	}
+	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
+	if err != nil {
+		return err
+	}
+	if _, ok := ref.(buffetch.MessageRef); ok {
+		return exportImage(ctx, controller, input, flags)
+	}
 	workspace, err := controller.GetWorkspace(
 		ctx,
 		input,
@@ -149,18 +166,7 @@ func run(
 		return err
 	}
 	moduleReadBucket := bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(workspace)
-
-	if err := os.MkdirAll(flags.Output, 0755); err != nil {
-		return err
-	}
-	var options []storageos.ProviderOption
-	if !flags.DisableSymlinks {
-		options = append(options, storageos.ProviderWithSymlinks())
-	}
-	readWriteBucket, err := storageos.NewProvider(options...).NewReadWriteBucket(
-		flags.Output,
-		storageos.ReadWriteBucketWithSymlinksIfSupported(),
-	)
+	readWriteBucket, err := newOutputReadWriteBucket(flags)
 	if err != nil {
 		return err
 	}
@@ -226,3 +232,62 @@ func run(
 	}
 	return nil
 }
+
+// exportImage reconstructs the .proto files of an image input, as images do not
+// contain the original files.
+func exportImage(
+	ctx context.Context,
+	controller bufctl.Controller,
+	input string,
+	flags *flags,
+) error {
+	image, err := controller.GetImage(
+		ctx,
+		input,
+		bufctl.WithTargetPaths(flags.Paths, flags.ExcludePaths),
+		bufctl.WithImageExcludeImports(flags.ExcludeImports),
+	)
+	if err != nil {
+		return err
+	}
+	imageFiles := image.Files()
+	if len(imageFiles) == 0 {
+		return errors.New("no .proto target files found")
+	}
+	formattedReadBucket, err := bufformat.FormatImage(ctx, image)
+	if err != nil {
+		return err
+	}
+	readWriteBucket, err := newOutputReadWriteBucket(flags)
+	if err != nil {
+		return err
+	}
+	for _, imageFile := range imageFiles {
+		if imageFile.IsImport() && datawkt.Exists(imageFile.Path()) {
+			// WKTs are implicitly added to images as imports, see above.
+			continue
+		}
+		data, err := storage.ReadPath(ctx, formattedReadBucket, imageFile.Path())
+		if err != nil {
+			return syserror.Wrap(err)
+		}
+		if err := storage.PutPath(ctx, readWriteBucket, imageFile.Path(), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newOutputReadWriteBucket(flags *flags) (storage.ReadWriteBucket, error) {
+	if err := os.MkdirAll(flags.Output, 0755); err != nil {
+		return nil, err
+	}
+	var options []storageos.ProviderOption
+	if !flags.DisableSymlinks {
+		options = append(options, storageos.ProviderWithSymlinks())
+	}
+	return storageos.NewProvider(options...).NewReadWriteBucket(
+		flags.Output,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+}
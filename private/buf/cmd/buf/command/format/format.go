@@ -49,6 +49,7 @@ const (
 	outputFlagName          = "output"
 	outputFlagShortName     = "o"
 	pathsFlagName           = "path"
+	watchFlagName           = "watch"
 	writeFlagName           = "write"
 	writeFlagShortName      = "w"
 )
@@ -177,6 +178,7 @@ type flags struct {
 	ExitCode        bool
 	Paths           []string
 	Output          string
+	Watch           bool
 	Write           bool
 	// special
 	InputHashtag string
@@ -191,6 +193,7 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
 	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	bufcli.BindWatch(flagSet, &f.Watch, watchFlagName)
 	flagSet.BoolVarP(
 		&f.Diff,
 		diffFlagName,
@@ -263,6 +266,9 @@ func run(
 			return err
 		}
 	}
+	if flags.Watch && !flags.Write {
+		return appcmd.NewInvalidArgumentErrorf("--%s requires --%s", watchFlagName, writeFlagName)
+	}
 	if flags.Write {
 		if flags.Output != "-" {
 			return appcmd.NewInvalidArgumentErrorf("cannot use --%s when using --%s", outputFlagName, writeFlagName)
@@ -296,6 +302,32 @@ func run(
 	if err != nil {
 		return err
 	}
+	if flags.Watch {
+		return bufcli.Watch(
+			ctx,
+			container,
+			controller,
+			source,
+			nil,
+			// The files are formatted in place. The files that are rewritten start one more
+			// run, which does not rewrite them again.
+			nil,
+			func(ctx context.Context) error {
+				return format(ctx, container, controller, source, dirOrProtoFileRef, flags)
+			},
+		)
+	}
+	return format(ctx, container, controller, source, dirOrProtoFileRef, flags)
+}
+
+func format(
+	ctx context.Context,
+	container appext.Container,
+	controller bufctl.Controller,
+	source string,
+	dirOrProtoFileRef buffetch.DirOrProtoFileRef,
+	flags *flags,
+) (retErr error) {
 	workspace, err := controller.GetWorkspace(
 		ctx,
 		source,
@@ -15,13 +15,16 @@
 package generate
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
@@ -31,6 +34,8 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/spf13/pflag"
@@ -41,6 +46,7 @@ const (
 	baseOutDirPathFlagName      = "output"
 	baseOutDirPathFlagShortName = "o"
 	deleteOutsFlagName          = "clean"
+	checkFlagName               = "check"
 	errorFormatFlagName         = "error-format"
 	configFlagName              = "config"
 	pathsFlagName               = "path"
@@ -50,6 +56,12 @@ const (
 	disableSymlinksFlagName     = "disable-symlinks"
 	typeFlagName                = "type"
 	typeDeprecatedFlagName      = "include-types"
+	pluginTimeoutFlagName       = "plugin-timeout"
+	maxParallelismFlagName      = "max-parallelism"
+	affectedFlagName            = "affected"
+	watchFlagName               = "watch"
+
+	bufGenYAMLFileName = "buf.gen.yaml"
 )
 
 // NewCommand returns a new Command.
@@ -68,8 +80,10 @@ func NewCommand(
     # The valid values are v1beta1, v1 and v2.
     # Required.
     version: v2
-    # When clean is set to true, delete the directories, zip files, and/or jar files specified in the
-    # "out" field for all plugins before running code generation. Defaults to false.
+    # When clean is set to true, delete the zip files and/or jar files specified in the "out" field
+    # for all plugins before running code generation, and delete the files in the "out" directories
+    # that were previously generated and are no longer generated, as recorded in their generation
+    # manifest. Directories without a generation manifest have no files deleted. Defaults to false.
     # Optional.
     clean: true
     # The plugins to run.
@@ -95,12 +109,72 @@ func NewCommand(
         # Whether to generate code for the well-known types.
         # Optional.
         include_wkt: false
+        # The number of times to retry the execution of this remote plugin if it fails
+        # with a transient error, such as the remote being unavailable. Only valid for
+        # remote plugins.
+        # Optional.
+        retries: 3
 
         # The name of a local plugin if discoverable in "${PATH}" or its path in the file system.
       - local: protoc-gen-es
         out: gen/es
         include_imports: true
         include_wkt: true
+        # Only generate for these types or packages with this plugin. This is applied
+        # after the types of the input. If omitted, all types are generated for.
+        # Optional.
+        types:
+          - acme.public.v1
+        # Do not generate for these types or packages with this plugin. Types nested
+        # within an excluded type are also excluded. It is an error to exclude a type
+        # that is required by a type that is generated for.
+        # Optional.
+        exclude_types:
+          - acme.public.v1.InternalService
+        # Only generate for these files or directories with this plugin. Paths are
+        # relative to the module root, as in import statements, and are applied after
+        # the paths of the input.
+        # Optional.
+        paths:
+          - acme/public
+        # Do not generate for these files or directories with this plugin.
+        # Optional.
+        exclude_paths:
+          - acme/public/v1/internal.proto
+        # Post-processing steps applied to the files generated by this plugin, in order,
+        # before they are written. "buf generate --check" compares the post-processed files.
+        # Insertion points are not post-processed.
+        # Optional.
+        post_process:
+            # Add or replace the license header of each generated file. The license_type is
+            # one of "apache", "proprietary" or "none", where "none" removes the license header.
+            # The copyright_holder and year_range are required unless license_type is "none".
+            # Files with extensions that are not recognized are left as is.
+          - license_header:
+              license_type: apache
+              copyright_holder: Acme, Inc.
+              year_range: 2024
+            # Rewrite the paths of the generated files that match the regular expression. The
+            # replacement can refer to capture groups such as $1. It is an error for two files
+            # to be rewritten to the same path.
+          - rewrite_path:
+              match: ^acme/public/v1/(.*)_pb\.js$
+              replace: acme/public/$1.js
+            # Run a local command in a temporary directory containing the generated files. The
+            # paths of the generated files are appended as arguments. The files in the directory
+            # after the command completes, including any added or modified by the command, are
+            # the new generated files. This can be one string (the command) or a list (the command
+            # followed by its arguments).
+          - run: ["npx", "prettier", "--write"]
+        # The maximum duration of the execution of this plugin, including all invocations
+        # of the plugin for the strategy, such as "30s" or "5m". If the timeout expires,
+        # generation fails. This overrides the --plugin-timeout flag.
+        # Optional.
+        timeout: 5m
+        # The maximum number of invocations of this plugin to execute at once, such as
+        # with the "directory" strategy. Defaults to the number of CPUs.
+        # Optional.
+        max_parallelism: 2
 
         # The full invocation of a local plugin can be specified as a list.
       - local: ["go", "run", "path/to/plugin.go"]
@@ -140,6 +214,16 @@ func NewCommand(
         # Optional.
         protoc_path: path/to/protoc
 
+        # "protoc_builtin" also specifies the plugins that are built into buf, which do not need protoc:
+        #  - buf-jsonschema generates a JSON Schema for every message, named after the full name of
+        #    the message with the ".schema.json" extension. The schemas follow the protobuf JSON mapping,
+        #    and include protovalidate constraints as keywords and comments as descriptions.
+        #  - buf-openapiv3 generates an OpenAPI v3.1 document for every file with services, named after
+        #    the file with the ".openapi.json" extension. Unary methods are described as Connect POST
+        #    requests with JSON bodies. Streaming methods are skipped.
+      - protoc_builtin: buf-openapiv3
+        out: gen/openapi
+
     # Managed mode modifies file options and/or field options on the fly.
     managed:
       # Enables managed mode.
@@ -167,11 +251,20 @@ func NewCommand(
       #  - php_metadata_namespace
       #  - php_metadata_namespace_suffix
       #  - cc_enable_arenas
+      #  - swift_prefix
       #
       # An override rule can apply to a field option.
       # The accepted field options are:
       #  - jstype
       #
+      # An override rule can also set an editions feature or a custom option
+      # on files, messages or fields, by naming it as in a Protobuf option,
+      # for example "features.(pb.cpp).legacy_closed_enum" or "(acme.v1.retention).days".
+      # Custom message options are set with "message_option", and can be
+      # applied to a single message with "message".
+      # The value is checked against the type of the option, and enum values
+      # are set by name. Features are only set in files that use editions.
+      #
       # If multiple overrides for the same option apply to a file or field,
       # the last rule takes effect.
       # Optional.
@@ -202,6 +295,15 @@ func NewCommand(
           value: JS_STRING
           field: foo.v1.Bar.baz
 
+          # Sets the feature "(pb.java).legacy_closed_enum" for all files.
+        - file_option: features.(pb.java).legacy_closed_enum
+          value: true
+
+          # Sets the custom option "(acme.v1.cacheable)" for a message.
+        - message_option: (acme.v1.cacheable)
+          value: true
+          message: foo.v1.Bar
+
       # Disables managed mode under certain conditions.
       # Takes precedence over "overrides".
       # Optional.
@@ -220,6 +322,10 @@ func NewCommand(
         - module: buf.build/acme/weather
           file_option: csharp_namespace
 
+          # Do not modify the custom option "(acme.v1.cacheable)" for this message.
+        - message_option: (acme.v1.cacheable)
+          message: foo.v1.Baz
+
     # The inputs to generate code for.
     # The inputs here are ignored if an input is specified as a command line argument.
     # Each input is one of "directory", "git_repo", "module", "tarball", "zip_archive",
@@ -361,6 +467,24 @@ has a per-directory parallel invocation, with results from each invocation combi
 before writing the result.
 
 Insertion points are processed in the order the plugins are specified in the template.
+
+To verify that generated code that is checked in is up to date, use --check. Nothing is
+written to disk. Instead, a unified diff of every file that would be changed, added, or
+removed is printed to stdout, and the command exits with a non-zero exit code if there
+are any differences:
+
+    $ buf generate --check
+
+Files that were previously generated but are no longer generated are only reported as removed
+if the out directories would be cleaned, for example with --clean.
+
+Every out directory gets a manifest of the files generated to it, named .buf.gen.manifest.json,
+which should be checked in alongside the generated code. The manifest is updated on every
+generation, and is compared by --check. Out directories are never deleted when cleaning. Instead,
+only the files in the manifest that are no longer generated are deleted, so hand-written files in
+the out directory are left alone. If an out directory does not have a manifest yet, a warning is
+printed and no files are deleted from it. A warning is printed for every generated file that was
+modified by hand since it was generated, and such files are never deleted.
 `,
 		Args: appcmd.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -376,6 +500,7 @@ type flags struct {
 	Template               string
 	BaseOutDirPath         string
 	DeleteOuts             *bool
+	Check                  bool
 	ErrorFormat            string
 	Files                  []string
 	Config                 string
@@ -384,6 +509,10 @@ type flags struct {
 	IncludeWKTOverride     *bool
 	ExcludePaths           []string
 	DisableSymlinks        bool
+	PluginTimeout          time.Duration
+	MaxParallelism         int
+	Affected               string
+	Watch                  bool
 	// We may be able to bind two flags to one string slice but I don't
 	// want to find out what will break if we do.
 	Types           []string
@@ -401,6 +530,8 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
+	bufcli.BindAffected(flagSet, &f.Affected, affectedFlagName)
+	bufcli.BindWatch(flagSet, &f.Watch, watchFlagName)
 	bindBoolPointer(
 		flagSet,
 		includeImportsFlagName,
@@ -433,7 +564,13 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		flagSet,
 		deleteOutsFlagName,
 		&f.DeleteOuts,
-		`Prior to generation, delete the directories, jar files, or zip files that the plugins will write to. Allows cleaning of existing assets without having to call rm -rf`,
+		`Prior to generation, delete the jar files or zip files that the plugins will write to, and after generation, delete the files in the directories that the plugins write to that were previously generated and are no longer generated, as recorded in their generation manifest`,
+	)
+	flagSet.BoolVar(
+		&f.Check,
+		checkFlagName,
+		false,
+		`Instead of writing generated files, print a diff against the files on disk and exit with a non-zero exit code if they are not up to date`,
 	)
 	flagSet.StringVar(
 		&f.ErrorFormat,
@@ -464,14 +601,31 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	)
 	_ = flagSet.MarkDeprecated(typeDeprecatedFlagName, fmt.Sprintf("use --%s instead", typeFlagName))
 	_ = flagSet.MarkHidden(typeDeprecatedFlagName)
+	flagSet.DurationVar(
+		&f.PluginTimeout,
+		pluginTimeoutFlagName,
+		0,
+		`The maximum duration of the execution of each plugin that does not set a timeout in buf.gen.yaml. Setting it to zero means no timeout`,
+	)
+	flagSet.IntVar(
+		&f.MaxParallelism,
+		maxParallelismFlagName,
+		0,
+		`The maximum number of plugins to execute at once. Setting it to zero means the number of CPUs. The number of invocations of each plugin is limited separately by max_parallelism in buf.gen.yaml`,
+	)
 }
 
 func run(
 	ctx context.Context,
 	container appext.Container,
 	flags *flags,
-) (retErr error) {
-	logger := container.Logger()
+) error {
+	if flags.PluginTimeout < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must not be negative", pluginTimeoutFlagName)
+	}
+	if flags.MaxParallelism < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must not be negative", maxParallelismFlagName)
+	}
 	if flags.IncludeWKTOverride != nil &&
 		*flags.IncludeWKTOverride &&
 		(flags.IncludeImportsOverride == nil || !*flags.IncludeImportsOverride) {
@@ -481,6 +635,9 @@ func run(
 		// only makes sense in the context of including imports.
 		return appcmd.NewInvalidArgumentErrorf("Cannot set --%s to true without setting --%s to true", includeWKTFlagName, includeImportsFlagName)
 	}
+	if flags.Watch && flags.Affected != "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", watchFlagName, affectedFlagName)
+	}
 	input, err := bufcli.GetInputValue(container, flags.InputHashtag, "")
 	if err != nil {
 		return err
@@ -491,11 +648,14 @@ func run(
 	} else {
 		storageosProvider = storageos.NewProvider(storageos.ProviderWithSymlinks())
 	}
-	controller, err := bufcli.NewController(
-		container,
+	controllerOptions := []bufctl.ControllerOption{
 		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
 		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
-	)
+	}
+	if flags.Watch {
+		controllerOptions = append(controllerOptions, bufctl.WithBuildCache(bufimage.NewBuildCache()))
+	}
+	controller, err := bufcli.NewController(container, controllerOptions...)
 	if err != nil {
 		return err
 	}
@@ -503,10 +663,73 @@ func run(
 	if err != nil {
 		return err
 	}
+	if flags.Watch {
+		watchInput := input
+		if watchInput == "" {
+			watchInput = "."
+		}
+		// The template is read again on each run, so changes to it are picked up.
+		templateFilePath := bufGenYAMLFileName
+		if templateExtension := filepath.Ext(flags.Template); templateExtension == ".yaml" || templateExtension == ".yml" || templateExtension == ".json" {
+			templateFilePath = flags.Template
+		}
+		return bufcli.Watch(
+			ctx,
+			container,
+			controller,
+			watchInput,
+			[]string{templateFilePath},
+			getWatchOutputPaths(ctx, storageosProvider, flags),
+			func(ctx context.Context) error {
+				return generate(ctx, container, controller, clientConfig, storageosProvider, input, flags)
+			},
+		)
+	}
+	return generate(ctx, container, controller, clientConfig, storageosProvider, input, flags)
+}
+
+func generate(
+	ctx context.Context,
+	container appext.Container,
+	controller bufctl.Controller,
+	clientConfig *connectclient.Config,
+	storageosProvider storageos.Provider,
+	input string,
+	flags *flags,
+) error {
+	logger := container.Logger()
 	bufGenYAMLFile, err := readBufGenYAMLFile(ctx, storageosProvider, flags.Template)
 	if err != nil {
 		return err
 	}
+	paths := flags.Paths
+	generateConfig := bufGenYAMLFile.GenerateConfig()
+	if flags.Affected != "" {
+		if len(flags.Paths) > 0 {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", affectedFlagName, pathsFlagName)
+		}
+		if flags.DeleteOuts != nil && *flags.DeleteOuts {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", affectedFlagName, deleteOutsFlagName)
+		}
+		affected, err := bufcli.ReadAffected(container, flags.Affected, affectedFlagName)
+		if err != nil {
+			return err
+		}
+		// Deleted files have nothing left to generate.
+		affected.Files, _, err = affected.SplitFiles()
+		if err != nil {
+			return err
+		}
+		generateConfig, err = getAffectedGenerateConfig(generateConfig, affected)
+		if err != nil {
+			return err
+		}
+		if generateConfig == nil {
+			logger.Info("no affected files or plugins to generate")
+			return nil
+		}
+		paths = affected.Files
+	}
 	images, err := getInputImages(
 		ctx,
 		logger,
@@ -514,7 +737,7 @@ func run(
 		input,
 		bufGenYAMLFile,
 		flags.Config,
-		flags.Paths,
+		paths,
 		flags.ExcludePaths,
 		flags.Types,
 	)
@@ -542,19 +765,98 @@ func run(
 			bufgen.GenerateWithIncludeWellKnownTypesOverride(*flags.IncludeWKTOverride),
 		)
 	}
-	return bufgen.NewGenerator(
+	if flags.PluginTimeout != 0 {
+		generateOptions = append(
+			generateOptions,
+			bufgen.GenerateWithPluginTimeout(flags.PluginTimeout),
+		)
+	}
+	if flags.MaxParallelism != 0 {
+		generateOptions = append(
+			generateOptions,
+			bufgen.GenerateWithMaxParallelism(flags.MaxParallelism),
+		)
+	}
+	var checkBuffer *bytes.Buffer
+	if flags.Check {
+		checkBuffer = bytes.NewBuffer(nil)
+		generateOptions = append(
+			generateOptions,
+			bufgen.GenerateWithCheck(checkBuffer),
+		)
+	}
+	if err := bufgen.NewGenerator(
 		logger,
 		storageosProvider,
 		clientConfig,
 	).Generate(
 		ctx,
 		container,
-		bufGenYAMLFile.GenerateConfig(),
+		generateConfig,
 		images,
 		generateOptions...,
+	); err != nil {
+		return err
+	}
+	if checkBuffer != nil && checkBuffer.Len() > 0 {
+		if _, err := io.Copy(container.Stdout(), checkBuffer); err != nil {
+			return err
+		}
+		return bufctl.ErrFileAnnotation
+	}
+	return nil
+}
+
+// getAffectedGenerateConfig returns the GenerateConfig restricted to the affected plugins.
+//
+// Plugin outputs are never cleaned, as only the affected files are generated. Returns nil
+// if there are no affected files or plugins.
+func getAffectedGenerateConfig(
+	generateConfig bufconfig.GenerateConfig,
+	affected *bufcli.Affected,
+) (bufconfig.GenerateConfig, error) {
+	if len(affected.Files) == 0 {
+		return nil, nil
+	}
+	affectedPluginNames := slicesext.ToStructMap(affected.Plugins)
+	var generatePluginConfigs []bufconfig.GeneratePluginConfig
+	for _, generatePluginConfig := range generateConfig.GeneratePluginConfigs() {
+		if _, ok := affectedPluginNames[generatePluginConfig.Name()]; ok {
+			generatePluginConfigs = append(generatePluginConfigs, generatePluginConfig)
+		}
+	}
+	if len(generatePluginConfigs) == 0 {
+		return nil, nil
+	}
+	return bufconfig.NewGenerateConfig(
+		false,
+		generatePluginConfigs,
+		generateConfig.GenerateManagedConfig(),
+		generateConfig.GenerateTypeConfig(),
 	)
 }
 
+// getWatchOutputPaths returns the output paths of the plugins of the template, so that
+// the generated files are not watched.
+//
+// The template is read when watching starts. If it cannot be read, there are no output
+// paths, and the error is printed by the first run.
+func getWatchOutputPaths(
+	ctx context.Context,
+	storageosProvider storageos.Provider,
+	flags *flags,
+) []string {
+	bufGenYAMLFile, err := readBufGenYAMLFile(ctx, storageosProvider, flags.Template)
+	if err != nil {
+		return nil
+	}
+	var outputPaths []string
+	for _, generatePluginConfig := range bufGenYAMLFile.GenerateConfig().GeneratePluginConfigs() {
+		outputPaths = append(outputPaths, filepath.Join(flags.BaseOutDirPath, generatePluginConfig.Out()))
+	}
+	return outputPaths
+}
+
 func readBufGenYAMLFile(
 	ctx context.Context,
 	storageosProvider storageos.Provider,
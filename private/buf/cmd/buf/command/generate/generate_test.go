@@ -23,11 +23,15 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
+	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/buf/buftesting"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/internal/internaltesting"
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufprotoplugin/bufprotopluginos"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
@@ -165,7 +169,361 @@ func TestGenerateV2LocalPluginBasic(t *testing.T) {
 	actual, err := storageos.NewProvider().NewReadWriteBucket(tempDirPath)
 	require.NoError(t, err)
 
-	diff, err := storage.DiffBytes(context.Background(), expected, actual)
+	diff, err := storage.DiffBytes(context.Background(), expected, withoutManifests(actual))
+	require.NoError(t, err)
+	require.Empty(t, string(diff))
+}
+
+const (
+	// The digests of the files generated by protoc-gen-top-level-type-names-yaml for testdata/v2/local_plugin.
+	testTopLevelTypeNamesADigest = "shake256:49d4ea51cbcb50a481299ccf9ff27fc031d59a1cbde383bf8841a741755e29c714f59d4662152774accbdedceed265dd7ebcb67b83ba49471a620d614cd2df6e"
+	testTopLevelTypeNamesBDigest = "shake256:eb5facf599ba0f3c2fbe45e8e65d55fe0e45773d253bc37a0e0b2dd04f552839d150c6dfe4d024782643c61fef8062654dfca9ec8042cf51cb3e6a1f86b3dc4b"
+)
+
+func TestGenerateCheck(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tempDirPath := t.TempDir()
+	input := filepath.Join("testdata", "v2", "local_plugin")
+	template := filepath.Join("testdata", "v2", "local_plugin", "buf.basic.gen.yaml")
+	aPath := filepath.Join(tempDirPath, "gen", "a", "v1", "a.top-level-type-names.yaml")
+	bPath := filepath.Join(tempDirPath, "gen", "b", "v1", "b.top-level-type-names.yaml")
+
+	testRunSuccess(
+		t,
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		input,
+	)
+	// Up to date.
+	testRunStdoutStderr(
+		t,
+		nil,
+		0,
+		``,
+		``,
+		"--check",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		input,
+	)
+
+	bucket, err := storageos.NewProvider().NewReadWriteBucket(tempDirPath)
+	require.NoError(t, err)
+	require.NoError(t, storage.PutPath(ctx, bucket, "gen/a/v1/a.top-level-type-names.yaml", []byte("messages:\n    - a.v1.Bar\n")))
+	require.NoError(t, bucket.Delete(ctx, "gen/b/v1/b.top-level-type-names.yaml"))
+	require.NoError(t, storage.PutPath(ctx, bucket, "gen/extra.txt", []byte("extra\n")))
+	expectedChangedStdout := fmt.Sprintf(`diff -u %[1]s.orig %[1]s
+--- %[1]s.orig
++++ %[1]s
+@@ -1,2 +1,3 @@
+ messages:
+     - a.v1.Bar
++    - a.v1.Foo
+`,
+		aPath,
+	)
+	expectedAddedStdout := fmt.Sprintf(`diff -u %[1]s.orig %[1]s
+--- %[1]s.orig
++++ %[1]s
+@@ -0,0 +1,3 @@
++messages:
++    - b.v1.Bar
++    - b.v1.Foo
+`,
+		bPath,
+	)
+	testRunStdout(
+		t,
+		nil,
+		bufctl.ExitCodeFileAnnotation,
+		expectedChangedStdout+expectedAddedStdout,
+		"--check",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		input,
+	)
+	// The manifest written by the first generation does not have extra.txt, so it is
+	// not reported as removed even if the outs are cleaned.
+	testRunStdout(
+		t,
+		nil,
+		bufctl.ExitCodeFileAnnotation,
+		expectedChangedStdout+expectedAddedStdout,
+		"--check",
+		"--clean",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		input,
+	)
+	// Nothing was written or deleted.
+	data, err := storage.ReadPath(ctx, bucket, "gen/a/v1/a.top-level-type-names.yaml")
+	require.NoError(t, err)
+	require.Equal(t, "messages:\n    - a.v1.Bar\n", string(data))
+	_, err = storage.ReadPath(ctx, bucket, "gen/b/v1/b.top-level-type-names.yaml")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = storage.ReadPath(ctx, bucket, "gen/extra.txt")
+	require.NoError(t, err)
+}
+
+func TestGenerateCleanManifest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tempDirPath := t.TempDir()
+	input := filepath.Join("testdata", "v2", "local_plugin")
+	template := filepath.Join("testdata", "v2", "local_plugin", "buf.basic.gen.yaml")
+	aPath := "gen/a/v1/a.top-level-type-names.yaml"
+	bPath := "gen/b/v1/b.top-level-type-names.yaml"
+	extraPath := "gen/extra.txt"
+
+	// Cleaning an out directory that does not have a manifest yet deletes nothing from it.
+	bucket, err := storageos.NewProvider().NewReadWriteBucket(tempDirPath)
+	require.NoError(t, err)
+	require.NoError(t, storage.PutPath(ctx, bucket, extraPath, []byte("extra\n")))
+	testRunStderrContains(
+		t,
+		nil,
+		0,
+		[]string{
+			filepath.Join(tempDirPath, "gen") + " has no generation manifest, so no files were deleted from it",
+		},
+		"--clean",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		input,
+	)
+	_, err = storage.ReadPath(ctx, bucket, extraPath)
+	require.NoError(t, err)
+	// A manifest is written on every generation.
+	manifestData, err := storage.ReadPath(ctx, bucket, normalpath.Join("gen", bufprotopluginos.ManifestFileName))
+	require.NoError(t, err)
+	assert.Contains(t, string(manifestData), `"path": "a/v1/a.top-level-type-names.yaml"`)
+	assert.Contains(t, string(manifestData), `"path": "b/v1/b.top-level-type-names.yaml"`)
+	assert.NotContains(t, string(manifestData), `"path": "extra.txt"`)
+
+	// Only previously generated files are reported as removed.
+	testRunStdout(
+		t,
+		nil,
+		bufctl.ExitCodeFileAnnotation,
+		fmt.Sprintf(`diff -u %[1]s.orig %[1]s
+--- %[1]s.orig
++++ %[1]s
+@@ -1,3 +0,0 @@
+-messages:
+-    - b.v1.Bar
+-    - b.v1.Foo
+diff -u %[2]s.orig %[2]s
+--- %[2]s.orig
++++ %[2]s
+@@ -4,10 +4,6 @@
+     {
+       "path": "a/v1/a.top-level-type-names.yaml",
+       "digest": "%[3]s"
+-    },
+-    {
+-      "path": "b/v1/b.top-level-type-names.yaml",
+-      "digest": "%[4]s"
+     }
+   ]
+ }
+`,
+			filepath.Join(tempDirPath, "gen", "b", "v1", "b.top-level-type-names.yaml"),
+			filepath.Join(tempDirPath, "gen", bufprotopluginos.ManifestFileName),
+			testTopLevelTypeNamesADigest,
+			testTopLevelTypeNamesBDigest,
+		),
+		"--check",
+		"--clean",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		"--path",
+		filepath.Join(input, "a"),
+		input,
+	)
+	// Only the file that is no longer generated is deleted.
+	testRunSuccess(
+		t,
+		"--clean",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		"--path",
+		filepath.Join(input, "a"),
+		input,
+	)
+	_, err = storage.ReadPath(ctx, bucket, aPath)
+	require.NoError(t, err)
+	_, err = storage.ReadPath(ctx, bucket, bPath)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = storage.ReadPath(ctx, bucket, extraPath)
+	require.NoError(t, err)
+
+	// Files that were modified by hand are not deleted.
+	require.NoError(t, storage.PutPath(ctx, bucket, aPath, []byte("messages: []\n")))
+	testRunSuccess(
+		t,
+		"--clean",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		"--path",
+		filepath.Join(input, "b"),
+		input,
+	)
+	data, err := storage.ReadPath(ctx, bucket, aPath)
+	require.NoError(t, err)
+	require.Equal(t, "messages: []\n", string(data))
+	_, err = storage.ReadPath(ctx, bucket, bPath)
+	require.NoError(t, err)
+	manifestData, err = storage.ReadPath(ctx, bucket, normalpath.Join("gen", bufprotopluginos.ManifestFileName))
+	require.NoError(t, err)
+	assert.NotContains(t, string(manifestData), `"path": "a/v1/a.top-level-type-names.yaml"`)
+	assert.Contains(t, string(manifestData), `"path": "b/v1/b.top-level-type-names.yaml"`)
+
+	// An existing manifest is updated without cleaning.
+	testRunSuccess(
+		t,
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		input,
+	)
+	manifestData, err = storage.ReadPath(ctx, bucket, normalpath.Join("gen", bufprotopluginos.ManifestFileName))
+	require.NoError(t, err)
+	assert.Contains(t, string(manifestData), `"path": "a/v1/a.top-level-type-names.yaml"`)
+	assert.Contains(t, string(manifestData), `"path": "b/v1/b.top-level-type-names.yaml"`)
+	testRunStdoutStderr(
+		t,
+		nil,
+		0,
+		``,
+		``,
+		"--check",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+		input,
+	)
+}
+
+func TestGenerateV2PostProcess(t *testing.T) {
+	t.Parallel()
+
+	tempDirPath := t.TempDir()
+	template := `version: v2
+plugins:
+  - local: protoc-gen-top-level-type-names-yaml
+    out: gen
+    post_process:
+      - rewrite_path:
+          match: ^(.*)\.top-level-type-names\.yaml$
+          replace: $1.types.yaml
+      - rewrite_path:
+          match: ^b/v1/
+          replace: b/
+inputs:
+  - directory: ./testdata/v2/local_plugin`
+	testRunSuccess(
+		t,
+		"--clean",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+	)
+	expected, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			filepath.Join("gen", "a", "v1", "a.types.yaml"): []byte(`messages:
+    - a.v1.Bar
+    - a.v1.Foo
+`),
+			filepath.Join("gen", "b", "b.types.yaml"): []byte(`messages:
+    - b.v1.Bar
+    - b.v1.Foo
+`),
+		},
+	)
+	require.NoError(t, err)
+	actual, err := storageos.NewProvider().NewReadWriteBucket(tempDirPath)
+	require.NoError(t, err)
+	diff, err := storage.DiffBytes(context.Background(), expected, withoutManifests(actual))
+	require.NoError(t, err)
+	require.Empty(t, string(diff))
+	// The check compares against the post-processed files.
+	testRunStdoutStderr(
+		t,
+		nil,
+		0,
+		``,
+		``,
+		"--check",
+		"--clean",
+		"--output",
+		tempDirPath,
+		"--template",
+		template,
+	)
+}
+
+func TestGenerateV2BuiltinJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	tempDirPath := t.TempDir()
+	testRunSuccess(
+		t,
+		"--output",
+		tempDirPath,
+		"--template",
+		`version: v2
+plugins:
+  - protoc_builtin: buf-jsonschema
+    out: gen
+inputs:
+  - directory: ./testdata/v2/local_plugin
+    paths:
+      - ./testdata/v2/local_plugin/a`,
+	)
+	expectedSchema := func(fullName string) []byte {
+		return []byte(`{
+  "$defs": {
+    "` + fullName + `": {
+      "type": "object"
+    }
+  },
+  "$ref": "#/$defs/` + fullName + `",
+  "$schema": "https://json-schema.org/draft/2020-12/schema"
+}
+`)
+	}
+	expected, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			filepath.Join("gen", "a", "v1", "a.v1.Foo.schema.json"): expectedSchema("a.v1.Foo"),
+			filepath.Join("gen", "a", "v1", "a.v1.Bar.schema.json"): expectedSchema("a.v1.Bar"),
+		},
+	)
+	require.NoError(t, err)
+	actual, err := storageos.NewProvider().NewReadWriteBucket(tempDirPath)
+	require.NoError(t, err)
+	diff, err := storage.DiffBytes(context.Background(), expected, withoutManifests(actual))
 	require.NoError(t, err)
 	require.Empty(t, string(diff))
 }
@@ -188,7 +546,7 @@ func TestGenerateV2LocalPluginTypes(t *testing.T) {
 		actual, err := storageos.NewProvider().NewReadWriteBucket(tempDirPath)
 		require.NoError(t, err)
 
-		diff, err := storage.DiffBytes(context.Background(), expected, actual)
+		diff, err := storage.DiffBytes(context.Background(), expected, withoutManifests(actual))
 		require.NoError(t, err)
 		require.Empty(t, string(diff))
 	}
@@ -317,6 +675,65 @@ inputs:
 		"--type",
 		"b.v1.Bar",
 	)
+	// per-plugin filters
+	testRunTypeArgs(t, map[string][]byte{
+		filepath.Join("gen", "types", "a", "v1", "a.top-level-type-names.yaml"): []byte(`messages:
+    - a.v1.Foo
+`),
+		filepath.Join("gen", "types", "b", "v1", "b.top-level-type-names.yaml"): []byte(`messages:
+    - b.v1.Bar
+    - b.v1.Foo
+`),
+		filepath.Join("gen", "paths", "b", "v1", "b.top-level-type-names.yaml"): []byte(`messages:
+    - b.v1.Bar
+    - b.v1.Foo
+`),
+		filepath.Join("gen", "all", "a", "v1", "a.top-level-type-names.yaml"): []byte(`messages:
+    - a.v1.Bar
+    - a.v1.Foo
+`),
+		filepath.Join("gen", "all", "b", "v1", "b.top-level-type-names.yaml"): []byte(`messages:
+    - b.v1.Bar
+    - b.v1.Foo
+`),
+	},
+		"--template",
+		`version: v2
+plugins:
+  - local: protoc-gen-top-level-type-names-yaml
+    out: gen/types
+    types:
+      - a.v1.Foo
+      - b.v1
+  - local: protoc-gen-top-level-type-names-yaml
+    out: gen/paths
+    strategy: all
+    paths:
+      - b
+    exclude_paths:
+      - a/v1/a.proto
+  - local: protoc-gen-top-level-type-names-yaml
+    out: gen/all
+inputs:
+  - directory: ./testdata/v2/local_plugin`,
+	)
+	// per-plugin exclude_types
+	testRunTypeArgs(t, map[string][]byte{
+		filepath.Join("gen", "a", "v1", "a.top-level-type-names.yaml"): []byte(`messages:
+    - a.v1.Foo
+`),
+	},
+		"--template",
+		`version: v2
+plugins:
+  - local: protoc-gen-top-level-type-names-yaml
+    out: gen
+    exclude_types:
+      - a.v1.Bar
+      - b.v1
+inputs:
+  - directory: ./testdata/v2/local_plugin`,
+	)
 }
 
 func TestOutputFlag(t *testing.T) {
@@ -480,6 +897,47 @@ managed:
 	)
 }
 
+func TestGeneratePluginTimeout(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("sleep is not available on windows")
+	}
+	template := `
+version: v2
+plugins:
+  - local: ["sleep", "10"]
+    out: gen
+    timeout: 100ms
+`
+	testRunStdoutStderr(
+		t,
+		nil,
+		1,
+		``,
+		`Failure: plugin sleep 10 timed out after 100ms`,
+		filepath.Join("testdata", "v2", "local_plugin"),
+		"--template",
+		template,
+		"-o",
+		t.TempDir(),
+	)
+	// The flag applies to plugins that do not set a timeout.
+	testRunStdoutStderr(
+		t,
+		nil,
+		1,
+		``,
+		`Failure: plugin sleep 10 timed out after 200ms`,
+		filepath.Join("testdata", "v2", "local_plugin"),
+		"--template",
+		strings.Replace(template, "    timeout: 100ms\n", "", 1),
+		"--plugin-timeout",
+		"200ms",
+		"-o",
+		t.TempDir(),
+	)
+}
+
 func TestGenerateDuplicateFileFail(t *testing.T) {
 	t.Parallel()
 	successTemplate := `
@@ -649,7 +1107,7 @@ func testGenerateInsertionPoint(
 	)
 	expectedOutput, err := storageosProvider.NewReadWriteBucket(expectedOutputPath)
 	require.NoError(t, err)
-	diff, err := storage.DiffBytes(context.Background(), expectedOutput, readWriteBucket)
+	diff, err := storage.DiffBytes(context.Background(), expectedOutput, withoutManifests(readWriteBucket))
 	require.NoError(t, err)
 	require.Empty(t, string(diff))
 }
@@ -783,7 +1241,7 @@ func testCompareGeneratedStubs(
 	diff, err := storage.DiffBytes(
 		context.Background(),
 		actualReadWriteBucket,
-		bufReadWriteBucket,
+		withoutManifests(bufReadWriteBucket),
 		transformGolangProtocVersionToUnknown(t),
 	)
 	require.NoError(t, err)
@@ -885,6 +1343,40 @@ func testRunSuccess(t *testing.T, args ...string) {
 	)
 }
 
+func testRunStdout(t *testing.T, stdin io.Reader, expectedExitCode int, expectedStdout string, args ...string) {
+	appcmdtesting.RunCommandExitCodeStdout(
+		t,
+		func(name string) *appcmd.Command {
+			return NewCommand(
+				name,
+				appext.NewBuilder(name),
+			)
+		},
+		expectedExitCode,
+		expectedStdout,
+		internaltesting.NewEnvFunc(t),
+		stdin,
+		args...,
+	)
+}
+
+func testRunStderrContains(t *testing.T, stdin io.Reader, expectedExitCode int, expectedStderrPartials []string, args ...string) {
+	appcmdtesting.RunCommandExitCodeStderrContains(
+		t,
+		func(name string) *appcmd.Command {
+			return NewCommand(
+				name,
+				appext.NewBuilder(name),
+			)
+		},
+		expectedExitCode,
+		expectedStderrPartials,
+		internaltesting.NewEnvFunc(t),
+		stdin,
+		args...,
+	)
+}
+
 func testGenerateDeleteOuts(
 	t *testing.T,
 	baseOutDirPath string,
@@ -937,7 +1429,8 @@ func testGenerateDeleteOutsWithArgAndConfig(
 				),
 			)
 		default:
-			// Write a file that won't be generated to the location.
+			// Write a file that won't be generated to the location, along with a manifest
+			// that records it as previously generated, so that it is deleted when cleaning.
 			require.NoError(
 				t,
 				storage.PutPath(
@@ -947,6 +1440,17 @@ func testGenerateDeleteOutsWithArgAndConfig(
 					[]byte(`1`),
 				),
 			)
+			digest, err := bufcas.NewDigestForContent(strings.NewReader(`1`))
+			require.NoError(t, err)
+			require.NoError(
+				t,
+				storage.PutPath(
+					ctx,
+					storageBucket,
+					normalpath.Join(fullOutputPath, bufprotopluginos.ManifestFileName),
+					[]byte(fmt.Sprintf(`{"version":"v1","files":[{"path":"foo.txt","digest":%q}]}`, digest.String())),
+				),
+			)
 		}
 	}
 	var templateBuilder strings.Builder
@@ -1075,6 +1579,14 @@ func newExternalConfigV1String(t *testing.T, plugins []*testPluginInfo, out stri
 	return string(data)
 }
 
+// withoutManifests filters the generation manifests out of the ReadBucket.
+func withoutManifests(readBucket storage.ReadBucket) storage.ReadBucket {
+	return storage.FilterReadBucket(
+		readBucket,
+		storage.MatchNot(storage.MatchPathBase(bufprotopluginos.ManifestFileName)),
+	)
+}
+
 type testPluginInfo struct {
 	name string
 	opt  string
@@ -18,11 +18,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufctl"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appext"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
@@ -36,6 +38,9 @@ const (
 	pathsFlagName           = "path"
 	excludePathsFlagName    = "exclude-path"
 	disableSymlinksFlagName = "disable-symlinks"
+	listIgnoresFlagName     = "list-ignores"
+	affectedFlagName        = "affected"
+	watchFlagName           = "watch"
 )
 
 // NewCommand returns a new Command.
@@ -64,6 +69,9 @@ type flags struct {
 	Paths           []string
 	ExcludePaths    []string
 	DisableSymlinks bool
+	ListIgnores     bool
+	Affected        string
+	Watch           bool
 	// special
 	InputHashtag string
 }
@@ -77,6 +85,8 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
 	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	bufcli.BindAffected(flagSet, &f.Affected, affectedFlagName)
+	bufcli.BindWatch(flagSet, &f.Watch, watchFlagName)
 	flagSet.StringVar(
 		&f.ErrorFormat,
 		errorFormatFlagName,
@@ -92,6 +102,16 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		"",
 		`The buf.yaml file or data to use for configuration`,
 	)
+	flagSet.BoolVar(
+		&f.ListIgnores,
+		listIgnoresFlagName,
+		false,
+		fmt.Sprintf(
+			`List all comment ignores and ignore_only entries with their status and the number of ignores for each rule instead of linting.
+Printed as JSON if --%s is set to json`,
+			errorFormatFlagName,
+		),
+	)
 }
 
 func run(
@@ -112,35 +132,89 @@ func run(
 	if err != nil {
 		return err
 	}
-	controller, err := bufcli.NewController(
-		container,
+	paths := flags.Paths
+	if flags.Affected != "" {
+		if len(flags.Paths) > 0 {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", affectedFlagName, pathsFlagName)
+		}
+		affected, err := bufcli.ReadAffected(container, flags.Affected, affectedFlagName)
+		if err != nil {
+			return err
+		}
+		// Deleted files have nothing left to lint.
+		existingFiles, _, err := affected.SplitFiles()
+		if err != nil {
+			return err
+		}
+		if len(existingFiles) == 0 {
+			return nil
+		}
+		paths = existingFiles
+	}
+	controllerOptions := []bufctl.ControllerOption{
 		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
 		bufctl.WithFileAnnotationErrorFormat(controllerErrorFormat),
 		bufctl.WithFileAnnotationsToStdout(),
-	)
+	}
+	if flags.Watch {
+		controllerOptions = append(controllerOptions, bufctl.WithBuildCache(bufimage.NewBuildCache()))
+	}
+	controller, err := bufcli.NewController(container, controllerOptions...)
 	if err != nil {
 		return err
 	}
+	var wasmRuntime wasm.Runtime
+	if !flags.ListIgnores {
+		wasmRuntimeCacheDir, err := bufcli.CreateWasmRuntimeCacheDir(container)
+		if err != nil {
+			return err
+		}
+		wasmRuntime, err = wasm.NewRuntime(ctx, wasm.WithLocalCacheDir(wasmRuntimeCacheDir))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			retErr = errors.Join(retErr, wasmRuntime.Close(ctx))
+		}()
+	}
+	if flags.Watch {
+		return bufcli.Watch(
+			ctx,
+			container,
+			controller,
+			input,
+			nil,
+			nil,
+			func(ctx context.Context) error {
+				return lint(ctx, container, controller, wasmRuntime, input, paths, flags)
+			},
+		)
+	}
+	return lint(ctx, container, controller, wasmRuntime, input, paths, flags)
+}
+
+// lint lints the input. The wasm.Runtime is nil if flags.ListIgnores is set.
+func lint(
+	ctx context.Context,
+	container appext.Container,
+	controller bufctl.Controller,
+	wasmRuntime wasm.Runtime,
+	input string,
+	paths []string,
+	flags *flags,
+) error {
 	imageWithConfigs, err := controller.GetTargetImageWithConfigs(
 		ctx,
 		input,
-		bufctl.WithTargetPaths(flags.Paths, flags.ExcludePaths),
+		bufctl.WithTargetPaths(paths, flags.ExcludePaths),
 		bufctl.WithConfigOverride(flags.Config),
 	)
 	if err != nil {
 		return err
 	}
-	wasmRuntimeCacheDir, err := bufcli.CreateWasmRuntimeCacheDir(container)
-	if err != nil {
-		return err
+	if flags.ListIgnores {
+		return listIgnores(container, imageWithConfigs, flags.ErrorFormat)
 	}
-	wasmRuntime, err := wasm.NewRuntime(ctx, wasm.WithLocalCacheDir(wasmRuntimeCacheDir))
-	if err != nil {
-		return err
-	}
-	defer func() {
-		retErr = errors.Join(retErr, wasmRuntime.Close(ctx))
-	}()
 	var allFileAnnotations []bufanalysis.FileAnnotation
 	for _, imageWithConfig := range imageWithConfigs {
 		client, err := bufcheck.NewClient(
@@ -190,3 +264,23 @@ func run(
 	}
 	return nil
 }
+
+func listIgnores(
+	container appext.Container,
+	imageWithConfigs []bufctl.ImageWithConfig,
+	errorFormat string,
+) error {
+	now := time.Now()
+	var lintIgnores []bufcheck.LintIgnore
+	for _, imageWithConfig := range imageWithConfigs {
+		lintIgnores = append(
+			lintIgnores,
+			bufcheck.GetLintIgnores(imageWithConfig, imageWithConfig.LintConfig(), now)...,
+		)
+	}
+	var printLintIgnoresOptions []bufcheck.PrintLintIgnoresOption
+	if errorFormat == "json" {
+		printLintIgnoresOptions = append(printLintIgnoresOptions, bufcheck.PrintLintIgnoresWithJSON())
+	}
+	return bufcheck.PrintLintIgnores(container.Stdout(), lintIgnores, printLintIgnoresOptions...)
+}
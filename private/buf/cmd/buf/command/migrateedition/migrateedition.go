@@ -0,0 +1,418 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package migrateedition
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/buf/bufedition"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/spf13/pflag"
+)
+
+const (
+	configFlagName          = "config"
+	diffFlagName            = "diff"
+	diffFlagShortName       = "d"
+	disableSymlinksFlagName = "disable-symlinks"
+	editionFlagName         = "edition"
+	errorFormatFlagName     = "error-format"
+	excludePathsFlagName    = "exclude-path"
+	exitCodeFlagName        = "exit-code"
+	outputFlagName          = "output"
+	outputFlagShortName     = "o"
+	pathsFlagName           = "path"
+	writeFlagName           = "write"
+	writeFlagShortName      = "w"
+
+	defaultEdition = "2023"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <source>",
+		Short: "Migrate Protobuf files to Protobuf Editions",
+		Long: `
+Rewrites proto2 and proto3 files to use Protobuf Editions, adding the feature options
+needed to keep the semantics of the files unchanged. The original and migrated files are
+compiled and compared, and the command fails if the resulting descriptors differ in any
+way that affects behavior. Files that already use Editions are left unchanged.
+
+Groups cannot be migrated automatically, and must be replaced with message fields first.
+
+By default, the source is the current directory and the migrated content is written to stdout.
+
+Examples:
+
+Write the current directory's migrated content to stdout:
+
+    $ buf migrate-edition
+
+Rewrite the files defined in the current directory in-place with -w:
+
+    $ buf migrate-edition -w
+
+Display a diff between the original and migrated content with -d:
+
+    $ buf migrate-edition -d
+
+Use the --exit-code flag to exit with a non-zero exit code if any file needs to be migrated:
+
+    $ buf migrate-edition -d --exit-code
+
+Write the migrated directory to another directory, creating it if it doesn't exist:
+
+    $ buf migrate-edition proto -o migrated
+
+The -w and -o flags cannot be used together in a single invocation.
+`,
+		Args: appcmd.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Config          string
+	Diff            bool
+	DisableSymlinks bool
+	Edition         string
+	ErrorFormat     string
+	ExcludePaths    []string
+	ExitCode        bool
+	Paths           []string
+	Output          string
+	Write           bool
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
+	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.Edition,
+		editionFlagName,
+		defaultEdition,
+		"The edition to migrate files to",
+	)
+	flagSet.BoolVarP(
+		&f.Diff,
+		diffFlagName,
+		diffFlagShortName,
+		false,
+		"Display diffs instead of rewriting files",
+	)
+	flagSet.BoolVar(
+		&f.ExitCode,
+		exitCodeFlagName,
+		false,
+		"Exit with a non-zero exit code if files were not already migrated",
+	)
+	flagSet.BoolVarP(
+		&f.Write,
+		writeFlagName,
+		writeFlagShortName,
+		false,
+		"Rewrite files in-place",
+	)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVarP(
+		&f.Output,
+		outputFlagName,
+		outputFlagShortName,
+		"-",
+		fmt.Sprintf(
+			`The output location for the migrated files. Must be one of format %s. If omitted, the result is written to stdout`,
+			buffetch.DirOrProtoFileFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The buf.yaml file or data to use for configuration`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) (retErr error) {
+	edition, err := bufedition.ParseEdition(flags.Edition)
+	if err != nil {
+		return appcmd.NewInvalidArgumentErrorf("--%s: %v", editionFlagName, err)
+	}
+	source, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	if flags.Write {
+		if flags.Output != "-" {
+			return appcmd.NewInvalidArgumentErrorf("cannot use --%s when using --%s", outputFlagName, writeFlagName)
+		}
+		// Like buf format, we rely on ExternalPaths being writable when flags.Write is set,
+		// which is only the case for dirs and proto files.
+		sourceDirOrProtoFileRef, err := getDirOrProtoFileRef(ctx, container, source)
+		if err != nil {
+			if errors.Is(err, buffetch.ErrModuleFormatDetectedForDirOrProtoFileRef) {
+				return appcmd.NewInvalidArgumentErrorf("invalid input %q when using --%s: must be a directory or proto file", source, writeFlagName)
+			}
+			return appcmd.NewInvalidArgumentErrorf("invalid input %q when using --%s: %v", source, writeFlagName, err)
+		}
+		if err := validateNoIncludePackageFiles(sourceDirOrProtoFileRef); err != nil {
+			return err
+		}
+	}
+	dirOrProtoFileRef, err := getDirOrProtoFileRef(ctx, container, flags.Output)
+	if err != nil {
+		if errors.Is(err, buffetch.ErrModuleFormatDetectedForDirOrProtoFileRef) {
+			return appcmd.NewInvalidArgumentErrorf("--%s must be a directory or proto file", outputFlagName)
+		}
+		return err
+	}
+	if err := validateNoIncludePackageFiles(dirOrProtoFileRef); err != nil {
+		return err
+	}
+
+	controller, err := bufcli.NewController(
+		container,
+		bufctl.WithDisableSymlinks(flags.DisableSymlinks),
+		bufctl.WithFileAnnotationErrorFormat(flags.ErrorFormat),
+	)
+	if err != nil {
+		return err
+	}
+	workspace, err := controller.GetWorkspace(
+		ctx,
+		source,
+		bufctl.WithTargetPaths(flags.Paths, flags.ExcludePaths),
+		bufctl.WithConfigOverride(flags.Config),
+	)
+	if err != nil {
+		return err
+	}
+	originalReadBucket := bufmodule.ModuleReadBucketToStorageReadBucket(
+		bufmodule.ModuleReadBucketWithOnlyTargetFiles(
+			bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFilesForTargetModules(workspace),
+		),
+	)
+	paths, err := storage.AllPaths(ctx, originalReadBucket, "")
+	if err != nil {
+		return err
+	}
+	// Unlike buf format, we need all dependencies of the target files to be able to
+	// compile them and verify that the migrated files are equivalent.
+	resolutionReadBucket := bufmodule.ModuleReadBucketToStorageReadBucket(
+		bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(workspace),
+	)
+	migratedReadBucket, err := bufedition.MigrateBucket(ctx, resolutionReadBucket, paths, edition)
+	if err != nil {
+		return err
+	}
+
+	diffBuffer := bytes.NewBuffer(nil)
+	changedPaths, err := storage.DiffWithFilenames(
+		ctx,
+		diffBuffer,
+		originalReadBucket,
+		migratedReadBucket,
+		storage.DiffWithExternalPaths(), // No need to set prefixes as the buckets are from the same location.
+	)
+	if err != nil {
+		return err
+	}
+	diffExists := diffBuffer.Len() > 0
+	defer func() {
+		if retErr == nil && flags.ExitCode && diffExists {
+			retErr = bufctl.ErrFileAnnotation
+		}
+	}()
+
+	if flags.Diff {
+		if diffExists {
+			if _, err := io.Copy(container.Stdout(), diffBuffer); err != nil {
+				return err
+			}
+		}
+		// If we haven't overridden the output flag and haven't set write, we can stop here.
+		if flags.Output == "-" && !flags.Write {
+			return nil
+		}
+	}
+	if flags.Write {
+		changedPathSet := slicesext.ToStructMap(changedPaths)
+		return storage.WalkReadObjects(
+			ctx,
+			migratedReadBucket,
+			"",
+			func(readObject storage.ReadObject) error {
+				if _, ok := changedPathSet[readObject.Path()]; !ok {
+					// no change, nothing to re-write
+					return nil
+				}
+				file, err := os.OpenFile(readObject.ExternalPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					retErr = errors.Join(retErr, file.Close())
+				}()
+				if _, err := file.ReadFrom(readObject); err != nil {
+					return err
+				}
+				return nil
+			},
+		)
+	}
+	// Both flags.Diff and flags.Write not set, do output logic.
+	switch t := dirOrProtoFileRef.(type) {
+	case buffetch.DirRef:
+		if err := writeToDir(ctx, flags.DisableSymlinks, migratedReadBucket, t); err != nil {
+			return err
+		}
+	case buffetch.ProtoFileRef:
+		if err := writeToProtoFile(ctx, container, migratedReadBucket, t); err != nil {
+			return err
+		}
+	default:
+		return syserror.Newf("buffetch ref type must be dir or proto file: %T", dirOrProtoFileRef)
+	}
+	return nil
+}
+
+func writeToDir(
+	ctx context.Context,
+	disableSymlinks bool,
+	migratedReadBucket storage.ReadBucket,
+	dirRef buffetch.DirRef,
+) error {
+	// OK to use os.MkdirAll here as this is CLI-only.
+	if err := os.MkdirAll(dirRef.DirPath(), 0755); err != nil {
+		return err
+	}
+	readWriteBucket, err := newStorageosProvider(disableSymlinks).NewReadWriteBucket(
+		dirRef.DirPath(),
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = storage.Copy(
+		ctx,
+		migratedReadBucket,
+		readWriteBucket,
+	)
+	return err
+}
+
+func writeToProtoFile(
+	ctx context.Context,
+	container appext.Container,
+	migratedReadBucket storage.ReadBucket,
+	protoFileRef buffetch.ProtoFileRef,
+) (retErr error) {
+	writeCloser, err := buffetch.NewProtoFileWriter(container.Logger()).PutProtoFile(
+		ctx,
+		container,
+		protoFileRef,
+	)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, writeCloser.Close())
+	}()
+	return storage.WalkReadObjects(
+		ctx,
+		migratedReadBucket,
+		"",
+		func(readObject storage.ReadObject) error {
+			data, err := io.ReadAll(readObject)
+			if err != nil {
+				return err
+			}
+			if _, err := writeCloser.Write(data); err != nil {
+				return err
+			}
+			return nil
+		},
+	)
+}
+
+func getDirOrProtoFileRef(
+	ctx context.Context,
+	container appext.Container,
+	value string,
+) (buffetch.DirOrProtoFileRef, error) {
+	return buffetch.NewDirOrProtoFileRefParser(
+		container.Logger(),
+	).GetDirOrProtoFileRef(ctx, value)
+}
+
+func validateNoIncludePackageFiles(dirOrProtoFileRef buffetch.DirOrProtoFileRef) error {
+	if protoFileRef, ok := dirOrProtoFileRef.(buffetch.ProtoFileRef); ok && protoFileRef.IncludePackageFiles() {
+		return appcmd.NewInvalidArgumentError("cannot specify include_package_files=true with migrate-edition")
+	}
+	return nil
+}
+
+func newStorageosProvider(disableSymlinks bool) storageos.Provider {
+	var options []storageos.ProviderOption
+	if !disableSymlinks {
+		options = append(options, storageos.ProviderWithSymlinks())
+	}
+	return storageos.NewProvider(options...)
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
@@ -47,6 +48,7 @@ const (
 	sourceControlURLFlagName   = "source-control-url"
 	gitMetadataFlagName        = "git-metadata"
 	excludeUnnamedFlagName     = "exclude-unnamed"
+	signingKeyFlagName         = "signing-key"
 
 	// All deprecated.
 	tagFlagName      = "tag"
@@ -94,6 +96,7 @@ type flags struct {
 	SourceControlURL   string
 	ExcludeUnnamed     bool
 	GitMetadata        bool
+	SigningKey         string
 	// special
 	InputHashtag string
 }
@@ -172,6 +175,12 @@ If you set the --%s flag and/or --%s flag yourself, then the value(s) will be us
 		false,
 		"Only push named modules to the BSR. Named modules must not have any unnamed dependencies.",
 	)
+	flagSet.StringVar(
+		&f.SigningKey,
+		signingKeyFlagName,
+		"",
+		`The path to a PEM-encoded ed25519 private key to sign the digest of each pushed module with. Only supported when pushing to a module registry set with BUF_MODULE_REGISTRY or the registry key of buf.yaml`,
+	)
 
 	flagSet.StringSliceVarP(&f.Tags, tagFlagName, tagFlagShortName, nil, useLabelInstead)
 	_ = flagSet.MarkHidden(tagFlagName)
@@ -212,6 +221,17 @@ func run(
 	if err := validateFlags(flags); err != nil {
 		return err
 	}
+	var signingKey bufmodule.SigningKey
+	if flags.SigningKey != "" {
+		data, err := os.ReadFile(flags.SigningKey)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", signingKeyFlagName, err)
+		}
+		signingKey, err = bufmodule.ParseSigningKeyPEM(data)
+		if err != nil {
+			return fmt.Errorf("--%s: %w", signingKeyFlagName, err)
+		}
+	}
 
 	workspace, err := getBuildableWorkspace(ctx, container, flags)
 	if err != nil {
@@ -256,6 +276,9 @@ func run(
 	if flags.ExcludeUnnamed {
 		uploadOptions = append(uploadOptions, bufmodule.UploadWithExcludeUnnamed())
 	}
+	if signingKey != nil {
+		uploadOptions = append(uploadOptions, bufmodule.UploadWithSigningKey(signingKey))
+	}
 
 	commits, err := uploader.Upload(ctx, workspace, uploadOptions...)
 	if err != nil {
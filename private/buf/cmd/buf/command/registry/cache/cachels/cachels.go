@@ -0,0 +1,215 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cachels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/bufprint"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/cache/internal"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+	"github.com/spf13/pflag"
+)
+
+const (
+	formatFlagName = "format"
+
+	entryKindModule = "module"
+	entryKindCommit = "commit"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name,
+		Short: "List the modules and commits in the registry cache",
+		Long: `Each cached module commit is listed with its kind, digest type, size on disk, and the last time it
+was read from or written to the cache. The kind is "module" for the content of the module, and "commit"
+for the information about the commit. The last access time is empty for entries written by older
+versions of buf that have not been used since.`,
+		Args: appcmd.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	Format string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		bufprint.FormatText.String(),
+		fmt.Sprintf(`The output format to use. Must be one of %s`, bufprint.AllFormatsString),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	format, err := bufprint.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.WrapInvalidArgumentError(err)
+	}
+	moduleDataStore, err := bufcli.NewModuleDataStore(container)
+	if err != nil {
+		return err
+	}
+	moduleDataStoreEntries, err := moduleDataStore.ListEntries(ctx)
+	if err != nil {
+		return err
+	}
+	commitStore, err := bufcli.NewCommitStore(container)
+	if err != nil {
+		return err
+	}
+	commitStoreEntries, err := commitStore.ListEntries(ctx)
+	if err != nil {
+		return err
+	}
+	externalEntries := make([]*externalEntry, 0, len(moduleDataStoreEntries)+len(commitStoreEntries))
+	for _, moduleDataStoreEntry := range moduleDataStoreEntries {
+		externalEntry := newExternalEntry(
+			entryKindModule,
+			moduleDataStoreEntry.FullName(),
+			moduleDataStoreEntry.CommitID(),
+			moduleDataStoreEntry.DigestType(),
+			moduleDataStoreEntry.Size(),
+			moduleDataStoreEntry.LastAccessTime(),
+		)
+		if digest := moduleDataStoreEntry.Digest(); digest != nil {
+			externalEntry.Digest = digest.String()
+		}
+		externalEntries = append(externalEntries, externalEntry)
+	}
+	for _, commitStoreEntry := range commitStoreEntries {
+		externalEntries = append(
+			externalEntries,
+			newExternalEntry(
+				entryKindCommit,
+				commitStoreEntry.FullName(),
+				commitStoreEntry.CommitID(),
+				commitStoreEntry.DigestType(),
+				commitStoreEntry.Size(),
+				commitStoreEntry.LastAccessTime(),
+			),
+		)
+	}
+	switch format {
+	case bufprint.FormatText:
+		var totalSize int64
+		if err := bufprint.WithTabWriter(
+			container.Stdout(),
+			[]string{
+				"Module",
+				"Commit",
+				"Kind",
+				"Digest Type",
+				"Size",
+				"Last Access",
+			},
+			func(tabWriter bufprint.TabWriter) error {
+				for _, externalEntry := range externalEntries {
+					totalSize += externalEntry.Size
+					var lastAccessTime string
+					if externalEntry.LastAccessTime != nil {
+						lastAccessTime = externalEntry.LastAccessTime.Local().Format(time.RFC3339)
+					}
+					if err := tabWriter.Write(
+						externalEntry.Module,
+						externalEntry.Commit,
+						externalEntry.Kind,
+						externalEntry.DigestType,
+						internal.FormatSize(externalEntry.Size),
+						lastAccessTime,
+					); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		); err != nil {
+			return err
+		}
+		_, err := fmt.Fprintf(container.Stderr(), "%d entries, %s total\n", len(externalEntries), internal.FormatSize(totalSize))
+		return err
+	case bufprint.FormatJSON:
+		encoder := json.NewEncoder(container.Stdout())
+		for _, externalEntry := range externalEntries {
+			if err := encoder.Encode(externalEntry); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format: %v", format)
+	}
+}
+
+type externalEntry struct {
+	Module         string     `json:"module,omitempty"`
+	Commit         string     `json:"commit,omitempty"`
+	Kind           string     `json:"kind,omitempty"`
+	DigestType     string     `json:"digest_type,omitempty"`
+	Digest         string     `json:"digest,omitempty"`
+	Size           int64      `json:"size"`
+	LastAccessTime *time.Time `json:"last_access_time,omitempty"`
+}
+
+func newExternalEntry(
+	kind string,
+	fullName bufparse.FullName,
+	commitID uuid.UUID,
+	digestType bufmodule.DigestType,
+	size int64,
+	lastAccessTime time.Time,
+) *externalEntry {
+	externalEntry := &externalEntry{
+		Module:     fullName.String(),
+		Commit:     uuidutil.ToDashless(commitID),
+		Kind:       kind,
+		DigestType: digestType.String(),
+		Size:       size,
+	}
+	if !lastAccessTime.IsZero() {
+		externalEntry.LastAccessTime = &lastAccessTime
+	}
+	return externalEntry
+}
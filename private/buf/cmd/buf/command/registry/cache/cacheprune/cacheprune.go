@@ -0,0 +1,168 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheprune
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/cache/internal"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+	"github.com/spf13/pflag"
+)
+
+const (
+	maxSizeFlagName = "max-size"
+	maxAgeFlagName  = "max-age"
+	dryRunFlagName  = "dry-run"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name,
+		Short: "Evict the least recently used modules from the registry cache",
+		Long: `Entries not accessed within --` + maxAgeFlagName + ` are evicted first. Then, while the cache is larger than
+--` + maxSizeFlagName + `, the least recently used entries are evicted. At least one of the two flags must be set.
+
+Entries written by older versions of buf have no recorded access time until they are next used, and are
+evicted first. Cached commits are small, and are not evicted.
+This is safe to run while other buf commands are using the cache.`,
+		Args: appcmd.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	MaxSize string
+	MaxAge  time.Duration
+	DryRun  bool
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.MaxSize,
+		maxSizeFlagName,
+		"",
+		`The maximum total size of the cache, such as "500MB" or "2GiB"`,
+	)
+	flagSet.DurationVar(
+		&f.MaxAge,
+		maxAgeFlagName,
+		0,
+		`The maximum time since an entry was last accessed, such as "720h"`,
+	)
+	flagSet.BoolVar(
+		&f.DryRun,
+		dryRunFlagName,
+		false,
+		"Print the entries that would be evicted without evicting them",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+	flags *flags,
+) error {
+	if flags.MaxSize == "" && flags.MaxAge == 0 {
+		return appcmd.NewInvalidArgumentErrorf("at least one of --%s or --%s must be set", maxSizeFlagName, maxAgeFlagName)
+	}
+	if flags.MaxAge < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must not be negative", maxAgeFlagName)
+	}
+	maxSize := int64(-1)
+	if flags.MaxSize != "" {
+		var err error
+		maxSize, err = internal.ParseSize(flags.MaxSize)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", maxSizeFlagName, err)
+		}
+	}
+	moduleDataStore, err := bufcli.NewModuleDataStore(container)
+	if err != nil {
+		return err
+	}
+	entries, err := moduleDataStore.ListEntries(ctx)
+	if err != nil {
+		return err
+	}
+	var freedSize int64
+	for _, entry := range getEntriesToEvict(entries, maxSize, flags.MaxAge, time.Now()) {
+		if !flags.DryRun {
+			if err := moduleDataStore.DeleteEntry(ctx, entry); err != nil {
+				return err
+			}
+		}
+		freedSize += entry.Size()
+		if _, err := fmt.Fprintf(container.Stderr(), "evicted %s (%s)\n", internal.EntryString(entry), internal.FormatSize(entry.Size())); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(container.Stderr(), "freed %s\n", internal.FormatSize(freedSize))
+	return err
+}
+
+// getEntriesToEvict returns the entries to evict, least recently used first.
+//
+// maxSize is ignored if negative, maxAge is ignored if zero.
+func getEntriesToEvict(
+	entries []bufmodulestore.ModuleDataStoreEntry,
+	maxSize int64,
+	maxAge time.Duration,
+	now time.Time,
+) []bufmodulestore.ModuleDataStoreEntry {
+	entries = append([]bufmodulestore.ModuleDataStoreEntry{}, entries...)
+	sort.SliceStable(
+		entries,
+		func(i int, j int) bool {
+			return entries[i].LastAccessTime().Before(entries[j].LastAccessTime())
+		},
+	)
+	var totalSize int64
+	for _, entry := range entries {
+		totalSize += entry.Size()
+	}
+	var entriesToEvict []bufmodulestore.ModuleDataStoreEntry
+	for _, entry := range entries {
+		tooOld := maxAge > 0 && now.Sub(entry.LastAccessTime()) > maxAge
+		tooLarge := maxSize >= 0 && totalSize > maxSize
+		if !tooOld && !tooLarge {
+			// Entries are sorted by last access time, so no later entry can be evicted.
+			break
+		}
+		entriesToEvict = append(entriesToEvict, entry)
+		totalSize -= entry.Size()
+	}
+	return entriesToEvict
+}
@@ -0,0 +1,118 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cacheverify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/cache/internal"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appext"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appext.SubCommandBuilder,
+) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   name,
+		Short: "Verify the modules in the registry cache",
+		Long: `The files of each cached module commit are re-digested and compared to the digest of the commit.
+Entries that cannot be read or do not match are evicted from the cache, and will be downloaded again
+the next time they are needed.
+
+The expected digest is taken from the cached commit if present, and otherwise from the digest
+recorded when the entry was written. Entries with neither are skipped.
+
+This is safe to run while other buf commands are using the cache.`,
+		Args: appcmd.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appext.Container) error {
+				return run(ctx, container)
+			},
+		),
+	}
+}
+
+func run(
+	ctx context.Context,
+	container appext.Container,
+) error {
+	moduleDataStore, err := bufcli.NewModuleDataStore(container)
+	if err != nil {
+		return err
+	}
+	commitStore, err := bufcli.NewCommitStore(container)
+	if err != nil {
+		return err
+	}
+	entries, err := moduleDataStore.ListEntries(ctx)
+	if err != nil {
+		return err
+	}
+	var numVerified, numEvicted, numSkipped int
+	for _, entry := range entries {
+		expectedDigest, err := getExpectedDigest(ctx, commitStore, entry)
+		if err != nil {
+			return err
+		}
+		if expectedDigest == nil {
+			numSkipped++
+			if _, err := fmt.Fprintf(container.Stderr(), "skipped %s: no digest available\n", internal.EntryString(entry)); err != nil {
+				return err
+			}
+			continue
+		}
+		valid, err := moduleDataStore.VerifyEntry(ctx, entry, expectedDigest)
+		if err != nil {
+			return err
+		}
+		if valid {
+			numVerified++
+			continue
+		}
+		numEvicted++
+		if _, err := fmt.Fprintf(container.Stderr(), "evicted %s: content does not match digest %s\n", internal.EntryString(entry), expectedDigest.String()); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(container.Stderr(), "%d verified, %d evicted, %d skipped\n", numVerified, numEvicted, numSkipped)
+	return err
+}
+
+// getExpectedDigest returns the expected Digest for the entry, or nil if there is none.
+func getExpectedDigest(
+	ctx context.Context,
+	commitStore bufmodulestore.CommitStore,
+	entry bufmodulestore.ModuleDataStoreEntry,
+) (bufmodule.Digest, error) {
+	commitKey, err := bufmodule.NewCommitKey(entry.FullName().Registry(), entry.CommitID(), entry.DigestType())
+	if err != nil {
+		return nil, err
+	}
+	commits, _, err := commitStore.GetCommitsForCommitKeys(ctx, []bufmodule.CommitKey{commitKey})
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 1 {
+		return commits[0].ModuleKey().Digest()
+	}
+	return entry.Digest(), nil
+}
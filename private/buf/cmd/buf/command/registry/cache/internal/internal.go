@@ -0,0 +1,79 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+)
+
+var (
+	// sizeUnits are ordered from largest to smallest, so that the longest matching suffix wins.
+	sizeUnits = []sizeUnit{
+		{suffix: "GiB", multiplier: 1 << 30},
+		{suffix: "MiB", multiplier: 1 << 20},
+		{suffix: "KiB", multiplier: 1 << 10},
+		{suffix: "GB", multiplier: 1000 * 1000 * 1000},
+		{suffix: "MB", multiplier: 1000 * 1000},
+		{suffix: "KB", multiplier: 1000},
+		{suffix: "B", multiplier: 1},
+	}
+)
+
+// EntryString returns the string representation of the cache entry for output.
+//
+// This is "registry/owner/name:dashlessCommitID".
+func EntryString(entry bufmodulestore.ModuleDataStoreEntry) string {
+	return entry.FullName().String() + ":" + uuidutil.ToDashless(entry.CommitID())
+}
+
+// FormatSize formats the size in bytes for output, using binary units.
+func FormatSize(size int64) string {
+	for _, unit := range sizeUnits[:3] {
+		if size >= unit.multiplier {
+			return fmt.Sprintf("%.1f %s", float64(size)/float64(unit.multiplier), unit.suffix)
+		}
+	}
+	return strconv.FormatInt(size, 10) + " B"
+}
+
+// ParseSize parses a size such as "500MB" or "2GiB" into bytes.
+//
+// A size without a unit is in bytes.
+func ParseSize(value string) (int64, error) {
+	trimmedValue := strings.TrimSpace(value)
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(trimmedValue, unit.suffix) {
+			trimmedValue = strings.TrimSpace(strings.TrimSuffix(trimmedValue, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(trimmedValue, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q: must be a non-negative number with an optional unit such as B, KB, MB, GB, KiB, MiB, or GiB", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
+
+type sizeUnit struct {
+	suffix     string
+	multiplier int64
+}
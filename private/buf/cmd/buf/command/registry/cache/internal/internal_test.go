@@ -0,0 +1,53 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+	testParseSize(t, "0", 0)
+	testParseSize(t, "100", 100)
+	testParseSize(t, "100B", 100)
+	testParseSize(t, "1KB", 1000)
+	testParseSize(t, "1.5 MB", 1500000)
+	testParseSize(t, "2GB", 2000000000)
+	testParseSize(t, "1KiB", 1024)
+	testParseSize(t, "500MiB", 500*1024*1024)
+	testParseSize(t, "2GiB", 2*1024*1024*1024)
+	for _, invalid := range []string{"", "MB", "-1MB", "1TB", "one"} {
+		_, err := ParseSize(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+	require.Equal(t, "0 B", FormatSize(0))
+	require.Equal(t, "1023 B", FormatSize(1023))
+	require.Equal(t, "1.0 KiB", FormatSize(1024))
+	require.Equal(t, "1.5 MiB", FormatSize(1024*1024*3/2))
+	require.Equal(t, "2.0 GiB", FormatSize(2*1024*1024*1024))
+}
+
+func testParseSize(t *testing.T, value string, expected int64) {
+	actual, err := ParseSize(value)
+	require.NoError(t, err, value)
+	require.Equal(t, expected, actual, value)
+}
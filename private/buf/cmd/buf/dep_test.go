@@ -0,0 +1,491 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDepVendor(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	registryDirPath := filepath.Join(dirPath, "registry")
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/buf.yaml": "version: v2\nname: buf.build/acme/a\n",
+			"a/a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+			"b/buf.yaml": "version: v2\nname: buf.build/acme/b\ndeps:\n  - buf.build/acme/a\n",
+			"b/b.proto":  "syntax = \"proto3\";\n\npackage b;\n\nimport \"a.proto\";\n\nmessage B {\n  a.A a = 1;\n}\n",
+		},
+	)
+	testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "a"))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "update", filepath.Join(dirPath, "b"))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "vendor", filepath.Join(dirPath, "b"))
+	data, err := os.ReadFile(filepath.Join(dirPath, "b", "vendor", "buf.build", "acme", "a", "a.proto"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "message A")
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "vendor", filepath.Join(dirPath, "b"), "--check")
+
+	// With the registry gone and an empty cache, the dependencies can only come from
+	// the vendor directory.
+	require.NoError(t, os.RemoveAll(registryDirPath))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "build", filepath.Join(dirPath, "b"))
+	require.NoError(t, os.RemoveAll(filepath.Join(dirPath, "b", "vendor")))
+	testRunWithModuleRegistry(t, registryDirPath, 1, nil, "build", filepath.Join(dirPath, "b"))
+}
+
+func TestDepVendorV1(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"v1/buf.yaml":             "version: v1\n",
+			"workspace/buf.work.yaml": "version: v1\ndirectories:\n  - a\n",
+			"workspace/a/buf.yaml":    "version: v1\n",
+		},
+	)
+	for _, subDirPath := range []string{"v1", "workspace", filepath.Join("workspace", "a")} {
+		testRunStderrContainsNoWarn(
+			t,
+			nil,
+			1,
+			[]string{
+				"dependencies can only be vendored for v2 buf.yaml files",
+				"as v1 buf.yaml and buf.work.yaml workspaces do not read vendored dependencies, run buf config migrate first",
+			},
+			"dep",
+			"vendor",
+			filepath.Join(dirPath, subDirPath),
+		)
+	}
+}
+
+func TestDepOutdated(t *testing.T) {
+	t.Parallel()
+	dirPath, registryDirPath, aCommitID, bCommitID := testDepWorkspace(t)
+	// Push a new commit for the transitive dependency buf.build/acme/a, which is now
+	// behind in the buf.lock of buf.build/acme/c.
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/a.proto": "syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string name = 1;\n}\n",
+		},
+	)
+	latestACommitID := testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "a"))
+	require.NotEqual(t, aCommitID, latestACommitID)
+
+	stdout := bytes.NewBuffer(nil)
+	testRunWithModuleRegistry(t, registryDirPath, 0, stdout, "dep", "outdated", filepath.Join(dirPath, "c"))
+	assert.Equal(
+		t,
+		fmt.Sprintf(
+			`Module            Ref   Pinned                            Latest                            Behind
+buf.build/acme/a        %s  %s  less than 2 hours
+buf.build/acme/b  main  %s  %s  up to date
+`,
+			aCommitID,
+			latestACommitID,
+			bCommitID,
+			bCommitID,
+		),
+		stdout.String(),
+	)
+
+	stdout = bytes.NewBuffer(nil)
+	testRunWithModuleRegistry(t, registryDirPath, 0, stdout, "dep", "outdated", filepath.Join(dirPath, "c"), "--format", "json")
+	assert.Equal(
+		t,
+		fmt.Sprintf(
+			`{"module":"buf.build/acme/a","pinned_commit":"%s","latest_commit":"%s","up_to_date":false,"behind_seconds":0}
+{"module":"buf.build/acme/b","ref":"main","pinned_commit":"%s","latest_commit":"%s","up_to_date":true,"behind_seconds":0}
+`,
+			aCommitID,
+			latestACommitID,
+			bCommitID,
+			bCommitID,
+		),
+		stdout.String(),
+	)
+
+	// The buf.lock is not modified.
+	data, err := os.ReadFile(filepath.Join(dirPath, "c", "buf.lock"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), aCommitID)
+	require.NotContains(t, string(data), latestACommitID)
+}
+
+func TestDepWhy(t *testing.T) {
+	t.Parallel()
+	dirPath, registryDirPath, aCommitID, bCommitID := testDepWorkspace(t)
+	a := "buf.build/acme/a:" + aCommitID
+	b := "buf.build/acme/b:" + bCommitID
+
+	stdout := bytes.NewBuffer(nil)
+	testRunWithModuleRegistry(t, registryDirPath, 0, stdout, "dep", "why", "buf.build/acme/a", filepath.Join(dirPath, "c"))
+	assert.Equal(
+		t,
+		fmt.Sprintf(
+			`Paths:
+  buf.build/acme/c -> %s
+  buf.build/acme/c -> %s -> %s
+
+Imports:
+  %s: b.proto imports a.proto
+  buf.build/acme/c: c.proto imports a.proto (unused)
+`,
+			a,
+			b,
+			a,
+			b,
+		),
+		stdout.String(),
+	)
+
+	stdout = bytes.NewBuffer(nil)
+	testRunWithModuleRegistry(t, registryDirPath, 0, stdout, "dep", "why", "buf.build/acme/a", filepath.Join(dirPath, "c"), "--format", "json")
+	var why struct {
+		Module  string     `json:"module"`
+		Paths   [][]string `json:"paths"`
+		Imports []struct {
+			Module string `json:"module"`
+			File   string `json:"file"`
+			Import string `json:"import"`
+			Used   bool   `json:"used"`
+		} `json:"imports"`
+	}
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &why))
+	assert.Equal(t, a, why.Module)
+	assert.Equal(
+		t,
+		[][]string{
+			{"buf.build/acme/c", a},
+			{"buf.build/acme/c", b, a},
+		},
+		why.Paths,
+	)
+	require.Len(t, why.Imports, 2)
+	assert.Equal(t, b, why.Imports[0].Module)
+	assert.Equal(t, "b.proto", why.Imports[0].File)
+	assert.Equal(t, "a.proto", why.Imports[0].Import)
+	assert.True(t, why.Imports[0].Used)
+	assert.Equal(t, "buf.build/acme/c", why.Imports[1].Module)
+	assert.Equal(t, "c.proto", why.Imports[1].File)
+	assert.Equal(t, "a.proto", why.Imports[1].Import)
+	assert.False(t, why.Imports[1].Used)
+
+	// Only dependencies of the target modules are explained.
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{"buf.build/acme/c is not a dependency of"},
+		"dep",
+		"why",
+		"buf.build/acme/c",
+		filepath.Join(dirPath, "a"),
+	)
+}
+
+func TestDepUpdatePreview(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	registryDirPath := filepath.Join(dirPath, "registry")
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/buf.yaml": "version: v2\nname: buf.build/acme/a\n",
+			"a/a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string name = 1;\n  string old = 2;\n}\n\nmessage Unused {}\n",
+			"b/buf.yaml": "version: v2\nname: buf.build/acme/b\ndeps:\n  - buf.build/acme/a\n",
+			"b/b.proto":  "syntax = \"proto3\";\n\npackage b;\n\nimport \"a.proto\";\n\nmessage B {\n  a.A a = 1;\n}\n",
+		},
+	)
+	aCommitID := testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "a"))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "update", filepath.Join(dirPath, "b"))
+	// Remove a field of the referenced type, and deprecate another field.
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/a.proto": "syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string name = 1 [deprecated = true];\n}\n",
+		},
+	)
+	latestACommitID := testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "a"))
+	bufLockFilePath := filepath.Join(dirPath, "b", "buf.lock")
+	bufLockData, err := os.ReadFile(bufLockFilePath)
+	require.NoError(t, err)
+	bufLockFileInfo, err := os.Stat(bufLockFilePath)
+	require.NoError(t, err)
+
+	stdout := bytes.NewBuffer(nil)
+	testRunWithModuleRegistry(t, registryDirPath, 0, stdout, "dep", "update", filepath.Join(dirPath, "b"), "--preview")
+	assert.Equal(
+		t,
+		fmt.Sprintf(
+			`buf.build/acme/a: %s -> %s
+  a.proto:5:1:Previously present field "2" with name "old" on message "A" was deleted.
+  deprecated a.A.name
+`,
+			aCommitID,
+			latestACommitID,
+		),
+		stdout.String(),
+	)
+	// The buf.lock is never written.
+	data, err := os.ReadFile(bufLockFilePath)
+	require.NoError(t, err)
+	require.Equal(t, bufLockData, data)
+	fileInfo, err := os.Stat(bufLockFilePath)
+	require.NoError(t, err)
+	require.Equal(t, bufLockFileInfo.ModTime(), fileInfo.ModTime())
+}
+
+func TestDepSignatures(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	registryDirPath := filepath.Join(dirPath, "registry")
+	signingKeyPath := filepath.Join(dirPath, "signing-key.pem")
+	stdout := bytes.NewBuffer(nil)
+	testRunWithModuleRegistry(t, registryDirPath, 0, stdout, "beta", "signing-key", "generate", signingKeyPath)
+	publicKey := strings.TrimSpace(stdout.String())
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/buf.yaml":        "version: v2\nname: buf.build/acme/a\n",
+			"a/a.proto":         "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+			"b/buf.yaml":        "version: v2\nname: buf.build/acme/b\ndeps:\n  - buf.build/acme/a\ntrusted_signing_keys:\n  - " + publicKey + "\n",
+			"b/b.proto":         "syntax = \"proto3\";\n\npackage b;\n\nimport \"a.proto\";\n\nmessage B {\n  a.A a = 1;\n}\n",
+			"b/buf.gen.yaml":    "version: v2\nplugins:\n  - local: protoc-gen-unused\n    out: gen\n",
+			"unsigned/buf.yaml": "version: v2\ndeps:\n  - buf.build/acme/a\n",
+			"unsigned/u.proto":  "syntax = \"proto3\";\n\npackage u;\n\nimport \"a.proto\";\n\nmessage U {\n  a.A a = 1;\n}\n",
+		},
+	)
+	testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "a"), "--signing-key", signingKeyPath)
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "update", filepath.Join(dirPath, "b"))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "build", filepath.Join(dirPath, "b"))
+	signedBufLockData, err := os.ReadFile(filepath.Join(dirPath, "b", "buf.lock"))
+	require.NoError(t, err)
+
+	// Push an unsigned commit, and pin it in a buf.lock without trusted signing keys.
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/a.proto": "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n\nmessage Other {}\n",
+		},
+	)
+	unsignedCommitID := testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "a"))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "update", filepath.Join(dirPath, "unsigned"))
+	unsignedBufLockData, err := os.ReadFile(filepath.Join(dirPath, "unsigned", "buf.lock"))
+	require.NoError(t, err)
+	digestRegexp := regexp.MustCompile(`digest: \S+`)
+
+	for _, testCase := range []struct {
+		name                   string
+		bufLockData            string
+		expectedStderrPartials []string
+	}{
+		{
+			name:                   "unsigned_commit",
+			bufLockData:            string(unsignedBufLockData),
+			expectedStderrPartials: []string{"signature verification failed: buf.build/acme/a:" + unsignedCommitID + " is not signed"},
+		},
+		{
+			// The signed commit with the digest of the unsigned commit.
+			name:                   "tampered_digest",
+			bufLockData:            digestRegexp.ReplaceAllString(string(signedBufLockData), digestRegexp.FindString(string(unsignedBufLockData))),
+			expectedStderrPartials: []string{`Digest verification failed for "buf.build/acme/a:`},
+		},
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dirPath, "b", "buf.lock"), []byte(testCase.bufLockData), 0600))
+		for _, args := range [][]string{
+			{"build", filepath.Join(dirPath, "b")},
+			{"lint", filepath.Join(dirPath, "b")},
+			{"generate", filepath.Join(dirPath, "b"), "--template", filepath.Join(dirPath, "b", "buf.gen.yaml")},
+			{"push", filepath.Join(dirPath, "b"), "--create"},
+		} {
+			t.Run(testCase.name+"_"+args[0], func(t *testing.T) {
+				appcmdtesting.RunCommandExitCodeStderrContains(
+					t,
+					func(use string) *appcmd.Command { return NewRootCommand(use) },
+					1,
+					testCase.expectedStderrPartials,
+					testModuleRegistryEnvFunc(t, registryDirPath),
+					nil,
+					args...,
+				)
+			})
+		}
+	}
+}
+
+func TestDepVendorSignatures(t *testing.T) {
+	t.Parallel()
+	dirPath, registryDirPath := testDepSignedWorkspace(t)
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "vendor", filepath.Join(dirPath, "b"))
+	modulesFilePath := filepath.Join(dirPath, "b", "vendor", "modules.yaml")
+	modulesData, err := os.ReadFile(modulesFilePath)
+	require.NoError(t, err)
+	require.Contains(t, string(modulesData), "signatures:")
+
+	// With the registry gone and an empty cache, the signatures can only come from
+	// the vendor directory.
+	require.NoError(t, os.RemoveAll(registryDirPath))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "build", filepath.Join(dirPath, "b"))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "vendor", filepath.Join(dirPath, "b"), "--check")
+	unsignedModulesData := regexp.MustCompile(`(?s)\s*signatures:.*`).ReplaceAll(modulesData, []byte("\n"))
+	require.NoError(t, os.WriteFile(modulesFilePath, unsignedModulesData, 0600))
+	appcmdtesting.RunCommandExitCodeStderrContains(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		1,
+		[]string{"signature verification failed: buf.build/acme/a:", "is not signed"},
+		testModuleRegistryEnvFunc(t, registryDirPath),
+		nil,
+		"build",
+		filepath.Join(dirPath, "b"),
+	)
+}
+
+func TestDepSignaturesUnsignedCache(t *testing.T) {
+	t.Parallel()
+	dirPath, registryDirPath := testDepSignedWorkspace(t)
+	// Share the cache between commands.
+	envFunc := testModuleRegistryEnvFunc(t, registryDirPath)
+	cacheDirPath := envFunc("buf")["BUF_CACHE_DIR"]
+	testRunEnv := func(args ...string) {
+		appcmdtesting.RunCommandExitCode(
+			t,
+			func(use string) *appcmd.Command { return NewRootCommand(use) },
+			0,
+			envFunc,
+			nil,
+			nil,
+			nil,
+			args...,
+		)
+	}
+	testRunEnv("build", filepath.Join(dirPath, "b"))
+
+	// Commits cached by older versions have no signatures.
+	var commitFilePaths []string
+	require.NoError(
+		t,
+		filepath.WalkDir(
+			filepath.Join(cacheDirPath, "v3", "commits"),
+			func(path string, dirEntry os.DirEntry, err error) error {
+				if err != nil || dirEntry.IsDir() || filepath.Ext(path) != ".json" {
+					return err
+				}
+				commitFilePaths = append(commitFilePaths, path)
+				return nil
+			},
+		),
+	)
+	require.NotEmpty(t, commitFilePaths)
+	for _, commitFilePath := range commitFilePaths {
+		data, err := os.ReadFile(commitFilePath)
+		require.NoError(t, err)
+		var externalCommit map[string]any
+		require.NoError(t, json.Unmarshal(data, &externalCommit))
+		require.Contains(t, externalCommit, "signatures")
+		delete(externalCommit, "signatures")
+		data, err = json.Marshal(externalCommit)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(commitFilePath, data, 0600))
+	}
+
+	// The unsigned commits are refreshed from the registry, and replaced in the cache.
+	testRunEnv("build", filepath.Join(dirPath, "b"))
+	for _, commitFilePath := range commitFilePaths {
+		data, err := os.ReadFile(commitFilePath)
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"signatures"`)
+	}
+}
+
+// testDepWorkspace pushes buf.build/acme/a, and buf.build/acme/b that depends on it,
+// to a module registry, and pins them in the buf.lock of buf.build/acme/c, which only
+// declares buf.build/acme/b as a dependency but also has an unused import of a file of
+// buf.build/acme/a.
+//
+// Returns the directory of the modules, the directory of the module registry, and the
+// dashless commit IDs of buf.build/acme/a and buf.build/acme/b.
+func testDepWorkspace(t *testing.T) (string, string, string, string) {
+	dirPath := t.TempDir()
+	registryDirPath := filepath.Join(dirPath, "registry")
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/buf.yaml": "version: v2\nname: buf.build/acme/a\n",
+			"a/a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+			"b/buf.yaml": "version: v2\nname: buf.build/acme/b\ndeps:\n  - buf.build/acme/a\n",
+			"b/b.proto":  "syntax = \"proto3\";\n\npackage b;\n\nimport \"a.proto\";\n\nmessage B {\n  a.A a = 1;\n}\n",
+			"c/buf.yaml": "version: v2\nname: buf.build/acme/c\ndeps:\n  - buf.build/acme/b:main\n",
+			"c/c.proto":  "syntax = \"proto3\";\n\npackage c;\n\nimport \"a.proto\";\nimport \"b.proto\";\n\nmessage C {\n  b.B b = 1;\n}\n",
+		},
+	)
+	aCommitID := testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "a"))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "update", filepath.Join(dirPath, "b"))
+	bCommitID := testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "b"))
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "update", filepath.Join(dirPath, "c"))
+	return dirPath, registryDirPath, aCommitID, bCommitID
+}
+
+// testDepSignedWorkspace pushes buf.build/acme/a signed with a new signing key to a
+// module registry, and pins it in the buf.lock of buf.build/acme/b, which trusts the
+// signing key.
+//
+// Returns the directory of the modules, and the directory of the module registry.
+func testDepSignedWorkspace(t *testing.T) (string, string) {
+	dirPath := t.TempDir()
+	registryDirPath := filepath.Join(dirPath, "registry")
+	signingKeyPath := filepath.Join(dirPath, "signing-key.pem")
+	stdout := bytes.NewBuffer(nil)
+	testRunWithModuleRegistry(t, registryDirPath, 0, stdout, "beta", "signing-key", "generate", signingKeyPath)
+	publicKey := strings.TrimSpace(stdout.String())
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/buf.yaml": "version: v2\nname: buf.build/acme/a\n",
+			"a/a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+			"b/buf.yaml": "version: v2\nname: buf.build/acme/b\ndeps:\n  - buf.build/acme/a\ntrusted_signing_keys:\n  - " + publicKey + "\n",
+			"b/b.proto":  "syntax = \"proto3\";\n\npackage b;\n\nimport \"a.proto\";\n\nmessage B {\n  a.A a = 1;\n}\n",
+		},
+	)
+	testPushToModuleRegistry(t, registryDirPath, filepath.Join(dirPath, "a"), "--signing-key", signingKeyPath)
+	testRunWithModuleRegistry(t, registryDirPath, 0, nil, "dep", "update", filepath.Join(dirPath, "b"))
+	return dirPath, registryDirPath
+}
@@ -0,0 +1,247 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/buf/bufctl"
+	"github.com/bufbuild/buf/private/pkg/osext"
+	"github.com/stretchr/testify/require"
+)
+
+// The tests in this file run git in the current directory, so they change the
+// working directory and cannot be run in parallel.
+
+func TestBreakingAgainstGitRefs(t *testing.T) {
+	dirPath := t.TempDir()
+	testGitInit(t, dirPath)
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"buf.yaml": "version: v2\n",
+			"a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string one = 1;\n}\n",
+		},
+	)
+	testGitCommitAndTag(t, dirPath, "v1.0.0")
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a.proto": "syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string one = 1;\n  string two = 2;\n}\n",
+		},
+	)
+	testGitCommitAndTag(t, dirPath, "v1.1.0")
+	// Delete the field that was added in v1.1.0.
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a.proto": "syntax = \"proto3\";\n\npackage a;\n\nmessage A {\n  string one = 1;\n}\n",
+		},
+	)
+	testChdir(t, dirPath)
+
+	// Tag globs check against each matching tag, and only v1.1.0 has the deleted field.
+	testRunStdout(
+		t,
+		nil,
+		bufctl.ExitCodeFileAnnotation,
+		`a.proto:5:1:Previously present field "2" with name "two" on message "A" was deleted. (against ".git#ref=v1.1.0")`,
+		"breaking",
+		"--against-git-refs",
+		"v1.*",
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		``,
+		"breaking",
+		"--against-git-refs",
+		"v1.0.*",
+	)
+	// Revision ranges check against each commit in the range, excluding the start.
+	testRunStdout(
+		t,
+		nil,
+		bufctl.ExitCodeFileAnnotation,
+		`a.proto:5:1:Previously present field "2" with name "two" on message "A" was deleted.`,
+		"breaking",
+		"--against-git-refs",
+		"v1.0.0..v1.1.0",
+	)
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{`--against-git-refs: no git refs matched "v1.1.0..v1.1.0"`},
+		"breaking",
+		"--against-git-refs",
+		"v1.1.0..v1.1.0",
+	)
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{`--against-git-refs: no git refs matched "v2.*"`},
+		"breaking",
+		"--against-git-refs",
+		"v2.*",
+	)
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{`--against or --against-git-refs is required`},
+		"breaking",
+	)
+}
+
+func testGitInit(t *testing.T, dirPath string) {
+	testRunGit(t, dirPath, "init")
+	testRunGit(t, dirPath, "config", "user.email", "tests@buf.build")
+	testRunGit(t, dirPath, "config", "user.name", "Buf go tests")
+}
+
+func testGitCommitAndTag(t *testing.T, dirPath string, tag string) {
+	testRunGit(t, dirPath, "add", "-A")
+	testRunGit(t, dirPath, "commit", "-m", tag)
+	testRunGit(t, dirPath, "tag", tag)
+}
+
+func testRunGit(t *testing.T, dirPath string, args ...string) {
+	output, err := exec.Command("git", append([]string{"-C", dirPath}, args...)...).CombinedOutput()
+	require.NoError(t, err, string(output))
+}
+
+func testWriteFiles(t *testing.T, dirPath string, pathToData map[string]string) {
+	for path, data := range pathToData {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dirPath, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dirPath, path), []byte(data), 0600))
+	}
+}
+
+func testChdir(t *testing.T, dirPath string) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, osext.Chdir(dirPath))
+	t.Cleanup(func() {
+		require.NoError(t, osext.Chdir(wd))
+	})
+}
+
+func TestAffected(t *testing.T) {
+	dirPath := t.TempDir()
+	testGitInit(t, dirPath)
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"buf.yaml":     "version: v2\n",
+			"a/v1/a.proto": "syntax = \"proto3\";\n\npackage a.v1;\n\nmessage A {\n  string one = 1;\n}\n",
+			"a/v1/b.proto": "syntax = \"proto3\";\n\npackage a.v1;\n\nmessage B {}\n",
+			"a/v1/c.proto": "syntax = \"proto3\";\n\npackage a.v1;\n\nimport \"a/v1/a.proto\";\n\nmessage C {\n  A a = 1;\n}\n",
+			"e/v1/e.proto": "syntax = \"proto3\";\n\npackage e.v1;\n\nmessage E {}\n",
+		},
+	)
+	testGitCommitAndTag(t, dirPath, "v1.0.0")
+	// Add a field that fails lint, delete a file, and add a file.
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/v1/a.proto": "syntax = \"proto3\";\n\npackage a.v1;\n\nmessage A {\n  string one = 1;\n  string Two = 2;\n}\n",
+			"f/v1/f.proto": "syntax = \"proto3\";\n\npackage f.v1;\n\nmessage F {}\n",
+		},
+	)
+	require.NoError(t, os.Remove(filepath.Join(dirPath, "a", "v1", "b.proto")))
+	testChdir(t, dirPath)
+
+	// c.proto is affected as it imports a.proto, and the deleted b.proto is listed
+	// so that buf breaking checks it.
+	affectedJSON := `{"modules":["."],"files":["a/v1/a.proto","a/v1/b.proto","a/v1/c.proto","f/v1/f.proto"],"plugins":[]}`
+	testRunStdout(
+		t,
+		nil,
+		0,
+		affectedJSON,
+		"beta",
+		"affected",
+		"--against",
+		"v1.0.0",
+		"--format",
+		"json",
+	)
+	testRunStdout(
+		t,
+		strings.NewReader(affectedJSON),
+		bufctl.ExitCodeFileAnnotation,
+		`a/v1/a.proto:7:10:Field name "Two" should be lower_snake_case, such as "two".`,
+		"lint",
+		"--affected",
+		"-",
+	)
+	testRunStdout(
+		t,
+		strings.NewReader(affectedJSON),
+		bufctl.ExitCodeFileAnnotation,
+		`<input>:1:1:Previously present file "a/v1/b.proto" was deleted.`,
+		"breaking",
+		"--against",
+		".git#ref=v1.0.0",
+		"--affected",
+		"-",
+	)
+	// A deleted file has nothing to lint, but is still checked by buf breaking.
+	deletedAffectedJSON := `{"modules":["."],"files":["a/v1/b.proto"],"plugins":[]}`
+	testRunStdout(
+		t,
+		strings.NewReader(deletedAffectedJSON),
+		0,
+		``,
+		"lint",
+		"--affected",
+		"-",
+	)
+	testRunStdout(
+		t,
+		strings.NewReader(deletedAffectedJSON),
+		bufctl.ExitCodeFileAnnotation,
+		`<input>:1:1:Previously present file "a/v1/b.proto" was deleted.`,
+		"breaking",
+		"--against",
+		".git#ref=v1.0.0",
+		"--affected",
+		"-",
+	)
+	// An added file has nothing to break.
+	testRunStdout(
+		t,
+		strings.NewReader(`{"modules":["."],"files":["f/v1/f.proto"],"plugins":[]}`),
+		0,
+		``,
+		"breaking",
+		"--against",
+		".git#ref=v1.0.0",
+		"--affected",
+		"-",
+	)
+}
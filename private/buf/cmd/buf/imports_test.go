@@ -15,6 +15,7 @@
 package buf
 
 import (
+	"context"
 	"io"
 	"path/filepath"
 	"strings"
@@ -25,6 +26,8 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufparse"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/uuidutil"
 	"github.com/stretchr/testify/require"
 )
@@ -74,7 +77,7 @@ func TestValidImportFromCorruptedCacheFile(t *testing.T) {
 		appFailureError(digestMismatchError).Error(),
 		func(use string) map[string]string {
 			return map[string]string{
-				useEnvVar(use, "CACHE_DIR"): filepath.Join("testdata", "imports", "corrupted_cache_file"),
+				useEnvVar(use, "CACHE_DIR"): testCopyCacheDir(t, "corrupted_cache_file"),
 			}
 		},
 		nil,
@@ -108,7 +111,7 @@ func TestValidImportFromCorruptedCacheDep(t *testing.T) {
 		appFailureError(digestMismatchError).Error(),
 		func(use string) map[string]string {
 			return map[string]string{
-				useEnvVar(use, "CACHE_DIR"): filepath.Join("testdata", "imports", "corrupted_cache_dep"),
+				useEnvVar(use, "CACHE_DIR"): testCopyCacheDir(t, "corrupted_cache_dep"),
 			}
 		},
 		nil,
@@ -215,7 +218,7 @@ func testRunStderrWithCache(t *testing.T, stdin io.Reader, expectedExitCode int,
 		expectedStderr,
 		func(use string) map[string]string {
 			return map[string]string{
-				useEnvVar(use, "CACHE_DIR"): filepath.Join("testdata", "imports", "cache"),
+				useEnvVar(use, "CACHE_DIR"): testCopyCacheDir(t, "cache"),
 			}
 		},
 		stdin,
@@ -231,7 +234,7 @@ func testRunStderrContainsWithCache(t *testing.T, stdin io.Reader, expectedExitC
 		expectedStderrPartials,
 		func(use string) map[string]string {
 			return map[string]string{
-				useEnvVar(use, "CACHE_DIR"): filepath.Join("testdata", "imports", "cache"),
+				useEnvVar(use, "CACHE_DIR"): testCopyCacheDir(t, "cache"),
 			}
 		},
 		stdin,
@@ -239,6 +242,20 @@ func testRunStderrContainsWithCache(t *testing.T, stdin io.Reader, expectedExitC
 	)
 }
 
+// testCopyCacheDir copies the cache directory within testdata/imports to a temporary
+// directory, as reading from the cache records the last access time of each entry.
+func testCopyCacheDir(t *testing.T, name string) string {
+	tempDirPath := t.TempDir()
+	storageosProvider := storageos.NewProvider()
+	readBucket, err := storageosProvider.NewReadWriteBucket(filepath.Join("testdata", "imports", name))
+	require.NoError(t, err)
+	writeBucket, err := storageosProvider.NewReadWriteBucket(tempDirPath)
+	require.NoError(t, err)
+	_, err = storage.Copy(context.Background(), readBucket, writeBucket)
+	require.NoError(t, err)
+	return tempDirPath
+}
+
 func useEnvVar(use string, suffix string) string {
 	return strings.ToUpper(use) + "_" + suffix
 }
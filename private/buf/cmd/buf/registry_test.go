@@ -0,0 +1,173 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/buf/cmd/buf/internal/internaltesting"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleRegistryBufYAML(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/buf.yaml": "version: v2\nname: buf.build/acme/a\nregistry: file://../registry\n",
+			"a/a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+			"b/buf.yaml": "version: v2\nname: buf.build/acme/b\ndeps:\n  - buf.build/acme/a\nregistry: file://../registry\n",
+			"b/b.proto":  "syntax = \"proto3\";\n\npackage b;\n\nimport \"a.proto\";\n\nmessage B {\n  a.A a = 1;\n}\n",
+		},
+	)
+
+	stdout := bytes.NewBuffer(nil)
+	testRun(t, 0, nil, stdout, "push", filepath.Join(dirPath, "a"), "--create")
+	require.True(t, strings.HasPrefix(stdout.String(), "buf.build/acme/a:"), stdout.String())
+	// The file path is relative to the directory of the buf.yaml file.
+	entries, err := os.ReadDir(filepath.Join(dirPath, "registry"))
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	testRunStdout(t, nil, 0, "", "dep", "update", filepath.Join(dirPath, "b"))
+	data, err := os.ReadFile(filepath.Join(dirPath, "b", "buf.lock"))
+	require.NoError(t, err)
+	require.Contains(t, string(data), "buf.build/acme/a")
+	testRunStdout(t, nil, 0, "", "build", filepath.Join(dirPath, "b"))
+}
+
+// This test changes the working directory, so it cannot be run in parallel.
+func TestModuleRegistryBufYAMLIgnoresCurrentDirectory(t *testing.T) {
+	dirPath := t.TempDir()
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/buf.yaml":   "version: v2\nname: buf.build/acme/a\nregistry: file://../registry\n",
+			"a/a.proto":    "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+			"b/buf.yaml":   "version: v2\nname: buf.build/acme/b\ndeps:\n  - buf.build/acme/a\nregistry: file://../registry\n",
+			"b/b.proto":    "syntax = \"proto3\";\n\npackage b;\n\nimport \"a.proto\";\n\nmessage B {\n  a.A a = 1;\n}\n",
+			"cwd/buf.yaml": "version: v2\nregistry: [\n",
+		},
+	)
+	testPushToModuleRegistry(t, filepath.Join(dirPath, "registry"), filepath.Join(dirPath, "a"))
+	testRunStdout(t, nil, 0, "", "dep", "update", filepath.Join(dirPath, "b"))
+
+	// The buf.yaml in the current directory does not parse, and is not read, as the
+	// module registry is selected by the buf.yaml of the input.
+	testChdir(t, filepath.Join(dirPath, "cwd"))
+	testRunStdout(t, nil, 0, "", "build", filepath.Join("..", "b"))
+	testRunStdout(t, nil, 0, "", "build", filepath.Join("..", "a"))
+}
+
+func TestModuleRegistryBufYAMLInvalid(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"buf.yaml": "version: v2\nregistry: https://example.com\n",
+			"a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+		},
+	)
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{
+			"registry in " + filepath.Join(dirPath, "buf.yaml") + ` must start with one of file://, oci://, or oci+http://, got "https://example.com"`,
+		},
+		"build",
+		dirPath,
+	)
+}
+
+func TestModuleRegistryBufYAMLConflict(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	testWriteFiles(
+		t,
+		dirPath,
+		map[string]string{
+			"a/buf.yaml": "version: v2\nregistry: file://../registry-a\n",
+			"a/a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+			"b/buf.yaml": "version: v2\nregistry: file://../registry-b\n",
+			"b/a.proto":  "syntax = \"proto3\";\n\npackage a;\n\nmessage A {}\n",
+		},
+	)
+	testRunStderrContainsNoWarn(
+		t,
+		nil,
+		1,
+		[]string{
+			"registry in " + filepath.Join(dirPath, "b", "buf.yaml") + ` is "file://../registry-b", but modules are already being resolved from "file://../registry-a"`,
+		},
+		"breaking",
+		filepath.Join(dirPath, "a"),
+		"--against",
+		filepath.Join(dirPath, "b"),
+	)
+}
+
+// testRunWithModuleRegistry runs the command with the module registry in the given
+// directory, and a new empty cache.
+func testRunWithModuleRegistry(
+	t *testing.T,
+	registryDirPath string,
+	expectedExitCode int,
+	stdout io.Writer,
+	args ...string,
+) {
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		expectedExitCode,
+		testModuleRegistryEnvFunc(t, registryDirPath),
+		nil,
+		stdout,
+		nil,
+		args...,
+	)
+}
+
+// testPushToModuleRegistry pushes the module in the directory to the module registry
+// in the given directory with the extra args, and returns the dashless commit ID of the
+// pushed commit.
+func testPushToModuleRegistry(t *testing.T, registryDirPath string, moduleDirPath string, extraArgs ...string) string {
+	stdout := bytes.NewBuffer(nil)
+	testRunWithModuleRegistry(t, registryDirPath, 0, stdout, append([]string{"push", moduleDirPath, "--create"}, extraArgs...)...)
+	_, commitID, ok := strings.Cut(strings.TrimSpace(stdout.String()), ":")
+	require.True(t, ok, stdout.String())
+	return commitID
+}
+
+func testModuleRegistryEnvFunc(t *testing.T, registryDirPath string) func(string) map[string]string {
+	envFunc := internaltesting.NewEnvFunc(t)
+	return func(use string) map[string]string {
+		env := envFunc(use)
+		env["BUF_MODULE_REGISTRY"] = "file://" + registryDirPath
+		return env
+	}
+}
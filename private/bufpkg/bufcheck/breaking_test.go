@@ -1000,6 +1000,24 @@ func TestRunBreakingMessageSameRequiredFields(t *testing.T) {
 	)
 }
 
+func TestRunBreakingNoDeleteUnlessDeprecated(t *testing.T) {
+	t.Parallel()
+	testBreaking(
+		t,
+		"breaking_no_delete_unless_deprecated",
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 5, 1, 10, 2, "ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 5, 1, 10, 2, "ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 5, 1, 10, 2, "ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 12, 1, 15, 2, "FIELD_NO_DELETE_UNLESS_DEPRECATED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 12, 1, 15, 2, "FIELD_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 12, 1, 15, 2, "FIELD_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 12, 1, 15, 2, "FIELD_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 17, 1, 17, 16, "MESSAGE_NO_DELETE_UNLESS_DEPRECATED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 21, 1, 21, 15, "RPC_NO_DELETE_UNLESS_DEPRECATED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 23, 1, 25, 2, "MESSAGE_NO_DELETE_UNLESS_DEPRECATED"),
+	)
+}
+
 func TestRunBreakingOneofNoDelete(t *testing.T) {
 	t.Parallel()
 	testBreaking(
@@ -1052,6 +1070,27 @@ func TestRunBreakingPackageServiceNoDelete(t *testing.T) {
 	)
 }
 
+func TestRunBreakingProtovalidate(t *testing.T) {
+	t.Parallel()
+	testBreaking(
+		t,
+		"breaking_protovalidate",
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 8, 23, 8, 62, "FIELD_PROTOVALIDATE_NO_NARROWED_RULES"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 9, 23, 9, 62, "FIELD_PROTOVALIDATE_NO_NARROWED_RULES"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 11, 24, 11, 60, "FIELD_PROTOVALIDATE_NO_ADD_REQUIRED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 13, 10, 13, 11, "FIELD_PROTOVALIDATE_NO_NARROWED_RULES"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 16, 14, 16, 15, "FIELD_PROTOVALIDATE_NO_NARROWED_RULES"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 18, 20, 18, 54, "FIELD_PROTOVALIDATE_NO_NARROWED_RULES"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 25, 5, 28, 6, "FIELD_PROTOVALIDATE_NO_ADD_CEL"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 30, 3, 30, 65, "FIELD_PROTOVALIDATE_NO_NARROWED_RULES"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 31, 31, 31, 85, "FIELD_PROTOVALIDATE_NO_NARROWED_RULES"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 33, 25, 33, 73, "FIELD_PROTOVALIDATE_NO_NARROWED_RULES"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 35, 5, 35, 49, "ONEOF_PROTOVALIDATE_NO_ADD_REQUIRED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 41, 1, 43, 2, "MESSAGE_PROTOVALIDATE_NO_REMOVE_DISABLED"),
+		bufanalysistesting.NewFileAnnotation(t, "1.proto", 46, 3, 49, 5, "MESSAGE_PROTOVALIDATE_NO_ADD_CEL"),
+	)
+}
+
 func TestRunBreakingReservedEnumNoDelete(t *testing.T) {
 	t.Parallel()
 	testBreaking(
@@ -18,6 +18,8 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"strconv"
+	"time"
 
 	"buf.build/go/bufplugin/check"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
@@ -205,6 +207,17 @@ func ClientWithStderr(stderr io.Writer) ClientOption {
 	}
 }
 
+// ClientWithNow returns a new ClientOption that specifies the function to get the current time.
+//
+// This is used to determine if comment ignores with an until date have lapsed.
+//
+// The default is time.Now.
+func ClientWithNow(now func() time.Time) ClientOption {
+	return func(clientOptions *clientOptions) {
+		clientOptions.now = now
+	}
+}
+
 // PrintRules prints the rules to the Writer.
 func PrintRules(writer io.Writer, rules []Rule, options ...PrintRulesOption) (retErr error) {
 	return printRules(writer, rules, options...)
@@ -229,6 +242,114 @@ func PrintRulesWithDeprecated() PrintRulesOption {
 	}
 }
 
+const (
+	// LintIgnoreSourceComment says that the LintIgnore came from a comment ignore within a file.
+	LintIgnoreSourceComment LintIgnoreSource = iota + 1
+	// LintIgnoreSourceConfig says that the LintIgnore came from an ignore_only entry in a buf.yaml.
+	LintIgnoreSourceConfig
+)
+
+const (
+	// LintIgnoreStatusActive says that the LintIgnore is in effect.
+	LintIgnoreStatusActive LintIgnoreStatus = iota + 1
+	// LintIgnoreStatusExpired says that the until date of the LintIgnore has passed.
+	LintIgnoreStatusExpired
+	// LintIgnoreStatusMissingReason says that a reason is required for comment ignores, but
+	// the LintIgnore has none.
+	LintIgnoreStatusMissingReason
+	// LintIgnoreStatusInvalidUntil says that the until date of the LintIgnore could not be parsed.
+	LintIgnoreStatusInvalidUntil
+	// LintIgnoreStatusDisabled says that the LintIgnore is a comment ignore, but comment ignores
+	// are not allowed by the LintConfig.
+	LintIgnoreStatusDisabled
+)
+
+// LintIgnoreSource is where a LintIgnore was declared.
+type LintIgnoreSource int
+
+// String implements fmt.Stringer.
+func (l LintIgnoreSource) String() string {
+	s, ok := lintIgnoreSourceToString[l]
+	if !ok {
+		return strconv.Itoa(int(l))
+	}
+	return s
+}
+
+// LintIgnoreStatus is the status of a LintIgnore.
+type LintIgnoreStatus int
+
+// String implements fmt.Stringer.
+func (l LintIgnoreStatus) String() string {
+	s, ok := lintIgnoreStatusToString[l]
+	if !ok {
+		return strconv.Itoa(int(l))
+	}
+	return s
+}
+
+// LintIgnore is a single lint ignore, either declared with a comment or with an ignore_only
+// entry in a buf.yaml.
+type LintIgnore interface {
+	// Source is where the LintIgnore was declared.
+	Source() LintIgnoreSource
+	// RuleIDOrCategory is the ID of the Rule or Category that is ignored.
+	//
+	// Comment ignores always refer to a Rule ID.
+	RuleIDOrCategory() string
+	// Path is the path of the file or directory that the LintIgnore applies to.
+	//
+	// This is relative to the root of the module.
+	Path() string
+	// StartLine is the 1-indexed line of the element that the comment ignore is attached to.
+	//
+	// Always 0 for LintIgnoreSourceConfig.
+	StartLine() int
+	// StartColumn is the 1-indexed column of the element that the comment ignore is attached to.
+	//
+	// Always 0 for LintIgnoreSourceConfig.
+	StartColumn() int
+	// Until is the last date that the LintIgnore is in effect.
+	//
+	// Zero if the LintIgnore has no until date, which is always the case for LintIgnoreSourceConfig.
+	Until() time.Time
+	// Reason is the justification given after the Rule ID in a comment ignore.
+	//
+	// Always empty for LintIgnoreSourceConfig.
+	Reason() string
+	// Status is the status of the LintIgnore at the time the LintIgnores were retrieved.
+	Status() LintIgnoreStatus
+
+	isLintIgnore()
+}
+
+// GetLintIgnores gets all the LintIgnores declared for the non-import files of the Image and
+// the given LintConfig, as evaluated at the given time.
+//
+// LintIgnores are sorted by path, then by location, then by Rule ID or Category.
+func GetLintIgnores(image bufimage.Image, config bufconfig.LintConfig, now time.Time) []LintIgnore {
+	return getLintIgnores(image, config, now)
+}
+
+// PrintLintIgnores prints the LintIgnores to the Writer, followed by the number of
+// LintIgnores for each Rule ID or Category.
+func PrintLintIgnores(writer io.Writer, lintIgnores []LintIgnore, options ...PrintLintIgnoresOption) error {
+	return printLintIgnores(writer, lintIgnores, options...)
+}
+
+// PrintLintIgnoresOption is an option for PrintLintIgnores.
+type PrintLintIgnoresOption func(*printLintIgnoresOptions)
+
+// PrintLintIgnoresWithJSON returns a new PrintLintIgnoresOption that says to print the
+// LintIgnores as a single JSON object.
+//
+// The default is to print as text.
+func PrintLintIgnoresWithJSON() PrintLintIgnoresOption {
+	return func(printLintIgnoresOptions *printLintIgnoresOptions) {
+		printLintIgnoresOptions.asJSON = true
+	}
+}
+
 // GetDeprecatedIDToReplacementIDs gets a map from deprecated ID to replacement IDs.
 func GetDeprecatedIDToReplacementIDs[R RuleOrCategory](rulesOrCategories []R) (map[string][]string, error) {
 	idToRuleOrCategory, err := slicesext.ToUniqueValuesMap(rulesOrCategories, func(ruleOrCategory R) string { return ruleOrCategory.ID() })
@@ -348,6 +348,18 @@ var (
 			bufcheckserverbuild.BreakingFieldNoDeleteUnlessNumberReservedRuleSpecBuilder.Build(false, []string{"WIRE_JSON", "WIRE"}),
 			bufcheckserverbuild.BreakingFieldWireCompatibleCardinalityRuleSpecBuilder.Build(false, []string{"WIRE"}),
 			bufcheckserverbuild.BreakingFieldWireCompatibleTypeRuleSpecBuilder.Build(false, []string{"WIRE"}),
+			bufcheckserverbuild.BreakingFieldProtovalidateNoAddCELRuleSpecBuilder.Build(false, []string{"PROTOVALIDATE_CONSTRAINTS"}),
+			bufcheckserverbuild.BreakingFieldProtovalidateNoAddRequiredRuleSpecBuilder.Build(false, []string{"PROTOVALIDATE_CONSTRAINTS"}),
+			bufcheckserverbuild.BreakingFieldProtovalidateNoNarrowedRulesRuleSpecBuilder.Build(false, []string{"PROTOVALIDATE_CONSTRAINTS"}),
+			bufcheckserverbuild.BreakingMessageProtovalidateNoAddCELRuleSpecBuilder.Build(false, []string{"PROTOVALIDATE_CONSTRAINTS"}),
+			bufcheckserverbuild.BreakingMessageProtovalidateNoRemoveDisabledRuleSpecBuilder.Build(false, []string{"PROTOVALIDATE_CONSTRAINTS"}),
+			bufcheckserverbuild.BreakingOneofProtovalidateNoAddRequiredRuleSpecBuilder.Build(false, []string{"PROTOVALIDATE_CONSTRAINTS"}),
+			bufcheckserverbuild.BreakingEnumValueNoDeleteUnlessDeprecatedRuleSpecBuilder.Build(false, []string{}),
+			bufcheckserverbuild.BreakingEnumValueNoDeleteUnlessDeprecatedAndReservedRuleSpecBuilder.Build(false, []string{}),
+			bufcheckserverbuild.BreakingFieldNoDeleteUnlessDeprecatedRuleSpecBuilder.Build(false, []string{}),
+			bufcheckserverbuild.BreakingFieldNoDeleteUnlessDeprecatedAndReservedRuleSpecBuilder.Build(false, []string{}),
+			bufcheckserverbuild.BreakingMessageNoDeleteUnlessDeprecatedRuleSpecBuilder.Build(false, []string{}),
+			bufcheckserverbuild.BreakingRPCNoDeleteUnlessDeprecatedRuleSpecBuilder.Build(false, []string{}),
 			bufcheckserverbuild.BreakingMessageSameMessageSetWireFormatRuleSpecBuilder.Build(false, []string{}),
 			bufcheckserverbuild.LintCommentEnumRuleSpecBuilder.Build(false, []string{"COMMENTS"}),
 			bufcheckserverbuild.LintCommentEnumValueRuleSpecBuilder.Build(false, []string{"COMMENTS"}),
@@ -363,6 +375,8 @@ var (
 			bufcheckserverbuild.LintEnumValuePrefixRuleSpecBuilder.Build(true, []string{"DEFAULT", "STANDARD"}),
 			bufcheckserverbuild.LintEnumValueUpperSnakeCaseRuleSpecBuilder.Build(true, []string{"BASIC", "DEFAULT", "STANDARD"}),
 			bufcheckserverbuild.LintEnumZeroValueSuffixRuleSpecBuilder.Build(true, []string{"DEFAULT", "STANDARD"}),
+			bufcheckserverbuild.LintFeatureNoLegacyValueRuleSpecBuilder.Build(false, []string{}),
+			bufcheckserverbuild.LintFeatureNoRedundantOverrideRuleSpecBuilder.Build(false, []string{}),
 			bufcheckserverbuild.LintFieldLowerSnakeCaseRuleSpecBuilder.Build(true, []string{"BASIC", "DEFAULT", "STANDARD"}),
 			bufcheckserverbuild.LintFieldNotRequiredRuleSpecBuilder.Build(true, []string{"BASIC", "DEFAULT", "STANDARD"}),
 			bufcheckserverbuild.LintFileLowerSnakeCaseRuleSpecBuilder.Build(true, []string{"DEFAULT", "STANDARD"}),
@@ -401,6 +415,7 @@ var (
 			bufcheckserverbuild.PackageCategorySpec,
 			bufcheckserverbuild.WireCategorySpec,
 			bufcheckserverbuild.WireJSONCategorySpec,
+			bufcheckserverbuild.ProtovalidateConstraintsCategorySpec,
 			bufcheckserverbuild.BasicCategorySpec,
 			bufcheckserverbuild.CommentsCategorySpec,
 			bufcheckserverbuild.DefaultCategorySpec,
@@ -0,0 +1,130 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufbreakingvalidate compares protovalidate constraints between two
+// versions of a schema, and reports any changes that narrow the set of values
+// that pass validation.
+package bufbreakingvalidate
+
+import (
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+)
+
+const (
+	// https://buf.build/bufbuild/protovalidate/docs/main:buf.validate#buf.validate.FieldConstraints
+	celFieldNumberInFieldConstraints = 23
+	// https://buf.build/bufbuild/protovalidate/docs/main:buf.validate#buf.validate.MessageConstraints
+	celFieldNumberInMessageConstraints = 3
+)
+
+// Narrowing is a change to protovalidate constraints that narrows the set of
+// values that pass validation.
+type Narrowing struct {
+	// Path is the path of the narrowed rule, relative to the constraints option.
+	//
+	// This can be passed to bufprotosource.OptionExtensionDescriptor.OptionExtensionLocation.
+	Path []int32
+	// Description is a human-readable description of the narrowing, with
+	// the name of the rule and a trailing period.
+	Description string
+}
+
+// FieldRulesNarrowings returns the Narrowings of the rules in the given FieldConstraints
+// compared to the previous FieldConstraints.
+//
+// This covers the type-specific rules (such as string.max_len) and the ignore
+// behavior of the field, but not the top-level required and cel rules, which are
+// checked separately with FieldRequiredAdded and FieldCELNarrowings.
+//
+// Either FieldConstraints may be nil.
+func FieldRulesNarrowings(
+	fieldConstraints *validate.FieldConstraints,
+	previousFieldConstraints *validate.FieldConstraints,
+) []Narrowing {
+	return newFieldConstraintsComparer(
+		fieldConstraintsRuleName,
+		nil,
+		false,
+	).compare(
+		fieldConstraints,
+		previousFieldConstraints,
+	)
+}
+
+// FieldRequiredAdded returns true if the field became required.
+//
+// Either FieldConstraints may be nil.
+func FieldRequiredAdded(
+	fieldConstraints *validate.FieldConstraints,
+	previousFieldConstraints *validate.FieldConstraints,
+) bool {
+	return fieldConstraints.GetRequired() && !previousFieldConstraints.GetRequired()
+}
+
+// FieldCELNarrowings returns a Narrowing for every CEL constraint on the field that
+// was added or changed.
+//
+// Either FieldConstraints may be nil.
+func FieldCELNarrowings(
+	fieldConstraints *validate.FieldConstraints,
+	previousFieldConstraints *validate.FieldConstraints,
+) []Narrowing {
+	return celNarrowings(
+		fieldConstraintsRuleName+".cel",
+		[]int32{celFieldNumberInFieldConstraints},
+		fieldConstraints.GetCel(),
+		previousFieldConstraints.GetCel(),
+	)
+}
+
+// MessageCELNarrowings returns a Narrowing for every CEL constraint on the message that
+// was added or changed.
+//
+// Either MessageConstraints may be nil.
+func MessageCELNarrowings(
+	messageConstraints *validate.MessageConstraints,
+	previousMessageConstraints *validate.MessageConstraints,
+) []Narrowing {
+	if messageConstraints.GetDisabled() {
+		// None of the rules on the message are applied.
+		return nil
+	}
+	return celNarrowings(
+		messageConstraintsRuleName+".cel",
+		[]int32{celFieldNumberInMessageConstraints},
+		messageConstraints.GetCel(),
+		previousMessageConstraints.GetCel(),
+	)
+}
+
+// MessageDisabledRemoved returns true if the message previously had validation
+// disabled, and now has validation enabled.
+//
+// Either MessageConstraints may be nil.
+func MessageDisabledRemoved(
+	messageConstraints *validate.MessageConstraints,
+	previousMessageConstraints *validate.MessageConstraints,
+) bool {
+	return previousMessageConstraints.GetDisabled() && !messageConstraints.GetDisabled()
+}
+
+// OneofRequiredAdded returns true if the oneof became required.
+//
+// Either OneofConstraints may be nil.
+func OneofRequiredAdded(
+	oneofConstraints *validate.OneofConstraints,
+	previousOneofConstraints *validate.OneofConstraints,
+) bool {
+	return oneofConstraints.GetRequired() && !previousOneofConstraints.GetRequired()
+}
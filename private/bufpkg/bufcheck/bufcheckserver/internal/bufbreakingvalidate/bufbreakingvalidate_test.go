@@ -0,0 +1,212 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufbreakingvalidate
+
+import (
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestFieldRulesNarrowings(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name                     string
+		fieldConstraints         *validate.FieldConstraints
+		previousFieldConstraints *validate.FieldConstraints
+		expectedDescriptions     []string
+	}{
+		{
+			name:                     "no_constraints",
+			fieldConstraints:         nil,
+			previousFieldConstraints: stringRules(&validate.StringRules{MaxLen: proto.Uint64(1)}),
+		},
+		{
+			name:                     "removed_constraints",
+			fieldConstraints:         &validate.FieldConstraints{},
+			previousFieldConstraints: stringRules(&validate.StringRules{MaxLen: proto.Uint64(1)}),
+		},
+		{
+			name:                     "added_max_len",
+			fieldConstraints:         stringRules(&validate.StringRules{MaxLen: proto.Uint64(1)}),
+			previousFieldConstraints: nil,
+			expectedDescriptions: []string{
+				"(buf.validate.field).string.max_len was added with value 1.",
+			},
+		},
+		{
+			name:                     "loosened_max_len",
+			fieldConstraints:         stringRules(&validate.StringRules{MaxLen: proto.Uint64(10)}),
+			previousFieldConstraints: stringRules(&validate.StringRules{MaxLen: proto.Uint64(5)}),
+		},
+		{
+			name:                     "strict_unset_is_true",
+			fieldConstraints:         stringRules(&validate.StringRules{}),
+			previousFieldConstraints: stringRules(&validate.StringRules{Strict: proto.Bool(false)}),
+			expectedDescriptions: []string{
+				"(buf.validate.field).string.strict was changed from false to true.",
+			},
+		},
+		{
+			name:                     "well_known_replaced",
+			fieldConstraints:         stringRules(&validate.StringRules{WellKnown: &validate.StringRules_Hostname{Hostname: true}}),
+			previousFieldConstraints: stringRules(&validate.StringRules{WellKnown: &validate.StringRules_Email{Email: true}}),
+			expectedDescriptions: []string{
+				"(buf.validate.field).string.email was replaced by (buf.validate.field).string.hostname.",
+			},
+		},
+		{
+			name: "type_replaced",
+			fieldConstraints: &validate.FieldConstraints{
+				Type: &validate.FieldConstraints_Bytes{Bytes: &validate.BytesRules{}},
+			},
+			previousFieldConstraints: stringRules(&validate.StringRules{}),
+			expectedDescriptions: []string{
+				"(buf.validate.field).string was replaced by (buf.validate.field).bytes.",
+			},
+		},
+		{
+			name: "duration_upper_bound",
+			fieldConstraints: &validate.FieldConstraints{
+				Type: &validate.FieldConstraints_Duration{
+					Duration: &validate.DurationRules{
+						LessThan: &validate.DurationRules_Lte{Lte: durationpb.New(5e9)},
+					},
+				},
+			},
+			previousFieldConstraints: &validate.FieldConstraints{
+				Type: &validate.FieldConstraints_Duration{
+					Duration: &validate.DurationRules{
+						LessThan: &validate.DurationRules_Lte{Lte: durationpb.New(10e9)},
+					},
+				},
+			},
+			expectedDescriptions: []string{
+				"(buf.validate.field).duration.lte = 10s was narrowed to (buf.validate.field).duration.lte = 5s.",
+			},
+		},
+		{
+			name: "exclusive_range_changed",
+			fieldConstraints: &validate.FieldConstraints{
+				Type: &validate.FieldConstraints_Int32{
+					Int32: &validate.Int32Rules{
+						GreaterThan: &validate.Int32Rules_Gt{Gt: 20},
+						LessThan:    &validate.Int32Rules_Lt{Lt: 5},
+					},
+				},
+			},
+			previousFieldConstraints: &validate.FieldConstraints{
+				Type: &validate.FieldConstraints_Int32{
+					Int32: &validate.Int32Rules{
+						GreaterThan: &validate.Int32Rules_Gt{Gt: 10},
+						LessThan:    &validate.Int32Rules_Lt{Lt: 5},
+					},
+				},
+			},
+			expectedDescriptions: []string{
+				"(buf.validate.field).int32 range changed from [gt = 10, lt = 5] to [gt = 20, lt = 5].",
+			},
+		},
+		{
+			name: "ignore_always_removed",
+			fieldConstraints: &validate.FieldConstraints{
+				Type: &validate.FieldConstraints_String_{String_: &validate.StringRules{MinLen: proto.Uint64(1)}},
+			},
+			previousFieldConstraints: &validate.FieldConstraints{
+				Ignore: validate.Ignore_IGNORE_ALWAYS.Enum(),
+			},
+			expectedDescriptions: []string{
+				"(buf.validate.field).ignore changed from IGNORE_ALWAYS to IGNORE_UNSPECIFIED.",
+			},
+		},
+		{
+			name: "map_values_required",
+			fieldConstraints: &validate.FieldConstraints{
+				Type: &validate.FieldConstraints_Map{
+					Map: &validate.MapRules{
+						Values: &validate.FieldConstraints{Required: proto.Bool(true)},
+					},
+				},
+			},
+			previousFieldConstraints: &validate.FieldConstraints{
+				Type: &validate.FieldConstraints_Map{
+					Map: &validate.MapRules{},
+				},
+			},
+			expectedDescriptions: []string{
+				"(buf.validate.field).map.values.required was added.",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			narrowings := FieldRulesNarrowings(testCase.fieldConstraints, testCase.previousFieldConstraints)
+			descriptions := make([]string, len(narrowings))
+			for i, narrowing := range narrowings {
+				descriptions[i] = narrowing.Description
+			}
+			if len(testCase.expectedDescriptions) == 0 {
+				assert.Empty(t, descriptions)
+				return
+			}
+			assert.Equal(t, testCase.expectedDescriptions, descriptions)
+		})
+	}
+}
+
+func TestFieldCELNarrowings(t *testing.T) {
+	t.Parallel()
+	narrowings := FieldCELNarrowings(
+		&validate.FieldConstraints{
+			Cel: []*validate.Constraint{
+				{Id: proto.String("a"), Expression: proto.String("true")},
+				{Id: proto.String("b"), Expression: proto.String("false")},
+				{Expression: proto.String("this > 1")},
+			},
+		},
+		&validate.FieldConstraints{
+			Cel: []*validate.Constraint{
+				{Id: proto.String("a"), Expression: proto.String("true")},
+				{Id: proto.String("b"), Expression: proto.String("true")},
+			},
+		},
+	)
+	assert.Equal(
+		t,
+		[]Narrowing{
+			{
+				Path:        []int32{celFieldNumberInFieldConstraints, 1},
+				Description: `(buf.validate.field).cel constraint "b" changed expression from "true" to "false".`,
+			},
+			{
+				Path:        []int32{celFieldNumberInFieldConstraints, 2},
+				Description: `(buf.validate.field).cel constraint with expression "this > 1" was added.`,
+			},
+		},
+		narrowings,
+	)
+}
+
+func stringRules(stringRules *validate.StringRules) *validate.FieldConstraints {
+	return &validate.FieldConstraints{
+		Type: &validate.FieldConstraints_String_{
+			String_: stringRules,
+		},
+	}
+}
@@ -0,0 +1,557 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufbreakingvalidate
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	fieldConstraintsRuleName   = "(buf.validate.field)"
+	messageConstraintsRuleName = "(buf.validate.message)"
+
+	// https://buf.build/bufbuild/protovalidate/docs/main:buf.validate#buf.validate.FieldConstraints
+	requiredFieldNumberInFieldConstraints = 25
+	ignoreFieldNumberInFieldConstraints   = 27
+)
+
+var (
+	// https://buf.build/bufbuild/protovalidate/docs/main:buf.validate#buf.validate.FieldConstraints
+	fieldConstraintsDescriptor = validate.File_buf_validate_validate_proto.Messages().ByName("FieldConstraints")
+	typeOneofDescriptor        = fieldConstraintsDescriptor.Oneofs().ByName("type")
+
+	// The effective ignore behaviors, ordered from least to most permissive.
+	ignoreRankToName = []string{
+		validate.Ignore_IGNORE_UNSPECIFIED.String(),
+		validate.Ignore_IGNORE_IF_UNPOPULATED.String(),
+		validate.Ignore_IGNORE_IF_DEFAULT_VALUE.String(),
+		validate.Ignore_IGNORE_ALWAYS.String(),
+	}
+)
+
+// fieldConstraintsComparer compares a FieldConstraints to a previous FieldConstraints.
+//
+// This is recursive for repeated.items, map.keys and map.values, in which case the
+// comparer is created with the rule name and path of the nested FieldConstraints.
+type fieldConstraintsComparer struct {
+	ruleName string
+	basePath []int32
+	// nested is true if this is a nested FieldConstraints, in which case required and
+	// cel are also compared, as there is no dedicated rule for them.
+	nested     bool
+	narrowings []Narrowing
+}
+
+func newFieldConstraintsComparer(ruleName string, basePath []int32, nested bool) *fieldConstraintsComparer {
+	return &fieldConstraintsComparer{
+		ruleName: ruleName,
+		basePath: basePath,
+		nested:   nested,
+	}
+}
+
+func (c *fieldConstraintsComparer) compare(
+	fieldConstraints *validate.FieldConstraints,
+	previousFieldConstraints *validate.FieldConstraints,
+) []Narrowing {
+	if fieldConstraints == nil {
+		// No constraints, nothing can be narrowed.
+		return nil
+	}
+	if previousFieldConstraints == nil {
+		previousFieldConstraints = &validate.FieldConstraints{}
+	}
+	ignoreRank := getIgnoreRank(fieldConstraints)
+	previousIgnoreRank := getIgnoreRank(previousFieldConstraints)
+	if ignoreRank < previousIgnoreRank {
+		c.addf(
+			[]int32{ignoreFieldNumberInFieldConstraints},
+			"%s.ignore changed from %s to %s.",
+			c.ruleName,
+			ignoreRankToName[previousIgnoreRank],
+			ignoreRankToName[ignoreRank],
+		)
+	}
+	if ignoreRank == len(ignoreRankToName)-1 || previousIgnoreRank == len(ignoreRankToName)-1 {
+		// Either no rules are applied, or no rules were previously applied. In the latter
+		// case, we have already reported the change to ignore above.
+		return c.narrowings
+	}
+	if c.nested {
+		if FieldRequiredAdded(fieldConstraints, previousFieldConstraints) {
+			c.addf(
+				[]int32{requiredFieldNumberInFieldConstraints},
+				"%s.required was added.",
+				c.ruleName,
+			)
+		}
+		for _, narrowing := range celNarrowings(
+			c.ruleName+".cel",
+			[]int32{celFieldNumberInFieldConstraints},
+			fieldConstraints.GetCel(),
+			previousFieldConstraints.GetCel(),
+		) {
+			c.addf(narrowing.Path, "%s", narrowing.Description)
+		}
+	}
+	fieldConstraintsMessage := fieldConstraints.ProtoReflect()
+	previousFieldConstraintsMessage := previousFieldConstraints.ProtoReflect()
+	typeRulesFieldDescriptor := fieldConstraintsMessage.WhichOneof(typeOneofDescriptor)
+	if typeRulesFieldDescriptor == nil {
+		return c.narrowings
+	}
+	typeRulesName := c.ruleName + "." + string(typeRulesFieldDescriptor.Name())
+	typeRulesPath := []int32{int32(typeRulesFieldDescriptor.Number())}
+	previousTypeRulesFieldDescriptor := previousFieldConstraintsMessage.WhichOneof(typeOneofDescriptor)
+	if previousTypeRulesFieldDescriptor != nil && previousTypeRulesFieldDescriptor.Number() != typeRulesFieldDescriptor.Number() {
+		c.addf(
+			typeRulesPath,
+			"%s was replaced by %s.",
+			c.ruleName+"."+string(previousTypeRulesFieldDescriptor.Name()),
+			typeRulesName,
+		)
+		return c.narrowings
+	}
+	// If the previous type rules are not set, Get returns an empty message, which
+	// means that every rule that is now set is reported as added.
+	c.compareRules(
+		typeRulesPath,
+		typeRulesName,
+		fieldConstraintsMessage.Get(typeRulesFieldDescriptor).Message(),
+		previousFieldConstraintsMessage.Get(typeRulesFieldDescriptor).Message(),
+	)
+	return c.narrowings
+}
+
+// compareRules compares a type rules message, such as StringRules, to the previous
+// type rules message of the same type.
+func (c *fieldConstraintsComparer) compareRules(
+	path []int32,
+	ruleName string,
+	rules protoreflect.Message,
+	previousRules protoreflect.Message,
+) {
+	fields := rules.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fieldDescriptor := fields.Get(i)
+		if oneof := fieldDescriptor.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+			// Oneofs are compared as a whole below.
+			continue
+		}
+		fieldPath := appendPath(path, int32(fieldDescriptor.Number()))
+		fieldRuleName := ruleName + "." + string(fieldDescriptor.Name())
+		value := rules.Get(fieldDescriptor)
+		previousValue := previousRules.Get(fieldDescriptor)
+		switch fieldDescriptor.Name() {
+		case "example":
+			// Examples do not affect validation.
+		case "min_len", "min_bytes", "min_items", "min_pairs":
+			if !rules.Has(fieldDescriptor) {
+				continue
+			}
+			if !previousRules.Has(fieldDescriptor) {
+				c.addf(fieldPath, "%s was added with value %s.", fieldRuleName, formatValue(fieldDescriptor, value))
+			} else if compareValues(fieldDescriptor, value, previousValue) > 0 {
+				c.addf(
+					fieldPath,
+					"%s was increased from %s to %s.",
+					fieldRuleName,
+					formatValue(fieldDescriptor, previousValue),
+					formatValue(fieldDescriptor, value),
+				)
+			}
+		case "max_len", "max_bytes", "max_items", "max_pairs", "within":
+			if !rules.Has(fieldDescriptor) {
+				continue
+			}
+			if !previousRules.Has(fieldDescriptor) {
+				c.addf(fieldPath, "%s was added with value %s.", fieldRuleName, formatValue(fieldDescriptor, value))
+			} else if compareValues(fieldDescriptor, value, previousValue) < 0 {
+				c.addf(
+					fieldPath,
+					"%s was decreased from %s to %s.",
+					fieldRuleName,
+					formatValue(fieldDescriptor, previousValue),
+					formatValue(fieldDescriptor, value),
+				)
+			}
+		case "in":
+			list := value.List()
+			if list.Len() == 0 {
+				continue
+			}
+			previousList := previousValue.List()
+			if previousList.Len() == 0 {
+				c.addf(
+					fieldPath,
+					"%s was added, restricting values to [%s].",
+					fieldRuleName,
+					formatValues(fieldDescriptor, listValues(list)),
+				)
+				continue
+			}
+			if removedValues := listDifference(previousList, list); len(removedValues) > 0 {
+				c.addf(
+					fieldPath,
+					"%s no longer allows [%s].",
+					fieldRuleName,
+					formatValues(fieldDescriptor, removedValues),
+				)
+			}
+		case "not_in":
+			if addedValues := listDifference(value.List(), previousValue.List()); len(addedValues) > 0 {
+				c.addf(
+					fieldPath,
+					"%s now also disallows [%s].",
+					fieldRuleName,
+					formatValues(fieldDescriptor, addedValues),
+				)
+			}
+		case "strict":
+			// strict defaults to true if not set.
+			if (!rules.Has(fieldDescriptor) || value.Bool()) &&
+				(previousRules.Has(fieldDescriptor) && !previousValue.Bool()) {
+				c.addf(fieldPath, "%s was changed from false to true.", fieldRuleName)
+			}
+		case "items", "keys", "values":
+			c.narrowings = append(
+				c.narrowings,
+				newFieldConstraintsComparer(
+					fieldRuleName,
+					fieldPath,
+					true,
+				).compare(
+					getFieldConstraints(rules, fieldDescriptor),
+					getFieldConstraints(previousRules, fieldDescriptor),
+				)...,
+			)
+		default:
+			if fieldDescriptor.Kind() == protoreflect.BoolKind && fieldDescriptor.Name() != "const" {
+				// Flags such as unique, defined_only and finite.
+				if value.Bool() && !previousValue.Bool() {
+					c.addf(fieldPath, "%s was added.", fieldRuleName)
+				}
+				continue
+			}
+			c.compareSetValue(fieldPath, fieldRuleName, fieldDescriptor, rules, previousRules)
+		}
+	}
+	if isExclusiveRange(rules) || isExclusiveRange(previousRules) {
+		// A range where the lower bound is greater than the upper bound matches values
+		// outside of the range. We do not attempt to compare these, and treat any change
+		// to either bound as a narrowing.
+		if formatRange(rules) != formatRange(previousRules) {
+			c.addf(path, "%s range changed from %s to %s.", ruleName, formatRange(previousRules), formatRange(rules))
+		}
+	} else {
+		c.compareBound(path, ruleName, rules, previousRules, "less_than")
+		c.compareBound(path, ruleName, rules, previousRules, "greater_than")
+	}
+	oneofs := rules.Descriptor().Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		oneof := oneofs.Get(i)
+		if oneof.IsSynthetic() || oneof.Name() == "less_than" || oneof.Name() == "greater_than" {
+			continue
+		}
+		// Other oneofs are the well-known formats for strings and bytes, such as email or ipv4.
+		fieldDescriptor := rules.WhichOneof(oneof)
+		if fieldDescriptor == nil {
+			continue
+		}
+		fieldPath := appendPath(path, int32(fieldDescriptor.Number()))
+		fieldRuleName := ruleName + "." + string(fieldDescriptor.Name())
+		previousFieldDescriptor := previousRules.WhichOneof(oneof)
+		if previousFieldDescriptor != nil && previousFieldDescriptor.Number() != fieldDescriptor.Number() {
+			c.addf(fieldPath, "%s was replaced by %s.", ruleName+"."+string(previousFieldDescriptor.Name()), fieldRuleName)
+			continue
+		}
+		if fieldDescriptor.Kind() == protoreflect.BoolKind {
+			if rules.Get(fieldDescriptor).Bool() && (previousFieldDescriptor == nil || !previousRules.Get(fieldDescriptor).Bool()) {
+				c.addf(fieldPath, "%s was added.", fieldRuleName)
+			}
+			continue
+		}
+		c.compareSetValue(fieldPath, fieldRuleName, fieldDescriptor, rules, previousRules)
+	}
+	// Predefined rules are extensions of the rules messages. Since these are not known to
+	// us, they are either unknown fields, or extension fields if resolved.
+	rules.Range(func(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		if fieldDescriptor.IsExtension() {
+			c.compareSetValue(
+				appendPath(path, int32(fieldDescriptor.Number())),
+				ruleName+".("+string(fieldDescriptor.FullName())+")",
+				fieldDescriptor,
+				rules,
+				previousRules,
+			)
+		}
+		return true
+	})
+	if unknown := rules.GetUnknown(); len(unknown) > 0 && !bytes.Equal(unknown, previousRules.GetUnknown()) {
+		c.addf(path, "%s has added or changed predefined rules.", ruleName)
+	}
+}
+
+// compareBound compares the less_than or greater_than oneof.
+func (c *fieldConstraintsComparer) compareBound(
+	path []int32,
+	ruleName string,
+	rules protoreflect.Message,
+	previousRules protoreflect.Message,
+	oneofName protoreflect.Name,
+) {
+	oneof := rules.Descriptor().Oneofs().ByName(oneofName)
+	if oneof == nil {
+		return
+	}
+	fieldDescriptor := rules.WhichOneof(oneof)
+	if fieldDescriptor == nil {
+		return
+	}
+	fieldPath := appendPath(path, int32(fieldDescriptor.Number()))
+	value := rules.Get(fieldDescriptor)
+	previousFieldDescriptor := previousRules.WhichOneof(oneof)
+	if previousFieldDescriptor == nil {
+		c.addf(fieldPath, "%s was added.", formatBound(ruleName, fieldDescriptor, value))
+		return
+	}
+	previousValue := previousRules.Get(previousFieldDescriptor)
+	if fieldDescriptor.Kind() == protoreflect.BoolKind || previousFieldDescriptor.Kind() == protoreflect.BoolKind {
+		// lt_now and gt_now cannot be compared to a fixed value.
+		if fieldDescriptor.Number() != previousFieldDescriptor.Number() || value.Bool() != previousValue.Bool() {
+			c.addf(
+				fieldPath,
+				"%s was narrowed to %s.",
+				formatBound(ruleName, previousFieldDescriptor, previousValue),
+				formatBound(ruleName, fieldDescriptor, value),
+			)
+		}
+		return
+	}
+	comparison := compareValues(fieldDescriptor, value, previousValue)
+	if oneofName == "greater_than" {
+		comparison = -comparison
+	}
+	// Inclusive bounds are lte and gte.
+	inclusive := strings.HasSuffix(string(fieldDescriptor.Name()), "e")
+	previousInclusive := strings.HasSuffix(string(previousFieldDescriptor.Name()), "e")
+	if comparison < 0 || (comparison == 0 && previousInclusive && !inclusive) {
+		c.addf(
+			fieldPath,
+			"%s was narrowed to %s.",
+			formatBound(ruleName, previousFieldDescriptor, previousValue),
+			formatBound(ruleName, fieldDescriptor, value),
+		)
+	}
+}
+
+// compareSetValue reports a narrowing if the field is set, and was either not previously
+// set or set to a different value.
+func (c *fieldConstraintsComparer) compareSetValue(
+	path []int32,
+	ruleName string,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	rules protoreflect.Message,
+	previousRules protoreflect.Message,
+) {
+	if !rules.Has(fieldDescriptor) {
+		return
+	}
+	value := rules.Get(fieldDescriptor)
+	if !previousRules.Has(fieldDescriptor) {
+		c.addf(path, "%s was added with value %s.", ruleName, formatValue(fieldDescriptor, value))
+		return
+	}
+	previousValue := previousRules.Get(fieldDescriptor)
+	if valueKey(value) != valueKey(previousValue) {
+		c.addf(
+			path,
+			"%s was changed from %s to %s.",
+			ruleName,
+			formatValue(fieldDescriptor, previousValue),
+			formatValue(fieldDescriptor, value),
+		)
+	}
+}
+
+func (c *fieldConstraintsComparer) addf(path []int32, format string, args ...any) {
+	c.narrowings = append(
+		c.narrowings,
+		Narrowing{
+			Path:        appendPath(c.basePath, path...),
+			Description: fmt.Sprintf(format, args...),
+		},
+	)
+}
+
+// celNarrowings returns a Narrowing for every constraint that was added or changed.
+//
+// Constraints are matched by ID if they have one, otherwise by expression.
+func celNarrowings(
+	ruleName string,
+	basePath []int32,
+	constraints []*validate.Constraint,
+	previousConstraints []*validate.Constraint,
+) []Narrowing {
+	previousIDToExpression := make(map[string]string, len(previousConstraints))
+	previousExpressions := make(map[string]struct{}, len(previousConstraints))
+	for _, previousConstraint := range previousConstraints {
+		if previousConstraint.GetId() != "" {
+			previousIDToExpression[previousConstraint.GetId()] = previousConstraint.GetExpression()
+		}
+		previousExpressions[previousConstraint.GetExpression()] = struct{}{}
+	}
+	var narrowings []Narrowing
+	for i, constraint := range constraints {
+		path := appendPath(basePath, int32(i))
+		if id := constraint.GetId(); id != "" {
+			previousExpression, ok := previousIDToExpression[id]
+			if !ok {
+				narrowings = append(narrowings, Narrowing{
+					Path:        path,
+					Description: fmt.Sprintf("%s constraint %q was added.", ruleName, id),
+				})
+			} else if previousExpression != constraint.GetExpression() {
+				narrowings = append(narrowings, Narrowing{
+					Path: path,
+					Description: fmt.Sprintf(
+						"%s constraint %q changed expression from %q to %q.",
+						ruleName,
+						id,
+						previousExpression,
+						constraint.GetExpression(),
+					),
+				})
+			}
+			continue
+		}
+		if _, ok := previousExpressions[constraint.GetExpression()]; !ok {
+			narrowings = append(narrowings, Narrowing{
+				Path:        path,
+				Description: fmt.Sprintf("%s constraint with expression %q was added.", ruleName, constraint.GetExpression()),
+			})
+		}
+	}
+	return narrowings
+}
+
+func getIgnoreRank(fieldConstraints *validate.FieldConstraints) int {
+	if fieldConstraints.GetSkipped() {
+		return 3
+	}
+	switch fieldConstraints.GetIgnore() {
+	case validate.Ignore_IGNORE_ALWAYS:
+		return 3
+	case validate.Ignore_IGNORE_IF_DEFAULT_VALUE:
+		return 2
+	case validate.Ignore_IGNORE_IF_UNPOPULATED:
+		return 1
+	}
+	if fieldConstraints.GetIgnoreEmpty() {
+		return 1
+	}
+	return 0
+}
+
+func getFieldConstraints(rules protoreflect.Message, fieldDescriptor protoreflect.FieldDescriptor) *validate.FieldConstraints {
+	if !rules.Has(fieldDescriptor) {
+		return nil
+	}
+	fieldConstraints, ok := rules.Get(fieldDescriptor).Message().Interface().(*validate.FieldConstraints)
+	if !ok {
+		return nil
+	}
+	return fieldConstraints
+}
+
+func isExclusiveRange(rules protoreflect.Message) bool {
+	oneofs := rules.Descriptor().Oneofs()
+	lessThanOneof := oneofs.ByName("less_than")
+	greaterThanOneof := oneofs.ByName("greater_than")
+	if lessThanOneof == nil || greaterThanOneof == nil {
+		return false
+	}
+	lessThanFieldDescriptor := rules.WhichOneof(lessThanOneof)
+	greaterThanFieldDescriptor := rules.WhichOneof(greaterThanOneof)
+	if lessThanFieldDescriptor == nil ||
+		greaterThanFieldDescriptor == nil ||
+		lessThanFieldDescriptor.Kind() == protoreflect.BoolKind ||
+		greaterThanFieldDescriptor.Kind() == protoreflect.BoolKind {
+		return false
+	}
+	return compareValues(
+		lessThanFieldDescriptor,
+		rules.Get(greaterThanFieldDescriptor),
+		rules.Get(lessThanFieldDescriptor),
+	) > 0
+}
+
+func formatRange(rules protoreflect.Message) string {
+	var bounds []string
+	for _, oneofName := range []protoreflect.Name{"greater_than", "less_than"} {
+		oneof := rules.Descriptor().Oneofs().ByName(oneofName)
+		if oneof == nil {
+			continue
+		}
+		if fieldDescriptor := rules.WhichOneof(oneof); fieldDescriptor != nil {
+			bounds = append(bounds, fmt.Sprintf("%s = %s", fieldDescriptor.Name(), formatValue(fieldDescriptor, rules.Get(fieldDescriptor))))
+		}
+	}
+	if len(bounds) == 0 {
+		return "unbounded"
+	}
+	return "[" + strings.Join(bounds, ", ") + "]"
+}
+
+func formatBound(ruleName string, fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	return fmt.Sprintf("%s.%s = %s", ruleName, fieldDescriptor.Name(), formatValue(fieldDescriptor, value))
+}
+
+func listValues(list protoreflect.List) []protoreflect.Value {
+	values := make([]protoreflect.Value, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		values[i] = list.Get(i)
+	}
+	return values
+}
+
+// listDifference returns the values in list that are not in otherList.
+func listDifference(list protoreflect.List, otherList protoreflect.List) []protoreflect.Value {
+	if list.Len() == 0 {
+		return nil
+	}
+	// The lists are always of the same scalar or message type, so the keys are comparable.
+	otherKeys := make(map[string]struct{}, otherList.Len())
+	for i := 0; i < otherList.Len(); i++ {
+		otherKeys[valueKey(otherList.Get(i))] = struct{}{}
+	}
+	var difference []protoreflect.Value
+	for i := 0; i < list.Len(); i++ {
+		if _, ok := otherKeys[valueKey(list.Get(i))]; !ok {
+			difference = append(difference, list.Get(i))
+		}
+	}
+	return difference
+}
+
+func appendPath(path []int32, elements ...int32) []int32 {
+	return append(slices.Clone(path), elements...)
+}
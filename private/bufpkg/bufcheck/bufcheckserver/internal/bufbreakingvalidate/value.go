@@ -0,0 +1,133 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufbreakingvalidate
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	durationFullName  = (&durationpb.Duration{}).ProtoReflect().Descriptor().FullName()
+	timestampFullName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
+)
+
+// compareValues compares two values of the given numeric, Duration or Timestamp field.
+//
+// Returns 0 for values of other types.
+func compareValues(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value, otherValue protoreflect.Value) int {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		return cmp.Compare(value.Int(), otherValue.Int())
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return cmp.Compare(value.Uint(), otherValue.Uint())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return cmp.Compare(value.Float(), otherValue.Float())
+	case protoreflect.MessageKind:
+		seconds, nanos, ok := getSecondsAndNanos(value.Message())
+		if !ok {
+			return 0
+		}
+		otherSeconds, otherNanos, ok := getSecondsAndNanos(otherValue.Message())
+		if !ok {
+			return 0
+		}
+		if comparison := cmp.Compare(seconds, otherSeconds); comparison != 0 {
+			return comparison
+		}
+		return cmp.Compare(nanos, otherNanos)
+	default:
+		return 0
+	}
+}
+
+// valueKey returns a key for the value that can be used for equality.
+func valueKey(value protoreflect.Value) string {
+	switch typedValue := value.Interface().(type) {
+	case []byte:
+		return string(typedValue)
+	case protoreflect.Message:
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(typedValue.Interface())
+		if err != nil {
+			// Will never happen for the well-known types used in rules.
+			return string(typedValue.Descriptor().FullName())
+		}
+		return string(data)
+	case protoreflect.List:
+		keys := make([]string, typedValue.Len())
+		for i := 0; i < typedValue.Len(); i++ {
+			keys[i] = valueKey(typedValue.Get(i))
+		}
+		return strings.Join(keys, "\x00")
+	default:
+		return fmt.Sprint(typedValue)
+	}
+}
+
+func formatValues(fieldDescriptor protoreflect.FieldDescriptor, values []protoreflect.Value) string {
+	formattedValues := make([]string, len(values))
+	for i, value := range values {
+		formattedValues[i] = formatSingularValue(fieldDescriptor, value)
+	}
+	return strings.Join(formattedValues, ", ")
+}
+
+func formatValue(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	if fieldDescriptor.IsList() {
+		return "[" + formatValues(fieldDescriptor, listValues(value.List())) + "]"
+	}
+	return formatSingularValue(fieldDescriptor, value)
+}
+
+func formatSingularValue(fieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) string {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.StringKind:
+		return strconv.Quote(value.String())
+	case protoreflect.BytesKind:
+		return fmt.Sprintf("%q", value.Bytes())
+	case protoreflect.MessageKind:
+		message := value.Message()
+		seconds, nanos, ok := getSecondsAndNanos(message)
+		if !ok {
+			return string(message.Descriptor().FullName())
+		}
+		if message.Descriptor().FullName() == timestampFullName {
+			return time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano)
+		}
+		return (time.Duration(seconds)*time.Second + time.Duration(nanos)).String()
+	default:
+		return fmt.Sprint(value.Interface())
+	}
+}
+
+func getSecondsAndNanos(message protoreflect.Message) (int64, int64, bool) {
+	fullName := message.Descriptor().FullName()
+	if fullName != durationFullName && fullName != timestampFullName {
+		return 0, 0, false
+	}
+	fields := message.Descriptor().Fields()
+	return message.Get(fields.ByName("seconds")).Int(), message.Get(fields.ByName("nanos")).Int(), true
+}
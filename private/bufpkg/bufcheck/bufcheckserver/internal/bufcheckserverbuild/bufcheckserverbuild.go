@@ -51,6 +51,20 @@ var (
 		Type:    check.RuleTypeBreaking,
 		Handler: bufcheckserverhandle.HandleBreakingEnumValueNoDelete,
 	}
+	// BreakingEnumValueNoDeleteUnlessDeprecatedRuleSpecBuilder is a rule spec builder.
+	BreakingEnumValueNoDeleteUnlessDeprecatedRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED",
+		Purpose: "Checks that enum values are not deleted from a given enum unless they were deprecated.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingEnumValueNoDeleteUnlessDeprecated,
+	}
+	// BreakingEnumValueNoDeleteUnlessDeprecatedAndReservedRuleSpecBuilder is a rule spec builder.
+	BreakingEnumValueNoDeleteUnlessDeprecatedAndReservedRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "ENUM_VALUE_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED",
+		Purpose: "Checks that enum values are not deleted from a given enum unless they were deprecated and the number and name are reserved.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingEnumValueNoDeleteUnlessDeprecatedAndReserved,
+	}
 	// BreakingEnumValueNoDeleteUnlessNameReservedRuleSpecBuilder is a rule spec builder.
 	BreakingEnumValueNoDeleteUnlessNameReservedRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
 		ID:      "ENUM_VALUE_NO_DELETE_UNLESS_NAME_RESERVED",
@@ -93,6 +107,20 @@ var (
 		Type:    check.RuleTypeBreaking,
 		Handler: bufcheckserverhandle.HandleBreakingFieldNoDelete,
 	}
+	// BreakingFieldNoDeleteUnlessDeprecatedRuleSpecBuilder is a rule spec builder.
+	BreakingFieldNoDeleteUnlessDeprecatedRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "FIELD_NO_DELETE_UNLESS_DEPRECATED",
+		Purpose: "Checks that fields are not deleted from a given message unless they were deprecated.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingFieldNoDeleteUnlessDeprecated,
+	}
+	// BreakingFieldNoDeleteUnlessDeprecatedAndReservedRuleSpecBuilder is a rule spec builder.
+	BreakingFieldNoDeleteUnlessDeprecatedAndReservedRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "FIELD_NO_DELETE_UNLESS_DEPRECATED_AND_RESERVED",
+		Purpose: "Checks that fields are not deleted from a given message unless they were deprecated and the number and name are reserved.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingFieldNoDeleteUnlessDeprecatedAndReserved,
+	}
 	// BreakingFieldNoDeleteUnlessNameReservedRuleSpecBuilder is a rule spec builder.
 	BreakingFieldNoDeleteUnlessNameReservedRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
 		ID:      "FIELD_NO_DELETE_UNLESS_NAME_RESERVED",
@@ -107,6 +135,27 @@ var (
 		Type:    check.RuleTypeBreaking,
 		Handler: bufcheckserverhandle.HandleBreakingFieldNoDeleteUnlessNumberReserved,
 	}
+	// BreakingFieldProtovalidateNoAddCELRuleSpecBuilder is a rule spec builder.
+	BreakingFieldProtovalidateNoAddCELRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "FIELD_PROTOVALIDATE_NO_ADD_CEL",
+		Purpose: "Checks that fields do not have protovalidate CEL constraints added or changed.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingFieldProtovalidateNoAddCEL,
+	}
+	// BreakingFieldProtovalidateNoAddRequiredRuleSpecBuilder is a rule spec builder.
+	BreakingFieldProtovalidateNoAddRequiredRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "FIELD_PROTOVALIDATE_NO_ADD_REQUIRED",
+		Purpose: "Checks that fields do not become required with protovalidate.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingFieldProtovalidateNoAddRequired,
+	}
+	// BreakingFieldProtovalidateNoNarrowedRulesRuleSpecBuilder is a rule spec builder.
+	BreakingFieldProtovalidateNoNarrowedRulesRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "FIELD_PROTOVALIDATE_NO_NARROWED_RULES",
+		Purpose: "Checks that fields do not have protovalidate rules that narrow the accepted values.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingFieldProtovalidateNoNarrowedRules,
+	}
 	// BreakingFieldSameCardinalityRuleSpecBuilder is a rule spec builder.
 	BreakingFieldSameCardinalityRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
 		ID:      "FIELD_SAME_CARDINALITY",
@@ -413,6 +462,13 @@ var (
 		Type:    check.RuleTypeBreaking,
 		Handler: bufcheckserverhandle.HandleBreakingMessageNoDelete,
 	}
+	// BreakingMessageNoDeleteUnlessDeprecatedRuleSpecBuilder is a rule spec builder.
+	BreakingMessageNoDeleteUnlessDeprecatedRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "MESSAGE_NO_DELETE_UNLESS_DEPRECATED",
+		Purpose: "Checks that messages are not deleted from a given file unless they were deprecated.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingMessageNoDeleteUnlessDeprecated,
+	}
 	// BreakingMessageNoRemoveStandardDescriptorAccessorRuleSpecBuilder is a rule spec builder.
 	BreakingMessageNoRemoveStandardDescriptorAccessorRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
 		ID:      "MESSAGE_NO_REMOVE_STANDARD_DESCRIPTOR_ACCESSOR",
@@ -442,6 +498,20 @@ var (
 			},
 		),
 	}
+	// BreakingMessageProtovalidateNoAddCELRuleSpecBuilder is a rule spec builder.
+	BreakingMessageProtovalidateNoAddCELRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "MESSAGE_PROTOVALIDATE_NO_ADD_CEL",
+		Purpose: "Checks that messages do not have protovalidate CEL constraints added or changed.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingMessageProtovalidateNoAddCEL,
+	}
+	// BreakingMessageProtovalidateNoRemoveDisabledRuleSpecBuilder is a rule spec builder.
+	BreakingMessageProtovalidateNoRemoveDisabledRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "MESSAGE_PROTOVALIDATE_NO_REMOVE_DISABLED",
+		Purpose: "Checks that messages do not have protovalidate validation re-enabled by removing the disabled option.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingMessageProtovalidateNoRemoveDisabled,
+	}
 	// BreakingMessageSameRequiredFieldsRuleSpecBuilder is a rule spec builder.
 	BreakingMessageSameRequiredFieldsRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
 		ID:      "MESSAGE_SAME_REQUIRED_FIELDS",
@@ -491,6 +561,13 @@ var (
 		Type:    check.RuleTypeBreaking,
 		Handler: bufcheckserverhandle.HandleBreakingPackageServiceNoDelete,
 	}
+	// BreakingOneofProtovalidateNoAddRequiredRuleSpecBuilder is a rule spec builder.
+	BreakingOneofProtovalidateNoAddRequiredRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "ONEOF_PROTOVALIDATE_NO_ADD_REQUIRED",
+		Purpose: "Checks that oneofs do not become required with protovalidate.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingOneofProtovalidateNoAddRequired,
+	}
 	// BreakingReservedEnumNoDeleteRuleSpecBuilder is a rule spec builder.
 	BreakingReservedEnumNoDeleteRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
 		ID:      "RESERVED_ENUM_NO_DELETE",
@@ -512,6 +589,13 @@ var (
 		Type:    check.RuleTypeBreaking,
 		Handler: bufcheckserverhandle.HandleBreakingRPCNoDelete,
 	}
+	// BreakingRPCNoDeleteUnlessDeprecatedRuleSpecBuilder is a rule spec builder.
+	BreakingRPCNoDeleteUnlessDeprecatedRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "RPC_NO_DELETE_UNLESS_DEPRECATED",
+		Purpose: "Checks that rpcs are not deleted from a given service unless they were deprecated.",
+		Type:    check.RuleTypeBreaking,
+		Handler: bufcheckserverhandle.HandleBreakingRPCNoDeleteUnlessDeprecated,
+	}
 	// BreakingRPCSameClientStreamingRuleSpecBuilder is a rule spec builder.
 	BreakingRPCSameClientStreamingRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
 		ID:      "RPC_SAME_CLIENT_STREAMING",
@@ -652,6 +736,20 @@ var (
 		Type:    check.RuleTypeLint,
 		Handler: bufcheckserverhandle.HandleLintEnumZeroValueSuffix,
 	}
+	// LintFeatureNoLegacyValueRuleSpecBuilder is a rule spec builder.
+	LintFeatureNoLegacyValueRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "FEATURE_NO_LEGACY_VALUE",
+		Purpose: "Checks that features are not set to values that only exist for compatibility with proto2 and proto3.",
+		Type:    check.RuleTypeLint,
+		Handler: bufcheckserverhandle.HandleLintFeatureNoLegacyValue,
+	}
+	// LintFeatureNoRedundantOverrideRuleSpecBuilder is a rule spec builder.
+	LintFeatureNoRedundantOverrideRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
+		ID:      "FEATURE_NO_REDUNDANT_OVERRIDE",
+		Purpose: "Checks that features are not set to the value they would already inherit.",
+		Type:    check.RuleTypeLint,
+		Handler: bufcheckserverhandle.HandleLintFeatureNoRedundantOverride,
+	}
 	// LintFieldLowerSnakeCaseRuleSpecBuilder is a rule spec builder.
 	LintFieldLowerSnakeCaseRuleSpecBuilder = &bufcheckserverutil.RuleSpecBuilder{
 		ID:      "FIELD_LOWER_SNAKE_CASE",
@@ -906,6 +1004,11 @@ var (
 		ID:      "WIRE_JSON",
 		Purpose: "Checks that there are no wire breaking changes for the binary or JSON encodings.",
 	}
+	// ProtovalidateConstraintsCategorySpec is a category spec.
+	ProtovalidateConstraintsCategorySpec = &check.CategorySpec{
+		ID:      "PROTOVALIDATE_CONSTRAINTS",
+		Purpose: "Checks that protovalidate constraints are not narrowed to reject previously valid values.",
+	}
 
 	// BasicCategorySpec is a category spec.
 	BasicCategorySpec = &check.CategorySpec{
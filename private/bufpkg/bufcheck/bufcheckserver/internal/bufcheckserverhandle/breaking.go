@@ -16,7 +16,6 @@ package bufcheckserverhandle
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"slices"
 	"strconv"
@@ -168,6 +167,37 @@ func handleBreakingMessageNoDelete(
 	request bufcheckserverutil.Request,
 	file bufprotosource.File,
 	previousFile bufprotosource.File,
+) error {
+	return checkMessageNoDeleteWithRules(
+		responseWriter,
+		previousFile,
+		file,
+		false,
+	)
+}
+
+// HandleBreakingMessageNoDeleteUnlessDeprecated is a check function.
+var HandleBreakingMessageNoDeleteUnlessDeprecated = bufcheckserverutil.NewBreakingFilePairRuleHandler(handleBreakingMessageNoDeleteUnlessDeprecated)
+
+func handleBreakingMessageNoDeleteUnlessDeprecated(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	file bufprotosource.File,
+	previousFile bufprotosource.File,
+) error {
+	return checkMessageNoDeleteWithRules(
+		responseWriter,
+		previousFile,
+		file,
+		true,
+	)
+}
+
+func checkMessageNoDeleteWithRules(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	previousFile bufprotosource.File,
+	file bufprotosource.File,
+	allowIfDeprecated bool,
 ) error {
 	previousNestedNameToMessage, err := bufprotosource.NestedNameToMessage(previousFile)
 	if err != nil {
@@ -179,13 +209,21 @@ func handleBreakingMessageNoDelete(
 	}
 	for previousNestedName, previousMessage := range previousNestedNameToMessage {
 		if _, ok := nestedNameToMessage[previousNestedName]; !ok {
+			if allowIfDeprecated && isDeletedMessageDeprecated(previousMessage, nestedNameToMessage) {
+				continue
+			}
+			suffix := ""
+			if allowIfDeprecated {
+				suffix = getDeletedWithoutSuffix([]string{"first marking it as deprecated"})
+			}
 			descriptor, location := getDescriptorAndLocationForDeletedMessage(file, nestedNameToMessage, previousNestedName)
 			if location != nil {
 				responseWriter.AddProtosourceAnnotation(
 					location,
 					previousMessage.Location(),
-					`Previously present message %q was deleted from file.`,
+					`Previously present message %q was deleted from file%s.`,
 					previousNestedName,
+					suffix,
 				)
 			} else {
 				responseWriter.AddAnnotation(
@@ -195,8 +233,9 @@ func handleBreakingMessageNoDelete(
 						previousMessage.Location().SourcePath(),
 					),
 					check.WithMessagef(
-						`Previously present message %q was deleted from file.`,
+						`Previously present message %q was deleted from file%s.`,
 						previousNestedName,
+						suffix,
 					),
 				)
 			}
@@ -205,6 +244,30 @@ func handleBreakingMessageNoDelete(
 	return nil
 }
 
+// isDeletedMessageDeprecated returns true if the deleted message is deprecated, or if
+// it was deleted along with a deprecated parent message.
+//
+// A deprecated parent does not cover a nested message that was deleted on its own, as
+// the parent is still present.
+func isDeletedMessageDeprecated(
+	message bufprotosource.Message,
+	nestedNameToMessage map[string]bufprotosource.Message,
+) bool {
+	for ; message != nil; message = message.Parent() {
+		if message.Deprecated() {
+			return true
+		}
+		parent := message.Parent()
+		if parent == nil {
+			return false
+		}
+		if _, ok := nestedNameToMessage[parent.NestedName()]; ok {
+			return false
+		}
+	}
+	return false
+}
+
 // HandleBreakingServiceNoDelete is a check function.
 var HandleBreakingServiceNoDelete = bufcheckserverutil.NewBreakingFilePairRuleHandler(handleBreakingServiceNoDelete)
 
@@ -294,6 +357,7 @@ func handleBreakingEnumValueNoDelete(
 		enum,
 		false,
 		false,
+		false,
 	)
 }
 
@@ -312,6 +376,7 @@ func handleBreakingEnumValueNoDeleteUnlessNameReserved(
 		enum,
 		false,
 		true,
+		false,
 	)
 }
 
@@ -330,6 +395,45 @@ func handleBreakingEnumValueNoDeleteUnlessNumberReserved(
 		enum,
 		true,
 		false,
+		false,
+	)
+}
+
+// HandleBreakingEnumValueNoDeleteUnlessDeprecated is a check function.
+var HandleBreakingEnumValueNoDeleteUnlessDeprecated = bufcheckserverutil.NewBreakingEnumPairRuleHandler(handleBreakingEnumValueNoDeleteUnlessDeprecated)
+
+func handleBreakingEnumValueNoDeleteUnlessDeprecated(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	enum bufprotosource.Enum,
+	previousEnum bufprotosource.Enum,
+) error {
+	return checkEnumValueNoDeleteWithRules(
+		responseWriter,
+		previousEnum,
+		enum,
+		false,
+		false,
+		true,
+	)
+}
+
+// HandleBreakingEnumValueNoDeleteUnlessDeprecatedAndReserved is a check function.
+var HandleBreakingEnumValueNoDeleteUnlessDeprecatedAndReserved = bufcheckserverutil.NewBreakingEnumPairRuleHandler(handleBreakingEnumValueNoDeleteUnlessDeprecatedAndReserved)
+
+func handleBreakingEnumValueNoDeleteUnlessDeprecatedAndReserved(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	enum bufprotosource.Enum,
+	previousEnum bufprotosource.Enum,
+) error {
+	return checkEnumValueNoDeleteWithRules(
+		responseWriter,
+		previousEnum,
+		enum,
+		true,
+		true,
+		true,
 	)
 }
 
@@ -339,6 +443,7 @@ func checkEnumValueNoDeleteWithRules(
 	enum bufprotosource.Enum,
 	allowIfNumberReserved bool,
 	allowIfNameReserved bool,
+	allowIfDeprecated bool,
 ) error {
 	previousNumberToNameToEnumValue, err := bufprotosource.NumberToNameToEnumValue(previousEnum)
 	if err != nil {
@@ -350,34 +455,33 @@ func checkEnumValueNoDeleteWithRules(
 	}
 	for previousNumber, previousNameToEnumValue := range previousNumberToNameToEnumValue {
 		if _, ok := numberToNameToEnumValue[previousNumber]; !ok {
-			if !isDeletedEnumValueAllowedWithRules(
-				previousNumber,
-				previousNameToEnumValue,
-				enum,
-				allowIfNumberReserved,
-				allowIfNameReserved,
-			) {
-				suffix := ""
-				if allowIfNumberReserved && allowIfNameReserved {
-					return errors.New("both allowIfNumberReserved and allowIfNameReserved set")
-				}
-				if allowIfNumberReserved {
-					suffix = fmt.Sprintf(` without reserving the number "%d"`, previousNumber)
-				}
-				if allowIfNameReserved {
-					nameSuffix := ""
-					if len(previousNameToEnumValue) > 1 {
-						nameSuffix = "s"
-					}
-					suffix = fmt.Sprintf(` without reserving the name%s %s`, nameSuffix, stringutil.JoinSliceQuoted(getSortedEnumValueNames(previousNameToEnumValue), ", "))
+			// A deletion is allowed only if every condition of the rule is met,
+			// so we collect the conditions that were not met to describe them.
+			var unmetConditions []string
+			if allowIfDeprecated && !isEnumValueDeprecated(previousNameToEnumValue) {
+				unmetConditions = append(unmetConditions, "first marking it as deprecated")
+			}
+			if allowIfNumberReserved && !bufprotosource.NumberInReservedRanges(previousNumber, enum.ReservedTagRanges()...) {
+				unmetConditions = append(unmetConditions, fmt.Sprintf(`reserving the number "%d"`, previousNumber))
+			}
+			if allowIfNameReserved && !areEnumValueNamesReserved(previousNameToEnumValue, enum) {
+				nameSuffix := ""
+				if len(previousNameToEnumValue) > 1 {
+					nameSuffix = "s"
 				}
+				unmetConditions = append(
+					unmetConditions,
+					fmt.Sprintf(`reserving the name%s %s`, nameSuffix, stringutil.JoinSliceQuoted(getSortedEnumValueNames(previousNameToEnumValue), ", ")),
+				)
+			}
+			if len(unmetConditions) > 0 || !(allowIfNumberReserved || allowIfNameReserved || allowIfDeprecated) {
 				responseWriter.AddProtosourceAnnotation(
 					enum.Location(),
 					previousEnum.Location(),
 					`Previously present enum value "%d" on enum %q was deleted%s.`,
 					previousNumber,
 					enum.Name(),
-					suffix,
+					getDeletedWithoutSuffix(unmetConditions),
 				)
 			}
 		}
@@ -385,26 +489,27 @@ func checkEnumValueNoDeleteWithRules(
 	return nil
 }
 
-func isDeletedEnumValueAllowedWithRules(
-	previousNumber int,
+func isEnumValueDeprecated(previousNameToEnumValue map[string]bufprotosource.EnumValue) bool {
+	// if true for all names, then ok
+	for _, previousEnumValue := range previousNameToEnumValue {
+		if !previousEnumValue.Deprecated() {
+			return false
+		}
+	}
+	return true
+}
+
+func areEnumValueNamesReserved(
 	previousNameToEnumValue map[string]bufprotosource.EnumValue,
 	enum bufprotosource.Enum,
-	allowIfNumberReserved bool,
-	allowIfNameReserved bool,
 ) bool {
-	if allowIfNumberReserved {
-		return bufprotosource.NumberInReservedRanges(previousNumber, enum.ReservedTagRanges()...)
-	}
-	if allowIfNameReserved {
-		// if true for all names, then ok
-		for previousName := range previousNameToEnumValue {
-			if !bufprotosource.NameInReservedNames(previousName, enum.ReservedNames()...) {
-				return false
-			}
+	// if true for all names, then ok
+	for previousName := range previousNameToEnumValue {
+		if !bufprotosource.NameInReservedNames(previousName, enum.ReservedNames()...) {
+			return false
 		}
-		return true
 	}
-	return false
+	return true
 }
 
 // HandleBreakingExtensionMessageNoDelete is a check function.
@@ -441,6 +546,7 @@ func handleBreakingFieldNoDelete(
 		message,
 		false,
 		false,
+		false,
 	)
 }
 
@@ -459,6 +565,7 @@ func handleBreakingFieldNoDeleteUnlessNameReserved(
 		message,
 		false,
 		true,
+		false,
 	)
 }
 
@@ -477,6 +584,45 @@ func handleBreakingFieldNoDeleteUnlessNumberReserved(
 		message,
 		true,
 		false,
+		false,
+	)
+}
+
+// HandleBreakingFieldNoDeleteUnlessDeprecated is a check function.
+var HandleBreakingFieldNoDeleteUnlessDeprecated = bufcheckserverutil.NewBreakingMessagePairRuleHandler(handleBreakingFieldNoDeleteUnlessDeprecated)
+
+func handleBreakingFieldNoDeleteUnlessDeprecated(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	message bufprotosource.Message,
+	previousMessage bufprotosource.Message,
+) error {
+	return checkFieldNoDeleteWithRules(
+		responseWriter,
+		previousMessage,
+		message,
+		false,
+		false,
+		true,
+	)
+}
+
+// HandleBreakingFieldNoDeleteUnlessDeprecatedAndReserved is a check function.
+var HandleBreakingFieldNoDeleteUnlessDeprecatedAndReserved = bufcheckserverutil.NewBreakingMessagePairRuleHandler(handleBreakingFieldNoDeleteUnlessDeprecatedAndReserved)
+
+func handleBreakingFieldNoDeleteUnlessDeprecatedAndReserved(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	message bufprotosource.Message,
+	previousMessage bufprotosource.Message,
+) error {
+	return checkFieldNoDeleteWithRules(
+		responseWriter,
+		previousMessage,
+		message,
+		true,
+		true,
+		true,
 	)
 }
 
@@ -486,6 +632,7 @@ func checkFieldNoDeleteWithRules(
 	message bufprotosource.Message,
 	allowIfNumberReserved bool,
 	allowIfNameReserved bool,
+	allowIfDeprecated bool,
 ) error {
 	previousNumberToField, err := bufprotosource.NumberToMessageField(previousMessage)
 	if err != nil {
@@ -497,17 +644,19 @@ func checkFieldNoDeleteWithRules(
 	}
 	for previousNumber, previousField := range previousNumberToField {
 		if _, ok := numberToField[previousNumber]; !ok {
-			if !isDeletedFieldAllowedWithRules(previousField, message, allowIfNumberReserved, allowIfNameReserved) {
-				suffix := ""
-				if allowIfNumberReserved && allowIfNameReserved {
-					return errors.New("both allowIfNumberReserved and allowIfNameReserved set")
-				}
-				if allowIfNumberReserved {
-					suffix = fmt.Sprintf(` without reserving the number "%d"`, previousField.Number())
-				}
-				if allowIfNameReserved {
-					suffix = fmt.Sprintf(` without reserving the name %q`, previousField.Name())
-				}
+			// A deletion is allowed only if every condition of the rule is met,
+			// so we collect the conditions that were not met to describe them.
+			var unmetConditions []string
+			if allowIfDeprecated && !previousField.Deprecated() {
+				unmetConditions = append(unmetConditions, "first marking it as deprecated")
+			}
+			if allowIfNumberReserved && !bufprotosource.NumberInReservedRanges(previousField.Number(), message.ReservedTagRanges()...) {
+				unmetConditions = append(unmetConditions, fmt.Sprintf(`reserving the number "%d"`, previousField.Number()))
+			}
+			if allowIfNameReserved && !bufprotosource.NameInReservedNames(previousField.Name(), message.ReservedNames()...) {
+				unmetConditions = append(unmetConditions, fmt.Sprintf(`reserving the name %q`, previousField.Name()))
+			}
+			if len(unmetConditions) > 0 || !(allowIfNumberReserved || allowIfNameReserved || allowIfDeprecated) {
 				description := fieldDescription(previousField)
 				// Description will start with capital letter; lower-case it
 				// to better fit in this message.
@@ -517,7 +666,7 @@ func checkFieldNoDeleteWithRules(
 					previousMessage.Location(),
 					`Previously present %s was deleted%s.`,
 					description,
-					suffix,
+					getDeletedWithoutSuffix(unmetConditions),
 				)
 			}
 		}
@@ -525,14 +674,13 @@ func checkFieldNoDeleteWithRules(
 	return nil
 }
 
-func isDeletedFieldAllowedWithRules(
-	previousField bufprotosource.Field,
-	message bufprotosource.Message,
-	allowIfNumberReserved bool,
-	allowIfNameReserved bool,
-) bool {
-	return (allowIfNumberReserved && bufprotosource.NumberInReservedRanges(previousField.Number(), message.ReservedTagRanges()...)) ||
-		(allowIfNameReserved && bufprotosource.NameInReservedNames(previousField.Name(), message.ReservedNames()...))
+// getDeletedWithoutSuffix returns the suffix for a deletion message that
+// lists the conditions that were not met, or an empty string if there were none.
+func getDeletedWithoutSuffix(unmetConditions []string) string {
+	if len(unmetConditions) == 0 {
+		return ""
+	}
+	return " without " + strings.Join(unmetConditions, " and ")
 }
 
 // HandleBreakingFieldSameCardinality is a check function.
@@ -1465,6 +1613,37 @@ func handleBreakingRPCNoDelete(
 	request bufcheckserverutil.Request,
 	service bufprotosource.Service,
 	previousService bufprotosource.Service,
+) error {
+	return checkRPCNoDeleteWithRules(
+		responseWriter,
+		previousService,
+		service,
+		false,
+	)
+}
+
+// HandleBreakingRPCNoDeleteUnlessDeprecated is a check function.
+var HandleBreakingRPCNoDeleteUnlessDeprecated = bufcheckserverutil.NewBreakingServicePairRuleHandler(handleBreakingRPCNoDeleteUnlessDeprecated)
+
+func handleBreakingRPCNoDeleteUnlessDeprecated(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	service bufprotosource.Service,
+	previousService bufprotosource.Service,
+) error {
+	return checkRPCNoDeleteWithRules(
+		responseWriter,
+		previousService,
+		service,
+		true,
+	)
+}
+
+func checkRPCNoDeleteWithRules(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	previousService bufprotosource.Service,
+	service bufprotosource.Service,
+	allowIfDeprecated bool,
 ) error {
 	previousNameToMethod, err := bufprotosource.NameToMethod(previousService)
 	if err != nil {
@@ -1474,14 +1653,22 @@ func handleBreakingRPCNoDelete(
 	if err != nil {
 		return err
 	}
-	for previousName := range previousNameToMethod {
+	for previousName, previousMethod := range previousNameToMethod {
 		if _, ok := nameToMethod[previousName]; !ok {
+			if allowIfDeprecated && previousMethod.Deprecated() {
+				continue
+			}
+			suffix := ""
+			if allowIfDeprecated {
+				suffix = getDeletedWithoutSuffix([]string{"first marking it as deprecated"})
+			}
 			responseWriter.AddProtosourceAnnotation(
 				service.Location(),
 				previousService.Location(),
-				`Previously present RPC %q on service %q was deleted.`,
+				`Previously present RPC %q on service %q was deleted%s.`,
 				previousName,
 				service.Name(),
+				suffix,
 			)
 		}
 	}
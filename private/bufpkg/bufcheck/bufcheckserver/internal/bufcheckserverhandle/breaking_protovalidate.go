@@ -0,0 +1,249 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcheckserverhandle
+
+import (
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufcheckserver/internal/bufbreakingvalidate"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufcheckserver/internal/bufcheckserverutil"
+	"github.com/bufbuild/buf/private/bufpkg/bufprotosource"
+	"github.com/bufbuild/protovalidate-go/resolver"
+)
+
+const (
+	// https://buf.build/bufbuild/protovalidate/docs/main:buf.validate#buf.validate.FieldConstraints
+	requiredFieldNumberInFieldConstraints = 25
+	// https://buf.build/bufbuild/protovalidate/docs/main:buf.validate#buf.validate.MessageConstraints
+	disabledFieldNumberInMessageConstraints = 1
+	// https://buf.build/bufbuild/protovalidate/docs/main:buf.validate#buf.validate.OneofConstraints
+	requiredFieldNumberInOneofConstraints = 1
+)
+
+// HandleBreakingFieldProtovalidateNoAddRequired is a check function.
+var HandleBreakingFieldProtovalidateNoAddRequired = bufcheckserverutil.NewBreakingFieldPairRuleHandler(handleBreakingFieldProtovalidateNoAddRequired)
+
+func handleBreakingFieldProtovalidateNoAddRequired(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	field bufprotosource.Field,
+	previousField bufprotosource.Field,
+) error {
+	fieldConstraints, previousFieldConstraints, err := getFieldConstraintsPair(field, previousField)
+	if err != nil {
+		return err
+	}
+	if bufbreakingvalidate.FieldRequiredAdded(fieldConstraints, previousFieldConstraints) {
+		responseWriter.AddProtosourceAnnotation(
+			withBackupLocation(field.OptionExtensionLocation(validate.E_Field, requiredFieldNumberInFieldConstraints), field.Location()),
+			previousField.Location(),
+			`%s became required with (buf.validate.field).required.`,
+			fieldDescription(field),
+		)
+	}
+	return nil
+}
+
+// HandleBreakingFieldProtovalidateNoNarrowedRules is a check function.
+var HandleBreakingFieldProtovalidateNoNarrowedRules = bufcheckserverutil.NewBreakingFieldPairRuleHandler(handleBreakingFieldProtovalidateNoNarrowedRules)
+
+func handleBreakingFieldProtovalidateNoNarrowedRules(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	field bufprotosource.Field,
+	previousField bufprotosource.Field,
+) error {
+	fieldConstraints, previousFieldConstraints, err := getFieldConstraintsPair(field, previousField)
+	if err != nil {
+		return err
+	}
+	addFieldNarrowings(
+		responseWriter,
+		field,
+		previousField,
+		bufbreakingvalidate.FieldRulesNarrowings(fieldConstraints, previousFieldConstraints),
+	)
+	return nil
+}
+
+// HandleBreakingFieldProtovalidateNoAddCEL is a check function.
+var HandleBreakingFieldProtovalidateNoAddCEL = bufcheckserverutil.NewBreakingFieldPairRuleHandler(handleBreakingFieldProtovalidateNoAddCEL)
+
+func handleBreakingFieldProtovalidateNoAddCEL(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	field bufprotosource.Field,
+	previousField bufprotosource.Field,
+) error {
+	fieldConstraints, previousFieldConstraints, err := getFieldConstraintsPair(field, previousField)
+	if err != nil {
+		return err
+	}
+	addFieldNarrowings(
+		responseWriter,
+		field,
+		previousField,
+		bufbreakingvalidate.FieldCELNarrowings(fieldConstraints, previousFieldConstraints),
+	)
+	return nil
+}
+
+// HandleBreakingMessageProtovalidateNoAddCEL is a check function.
+var HandleBreakingMessageProtovalidateNoAddCEL = bufcheckserverutil.NewBreakingMessagePairRuleHandler(handleBreakingMessageProtovalidateNoAddCEL)
+
+func handleBreakingMessageProtovalidateNoAddCEL(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	message bufprotosource.Message,
+	previousMessage bufprotosource.Message,
+) error {
+	messageConstraints, previousMessageConstraints, err := getMessageConstraintsPair(message, previousMessage)
+	if err != nil {
+		return err
+	}
+	for _, narrowing := range bufbreakingvalidate.MessageCELNarrowings(messageConstraints, previousMessageConstraints) {
+		responseWriter.AddProtosourceAnnotation(
+			withBackupLocation(message.OptionExtensionLocation(validate.E_Message, narrowing.Path...), message.Location()),
+			previousMessage.Location(),
+			`Message %q has narrowed protovalidate constraints: %s`,
+			message.Name(),
+			narrowing.Description,
+		)
+	}
+	return nil
+}
+
+// HandleBreakingMessageProtovalidateNoRemoveDisabled is a check function.
+var HandleBreakingMessageProtovalidateNoRemoveDisabled = bufcheckserverutil.NewBreakingMessagePairRuleHandler(handleBreakingMessageProtovalidateNoRemoveDisabled)
+
+func handleBreakingMessageProtovalidateNoRemoveDisabled(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	message bufprotosource.Message,
+	previousMessage bufprotosource.Message,
+) error {
+	messageConstraints, previousMessageConstraints, err := getMessageConstraintsPair(message, previousMessage)
+	if err != nil {
+		return err
+	}
+	if bufbreakingvalidate.MessageDisabledRemoved(messageConstraints, previousMessageConstraints) {
+		responseWriter.AddProtosourceAnnotation(
+			message.Location(),
+			withBackupLocation(
+				previousMessage.OptionExtensionLocation(validate.E_Message, disabledFieldNumberInMessageConstraints),
+				previousMessage.Location(),
+			),
+			`Message %q no longer has (buf.validate.message).disabled set, so the protovalidate constraints on the message and its fields are now applied.`,
+			message.Name(),
+		)
+	}
+	return nil
+}
+
+// HandleBreakingOneofProtovalidateNoAddRequired is a check function.
+var HandleBreakingOneofProtovalidateNoAddRequired = bufcheckserverutil.NewBreakingMessagePairRuleHandler(handleBreakingOneofProtovalidateNoAddRequired)
+
+func handleBreakingOneofProtovalidateNoAddRequired(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	request bufcheckserverutil.Request,
+	message bufprotosource.Message,
+	previousMessage bufprotosource.Message,
+) error {
+	previousNameToOneof, err := bufprotosource.NameToMessageOneof(previousMessage)
+	if err != nil {
+		return err
+	}
+	nameToOneof, err := bufprotosource.NameToMessageOneof(message)
+	if err != nil {
+		return err
+	}
+	for previousName, previousOneof := range previousNameToOneof {
+		oneof, ok := nameToOneof[previousName]
+		if !ok {
+			continue
+		}
+		oneofDescriptor, err := oneof.AsDescriptor()
+		if err != nil {
+			return err
+		}
+		previousOneofDescriptor, err := previousOneof.AsDescriptor()
+		if err != nil {
+			return err
+		}
+		if bufbreakingvalidate.OneofRequiredAdded(
+			resolver.DefaultResolver{}.ResolveOneofConstraints(oneofDescriptor),
+			resolver.DefaultResolver{}.ResolveOneofConstraints(previousOneofDescriptor),
+		) {
+			responseWriter.AddProtosourceAnnotation(
+				withBackupLocation(oneof.OptionExtensionLocation(validate.E_Oneof, requiredFieldNumberInOneofConstraints), oneof.Location()),
+				previousOneof.Location(),
+				`Oneof %q on message %q became required with (buf.validate.oneof).required.`,
+				oneof.Name(),
+				message.Name(),
+			)
+		}
+	}
+	return nil
+}
+
+func addFieldNarrowings(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	field bufprotosource.Field,
+	previousField bufprotosource.Field,
+	narrowings []bufbreakingvalidate.Narrowing,
+) {
+	for _, narrowing := range narrowings {
+		responseWriter.AddProtosourceAnnotation(
+			withBackupLocation(field.OptionExtensionLocation(validate.E_Field, narrowing.Path...), field.Location()),
+			previousField.Location(),
+			`%s has narrowed protovalidate constraints: %s`,
+			fieldDescription(field),
+			narrowing.Description,
+		)
+	}
+}
+
+func getFieldConstraintsPair(
+	field bufprotosource.Field,
+	previousField bufprotosource.Field,
+) (*validate.FieldConstraints, *validate.FieldConstraints, error) {
+	fieldDescriptor, err := field.AsDescriptor()
+	if err != nil {
+		return nil, nil, err
+	}
+	previousFieldDescriptor, err := previousField.AsDescriptor()
+	if err != nil {
+		return nil, nil, err
+	}
+	return resolver.DefaultResolver{}.ResolveFieldConstraints(fieldDescriptor),
+		resolver.DefaultResolver{}.ResolveFieldConstraints(previousFieldDescriptor),
+		nil
+}
+
+func getMessageConstraintsPair(
+	message bufprotosource.Message,
+	previousMessage bufprotosource.Message,
+) (*validate.MessageConstraints, *validate.MessageConstraints, error) {
+	messageDescriptor, err := message.AsDescriptor()
+	if err != nil {
+		return nil, nil, err
+	}
+	previousMessageDescriptor, err := previousMessage.AsDescriptor()
+	if err != nil {
+		return nil, nil, err
+	}
+	return resolver.DefaultResolver{}.ResolveMessageConstraints(messageDescriptor),
+		resolver.DefaultResolver{}.ResolveMessageConstraints(previousMessageDescriptor),
+		nil
+}
@@ -341,6 +341,58 @@ func handleLintEnumZeroValueSuffix(
 	return nil
 }
 
+// HandleLintFeatureNoLegacyValue is a handle function.
+var HandleLintFeatureNoLegacyValue = bufcheckserverutil.NewLintFileRuleHandler(handleLintFeatureNoLegacyValue)
+
+func handleLintFeatureNoLegacyValue(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	_ bufcheckserverutil.Request,
+	file bufprotosource.File,
+) error {
+	return forEachExplicitFeature(
+		file,
+		func(explicitFeature *explicitFeature) error {
+			if isLegacyFeatureValue(explicitFeature.featureField, explicitFeature.value) {
+				responseWriter.AddProtosourceAnnotation(
+					explicitFeature.location,
+					nil,
+					`Feature %q on %s should not be set to %s, which only exists for compatibility with proto2 and proto3.`,
+					explicitFeature.featureField.Name(),
+					explicitFeature.elementDescription,
+					explicitFeature.valueName(),
+				)
+			}
+			return nil
+		},
+	)
+}
+
+// HandleLintFeatureNoRedundantOverride is a handle function.
+var HandleLintFeatureNoRedundantOverride = bufcheckserverutil.NewLintFileRuleHandler(handleLintFeatureNoRedundantOverride)
+
+func handleLintFeatureNoRedundantOverride(
+	responseWriter bufcheckserverutil.ResponseWriter,
+	_ bufcheckserverutil.Request,
+	file bufprotosource.File,
+) error {
+	return forEachExplicitFeature(
+		file,
+		func(explicitFeature *explicitFeature) error {
+			if explicitFeature.value == explicitFeature.inheritedValue {
+				responseWriter.AddProtosourceAnnotation(
+					explicitFeature.location,
+					nil,
+					`Feature %q on %s is set to %s, which is the value it already inherits.`,
+					explicitFeature.featureField.Name(),
+					explicitFeature.elementDescription,
+					explicitFeature.valueName(),
+				)
+			}
+			return nil
+		},
+	)
+}
+
 // HandleLintFieldLowerSnakeCase is a handle function.
 var HandleLintFieldLowerSnakeCase = bufcheckserverutil.NewLintFieldRuleHandler(handleLintFieldLowerSnakeCase)
 
@@ -15,12 +15,288 @@
 package bufcheckserverhandle
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufprotosource"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/protocompile/protoutil"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+// explicitFeature is a standard feature that is explicitly set in the
+// options of an element in a file that uses Editions.
+type explicitFeature struct {
+	// featureField is the field of google.protobuf.FeatureSet that is set.
+	featureField protoreflect.FieldDescriptor
+	// value is the explicitly set value.
+	value protoreflect.EnumNumber
+	// inheritedValue is the value that the element would have if the
+	// feature was not set, that is the value resolved for the parent
+	// element, or the edition default for files.
+	inheritedValue protoreflect.EnumNumber
+	// elementDescription is a description of the element, such as
+	// `message "foo.v1.Foo"`.
+	elementDescription string
+	// location is the location of the feature.
+	location bufprotosource.Location
+}
+
+// valueName returns the name of the explicitly set value.
+func (e *explicitFeature) valueName() string {
+	return featureEnumValueName(e.featureField, e.value)
+}
+
+// forEachExplicitFeature calls f for every standard feature explicitly set
+// on the file, or on any message, field, oneof, or enum within the file.
+//
+// Custom features, that is extensions of google.protobuf.FeatureSet, are not
+// included. Files that do not use Editions never have explicit features, and
+// f is never called for them.
+func forEachExplicitFeature(file bufprotosource.File, f func(*explicitFeature) error) error {
+	if file.Syntax() != bufprotosource.SyntaxEditions {
+		return nil
+	}
+	edition := file.Edition()
+	if err := forEachExplicitFeatureInOptions(
+		file.FileDescriptor().GetOptions(),
+		file.Features(),
+		fmt.Sprintf("file %q", file.Path()),
+		func(featureField protoreflect.FieldDescriptor) (protoreflect.Value, error) {
+			return protoutil.GetFeatureDefault(edition, featureField)
+		},
+		file.SyntaxLocation(),
+		f,
+	); err != nil {
+		return err
+	}
+	if err := bufprotosource.ForEachMessage(
+		func(message bufprotosource.Message) error {
+			messageDescriptor, err := message.AsDescriptor()
+			if err != nil {
+				return err
+			}
+			if err := forEachExplicitFeatureInDescriptor(
+				messageDescriptor,
+				messageDescriptor.Parent(),
+				message.Features(),
+				fmt.Sprintf("message %q", message.FullName()),
+				message.NameLocation(),
+				f,
+			); err != nil {
+				return err
+			}
+			for _, oneof := range message.Oneofs() {
+				oneofDescriptor, err := oneof.AsDescriptor()
+				if err != nil {
+					return err
+				}
+				if err := forEachExplicitFeatureInDescriptor(
+					oneofDescriptor,
+					messageDescriptor,
+					oneof.Features(),
+					fmt.Sprintf("oneof %q", oneof.FullName()),
+					oneof.NameLocation(),
+					f,
+				); err != nil {
+					return err
+				}
+			}
+			for _, field := range message.Fields() {
+				if err := forEachExplicitFeatureInField(field, f); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		file,
+	); err != nil {
+		return err
+	}
+	if err := bufprotosource.ForEachExtension(
+		func(field bufprotosource.Field) error {
+			return forEachExplicitFeatureInField(field, f)
+		},
+		file,
+	); err != nil {
+		return err
+	}
+	return bufprotosource.ForEachEnum(
+		func(enum bufprotosource.Enum) error {
+			enumDescriptor, err := enum.AsDescriptor()
+			if err != nil {
+				return err
+			}
+			return forEachExplicitFeatureInDescriptor(
+				enumDescriptor,
+				enumDescriptor.Parent(),
+				enum.Features(),
+				fmt.Sprintf("enum %q", enum.FullName()),
+				enum.NameLocation(),
+				f,
+			)
+		},
+		file,
+	)
+}
+
+func forEachExplicitFeatureInField(field bufprotosource.Field, f func(*explicitFeature) error) error {
+	fieldDescriptor, err := field.AsDescriptor()
+	if err != nil {
+		return err
+	}
+	// Fields in a oneof inherit their features from the oneof. For extensions,
+	// the parent is the scope the extension is declared in.
+	parent := fieldDescriptor.Parent()
+	if oneof := fieldDescriptor.ContainingOneof(); oneof != nil && !oneof.IsSynthetic() {
+		parent = oneof
+	}
+	elementDescription := fmt.Sprintf("field %q", field.FullName())
+	if fieldDescriptor.IsExtension() {
+		elementDescription = fmt.Sprintf("extension %q", field.FullName())
+	}
+	return forEachExplicitFeatureInDescriptor(
+		fieldDescriptor,
+		parent,
+		field.Features(),
+		elementDescription,
+		field.NameLocation(),
+		f,
+	)
+}
+
+func forEachExplicitFeatureInDescriptor(
+	descriptor protoreflect.Descriptor,
+	parent protoreflect.Descriptor,
+	featuresDescriptor bufprotosource.FeaturesDescriptor,
+	elementDescription string,
+	backupLocation bufprotosource.Location,
+	f func(*explicitFeature) error,
+) error {
+	return forEachExplicitFeatureInOptions(
+		descriptor.Options(),
+		featuresDescriptor,
+		elementDescription,
+		func(featureField protoreflect.FieldDescriptor) (protoreflect.Value, error) {
+			return protoutil.ResolveFeature(parent, featureField)
+		},
+		backupLocation,
+		f,
+	)
+}
+
+func forEachExplicitFeatureInOptions(
+	options proto.Message,
+	featuresDescriptor bufprotosource.FeaturesDescriptor,
+	elementDescription string,
+	getInheritedValue func(protoreflect.FieldDescriptor) (protoreflect.Value, error),
+	backupLocation bufprotosource.Location,
+	f func(*explicitFeature) error,
+) error {
+	if options == nil {
+		return nil
+	}
+	optionsMessage := options.ProtoReflect()
+	featuresField := optionsMessage.Descriptor().Fields().ByName(featuresFieldName)
+	if featuresField == nil || !optionsMessage.Has(featuresField) {
+		return nil
+	}
+	var explicitFeatures []*explicitFeature
+	var rangeErr error
+	optionsMessage.Get(featuresField).Message().Range(
+		func(featureField protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			if featureField.IsExtension() || featureField.Kind() != protoreflect.EnumKind {
+				return true
+			}
+			inheritedValue, err := getInheritedValue(featureField)
+			if err != nil {
+				rangeErr = err
+				return false
+			}
+			explicitFeatures = append(
+				explicitFeatures,
+				&explicitFeature{
+					featureField:       featureField,
+					value:              value.Enum(),
+					inheritedValue:     inheritedValue.Enum(),
+					elementDescription: elementDescription,
+					location: withBackupLocation(
+						getFeatureLocation(featuresDescriptor, featureField.Name()),
+						backupLocation,
+					),
+				},
+			)
+			return true
+		},
+	)
+	if rangeErr != nil {
+		return rangeErr
+	}
+	// Range does not guarantee any order.
+	sort.Slice(
+		explicitFeatures,
+		func(i int, j int) bool {
+			return explicitFeatures[i].featureField.Number() < explicitFeatures[j].featureField.Number()
+		},
+	)
+	for _, explicitFeature := range explicitFeatures {
+		if err := f(explicitFeature); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getFeatureLocation(featuresDescriptor bufprotosource.FeaturesDescriptor, name protoreflect.Name) bufprotosource.Location {
+	switch name {
+	case "field_presence":
+		return featuresDescriptor.FieldPresenceLocation()
+	case "enum_type":
+		return featuresDescriptor.EnumTypeLocation()
+	case "repeated_field_encoding":
+		return featuresDescriptor.RepeatedFieldEncodingLocation()
+	case "utf8_validation":
+		return featuresDescriptor.UTF8ValidationLocation()
+	case "message_encoding":
+		return featuresDescriptor.MessageEncodingLocation()
+	case "json_format":
+		return featuresDescriptor.JSONFormatLocation()
+	default:
+		return nil
+	}
+}
+
+// isLegacyFeatureValue returns true if the value of the feature only exists
+// to preserve the behavior of proto2 or proto3 files.
+func isLegacyFeatureValue(featureField protoreflect.FieldDescriptor, value protoreflect.EnumNumber) bool {
+	switch featureField.Name() {
+	case "field_presence":
+		return value == protoreflect.EnumNumber(descriptorpb.FeatureSet_LEGACY_REQUIRED)
+	case "enum_type":
+		return value == protoreflect.EnumNumber(descriptorpb.FeatureSet_CLOSED)
+	case "repeated_field_encoding":
+		return value == protoreflect.EnumNumber(descriptorpb.FeatureSet_EXPANDED)
+	case "utf8_validation":
+		return value == protoreflect.EnumNumber(descriptorpb.FeatureSet_NONE)
+	case "message_encoding":
+		return value == protoreflect.EnumNumber(descriptorpb.FeatureSet_DELIMITED)
+	case "json_format":
+		return value == protoreflect.EnumNumber(descriptorpb.FeatureSet_LEGACY_BEST_EFFORT)
+	default:
+		return false
+	}
+}
+
+func featureEnumValueName(featureField protoreflect.FieldDescriptor, value protoreflect.EnumNumber) string {
+	if enumValue := featureField.Enum().Values().ByNumber(value); enumValue != nil {
+		return string(enumValue.Name())
+	}
+	return fmt.Sprintf("%d", value)
+}
+
 func fieldToLowerSnakeCase(s string) string {
 	// Try running this on googleapis and watch
 	// We allow both effectively by not passing the option
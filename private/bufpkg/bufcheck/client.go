@@ -20,6 +20,7 @@ import (
 	"io"
 	"log/slog"
 	"strings"
+	"time"
 
 	"buf.build/go/bufplugin/check"
 	"buf.build/go/bufplugin/descriptor"
@@ -42,6 +43,7 @@ type client struct {
 	logger                          *slog.Logger
 	runnerProvider                  RunnerProvider
 	stderr                          io.Writer
+	now                             func() time.Time
 	fileVersionToDefaultCheckClient map[bufconfig.FileVersion]check.Client
 }
 
@@ -72,6 +74,7 @@ func newClient(
 		logger:         logger,
 		runnerProvider: runnerProvider,
 		stderr:         clientOptions.stderr,
+		now:            clientOptions.now,
 		fileVersionToDefaultCheckClient: map[bufconfig.FileVersion]check.Client{
 			bufconfig.FileVersionV1Beta1: v1beta1DefaultCheckClient,
 			bufconfig.FileVersionV1:      v1DefaultCheckClient,
@@ -108,6 +111,7 @@ func (c *client) Lint(
 	if err != nil {
 		return err
 	}
+	config.now = c.now()
 	logRulesConfig(c.logger, config.rulesConfig)
 	files, err := descriptor.FileDescriptorsForProtoFileDescriptors(imageToProtoFileDescriptors(image))
 	if err != nil {
@@ -135,7 +139,12 @@ func (c *client) Lint(
 	if err != nil {
 		return err
 	}
-	return annotationsToFilteredFileAnnotationSetOrError(config, image, annotations)
+	return annotationsToFilteredFileAnnotationSetOrError(
+		config,
+		image,
+		annotations,
+		getInvalidCommentIgnoreFileAnnotations(config, image)...,
+	)
 }
 
 func (c *client) Breaking(
@@ -335,30 +344,36 @@ func (c *client) getMultiClient(
 	return newMultiClient(c.logger, checkClientSpecs), nil
 }
 
+// annotationsToFilteredFileAnnotationSetOrError filters the annotations, and returns
+// a FileAnnotationSet for the remaining annotations and the additional FileAnnotations,
+// or nil if there are none.
+//
+// The additional FileAnnotations are not filtered.
 func annotationsToFilteredFileAnnotationSetOrError(
 	config *config,
 	image bufimage.Image,
 	annotations []*annotation,
+	additionalFileAnnotations ...bufanalysis.FileAnnotation,
 ) error {
-	if len(annotations) == 0 {
-		return nil
-	}
 	annotations, err := filterAnnotations(config, annotations)
 	if err != nil {
 		return err
 	}
-	if len(annotations) == 0 {
+	if len(annotations) == 0 && len(additionalFileAnnotations) == 0 {
 		return nil
 	}
 	// Note that NewFileAnnotationSet does its own sorting and deduplication.
 	// The bufplugin SDK does this as well, but we don't need to worry about the sort
 	// order being different.
 	return bufanalysis.NewFileAnnotationSet(
-		annotationsToFileAnnotations(
-			imageToPathToExternalPath(
-				image,
+		append(
+			annotationsToFileAnnotations(
+				imageToPathToExternalPath(
+					image,
+				),
+				annotations,
 			),
-			annotations,
+			additionalFileAnnotations...,
 		)...,
 	)
 }
@@ -443,7 +458,13 @@ func ignoreFileLocation(
 			sourceLocation := sourceLocations.ByPath(associatedSourcePath)
 			if leadingComments := sourceLocation.LeadingComments; leadingComments != "" {
 				for _, line := range stringutil.SplitTrimLinesNoEmpty(leadingComments) {
-					if checkCommentLineForCheckIgnore(line, config.CommentIgnorePrefix, ruleID) {
+					if checkCommentLineForCheckIgnore(
+						line,
+						config.CommentIgnorePrefix,
+						ruleID,
+						config.RequireCommentIgnoreReason,
+						config.now,
+					) {
 						return true, nil
 					}
 				}
@@ -454,7 +475,8 @@ func ignoreFileLocation(
 }
 
 // checkCommentLineForCheckIgnore checks that the comment line starts with the configured
-// comment ignore prefix, a space and the ruleID of the check.
+// comment ignore prefix, a space and the ruleID of the check, and that the comment ignore
+// is active.
 //
 // All of the following comments are valid, ignoring SERVICE_PASCAL_CASE and this rule only:
 //
@@ -462,17 +484,28 @@ func ignoreFileLocation(
 //	// buf:lint:ignore SERVICE_PASCAL_CASE
 //	// buf:lint:ignore SERVICE_PASCAL_CASEsome other comment
 //	// buf:lint:ignore SERVICE_PASCAL_CASE some other comment
+//	// buf:lint:ignore SERVICE_PASCAL_CASE until=2025-01-31 some other comment
 //
 // While the following is invalid and a nop
 //
 //	// buf:lint:ignoreSERVICE_PASCAL_CASE
+//
+// The comment ignore is not active after its until date, if its until date is invalid, such
+// as until=2025-1-31, or if requireReason is set and there is no reason after the rule ID
+// and until date.
 func checkCommentLineForCheckIgnore(
 	commentLine string,
 	commentIgnorePrefix string,
 	ruleID string,
+	requireReason bool,
+	now time.Time,
 ) bool {
 	fullIgnorePrefix := commentIgnorePrefix + " " + ruleID
-	return strings.HasPrefix(commentLine, fullIgnorePrefix)
+	remainder, ok := strings.CutPrefix(commentLine, fullIgnorePrefix)
+	if !ok {
+		return false
+	}
+	return parseCommentIgnoreRemainder(remainder).status(requireReason, now) == LintIgnoreStatusActive
 }
 
 type lintOptions struct {
@@ -518,10 +551,13 @@ func newAllCategoriesOptions() *allCategoriesOptions {
 
 type clientOptions struct {
 	stderr io.Writer
+	now    func() time.Time
 }
 
 func newClientOptions() *clientOptions {
-	return &clientOptions{}
+	return &clientOptions{
+		now: time.Now,
+	}
 }
 
 type excludeImportsOption struct{}
@@ -15,6 +15,8 @@
 package bufcheck
 
 import (
+	"time"
+
 	"buf.build/go/bufplugin/check"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 )
@@ -22,6 +24,11 @@ import (
 type config struct {
 	*rulesConfig
 	*optionsConfig
+
+	// now is the time used to determine if comment ignores with an until date have lapsed.
+	//
+	// Only set for lint.
+	now time.Time
 }
 
 func configForLintConfig(
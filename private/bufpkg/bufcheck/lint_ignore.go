@@ -0,0 +1,442 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcheck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	commentIgnoreUntilPrefix = "until="
+	commentIgnoreUntilLayout = "2006-01-02"
+	// invalidCommentIgnoreTypeString is the type of the FileAnnotations for comment ignores
+	// with an until date that could not be parsed.
+	invalidCommentIgnoreTypeString = "INVALID_COMMENT_IGNORE"
+
+	lintIgnoresTextHeader      = "RULE\tSOURCE\tLOCATION\tUNTIL\tSTATUS\tREASON"
+	lintIgnoreCountsTextHeader = "RULE\tCOUNT"
+)
+
+var (
+	lintIgnoreSourceToString = map[LintIgnoreSource]string{
+		LintIgnoreSourceComment: "comment",
+		LintIgnoreSourceConfig:  "ignore_only",
+	}
+	lintIgnoreStatusToString = map[LintIgnoreStatus]string{
+		LintIgnoreStatusActive:        "active",
+		LintIgnoreStatusExpired:       "expired",
+		LintIgnoreStatusMissingReason: "missing reason",
+		LintIgnoreStatusInvalidUntil:  "invalid until",
+		LintIgnoreStatusDisabled:      "disabled",
+	}
+)
+
+type lintIgnore struct {
+	source           LintIgnoreSource
+	ruleIDOrCategory string
+	path             string
+	startLine        int
+	startColumn      int
+	until            time.Time
+	reason           string
+	status           LintIgnoreStatus
+}
+
+func (l *lintIgnore) Source() LintIgnoreSource {
+	return l.source
+}
+
+func (l *lintIgnore) RuleIDOrCategory() string {
+	return l.ruleIDOrCategory
+}
+
+func (l *lintIgnore) Path() string {
+	return l.path
+}
+
+func (l *lintIgnore) StartLine() int {
+	return l.startLine
+}
+
+func (l *lintIgnore) StartColumn() int {
+	return l.startColumn
+}
+
+func (l *lintIgnore) Until() time.Time {
+	return l.until
+}
+
+func (l *lintIgnore) Reason() string {
+	return l.reason
+}
+
+func (l *lintIgnore) Status() LintIgnoreStatus {
+	return l.status
+}
+
+func (*lintIgnore) isLintIgnore() {}
+
+// commentIgnoreRemainder is the parsed content of a comment ignore after the rule ID.
+type commentIgnoreRemainder struct {
+	until        time.Time
+	invalidUntil string
+	reason       string
+}
+
+// parseCommentIgnoreRemainder parses the content of a comment ignore after the rule ID.
+//
+// The remainder may optionally start with until=YYYY-MM-DD, and everything after that
+// is the reason.
+func parseCommentIgnoreRemainder(remainder string) *commentIgnoreRemainder {
+	commentIgnoreRemainder := &commentIgnoreRemainder{}
+	remainder = strings.TrimSpace(remainder)
+	if untilField, ok := strings.CutPrefix(remainder, commentIgnoreUntilPrefix); ok {
+		untilValue, reason, _ := strings.Cut(untilField, " ")
+		until, err := time.Parse(commentIgnoreUntilLayout, untilValue)
+		if err != nil {
+			commentIgnoreRemainder.invalidUntil = untilValue
+		} else {
+			commentIgnoreRemainder.until = until
+		}
+		remainder = strings.TrimSpace(reason)
+	}
+	commentIgnoreRemainder.reason = remainder
+	return commentIgnoreRemainder
+}
+
+// status returns the status of the comment ignore at the given time.
+//
+// The until date is inclusive, that is a comment ignore with until=2025-01-31 lapses at
+// the start of 2025-02-01 UTC.
+func (c *commentIgnoreRemainder) status(requireReason bool, now time.Time) LintIgnoreStatus {
+	if c.invalidUntil != "" {
+		return LintIgnoreStatusInvalidUntil
+	}
+	if !c.until.IsZero() && !now.UTC().Before(c.until.AddDate(0, 0, 1)) {
+		return LintIgnoreStatusExpired
+	}
+	if requireReason && c.reason == "" {
+		return LintIgnoreStatusMissingReason
+	}
+	return LintIgnoreStatusActive
+}
+
+func getLintIgnores(image bufimage.Image, config bufconfig.LintConfig, now time.Time) []LintIgnore {
+	var lintIgnores []LintIgnore
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		lintIgnores = append(lintIgnores, getCommentLintIgnoresForImageFile(imageFile, config, now)...)
+	}
+	for ruleIDOrCategory, paths := range config.IgnoreIDOrCategoryToPaths() {
+		for _, path := range paths {
+			lintIgnores = append(
+				lintIgnores,
+				&lintIgnore{
+					source:           LintIgnoreSourceConfig,
+					ruleIDOrCategory: ruleIDOrCategory,
+					path:             path,
+					status:           LintIgnoreStatusActive,
+				},
+			)
+		}
+	}
+	sort.Slice(
+		lintIgnores,
+		func(i int, j int) bool {
+			one := lintIgnores[i]
+			two := lintIgnores[j]
+			if one.Path() != two.Path() {
+				return one.Path() < two.Path()
+			}
+			if one.Source() != two.Source() {
+				return one.Source() < two.Source()
+			}
+			if one.StartLine() != two.StartLine() {
+				return one.StartLine() < two.StartLine()
+			}
+			if one.StartColumn() != two.StartColumn() {
+				return one.StartColumn() < two.StartColumn()
+			}
+			return one.RuleIDOrCategory() < two.RuleIDOrCategory()
+		},
+	)
+	return lintIgnores
+}
+
+func getCommentLintIgnoresForImageFile(
+	imageFile bufimage.ImageFile,
+	config bufconfig.LintConfig,
+	now time.Time,
+) []LintIgnore {
+	var lintIgnores []LintIgnore
+	forEachCommentIgnore(
+		imageFile,
+		lintCommentIgnorePrefix,
+		func(
+			location *descriptorpb.SourceCodeInfo_Location,
+			ruleID string,
+			commentIgnoreRemainder *commentIgnoreRemainder,
+		) {
+			status := LintIgnoreStatusDisabled
+			if config.AllowCommentIgnores() {
+				status = commentIgnoreRemainder.status(config.RequireCommentIgnoreReason(), now)
+			}
+			lintIgnore := &lintIgnore{
+				source:           LintIgnoreSourceComment,
+				ruleIDOrCategory: ruleID,
+				path:             imageFile.Path(),
+				until:            commentIgnoreRemainder.until,
+				reason:           commentIgnoreRemainder.reason,
+				status:           status,
+			}
+			if span := location.GetSpan(); len(span) >= 2 {
+				lintIgnore.startLine = int(span[0]) + 1
+				lintIgnore.startColumn = int(span[1]) + 1
+			}
+			lintIgnores = append(lintIgnores, lintIgnore)
+		},
+	)
+	return lintIgnores
+}
+
+// getInvalidCommentIgnoreFileAnnotations returns a FileAnnotation for each comment ignore
+// in the non-import files of the Image with an until date that could not be parsed.
+func getInvalidCommentIgnoreFileAnnotations(config *config, image bufimage.Image) []bufanalysis.FileAnnotation {
+	if !config.AllowCommentIgnores || config.CommentIgnorePrefix == "" {
+		return nil
+	}
+	pathToExternalPath := imageToPathToExternalPath(image)
+	var fileAnnotations []bufanalysis.FileAnnotation
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		forEachCommentIgnore(
+			imageFile,
+			config.CommentIgnorePrefix,
+			func(
+				location *descriptorpb.SourceCodeInfo_Location,
+				ruleID string,
+				commentIgnoreRemainder *commentIgnoreRemainder,
+			) {
+				if commentIgnoreRemainder.invalidUntil == "" {
+					return
+				}
+				var startLine, startColumn, endLine, endColumn int
+				switch span := location.GetSpan(); len(span) {
+				case 3:
+					startLine, startColumn, endLine, endColumn = int(span[0]), int(span[1]), int(span[0]), int(span[2])
+				case 4:
+					startLine, startColumn, endLine, endColumn = int(span[0]), int(span[1]), int(span[2]), int(span[3])
+				}
+				fileAnnotations = append(
+					fileAnnotations,
+					bufanalysis.NewFileAnnotation(
+						newFileInfo(imageFile.Path(), pathToExternalPath[imageFile.Path()]),
+						startLine+1,
+						startColumn+1,
+						endLine+1,
+						endColumn+1,
+						invalidCommentIgnoreTypeString,
+						fmt.Sprintf(
+							"Comment ignore for %s has invalid until date %q, which must be of the form YYYY-MM-DD, and is not in effect.",
+							ruleID,
+							commentIgnoreRemainder.invalidUntil,
+						),
+						"",
+					),
+				)
+			},
+		)
+	}
+	return fileAnnotations
+}
+
+// forEachCommentIgnore calls f for each comment ignore with the given prefix in the leading
+// comments of the file.
+func forEachCommentIgnore(
+	imageFile bufimage.ImageFile,
+	commentIgnorePrefix string,
+	f func(*descriptorpb.SourceCodeInfo_Location, string, *commentIgnoreRemainder),
+) {
+	fullIgnorePrefix := commentIgnorePrefix + " "
+	for _, location := range imageFile.FileDescriptorProto().GetSourceCodeInfo().GetLocation() {
+		leadingComments := location.GetLeadingComments()
+		if leadingComments == "" {
+			continue
+		}
+		for _, line := range stringutil.SplitTrimLinesNoEmpty(leadingComments) {
+			remainder, ok := strings.CutPrefix(line, fullIgnorePrefix)
+			if !ok {
+				continue
+			}
+			ruleID := getCommentIgnoreRuleID(remainder)
+			if ruleID == "" {
+				continue
+			}
+			f(location, ruleID, parseCommentIgnoreRemainder(strings.TrimPrefix(remainder, ruleID)))
+		}
+	}
+}
+
+// getCommentIgnoreRuleID returns the leading rule ID of the content of a comment ignore
+// after the comment ignore prefix.
+func getCommentIgnoreRuleID(value string) string {
+	for i, r := range value {
+		if !(('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') || r == '_') {
+			return value[:i]
+		}
+	}
+	return value
+}
+
+type externalLintIgnores struct {
+	Ignores []*externalLintIgnore `json:"ignores"`
+	Counts  map[string]int        `json:"counts"`
+}
+
+type externalLintIgnore struct {
+	Rule        string `json:"rule"`
+	Source      string `json:"source"`
+	Path        string `json:"path"`
+	StartLine   int    `json:"start_line,omitempty"`
+	StartColumn int    `json:"start_column,omitempty"`
+	Until       string `json:"until,omitempty"`
+	Status      string `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+func newExternalLintIgnore(lintIgnore LintIgnore) *externalLintIgnore {
+	return &externalLintIgnore{
+		Rule:        lintIgnore.RuleIDOrCategory(),
+		Source:      lintIgnore.Source().String(),
+		Path:        lintIgnore.Path(),
+		StartLine:   lintIgnore.StartLine(),
+		StartColumn: lintIgnore.StartColumn(),
+		Until:       getLintIgnoreUntilString(lintIgnore),
+		Status:      lintIgnore.Status().String(),
+		Reason:      lintIgnore.Reason(),
+	}
+}
+
+type printLintIgnoresOptions struct {
+	asJSON bool
+}
+
+func newPrintLintIgnoresOptions() *printLintIgnoresOptions {
+	return &printLintIgnoresOptions{}
+}
+
+func printLintIgnores(writer io.Writer, lintIgnores []LintIgnore, options ...PrintLintIgnoresOption) error {
+	printLintIgnoresOptions := newPrintLintIgnoresOptions()
+	for _, option := range options {
+		option(printLintIgnoresOptions)
+	}
+	ruleIDOrCategoryToCount := make(map[string]int)
+	for _, lintIgnore := range lintIgnores {
+		ruleIDOrCategoryToCount[lintIgnore.RuleIDOrCategory()]++
+	}
+	if printLintIgnoresOptions.asJSON {
+		return printLintIgnoresJSON(writer, lintIgnores, ruleIDOrCategoryToCount)
+	}
+	return printLintIgnoresText(writer, lintIgnores, ruleIDOrCategoryToCount)
+}
+
+func printLintIgnoresJSON(writer io.Writer, lintIgnores []LintIgnore, ruleIDOrCategoryToCount map[string]int) error {
+	externalLintIgnores := &externalLintIgnores{
+		Ignores: make([]*externalLintIgnore, len(lintIgnores)),
+		Counts:  ruleIDOrCategoryToCount,
+	}
+	for i, lintIgnore := range lintIgnores {
+		externalLintIgnores.Ignores[i] = newExternalLintIgnore(lintIgnore)
+	}
+	data, err := json.Marshal(externalLintIgnores)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(writer, string(data))
+	return err
+}
+
+func printLintIgnoresText(writer io.Writer, lintIgnores []LintIgnore, ruleIDOrCategoryToCount map[string]int) (retErr error) {
+	if len(lintIgnores) == 0 {
+		return nil
+	}
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	defer func() {
+		retErr = errors.Join(retErr, tabWriter.Flush())
+	}()
+	if _, err := fmt.Fprintln(tabWriter, lintIgnoresTextHeader); err != nil {
+		return err
+	}
+	for _, lintIgnore := range lintIgnores {
+		location := lintIgnore.Path()
+		if lintIgnore.StartLine() > 0 {
+			location = location + ":" + strconv.Itoa(lintIgnore.StartLine()) + ":" + strconv.Itoa(lintIgnore.StartColumn())
+		}
+		if _, err := fmt.Fprintf(
+			tabWriter,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			lintIgnore.RuleIDOrCategory(),
+			lintIgnore.Source().String(),
+			location,
+			getLintIgnoreUntilString(lintIgnore),
+			lintIgnore.Status().String(),
+			lintIgnore.Reason(),
+		); err != nil {
+			return err
+		}
+	}
+	// Flush so that the counts table is aligned separately from the ignores table.
+	if err := tabWriter.Flush(); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(tabWriter); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(tabWriter, lintIgnoreCountsTextHeader); err != nil {
+		return err
+	}
+	for _, ruleIDOrCategory := range slicesext.MapKeysToSortedSlice(ruleIDOrCategoryToCount) {
+		if _, err := fmt.Fprintf(tabWriter, "%s\t%d\n", ruleIDOrCategory, ruleIDOrCategoryToCount[ruleIDOrCategory]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getLintIgnoreUntilString(lintIgnore LintIgnore) string {
+	if until := lintIgnore.Until(); !until.IsZero() {
+		return until.Format(commentIgnoreUntilLayout)
+	}
+	return ""
+}
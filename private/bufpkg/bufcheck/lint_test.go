@@ -16,6 +16,7 @@ package bufcheck_test
 
 import (
 	"context"
+	"log/slog"
 	"path/filepath"
 	"testing"
 	"time"
@@ -308,6 +309,28 @@ func TestRunEnumZeroValueSuffixCustom(t *testing.T) {
 	)
 }
 
+func TestRunFeatureOverrides(t *testing.T) {
+	t.Parallel()
+	testLint(
+		t,
+		"feature_overrides",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 5, 1, 5, 43, "FEATURE_NO_REDUNDANT_OVERRIDE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 6, 1, 6, 40, "FEATURE_NO_LEGACY_VALUE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 9, 3, 9, 39, "FEATURE_NO_REDUNDANT_OVERRIDE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 12, 17, 12, 51, "FEATURE_NO_REDUNDANT_OVERRIDE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 13, 17, 13, 58, "FEATURE_NO_LEGACY_VALUE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 14, 25, 14, 68, "FEATURE_NO_LEGACY_VALUE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 15, 25, 15, 66, "FEATURE_NO_REDUNDANT_OVERRIDE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 16, 17, 16, 48, "FEATURE_NO_LEGACY_VALUE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 16, 17, 16, 48, "FEATURE_NO_REDUNDANT_OVERRIDE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 21, 3, 21, 52, "FEATURE_NO_LEGACY_VALUE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 24, 5, 24, 54, "FEATURE_NO_LEGACY_VALUE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 24, 5, 24, 54, "FEATURE_NO_REDUNDANT_OVERRIDE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 29, 3, 29, 38, "FEATURE_NO_LEGACY_VALUE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 35, 3, 35, 36, "FEATURE_NO_REDUNDANT_OVERRIDE"),
+	)
+}
+
 func TestRunFieldLowerSnakeCase(t *testing.T) {
 	t.Parallel()
 	testLint(
@@ -1233,6 +1256,108 @@ func TestCommentIgnoresWithTrailingComment(t *testing.T) {
 	)
 }
 
+func TestCommentIgnoresRequireReason(t *testing.T) {
+	t.Parallel()
+	testLint(
+		t,
+		"comment_ignores_require_reason",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 7, 9, 7, 12, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 11, 9, 11, 14, "FIELD_LOWER_SNAKE_CASE"),
+	)
+}
+
+func TestCommentIgnoresUntil(t *testing.T) {
+	t.Parallel()
+	testLintWithClientOptions(
+		t,
+		"comment_ignores_until",
+		[]bufcheck.ClientOption{
+			bufcheck.ClientWithNow(
+				func() time.Time {
+					return time.Date(2500, time.January, 1, 12, 0, 0, 0, time.UTC)
+				},
+			),
+		},
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 7, 9, 7, 12, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 11, 3, 11, 19, "INVALID_COMMENT_IGNORE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 11, 9, 11, 14, "FIELD_LOWER_SNAKE_CASE"),
+	)
+}
+
+func TestCommentIgnoresUntilWithoutReason(t *testing.T) {
+	t.Parallel()
+	// Until dates do not require require_comment_ignore_reason to be set.
+	testLintWithClientOptions(
+		t,
+		"comment_ignores_until_without_reason",
+		[]bufcheck.ClientOption{
+			bufcheck.ClientWithNow(
+				func() time.Time {
+					return time.Date(2500, time.January, 1, 12, 0, 0, 0, time.UTC)
+				},
+			),
+		},
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 7, 9, 7, 12, "FIELD_LOWER_SNAKE_CASE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 9, 3, 9, 17, "INVALID_COMMENT_IGNORE"),
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 9, 9, 9, 12, "FIELD_LOWER_SNAKE_CASE"),
+	)
+}
+
+func TestGetLintIgnores(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	workspace, opaqueID, image := testBuildLintImage(
+		t,
+		ctx,
+		slogtestext.NewLogger(t),
+		"comment_ignores_require_reason",
+		"",
+	)
+	lintConfig := workspace.GetLintConfigForOpaqueID(opaqueID)
+	require.NotNil(t, lintConfig)
+	lintIgnores := bufcheck.GetLintIgnores(
+		image,
+		lintConfig,
+		time.Date(3000, time.January, 1, 0, 0, 0, 0, time.UTC),
+	)
+	type testLintIgnore struct {
+		Source           string
+		RuleIDOrCategory string
+		Path             string
+		StartLine        int
+		Until            string
+		Reason           string
+		Status           string
+	}
+	actual := make([]testLintIgnore, len(lintIgnores))
+	for i, lintIgnore := range lintIgnores {
+		var until string
+		if !lintIgnore.Until().IsZero() {
+			until = lintIgnore.Until().Format(time.DateOnly)
+		}
+		actual[i] = testLintIgnore{
+			Source:           lintIgnore.Source().String(),
+			RuleIDOrCategory: lintIgnore.RuleIDOrCategory(),
+			Path:             lintIgnore.Path(),
+			StartLine:        lintIgnore.StartLine(),
+			Until:            until,
+			Reason:           lintIgnore.Reason(),
+			Status:           lintIgnore.Status().String(),
+		}
+	}
+	assert.Equal(
+		t,
+		[]testLintIgnore{
+			{"comment", "FIELD_LOWER_SNAKE_CASE", "a.proto", 7, "", "", "missing reason"},
+			{"comment", "FIELD_LOWER_SNAKE_CASE", "a.proto", 9, "", "kept for wire compatibility with v1 clients", "active"},
+			{"comment", "FIELD_LOWER_SNAKE_CASE", "a.proto", 11, "2999-12-31", "", "expired"},
+			{"comment", "FIELD_LOWER_SNAKE_CASE", "a.proto", 13, "2999-12-31", "removed in v2", "expired"},
+			{"ignore_only", "FIELD_LOWER_SNAKE_CASE", "b.proto", 0, "", "", "active"},
+		},
+		actual,
+	)
+}
+
 func TestRunLintCustomPlugins(t *testing.T) {
 	t.Parallel()
 	testLint(
@@ -1290,13 +1415,93 @@ func testLintWithOptions(
 	moduleFullNameString string,
 	imageModifier func(bufimage.Image) bufimage.Image,
 	expectedFileAnnotations ...bufanalysis.FileAnnotation,
+) {
+	testLintWithOptionsAndClientOptions(
+		t,
+		relDirPath,
+		moduleFullNameString,
+		imageModifier,
+		nil,
+		expectedFileAnnotations...,
+	)
+}
+
+func testLintWithClientOptions(
+	t *testing.T,
+	relDirPath string,
+	clientOptions []bufcheck.ClientOption,
+	expectedFileAnnotations ...bufanalysis.FileAnnotation,
+) {
+	testLintWithOptionsAndClientOptions(
+		t,
+		relDirPath,
+		"",
+		nil,
+		clientOptions,
+		expectedFileAnnotations...,
+	)
+}
+
+func testLintWithOptionsAndClientOptions(
+	t *testing.T,
+	relDirPath string,
+	// only set if in workspace
+	moduleFullNameString string,
+	imageModifier func(bufimage.Image) bufimage.Image,
+	clientOptions []bufcheck.ClientOption,
+	expectedFileAnnotations ...bufanalysis.FileAnnotation,
 ) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second) // Increased timeout for Wasm runtime
 	defer cancel()
 
+	logger := slogtestext.NewLogger(t)
+	workspace, opaqueID, image := testBuildLintImage(t, ctx, logger, relDirPath, moduleFullNameString)
+	if imageModifier != nil {
+		image = imageModifier(image)
+	}
+
+	lintConfig := workspace.GetLintConfigForOpaqueID(opaqueID)
+	require.NotNil(t, lintConfig)
+	wasmRuntime, err := wasm.NewRuntime(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, wasmRuntime.Close(ctx))
+	})
+	client, err := bufcheck.NewClient(
+		logger,
+		bufcheck.NewRunnerProvider(wasmRuntime),
+		clientOptions...,
+	)
+	require.NoError(t, err)
+	err = client.Lint(
+		ctx,
+		lintConfig,
+		image,
+		bufcheck.WithPluginConfigs(workspace.PluginConfigs()...),
+	)
+	if len(expectedFileAnnotations) == 0 {
+		assert.NoError(t, err)
+	} else {
+		var fileAnnotationSet bufanalysis.FileAnnotationSet
+		require.ErrorAs(t, err, &fileAnnotationSet, "error has unexpected type: %T", err)
+		bufanalysistesting.AssertFileAnnotationsEqual(
+			t,
+			expectedFileAnnotations,
+			fileAnnotationSet.FileAnnotations(),
+		)
+	}
+}
+
+func testBuildLintImage(
+	t *testing.T,
+	ctx context.Context,
+	logger *slog.Logger,
+	relDirPath string,
+	// only set if in workspace
+	moduleFullNameString string,
+) (bufworkspace.Workspace, string, bufimage.Image) {
 	baseDirPath := filepath.Join("testdata", "lint")
 	dirPath := filepath.Join(baseDirPath, relDirPath)
-	logger := slogtestext.NewLogger(t)
 	storageosProvider := storageos.NewProvider(storageos.ProviderWithSymlinks())
 	readWriteBucket, err := storageosProvider.NewReadWriteBucket(
 		dirPath,
@@ -1342,37 +1547,5 @@ func testLintWithOptions(
 		moduleReadBucket,
 	)
 	require.NoError(t, err)
-	if imageModifier != nil {
-		image = imageModifier(image)
-	}
-
-	lintConfig := workspace.GetLintConfigForOpaqueID(opaqueID)
-	require.NotNil(t, lintConfig)
-	wasmRuntime, err := wasm.NewRuntime(ctx)
-	require.NoError(t, err)
-	t.Cleanup(func() {
-		require.NoError(t, wasmRuntime.Close(ctx))
-	})
-	client, err := bufcheck.NewClient(
-		logger,
-		bufcheck.NewRunnerProvider(wasmRuntime),
-	)
-	require.NoError(t, err)
-	err = client.Lint(
-		ctx,
-		lintConfig,
-		image,
-		bufcheck.WithPluginConfigs(workspace.PluginConfigs()...),
-	)
-	if len(expectedFileAnnotations) == 0 {
-		assert.NoError(t, err)
-	} else {
-		var fileAnnotationSet bufanalysis.FileAnnotationSet
-		require.ErrorAs(t, err, &fileAnnotationSet, "error has unexpected type: %T", err)
-		bufanalysistesting.AssertFileAnnotationsEqual(
-			t,
-			expectedFileAnnotations,
-			fileAnnotationSet.FileAnnotations(),
-		)
-	}
+	return workspace, opaqueID, image
 }
@@ -28,11 +28,12 @@ type optionsConfig struct {
 	//
 	// Do not pass these to plugin check.Clients. Use options from checkClientSpecs instead.
 	// Will never be nil.
-	DefaultOptions         option.Options
-	AllowCommentIgnores    bool
-	IgnoreUnstablePackages bool
-	CommentIgnorePrefix    string
-	ExcludeImports         bool
+	DefaultOptions             option.Options
+	AllowCommentIgnores        bool
+	RequireCommentIgnoreReason bool
+	IgnoreUnstablePackages     bool
+	CommentIgnorePrefix        string
+	ExcludeImports             bool
 }
 
 func optionsConfigForLintConfig(
@@ -56,6 +57,7 @@ func optionsConfigForBreakingConfig(
 
 type optionsConfigSpec struct {
 	AllowCommentIgnores                  bool
+	RequireCommentIgnoreReason           bool
 	IgnoreUnstablePackages               bool
 	EnumZeroValueSuffix                  string
 	RPCAllowSameRequestResponse          bool
@@ -69,6 +71,7 @@ type optionsConfigSpec struct {
 func optionsConfigSpecForLintConfig(lintConfig bufconfig.LintConfig) *optionsConfigSpec {
 	return &optionsConfigSpec{
 		AllowCommentIgnores:                  lintConfig.AllowCommentIgnores(),
+		RequireCommentIgnoreReason:           lintConfig.RequireCommentIgnoreReason(),
 		IgnoreUnstablePackages:               false,
 		EnumZeroValueSuffix:                  lintConfig.EnumZeroValueSuffix(),
 		RPCAllowSameRequestResponse:          lintConfig.RPCAllowSameRequestResponse(),
@@ -86,6 +89,7 @@ func optionsConfigSpecForBreakingConfig(
 ) *optionsConfigSpec {
 	return &optionsConfigSpec{
 		AllowCommentIgnores:                  false,
+		RequireCommentIgnoreReason:           false,
 		IgnoreUnstablePackages:               breakingConfig.IgnoreUnstablePackages(),
 		EnumZeroValueSuffix:                  "",
 		RPCAllowSameRequestResponse:          false,
@@ -113,10 +117,11 @@ func (b *optionsConfigSpec) newOptionsConfig(ruleType check.RuleType) (*optionsC
 		return nil, err
 	}
 	return &optionsConfig{
-		DefaultOptions:         options,
-		AllowCommentIgnores:    b.AllowCommentIgnores,
-		IgnoreUnstablePackages: b.IgnoreUnstablePackages,
-		CommentIgnorePrefix:    b.CommentIgnorePrefix,
-		ExcludeImports:         b.ExcludeImports,
+		DefaultOptions:             options,
+		AllowCommentIgnores:        b.AllowCommentIgnores,
+		RequireCommentIgnoreReason: b.RequireCommentIgnoreReason,
+		IgnoreUnstablePackages:     b.IgnoreUnstablePackages,
+		CommentIgnorePrefix:        b.CommentIgnorePrefix,
+		ExcludeImports:             b.ExcludeImports,
 	}, nil
 }
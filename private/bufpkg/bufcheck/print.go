@@ -45,17 +45,18 @@ const (
 //
 // priority 1 should be printed before priority 2.
 var topLevelCategoryIDToPriority = map[string]int{
-	"MINIMAL":   1,
-	"BASIC":     2,
-	"STANDARD":  3,
-	"DEFAULT":   4,
-	"COMMENTS":  5,
-	"UNARY_RPC": 6,
-	"OTHER":     7,
-	"FILE":      1,
-	"PACKAGE":   2,
-	"WIRE_JSON": 3,
-	"WIRE":      4,
+	"MINIMAL":                   1,
+	"BASIC":                     2,
+	"STANDARD":                  3,
+	"DEFAULT":                   4,
+	"COMMENTS":                  5,
+	"UNARY_RPC":                 6,
+	"OTHER":                     7,
+	"FILE":                      1,
+	"PACKAGE":                   2,
+	"WIRE_JSON":                 3,
+	"WIRE":                      4,
+	"PROTOVALIDATE_CONSTRAINTS": 5,
 }
 
 func printRules(writer io.Writer, rules []Rule, options ...PrintRulesOption) (retErr error) {
@@ -514,6 +514,45 @@ type externalGeneratePluginConfigV2 struct {
 	IncludeWKT     bool `json:"include_wkt,omitempty" yaml:"include_wkt,omitempty"`
 	// Strategy is only valid with ProtoBuiltin and Local.
 	Strategy *string `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	// Types, ExcludeTypes, Paths and ExcludePaths filter the files and types that
+	// this plugin generates for.
+	Types        []string `json:"types,omitempty" yaml:"types,omitempty"`
+	ExcludeTypes []string `json:"exclude_types,omitempty" yaml:"exclude_types,omitempty"`
+	Paths        []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+	ExcludePaths []string `json:"exclude_paths,omitempty" yaml:"exclude_paths,omitempty"`
+	// Timeout is a duration such as "30s" or "5m".
+	Timeout        *string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	MaxParallelism int     `json:"max_parallelism,omitempty" yaml:"max_parallelism,omitempty"`
+	// Retries is only valid with Remote set.
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// PostProcess are the post-processing steps applied to the generated files, in order.
+	PostProcess []externalGeneratePostProcessConfigV2 `json:"post_process,omitempty" yaml:"post_process,omitempty"`
+}
+
+// externalGeneratePostProcessConfigV2 represents a post-processing step of a plugin
+// in a v2 buf.gen.yaml file.
+type externalGeneratePostProcessConfigV2 struct {
+	// Exactly one of LicenseHeader, RewritePath and Run is required.
+	LicenseHeader *externalGenerateLicenseHeaderConfigV2 `json:"license_header,omitempty" yaml:"license_header,omitempty"`
+	RewritePath   *externalGenerateRewritePathConfigV2   `json:"rewrite_path,omitempty" yaml:"rewrite_path,omitempty"`
+	// Run can be one string (the command) or multiple (remaining strings are arguments
+	// to the command).
+	Run any `json:"run,omitempty" yaml:"run,omitempty"`
+}
+
+// externalGenerateLicenseHeaderConfigV2 represents a license_header post-processing
+// step in a v2 buf.gen.yaml file.
+type externalGenerateLicenseHeaderConfigV2 struct {
+	LicenseType     string `json:"license_type,omitempty" yaml:"license_type,omitempty"`
+	CopyrightHolder string `json:"copyright_holder,omitempty" yaml:"copyright_holder,omitempty"`
+	YearRange       string `json:"year_range,omitempty" yaml:"year_range,omitempty"`
+}
+
+// externalGenerateRewritePathConfigV2 represents a rewrite_path post-processing
+// step in a v2 buf.gen.yaml file.
+type externalGenerateRewritePathConfigV2 struct {
+	Match   string `json:"match,omitempty" yaml:"match,omitempty"`
+	Replace string `json:"replace,omitempty" yaml:"replace,omitempty"`
 }
 
 // externalGenerateManagedConfigV2 represents the managed mode config in a v2 buf.gen.yaml file.
@@ -526,25 +565,31 @@ type externalGenerateManagedConfigV2 struct {
 // externalManagedDisableConfigV2 represents a disable rule in managed mode in a v2 buf.gen.yaml file.
 type externalManagedDisableConfigV2 struct {
 	// At least one field must be set.
-	// At most one of FileOption and FieldOption can be set
-	FileOption  string `json:"file_option,omitempty" yaml:"file_option,omitempty"`
-	FieldOption string `json:"field_option,omitempty" yaml:"field_option,omitempty"`
-	Module      string `json:"module,omitempty" yaml:"module,omitempty"`
+	// At most one of FileOption, FieldOption and MessageOption can be set
+	FileOption    string `json:"file_option,omitempty" yaml:"file_option,omitempty"`
+	FieldOption   string `json:"field_option,omitempty" yaml:"field_option,omitempty"`
+	MessageOption string `json:"message_option,omitempty" yaml:"message_option,omitempty"`
+	Module        string `json:"module,omitempty" yaml:"module,omitempty"`
 	// Path must be normalized.
 	Path string `json:"path,omitempty" yaml:"path,omitempty"`
-	// Field must not be set if FileOption is set.
+	// Message must not be set if FileOption or FieldOption is set.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Field must not be set if FileOption or MessageOption is set.
 	Field string `json:"field,omitempty" yaml:"field,omitempty"`
 }
 
 // externalManagedOverrideConfigV2 represents an override rule in managed mode in a v2 buf.gen.yaml file.
 type externalManagedOverrideConfigV2 struct {
-	// Exactly one of FileOpion and FieldOption must be set.
-	FileOption  string `json:"file_option,omitempty" yaml:"file_option,omitempty"`
-	FieldOption string `json:"field_option,omitempty" yaml:"field_option,omitempty"`
-	Module      string `json:"module,omitempty" yaml:"module,omitempty"`
+	// Exactly one of FileOpion, FieldOption and MessageOption must be set.
+	FileOption    string `json:"file_option,omitempty" yaml:"file_option,omitempty"`
+	FieldOption   string `json:"field_option,omitempty" yaml:"field_option,omitempty"`
+	MessageOption string `json:"message_option,omitempty" yaml:"message_option,omitempty"`
+	Module        string `json:"module,omitempty" yaml:"module,omitempty"`
 	// Path must be normalized.
 	Path string `json:"path,omitempty" yaml:"path,omitempty"`
-	// Field must not be set if FileOption is set.
+	// Message must not be set if FileOption or FieldOption is set.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Field must not be set if FileOption or MessageOption is set.
 	Field string `json:"field,omitempty" yaml:"field,omitempty"`
 	// Value is required
 	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
@@ -140,6 +140,10 @@ managed:
       field: foo.bar.Baz.field_name
       path: foo/v1
       field_option: jstype
+    - module: buf.build/acme/petapis
+      field_option: (acme.option.v1.field_option)
+    - message: foo.bar.Baz
+      message_option: (acme.option.v1.message_option)
   override:
     - file_option: java_package_prefix
       value: net
@@ -158,19 +162,53 @@ managed:
     - field_option: jstype
       value: JS_STRING
       field: package1.Message2.field3
+    - file_option: swift_prefix
+      value: ACM
+    - file_option: features.(pb.go).api_level
+      path: foo/bar/baz.proto
+      value: API_OPAQUE
+    - file_option: (.acme.option.v1.file_option).count
+      value: 3
+    - message_option: (acme.option.v1.message_option)
+      message: package1.Message2
+      value: true
+    - field_option: features.field_presence
+      field: package1.Message2.field3
+      value: EXPLICIT
 plugins:
   - remote: buf.build/protocolbuffers/go
     revision: 1
     out: gen/proto
+    timeout: 90s
+    retries: 3
   - protoc_builtin: cpp
     protoc_path: /path/to/protoc
     out: gen/proto
+    max_parallelism: 2
   - local: protoc-gen-validate
     out: gen/proto
   - local: path/to/protoc-gen-validate
     out: gen/proto
+    types:
+      - foo.v1
+    exclude_types:
+      - foo.v1.Internal
+    paths:
+      - ./foo/v1
+    exclude_paths:
+      - foo/v1/internal.proto
   - local: /usr/bin/path/to/protoc-gen-validate
     out: gen/proto2
+    post_process:
+      - license_header:
+          license_type: apache
+          copyright_holder: Acme, Inc.
+          year_range: 2024
+      - rewrite_path:
+          match: ^foo/v1/(.*)\.pb\.validate\.go$
+          replace: foov1/$1.validate.go
+      - run: ./scripts/format.sh
+      - run: ["npx", "prettier", "--write"]
   - local: ["go", "run", "google.golang.org/protobuf/cmd/protoc-gen-go"]
     out: gen/proto
     opt:
@@ -223,6 +261,10 @@ managed:
       module: buf.build/acme/petapis
       path: foo/v1
       field: foo.bar.Baz.field_name
+    - field_option: (acme.option.v1.field_option)
+      module: buf.build/acme/petapis
+    - message_option: (acme.option.v1.message_option)
+      message: foo.bar.Baz
   override:
     - file_option: java_package_prefix
       value: net
@@ -241,19 +283,56 @@ managed:
     - field_option: jstype
       field: package1.Message2.field3
       value: JS_STRING
+    - file_option: swift_prefix
+      value: ACM
+    - file_option: features.(pb.go).api_level
+      path: foo/bar/baz.proto
+      value: API_OPAQUE
+    - file_option: (acme.option.v1.file_option).count
+      value: 3
+    - message_option: (acme.option.v1.message_option)
+      message: package1.Message2
+      value: true
+    - field_option: features.field_presence
+      field: package1.Message2.field3
+      value: EXPLICIT
 plugins:
   - remote: buf.build/protocolbuffers/go
     revision: 1
     out: gen/proto
+    timeout: 1m30s
+    retries: 3
   - protoc_builtin: cpp
     protoc_path: /path/to/protoc
     out: gen/proto
+    max_parallelism: 2
   - local: protoc-gen-validate
     out: gen/proto
   - local: path/to/protoc-gen-validate
     out: gen/proto
+    types:
+      - foo.v1
+    exclude_types:
+      - foo.v1.Internal
+    paths:
+      - foo/v1
+    exclude_paths:
+      - foo/v1/internal.proto
   - local: /usr/bin/path/to/protoc-gen-validate
     out: gen/proto2
+    post_process:
+      - license_header:
+          license_type: apache
+          copyright_holder: Acme, Inc.
+          year_range: "2024"
+      - rewrite_path:
+          match: ^foo/v1/(.*)\.pb\.validate\.go$
+          replace: foov1/$1.validate.go
+      - run: ./scripts/format.sh
+      - run:
+          - npx
+          - prettier
+          - --write
   - local:
       - go
       - run
@@ -324,7 +403,7 @@ plugins:
     out: gen
 `),
 	)
-	require.ErrorContains(t, err, "must set file_option or field_option for an override")
+	require.ErrorContains(t, err, "must set file_option, field_option or message_option for an override")
 
 	_, err = ReadBufGenYAMLFile(
 		strings.NewReader(`version: v2
@@ -339,7 +418,7 @@ plugins:
     out: gen
 `),
 	)
-	require.ErrorContains(t, err, "exactly one of file_option and field_option must be set for an override")
+	require.ErrorContains(t, err, "exactly one of file_option, field_option and message_option must be set for an override")
 
 	_, err = ReadBufGenYAMLFile(
 		strings.NewReader(`version: v2
@@ -367,6 +446,75 @@ plugins:
 `),
 	)
 	require.ErrorContains(t, err, "at most one of file_option and field_option can be specified")
+
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+managed:
+  enabled: true
+  override:
+    - file_option: (acme.option.v1.file_option
+      value: "Override"
+plugins:
+  - local: protoc-gen-csharp
+    out: gen
+`),
+	)
+	require.ErrorContains(t, err, `invalid file_option "(acme.option.v1.file_option": missing closing parenthesis`)
+
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+managed:
+  enabled: true
+  override:
+    - message_option: deprecated
+      value: true
+plugins:
+  - local: protoc-gen-csharp
+    out: gen
+`),
+	)
+	require.ErrorContains(t, err, `invalid message_option "deprecated": must start with "features" or an extension name in parentheses`)
+
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+managed:
+  enabled: true
+  override:
+    - message_option: (acme.option.v1.message_option)
+      field: a.v1.Foo.bar
+      value: true
+plugins:
+  - local: protoc-gen-csharp
+    out: gen
+`),
+	)
+	require.ErrorContains(t, err, "must not set field for a message_option override")
+
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+managed:
+  enabled: true
+  override:
+    - field_option: (acme.option.v1.field_option)
+      value: [1, 2]
+plugins:
+  - local: protoc-gen-csharp
+    out: gen
+`),
+	)
+	require.ErrorContains(t, err, "must be a string, bool or number")
+
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+managed:
+  disable:
+    - message: a.v1.Foo
+      file_option: java_package
+plugins:
+  - local: protoc-gen-csharp
+`),
+	)
+	require.ErrorContains(t, err, "cannot disable a file option or field option for a message")
 }
 
 func TestBufGenYAMLFilePluginConfigErrors(t *testing.T) {
@@ -433,6 +581,120 @@ plugins:
 `),
 	)
 	require.ErrorContains(t, err, "cannot specify strategy for remote plugin")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    paths:
+      - ../foo
+`),
+	)
+	require.ErrorContains(t, err, `invalid plugin path "../foo"`)
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    retries: 1
+`),
+	)
+	require.ErrorContains(t, err, "cannot specify retries for local plugin")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - protoc_builtin: cpp
+    out: .
+    retries: 1
+`),
+	)
+	require.ErrorContains(t, err, "cannot specify retries for protoc built-in plugin")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    timeout: 30
+`),
+	)
+	require.ErrorContains(t, err, "invalid timeout")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    timeout: 0s
+`),
+	)
+	require.ErrorContains(t, err, "timeout must be positive")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    max_parallelism: -1
+`),
+	)
+	require.ErrorContains(t, err, "max_parallelism must not be negative")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    post_process:
+      - run: gofmt
+        rewrite_path:
+          match: foo
+`),
+	)
+	require.ErrorContains(t, err, "exactly one of license_header, rewrite_path and run must be set")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    post_process:
+      - license_header:
+          license_type: mit
+          copyright_holder: Acme, Inc.
+          year_range: 2024
+`),
+	)
+	require.ErrorContains(t, err, `unknown LicenseType: "mit"`)
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    post_process:
+      - license_header:
+          license_type: apache
+          year_range: 2024
+`),
+	)
+	require.ErrorContains(t, err, "must specify copyright_holder for license_header")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    post_process:
+      - rewrite_path:
+          match: "foo("
+          replace: bar
+`),
+	)
+	require.ErrorContains(t, err, "invalid match for rewrite_path")
+	_, err = ReadBufGenYAMLFile(
+		strings.NewReader(`version: v2
+plugins:
+  - local: protoc-gen-go
+    out: .
+    post_process:
+      - run: []
+`),
+	)
+	require.ErrorContains(t, err, "must specify a command for run")
 
 	_, err = ReadBufGenYAMLFile(
 		strings.NewReader(`version: v2
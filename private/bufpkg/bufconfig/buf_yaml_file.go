@@ -25,6 +25,7 @@ import (
 	"path/filepath"
 	"sort"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufparse"
 	"github.com/bufbuild/buf/private/pkg/encoding"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
@@ -111,6 +112,22 @@ type BufYAMLFile interface {
 	// The ModuleRefs in this list will be unique by FullName.
 	// Sorted by FullName.
 	ConfiguredDepModuleRefs() []bufparse.Ref
+	// TrustedSigningKeys returns the PublicKeys that the Signatures of dependencies
+	// are verified with.
+	//
+	// If empty, Signatures are not verified.
+	// For v1beta1 and v1 buf.yaml files, this will always return nil.
+	TrustedSigningKeys() []bufmodule.PublicKey
+	// ModuleRegistry returns the module registry that dependencies are resolved from
+	// and modules are pushed to.
+	//
+	// This is in the same form as the BUF_MODULE_REGISTRY environment variable, that is
+	// one of file://path, oci://host/path, or oci+http://host/path. Relative file paths
+	// are relative to the directory of the buf.yaml file.
+	//
+	// If empty, the BSR is used.
+	// For v1beta1 and v1 buf.yaml files, this will always return empty.
+	ModuleRegistry() string
 	//IncludeDocsLink specifies whether a top-level comment with a link to our public docs
 	// should be included at the top of the buf.yaml file.
 	IncludeDocsLink() bool
@@ -140,6 +157,8 @@ func NewBufYAMLFile(
 		nil, // Do not set top-level breaking config, use only module configs
 		pluginConfigs,
 		configuredDepModuleRefs,
+		nil,
+		"",
 		bufYAMLFileOptions.includeDocsLink,
 	)
 }
@@ -254,6 +273,8 @@ type bufYAMLFile struct {
 	topLevelBreakingConfig  BreakingConfig
 	pluginConfigs           []PluginConfig
 	configuredDepModuleRefs []bufparse.Ref
+	trustedSigningKeys      []bufmodule.PublicKey
+	moduleRegistry          string
 	includeDocsLink         bool
 }
 
@@ -265,6 +286,8 @@ func newBufYAMLFile(
 	topLevelBreakingConfig BreakingConfig,
 	pluginConfigs []PluginConfig,
 	configuredDepModuleRefs []bufparse.Ref,
+	trustedSigningKeys []bufmodule.PublicKey,
+	moduleRegistry string,
 	includeDocsLink bool,
 ) (*bufYAMLFile, error) {
 	if (fileVersion == FileVersionV1Beta1 || fileVersion == FileVersionV1) && len(moduleConfigs) > 1 {
@@ -320,6 +343,8 @@ func newBufYAMLFile(
 		topLevelBreakingConfig:  topLevelBreakingConfig,
 		pluginConfigs:           pluginConfigs,
 		configuredDepModuleRefs: configuredDepModuleRefs,
+		trustedSigningKeys:      trustedSigningKeys,
+		moduleRegistry:          moduleRegistry,
 		includeDocsLink:         includeDocsLink,
 	}, nil
 }
@@ -356,6 +381,14 @@ func (c *bufYAMLFile) ConfiguredDepModuleRefs() []bufparse.Ref {
 	return slicesext.Copy(c.configuredDepModuleRefs)
 }
 
+func (c *bufYAMLFile) TrustedSigningKeys() []bufmodule.PublicKey {
+	return slicesext.Copy(c.trustedSigningKeys)
+}
+
+func (c *bufYAMLFile) ModuleRegistry() string {
+	return c.moduleRegistry
+}
+
 func (c *bufYAMLFile) IncludeDocsLink() bool {
 	return c.includeDocsLink
 }
@@ -455,6 +488,8 @@ func readBufYAMLFile(
 			breakingConfig,
 			nil,
 			configuredDepModuleRefs,
+			nil,
+			"",
 			includeDocsLink,
 		)
 	case FileVersionV2:
@@ -654,6 +689,19 @@ func readBufYAMLFile(
 		if err != nil {
 			return nil, err
 		}
+		trustedSigningKeys, err := slicesext.MapError(
+			externalBufYAMLFile.TrustedSigningKeys,
+			func(externalTrustedSigningKey string) (bufmodule.PublicKey, error) {
+				publicKey, err := bufmodule.ParsePublicKey(externalTrustedSigningKey)
+				if err != nil {
+					return nil, fmt.Errorf("invalid trusted_signing_keys: %w", err)
+				}
+				return publicKey, nil
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
 		return newBufYAMLFile(
 			fileVersion,
 			objectData,
@@ -662,6 +710,8 @@ func readBufYAMLFile(
 			topLevelBreakingConfig,
 			pluginConfigs,
 			configuredDepModuleRefs,
+			trustedSigningKeys,
+			externalBufYAMLFile.Registry,
 			includeDocsLink,
 		)
 	default:
@@ -765,6 +815,11 @@ func writeBufYAMLFile(writer io.Writer, bufYAMLFile BufYAMLFile) error {
 				return moduleRef.String()
 			},
 		)
+		externalBufYAMLFile.TrustedSigningKeys = slicesext.Map(
+			bufYAMLFile.TrustedSigningKeys(),
+			bufmodule.PublicKey.String,
+		)
+		externalBufYAMLFile.Registry = bufYAMLFile.ModuleRegistry()
 		// Keep maps of the JSON-marshaled data to the external lint and breaking configs.
 		//
 		// If both of these maps are of length 0 or 1, we say that the user really just has a
@@ -1023,6 +1078,7 @@ func getLintConfigForExternalLintV1Beta1V1(
 		externalLint.RPCAllowGoogleProtobufEmptyResponses,
 		externalLint.ServiceSuffix,
 		externalLint.AllowCommentIgnores,
+		false,
 	), nil
 }
 
@@ -1074,6 +1130,7 @@ func getLintConfigForExternalLintV2(
 		externalLint.RPCAllowGoogleProtobufEmptyResponses,
 		externalLint.ServiceSuffix,
 		!externalLint.DisallowCommentIgnores,
+		externalLint.RequireCommentIgnoreReason,
 	), nil
 }
 
@@ -1232,6 +1289,7 @@ func getExternalLintV2ForLintConfig(lintConfig LintConfig, moduleDirPath string)
 	externalLint.RPCAllowGoogleProtobufEmptyResponses = lintConfig.RPCAllowGoogleProtobufEmptyResponses()
 	externalLint.ServiceSuffix = lintConfig.ServiceSuffix()
 	externalLint.DisallowCommentIgnores = !lintConfig.AllowCommentIgnores()
+	externalLint.RequireCommentIgnoreReason = lintConfig.RequireCommentIgnoreReason()
 	externalLint.DisableBuiltin = lintConfig.DisableBuiltin()
 	return externalLint
 }
@@ -1280,6 +1338,10 @@ type externalBufYAMLFileV2 struct {
 	Lint     externalBufYAMLFileLintV2              `json:"lint,omitempty" yaml:"lint,omitempty"`
 	Breaking externalBufYAMLFileBreakingV1Beta1V1V2 `json:"breaking,omitempty" yaml:"breaking,omitempty"`
 	Plugins  []externalBufYAMLFilePluginV2          `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	// TrustedSigningKeys are in the form of bufmodule.PublicKey.String().
+	TrustedSigningKeys []string `json:"trusted_signing_keys,omitempty" yaml:"trusted_signing_keys,omitempty"`
+	// Registry is in the same form as the BUF_MODULE_REGISTRY environment variable.
+	Registry string `json:"registry,omitempty" yaml:"registry,omitempty"`
 }
 
 // externalBufYAMLFileModuleV2 represents a single module configuation within a v2 buf.yaml file.
@@ -1355,6 +1417,7 @@ type externalBufYAMLFileLintV2 struct {
 	RPCAllowGoogleProtobufEmptyResponses bool                `json:"rpc_allow_google_protobuf_empty_responses,omitempty" yaml:"rpc_allow_google_protobuf_empty_responses,omitempty"`
 	ServiceSuffix                        string              `json:"service_suffix,omitempty" yaml:"service_suffix,omitempty"`
 	DisallowCommentIgnores               bool                `json:"disallow_comment_ignores,omitempty" yaml:"disallow_comment_ignores,omitempty"`
+	RequireCommentIgnoreReason           bool                `json:"require_comment_ignore_reason,omitempty" yaml:"require_comment_ignore_reason,omitempty"`
 	DisableBuiltin                       bool                `json:"disable_builtin,omitempty" yaml:"disable_builtin,omitempty"`
 }
 
@@ -1369,6 +1432,7 @@ func (el externalBufYAMLFileLintV2) isEmpty() bool {
 		!el.RPCAllowGoogleProtobufEmptyResponses &&
 		el.ServiceSuffix == "" &&
 		!el.DisallowCommentIgnores &&
+		!el.RequireCommentIgnoreReason &&
 		!el.DisableBuiltin
 }
 
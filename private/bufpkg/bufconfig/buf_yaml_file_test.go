@@ -480,6 +480,47 @@ modules:
       - proto/foo
 `,
 	)
+
+	testReadWriteBufYAMLFileRoundTrip(
+		t,
+		// input
+		`version: v2
+deps:
+  - buf.build/acme/weather
+trusted_signing_keys:
+  - ed25519:Y4+UyQIDi2FBp5To0rudkXNvt/7I5nDlQGkXW8WLk6g=
+`,
+		// expected output
+		`version: v2
+deps:
+  - buf.build/acme/weather
+trusted_signing_keys:
+  - ed25519:Y4+UyQIDi2FBp5To0rudkXNvt/7I5nDlQGkXW8WLk6g=
+`,
+	)
+	testReadWriteBufYAMLFileRoundTrip(
+		t,
+		// input
+		`version: v2
+deps:
+  - buf.build/acme/weather
+registry: file://../registry
+`,
+		// expected output
+		`version: v2
+deps:
+  - buf.build/acme/weather
+registry: file://../registry
+`,
+	)
+	testReadBufYAMLFileFail(
+		t,
+		`version: v2
+trusted_signing_keys:
+  - ed25519:AAAA
+`,
+		"invalid trusted_signing_keys",
+	)
 }
 
 func TestBufYAMLFileLintDisabled(t *testing.T) {
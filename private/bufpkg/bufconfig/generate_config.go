@@ -22,8 +22,8 @@ import (
 
 // GenerateConfig is a generation configuration.
 type GenerateConfig interface {
-	// CleanPluginOuts is whether to delete the output directories, zip files, or jar files before
-	// generation is run.
+	// CleanPluginOuts is whether to delete the zip files or jar files before generation is run,
+	// and the files that are no longer generated from the output directories after generation is run.
 	CleanPluginOuts() bool
 	// GeneratePluginConfigs returns the plugin configurations. This will always be
 	// non-empty. Zero plugin configs will cause an error at construction time.
@@ -58,8 +58,8 @@ func NewGenerateManagedConfig(
 //     it means for all files/fields the specified options are not modified.
 //
 // A ManagedDisableRule is guaranteed to specify at least one of the two aspects.
-// i.e. At least one of Path, FullName, FieldName, FileOption and
-// FieldOption is not empty. A rule can disable all options for certain files/fields,
+// i.e. At least one of Path, FullName, MessageName, FieldName, FileOption,
+// FieldOption and CustomOption is not empty. A rule can disable all options for certain files/fields,
 // disable certains options for all files/fields, or disable certain options for
 // certain files/fields. To disable all options for all files/fields, turn off managed mode.
 type ManagedDisableRule interface {
@@ -76,6 +76,13 @@ type ManagedDisableRule interface {
 	FileOption() FileOption
 	// FieldOption returns the field option to disalbe managed mode for.
 	FieldOption() FieldOption
+	// MessageName returns the fully qualified name for the message to disable
+	// managed mode for. This is only set for message options, and is guaranteed
+	// to be empty if FileOption, FieldOption or FieldName is not empty.
+	MessageName() string
+	// CustomOption returns the custom option to disable managed mode for. This
+	// is guaranteed to be nil if FileOption or FieldOption is not empty.
+	CustomOption() CustomOption
 
 	isManagedDisableRule()
 }
@@ -91,15 +98,44 @@ func NewManagedDisableRule(
 	return newManagedDisableRule(
 		path,
 		moduleFullName,
+		"",
 		fieldName,
 		fileOption,
 		fieldOption,
+		nil,
+	)
+}
+
+// NewManagedDisableRuleForCustomOption returns a new ManagedDisableRule for a
+// custom option.
+//
+// The name is the fully qualified name of the message or field to disable the
+// option for, depending on the target of the custom option. It must be empty
+// for file options.
+func NewManagedDisableRuleForCustomOption(
+	path string,
+	moduleFullName string,
+	name string,
+	customOption CustomOption,
+) (ManagedDisableRule, error) {
+	messageName, fieldName, err := getMessageAndFieldNameForCustomOption(customOption, name)
+	if err != nil {
+		return nil, err
+	}
+	return newManagedDisableRule(
+		path,
+		moduleFullName,
+		messageName,
+		fieldName,
+		FileOptionUnspecified,
+		FieldOptionUnspecified,
+		customOption,
 	)
 }
 
 // ManagedOverrideRule is an override rule. An override describes:
 //
-//   - The options to modify. Exactly one of FileOption and FieldOption is not empty.
+//   - The options to modify. Exactly one of FileOption, FieldOption and CustomOption is not empty.
 //   - The value to modify these options with.
 //   - The files/fields for which the options are modified. If all of Path, FullName
 //   - or FieldName are empty, all files/fields are modified. Otherwise, only
@@ -119,7 +155,15 @@ type ManagedOverrideRule interface {
 	FileOption() FileOption
 	// FieldOption returns the field option to disable managed mode for.
 	FieldOption() FieldOption
+	// MessageName is the fully qualified name for the message to modify. This
+	// is only set for message options.
+	MessageName() string
+	// CustomOption returns the custom option to modify.
+	CustomOption() CustomOption
 	// Value returns the override value.
+	//
+	// For custom options, this is a string, bool or number, and is only validated
+	// against the type of the option when it is applied.
 	Value() interface{}
 
 	isManagedOverrideRule()
@@ -157,6 +201,32 @@ func NewManagedOverrideRuleForFieldOption(
 	)
 }
 
+// NewManagedOverrideRuleForCustomOption returns a new ManagedOverrideRule for a
+// custom option.
+//
+// The name is the fully qualified name of the message or field to modify,
+// depending on the target of the custom option. It must be empty for file options.
+func NewManagedOverrideRuleForCustomOption(
+	path string,
+	moduleFullName string,
+	name string,
+	customOption CustomOption,
+	value interface{},
+) (ManagedOverrideRule, error) {
+	messageName, fieldName, err := getMessageAndFieldNameForCustomOption(customOption, name)
+	if err != nil {
+		return nil, err
+	}
+	return newCustomOptionManagedOverrideRule(
+		path,
+		moduleFullName,
+		messageName,
+		fieldName,
+		customOption,
+		value,
+	)
+}
+
 // *** PRIVATE ***
 
 type generateManagedConfig struct {
@@ -406,18 +476,32 @@ func newGenerateManagedConfigFromExternalV2(
 	var overrides []ManagedOverrideRule
 	for _, externalDisableConfig := range externalConfig.Disable {
 		var (
-			fileOption  FileOption
-			fieldOption FieldOption
-			err         error
+			fileOption   FileOption
+			fieldOption  FieldOption
+			customOption CustomOption
+			err          error
 		)
+		if externalDisableConfig.FileOption != "" && externalDisableConfig.MessageOption != "" ||
+			externalDisableConfig.FieldOption != "" && externalDisableConfig.MessageOption != "" {
+			return nil, errors.New("at most one of file_option, field_option and message_option can be specified")
+		}
 		if externalDisableConfig.FileOption != "" {
-			fileOption, err = parseFileOption(externalDisableConfig.FileOption)
+			fileOption, customOption, err = parseFileOptionOrCustomOption(externalDisableConfig.FileOption)
 			if err != nil {
 				return nil, err
 			}
 		}
 		if externalDisableConfig.FieldOption != "" {
-			fieldOption, err = parseFieldOption(externalDisableConfig.FieldOption)
+			if customOption != nil {
+				return nil, errors.New("at most one of file_option and field_option can be specified")
+			}
+			fieldOption, customOption, err = parseFieldOptionOrCustomOption(externalDisableConfig.FieldOption)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if externalDisableConfig.MessageOption != "" {
+			customOption, err = newCustomOption(CustomOptionTargetMessage, externalDisableConfig.MessageOption)
 			if err != nil {
 				return nil, err
 			}
@@ -425,9 +509,11 @@ func newGenerateManagedConfigFromExternalV2(
 		disable, err := newManagedDisableRule(
 			externalDisableConfig.Path,
 			externalDisableConfig.Module,
+			externalDisableConfig.Message,
 			externalDisableConfig.Field,
 			fileOption,
 			fieldOption,
+			customOption,
 		)
 		if err != nil {
 			return nil, err
@@ -435,20 +521,70 @@ func newGenerateManagedConfigFromExternalV2(
 		disables = append(disables, disable)
 	}
 	for _, externalOverrideConfig := range externalConfig.Override {
-		if externalOverrideConfig.FileOption == "" && externalOverrideConfig.FieldOption == "" {
-			return nil, errors.New("must set file_option or field_option for an override")
+		var setOptionCount int
+		for _, optionName := range []string{
+			externalOverrideConfig.FileOption,
+			externalOverrideConfig.FieldOption,
+			externalOverrideConfig.MessageOption,
+		} {
+			if optionName != "" {
+				setOptionCount++
+			}
+		}
+		if setOptionCount == 0 {
+			return nil, errors.New("must set file_option, field_option or message_option for an override")
 		}
-		if externalOverrideConfig.FileOption != "" && externalOverrideConfig.FieldOption != "" {
-			return nil, errors.New("exactly one of file_option and field_option must be set for an override")
+		if setOptionCount > 1 {
+			return nil, errors.New("exactly one of file_option, field_option and message_option must be set for an override")
 		}
 		if externalOverrideConfig.Value == nil {
 			return nil, errors.New("must set value for an override")
 		}
+		if externalOverrideConfig.Message != "" && externalOverrideConfig.MessageOption == "" {
+			return nil, errors.New("must not set message for a file_option or field_option override")
+		}
+		if externalOverrideConfig.MessageOption != "" {
+			if externalOverrideConfig.Field != "" {
+				return nil, errors.New("must not set field for a message_option override")
+			}
+			customOption, err := newCustomOption(CustomOptionTargetMessage, externalOverrideConfig.MessageOption)
+			if err != nil {
+				return nil, err
+			}
+			override, err := newCustomOptionManagedOverrideRule(
+				externalOverrideConfig.Path,
+				externalOverrideConfig.Module,
+				externalOverrideConfig.Message,
+				"",
+				customOption,
+				externalOverrideConfig.Value,
+			)
+			if err != nil {
+				return nil, err
+			}
+			overrides = append(overrides, override)
+			continue
+		}
 		if externalOverrideConfig.FieldOption != "" {
-			fieldOption, err := parseFieldOption(externalOverrideConfig.FieldOption)
+			fieldOption, customOption, err := parseFieldOptionOrCustomOption(externalOverrideConfig.FieldOption)
 			if err != nil {
 				return nil, err
 			}
+			if customOption != nil {
+				override, err := newCustomOptionManagedOverrideRule(
+					externalOverrideConfig.Path,
+					externalOverrideConfig.Module,
+					"",
+					externalOverrideConfig.Field,
+					customOption,
+					externalOverrideConfig.Value,
+				)
+				if err != nil {
+					return nil, err
+				}
+				overrides = append(overrides, override)
+				continue
+			}
 			override, err := NewManagedOverrideRuleForFieldOption(
 				externalOverrideConfig.Path,
 				externalOverrideConfig.Module,
@@ -465,10 +601,25 @@ func newGenerateManagedConfigFromExternalV2(
 		if externalOverrideConfig.Field != "" {
 			return nil, errors.New("must not set field for a file_option override")
 		}
-		fileOption, err := parseFileOption(externalOverrideConfig.FileOption)
+		fileOption, customOption, err := parseFileOptionOrCustomOption(externalOverrideConfig.FileOption)
 		if err != nil {
 			return nil, err
 		}
+		if customOption != nil {
+			override, err := newCustomOptionManagedOverrideRule(
+				externalOverrideConfig.Path,
+				externalOverrideConfig.Module,
+				"",
+				"",
+				customOption,
+				externalOverrideConfig.Value,
+			)
+			if err != nil {
+				return nil, err
+			}
+			overrides = append(overrides, override)
+			continue
+		}
 		override, err := NewManagedOverrideRuleForFileOption(
 			externalOverrideConfig.Path,
 			externalOverrideConfig.Module,
@@ -504,19 +655,24 @@ func (g *generateManagedConfig) isGenerateManagedConfig() {}
 type managedDisableRule struct {
 	path           string
 	moduleFullName string
+	messageName    string
 	fieldName      string
 	fileOption     FileOption
 	fieldOption    FieldOption
+	customOption   CustomOption
 }
 
 func newManagedDisableRule(
 	path string,
 	moduleFullName string,
+	messageName string,
 	fieldName string,
 	fileOption FileOption,
 	fieldOption FieldOption,
+	customOption CustomOption,
 ) (ManagedDisableRule, error) {
-	if path == "" && moduleFullName == "" && fieldName == "" && fileOption == FileOptionUnspecified && fieldOption == FieldOptionUnspecified {
+	if path == "" && moduleFullName == "" && messageName == "" && fieldName == "" &&
+		fileOption == FileOptionUnspecified && fieldOption == FieldOptionUnspecified && customOption == nil {
 		return nil, errors.New("empty disable rule is not allowed")
 	}
 	if fieldName != "" && fileOption != FileOptionUnspecified {
@@ -525,6 +681,21 @@ func newManagedDisableRule(
 	if fileOption != FileOptionUnspecified && fieldOption != FieldOptionUnspecified {
 		return nil, errors.New("at most one of file_option and field_option can be specified")
 	}
+	if customOption != nil && (fileOption != FileOptionUnspecified || fieldOption != FieldOptionUnspecified) {
+		return nil, errors.New("at most one of file_option, field_option and message_option can be specified")
+	}
+	if messageName != "" {
+		if fieldName != "" {
+			return nil, errors.New("at most one of message and field can be specified")
+		}
+		if fileOption != FileOptionUnspecified || fieldOption != FieldOptionUnspecified ||
+			(customOption != nil && customOption.Target() != CustomOptionTargetMessage) {
+			return nil, errors.New("cannot disable a file option or field option for a message")
+		}
+	}
+	if fieldName != "" && customOption != nil && customOption.Target() != CustomOptionTargetField {
+		return nil, fmt.Errorf("cannot disable a %v for a field", customOption.Target())
+	}
 	if path != "" {
 		if err := validatePath(path); err != nil {
 			return nil, fmt.Errorf("invalid path for disable rule: %w", err)
@@ -538,9 +709,11 @@ func newManagedDisableRule(
 	return &managedDisableRule{
 		path:           path,
 		moduleFullName: moduleFullName,
+		messageName:    messageName,
 		fieldName:      fieldName,
 		fileOption:     fileOption,
 		fieldOption:    fieldOption,
+		customOption:   customOption,
 	}, nil
 }
 
@@ -564,14 +737,24 @@ func (m *managedDisableRule) FieldOption() FieldOption {
 	return m.fieldOption
 }
 
+func (m *managedDisableRule) MessageName() string {
+	return m.messageName
+}
+
+func (m *managedDisableRule) CustomOption() CustomOption {
+	return m.customOption
+}
+
 func (m *managedDisableRule) isManagedDisableRule() {}
 
 type managedOverrideRule struct {
 	path           string
 	moduleFullName string
+	messageName    string
 	fieldName      string
 	fileOption     FileOption
 	fieldOption    FieldOption
+	customOption   CustomOption
 	value          interface{}
 }
 
@@ -649,6 +832,50 @@ func newFieldOptionManagedOverrideRule(
 	}, nil
 }
 
+func newCustomOptionManagedOverrideRule(
+	path string,
+	moduleFullName string,
+	messageName string,
+	fieldName string,
+	customOption CustomOption,
+	value interface{},
+) (ManagedOverrideRule, error) {
+	if customOption == nil {
+		return nil, errors.New("custom option must be specified for override")
+	}
+	if value == nil {
+		return nil, fmt.Errorf("value must be specified for override")
+	}
+	parsedValue, err := parseOverrideValueCustomOption(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %v for %s: %w", value, customOption.Name(), err)
+	}
+	if messageName != "" && customOption.Target() != CustomOptionTargetMessage {
+		return nil, fmt.Errorf("cannot set message for a %v override", customOption.Target())
+	}
+	if fieldName != "" && customOption.Target() != CustomOptionTargetField {
+		return nil, fmt.Errorf("cannot set field for a %v override", customOption.Target())
+	}
+	if moduleFullName != "" {
+		if _, err := bufparse.ParseFullName(moduleFullName); err != nil {
+			return nil, fmt.Errorf("invalid module name for %s override: %w", customOption.Name(), err)
+		}
+	}
+	if path != "" {
+		if err := validatePath(path); err != nil {
+			return nil, fmt.Errorf("invalid path for %s override: %w", customOption.Name(), err)
+		}
+	}
+	return &managedOverrideRule{
+		path:           path,
+		moduleFullName: moduleFullName,
+		messageName:    messageName,
+		fieldName:      fieldName,
+		customOption:   customOption,
+		value:          parsedValue,
+	}, nil
+}
+
 func (m *managedOverrideRule) Path() string {
 	return m.path
 }
@@ -669,6 +896,14 @@ func (m *managedOverrideRule) FieldOption() FieldOption {
 	return m.fieldOption
 }
 
+func (m *managedOverrideRule) MessageName() string {
+	return m.messageName
+}
+
+func (m *managedOverrideRule) CustomOption() CustomOption {
+	return m.customOption
+}
+
 func (m *managedOverrideRule) Value() interface{} {
 	return m.value
 }
@@ -698,8 +933,10 @@ func disablesAndOverridesFromExceptAndOverrideV1(
 			"",
 			exceptFullName,
 			"",
+			"",
 			exceptFileOption,
 			FieldOptionUnspecified,
+			nil,
 		)
 		if err != nil {
 			return nil, nil, err
@@ -786,14 +1023,20 @@ func newExternalManagedConfigV2FromGenerateManagedConfig(
 		if disable.FieldOption() != FieldOptionUnspecified {
 			fieldOptionName = disable.FieldOption().String()
 		}
+		var messageOptionName string
+		if customOption := disable.CustomOption(); customOption != nil {
+			fileOptionName, fieldOptionName, messageOptionName = getExternalOptionNamesForCustomOption(customOption)
+		}
 		externalDisables = append(
 			externalDisables,
 			externalManagedDisableConfigV2{
-				FileOption:  fileOptionName,
-				FieldOption: fieldOptionName,
-				Module:      disable.FullName(),
-				Path:        disable.Path(),
-				Field:       disable.FieldName(),
+				FileOption:    fileOptionName,
+				FieldOption:   fieldOptionName,
+				MessageOption: messageOptionName,
+				Module:        disable.FullName(),
+				Path:          disable.Path(),
+				Message:       disable.MessageName(),
+				Field:         disable.FieldName(),
 			},
 		)
 	}
@@ -807,19 +1050,28 @@ func newExternalManagedConfigV2FromGenerateManagedConfig(
 		if override.FieldOption() != FieldOptionUnspecified {
 			fieldOptionName = override.FieldOption().String()
 		}
-		value, err := getOverrideValue(fileOptionName, fieldOptionName, override.Value())
-		if err != nil {
-			return externalGenerateManagedConfigV2{}, err
+		var messageOptionName string
+		value := override.Value()
+		if customOption := override.CustomOption(); customOption != nil {
+			fileOptionName, fieldOptionName, messageOptionName = getExternalOptionNamesForCustomOption(customOption)
+		} else {
+			var err error
+			value, err = getOverrideValue(fileOptionName, fieldOptionName, override.Value())
+			if err != nil {
+				return externalGenerateManagedConfigV2{}, err
+			}
 		}
 		externalOverrides = append(
 			externalOverrides,
 			externalManagedOverrideConfigV2{
-				FileOption:  fileOptionName,
-				FieldOption: fieldOptionName,
-				Module:      override.FullName(),
-				Path:        override.Path(),
-				Field:       override.FieldName(),
-				Value:       value,
+				FileOption:    fileOptionName,
+				FieldOption:   fieldOptionName,
+				MessageOption: messageOptionName,
+				Module:        override.FullName(),
+				Path:          override.Path(),
+				Message:       override.MessageName(),
+				Field:         override.FieldName(),
+				Value:         value,
 			},
 		)
 	}
@@ -830,6 +1082,34 @@ func newExternalManagedConfigV2FromGenerateManagedConfig(
 	}, nil
 }
 
+func getMessageAndFieldNameForCustomOption(customOption CustomOption, name string) (string, string, error) {
+	if customOption == nil {
+		return "", "", errors.New("custom option must be specified")
+	}
+	switch target := customOption.Target(); target {
+	case CustomOptionTargetMessage:
+		return name, "", nil
+	case CustomOptionTargetField:
+		return "", name, nil
+	default:
+		if name != "" {
+			return "", "", fmt.Errorf("cannot set a name for a %v", target)
+		}
+		return "", "", nil
+	}
+}
+
+func getExternalOptionNamesForCustomOption(customOption CustomOption) (string, string, string) {
+	switch customOption.Target() {
+	case CustomOptionTargetField:
+		return "", customOption.Name(), ""
+	case CustomOptionTargetMessage:
+		return "", "", customOption.Name()
+	default:
+		return customOption.Name(), "", ""
+	}
+}
+
 func validatePath(path string) error {
 	normalizedPath, err := normalpath.NormalizeAndValidate(path)
 	if err != nil {
@@ -20,9 +20,12 @@ import (
 	"strconv"
 	"strings"
 
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
+const customOptionFeaturesName = "features"
+
 // FileOption is a file option.
 type FileOption int
 
@@ -65,6 +68,8 @@ const (
 	FileOptionRubyPackage
 	// FileOptionRubyPackageSuffix is the file option ruby_package_suffix.
 	FileOptionRubyPackageSuffix
+	// FileOptionSwiftPrefix is the file option swift_prefix.
+	FileOptionSwiftPrefix
 )
 
 // String implements fmt.Stringer.
@@ -95,8 +100,90 @@ func (f FieldOption) String() string {
 	return s
 }
 
+// CustomOptionTarget is the kind of descriptor that a CustomOption is set on.
+type CustomOptionTarget int
+
+const (
+	// CustomOptionTargetUnspecified is an unspecified custom option target.
+	CustomOptionTargetUnspecified CustomOptionTarget = iota
+	// CustomOptionTargetFile says that the option is a file option.
+	CustomOptionTargetFile
+	// CustomOptionTargetMessage says that the option is a message option.
+	CustomOptionTargetMessage
+	// CustomOptionTargetField says that the option is a field option.
+	CustomOptionTargetField
+)
+
+// String implements fmt.Stringer.
+func (c CustomOptionTarget) String() string {
+	s, ok := customOptionTargetToString[c]
+	if !ok {
+		return strconv.Itoa(int(c))
+	}
+	return s
+}
+
+// CustomOption is an option that is not one of the predefined FileOptions or
+// FieldOptions. It is referred to by its name as it would be written in a Protobuf
+// option, for example "features.(pb.go).api_level" or "(acme.option.v1.retention)".
+//
+// The name must either start with "features", in which case it refers to an
+// editions feature, or with an extension name in parentheses. Values are not
+// validated against the option's type until the option is applied to an image.
+type CustomOption interface {
+	// Target returns the kind of descriptor that the option is set on.
+	Target() CustomOptionTarget
+	// Name returns the name of the option, for example "features.(pb.go).api_level".
+	Name() string
+	// NameParts returns the parts of the name. Extension names are wrapped in
+	// parentheses, for example ["features", "(pb.go)", "api_level"].
+	NameParts() []string
+
+	isCustomOption()
+}
+
+// NewCustomOption returns a new CustomOption for the name.
+func NewCustomOption(target CustomOptionTarget, name string) (CustomOption, error) {
+	return newCustomOption(target, name)
+}
+
 // *** PRIVATE ***
 
+type customOption struct {
+	target    CustomOptionTarget
+	name      string
+	nameParts []string
+}
+
+func newCustomOption(target CustomOptionTarget, name string) (*customOption, error) {
+	if _, ok := customOptionTargetToString[target]; !ok {
+		return nil, fmt.Errorf("invalid custom option target: %v", target)
+	}
+	nameParts, err := parseCustomOptionName(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v %q: %w", target, name, err)
+	}
+	return &customOption{
+		target:    target,
+		name:      strings.Join(nameParts, "."),
+		nameParts: nameParts,
+	}, nil
+}
+
+func (c *customOption) Target() CustomOptionTarget {
+	return c.target
+}
+
+func (c *customOption) Name() string {
+	return c.name
+}
+
+func (c *customOption) NameParts() []string {
+	return c.nameParts
+}
+
+func (*customOption) isCustomOption() {}
+
 var (
 	fileOptionToString = map[FileOption]string{
 		FileOptionJavaPackage:                "java_package",
@@ -117,6 +204,7 @@ var (
 		FileOptionPhpMetadataNamespaceSuffix: "php_metadata_namespace_suffix",
 		FileOptionRubyPackage:                "ruby_package",
 		FileOptionRubyPackageSuffix:          "ruby_package_suffix",
+		FileOptionSwiftPrefix:                "swift_prefix",
 	}
 	stringToFileOption = map[string]FileOption{
 		"java_package":                  FileOptionJavaPackage,
@@ -137,6 +225,7 @@ var (
 		"php_metadata_namespace_suffix": FileOptionPhpMetadataNamespaceSuffix,
 		"ruby_package":                  FileOptionRubyPackage,
 		"ruby_package_suffix":           FileOptionRubyPackageSuffix,
+		"swift_prefix":                  FileOptionSwiftPrefix,
 	}
 	fileOptionToParseOverrideValueFunc = map[FileOption]func(interface{}) (interface{}, error){
 		FileOptionJavaPackage:                parseOverrideValue[string],
@@ -157,6 +246,7 @@ var (
 		FileOptionPhpMetadataNamespaceSuffix: parseOverrideValue[string],
 		FileOptionRubyPackage:                parseOverrideValue[string],
 		FileOptionRubyPackageSuffix:          parseOverrideValue[string],
+		FileOptionSwiftPrefix:                parseOverrideValue[string],
 	}
 	fieldOptionToString = map[FieldOption]string{
 		FieldOptionJSType: "jstype",
@@ -167,6 +257,11 @@ var (
 	fieldOptionToParseOverrideValueFunc = map[FieldOption]func(interface{}) (interface{}, error){
 		FieldOptionJSType: parseOverrideValueJSType,
 	}
+	customOptionTargetToString = map[CustomOptionTarget]string{
+		CustomOptionTargetFile:    "file_option",
+		CustomOptionTargetMessage: "message_option",
+		CustomOptionTargetField:   "field_option",
+	}
 )
 
 func parseFileOption(s string) (FileOption, error) {
@@ -193,6 +288,95 @@ func parseFieldOption(s string) (FieldOption, error) {
 	return 0, fmt.Errorf("unknown field_option: %q", s)
 }
 
+// parseFileOptionOrCustomOption parses either a predefined FileOption, or a
+// CustomOption if the name is for an editions feature or an extension.
+func parseFileOptionOrCustomOption(s string) (FileOption, CustomOption, error) {
+	if isCustomOptionName(s) {
+		customOption, err := newCustomOption(CustomOptionTargetFile, s)
+		if err != nil {
+			return 0, nil, err
+		}
+		return FileOptionUnspecified, customOption, nil
+	}
+	fileOption, err := parseFileOption(s)
+	if err != nil {
+		return 0, nil, err
+	}
+	return fileOption, nil, nil
+}
+
+// parseFieldOptionOrCustomOption parses either a predefined FieldOption, or a
+// CustomOption if the name is for an editions feature or an extension.
+func parseFieldOptionOrCustomOption(s string) (FieldOption, CustomOption, error) {
+	if isCustomOptionName(s) {
+		customOption, err := newCustomOption(CustomOptionTargetField, s)
+		if err != nil {
+			return 0, nil, err
+		}
+		return FieldOptionUnspecified, customOption, nil
+	}
+	fieldOption, err := parseFieldOption(s)
+	if err != nil {
+		return 0, nil, err
+	}
+	return fieldOption, nil, nil
+}
+
+func isCustomOptionName(s string) bool {
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "(") || s == customOptionFeaturesName || strings.HasPrefix(s, customOptionFeaturesName+".")
+}
+
+// parseCustomOptionName parses an option name such as "features.(pb.go).api_level"
+// into its parts.
+func parseCustomOptionName(name string) ([]string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("empty option name")
+	}
+	var nameParts []string
+	for remaining := name; ; {
+		var namePart string
+		if strings.HasPrefix(remaining, "(") {
+			end := strings.Index(remaining, ")")
+			if end < 0 {
+				return nil, errors.New("missing closing parenthesis")
+			}
+			extensionName := strings.TrimPrefix(remaining[1:end], ".")
+			if !protoreflect.FullName(extensionName).IsValid() {
+				return nil, fmt.Errorf("invalid extension name %q", remaining[1:end])
+			}
+			namePart = "(" + extensionName + ")"
+			remaining = remaining[end+1:]
+		} else {
+			end := strings.Index(remaining, ".")
+			if end < 0 {
+				end = len(remaining)
+			}
+			if !protoreflect.Name(remaining[:end]).IsValid() {
+				return nil, fmt.Errorf("invalid field name %q", remaining[:end])
+			}
+			namePart = remaining[:end]
+			remaining = remaining[end:]
+		}
+		nameParts = append(nameParts, namePart)
+		if remaining == "" {
+			break
+		}
+		if !strings.HasPrefix(remaining, ".") || len(remaining) == 1 {
+			return nil, fmt.Errorf("unexpected %q", remaining)
+		}
+		remaining = remaining[1:]
+	}
+	if !strings.HasPrefix(nameParts[0], "(") && nameParts[0] != customOptionFeaturesName {
+		return nil, fmt.Errorf("must start with %q or an extension name in parentheses", customOptionFeaturesName)
+	}
+	if len(nameParts) == 1 && nameParts[0] == customOptionFeaturesName {
+		return nil, fmt.Errorf("must set a field of %q", customOptionFeaturesName)
+	}
+	return nameParts, nil
+}
+
 func parseOverrideValue[T string | bool](overrideValue interface{}) (interface{}, error) {
 	parsedValue, ok := overrideValue.(T)
 	if !ok {
@@ -225,6 +409,15 @@ func parseOverrideValueJSType(override interface{}) (interface{}, error) {
 	return descriptorpb.FieldOptions_JSType(jsTypeEnum), nil
 }
 
+func parseOverrideValueCustomOption(overrideValue interface{}) (interface{}, error) {
+	switch overrideValue.(type) {
+	case string, bool, int, int64, uint64, float64:
+		return overrideValue, nil
+	default:
+		return nil, fmt.Errorf("must be a string, bool or number, got %T", overrideValue)
+	}
+}
+
 // If the file or field option override value is one of the supported enum types,
 // then we want to write out the string representation of the enum value, not
 // the corresponding int32.
@@ -258,7 +451,8 @@ func getOverrideValue(fileOptionName string, fieldOptionName string, value inter
 			FileOptionPhpMetadataNamespace,
 			FileOptionPhpMetadataNamespaceSuffix,
 			FileOptionRubyPackage,
-			FileOptionRubyPackageSuffix:
+			FileOptionRubyPackageSuffix,
+			FileOptionSwiftPrefix:
 			return value, nil
 
 		case FileOptionOptimizeFor:
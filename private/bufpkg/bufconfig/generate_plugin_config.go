@@ -20,9 +20,11 @@ import (
 	"math"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufremoteplugin/bufremotepluginref"
 	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 )
 
@@ -73,6 +75,12 @@ var (
 		"kotlin": {},
 		"rust":   {},
 	}
+	// BuiltinPluginNames are the names of the plugins that are built into buf, and that
+	// are used in the absence of a binary.
+	BuiltinPluginNames = map[string]struct{}{
+		"buf-jsonschema": {},
+		"buf-openapiv3":  {},
+	}
 )
 
 // GeneratePluginConfig is a configuration for a plugin.
@@ -112,6 +120,46 @@ type GeneratePluginConfig interface {
 	//
 	// This is not empty only when the plugin is remote.
 	Revision() int
+	// Types returns the types, or packages, to generate for with this plugin.
+	//
+	// This is applied after the types of the input. If empty, all types are generated for.
+	// This is always empty in v1.
+	Types() []string
+	// ExcludeTypes returns the types, or packages, to not generate for with this plugin.
+	//
+	// This is always empty in v1.
+	ExcludeTypes() []string
+	// Paths returns the paths, relative to the module root, of the files or directories
+	// to generate for with this plugin.
+	//
+	// This is applied after the paths of the input. If empty, all files are generated for.
+	// This is always empty in v1.
+	Paths() []string
+	// ExcludePaths returns the paths, relative to the module root, of the files or
+	// directories to not generate for with this plugin.
+	//
+	// This is always empty in v1.
+	ExcludePaths() []string
+	// Timeout returns the maximum duration of the execution of the plugin, including
+	// all invocations of the plugin for the strategy. If zero, there is no timeout.
+	//
+	// This is always zero in v1.
+	Timeout() time.Duration
+	// MaxParallelism returns the maximum number of invocations of the plugin that
+	// are executed at once. If zero, the default parallelism is used.
+	//
+	// This is always zero in v1.
+	MaxParallelism() int
+	// Retries returns the number of times to retry the execution of the plugin
+	// if it fails with a transient error.
+	//
+	// This is not zero only when the plugin is remote. This is always zero in v1.
+	Retries() int
+	// PostProcess returns the post-processing steps applied to the files generated
+	// by this plugin, in order.
+	//
+	// This is always empty in v1.
+	PostProcess() []GeneratePostProcessConfig
 
 	isGeneratePluginConfig()
 }
@@ -232,6 +280,14 @@ type generatePluginConfig struct {
 	protocPath               []string
 	remoteHost               string
 	revision                 int
+	types                    []string
+	excludeTypes             []string
+	paths                    []string
+	excludePaths             []string
+	timeout                  time.Duration
+	maxParallelism           int
+	retries                  int
+	postProcess              []GeneratePostProcessConfig
 }
 
 func newGeneratePluginConfigFromExternalV1Beta1(
@@ -400,6 +456,54 @@ func newGeneratePluginConfigFromExternalV2(
 	if err != nil {
 		return nil, err
 	}
+	paths, err := normalizeAndValidateGeneratePluginPaths(externalConfig.Paths)
+	if err != nil {
+		return nil, err
+	}
+	excludePaths, err := normalizeAndValidateGeneratePluginPaths(externalConfig.ExcludePaths)
+	if err != nil {
+		return nil, err
+	}
+	var timeout time.Duration
+	if externalConfig.Timeout != nil {
+		timeout, err = time.ParseDuration(*externalConfig.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeout must be positive, got %s", *externalConfig.Timeout)
+		}
+	}
+	if externalConfig.MaxParallelism < 0 {
+		return nil, fmt.Errorf("max_parallelism must not be negative, got %d", externalConfig.MaxParallelism)
+	}
+	if externalConfig.Retries < 0 {
+		return nil, fmt.Errorf("retries must not be negative, got %d", externalConfig.Retries)
+	}
+	postProcess, err := newGeneratePostProcessConfigsFromExternalV2(externalConfig.PostProcess)
+	if err != nil {
+		return nil, err
+	}
+	generatePluginConfig, err := newGeneratePluginConfigFromExternalV2Type(externalConfig, opt, parsedStrategy)
+	if err != nil {
+		return nil, err
+	}
+	generatePluginConfig.types = externalConfig.Types
+	generatePluginConfig.excludeTypes = externalConfig.ExcludeTypes
+	generatePluginConfig.paths = paths
+	generatePluginConfig.excludePaths = excludePaths
+	generatePluginConfig.timeout = timeout
+	generatePluginConfig.maxParallelism = externalConfig.MaxParallelism
+	generatePluginConfig.retries = externalConfig.Retries
+	generatePluginConfig.postProcess = postProcess
+	return generatePluginConfig, nil
+}
+
+func newGeneratePluginConfigFromExternalV2Type(
+	externalConfig externalGeneratePluginConfigV2,
+	opt []string,
+	parsedStrategy *GenerateStrategy,
+) (*generatePluginConfig, error) {
 	switch {
 	case externalConfig.Remote != nil:
 		var revision int
@@ -432,6 +536,9 @@ func newGeneratePluginConfigFromExternalV2(
 		if externalConfig.ProtocPath != nil {
 			return nil, fmt.Errorf("cannot specify protoc_path for local plugin %s", localPluginName)
 		}
+		if externalConfig.Retries != 0 {
+			return nil, fmt.Errorf("cannot specify retries for local plugin %s", localPluginName)
+		}
 		return newLocalGeneratePluginConfig(
 			strings.Join(path, " "),
 			externalConfig.Out,
@@ -449,6 +556,9 @@ func newGeneratePluginConfigFromExternalV2(
 		if externalConfig.Revision != nil {
 			return nil, fmt.Errorf("cannot specify revision for protoc built-in plugin %s", *externalConfig.ProtocBuiltin)
 		}
+		if externalConfig.Retries != 0 {
+			return nil, fmt.Errorf("cannot specify retries for protoc built-in plugin %s", *externalConfig.ProtocBuiltin)
+		}
 		return newProtocBuiltinGeneratePluginConfig(
 			*externalConfig.ProtocBuiltin,
 			externalConfig.Out,
@@ -613,6 +723,38 @@ func (p *generatePluginConfig) Revision() int {
 	return p.revision
 }
 
+func (p *generatePluginConfig) Types() []string {
+	return p.types
+}
+
+func (p *generatePluginConfig) ExcludeTypes() []string {
+	return p.excludeTypes
+}
+
+func (p *generatePluginConfig) Paths() []string {
+	return p.paths
+}
+
+func (p *generatePluginConfig) ExcludePaths() []string {
+	return p.excludePaths
+}
+
+func (p *generatePluginConfig) Timeout() time.Duration {
+	return p.timeout
+}
+
+func (p *generatePluginConfig) MaxParallelism() int {
+	return p.maxParallelism
+}
+
+func (p *generatePluginConfig) Retries() int {
+	return p.retries
+}
+
+func (p *generatePluginConfig) PostProcess() []GeneratePostProcessConfig {
+	return p.postProcess
+}
+
 func (p *generatePluginConfig) isGeneratePluginConfig() {}
 
 func newExternalGeneratePluginConfigV2FromPluginConfig(
@@ -626,7 +768,21 @@ func newExternalGeneratePluginConfigV2FromPluginConfig(
 		Out:            generatePluginConfig.Out(),
 		IncludeImports: generatePluginConfig.IncludeImports(),
 		IncludeWKT:     generatePluginConfig.IncludeWKT(),
+		Types:          generatePluginConfig.Types(),
+		ExcludeTypes:   generatePluginConfig.ExcludeTypes(),
+		Paths:          generatePluginConfig.Paths(),
+		ExcludePaths:   generatePluginConfig.ExcludePaths(),
+		MaxParallelism: generatePluginConfig.MaxParallelism(),
+		Retries:        generatePluginConfig.Retries(),
+	}
+	if timeout := generatePluginConfig.Timeout(); timeout != 0 {
+		externalPluginConfigV2.Timeout = toPointer(timeout.String())
+	}
+	postProcess, err := newExternalGeneratePostProcessConfigsV2FromPostProcessConfigs(generatePluginConfig.PostProcess())
+	if err != nil {
+		return externalGeneratePluginConfigV2{}, err
 	}
+	externalPluginConfigV2.PostProcess = postProcess
 	opts := generatePluginConfig.opts
 	switch {
 	case len(opts) == 1:
@@ -672,17 +828,39 @@ func newExternalGeneratePluginConfigV2FromPluginConfig(
 			externalPluginConfigV2.Local = binaryName
 			break
 		}
-		// If not, check if it is a protoc plugin.
+		// If not, check if it is a protoc or buf built-in plugin.
 		if _, isProtocBuiltin := ProtocProxyPluginNames[generatePluginConfig.Name()]; isProtocBuiltin {
 			externalPluginConfigV2.ProtocBuiltin = toPointer(generatePluginConfig.Name())
 			break
 		}
+		if _, isBuiltin := BuiltinPluginNames[generatePluginConfig.Name()]; isBuiltin {
+			externalPluginConfigV2.ProtocBuiltin = toPointer(generatePluginConfig.Name())
+			break
+		}
 		// Otherwise, assume this is a binary.
 		externalPluginConfigV2.Local = binaryName
 	}
 	return externalPluginConfigV2, nil
 }
 
+func normalizeAndValidateGeneratePluginPaths(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	normalizedPaths := make([]string, len(paths))
+	for i, path := range paths {
+		normalizedPath, err := normalpath.NormalizeAndValidate(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid plugin path %q: %w", path, err)
+		}
+		if normalizedPath == "." {
+			return nil, errors.New(`"." is not a valid plugin path`)
+		}
+		normalizedPaths[i] = normalizedPath
+	}
+	return normalizedPaths, nil
+}
+
 func parseStrategy(s string) (*GenerateStrategy, error) {
 	var strategy GenerateStrategy
 	switch s {
@@ -0,0 +1,318 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufconfig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/licenseheader"
+	"github.com/bufbuild/buf/private/pkg/syserror"
+)
+
+const (
+	// GeneratePostProcessTypeLicenseHeader is the post-process type that adds or
+	// replaces the license header of each generated file.
+	GeneratePostProcessTypeLicenseHeader GeneratePostProcessType = iota + 1
+	// GeneratePostProcessTypeRewritePath is the post-process type that rewrites the
+	// paths of the generated files.
+	GeneratePostProcessTypeRewritePath
+	// GeneratePostProcessTypeRun is the post-process type that runs a local command
+	// on the generated files.
+	GeneratePostProcessTypeRun
+)
+
+var (
+	generatePostProcessTypeToString = map[GeneratePostProcessType]string{
+		GeneratePostProcessTypeLicenseHeader: "license_header",
+		GeneratePostProcessTypeRewritePath:   "rewrite_path",
+		GeneratePostProcessTypeRun:           "run",
+	}
+)
+
+// GeneratePostProcessType is the type of a post-processing step.
+type GeneratePostProcessType int
+
+// String implements fmt.Stringer.
+func (g GeneratePostProcessType) String() string {
+	s, ok := generatePostProcessTypeToString[g]
+	if !ok {
+		return strconv.Itoa(int(g))
+	}
+	return s
+}
+
+// GeneratePostProcessConfig is a post-processing step applied to the files
+// generated by a plugin, before they are written to the output directory.
+//
+// Post-processing steps are applied in the order they are specified. Insertion
+// points are not post-processed.
+type GeneratePostProcessConfig interface {
+	// Type returns the post-process type. This is never the zero value.
+	Type() GeneratePostProcessType
+	// LicenseType returns the license type of the license header.
+	//
+	// This is not empty only when the type is license_header.
+	LicenseType() licenseheader.LicenseType
+	// CopyrightHolder returns the copyright holder of the license header.
+	//
+	// This is not empty only when the type is license_header.
+	CopyrightHolder() string
+	// YearRange returns the year range of the license header.
+	//
+	// This is not empty only when the type is license_header.
+	YearRange() string
+	// Match returns the regular expression matched against the paths of
+	// the generated files.
+	//
+	// This is not nil only when the type is rewrite_path.
+	Match() *regexp.Regexp
+	// Replace returns the replacement for the paths of the generated files
+	// that match. This may refer to capture groups of Match, as in
+	// regexp.Regexp.ReplaceAllString.
+	//
+	// This is only set when the type is rewrite_path.
+	Replace() string
+	// Run returns the command to run, including arguments. The paths of the
+	// generated files are appended as arguments.
+	//
+	// This is not empty only when the type is run.
+	Run() []string
+
+	isGeneratePostProcessConfig()
+}
+
+// NewGenerateLicenseHeaderPostProcessConfig returns a new GeneratePostProcessConfig
+// that adds or replaces the license header of each generated file.
+func NewGenerateLicenseHeaderPostProcessConfig(
+	licenseType licenseheader.LicenseType,
+	copyrightHolder string,
+	yearRange string,
+) (GeneratePostProcessConfig, error) {
+	return newGenerateLicenseHeaderPostProcessConfig(licenseType, copyrightHolder, yearRange)
+}
+
+// NewGenerateRewritePathPostProcessConfig returns a new GeneratePostProcessConfig
+// that rewrites the paths of the generated files that match the regular expression.
+func NewGenerateRewritePathPostProcessConfig(
+	match string,
+	replace string,
+) (GeneratePostProcessConfig, error) {
+	return newGenerateRewritePathPostProcessConfig(match, replace)
+}
+
+// NewGenerateRunPostProcessConfig returns a new GeneratePostProcessConfig that runs
+// a local command on the generated files.
+func NewGenerateRunPostProcessConfig(
+	run []string,
+) (GeneratePostProcessConfig, error) {
+	return newGenerateRunPostProcessConfig(run)
+}
+
+// *** PRIVATE ***
+
+type generatePostProcessConfig struct {
+	generatePostProcessType GeneratePostProcessType
+	licenseType             licenseheader.LicenseType
+	copyrightHolder         string
+	yearRange               string
+	match                   *regexp.Regexp
+	replace                 string
+	run                     []string
+}
+
+func newGeneratePostProcessConfigsFromExternalV2(
+	externalConfigs []externalGeneratePostProcessConfigV2,
+) ([]GeneratePostProcessConfig, error) {
+	if len(externalConfigs) == 0 {
+		return nil, nil
+	}
+	postProcessConfigs := make([]GeneratePostProcessConfig, len(externalConfigs))
+	for i, externalConfig := range externalConfigs {
+		postProcessConfig, err := newGeneratePostProcessConfigFromExternalV2(externalConfig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid post_process: %w", err)
+		}
+		postProcessConfigs[i] = postProcessConfig
+	}
+	return postProcessConfigs, nil
+}
+
+func newGeneratePostProcessConfigFromExternalV2(
+	externalConfig externalGeneratePostProcessConfigV2,
+) (GeneratePostProcessConfig, error) {
+	var postProcessTypeCount int
+	if externalConfig.LicenseHeader != nil {
+		postProcessTypeCount++
+	}
+	if externalConfig.RewritePath != nil {
+		postProcessTypeCount++
+	}
+	if externalConfig.Run != nil {
+		postProcessTypeCount++
+	}
+	if postProcessTypeCount != 1 {
+		return nil, errors.New("exactly one of license_header, rewrite_path and run must be set")
+	}
+	switch {
+	case externalConfig.LicenseHeader != nil:
+		if externalConfig.LicenseHeader.LicenseType == "" {
+			return nil, errors.New("must specify license_type for license_header")
+		}
+		licenseType, err := licenseheader.ParseLicenseType(externalConfig.LicenseHeader.LicenseType)
+		if err != nil {
+			return nil, err
+		}
+		return newGenerateLicenseHeaderPostProcessConfig(
+			licenseType,
+			externalConfig.LicenseHeader.CopyrightHolder,
+			externalConfig.LicenseHeader.YearRange,
+		)
+	case externalConfig.RewritePath != nil:
+		return newGenerateRewritePathPostProcessConfig(
+			externalConfig.RewritePath.Match,
+			externalConfig.RewritePath.Replace,
+		)
+	case externalConfig.Run != nil:
+		run, err := encoding.InterfaceSliceOrStringToStringSlice(externalConfig.Run)
+		if err != nil {
+			return nil, err
+		}
+		return newGenerateRunPostProcessConfig(run)
+	default:
+		return nil, syserror.New("must specify one of license_header, rewrite_path and run")
+	}
+}
+
+func newGenerateLicenseHeaderPostProcessConfig(
+	licenseType licenseheader.LicenseType,
+	copyrightHolder string,
+	yearRange string,
+) (*generatePostProcessConfig, error) {
+	if _, err := licenseheader.ParseLicenseType(licenseType.String()); err != nil {
+		return nil, err
+	}
+	if licenseType != licenseheader.LicenseTypeNone {
+		if copyrightHolder == "" {
+			return nil, errors.New("must specify copyright_holder for license_header")
+		}
+		if yearRange == "" {
+			return nil, errors.New("must specify year_range for license_header")
+		}
+	}
+	return &generatePostProcessConfig{
+		generatePostProcessType: GeneratePostProcessTypeLicenseHeader,
+		licenseType:             licenseType,
+		copyrightHolder:         copyrightHolder,
+		yearRange:               yearRange,
+	}, nil
+}
+
+func newGenerateRewritePathPostProcessConfig(
+	match string,
+	replace string,
+) (*generatePostProcessConfig, error) {
+	if match == "" {
+		return nil, errors.New("must specify match for rewrite_path")
+	}
+	matchRegexp, err := regexp.Compile(match)
+	if err != nil {
+		return nil, fmt.Errorf("invalid match for rewrite_path: %w", err)
+	}
+	return &generatePostProcessConfig{
+		generatePostProcessType: GeneratePostProcessTypeRewritePath,
+		match:                   matchRegexp,
+		replace:                 replace,
+	}, nil
+}
+
+func newGenerateRunPostProcessConfig(
+	run []string,
+) (*generatePostProcessConfig, error) {
+	if len(run) == 0 || run[0] == "" {
+		return nil, errors.New("must specify a command for run")
+	}
+	return &generatePostProcessConfig{
+		generatePostProcessType: GeneratePostProcessTypeRun,
+		run:                     run,
+	}, nil
+}
+
+func (g *generatePostProcessConfig) Type() GeneratePostProcessType {
+	return g.generatePostProcessType
+}
+
+func (g *generatePostProcessConfig) LicenseType() licenseheader.LicenseType {
+	return g.licenseType
+}
+
+func (g *generatePostProcessConfig) CopyrightHolder() string {
+	return g.copyrightHolder
+}
+
+func (g *generatePostProcessConfig) YearRange() string {
+	return g.yearRange
+}
+
+func (g *generatePostProcessConfig) Match() *regexp.Regexp {
+	return g.match
+}
+
+func (g *generatePostProcessConfig) Replace() string {
+	return g.replace
+}
+
+func (g *generatePostProcessConfig) Run() []string {
+	return g.run
+}
+
+func (g *generatePostProcessConfig) isGeneratePostProcessConfig() {}
+
+func newExternalGeneratePostProcessConfigsV2FromPostProcessConfigs(
+	postProcessConfigs []GeneratePostProcessConfig,
+) ([]externalGeneratePostProcessConfigV2, error) {
+	if len(postProcessConfigs) == 0 {
+		return nil, nil
+	}
+	externalConfigs := make([]externalGeneratePostProcessConfigV2, len(postProcessConfigs))
+	for i, postProcessConfig := range postProcessConfigs {
+		switch postProcessConfig.Type() {
+		case GeneratePostProcessTypeLicenseHeader:
+			externalConfigs[i].LicenseHeader = &externalGenerateLicenseHeaderConfigV2{
+				LicenseType:     postProcessConfig.LicenseType().String(),
+				CopyrightHolder: postProcessConfig.CopyrightHolder(),
+				YearRange:       postProcessConfig.YearRange(),
+			}
+		case GeneratePostProcessTypeRewritePath:
+			externalConfigs[i].RewritePath = &externalGenerateRewritePathConfigV2{
+				Match:   postProcessConfig.Match().String(),
+				Replace: postProcessConfig.Replace(),
+			}
+		case GeneratePostProcessTypeRun:
+			run := postProcessConfig.Run()
+			if len(run) == 1 {
+				externalConfigs[i].Run = run[0]
+			} else {
+				externalConfigs[i].Run = run
+			}
+		default:
+			return nil, syserror.Newf("unknown GeneratePostProcessType: %v", postProcessConfig.Type())
+		}
+	}
+	return externalConfigs, nil
+}
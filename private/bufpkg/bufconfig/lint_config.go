@@ -24,6 +24,7 @@ var (
 		false,
 		"",
 		false,
+		false,
 	)
 
 	// DefaultLintConfigV2 is the default lint config for v2.
@@ -35,6 +36,7 @@ var (
 		false,
 		"",
 		true, // We default to allowing comment ignores in v2
+		false,
 	)
 )
 
@@ -48,6 +50,16 @@ type LintConfig interface {
 	RPCAllowGoogleProtobufEmptyResponses() bool
 	ServiceSuffix() string
 	AllowCommentIgnores() bool
+	// RequireCommentIgnoreReason returns true if comment ignores must have a reason
+	// after the rule ID to be honored, for example:
+	//
+	//	// buf:lint:ignore FIELD_LOWER_SNAKE_CASE matches the legacy JSON API
+	//
+	// Comment ignores may also have an until date before the reason, after which they
+	// are no longer honored, regardless of this setting, for example:
+	//
+	//	// buf:lint:ignore FIELD_LOWER_SNAKE_CASE until=2025-01-31 removed in v2
+	RequireCommentIgnoreReason() bool
 
 	isLintConfig()
 }
@@ -61,6 +73,7 @@ func NewLintConfig(
 	rpcAllowGoogleProtobufEmptyResponses bool,
 	serviceSuffix string,
 	allowCommentIgnores bool,
+	requireCommentIgnoreReason bool,
 ) LintConfig {
 	return newLintConfig(
 		checkConfig,
@@ -70,6 +83,7 @@ func NewLintConfig(
 		rpcAllowGoogleProtobufEmptyResponses,
 		serviceSuffix,
 		allowCommentIgnores,
+		requireCommentIgnoreReason,
 	)
 }
 
@@ -84,6 +98,7 @@ type lintConfig struct {
 	rpcAllowGoogleProtobufEmptyResponses bool
 	serviceSuffix                        string
 	allowCommentIgnores                  bool
+	requireCommentIgnoreReason           bool
 }
 
 func newLintConfig(
@@ -94,6 +109,7 @@ func newLintConfig(
 	rpcAllowGoogleProtobufEmptyResponses bool,
 	serviceSuffix string,
 	allowCommentIgnores bool,
+	requireCommentIgnoreReason bool,
 ) *lintConfig {
 	return &lintConfig{
 		CheckConfig:                          checkConfig,
@@ -103,6 +119,7 @@ func newLintConfig(
 		rpcAllowGoogleProtobufEmptyResponses: rpcAllowGoogleProtobufEmptyResponses,
 		serviceSuffix:                        serviceSuffix,
 		allowCommentIgnores:                  allowCommentIgnores,
+		requireCommentIgnoreReason:           requireCommentIgnoreReason,
 	}
 }
 
@@ -130,4 +147,8 @@ func (l *lintConfig) AllowCommentIgnores() bool {
 	return l.allowCommentIgnores
 }
 
+func (l *lintConfig) RequireCommentIgnoreReason() bool {
+	return l.requireCommentIgnoreReason
+}
+
 func (*lintConfig) isLintConfig() {}
@@ -285,6 +285,7 @@ func BuildImage(
 		moduleReadBucket,
 		buildImageOptions.excludeSourceCodeInfo,
 		buildImageOptions.noParallelism,
+		buildImageOptions.buildCache,
 	)
 }
 
@@ -309,6 +310,29 @@ func WithNoParallelism() BuildImageOption {
 	}
 }
 
+// WithBuildCache returns a new BuildImageOption that reuses the parsed files of previous
+// builds with the same BuildCache.
+//
+// Files are only parsed again if their content changed. This is useful when the same
+// files are built repeatedly, such as when watching files for changes.
+func WithBuildCache(cache BuildCache) BuildImageOption {
+	return func(buildImageOptions *buildImageOptions) {
+		buildImageOptions.buildCache, _ = cache.(*buildCache)
+	}
+}
+
+// BuildCache caches the parsed files of builds across calls to BuildImage.
+//
+// A BuildCache is safe to use concurrently.
+type BuildCache interface {
+	isBuildCache()
+}
+
+// NewBuildCache returns a new BuildCache.
+func NewBuildCache() BuildCache {
+	return newBuildCache()
+}
+
 // CloneImage returns a deep copy of the given image.
 func CloneImage(image Image) (Image, error) {
 	originalFiles := image.Files()
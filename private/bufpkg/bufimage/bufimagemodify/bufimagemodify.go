@@ -15,10 +15,13 @@
 package bufimagemodify
 
 import (
+	"slices"
+
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagemodify/internal"
 	"github.com/bufbuild/buf/private/gen/data/datawkt"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
 )
 
 // Modify modifies the image according to the managed config.
@@ -45,7 +48,9 @@ func Modify(
 			modifyPhpMetadataNamespace,
 			modifyPhpNamespace,
 			modifyRubyPackage,
+			modifySwiftPrefix,
 			modifyJsType,
+			modifyCustomOptions,
 		},
 		options...,
 	)
@@ -163,6 +168,20 @@ func ModifyRubyPackage(
 	)
 }
 
+// ModifySwiftPrefix modifies the swift_prefix file option.
+func ModifySwiftPrefix(
+	image bufimage.Image,
+	config bufconfig.GenerateManagedConfig,
+	options ...ModifyOption,
+) error {
+	return modifyImageForSingleOption(
+		image,
+		config,
+		modifySwiftPrefix,
+		options...,
+	)
+}
+
 // ModifyCcEnableArenas modifies the cc_enable_arenas file option.
 func ModifyCcEnableArenas(
 	image bufimage.Image,
@@ -233,6 +252,21 @@ func ModifyJsType(
 	)
 }
 
+// ModifyCustomOptions modifies editions features and extension options on files,
+// messages and fields.
+func ModifyCustomOptions(
+	image bufimage.Image,
+	config bufconfig.GenerateManagedConfig,
+	options ...ModifyOption,
+) error {
+	return modifyImageForSingleOption(
+		image,
+		config,
+		modifyCustomOptions,
+		options...,
+	)
+}
+
 // ModifyOption is an option for Modify.
 type ModifyOption func(*modifyOptions)
 
@@ -249,6 +283,8 @@ func ModifyPreserveExisting() ModifyOption {
 
 type modifyOptions struct {
 	preserveExisting bool
+	// resolver is used to resolve the extensions referred to by custom options.
+	resolver protoencoding.Resolver
 }
 
 func newModifyOptions() *modifyOptions {
@@ -265,6 +301,12 @@ func modifyImage(
 		return nil
 	}
 	sweeper := internal.NewMarkSweeper(image)
+	options = append(
+		slices.Clip(options),
+		func(modifyOptions *modifyOptions) {
+			modifyOptions.resolver = image.Resolver()
+		},
+	)
 	for _, imageFile := range image.Files() {
 		if datawkt.Exists(imageFile.Path()) {
 			continue
@@ -17,6 +17,7 @@ package bufimagemodify
 import (
 	"context"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
@@ -26,11 +27,13 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
 	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
 	"github.com/bufbuild/buf/private/pkg/slogtestext"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -211,6 +214,34 @@ func TestModifyImageFile(
 				"bar_empty/without_package.proto": {ccEnableArenasPath},
 			},
 		},
+		{
+			description: "swift_prefix",
+			dirPathToFullName: map[string]string{
+				filepath.Join("testdata", "foo"): "buf.build/acme/foo",
+				filepath.Join("testdata", "bar"): "buf.build/acme/bar",
+			},
+			config: bufconfig.NewGenerateManagedConfig(
+				true,
+				[]bufconfig.ManagedDisableRule{
+					newTestManagedDisableRule(t, "bar_empty/with_package.proto", "", "", bufconfig.FileOptionSwiftPrefix, bufconfig.FieldOptionUnspecified),
+				},
+				[]bufconfig.ManagedOverrideRule{
+					newTestFileOptionOverrideRule(t, "", "buf.build/acme/bar", bufconfig.FileOptionSwiftPrefix, "BAR"),
+				},
+			),
+			modifyFunc: modifySwiftPrefix,
+			filePathToExpectedOptions: map[string]*descriptorpb.FileOptions{
+				// swift_prefix is not modified by default
+				"foo_empty/without_package.proto": nil,
+				"bar_empty/with_package.proto":    nil,
+				"bar_empty/without_package.proto": {
+					SwiftPrefix: proto.String("BAR"),
+				},
+			},
+			filePathToExpectedMarkedLocationPaths: map[string][][]int32{
+				"bar_empty/without_package.proto": {swiftPrefixPath},
+			},
+		},
 		{
 			description: "csharp_namespace",
 			dirPathToFullName: map[string]string{
@@ -583,6 +614,168 @@ func TestModifyImageFile(
 	}
 }
 
+func TestModifyImageCustomOptions(t *testing.T) {
+	t.Parallel()
+	image := testGetImageFromDirs(
+		t,
+		map[string]string{
+			filepath.Join("testdata", "customoptions"): "buf.build/acme/customoptions",
+		},
+		true,
+	)
+	config := bufconfig.NewGenerateManagedConfig(
+		true,
+		[]bufconfig.ManagedDisableRule{
+			newTestCustomOptionDisableRule(t, "", "a.v1.Foo.name", bufconfig.CustomOptionTargetField, ""),
+		},
+		[]bufconfig.ManagedOverrideRule{
+			newTestCustomOptionOverrideRule(t, "", "", bufconfig.CustomOptionTargetFile, "(acme.option.v1.file_option).name", "first"),
+			newTestCustomOptionOverrideRule(t, "", "", bufconfig.CustomOptionTargetFile, "(acme.option.v1.file_option).count", 3),
+			newTestCustomOptionOverrideRule(t, "b.proto", "", bufconfig.CustomOptionTargetFile, "(acme.option.v1.file_option).name", "overridden"),
+			newTestCustomOptionOverrideRule(t, "", "", bufconfig.CustomOptionTargetFile, "features.(pb.cpp).legacy_closed_enum", true),
+			newTestCustomOptionOverrideRule(t, "", "a.v1.Foo", bufconfig.CustomOptionTargetMessage, "(acme.option.v1.message_option)", true),
+			newTestCustomOptionOverrideRule(t, "", "", bufconfig.CustomOptionTargetField, "(acme.option.v1.level)", "LEVEL_HIGH"),
+			newTestCustomOptionOverrideRule(t, "", "a.v1.Foo.id", bufconfig.CustomOptionTargetField, "(acme.option.v1.field_option)", 7),
+			newTestCustomOptionOverrideRule(t, "", "", bufconfig.CustomOptionTargetField, "features.field_presence", "EXPLICIT"),
+		},
+	)
+	require.NoError(t, Modify(image, config))
+	aFileDescriptor := image.GetFile("a.proto").FileDescriptorProto()
+	testRequireCustomOptionValue(t, image, aFileDescriptor.GetOptions(), "(acme.option.v1.file_option).name", "first")
+	testRequireCustomOptionValue(t, image, aFileDescriptor.GetOptions(), "(acme.option.v1.file_option).count", int32(3))
+	testRequireCustomOptionValue(t, image, aFileDescriptor.GetOptions(), "features.(pb.cpp).legacy_closed_enum", true)
+	fooDescriptor := aFileDescriptor.GetMessageType()[0]
+	testRequireCustomOptionValue(t, image, fooDescriptor.GetOptions(), "(acme.option.v1.message_option)", true)
+	testRequireCustomOptionValue(t, image, fooDescriptor.GetNestedType()[0].GetOptions(), "(acme.option.v1.message_option)", nil)
+	testRequireCustomOptionValue(t, image, fooDescriptor.GetField()[0].GetOptions(), "(acme.option.v1.field_option)", uint32(7))
+	testRequireCustomOptionValue(t, image, fooDescriptor.GetField()[0].GetOptions(), "(acme.option.v1.level)", protoreflect.EnumNumber(1))
+	testRequireCustomOptionValue(t, image, fooDescriptor.GetField()[0].GetOptions(), "features.field_presence", protoreflect.EnumNumber(descriptorpb.FeatureSet_EXPLICIT))
+	// The name field is disabled.
+	require.Nil(t, fooDescriptor.GetField()[1].GetOptions())
+	testRequireCustomOptionValue(t, image, fooDescriptor.GetNestedType()[0].GetField()[0].GetOptions(), "(acme.option.v1.level)", protoreflect.EnumNumber(1))
+	// The locations of the modified file and field options are removed.
+	for _, location := range aFileDescriptor.GetSourceCodeInfo().GetLocation() {
+		require.NotEqual(t, []int32{8, 50000, 1}, location.GetPath())
+		require.NotEqual(t, []int32{4, 0, 2, 0, 8, 50000}, location.GetPath())
+	}
+	bFileDescriptor := image.GetFile("b.proto").FileDescriptorProto()
+	testRequireCustomOptionValue(t, image, bFileDescriptor.GetOptions(), "(acme.option.v1.file_option).name", "overridden")
+	// Features are not set in files that do not use editions.
+	testRequireCustomOptionValue(t, image, bFileDescriptor.GetOptions(), "features.(pb.cpp).legacy_closed_enum", nil)
+	bazDescriptor := bFileDescriptor.GetMessageType()[0]
+	require.Nil(t, bazDescriptor.GetOptions())
+	testRequireCustomOptionValue(t, image, bazDescriptor.GetField()[0].GetOptions(), "(acme.option.v1.level)", protoreflect.EnumNumber(1))
+	testRequireCustomOptionValue(t, image, bazDescriptor.GetField()[0].GetOptions(), "features.field_presence", nil)
+}
+
+func TestModifyImageCustomOptionsPreserveExisting(t *testing.T) {
+	t.Parallel()
+	image := testGetImageFromDirs(
+		t,
+		map[string]string{
+			filepath.Join("testdata", "customoptions"): "buf.build/acme/customoptions",
+		},
+		false,
+	)
+	config := bufconfig.NewGenerateManagedConfig(
+		true,
+		nil,
+		[]bufconfig.ManagedOverrideRule{
+			newTestCustomOptionOverrideRule(t, "", "", bufconfig.CustomOptionTargetFile, "(acme.option.v1.file_option).name", "overridden"),
+			newTestCustomOptionOverrideRule(t, "", "", bufconfig.CustomOptionTargetField, "(acme.option.v1.field_option)", 7),
+		},
+	)
+	require.NoError(t, ModifyCustomOptions(image, config, ModifyPreserveExisting()))
+	aFileDescriptor := image.GetFile("a.proto").FileDescriptorProto()
+	testRequireCustomOptionValue(t, image, aFileDescriptor.GetOptions(), "(acme.option.v1.file_option).name", "original")
+	fooDescriptor := aFileDescriptor.GetMessageType()[0]
+	testRequireCustomOptionValue(t, image, fooDescriptor.GetField()[0].GetOptions(), "(acme.option.v1.field_option)", uint32(1))
+	testRequireCustomOptionValue(t, image, fooDescriptor.GetField()[1].GetOptions(), "(acme.option.v1.field_option)", uint32(7))
+	testRequireCustomOptionValue(t, image, image.GetFile("b.proto").FileDescriptorProto().GetOptions(), "(acme.option.v1.file_option).name", "overridden")
+}
+
+func TestModifyImageCustomOptionsErrors(t *testing.T) {
+	t.Parallel()
+	testcases := []struct {
+		description   string
+		target        bufconfig.CustomOptionTarget
+		name          string
+		value         interface{}
+		expectedError string
+	}{
+		{
+			description:   "wrong_type",
+			target:        bufconfig.CustomOptionTargetFile,
+			name:          "(acme.option.v1.file_option).name",
+			value:         1,
+			expectedError: "invalid override for file_option (acme.option.v1.file_option).name on a.proto: expected a string, got int",
+		},
+		{
+			description:   "out_of_range",
+			target:        bufconfig.CustomOptionTargetField,
+			name:          "(acme.option.v1.field_option)",
+			value:         -1,
+			expectedError: "invalid override for field_option (acme.option.v1.field_option) on a.v1.Foo.id: -1 is out of range",
+		},
+		{
+			description:   "unknown_enum_value",
+			target:        bufconfig.CustomOptionTargetField,
+			name:          "(acme.option.v1.level)",
+			value:         "LEVEL_LOW",
+			expectedError: "must be one of LEVEL_UNSPECIFIED, LEVEL_HIGH",
+		},
+		{
+			description:   "message",
+			target:        bufconfig.CustomOptionTargetFile,
+			name:          "(acme.option.v1.file_option)",
+			value:         "name",
+			expectedError: "acme.option.v1.file_option is a message, set one of its fields instead",
+		},
+		{
+			description:   "unknown_extension",
+			target:        bufconfig.CustomOptionTargetFile,
+			name:          "(acme.option.v1.unknown)",
+			value:         "value",
+			expectedError: "extension acme.option.v1.unknown not found",
+		},
+		{
+			description:   "wrong_extendee",
+			target:        bufconfig.CustomOptionTargetMessage,
+			name:          "(acme.option.v1.field_option)",
+			value:         1,
+			expectedError: "extension acme.option.v1.field_option extends google.protobuf.FieldOptions, not google.protobuf.MessageOptions",
+		},
+		{
+			description:   "unknown_feature",
+			target:        bufconfig.CustomOptionTargetFile,
+			name:          "features.unknown",
+			value:         "value",
+			expectedError: "google.protobuf.FeatureSet has no field unknown",
+		},
+	}
+	for _, testcase := range testcases {
+		testcase := testcase
+		t.Run(testcase.description, func(t *testing.T) {
+			t.Parallel()
+			image := testGetImageFromDirs(
+				t,
+				map[string]string{
+					filepath.Join("testdata", "customoptions"): "buf.build/acme/customoptions",
+				},
+				false,
+			)
+			config := bufconfig.NewGenerateManagedConfig(
+				true,
+				nil,
+				[]bufconfig.ManagedOverrideRule{
+					newTestCustomOptionOverrideRule(t, "a.proto", "", testcase.target, testcase.name, testcase.value),
+				},
+			)
+			require.ErrorContains(t, Modify(image, config), testcase.expectedError)
+		})
+	}
+}
+
 // TODO FUTURE: add default values
 func TestGetStringOverrideFromConfig(t *testing.T) {
 	t.Parallel()
@@ -855,3 +1048,83 @@ func newTestFileOptionOverrideRule(
 	require.NoError(t, err)
 	return fileOptionOverride
 }
+
+func newTestCustomOptionOverrideRule(
+	t *testing.T,
+	path string,
+	name string,
+	target bufconfig.CustomOptionTarget,
+	optionName string,
+	value interface{},
+) bufconfig.ManagedOverrideRule {
+	customOption, err := bufconfig.NewCustomOption(target, optionName)
+	require.NoError(t, err)
+	customOptionOverride, err := bufconfig.NewManagedOverrideRuleForCustomOption(
+		path,
+		"",
+		name,
+		customOption,
+		value,
+	)
+	require.NoError(t, err)
+	return customOptionOverride
+}
+
+func newTestCustomOptionDisableRule(
+	t *testing.T,
+	path string,
+	name string,
+	target bufconfig.CustomOptionTarget,
+	optionName string,
+) bufconfig.ManagedDisableRule {
+	var customOption bufconfig.CustomOption
+	if optionName != "" {
+		var err error
+		customOption, err = bufconfig.NewCustomOption(target, optionName)
+		require.NoError(t, err)
+	}
+	var (
+		disable bufconfig.ManagedDisableRule
+		err     error
+	)
+	if customOption == nil {
+		disable, err = bufconfig.NewManagedDisableRule(path, "", name, bufconfig.FileOptionUnspecified, bufconfig.FieldOptionUnspecified)
+	} else {
+		disable, err = bufconfig.NewManagedDisableRuleForCustomOption(path, "", name, customOption)
+	}
+	require.NoError(t, err)
+	return disable
+}
+
+// testRequireCustomOptionValue requires that the custom option is set to the
+// expected value, or is not set if the expected value is nil.
+func testRequireCustomOptionValue(
+	t *testing.T,
+	image bufimage.Image,
+	options proto.Message,
+	optionName string,
+	expectedValue interface{},
+) {
+	customOption, err := bufconfig.NewCustomOption(bufconfig.CustomOptionTargetFile, optionName)
+	require.NoError(t, err)
+	if reflect.ValueOf(options).IsNil() {
+		require.Nil(t, expectedValue, "%s is not set", optionName)
+		return
+	}
+	require.NoError(t, protoencoding.ReparseExtensions(image.Resolver(), options.ProtoReflect()))
+	message := options.ProtoReflect()
+	nameParts := customOption.NameParts()
+	for i, namePart := range nameParts {
+		fieldDescriptor, err := findCustomOptionField(image.Resolver(), message.Descriptor(), namePart)
+		require.NoError(t, err)
+		if !message.Has(fieldDescriptor) {
+			require.Nil(t, expectedValue, "%s is not set", optionName)
+			return
+		}
+		if i < len(nameParts)-1 {
+			message = message.Get(fieldDescriptor).Message()
+			continue
+		}
+		require.Equal(t, expectedValue, message.Get(fieldDescriptor).Interface(), "incorrect value for %s", optionName)
+	}
+}
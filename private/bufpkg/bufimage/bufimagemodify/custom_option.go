@@ -0,0 +1,487 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagemodify/internal"
+	"github.com/bufbuild/buf/private/gen/data/datawkt"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/protocompile/walk"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// fileOptionsTag is the tag of the options field in a FileDescriptorProto.
+	// https://github.com/protocolbuffers/protobuf/blob/61689226c0e3ec88287eaed66164614d9c4f2bf7/src/google/protobuf/descriptor.proto#L120
+	fileOptionsTag int32 = 8
+	// fieldOptionsTag is the tag of the options field in a FieldDescriptorProto.
+	// https://github.com/protocolbuffers/protobuf/blob/61689226c0e3ec88287eaed66164614d9c4f2bf7/src/google/protobuf/descriptor.proto#L215
+	fieldOptionsTag int32 = 8
+	// featuresOptionName is the name of the features field in all options messages.
+	featuresOptionName = "features"
+	// editionsSyntax is the syntax of files that use editions.
+	editionsSyntax = "editions"
+)
+
+func modifyCustomOptions(
+	sweeper internal.MarkSweeper,
+	imageFile bufimage.ImageFile,
+	config bufconfig.GenerateManagedConfig,
+	options ...ModifyOption,
+) error {
+	modifyOptions := newModifyOptions()
+	for _, option := range options {
+		option(modifyOptions)
+	}
+	overrideRules := slicesext.Filter(
+		config.Overrides(),
+		func(override bufconfig.ManagedOverrideRule) bool {
+			return override.CustomOption() != nil &&
+				fileMatchConfig(imageFile, override.Path(), override.FullName())
+		},
+	)
+	// Unless specified, custom options are not modified.
+	if len(overrideRules) == 0 {
+		return nil
+	}
+	disableRules := slicesext.Filter(
+		config.Disables(),
+		func(disable bufconfig.ManagedDisableRule) bool {
+			return disable.FileOption() == bufconfig.FileOptionUnspecified &&
+				disable.FieldOption() == bufconfig.FieldOptionUnspecified &&
+				fileMatchConfig(imageFile, disable.Path(), disable.FullName())
+		},
+	)
+	if datawkt.Exists(imageFile.Path()) {
+		return nil
+	}
+	fileDescriptor := imageFile.FileDescriptorProto()
+	modifier := &customOptionModifier{
+		sweeper:          sweeper,
+		imageFile:        imageFile,
+		resolver:         modifyOptions.resolver,
+		preserveExisting: modifyOptions.preserveExisting,
+		isEditions:       fileDescriptor.GetSyntax() == editionsSyntax,
+		overrideRules:    overrideRules,
+		disableRules:     disableRules,
+	}
+	fileOptions := fileDescriptor.Options
+	if fileOptions == nil {
+		fileOptions = &descriptorpb.FileOptions{}
+	}
+	modified, err := modifier.modify(
+		bufconfig.CustomOptionTargetFile,
+		"",
+		fileOptions,
+		[]int32{fileOptionsTag},
+	)
+	if err != nil {
+		return err
+	}
+	if modified {
+		fileDescriptor.Options = fileOptions
+	}
+	return walk.DescriptorProtosWithPath(
+		fileDescriptor,
+		func(
+			fullName protoreflect.FullName,
+			path protoreflect.SourcePath,
+			message proto.Message,
+		) error {
+			switch descriptor := message.(type) {
+			case *descriptorpb.DescriptorProto:
+				messageOptions := descriptor.Options
+				if messageOptions == nil {
+					messageOptions = &descriptorpb.MessageOptions{}
+				}
+				// The sweeper only removes file and field option locations, so the
+				// locations of message options are kept.
+				modified, err := modifier.modify(
+					bufconfig.CustomOptionTargetMessage,
+					string(fullName),
+					messageOptions,
+					nil,
+				)
+				if err != nil {
+					return err
+				}
+				if modified {
+					descriptor.Options = messageOptions
+				}
+			case *descriptorpb.FieldDescriptorProto:
+				fieldOptions := descriptor.Options
+				if fieldOptions == nil {
+					fieldOptions = &descriptorpb.FieldOptions{}
+				}
+				var optionsPath []int32
+				if len(path) > 0 {
+					optionsPath = append(slicesext.Copy(path), fieldOptionsTag)
+				}
+				modified, err := modifier.modify(
+					bufconfig.CustomOptionTargetField,
+					string(fullName),
+					fieldOptions,
+					optionsPath,
+				)
+				if err != nil {
+					return err
+				}
+				if modified {
+					descriptor.Options = fieldOptions
+				}
+			}
+			return nil
+		},
+	)
+}
+
+// *** PRIVATE ***
+
+type customOptionModifier struct {
+	sweeper          internal.MarkSweeper
+	imageFile        bufimage.ImageFile
+	resolver         protoencoding.Resolver
+	preserveExisting bool
+	isEditions       bool
+	overrideRules    []bufconfig.ManagedOverrideRule
+	disableRules     []bufconfig.ManagedDisableRule
+}
+
+// modify applies the custom option overrides for the target to the options
+// message of the descriptor with the given name. The name is empty for files.
+//
+// If optionsPath is not nil, the SourceCodeInfo location of every modified
+// option is marked for removal.
+//
+// Returns true if the options message was modified.
+func (c *customOptionModifier) modify(
+	target bufconfig.CustomOptionTarget,
+	name string,
+	options proto.Message,
+	optionsPath []int32,
+) (bool, error) {
+	var modified bool
+	for _, overrideRule := range c.getOverrideRules(target, name) {
+		customOption := overrideRule.CustomOption()
+		if customOption.NameParts()[0] == featuresOptionName && !c.isEditions {
+			// Features can only be set in files that use editions.
+			continue
+		}
+		if c.isDisabled(customOption, name) {
+			continue
+		}
+		optionPath, optionModified, err := setCustomOption(
+			c.resolver,
+			options,
+			customOption,
+			overrideRule.Value(),
+			c.preserveExisting,
+		)
+		if err != nil {
+			descriptorName := c.imageFile.Path()
+			if name != "" {
+				descriptorName = name
+			}
+			return false, fmt.Errorf("invalid override for %v %s on %s: %w", target, customOption.Name(), descriptorName, err)
+		}
+		if !optionModified {
+			continue
+		}
+		modified = true
+		if optionsPath != nil {
+			c.sweeper.Mark(c.imageFile, append(slicesext.Copy(optionsPath), optionPath...))
+		}
+	}
+	return modified, nil
+}
+
+// getOverrideRules returns the override rules that match the descriptor with the
+// given name, keeping only the last matching rule for each option.
+func (c *customOptionModifier) getOverrideRules(
+	target bufconfig.CustomOptionTarget,
+	name string,
+) []bufconfig.ManagedOverrideRule {
+	var overrideRules []bufconfig.ManagedOverrideRule
+	optionNameToIndex := make(map[string]int)
+	for _, overrideRule := range c.overrideRules {
+		customOption := overrideRule.CustomOption()
+		if customOption.Target() != target {
+			continue
+		}
+		if !customOptionRuleMatchName(target, overrideRule.MessageName(), overrideRule.FieldName(), name) {
+			continue
+		}
+		if index, ok := optionNameToIndex[customOption.Name()]; ok {
+			overrideRules[index] = overrideRule
+			continue
+		}
+		optionNameToIndex[customOption.Name()] = len(overrideRules)
+		overrideRules = append(overrideRules, overrideRule)
+	}
+	return overrideRules
+}
+
+func (c *customOptionModifier) isDisabled(customOption bufconfig.CustomOption, name string) bool {
+	for _, disableRule := range c.disableRules {
+		if disableCustomOption := disableRule.CustomOption(); disableCustomOption != nil &&
+			(disableCustomOption.Target() != customOption.Target() || disableCustomOption.Name() != customOption.Name()) {
+			continue
+		}
+		if customOptionRuleMatchName(customOption.Target(), disableRule.MessageName(), disableRule.FieldName(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// customOptionRuleMatchName returns true if a rule with the given message and
+// field names applies to the descriptor with the given name.
+func customOptionRuleMatchName(
+	target bufconfig.CustomOptionTarget,
+	ruleMessageName string,
+	ruleFieldName string,
+	name string,
+) bool {
+	switch target {
+	case bufconfig.CustomOptionTargetMessage:
+		return ruleFieldName == "" && (ruleMessageName == "" || ruleMessageName == name)
+	case bufconfig.CustomOptionTargetField:
+		return ruleMessageName == "" && (ruleFieldName == "" || ruleFieldName == name)
+	default:
+		return ruleMessageName == "" && ruleFieldName == ""
+	}
+}
+
+// setCustomOption sets the custom option in the options message to the value,
+// after checking the value against the type of the option.
+//
+// Returns the path of the option within the options message, and whether the
+// options message was modified.
+func setCustomOption(
+	resolver protoencoding.Resolver,
+	options proto.Message,
+	customOption bufconfig.CustomOption,
+	value interface{},
+	preserveExisting bool,
+) ([]int32, bool, error) {
+	// Make sure that extensions that are set are known, so that they are replaced
+	// instead of being duplicated as unknown fields.
+	if err := protoencoding.ReparseExtensions(resolver, options.ProtoReflect()); err != nil {
+		return nil, false, err
+	}
+	nameParts := customOption.NameParts()
+	fieldDescriptors := make([]protoreflect.FieldDescriptor, len(nameParts))
+	optionPath := make([]int32, len(nameParts))
+	messageDescriptor := options.ProtoReflect().Descriptor()
+	for i, namePart := range nameParts {
+		fieldDescriptor, err := findCustomOptionField(resolver, messageDescriptor, namePart)
+		if err != nil {
+			return nil, false, err
+		}
+		if fieldDescriptor.IsList() || fieldDescriptor.IsMap() {
+			return nil, false, fmt.Errorf("%s is a repeated field, which is not supported", namePart)
+		}
+		if i < len(nameParts)-1 {
+			if fieldDescriptor.Message() == nil {
+				return nil, false, fmt.Errorf("%s is not a message", namePart)
+			}
+			messageDescriptor = fieldDescriptor.Message()
+		}
+		fieldDescriptors[i] = fieldDescriptor
+		optionPath[i] = int32(fieldDescriptor.Number())
+	}
+	leafFieldDescriptor := fieldDescriptors[len(fieldDescriptors)-1]
+	optionValue, err := getCustomOptionValue(leafFieldDescriptor, value)
+	if err != nil {
+		return nil, false, err
+	}
+	// Check if the option is already set.
+	message := options.ProtoReflect()
+	isSet := true
+	for i, fieldDescriptor := range fieldDescriptors {
+		if !message.Has(fieldDescriptor) {
+			isSet = false
+			break
+		}
+		if i < len(fieldDescriptors)-1 {
+			message = message.Get(fieldDescriptor).Message()
+		}
+	}
+	if isSet && (preserveExisting || message.Get(leafFieldDescriptor).Equal(optionValue)) {
+		return nil, false, nil
+	}
+	message = options.ProtoReflect()
+	for _, fieldDescriptor := range fieldDescriptors[:len(fieldDescriptors)-1] {
+		message = message.Mutable(fieldDescriptor).Message()
+	}
+	message.Set(leafFieldDescriptor, optionValue)
+	return optionPath, true, nil
+}
+
+func findCustomOptionField(
+	resolver protoencoding.Resolver,
+	messageDescriptor protoreflect.MessageDescriptor,
+	namePart string,
+) (protoreflect.FieldDescriptor, error) {
+	if !strings.HasPrefix(namePart, "(") {
+		fieldDescriptor := messageDescriptor.Fields().ByName(protoreflect.Name(namePart))
+		if fieldDescriptor == nil {
+			return nil, fmt.Errorf("%s has no field %s", messageDescriptor.FullName(), namePart)
+		}
+		return fieldDescriptor, nil
+	}
+	extensionName := protoreflect.FullName(strings.TrimSuffix(strings.TrimPrefix(namePart, "("), ")"))
+	if resolver == nil {
+		return nil, fmt.Errorf("extension %s not found", extensionName)
+	}
+	extensionType, err := resolver.FindExtensionByName(extensionName)
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			return nil, fmt.Errorf("extension %s not found", extensionName)
+		}
+		return nil, err
+	}
+	extensionTypeDescriptor := extensionType.TypeDescriptor()
+	if containingMessageName := extensionTypeDescriptor.ContainingMessage().FullName(); containingMessageName != messageDescriptor.FullName() {
+		return nil, fmt.Errorf("extension %s extends %s, not %s", extensionName, containingMessageName, messageDescriptor.FullName())
+	}
+	return extensionTypeDescriptor, nil
+}
+
+// getCustomOptionValue checks the value against the type of the field, and
+// returns it as a protoreflect.Value.
+func getCustomOptionValue(
+	fieldDescriptor protoreflect.FieldDescriptor,
+	value interface{},
+) (protoreflect.Value, error) {
+	switch kind := fieldDescriptor.Kind(); kind {
+	case protoreflect.BoolKind:
+		boolValue, ok := value.(bool)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected a bool, got %T", value)
+		}
+		return protoreflect.ValueOfBool(boolValue), nil
+	case protoreflect.StringKind:
+		stringValue, ok := value.(string)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected a string, got %T", value)
+		}
+		return protoreflect.ValueOfString(stringValue), nil
+	case protoreflect.BytesKind:
+		stringValue, ok := value.(string)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("expected a string, got %T", value)
+		}
+		return protoreflect.ValueOfBytes([]byte(stringValue)), nil
+	case protoreflect.EnumKind:
+		enumValueNames := make([]string, 0, fieldDescriptor.Enum().Values().Len())
+		for i := 0; i < fieldDescriptor.Enum().Values().Len(); i++ {
+			enumValueNames = append(enumValueNames, string(fieldDescriptor.Enum().Values().Get(i).Name()))
+		}
+		stringValue, ok := value.(string)
+		if !ok {
+			return protoreflect.Value{}, fmt.Errorf("must be one of %s", strings.Join(enumValueNames, ", "))
+		}
+		enumValueDescriptor := fieldDescriptor.Enum().Values().ByName(protoreflect.Name(stringValue))
+		if enumValueDescriptor == nil {
+			return protoreflect.Value{}, fmt.Errorf("must be one of %s", strings.Join(enumValueNames, ", "))
+		}
+		return protoreflect.ValueOfEnum(enumValueDescriptor.Number()), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		intValue, err := getCustomOptionIntValue(value, math.MinInt32, math.MaxInt32)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfInt32(int32(intValue)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		intValue, err := getCustomOptionIntValue(value, math.MinInt64, math.MaxInt64)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfInt64(intValue), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		intValue, err := getCustomOptionIntValue(value, 0, math.MaxUint32)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfUint32(uint32(intValue)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if uint64Value, ok := value.(uint64); ok {
+			return protoreflect.ValueOfUint64(uint64Value), nil
+		}
+		intValue, err := getCustomOptionIntValue(value, 0, math.MaxInt64)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfUint64(uint64(intValue)), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		var floatValue float64
+		switch typedValue := value.(type) {
+		case float64:
+			floatValue = typedValue
+		case int:
+			floatValue = float64(typedValue)
+		case int64:
+			floatValue = float64(typedValue)
+		case uint64:
+			floatValue = float64(typedValue)
+		default:
+			return protoreflect.Value{}, fmt.Errorf("expected a number, got %T", value)
+		}
+		if kind == protoreflect.FloatKind {
+			return protoreflect.ValueOfFloat32(float32(floatValue)), nil
+		}
+		return protoreflect.ValueOfFloat64(floatValue), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("%s is a message, set one of its fields instead", fieldDescriptor.FullName())
+	}
+}
+
+func getCustomOptionIntValue(value interface{}, minValue int64, maxValue int64) (int64, error) {
+	var intValue int64
+	switch typedValue := value.(type) {
+	case int:
+		intValue = int64(typedValue)
+	case int64:
+		intValue = typedValue
+	case uint64:
+		if typedValue > math.MaxInt64 {
+			return 0, fmt.Errorf("%d is out of range", typedValue)
+		}
+		intValue = int64(typedValue)
+	case float64:
+		if typedValue != math.Trunc(typedValue) || typedValue < math.MinInt64 || typedValue > math.MaxInt64 {
+			return 0, fmt.Errorf("expected an integer, got %v", typedValue)
+		}
+		intValue = int64(typedValue)
+	default:
+		return 0, fmt.Errorf("expected an integer, got %T", value)
+	}
+	if intValue < minValue || intValue > maxValue {
+		return 0, fmt.Errorf("%d is out of range", intValue)
+	}
+	return intValue, nil
+}
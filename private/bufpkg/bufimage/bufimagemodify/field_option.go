@@ -59,7 +59,9 @@ func modifyJsType(
 		func(disable bufconfig.ManagedDisableRule) bool {
 			return (disable.FieldOption() == bufconfig.FieldOptionJSType ||
 				(disable.FieldOption() == bufconfig.FieldOptionUnspecified &&
-					disable.FileOption() == bufconfig.FileOptionUnspecified)) &&
+					disable.FileOption() == bufconfig.FileOptionUnspecified &&
+					disable.CustomOption() == nil &&
+					disable.MessageName() == "")) &&
 				fileMatchConfig(imageFile, disable.Path(), disable.FullName())
 		},
 	)
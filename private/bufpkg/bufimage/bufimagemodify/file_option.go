@@ -60,6 +60,9 @@ var (
 	// rubyPackagePath is the SourceCodeInfo path for the ruby_package option.
 	// https://github.com/protocolbuffers/protobuf/blob/61689226c0e3ec88287eaed66164614d9c4f2bf7/src/google/protobuf/descriptor.proto#L453
 	rubyPackagePath = []int32{8, 45}
+	// swiftPrefixPath is the SourceCodeInfo path for the swift_prefix option.
+	// https://github.com/protocolbuffers/protobuf/blob/61689226c0e3ec88287eaed66164614d9c4f2bf7/src/google/protobuf/descriptor.proto#L433
+	swiftPrefixPath = []int32{8, 39}
 )
 
 func modifyJavaOuterClass(
@@ -359,6 +362,44 @@ func modifyRubyPackage(
 	)
 }
 
+func modifySwiftPrefix(
+	sweeper internal.MarkSweeper,
+	imageFile bufimage.ImageFile,
+	config bufconfig.GenerateManagedConfig,
+	options ...ModifyOption,
+) error {
+	modifyOptions := newModifyOptions()
+	for _, option := range options {
+		option(modifyOptions)
+	}
+	return modifyStringOption(
+		sweeper,
+		imageFile,
+		config,
+		modifyOptions.preserveExisting,
+		bufconfig.FileOptionSwiftPrefix,
+		bufconfig.FileOptionUnspecified,
+		bufconfig.FileOptionUnspecified,
+		// Unless specified, swift_prefix is not modified.
+		func(bufimage.ImageFile) stringOverrideOptions {
+			return stringOverrideOptions{}
+		},
+		func(bufimage.ImageFile, stringOverrideOptions) string {
+			return ""
+		},
+		func(options *descriptorpb.FileOptions) string {
+			return options.GetSwiftPrefix()
+		},
+		func(options *descriptorpb.FileOptions, value string) {
+			options.SwiftPrefix = proto.String(value)
+		},
+		func(options *descriptorpb.FileOptions) bool {
+			return options != nil && options.SwiftPrefix != nil
+		},
+		swiftPrefixPath,
+	)
+}
+
 func modifyCcEnableArenas(
 	sweeper internal.MarkSweeper,
 	imageFile bufimage.ImageFile,
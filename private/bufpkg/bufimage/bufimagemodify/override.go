@@ -238,6 +238,9 @@ func isFileOptionDisabledForFile(
 		if disableRule.FieldOption() != bufconfig.FieldOptionUnspecified {
 			continue // FieldOption specified, not a matching rule.
 		}
+		if disableRule.CustomOption() != nil || disableRule.MessageName() != "" {
+			continue // Custom option or message specified, not a matching rule.
+		}
 		if !fileMatchConfig(imageFile, disableRule.Path(), disableRule.FullName()) {
 			continue
 		}
@@ -42,6 +42,10 @@ var (
 	// ErrImageFilterTypeIsImport is returned from ImageFilteredByTypes when
 	// a specified type name is declared in a module dependency.
 	ErrImageFilterTypeIsImport = errors.New("type declared in imported module")
+
+	// ErrImageFilterTypeIsExcluded is returned from ImageFilteredByTypesWithOptions
+	// when a type that is excluded is required by a type that is included.
+	ErrImageFilterTypeIsExcluded = errors.New("type is excluded but is required by an included type")
 )
 
 // FreeMessageRangeStrings gets the free MessageRange strings for the target files.
@@ -99,6 +103,23 @@ func WithAllowFilterByImportedType() ImageFilterOption {
 	}
 }
 
+// WithExcludeTypes returns an option for ImageFilteredByTypesWithOptions that
+// excludes the given types or packages from the filtered image. Excluding a type
+// also excludes all of the types nested within it, and excluding a service method
+// only removes that method from the service. As when including a package, excluding
+// a package does not exclude its sub-packages.
+//
+// If no types are given to include, all of the types declared in the non-import
+// files of the image are included, except for the excluded types.
+//
+// As the filtered image must be complete, an error wrapping ErrImageFilterTypeIsExcluded
+// is returned if an included type requires an excluded type.
+func WithExcludeTypes(types ...string) ImageFilterOption {
+	return func(opts *imageFilterOptions) {
+		opts.excludeTypes = append(opts.excludeTypes, types...)
+	}
+}
+
 // ImageFilteredByTypes returns a minimal image containing only the descriptors
 // required to define those types. The resulting contains only files in which
 // those descriptors and their transitive closure of required descriptors, with
@@ -184,6 +205,14 @@ func ImageFilteredByTypesWithOptions(image bufimage.Image, types []string, opts
 	if err != nil {
 		return nil, err
 	}
+	closure := newTransitiveClosure()
+	if err := closure.addExcludedTypes(options.excludeTypes, imageIndex); err != nil {
+		return nil, err
+	}
+	if len(types) == 0 && len(options.excludeTypes) > 0 {
+		// Include everything declared in the non-import files, except for the excluded types.
+		types = getNonImportPackageNames(image)
+	}
 	// Check types exist
 	startingDescriptors := make([]namedDescriptor, 0, len(types))
 	var startingPackages []*protoPackage
@@ -222,7 +251,6 @@ func ImageFilteredByTypesWithOptions(image bufimage.Image, types []string, opts
 		startingPackages = append(startingPackages, pkg)
 	}
 	// Find all types to include in filtered image.
-	closure := newTransitiveClosure()
 	for _, startingPackage := range startingPackages {
 		if err := closure.addPackage(startingPackage, imageIndex, options); err != nil {
 			return nil, err
@@ -461,6 +489,11 @@ type transitiveClosure struct {
 	// The set of imports for each file. This allows for re-writing imports
 	// for files whose contents have been pruned.
 	imports map[string]map[string]struct{}
+	// The elements that are excluded from the closure.
+	excluded map[namedDescriptor]struct{}
+	// The set of files that contain items in excluded. These files are never
+	// part of the closure in their entirety.
+	excludedFiles map[string]struct{}
 }
 
 type closureInclusionMode int
@@ -486,6 +519,8 @@ func newTransitiveClosure() *transitiveClosure {
 		files:         map[string]struct{}{},
 		completeFiles: map[string]struct{}{},
 		imports:       map[string]map[string]struct{}{},
+		excluded:      map[namedDescriptor]struct{}{},
+		excludedFiles: map[string]struct{}{},
 	}
 }
 
@@ -515,12 +550,20 @@ func (t *transitiveClosure) addPackage(
 	opts *imageFilterOptions,
 ) error {
 	for _, file := range pkg.files {
+		if _, ok := t.excludedFiles[file.Path()]; ok {
+			// The file has excluded elements, so its contents must be filtered, and
+			// it is only added if any of its elements are added below.
+			continue
+		}
 		if err := t.addFile(file.Path(), imageIndex, opts); err != nil {
 			return err
 		}
 		t.completeFiles[file.Path()] = struct{}{}
 	}
 	for _, descriptor := range pkg.elements {
+		if _, ok := t.excluded[descriptor]; ok {
+			continue
+		}
 		if err := t.addElement(descriptor, "", false, imageIndex, opts); err != nil {
 			return err
 		}
@@ -528,6 +571,36 @@ func (t *transitiveClosure) addPackage(
 	return nil
 }
 
+// addExcludedTypes adds the descriptors for the given types or packages, and all
+// of the descriptors nested within them, to the set of excluded descriptors.
+func (t *transitiveClosure) addExcludedTypes(excludeTypes []string, imageIndex *imageIndex) error {
+	for _, excludeType := range excludeTypes {
+		if excludeDescriptor, ok := imageIndex.ByName[excludeType]; ok {
+			t.addExcluded(excludeDescriptor, imageIndex)
+			prefix := excludeType + "."
+			for descriptor, descriptorInfo := range imageIndex.ByDescriptor {
+				if strings.HasPrefix(descriptorInfo.fullName, prefix) {
+					t.addExcluded(descriptor, imageIndex)
+				}
+			}
+			continue
+		}
+		pkg, ok := imageIndex.Packages[excludeType]
+		if !ok {
+			return fmt.Errorf("excluding type %q: %w", excludeType, ErrImageFilterTypeNotFound)
+		}
+		for _, descriptor := range pkg.elements {
+			t.addExcluded(descriptor, imageIndex)
+		}
+	}
+	return nil
+}
+
+func (t *transitiveClosure) addExcluded(descriptor namedDescriptor, imageIndex *imageIndex) {
+	t.excluded[descriptor] = struct{}{}
+	t.excludedFiles[imageIndex.ByDescriptor[descriptor].file] = struct{}{}
+}
+
 func (t *transitiveClosure) addElement(
 	descriptor namedDescriptor,
 	referrerFile string,
@@ -536,6 +609,9 @@ func (t *transitiveClosure) addElement(
 	opts *imageFilterOptions,
 ) error {
 	descriptorInfo := imageIndex.ByDescriptor[descriptor]
+	if _, ok := t.excluded[descriptor]; ok {
+		return fmt.Errorf("%q: %w", descriptorInfo.fullName, ErrImageFilterTypeIsExcluded)
+	}
 	if err := t.addFile(descriptorInfo.file, imageIndex, opts); err != nil {
 		return err
 	}
@@ -598,6 +674,9 @@ func (t *transitiveClosure) addElement(
 
 	case *descriptorpb.ServiceDescriptorProto:
 		for _, method := range typedDescriptor.GetMethod() {
+			if _, ok := t.excluded[method]; ok {
+				continue
+			}
 			if err := t.addElement(method, "", false, imageIndex, opts); err != nil {
 				return err
 			}
@@ -756,6 +835,9 @@ func (t *transitiveClosure) addExtensions(
 		}
 		descriptorInfo := imageIndex.ByDescriptor[msgDescriptor]
 		for _, extendsDescriptor := range imageIndex.NameToExtensions[descriptorInfo.fullName] {
+			if _, ok := t.excluded[extendsDescriptor]; ok {
+				continue
+			}
 			if err := t.addElement(extendsDescriptor, "", false, imageIndex, opts); err != nil {
 				return err
 			}
@@ -974,6 +1056,7 @@ type imageFilterOptions struct {
 	includeCustomOptions   bool
 	includeKnownExtensions bool
 	allowImportedTypes     bool
+	excludeTypes           []string
 }
 
 func newImageFilterOptions() *imageFilterOptions {
@@ -984,6 +1067,23 @@ func newImageFilterOptions() *imageFilterOptions {
 	}
 }
 
+// getNonImportPackageNames returns the sorted names of the packages of the
+// non-import files in the image.
+func getNonImportPackageNames(image bufimage.Image) []string {
+	packageNames := make(map[string]struct{})
+	for _, imageFile := range image.Files() {
+		if !imageFile.IsImport() {
+			packageNames[imageFile.FileDescriptorProto().GetPackage()] = struct{}{}
+		}
+	}
+	sortedPackageNames := make([]string, 0, len(packageNames))
+	for packageName := range packageNames {
+		sortedPackageNames = append(sortedPackageNames, packageName)
+	}
+	sort.Strings(sortedPackageNames)
+	return sortedPackageNames
+}
+
 func stripSourceRetentionOptionsFromFile(imageFile bufimage.ImageFile) (bufimage.ImageFile, error) {
 	updatedFileDescriptor, err := protopluginutil.StripSourceRetentionOptions(imageFile.FileDescriptorProto())
 	if err != nil {
@@ -137,6 +137,40 @@ func TestPackages(t *testing.T) {
 	runDiffTest(t, "testdata/packages", []string{"foo.bar.baz"}, "foo.bar.baz.txtar")
 }
 
+func TestExcludeTypes(t *testing.T) {
+	t.Parallel()
+	t.Run("only-exclude", func(t *testing.T) {
+		t.Parallel()
+		runDiffTest(t, "testdata/nesting", nil, "exclude-Baz.txtar", WithExcludeTypes("pkg.Baz"))
+	})
+	t.Run("nested", func(t *testing.T) {
+		t.Parallel()
+		runDiffTest(t, "testdata/nesting", []string{"pkg"}, "exclude-NestedButNotUsed.txtar", WithExcludeTypes("pkg.Foo.NestedButNotUsed"))
+	})
+	t.Run("package", func(t *testing.T) {
+		t.Parallel()
+		runDiffTest(t, "testdata/packages", nil, "exclude-foo.bar.txtar", WithExcludeTypes("foo.bar"))
+	})
+	t.Run("method", func(t *testing.T) {
+		t.Parallel()
+		runDiffTest(t, "testdata/options", []string{"pkg.FooService"}, "pkg.FooService-exclude-DoNot.txtar", WithExcludeTypes("pkg.FooService.DoNot"))
+	})
+	t.Run("required", func(t *testing.T) {
+		t.Parallel()
+		_, image, err := getImage(context.Background(), slogtestext.NewLogger(t), "testdata/nesting", bufimage.WithExcludeSourceCodeInfo())
+		require.NoError(t, err)
+		_, err = ImageFilteredByTypesWithOptions(image, []string{"pkg.Bar"}, WithExcludeTypes("pkg.FooEnum"))
+		assert.ErrorIs(t, err, ErrImageFilterTypeIsExcluded)
+	})
+	t.Run("not-found", func(t *testing.T) {
+		t.Parallel()
+		_, image, err := getImage(context.Background(), slogtestext.NewLogger(t), "testdata/nesting", bufimage.WithExcludeSourceCodeInfo())
+		require.NoError(t, err)
+		_, err = ImageFilteredByTypesWithOptions(image, nil, WithExcludeTypes("pkg.Nonexisting"))
+		assert.ErrorIs(t, err, ErrImageFilterTypeNotFound)
+	})
+}
+
 func TestAny(t *testing.T) {
 	t.Parallel()
 	runDiffTest(t, "testdata/any", []string{"ExtendedAnySyntax"}, "c1.txtar")
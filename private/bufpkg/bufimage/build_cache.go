@@ -0,0 +1,112 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+)
+
+type buildCache struct {
+	pathToEntry map[string]*buildCacheEntry
+	lock        sync.Mutex
+}
+
+func newBuildCache() *buildCache {
+	return &buildCache{
+		pathToEntry: make(map[string]*buildCacheEntry),
+	}
+}
+
+// resolve opens the file at the path and returns its cached parse result if the content
+// of the file did not change since it was last parsed.
+//
+// The warnings reported when the file was parsed are passed to addWarnings, as the
+// compiler does not report them again for a parse result.
+func (b *buildCache) resolve(
+	path string,
+	open func(string) (io.ReadCloser, error),
+	addWarnings func([]reporter.ErrorWithPos),
+) (protocompile.SearchResult, error) {
+	data, err := readAllAndClose(open(path))
+	if err != nil {
+		return protocompile.SearchResult{}, err
+	}
+	digest := sha256.Sum256(data)
+	b.lock.Lock()
+	entry, ok := b.pathToEntry[path]
+	b.lock.Unlock()
+	if ok && entry.digest == digest {
+		addWarnings(entry.warnings)
+		return protocompile.SearchResult{ParseResult: entry.parseResult}, nil
+	}
+	var hasErrors bool
+	var warnings []reporter.ErrorWithPos
+	handler := reporter.NewHandler(
+		reporter.NewReporter(
+			func(reporter.ErrorWithPos) error {
+				hasErrors = true
+				return nil
+			},
+			func(warning reporter.ErrorWithPos) {
+				warnings = append(warnings, warning)
+			},
+		),
+	)
+	fileNode, err := parser.Parse(path, bytes.NewReader(data), handler)
+	var parseResult parser.Result
+	if err == nil && !hasErrors {
+		parseResult, err = parser.ResultFromAST(fileNode, true, handler)
+	}
+	if err != nil || hasErrors {
+		// Let the compiler parse the file again, so that the errors are reported
+		// as they would be without a BuildCache. Failed results are not cached.
+		return protocompile.SearchResult{Source: bytes.NewReader(data)}, nil
+	}
+	b.lock.Lock()
+	b.pathToEntry[path] = &buildCacheEntry{
+		digest:      digest,
+		parseResult: parseResult,
+		warnings:    warnings,
+	}
+	b.lock.Unlock()
+	addWarnings(warnings)
+	return protocompile.SearchResult{ParseResult: parseResult}, nil
+}
+
+func (*buildCache) isBuildCache() {}
+
+type buildCacheEntry struct {
+	digest      [sha256.Size]byte
+	parseResult parser.Result
+	warnings    []reporter.ErrorWithPos
+}
+
+func readAllAndClose(readCloser io.ReadCloser, err error) (_ []byte, retErr error) {
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, readCloser.Close())
+	}()
+	return io.ReadAll(readCloser)
+}
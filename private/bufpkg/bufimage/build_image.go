@@ -21,6 +21,7 @@ import (
 	"log/slog"
 	"math"
 	"strings"
+	"sync"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
@@ -45,6 +46,7 @@ func buildImage(
 	moduleReadBucket bufmodule.ModuleReadBucket,
 	excludeSourceCodeInfo bool,
 	noParallelism bool,
+	cache *buildCache,
 ) (Image, error) {
 	defer slogext.DebugProfile(logger)()
 
@@ -70,6 +72,7 @@ func buildImage(
 		paths,
 		excludeSourceCodeInfo,
 		noParallelism,
+		cache,
 	)
 	if buildResult.Err != nil {
 		return nil, buildResult.Err
@@ -99,9 +102,17 @@ func getBuildResult(
 	paths []string,
 	excludeSourceCodeInfo bool,
 	noParallelism bool,
+	cache *buildCache,
 ) *buildResult {
 	var errorsWithPos []reporter.ErrorWithPos
 	var warningErrorsWithPos []reporter.ErrorWithPos
+	// Warnings of cached files are added by the resolver, concurrently with the reporter.
+	var warningLock sync.Mutex
+	addWarnings := func(warningErrorsWithPosToAdd ...reporter.ErrorWithPos) {
+		warningLock.Lock()
+		defer warningLock.Unlock()
+		warningErrorsWithPos = append(warningErrorsWithPos, warningErrorsWithPosToAdd...)
+	}
 	// With "extra option locations", buf can include more comments
 	// for an option value than protoc can. In particular, this allows
 	// it to preserve comments inside of message literals.
@@ -113,11 +124,25 @@ func getBuildResult(
 	if noParallelism {
 		parallelism = 1
 	}
+	var resolver protocompile.Resolver = &protocompile.SourceResolver{Accessor: parserAccessorHandler.Open}
+	if cache != nil {
+		resolver = protocompile.ResolverFunc(
+			func(path string) (protocompile.SearchResult, error) {
+				return cache.resolve(
+					path,
+					parserAccessorHandler.Open,
+					func(warningErrorsWithPos []reporter.ErrorWithPos) {
+						addWarnings(warningErrorsWithPos...)
+					},
+				)
+			},
+		)
+	}
 	symbols := &linker.Symbols{}
 	compiler := protocompile.Compiler{
 		MaxParallelism: parallelism,
 		SourceInfoMode: sourceInfoMode,
-		Resolver:       &protocompile.SourceResolver{Accessor: parserAccessorHandler.Open},
+		Resolver:       resolver,
 		Symbols:        symbols,
 		Reporter: reporter.NewReporter(
 			func(errorWithPos reporter.ErrorWithPos) error {
@@ -125,7 +150,7 @@ func getBuildResult(
 				return nil
 			},
 			func(errorWithPos reporter.ErrorWithPos) {
-				warningErrorsWithPos = append(warningErrorsWithPos, errorWithPos)
+				addWarnings(errorWithPos)
 			},
 		),
 	}
@@ -417,6 +442,7 @@ func newFailedBuildResult(err error) *buildResult {
 type buildImageOptions struct {
 	excludeSourceCodeInfo bool
 	noParallelism         bool
+	buildCache            *buildCache
 }
 
 func newBuildImageOptions() *buildImageOptions {
@@ -364,6 +364,60 @@ func TestModuleTargetFiles(t *testing.T) {
 	testTagetImageFiles(t, []string{"b.proto", "c.proto"}, "buf.build/foo/b", "buf.build/foo/c")
 }
 
+func TestBuildCache(t *testing.T) {
+	t.Parallel()
+	buildCache := bufimage.NewBuildCache()
+	build := func(t *testing.T, pathToData map[string][]byte) (bufimage.Image, error) {
+		moduleSet, err := bufmoduletesting.NewModuleSet(
+			bufmoduletesting.ModuleData{
+				Name:       "buf.build/foo/a",
+				PathToData: pathToData,
+			},
+		)
+		require.NoError(t, err)
+		return bufimage.BuildImage(
+			context.Background(),
+			slogtestext.NewLogger(t),
+			bufmodule.ModuleSetToModuleReadBucketWithOnlyProtoFiles(moduleSet),
+			bufimage.WithBuildCache(buildCache),
+		)
+	}
+	pathToData := map[string][]byte{
+		"a.proto": []byte(`syntax = "proto3"; package a; import "b.proto"; import "c.proto"; message A { b.B b = 1; }`),
+		"b.proto": []byte(`syntax = "proto3"; package b; message B {}`),
+		"c.proto": []byte(`package c; message C {}`),
+	}
+	testBuildCacheImage := func(t *testing.T, image bufimage.Image) {
+		aImageFile := image.GetFile("a.proto")
+		require.NotNil(t, aImageFile)
+		// The unused import and unspecified syntax are reported when the files are cached.
+		assert.Equal(t, []int32{1}, aImageFile.UnusedDependencyIndexes())
+		cImageFile := image.GetFile("c.proto")
+		require.NotNil(t, cImageFile)
+		assert.True(t, cImageFile.IsSyntaxUnspecified())
+		assert.NotNil(t, aImageFile.FileDescriptorProto().GetSourceCodeInfo())
+	}
+	image, err := build(t, pathToData)
+	require.NoError(t, err)
+	testBuildCacheImage(t, image)
+	image, err = build(t, pathToData)
+	require.NoError(t, err)
+	testBuildCacheImage(t, image)
+	// A changed file is parsed again.
+	pathToData["b.proto"] = []byte(`syntax = "proto3"; package b; message B { string s = 1; }`)
+	image, err = build(t, pathToData)
+	require.NoError(t, err)
+	testBuildCacheImage(t, image)
+	require.Len(t, image.GetFile("b.proto").FileDescriptorProto().GetMessageType()[0].GetField(), 1)
+	// Errors are reported as without a BuildCache.
+	pathToData["b.proto"] = []byte(`syntax = "proto3"; package b; message B {`)
+	_, err = build(t, pathToData)
+	var fileAnnotationSet bufanalysis.FileAnnotationSet
+	require.ErrorAs(t, err, &fileAnnotationSet)
+	require.Len(t, fileAnnotationSet.FileAnnotations(), 1)
+	assert.Equal(t, "b.proto", fileAnnotationSet.FileAnnotations()[0].FileInfo().Path())
+}
+
 func testCompare(t *testing.T, relDirPath string) {
 	dirPath := filepath.Join("testdata", relDirPath)
 	image, fileAnnotations := testBuild(t, false, dirPath, false)
@@ -0,0 +1,186 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufjsonschema generates JSON Schemas for Protobuf messages.
+//
+// The schemas describe the JSON mapping of the messages as produced by protojson
+// with the default options, which is the mapping used by protoencoding and Connect:
+// fields are named by their JSON name, enums are named by their value names, 64-bit
+// integers are strings, and the well-known types use their special JSON mappings.
+//
+// protovalidate constraints that have an equivalent in JSON Schema are added as
+// keywords, and comments are added as descriptions.
+package bufjsonschema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/bufbuild/protoplugin"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// Dialect is the JSON Schema dialect of the generated schemas.
+	Dialect = "https://json-schema.org/draft/2020-12/schema"
+	// FileExtension is the file extension of the generated schemas.
+	FileExtension = ".schema.json"
+)
+
+// Schema is a JSON Schema.
+type Schema map[string]any
+
+// Definitions builds the schemas of messages and enums.
+//
+// Schemas of messages and enums refer to each other by reference, and every
+// message or enum that is referenced is added to the definitions. The well-known
+// types are never added, and their schemas are used in place of references instead.
+type Definitions interface {
+	// Ref returns a schema that refers to the message, and adds the definitions of
+	// the message and of every message and enum that it refers to.
+	Ref(messageDescriptor protoreflect.MessageDescriptor) Schema
+	// Schemas returns the definitions that have been added, by full name.
+	Schemas() map[string]Schema
+
+	isDefinitions()
+}
+
+// NewDefinitions returns a new Definitions.
+//
+// References are the refPrefix followed by the full name of the message or enum,
+// such as "#/$defs/" for JSON Schema or "#/components/schemas/" for OpenAPI.
+func NewDefinitions(refPrefix string) Definitions {
+	return newDefinitions(refPrefix)
+}
+
+// NewHandler returns a new protoplugin.Handler that generates a JSON Schema for
+// every message in the files to generate.
+//
+// Each schema is written to a file in the same directory as the file that defines
+// the message, named by the full name of the message and FileExtension. Each schema
+// is self-contained, with the messages and enums that it refers to in "$defs".
+func NewHandler() protoplugin.Handler {
+	return protoplugin.HandlerFunc(handle)
+}
+
+// GetDescription returns the description of the descriptor from its leading comments.
+//
+// Returns the empty string if the descriptor has no leading comments.
+func GetDescription(descriptor protoreflect.Descriptor) string {
+	leadingComments := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor).LeadingComments
+	lines := strings.Split(strings.TrimSpace(leadingComments), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// IsDeprecated returns true if the descriptor has the deprecated option set.
+func IsDeprecated(descriptor protoreflect.Descriptor) bool {
+	options, ok := descriptor.Options().(interface{ GetDeprecated() bool })
+	return ok && options.GetDeprecated()
+}
+
+// MarshalJSON marshals the value as indented JSON, without escaping HTML characters.
+func MarshalJSON(value any) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// *** PRIVATE ***
+
+func handle(
+	_ context.Context,
+	_ protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	if parameter := request.Parameter(); parameter != "" {
+		return fmt.Errorf("unknown parameter: %q", parameter)
+	}
+	fileDescriptors, err := request.FileDescriptorsToGenerate()
+	if err != nil {
+		return err
+	}
+	for _, fileDescriptor := range fileDescriptors {
+		if err := rangeMessages(
+			fileDescriptor.Messages(),
+			func(messageDescriptor protoreflect.MessageDescriptor) error {
+				data, err := MarshalJSON(newMessageDocument(messageDescriptor))
+				if err != nil {
+					return err
+				}
+				responseWriter.AddFile(
+					path.Join(
+						path.Dir(fileDescriptor.Path()),
+						string(messageDescriptor.FullName())+FileExtension,
+					),
+					string(data),
+				)
+				return nil
+			},
+		); err != nil {
+			return err
+		}
+	}
+	responseWriter.SetFeatureProto3Optional()
+	responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+	return nil
+}
+
+// newMessageDocument returns the self-contained JSON Schema document for the message.
+func newMessageDocument(messageDescriptor protoreflect.MessageDescriptor) Schema {
+	definitions := NewDefinitions("#/$defs/")
+	document := Schema{
+		"$schema": Dialect,
+	}
+	for key, value := range definitions.Ref(messageDescriptor) {
+		document[key] = value
+	}
+	if schemas := definitions.Schemas(); len(schemas) > 0 {
+		document["$defs"] = schemas
+	}
+	return document
+}
+
+// rangeMessages calls f for every message and nested message, except map entries.
+func rangeMessages(
+	messageDescriptors protoreflect.MessageDescriptors,
+	f func(protoreflect.MessageDescriptor) error,
+) error {
+	for i := 0; i < messageDescriptors.Len(); i++ {
+		messageDescriptor := messageDescriptors.Get(i)
+		if messageDescriptor.IsMapEntry() {
+			continue
+		}
+		if err := f(messageDescriptor); err != nil {
+			return err
+		}
+		if err := rangeMessages(messageDescriptor.Messages(), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
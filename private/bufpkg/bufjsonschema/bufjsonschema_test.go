@@ -0,0 +1,293 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufjsonschema
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protoplugin"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+const testUserFile = `syntax = "proto3";
+
+package acme.v1;
+
+import "buf/validate/validate.proto";
+import "google/protobuf/timestamp.proto";
+import "google/protobuf/wrappers.proto";
+
+// A user.
+message User {
+  // The ID of the user.
+  string id = 1 [
+    (buf.validate.field).required = true,
+    (buf.validate.field).string.uuid = true
+  ];
+  string email = 2 [(buf.validate.field).string = {email: true, max_len: 254}];
+  int32 age = 3 [(buf.validate.field).int32 = {gte: 0, lt: 150}];
+  int64 balance = 4;
+  repeated string tags = 5 [(buf.validate.field).repeated = {
+    max_items: 10,
+    unique: true,
+    items: {string: {min_len: 1}}
+  }];
+  map<string, double> scores = 6;
+  Status status = 7 [(buf.validate.field).enum.not_in = 0];
+  google.protobuf.Timestamp create_time = 8;
+  google.protobuf.StringValue nickname = 9 [deprecated = true];
+  bytes avatar = 10;
+  User manager = 11;
+  int32 level = 12 [(buf.validate.field) = {
+    ignore: IGNORE_ALWAYS,
+    int32: {gt: 0}
+  }];
+  // An address.
+  message Address {
+    string city = 1;
+  }
+  Address address = 13;
+}
+
+// The status of a user.
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_ACTIVE = 1;
+}
+`
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	response, err := testRunHandler(t, "", "acme/v1/user.proto", testUserFile)
+	require.NoError(t, err)
+	require.Empty(t, response.GetError())
+	require.Equal(t, 2, len(response.GetFile()))
+	require.Equal(t, "acme/v1/acme.v1.User.schema.json", response.GetFile()[0].GetName())
+	require.Equal(t, "acme/v1/acme.v1.User.Address.schema.json", response.GetFile()[1].GetName())
+	require.JSONEq(
+		t,
+		`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$ref": "#/$defs/acme.v1.User",
+  "$defs": {
+    "acme.v1.Status": {
+      "type": "string",
+      "enum": ["STATUS_UNSPECIFIED", "STATUS_ACTIVE"],
+      "description": "The status of a user."
+    },
+    "acme.v1.User": {
+      "type": "object",
+      "description": "A user.",
+      "properties": {
+        "id": {"type": "string", "format": "uuid", "description": "The ID of the user."},
+        "email": {"type": "string", "format": "email", "maxLength": 254},
+        "age": {"type": "integer", "format": "int32", "minimum": 0, "exclusiveMaximum": 150},
+        "balance": {"type": "string", "format": "int64", "pattern": "^-?[0-9]+$"},
+        "tags": {
+          "type": "array",
+          "items": {"type": "string", "minLength": 1},
+          "maxItems": 10,
+          "uniqueItems": true
+        },
+        "scores": {
+          "type": "object",
+          "additionalProperties": {
+            "anyOf": [
+              {"type": "number", "format": "double"},
+              {"type": "string", "enum": ["NaN", "Infinity", "-Infinity"]}
+            ]
+          }
+        },
+        "status": {"$ref": "#/$defs/acme.v1.Status", "not": {"enum": ["STATUS_UNSPECIFIED"]}},
+        "createTime": {"type": "string", "format": "date-time"},
+        "nickname": {"type": "string", "deprecated": true},
+        "avatar": {"type": "string", "contentEncoding": "base64"},
+        "manager": {"$ref": "#/$defs/acme.v1.User"},
+        "level": {"type": "integer", "format": "int32"},
+        "address": {"$ref": "#/$defs/acme.v1.User.Address"}
+      },
+      "required": ["id"]
+    },
+    "acme.v1.User.Address": {
+      "type": "object",
+      "description": "An address.",
+      "properties": {
+        "city": {"type": "string"}
+      }
+    }
+  }
+}`,
+		response.GetFile()[0].GetContent(),
+	)
+}
+
+func TestHandlerUnknownParameter(t *testing.T) {
+	t.Parallel()
+	_, err := testRunHandler(t, "foo=bar", "acme/v1/user.proto", testUserFile)
+	require.EqualError(t, err, `unknown parameter: "foo=bar"`)
+}
+
+func TestConstraintsRanges(t *testing.T) {
+	t.Parallel()
+	response, err := testRunHandler(
+		t,
+		"",
+		"acme/v1/ranges.proto",
+		`syntax = "proto3";
+package acme.v1;
+import "buf/validate/validate.proto";
+message Ranges {
+  // Outside of the range, which has no equivalent keywords.
+  int32 outside = 1 [(buf.validate.field).int32 = {lt: 0, gt: 10}];
+  // 64-bit integers are strings.
+  int64 big = 2 [(buf.validate.field).int64 = {gt: 0, in: [1, 2]}];
+  float ratio = 3 [(buf.validate.field).float = {gte: 0.1, lte: 1}];
+  string code = 4 [(buf.validate.field).string = {len: 2, pattern: "^[A-Z]+$"}];
+  map<string, string> labels = 5 [(buf.validate.field).map = {
+    max_pairs: 4,
+    keys: {string: {min_len: 1}},
+    values: {string: {const: "x"}}
+  }];
+}
+`,
+	)
+	require.NoError(t, err)
+	require.Empty(t, response.GetError())
+	require.Equal(t, 1, len(response.GetFile()))
+	require.JSONEq(
+		t,
+		`{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$ref": "#/$defs/acme.v1.Ranges",
+  "$defs": {
+    "acme.v1.Ranges": {
+      "type": "object",
+      "properties": {
+        "outside": {
+          "type": "integer",
+          "format": "int32",
+          "description": "Outside of the range, which has no equivalent keywords."
+        },
+        "big": {
+          "type": "string",
+          "format": "int64",
+          "pattern": "^-?[0-9]+$",
+          "enum": ["1", "2"],
+          "description": "64-bit integers are strings."
+        },
+        "ratio": {
+          "anyOf": [
+            {"type": "number", "format": "float"},
+            {"type": "string", "enum": ["NaN", "Infinity", "-Infinity"]}
+          ],
+          "minimum": 0.1,
+          "maximum": 1
+        },
+        "code": {"type": "string", "minLength": 2, "maxLength": 2, "pattern": "^[A-Z]+$"},
+        "labels": {
+          "type": "object",
+          "additionalProperties": {"type": "string", "const": "x"},
+          "propertyNames": {"minLength": 1},
+          "maxProperties": 4
+        }
+      }
+    }
+  }
+}`,
+		response.GetFile()[0].GetContent(),
+	)
+}
+
+// testRunHandler compiles the file and runs the handler with a request to
+// generate the file, and returns the error of the handler.
+func testRunHandler(
+	t *testing.T,
+	parameter string,
+	path string,
+	content string,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.CompositeResolver{
+			&protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(map[string]string{
+					path: content,
+				}),
+			},
+			protocompile.ResolverFunc(func(path string) (protocompile.SearchResult, error) {
+				fileDescriptor, err := protoregistry.GlobalFiles.FindFileByPath(path)
+				if err != nil {
+					return protocompile.SearchResult{}, err
+				}
+				return protocompile.SearchResult{Desc: fileDescriptor}, nil
+			}),
+		},
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	files, err := compiler.Compile(context.Background(), path)
+	require.NoError(t, err)
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{path},
+		Parameter:      proto.String(parameter),
+		ProtoFile:      testGetFileDescriptorProtos(files[0], make(map[string]struct{})),
+	}
+	requestData, err := proto.Marshal(request)
+	require.NoError(t, err)
+	stdout := bytes.NewBuffer(nil)
+	err = protoplugin.Run(
+		context.Background(),
+		protoplugin.Env{
+			Stdin:  bytes.NewReader(requestData),
+			Stdout: stdout,
+			Stderr: bytes.NewBuffer(nil),
+		},
+		NewHandler(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	response := &pluginpb.CodeGeneratorResponse{}
+	require.NoError(t, proto.Unmarshal(stdout.Bytes(), response))
+	return response, nil
+}
+
+// testGetFileDescriptorProtos returns the file and its imports, with imports
+// before the files that import them.
+func testGetFileDescriptorProtos(
+	fileDescriptor protoreflect.FileDescriptor,
+	seen map[string]struct{},
+) []*descriptorpb.FileDescriptorProto {
+	if _, ok := seen[fileDescriptor.Path()]; ok {
+		return nil
+	}
+	seen[fileDescriptor.Path()] = struct{}{}
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	imports := fileDescriptor.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		fileDescriptorProtos = append(
+			fileDescriptorProtos,
+			testGetFileDescriptorProtos(imports.Get(i).FileDescriptor, seen)...,
+		)
+	}
+	return append(fileDescriptorProtos, protodesc.ToFileDescriptorProto(fileDescriptor))
+}
@@ -0,0 +1,285 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufjsonschema
+
+import (
+	"encoding/base64"
+	"math"
+	"strconv"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/bufbuild/protovalidate-go/resolver"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// stringFormats maps the well-known string rules of protovalidate to the
+// equivalent formats of JSON Schema.
+var stringFormats = map[protoreflect.Name]string{
+	"email":    "email",
+	"hostname": "hostname",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"uri":      "uri",
+	"uri_ref":  "uri-reference",
+	"uuid":     "uuid",
+}
+
+// getFieldConstraints returns the protovalidate constraints of the field, or nil
+// if the field has no constraints or if its constraints are always ignored.
+func getFieldConstraints(fieldDescriptor protoreflect.FieldDescriptor) *validate.FieldConstraints {
+	fieldConstraints := resolver.DefaultResolver{}.ResolveFieldConstraints(fieldDescriptor)
+	if fieldConstraints.GetIgnore() == validate.Ignore_IGNORE_ALWAYS {
+		return nil
+	}
+	return fieldConstraints
+}
+
+func applyRepeatedConstraints(
+	schema Schema,
+	itemSchema Schema,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	fieldConstraints *validate.FieldConstraints,
+) {
+	repeatedRules := fieldConstraints.GetRepeated()
+	if repeatedRules == nil {
+		return
+	}
+	if repeatedRules.MinItems != nil {
+		schema["minItems"] = repeatedRules.GetMinItems()
+	}
+	if repeatedRules.MaxItems != nil {
+		schema["maxItems"] = repeatedRules.GetMaxItems()
+	}
+	if repeatedRules.GetUnique() {
+		schema["uniqueItems"] = true
+	}
+	applyConstraints(itemSchema, fieldDescriptor, repeatedRules.GetItems())
+}
+
+func applyMapConstraints(
+	schema Schema,
+	keySchema Schema,
+	valueSchema Schema,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	fieldConstraints *validate.FieldConstraints,
+) {
+	mapRules := fieldConstraints.GetMap()
+	if mapRules == nil {
+		return
+	}
+	if mapRules.MinPairs != nil {
+		schema["minProperties"] = mapRules.GetMinPairs()
+	}
+	if mapRules.MaxPairs != nil {
+		schema["maxProperties"] = mapRules.GetMaxPairs()
+	}
+	// Keys are always strings in JSON, so only the rules of string keys apply.
+	if fieldDescriptor.MapKey().Kind() == protoreflect.StringKind {
+		applyConstraints(keySchema, fieldDescriptor.MapKey(), mapRules.GetKeys())
+	}
+	applyConstraints(valueSchema, fieldDescriptor.MapValue(), mapRules.GetValues())
+}
+
+// applyConstraints adds the keywords that are equivalent to the type-specific
+// rules of the field constraints to the schema.
+//
+// The rules are read by reflection, as the rules of all types share the names
+// of the rules that have an equivalent keyword.
+func applyConstraints(
+	schema Schema,
+	fieldDescriptor protoreflect.FieldDescriptor,
+	fieldConstraints *validate.FieldConstraints,
+) {
+	if fieldConstraints == nil || fieldConstraints.GetIgnore() == validate.Ignore_IGNORE_ALWAYS {
+		return
+	}
+	fieldConstraintsMessage := fieldConstraints.ProtoReflect()
+	typeFieldDescriptor := fieldConstraintsMessage.WhichOneof(
+		fieldConstraintsMessage.Descriptor().Oneofs().ByName("type"),
+	)
+	if typeFieldDescriptor == nil || typeFieldDescriptor.Message() == nil {
+		return
+	}
+	typeName := typeFieldDescriptor.Name()
+	if typeName == "repeated" || typeName == "map" {
+		return
+	}
+	isString := typeName == "string"
+	var lowerKeyword, upperKeyword string
+	var lower, upper float64
+	fieldConstraintsMessage.Get(typeFieldDescriptor).Message().Range(
+		func(ruleFieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+			name := ruleFieldDescriptor.Name()
+			switch name {
+			case "const":
+				if jsonValue, ok := getJSONValue(fieldDescriptor, ruleFieldDescriptor, value); ok {
+					schema["const"] = jsonValue
+				}
+			case "in", "not_in":
+				jsonValues, ok := getJSONValues(fieldDescriptor, ruleFieldDescriptor, value.List())
+				if !ok {
+					return true
+				}
+				if name == "in" {
+					schema["enum"] = jsonValues
+				} else {
+					schema["not"] = Schema{"enum": jsonValues}
+				}
+			case "gt", "gte":
+				if number, ok := getJSONNumber(ruleFieldDescriptor, value); ok {
+					lowerKeyword, lower = "minimum", number
+					if name == "gt" {
+						lowerKeyword = "exclusiveMinimum"
+					}
+				}
+			case "lt", "lte":
+				if number, ok := getJSONNumber(ruleFieldDescriptor, value); ok {
+					upperKeyword, upper = "maximum", number
+					if name == "lt" {
+						upperKeyword = "exclusiveMaximum"
+					}
+				}
+			case "len":
+				if isString {
+					schema["minLength"] = value.Uint()
+					schema["maxLength"] = value.Uint()
+				}
+			case "min_len":
+				if isString {
+					schema["minLength"] = value.Uint()
+				}
+			case "max_len":
+				if isString {
+					schema["maxLength"] = value.Uint()
+				}
+			case "pattern":
+				if isString {
+					schema["pattern"] = value.String()
+				}
+			default:
+				if format, ok := stringFormats[name]; ok && isString && value.Bool() {
+					schema["format"] = format
+				}
+			}
+			return true
+		},
+	)
+	// A lower bound that is greater than the upper bound means that the value
+	// must be outside of the range, which has no equivalent keywords.
+	if lowerKeyword != "" && upperKeyword != "" && lower > upper {
+		return
+	}
+	if lowerKeyword != "" {
+		schema[lowerKeyword] = lower
+	}
+	if upperKeyword != "" {
+		schema[upperKeyword] = upper
+	}
+}
+
+func getJSONValues(
+	fieldDescriptor protoreflect.FieldDescriptor,
+	ruleFieldDescriptor protoreflect.FieldDescriptor,
+	list protoreflect.List,
+) ([]any, bool) {
+	jsonValues := make([]any, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		jsonValue, ok := getJSONValue(fieldDescriptor, ruleFieldDescriptor, list.Get(i))
+		if !ok {
+			return nil, false
+		}
+		jsonValues = append(jsonValues, jsonValue)
+	}
+	return jsonValues, true
+}
+
+// getJSONValue returns the value of a rule in the JSON mapping of the field.
+//
+// Returns false if the value cannot be represented, such as values of rules
+// on well-known types.
+func getJSONValue(
+	fieldDescriptor protoreflect.FieldDescriptor,
+	ruleFieldDescriptor protoreflect.FieldDescriptor,
+	value protoreflect.Value,
+) (any, bool) {
+	if fieldDescriptor.Kind() == protoreflect.EnumKind {
+		enumValueDescriptor := fieldDescriptor.Enum().Values().ByNumber(protoreflect.EnumNumber(value.Int()))
+		if enumValueDescriptor == nil {
+			return nil, false
+		}
+		return string(enumValueDescriptor.Name()), true
+	}
+	switch ruleFieldDescriptor.Kind() {
+	case protoreflect.BoolKind:
+		return value.Bool(), true
+	case protoreflect.StringKind:
+		return value.String(), true
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(value.Bytes()), true
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return value.Int(), true
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return value.Uint(), true
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return strconv.FormatInt(value.Int(), 10), true
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return strconv.FormatUint(value.Uint(), 10), true
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		float := getFloat(ruleFieldDescriptor, value)
+		switch {
+		case math.IsNaN(float):
+			return "NaN", true
+		case math.IsInf(float, 1):
+			return "Infinity", true
+		case math.IsInf(float, -1):
+			return "-Infinity", true
+		default:
+			return float, true
+		}
+	default:
+		return nil, false
+	}
+}
+
+// getJSONNumber returns the value of a rule as a JSON number.
+//
+// Returns false if values of the rule are not JSON numbers, such as 64-bit integers
+// which are strings, and durations and timestamps.
+func getJSONNumber(ruleFieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) (float64, bool) {
+	switch ruleFieldDescriptor.Kind() {
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return float64(value.Int()), true
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return float64(value.Uint()), true
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		float := getFloat(ruleFieldDescriptor, value)
+		if math.IsNaN(float) || math.IsInf(float, 0) {
+			return 0, false
+		}
+		return float, true
+	default:
+		return 0, false
+	}
+}
+
+// getFloat returns the value as a float64, with floats converted by their shortest
+// representation so that 0.1 is not 0.10000000149011612.
+func getFloat(ruleFieldDescriptor protoreflect.FieldDescriptor, value protoreflect.Value) float64 {
+	float := value.Float()
+	if ruleFieldDescriptor.Kind() == protoreflect.FloatKind && !math.IsNaN(float) && !math.IsInf(float, 0) {
+		float, _ = strconv.ParseFloat(strconv.FormatFloat(float, 'g', -1, 32), 64)
+	}
+	return float
+}
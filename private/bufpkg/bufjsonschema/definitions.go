@@ -0,0 +1,248 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufjsonschema
+
+import (
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"github.com/bufbuild/protovalidate-go/resolver"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	int64Pattern    = `^-?[0-9]+$`
+	uint64Pattern   = `^[0-9]+$`
+	durationPattern = `^-?[0-9]+(\.[0-9]+)?s$`
+)
+
+type definitions struct {
+	refPrefix string
+	schemas   map[string]Schema
+}
+
+func newDefinitions(refPrefix string) *definitions {
+	return &definitions{
+		refPrefix: refPrefix,
+		schemas:   make(map[string]Schema),
+	}
+}
+
+func (d *definitions) Ref(messageDescriptor protoreflect.MessageDescriptor) Schema {
+	if schema := getWellKnownTypeSchema(messageDescriptor.FullName()); schema != nil {
+		return schema
+	}
+	fullName := string(messageDescriptor.FullName())
+	if _, ok := d.schemas[fullName]; !ok {
+		// The schema is added before it is built, so that messages that refer
+		// to themselves, directly or not, do not recurse forever.
+		schema := Schema{}
+		d.schemas[fullName] = schema
+		d.buildMessageSchema(schema, messageDescriptor)
+	}
+	return Schema{"$ref": d.refPrefix + fullName}
+}
+
+func (d *definitions) Schemas() map[string]Schema {
+	return d.schemas
+}
+
+func (*definitions) isDefinitions() {}
+
+func (d *definitions) enumRef(enumDescriptor protoreflect.EnumDescriptor) Schema {
+	fullName := string(enumDescriptor.FullName())
+	if _, ok := d.schemas[fullName]; !ok {
+		values := enumDescriptor.Values()
+		names := make([]string, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		schema := Schema{
+			"type": "string",
+			"enum": names,
+		}
+		addDescription(schema, enumDescriptor)
+		d.schemas[fullName] = schema
+	}
+	return Schema{"$ref": d.refPrefix + fullName}
+}
+
+func (d *definitions) buildMessageSchema(schema Schema, messageDescriptor protoreflect.MessageDescriptor) {
+	schema["type"] = "object"
+	addDescription(schema, messageDescriptor)
+	messageConstraints := resolver.DefaultResolver{}.ResolveMessageConstraints(messageDescriptor)
+	fields := messageDescriptor.Fields()
+	properties := make(map[string]Schema, fields.Len())
+	var required []string
+	for i := 0; i < fields.Len(); i++ {
+		fieldDescriptor := fields.Get(i)
+		fieldConstraints := getFieldConstraints(fieldDescriptor)
+		if messageConstraints.GetDisabled() {
+			fieldConstraints = nil
+		}
+		properties[fieldDescriptor.JSONName()] = d.fieldSchema(fieldDescriptor, fieldConstraints)
+		if fieldDescriptor.Cardinality() == protoreflect.Required || fieldConstraints.GetRequired() {
+			required = append(required, fieldDescriptor.JSONName())
+		}
+	}
+	if len(properties) > 0 {
+		schema["properties"] = properties
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+}
+
+func (d *definitions) fieldSchema(
+	fieldDescriptor protoreflect.FieldDescriptor,
+	fieldConstraints *validate.FieldConstraints,
+) Schema {
+	var schema Schema
+	switch {
+	case fieldDescriptor.IsMap():
+		keySchema := Schema{}
+		valueSchema := d.singularSchema(fieldDescriptor.MapValue())
+		schema = Schema{
+			"type":                 "object",
+			"additionalProperties": valueSchema,
+		}
+		applyMapConstraints(schema, keySchema, valueSchema, fieldDescriptor, fieldConstraints)
+		if len(keySchema) > 0 {
+			schema["propertyNames"] = keySchema
+		}
+	case fieldDescriptor.IsList():
+		itemSchema := d.singularSchema(fieldDescriptor)
+		schema = Schema{
+			"type":  "array",
+			"items": itemSchema,
+		}
+		applyRepeatedConstraints(schema, itemSchema, fieldDescriptor, fieldConstraints)
+	default:
+		schema = d.singularSchema(fieldDescriptor)
+		applyConstraints(schema, fieldDescriptor, fieldConstraints)
+	}
+	addDescription(schema, fieldDescriptor)
+	return schema
+}
+
+// singularSchema returns the schema of a single value of the field, ignoring
+// whether the field is repeated.
+func (d *definitions) singularSchema(fieldDescriptor protoreflect.FieldDescriptor) Schema {
+	switch fieldDescriptor.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return d.Ref(fieldDescriptor.Message())
+	case protoreflect.EnumKind:
+		if fieldDescriptor.Enum().FullName() == "google.protobuf.NullValue" {
+			return Schema{"type": "null"}
+		}
+		return d.enumRef(fieldDescriptor.Enum())
+	default:
+		return getScalarSchema(fieldDescriptor.Kind())
+	}
+}
+
+func getScalarSchema(kind protoreflect.Kind) Schema {
+	switch kind {
+	case protoreflect.BoolKind:
+		return Schema{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return Schema{"type": "integer", "format": "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return Schema{"type": "integer", "minimum": 0}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return Schema{"type": "string", "format": "int64", "pattern": int64Pattern}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return Schema{"type": "string", "format": "uint64", "pattern": uint64Pattern}
+	case protoreflect.FloatKind:
+		return newFloatSchema("float")
+	case protoreflect.DoubleKind:
+		return newFloatSchema("double")
+	case protoreflect.StringKind:
+		return Schema{"type": "string"}
+	case protoreflect.BytesKind:
+		return Schema{"type": "string", "contentEncoding": "base64"}
+	default:
+		return Schema{}
+	}
+}
+
+// getWellKnownTypeSchema returns the schema of the well-known type with the given
+// full name, or nil if it is not a well-known type with a special JSON mapping.
+//
+// A new schema is returned on every call, so that callers can modify it.
+func getWellKnownTypeSchema(fullName protoreflect.FullName) Schema {
+	switch fullName {
+	case "google.protobuf.Any":
+		return Schema{
+			"type": "object",
+			"properties": map[string]Schema{
+				"@type": {"type": "string"},
+			},
+			"required": []string{"@type"},
+		}
+	case "google.protobuf.Timestamp":
+		return Schema{"type": "string", "format": "date-time"}
+	case "google.protobuf.Duration":
+		return Schema{"type": "string", "pattern": durationPattern}
+	case "google.protobuf.FieldMask":
+		return Schema{"type": "string"}
+	case "google.protobuf.Struct", "google.protobuf.Empty":
+		return Schema{"type": "object"}
+	case "google.protobuf.Value":
+		return Schema{}
+	case "google.protobuf.ListValue":
+		return Schema{"type": "array"}
+	case "google.protobuf.DoubleValue":
+		return getScalarSchema(protoreflect.DoubleKind)
+	case "google.protobuf.FloatValue":
+		return getScalarSchema(protoreflect.FloatKind)
+	case "google.protobuf.Int64Value":
+		return getScalarSchema(protoreflect.Int64Kind)
+	case "google.protobuf.UInt64Value":
+		return getScalarSchema(protoreflect.Uint64Kind)
+	case "google.protobuf.Int32Value":
+		return getScalarSchema(protoreflect.Int32Kind)
+	case "google.protobuf.UInt32Value":
+		return getScalarSchema(protoreflect.Uint32Kind)
+	case "google.protobuf.BoolValue":
+		return getScalarSchema(protoreflect.BoolKind)
+	case "google.protobuf.StringValue":
+		return getScalarSchema(protoreflect.StringKind)
+	case "google.protobuf.BytesValue":
+		return getScalarSchema(protoreflect.BytesKind)
+	default:
+		return nil
+	}
+}
+
+// newFloatSchema returns the schema of a float or double, which are numbers
+// except for the special values that are strings.
+func newFloatSchema(format string) Schema {
+	return Schema{
+		"anyOf": []Schema{
+			{"type": "number", "format": format},
+			{"type": "string", "enum": []string{"NaN", "Infinity", "-Infinity"}},
+		},
+	}
+}
+
+// addDescription adds the description and the deprecation of the descriptor
+// to the schema.
+func addDescription(schema Schema, descriptor protoreflect.Descriptor) {
+	if description := GetDescription(descriptor); description != "" {
+		schema["description"] = description
+	}
+	if IsDeprecated(descriptor) {
+		schema["deprecated"] = true
+	}
+}
@@ -17,7 +17,9 @@ package bufmodule_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
@@ -27,6 +29,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/slogtestext"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -696,3 +699,74 @@ func testTargetFilePaths(t *testing.T, module bufmodule.Module, expectedFilePath
 		bufmodule.FileInfoPaths(fileInfos),
 	)
 }
+
+func TestSignatures(t *testing.T) {
+	t.Parallel()
+
+	signingKey, err := bufmodule.NewSigningKey()
+	require.NoError(t, err)
+	data, err := bufmodule.SigningKeyToPEM(signingKey)
+	require.NoError(t, err)
+	parsedSigningKey, err := bufmodule.ParseSigningKeyPEM(data)
+	require.NoError(t, err)
+	require.True(t, bufmodule.PublicKeyEqual(signingKey.PublicKey(), parsedSigningKey.PublicKey()))
+	publicKey, err := bufmodule.ParsePublicKey(signingKey.PublicKey().String())
+	require.NoError(t, err)
+	require.True(t, bufmodule.PublicKeyEqual(signingKey.PublicKey(), publicKey))
+	_, err = bufmodule.ParsePublicKey("ed25519:AAAA")
+	require.Error(t, err)
+	_, err = bufmodule.ParsePublicKey("rsa:AAAA")
+	require.Error(t, err)
+	otherSigningKey, err := bufmodule.NewSigningKey()
+	require.NoError(t, err)
+
+	fullName, err := bufparse.ParseFullName("buf.build/foo/a")
+	require.NoError(t, err)
+	otherFullName, err := bufparse.ParseFullName("buf.build/foo/b")
+	require.NoError(t, err)
+	digest, err := bufmodule.ParseDigest("b5:" + strings.Repeat("ab", 64))
+	require.NoError(t, err)
+	b4Digest, err := bufmodule.ParseDigest("shake256:" + strings.Repeat("ab", 64))
+	require.NoError(t, err)
+	_, err = bufmodule.SignModuleDigest(signingKey, fullName, b4Digest)
+	require.Error(t, err)
+	signature, err := bufmodule.SignModuleDigest(signingKey, fullName, digest)
+	require.NoError(t, err)
+	otherSignature, err := bufmodule.SignModuleDigest(otherSigningKey, fullName, digest)
+	require.NoError(t, err)
+	// A valid signature for another Module with the same content.
+	otherFullNameSignature, err := bufmodule.SignModuleDigest(signingKey, otherFullName, digest)
+	require.NoError(t, err)
+
+	newCommit := func(signatures ...bufmodule.Signature) bufmodule.Commit {
+		moduleKey, err := bufmodule.NewModuleKey(
+			fullName,
+			uuid.New(),
+			func() (bufmodule.Digest, error) {
+				return digest, nil
+			},
+		)
+		require.NoError(t, err)
+		return bufmodule.NewCommit(
+			moduleKey,
+			func() (time.Time, error) {
+				return time.Time{}, nil
+			},
+			bufmodule.CommitWithSignatures(signatures),
+		)
+	}
+	trustedPublicKeys := []bufmodule.PublicKey{signingKey.PublicKey()}
+	require.NoError(t, bufmodule.VerifyCommitSignatures([]bufmodule.Commit{newCommit(signature)}, trustedPublicKeys))
+	require.NoError(t, bufmodule.VerifyCommitSignatures([]bufmodule.Commit{newCommit(otherSignature, signature)}, trustedPublicKeys))
+	require.ErrorContains(t, bufmodule.VerifyCommitSignatures([]bufmodule.Commit{newCommit()}, trustedPublicKeys), "is not signed")
+	require.ErrorContains(t, bufmodule.VerifyCommitSignatures([]bufmodule.Commit{newCommit(otherSignature)}, trustedPublicKeys), "not signed by a trusted key")
+	require.ErrorContains(t, bufmodule.VerifyCommitSignatures([]bufmodule.Commit{newCommit(otherFullNameSignature)}, trustedPublicKeys), "invalid signature")
+	require.ErrorContains(
+		t,
+		bufmodule.VerifyCommitSignatures(
+			[]bufmodule.Commit{newCommit(bufmodule.NewSignature(signingKey.PublicKey(), otherSignature.Value()))},
+			trustedPublicKeys,
+		),
+		"invalid signature",
+	)
+}
@@ -16,6 +16,7 @@ package bufmoduleapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -103,6 +104,9 @@ func (a *uploader) Upload(
 	if err != nil {
 		return nil, err
 	}
+	if uploadOptions.SigningKey() != nil {
+		return nil, errors.New("signing commits is not supported by the BSR")
+	}
 
 	contentModules, err := bufmodule.ModuleSetTargetLocalModulesAndTransitiveLocalDeps(moduleSet)
 	if err != nil {
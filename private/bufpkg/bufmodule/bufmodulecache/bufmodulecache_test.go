@@ -162,6 +162,71 @@ func TestCommitProviderForCommitKeyBasic(t *testing.T) {
 	)
 }
 
+func TestCommitProviderRefreshUnsigned(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	bsrProvider, moduleKeys := testGetBSRProviderAndModuleKeys(t, ctx)
+	logger := slogtestext.NewLogger(t)
+	signingKey, err := bufmodule.NewSigningKey()
+	require.NoError(t, err)
+	signingProvider := &testSigningCommitProvider{
+		delegate:   bsrProvider,
+		signingKey: signingKey,
+	}
+	store := bufmodulestore.NewCommitStore(
+		logger,
+		storagemem.NewReadWriteBucket(),
+	)
+	// Commits stored by older versions have no Signatures.
+	unsignedCommits, err := bsrProvider.GetCommitsForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.NoError(t, store.PutCommits(ctx, unsignedCommits))
+
+	commits, err := newCommitProvider(
+		logger,
+		signingProvider,
+		store,
+	).GetCommitsForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Error(t, bufmodule.VerifyCommitSignatures(commits, []bufmodule.PublicKey{signingKey.PublicKey()}))
+	require.Equal(t, 0, signingProvider.keysRetrieved)
+
+	cacheProvider := newCommitProvider(
+		logger,
+		signingProvider,
+		store,
+		CommitProviderWithRefreshUnsigned(),
+	)
+	commits, err = cacheProvider.GetCommitsForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.NoError(t, bufmodule.VerifyCommitSignatures(commits, []bufmodule.PublicKey{signingKey.PublicKey()}))
+	require.Equal(t, 3, signingProvider.keysRetrieved)
+	require.Equal(
+		t,
+		[]string{
+			"buf.build/foo/mod1",
+			"buf.build/foo/mod3",
+			"buf.build/foo/mod2",
+		},
+		slicesext.Map(
+			commits,
+			func(commit bufmodule.Commit) string {
+				return commit.ModuleKey().FullName().String()
+			},
+		),
+	)
+
+	// The refreshed Commits replace the Commits in the store.
+	commitKeys, err := slicesext.MapError(moduleKeys, bufmodule.ModuleKeyToCommitKey)
+	require.NoError(t, err)
+	commits, err = cacheProvider.GetCommitsForCommitKeys(ctx, commitKeys)
+	require.NoError(t, err)
+	require.NoError(t, bufmodule.VerifyCommitSignatures(commits, []bufmodule.PublicKey{signingKey.PublicKey()}))
+	require.Equal(t, 3, signingProvider.keysRetrieved)
+}
+
 func TestModuleDataProviderBasic(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -333,3 +398,54 @@ func testGetBSRProviderAndModuleKeys(t *testing.T, ctx context.Context) (bufmodu
 	require.Equal(t, 3, len(moduleKeys))
 	return bsrProvider, moduleKeys
 }
+
+type testSigningCommitProvider struct {
+	delegate      bufmodule.CommitProvider
+	signingKey    bufmodule.SigningKey
+	keysRetrieved int
+}
+
+func (p *testSigningCommitProvider) GetCommitsForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.Commit, error) {
+	commits, err := p.delegate.GetCommitsForModuleKeys(ctx, moduleKeys)
+	if err != nil {
+		return nil, err
+	}
+	return p.sign(commits)
+}
+
+func (p *testSigningCommitProvider) GetCommitsForCommitKeys(
+	ctx context.Context,
+	commitKeys []bufmodule.CommitKey,
+) ([]bufmodule.Commit, error) {
+	commits, err := p.delegate.GetCommitsForCommitKeys(ctx, commitKeys)
+	if err != nil {
+		return nil, err
+	}
+	return p.sign(commits)
+}
+
+func (p *testSigningCommitProvider) sign(commits []bufmodule.Commit) ([]bufmodule.Commit, error) {
+	p.keysRetrieved += len(commits)
+	return slicesext.MapError(
+		commits,
+		func(commit bufmodule.Commit) (bufmodule.Commit, error) {
+			moduleKey := commit.ModuleKey()
+			digest, err := moduleKey.Digest()
+			if err != nil {
+				return nil, err
+			}
+			signature, err := bufmodule.SignModuleDigest(p.signingKey, moduleKey.FullName(), digest)
+			if err != nil {
+				return nil, err
+			}
+			return bufmodule.NewCommit(
+				moduleKey,
+				commit.CreateTime,
+				bufmodule.CommitWithSignatures([]bufmodule.Signature{signature}),
+			), nil
+		},
+	)
+}
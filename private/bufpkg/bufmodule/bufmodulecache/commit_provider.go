@@ -20,6 +20,7 @@ import (
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulestore"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/google/uuid"
 )
 
@@ -30,23 +31,43 @@ func NewCommitProvider(
 	logger *slog.Logger,
 	delegate bufmodule.CommitProvider,
 	store bufmodulestore.CommitStore,
+	options ...CommitProviderOption,
 ) bufmodule.CommitProvider {
-	return newCommitProvider(logger, delegate, store)
+	return newCommitProvider(logger, delegate, store, options...)
+}
+
+// CommitProviderOption is an option for a new CommitProvider.
+type CommitProviderOption func(*commitProvider)
+
+// CommitProviderWithRefreshUnsigned returns a new CommitProviderOption that reads Commits
+// without Signatures from the delegate even if they are in the store, and replaces the
+// Commits in the store with the results.
+//
+// This should be used when the Signatures of the Commits are verified, as Commits stored
+// by older versions never have Signatures.
+func CommitProviderWithRefreshUnsigned() CommitProviderOption {
+	return func(commitProvider *commitProvider) {
+		commitProvider.refreshUnsigned = true
+	}
 }
 
 /// *** PRIVATE ***
 
 type commitProvider struct {
-	byModuleKey *baseProvider[bufmodule.ModuleKey, bufmodule.Commit]
-	byCommitKey *baseProvider[bufmodule.CommitKey, bufmodule.Commit]
+	byModuleKey     *baseProvider[bufmodule.ModuleKey, bufmodule.Commit]
+	byCommitKey     *baseProvider[bufmodule.CommitKey, bufmodule.Commit]
+	delegate        bufmodule.CommitProvider
+	store           bufmodulestore.CommitStore
+	refreshUnsigned bool
 }
 
 func newCommitProvider(
 	logger *slog.Logger,
 	delegate bufmodule.CommitProvider,
 	store bufmodulestore.CommitStore,
+	options ...CommitProviderOption,
 ) *commitProvider {
-	return &commitProvider{
+	commitProvider := &commitProvider{
 		byModuleKey: newBaseProvider(
 			logger,
 			delegate.GetCommitsForModuleKeys,
@@ -67,19 +88,71 @@ func newCommitProvider(
 				return commit.ModuleKey().CommitID()
 			},
 		),
+		delegate: delegate,
+		store:    store,
 	}
+	for _, option := range options {
+		option(commitProvider)
+	}
+	return commitProvider
 }
 
 func (p *commitProvider) GetCommitsForModuleKeys(
 	ctx context.Context,
 	moduleKeys []bufmodule.ModuleKey,
 ) ([]bufmodule.Commit, error) {
-	return p.byModuleKey.getValuesForKeys(ctx, moduleKeys)
+	commits, err := p.byModuleKey.getValuesForKeys(ctx, moduleKeys)
+	if err != nil {
+		return nil, err
+	}
+	return refreshUnsignedCommits(ctx, p, commits, moduleKeys, p.delegate.GetCommitsForModuleKeys)
 }
 
 func (p *commitProvider) GetCommitsForCommitKeys(
 	ctx context.Context,
 	commitKeys []bufmodule.CommitKey,
 ) ([]bufmodule.Commit, error) {
-	return p.byCommitKey.getValuesForKeys(ctx, commitKeys)
+	commits, err := p.byCommitKey.getValuesForKeys(ctx, commitKeys)
+	if err != nil {
+		return nil, err
+	}
+	return refreshUnsignedCommits(ctx, p, commits, commitKeys, p.delegate.GetCommitsForCommitKeys)
+}
+
+// refreshUnsignedCommits replaces the Commits without Signatures with the Commits from
+// the delegate, if refreshUnsigned is set. The keys are in the same order as the Commits.
+//
+// The Commits from the delegate are put to the store, so that they are only read from
+// the delegate once if they have Signatures.
+func refreshUnsignedCommits[K any](
+	ctx context.Context,
+	p *commitProvider,
+	commits []bufmodule.Commit,
+	keys []K,
+	delegateGetCommitsForKeys func(context.Context, []K) ([]bufmodule.Commit, error),
+) ([]bufmodule.Commit, error) {
+	if !p.refreshUnsigned {
+		return commits, nil
+	}
+	var unsignedIndexedKeys []slicesext.Indexed[K]
+	for i, commit := range commits {
+		if len(commit.Signatures()) == 0 {
+			unsignedIndexedKeys = append(unsignedIndexedKeys, slicesext.Indexed[K]{Value: keys[i], Index: i})
+		}
+	}
+	if len(unsignedIndexedKeys) == 0 {
+		return commits, nil
+	}
+	delegateCommits, err := delegateGetCommitsForKeys(ctx, slicesext.IndexedToValues(unsignedIndexedKeys))
+	if err != nil {
+		return nil, err
+	}
+	if err := p.store.PutCommits(ctx, delegateCommits); err != nil {
+		return nil, err
+	}
+	commits = slicesext.Copy(commits)
+	for i, delegateCommit := range delegateCommits {
+		commits[unsignedIndexedKeys[i].Index] = delegateCommit
+	}
+	return commits, nil
 }
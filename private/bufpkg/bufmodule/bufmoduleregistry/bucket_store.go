@@ -0,0 +1,180 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleregistry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+const (
+	bucketStoreCommitsDir = "commits"
+	bucketStoreModulesDir = "modules"
+	bucketStoreLabelsDir  = "labels"
+	bucketStoreModuleFile = "module.yaml"
+	bucketStoreYAMLExt    = ".yaml"
+	bucketStoreTarExt     = ".tar"
+)
+
+type bucketStore struct {
+	bucket storage.ReadWriteBucket
+}
+
+func newBucketStore(bucket storage.ReadWriteBucket) *bucketStore {
+	return &bucketStore{
+		bucket: bucket,
+	}
+}
+
+func (s *bucketStore) getModule(ctx context.Context, fullName bufparse.FullName) (*externalModule, error) {
+	path := normalpath.Join(getBucketStoreModuleDirPath(fullName), bucketStoreModuleFile)
+	var externalModule externalModule
+	if err := s.readYAML(ctx, path, &externalModule); err != nil {
+		return nil, err
+	}
+	if !externalModule.isValid() {
+		return nil, fmt.Errorf("invalid %s for %s", path, fullName.String())
+	}
+	return &externalModule, nil
+}
+
+func (s *bucketStore) putModule(ctx context.Context, fullName bufparse.FullName, externalModule *externalModule) error {
+	return s.writeYAML(
+		ctx,
+		normalpath.Join(getBucketStoreModuleDirPath(fullName), bucketStoreModuleFile),
+		externalModule,
+	)
+}
+
+func (s *bucketStore) getCommit(ctx context.Context, commitID uuid.UUID) (*externalCommit, error) {
+	path := getBucketStoreCommitPath(commitID)
+	var externalCommit externalCommit
+	if err := s.readYAML(ctx, path, &externalCommit); err != nil {
+		return nil, err
+	}
+	if !externalCommit.isValid() {
+		return nil, fmt.Errorf("invalid %s for %s", path, uuidutil.ToDashless(commitID))
+	}
+	return &externalCommit, nil
+}
+
+func (s *bucketStore) putCommit(
+	ctx context.Context,
+	fullName bufparse.FullName,
+	externalCommit *externalCommit,
+	files storage.ReadBucket,
+) error {
+	commitID, err := uuidutil.FromDashless(externalCommit.ID)
+	if err != nil {
+		return err
+	}
+	data, err := filesToTar(ctx, files)
+	if err != nil {
+		return err
+	}
+	if err := storage.PutPath(
+		ctx,
+		s.bucket,
+		getBucketStoreFilesPath(fullName, commitID),
+		data,
+		storage.PutWithAtomic(),
+	); err != nil {
+		return err
+	}
+	return s.writeYAML(ctx, getBucketStoreCommitPath(commitID), externalCommit)
+}
+
+func (s *bucketStore) getFiles(ctx context.Context, fullName bufparse.FullName, commitID uuid.UUID) (storage.ReadBucket, error) {
+	data, err := storage.ReadPath(ctx, s.bucket, getBucketStoreFilesPath(fullName, commitID))
+	if err != nil {
+		return nil, err
+	}
+	return tarToFiles(ctx, data)
+}
+
+func (s *bucketStore) getCommitIDForLabel(ctx context.Context, fullName bufparse.FullName, label string) (uuid.UUID, error) {
+	path := getBucketStoreLabelPath(fullName, label)
+	var externalLabel externalLabel
+	if err := s.readYAML(ctx, path, &externalLabel); err != nil {
+		return uuid.Nil, err
+	}
+	if !externalLabel.isValid() {
+		return uuid.Nil, fmt.Errorf("invalid %s for %s", path, fullName.String())
+	}
+	return uuidutil.FromDashless(externalLabel.Commit)
+}
+
+func (s *bucketStore) putLabel(ctx context.Context, fullName bufparse.FullName, label string, commitID uuid.UUID) error {
+	return s.writeYAML(
+		ctx,
+		getBucketStoreLabelPath(fullName, label),
+		&externalLabel{
+			Version: externalVersion,
+			Commit:  uuidutil.ToDashless(commitID),
+		},
+	)
+}
+
+func (s *bucketStore) readYAML(ctx context.Context, path string, v any) error {
+	data, err := storage.ReadPath(ctx, s.bucket, path)
+	if err != nil {
+		return err
+	}
+	return encoding.UnmarshalYAMLNonStrict(data, v)
+}
+
+func (s *bucketStore) writeYAML(ctx context.Context, path string, v any) error {
+	data, err := encoding.MarshalYAML(v)
+	if err != nil {
+		return err
+	}
+	return storage.PutPath(ctx, s.bucket, path, data, storage.PutWithAtomic())
+}
+
+func getBucketStoreModuleDirPath(fullName bufparse.FullName) string {
+	return normalpath.Join(
+		bucketStoreModulesDir,
+		fullName.Registry(),
+		fullName.Owner(),
+		fullName.Name(),
+	)
+}
+
+func getBucketStoreCommitPath(commitID uuid.UUID) string {
+	return normalpath.Join(bucketStoreCommitsDir, uuidutil.ToDashless(commitID)+bucketStoreYAMLExt)
+}
+
+func getBucketStoreFilesPath(fullName bufparse.FullName, commitID uuid.UUID) string {
+	return normalpath.Join(
+		getBucketStoreModuleDirPath(fullName),
+		bucketStoreCommitsDir,
+		uuidutil.ToDashless(commitID)+bucketStoreTarExt,
+	)
+}
+
+func getBucketStoreLabelPath(fullName bufparse.FullName, label string) string {
+	return normalpath.Join(
+		getBucketStoreModuleDirPath(fullName),
+		bucketStoreLabelsDir,
+		label+bucketStoreYAMLExt,
+	)
+}
@@ -0,0 +1,97 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufmoduleregistry provides module registries that are backed by a
+// directory tree or an OCI registry instead of the BSR.
+//
+// These are meant for air-gapped environments, where Modules are pushed to and
+// resolved from storage that is reachable without access to a BSR instance.
+package bufmoduleregistry
+
+import (
+	"log/slog"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/storage"
+)
+
+// Registry is a module registry.
+//
+// A Registry can be used wherever the BSR-backed providers and Uploader are used.
+//
+// Only b5 Digests are supported. Any request for another DigestType will result in an error.
+//
+// All dependencies of a Commit must be present in the same Registry, as a Registry
+// never calls out to the BSR.
+type Registry interface {
+	bufmodule.ModuleKeyProvider
+	bufmodule.ModuleDataProvider
+	bufmodule.CommitProvider
+	bufmodule.GraphProvider
+	bufmodule.Uploader
+
+	isRegistry()
+}
+
+// NewBucketRegistry returns a new Registry that stores Modules in the given bucket.
+//
+// It is assumed that the Registry has complete control of the bucket, and that
+// there is only a single writer at a time.
+//
+// The layout of the bucket is:
+//
+//	commits/<commit-id>.yaml
+//	modules/<registry>/<owner>/<name>/module.yaml
+//	modules/<registry>/<owner>/<name>/labels/<label>.yaml
+//	modules/<registry>/<owner>/<name>/commits/<commit-id>.tar
+func NewBucketRegistry(
+	logger *slog.Logger,
+	bucket storage.ReadWriteBucket,
+) Registry {
+	return newRegistry(logger, newBucketStore(bucket))
+}
+
+// NewOCIRegistry returns a new Registry that stores Modules as artifacts in an OCI registry.
+//
+// The repositoryPrefix is the OCI repository that all artifacts are stored beneath,
+// for example "registry.example.com/buf". Each Module is stored in the repository
+// <repositoryPrefix>/<registry>/<owner>/<name>, with each Commit tagged as
+// commit-<commit-id> and each label tagged as label-<label>. An index of Commits
+// is stored in the repository <repositoryPrefix>/commits.
+//
+// Credentials are read from the default Docker keychain.
+func NewOCIRegistry(
+	logger *slog.Logger,
+	repositoryPrefix string,
+	options ...OCIRegistryOption,
+) (Registry, error) {
+	store, err := newOCIStore(repositoryPrefix, options...)
+	if err != nil {
+		return nil, err
+	}
+	return newRegistry(logger, store), nil
+}
+
+// OCIRegistryOption is an option for a new OCI Registry.
+type OCIRegistryOption func(*ociStore)
+
+// OCIRegistryWithInsecure returns a new OCIRegistryOption that talks to the OCI
+// registry over plain HTTP.
+//
+// The default is to use HTTPS.
+func OCIRegistryWithInsecure() OCIRegistryOption {
+	return func(ociStore *ociStore) {
+		ociStore.insecure = true
+	}
+}
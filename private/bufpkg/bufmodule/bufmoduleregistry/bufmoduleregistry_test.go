@@ -0,0 +1,207 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleregistry
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	ociregistry "github.com/google/go-containerregistry/pkg/registry"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketRegistry(t *testing.T) {
+	t.Parallel()
+	testRegistry(t, NewBucketRegistry(slogtestext.NewLogger(t), storagemem.NewReadWriteBucket()))
+}
+
+func TestOCIRegistry(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(ociregistry.New(ociregistry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+	ociRegistry, err := NewOCIRegistry(
+		slogtestext.NewLogger(t),
+		strings.TrimPrefix(server.URL, "http://")+"/buf",
+		OCIRegistryWithInsecure(),
+	)
+	require.NoError(t, err)
+	testRegistry(t, ociRegistry)
+}
+
+func testRegistry(t *testing.T, registry Registry) {
+	ctx := context.Background()
+
+	moduleSet, err := bufmoduletesting.NewModuleSet(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/a",
+			PathToData: map[string][]byte{
+				"a.proto": []byte(`syntax = "proto3"; package a; message A {}`),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/b",
+			PathToData: map[string][]byte{
+				"b.proto": []byte(`syntax = "proto3"; package b; import "a.proto"; message B { a.A a = 1; }`),
+			},
+		},
+	)
+	require.NoError(t, err)
+
+	// Modules must exist unless created.
+	_, err = registry.Upload(ctx, moduleSet)
+	require.ErrorContains(t, err, "--create")
+	commits, err := registry.Upload(
+		ctx,
+		moduleSet,
+		bufmodule.UploadWithCreateIfNotExist(bufmodule.ModuleVisibilityPrivate, ""),
+	)
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	require.Equal(t, "buf.build/foo/a", commits[0].ModuleKey().FullName().String())
+	require.Equal(t, "buf.build/foo/b", commits[1].ModuleKey().FullName().String())
+
+	// Uploading the same content again reuses the existing Commits.
+	sameCommits, err := registry.Upload(ctx, moduleSet, bufmodule.UploadWithLabels("v1"))
+	require.NoError(t, err)
+	require.Len(t, sameCommits, 2)
+	for i, commit := range commits {
+		require.Equal(t, commit.ModuleKey().CommitID(), sameCommits[i].ModuleKey().CommitID())
+	}
+
+	// Resolve by default label, label, and commit ID.
+	bRefs := []string{
+		"buf.build/foo/b",
+		"buf.build/foo/b:v1",
+		"buf.build/foo/b:" + commits[1].ModuleKey().CommitID().String(),
+	}
+	for _, bRef := range bRefs {
+		ref, err := bufparse.ParseRef(bRef)
+		require.NoError(t, err)
+		moduleKeys, err := registry.GetModuleKeysForModuleRefs(ctx, []bufparse.Ref{ref}, bufmodule.DigestTypeB5)
+		require.NoError(t, err)
+		require.Len(t, moduleKeys, 1)
+		require.Equal(t, commits[1].ModuleKey().CommitID(), moduleKeys[0].CommitID())
+	}
+	ref, err := bufparse.ParseRef("buf.build/foo/b:unknown")
+	require.NoError(t, err)
+	_, err = registry.GetModuleKeysForModuleRefs(ctx, []bufparse.Ref{ref}, bufmodule.DigestTypeB5)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	ref, err = bufparse.ParseRef("buf.build/foo/c")
+	require.NoError(t, err)
+	_, err = registry.GetModuleKeysForModuleRefs(ctx, []bufparse.Ref{ref}, bufmodule.DigestTypeB5)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = registry.GetModuleKeysForModuleRefs(ctx, []bufparse.Ref{ref}, bufmodule.DigestTypeB4)
+	require.Error(t, err)
+
+	bModuleKey := commits[1].ModuleKey()
+	graph, err := registry.GetGraphForModuleKeys(ctx, []bufmodule.ModuleKey{bModuleKey})
+	require.NoError(t, err)
+	graphModuleKeys, err := graph.TopoSort(bufmodule.ModuleKeyToRegistryCommitID(bModuleKey))
+	require.NoError(t, err)
+	require.Len(t, graphModuleKeys, 2)
+	require.Equal(t, commits[0].ModuleKey().CommitID(), graphModuleKeys[0].CommitID())
+
+	storedCommits, err := registry.GetCommitsForModuleKeys(ctx, []bufmodule.ModuleKey{bModuleKey})
+	require.NoError(t, err)
+	require.Len(t, storedCommits, 1)
+	_, err = storedCommits[0].CreateTime()
+	require.NoError(t, err)
+	commitKey, err := bufmodule.ModuleKeyToCommitKey(bModuleKey)
+	require.NoError(t, err)
+	storedCommits, err = registry.GetCommitsForCommitKeys(ctx, []bufmodule.CommitKey{commitKey})
+	require.NoError(t, err)
+	require.Len(t, storedCommits, 1)
+	require.Equal(t, "buf.build/foo/b", storedCommits[0].ModuleKey().FullName().String())
+
+	// Build the remote Module from the registry, which verifies the Digest.
+	remoteModuleSet, err := bufmodule.NewModuleSetForRemoteModule(
+		ctx,
+		slogtestext.NewLogger(t),
+		registry,
+		registry,
+		registry,
+		bModuleKey,
+	)
+	require.NoError(t, err)
+	remoteModule := remoteModuleSet.GetModuleForFullName(bModuleKey.FullName())
+	require.NotNil(t, remoteModule)
+	digest, err := remoteModule.Digest(bufmodule.DigestTypeB5)
+	require.NoError(t, err)
+	expectedDigest, err := bModuleKey.Digest()
+	require.NoError(t, err)
+	require.True(t, bufmodule.DigestEqual(expectedDigest, digest))
+	data, err := storage.ReadPath(ctx, bufmodule.ModuleReadBucketToStorageReadBucket(remoteModule), "b.proto")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "message B")
+
+	// Changed content creates a new Commit and moves the default label.
+	changedModuleSet, err := bufmoduletesting.NewModuleSet(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/a",
+			PathToData: map[string][]byte{
+				"a.proto": []byte(`syntax = "proto3"; package a; message A { string s = 1; }`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	changedCommits, err := registry.Upload(ctx, changedModuleSet)
+	require.NoError(t, err)
+	require.Len(t, changedCommits, 1)
+	require.NotEqual(t, commits[0].ModuleKey().CommitID(), changedCommits[0].ModuleKey().CommitID())
+	ref, err = bufparse.ParseRef("buf.build/foo/a")
+	require.NoError(t, err)
+	moduleKeys, err := registry.GetModuleKeysForModuleRefs(ctx, []bufparse.Ref{ref}, bufmodule.DigestTypeB5)
+	require.NoError(t, err)
+	require.Equal(t, changedCommits[0].ModuleKey().CommitID(), moduleKeys[0].CommitID())
+	ref, err = bufparse.ParseRef("buf.build/foo/a:v1")
+	require.NoError(t, err)
+	moduleKeys, err = registry.GetModuleKeysForModuleRefs(ctx, []bufparse.Ref{ref}, bufmodule.DigestTypeB5)
+	require.NoError(t, err)
+	require.Equal(t, commits[0].ModuleKey().CommitID(), moduleKeys[0].CommitID())
+
+	// Uploading with a SigningKey creates a new signed Commit, as the existing Commit was
+	// not signed with the SigningKey. Uploading again with the same SigningKey reuses it.
+	signingKey, err := bufmodule.NewSigningKey()
+	require.NoError(t, err)
+	signedCommits, err := registry.Upload(ctx, changedModuleSet, bufmodule.UploadWithSigningKey(signingKey))
+	require.NoError(t, err)
+	require.Len(t, signedCommits, 1)
+	require.NotEqual(t, changedCommits[0].ModuleKey().CommitID(), signedCommits[0].ModuleKey().CommitID())
+	sameSignedCommits, err := registry.Upload(ctx, changedModuleSet, bufmodule.UploadWithSigningKey(signingKey))
+	require.NoError(t, err)
+	require.Equal(t, signedCommits[0].ModuleKey().CommitID(), sameSignedCommits[0].ModuleKey().CommitID())
+	storedCommits, err = registry.GetCommitsForModuleKeys(
+		ctx,
+		[]bufmodule.ModuleKey{
+			signedCommits[0].ModuleKey(),
+			changedCommits[0].ModuleKey(),
+		},
+	)
+	require.NoError(t, err)
+	trustedPublicKeys := []bufmodule.PublicKey{signingKey.PublicKey()}
+	require.NoError(t, bufmodule.VerifyCommitSignatures(storedCommits[:1], trustedPublicKeys))
+	require.ErrorContains(t, bufmodule.VerifyCommitSignatures(storedCommits[1:], trustedPublicKeys), "is not signed")
+}
@@ -0,0 +1,295 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	pkgv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/google/uuid"
+)
+
+const (
+	ociStoreCommitsRepository = "commits"
+	ociStoreModuleTag         = "module"
+	ociStoreCommitTagPrefix   = "commit-"
+	ociStoreLabelTagPrefix    = "label-"
+
+	ociStoreModuleMediaType types.MediaType = "application/vnd.buf.module.v1+yaml"
+	ociStoreCommitMediaType types.MediaType = "application/vnd.buf.module.commit.v1+yaml"
+	ociStoreFilesMediaType  types.MediaType = "application/vnd.buf.module.files.v1.tar"
+)
+
+type ociStore struct {
+	repositoryPrefix string
+	insecure         bool
+}
+
+func newOCIStore(repositoryPrefix string, options ...OCIRegistryOption) (*ociStore, error) {
+	ociStore := &ociStore{
+		repositoryPrefix: strings.TrimSuffix(repositoryPrefix, "/"),
+	}
+	for _, option := range options {
+		option(ociStore)
+	}
+	// Validate the prefix up front so that misconfiguration is reported before any request.
+	if _, err := ociStore.getRepository(ociStoreCommitsRepository); err != nil {
+		return nil, fmt.Errorf("invalid OCI repository prefix %q: %w", repositoryPrefix, err)
+	}
+	return ociStore, nil
+}
+
+func (s *ociStore) getModule(ctx context.Context, fullName bufparse.FullName) (*externalModule, error) {
+	repository, err := s.getModuleRepository(fullName)
+	if err != nil {
+		return nil, err
+	}
+	var externalModule externalModule
+	if err := s.getYAMLLayer(ctx, repository.Tag(ociStoreModuleTag), &externalModule); err != nil {
+		return nil, err
+	}
+	if !externalModule.isValid() {
+		return nil, fmt.Errorf("invalid module artifact for %s", fullName.String())
+	}
+	return &externalModule, nil
+}
+
+func (s *ociStore) putModule(ctx context.Context, fullName bufparse.FullName, externalModule *externalModule) error {
+	repository, err := s.getModuleRepository(fullName)
+	if err != nil {
+		return err
+	}
+	data, err := encoding.MarshalYAML(externalModule)
+	if err != nil {
+		return err
+	}
+	return s.putImage(ctx, repository.Tag(ociStoreModuleTag), static.NewLayer(data, ociStoreModuleMediaType))
+}
+
+func (s *ociStore) getCommit(ctx context.Context, commitID uuid.UUID) (*externalCommit, error) {
+	repository, err := s.getRepository(ociStoreCommitsRepository)
+	if err != nil {
+		return nil, err
+	}
+	return s.getCommitForReference(ctx, repository.Tag(uuidutil.ToDashless(commitID)))
+}
+
+func (s *ociStore) putCommit(
+	ctx context.Context,
+	fullName bufparse.FullName,
+	externalCommit *externalCommit,
+	files storage.ReadBucket,
+) error {
+	repository, err := s.getModuleRepository(fullName)
+	if err != nil {
+		return err
+	}
+	commitsRepository, err := s.getRepository(ociStoreCommitsRepository)
+	if err != nil {
+		return err
+	}
+	data, err := encoding.MarshalYAML(externalCommit)
+	if err != nil {
+		return err
+	}
+	filesData, err := filesToTar(ctx, files)
+	if err != nil {
+		return err
+	}
+	commitLayer := static.NewLayer(data, ociStoreCommitMediaType)
+	if err := s.putImage(
+		ctx,
+		repository.Tag(ociStoreCommitTagPrefix+externalCommit.ID),
+		commitLayer,
+		static.NewLayer(filesData, ociStoreFilesMediaType),
+	); err != nil {
+		return err
+	}
+	// The index is written last, so that a Commit is only found once its files are present.
+	return s.putImage(ctx, commitsRepository.Tag(externalCommit.ID), commitLayer)
+}
+
+func (s *ociStore) getFiles(ctx context.Context, fullName bufparse.FullName, commitID uuid.UUID) (storage.ReadBucket, error) {
+	repository, err := s.getModuleRepository(fullName)
+	if err != nil {
+		return nil, err
+	}
+	layers, err := s.getLayers(ctx, repository.Tag(ociStoreCommitTagPrefix+uuidutil.ToDashless(commitID)))
+	if err != nil {
+		return nil, err
+	}
+	if len(layers) != 2 {
+		return nil, fmt.Errorf("expected 2 layers for commit %s, got %d", uuidutil.ToDashless(commitID), len(layers))
+	}
+	data, err := readLayer(layers[1])
+	if err != nil {
+		return nil, err
+	}
+	return tarToFiles(ctx, data)
+}
+
+func (s *ociStore) getCommitIDForLabel(ctx context.Context, fullName bufparse.FullName, label string) (uuid.UUID, error) {
+	repository, err := s.getModuleRepository(fullName)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	externalCommit, err := s.getCommitForReference(ctx, repository.Tag(ociStoreLabelTagPrefix+label))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuidutil.FromDashless(externalCommit.ID)
+}
+
+func (s *ociStore) putLabel(ctx context.Context, fullName bufparse.FullName, label string, commitID uuid.UUID) error {
+	repository, err := s.getModuleRepository(fullName)
+	if err != nil {
+		return err
+	}
+	descriptor, err := remote.Get(
+		repository.Tag(ociStoreCommitTagPrefix+uuidutil.ToDashless(commitID)),
+		s.getRemoteOptions(ctx)...,
+	)
+	if err != nil {
+		return s.wrapError(err)
+	}
+	return s.wrapError(
+		remote.Tag(
+			repository.Tag(ociStoreLabelTagPrefix+label),
+			descriptor,
+			s.getRemoteOptions(ctx)...,
+		),
+	)
+}
+
+func (s *ociStore) getCommitForReference(ctx context.Context, reference name.Reference) (*externalCommit, error) {
+	var externalCommit externalCommit
+	if err := s.getYAMLLayer(ctx, reference, &externalCommit); err != nil {
+		return nil, err
+	}
+	if !externalCommit.isValid() {
+		return nil, fmt.Errorf("invalid commit artifact %s", reference.String())
+	}
+	return &externalCommit, nil
+}
+
+// getYAMLLayer reads the first layer of the image at the reference as YAML.
+func (s *ociStore) getYAMLLayer(ctx context.Context, reference name.Reference, v any) error {
+	layers, err := s.getLayers(ctx, reference)
+	if err != nil {
+		return err
+	}
+	if len(layers) == 0 {
+		return fmt.Errorf("no layers for %s", reference.String())
+	}
+	data, err := readLayer(layers[0])
+	if err != nil {
+		return err
+	}
+	return encoding.UnmarshalYAMLNonStrict(data, v)
+}
+
+func (s *ociStore) getLayers(ctx context.Context, reference name.Reference) ([]pkgv1.Layer, error) {
+	image, err := remote.Image(reference, s.getRemoteOptions(ctx)...)
+	if err != nil {
+		return nil, s.wrapError(err)
+	}
+	layers, err := image.Layers()
+	if err != nil {
+		return nil, s.wrapError(err)
+	}
+	return layers, nil
+}
+
+func (s *ociStore) putImage(ctx context.Context, tag name.Tag, layers ...pkgv1.Layer) error {
+	image, err := mutate.AppendLayers(
+		mutate.ConfigMediaType(
+			mutate.MediaType(empty.Image, types.OCIManifestSchema1),
+			types.OCIConfigJSON,
+		),
+		layers...,
+	)
+	if err != nil {
+		return err
+	}
+	return s.wrapError(remote.Write(tag, image, s.getRemoteOptions(ctx)...))
+}
+
+func (s *ociStore) getModuleRepository(fullName bufparse.FullName) (name.Repository, error) {
+	return s.getRepository(
+		// Registries may contain a port, which is not valid in a repository path.
+		strings.ReplaceAll(strings.ToLower(fullName.Registry()), ":", "-"),
+		strings.ToLower(fullName.Owner()),
+		strings.ToLower(fullName.Name()),
+	)
+}
+
+func (s *ociStore) getRepository(components ...string) (name.Repository, error) {
+	var nameOptions []name.Option
+	if s.insecure {
+		nameOptions = append(nameOptions, name.Insecure)
+	}
+	return name.NewRepository(
+		strings.Join(append([]string{s.repositoryPrefix}, components...), "/"),
+		nameOptions...,
+	)
+}
+
+func (s *ociStore) getRemoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+	}
+}
+
+// wrapError maps not found errors from the OCI registry to fs.ErrNotExist.
+func (*ociStore) wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var transportError *transport.Error
+	if errors.As(err, &transportError) && transportError.StatusCode == http.StatusNotFound {
+		return errors.Join(err, fs.ErrNotExist)
+	}
+	return err
+}
+
+func readLayer(layer pkgv1.Layer) (_ []byte, retErr error) {
+	readCloser, err := layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, readCloser.Close())
+	}()
+	return io.ReadAll(readCloser)
+}
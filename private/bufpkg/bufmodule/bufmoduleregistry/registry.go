@@ -0,0 +1,726 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleregistry
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/dag"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+const defaultLabel = "main"
+
+type registry struct {
+	logger *slog.Logger
+	store  store
+}
+
+func newRegistry(logger *slog.Logger, store store) *registry {
+	return &registry{
+		logger: logger,
+		store:  store,
+	}
+}
+
+func (r *registry) GetModuleKeysForModuleRefs(
+	ctx context.Context,
+	moduleRefs []bufparse.Ref,
+	digestType bufmodule.DigestType,
+) ([]bufmodule.ModuleKey, error) {
+	if err := validateDigestType(digestType); err != nil {
+		return nil, err
+	}
+	if _, err := bufparse.FullNameStringToUniqueValue(moduleRefs); err != nil {
+		return nil, err
+	}
+	moduleKeys := make([]bufmodule.ModuleKey, len(moduleRefs))
+	for i, moduleRef := range moduleRefs {
+		externalCommit, err := r.getExternalCommitForModuleRef(ctx, moduleRef)
+		if err != nil {
+			return nil, err
+		}
+		moduleKey, err := externalCommitToModuleKey(externalCommit)
+		if err != nil {
+			return nil, err
+		}
+		moduleKeys[i] = moduleKey
+	}
+	return moduleKeys, nil
+}
+
+func (r *registry) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	if len(moduleKeys) == 0 {
+		return nil, nil
+	}
+	graph, err := r.GetGraphForModuleKeys(ctx, moduleKeys)
+	if err != nil {
+		return nil, err
+	}
+	moduleDatas := make([]bufmodule.ModuleData, len(moduleKeys))
+	for i, moduleKey := range moduleKeys {
+		// TopoSort will get us both the direct and transitive dependencies for the key,
+		// with the key itself last.
+		depModuleKeys, err := graph.TopoSort(bufmodule.ModuleKeyToRegistryCommitID(moduleKey))
+		if err != nil {
+			return nil, err
+		}
+		depModuleKeys = depModuleKeys[:len(depModuleKeys)-1]
+		sort.Slice(
+			depModuleKeys,
+			func(i int, j int) bool {
+				return depModuleKeys[i].FullName().String() < depModuleKeys[j].FullName().String()
+			},
+		)
+		moduleDatas[i] = bufmodule.NewModuleData(
+			ctx,
+			moduleKey,
+			func() (storage.ReadBucket, error) {
+				return r.store.getFiles(ctx, moduleKey.FullName(), moduleKey.CommitID())
+			},
+			func() ([]bufmodule.ModuleKey, error) { return depModuleKeys, nil },
+			// Registries only store b5 Digests, which do not use v1 buf.yaml or buf.lock files.
+			func() (bufmodule.ObjectData, error) { return nil, nil },
+			func() (bufmodule.ObjectData, error) { return nil, nil },
+		)
+	}
+	return moduleDatas, nil
+}
+
+func (r *registry) GetCommitsForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.Commit, error) {
+	if len(moduleKeys) == 0 {
+		return nil, nil
+	}
+	digestType, err := bufmodule.UniqueDigestTypeForModuleKeys(moduleKeys)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDigestType(digestType); err != nil {
+		return nil, err
+	}
+	commits := make([]bufmodule.Commit, len(moduleKeys))
+	for i, moduleKey := range moduleKeys {
+		externalCommit, err := r.getExternalCommitForModuleKey(ctx, moduleKey)
+		if err != nil {
+			return nil, err
+		}
+		expectedDigest, err := moduleKey.Digest()
+		if err != nil {
+			return nil, err
+		}
+		commit, err := externalCommitToCommit(externalCommit, bufmodule.CommitWithExpectedDigest(expectedDigest))
+		if err != nil {
+			return nil, err
+		}
+		commits[i] = commit
+	}
+	return commits, nil
+}
+
+func (r *registry) GetCommitsForCommitKeys(
+	ctx context.Context,
+	commitKeys []bufmodule.CommitKey,
+) ([]bufmodule.Commit, error) {
+	if len(commitKeys) == 0 {
+		return nil, nil
+	}
+	digestType, err := bufmodule.UniqueDigestTypeForCommitKeys(commitKeys)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDigestType(digestType); err != nil {
+		return nil, err
+	}
+	commits := make([]bufmodule.Commit, len(commitKeys))
+	for i, commitKey := range commitKeys {
+		externalCommit, err := r.store.getCommit(ctx, commitKey.CommitID())
+		if err != nil {
+			return nil, wrapNotExistError(err, "commit "+uuidutil.ToDashless(commitKey.CommitID()))
+		}
+		commit, err := externalCommitToCommit(externalCommit)
+		if err != nil {
+			return nil, err
+		}
+		commits[i] = commit
+	}
+	return commits, nil
+}
+
+func (r *registry) GetGraphForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) (*dag.Graph[bufmodule.RegistryCommitID, bufmodule.ModuleKey], error) {
+	graph := dag.NewGraph[bufmodule.RegistryCommitID, bufmodule.ModuleKey](bufmodule.ModuleKeyToRegistryCommitID)
+	if len(moduleKeys) == 0 {
+		return graph, nil
+	}
+	digestType, err := bufmodule.UniqueDigestTypeForModuleKeys(moduleKeys)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDigestType(digestType); err != nil {
+		return nil, err
+	}
+	if _, err := bufparse.FullNameStringToUniqueValue(moduleKeys); err != nil {
+		return nil, err
+	}
+	visited := make(map[uuid.UUID]struct{})
+	for _, moduleKey := range moduleKeys {
+		externalCommit, err := r.getExternalCommitForModuleKey(ctx, moduleKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.addExternalCommitToGraph(ctx, graph, moduleKey, externalCommit, visited); err != nil {
+			return nil, err
+		}
+	}
+	return graph, nil
+}
+
+func (r *registry) Upload(
+	ctx context.Context,
+	moduleSet bufmodule.ModuleSet,
+	options ...bufmodule.UploadOption,
+) ([]bufmodule.Commit, error) {
+	uploadOptions, err := bufmodule.NewUploadOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	contentModules, err := bufmodule.ModuleSetTargetLocalModulesAndTransitiveLocalDeps(moduleSet)
+	if err != nil {
+		return nil, err
+	}
+	// Only push named modules to the registry. Local unnamed modules can be excluded
+	// if the UploadWithExcludeUnnamed option is set.
+	contentModules, err = slicesext.FilterError(contentModules, func(module bufmodule.Module) (bool, error) {
+		if module.FullName() == nil {
+			if uploadOptions.ExcludeUnnamed() {
+				r.logger.Warn("Excluding unnamed module", slog.String("module", module.Description()))
+				return false, nil
+			}
+			return false, fmt.Errorf("a name must be specified in buf.yaml to push module: %s", module.Description())
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(contentModules) == 0 {
+		// Nothing to upload.
+		return nil, nil
+	}
+	fullNameStringToDefaultLabel := make(map[string]string, len(contentModules))
+	for _, contentModule := range contentModules {
+		moduleDefaultLabel, err := r.getOrCreateModuleDefaultLabel(ctx, contentModule.FullName(), uploadOptions)
+		if err != nil {
+			return nil, err
+		}
+		fullNameStringToDefaultLabel[contentModule.FullName().String()] = moduleDefaultLabel
+	}
+	var labels []string
+	if len(uploadOptions.Tags()) > 0 {
+		sortedDefaultLabels := slicesext.MapValuesToSortedSlice(fullNameStringToDefaultLabel)
+		sortedDefaultLabels = slicesext.ToUniqueSorted(sortedDefaultLabels)
+		if len(sortedDefaultLabels) > 1 {
+			return nil, fmt.Errorf(
+				`--tag was used, but modules had multiple default labels %q. If multiple modules are being pushed and --tag is used, all modules must have the same default label.`,
+				strings.Join(sortedDefaultLabels, ", "),
+			)
+		}
+		labels = append(uploadOptions.Tags(), sortedDefaultLabels[0])
+	} else {
+		labels = uploadOptions.Labels()
+	}
+	for _, label := range labels {
+		if err := validateLabel(label); err != nil {
+			return nil, err
+		}
+	}
+	sortedContentModules, err := sortModulesByDependencyOrder(contentModules)
+	if err != nil {
+		return nil, err
+	}
+	fullNameStringToCommitID := make(map[string]uuid.UUID, len(sortedContentModules))
+	fullNameStringToCommit := make(map[string]bufmodule.Commit, len(sortedContentModules))
+	for _, contentModule := range sortedContentModules {
+		moduleDefaultLabel := fullNameStringToDefaultLabel[contentModule.FullName().String()]
+		moduleLabels := labels
+		if len(moduleLabels) == 0 {
+			moduleLabels = []string{moduleDefaultLabel}
+		}
+		externalCommit, err := r.uploadModule(
+			ctx,
+			contentModule,
+			moduleLabels,
+			moduleDefaultLabel,
+			uploadOptions.SourceControlURL(),
+			uploadOptions.SigningKey(),
+			fullNameStringToCommitID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := externalCommitToCommit(externalCommit)
+		if err != nil {
+			return nil, err
+		}
+		fullNameStringToCommitID[contentModule.FullName().String()] = commit.ModuleKey().CommitID()
+		fullNameStringToCommit[contentModule.FullName().String()] = commit
+	}
+	// Return Commits in the same order as the content Modules.
+	return slicesext.Map(
+		contentModules,
+		func(module bufmodule.Module) bufmodule.Commit {
+			return fullNameStringToCommit[module.FullName().String()]
+		},
+	), nil
+}
+
+func (*registry) isRegistry() {}
+
+// uploadModule uploads a single Module to the store, moving the given labels to
+// the resulting Commit.
+//
+// If the Commit at any of the labels or the default label already has the same Digest
+// as the Module, that Commit is reused instead of creating a new Commit. If a SigningKey
+// is given, the Commit is only reused if it was signed with the SigningKey.
+//
+// fullNameStringToCommitID contains the Commit IDs of all local dependencies of the Module.
+func (r *registry) uploadModule(
+	ctx context.Context,
+	module bufmodule.Module,
+	labels []string,
+	moduleDefaultLabel string,
+	sourceControlURL string,
+	signingKey bufmodule.SigningKey,
+	fullNameStringToCommitID map[string]uuid.UUID,
+) (*externalCommit, error) {
+	fullName := module.FullName()
+	digest, err := module.Digest(bufmodule.DigestTypeB5)
+	if err != nil {
+		return nil, err
+	}
+	uploadExternalCommit, err := r.getExistingExternalCommitForDigest(
+		ctx,
+		fullName,
+		slicesext.ToUniqueSorted(append([]string{moduleDefaultLabel}, labels...)),
+		digest,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if uploadExternalCommit != nil && signingKey != nil && !isExternalCommitSignedBy(uploadExternalCommit, signingKey.PublicKey()) {
+		uploadExternalCommit = nil
+	}
+	if uploadExternalCommit == nil {
+		moduleDeps, err := bufmodule.ModuleDirectModuleDeps(module)
+		if err != nil {
+			return nil, err
+		}
+		externalCommitDeps, err := slicesext.MapError(
+			moduleDeps,
+			func(moduleDep bufmodule.ModuleDep) (*externalCommitDep, error) {
+				return r.getExternalCommitDepForModuleDep(ctx, moduleDep, fullNameStringToCommitID)
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		commitID, err := uuidutil.New()
+		if err != nil {
+			return nil, err
+		}
+		uploadExternalCommit = &externalCommit{
+			Version:          externalVersion,
+			ID:               uuidutil.ToDashless(commitID),
+			Module:           fullName.String(),
+			CreateTime:       time.Now().UTC(),
+			Digest:           digest.String(),
+			SourceControlURL: sourceControlURL,
+			Deps:             externalCommitDeps,
+		}
+		if signingKey != nil {
+			signature, err := bufmodule.SignModuleDigest(signingKey, fullName, digest)
+			if err != nil {
+				return nil, err
+			}
+			uploadExternalCommit.Signatures = []*externalCommitSignature{
+				{
+					PublicKey: signature.PublicKey().String(),
+					Signature: base64.StdEncoding.EncodeToString(signature.Value()),
+				},
+			}
+		}
+		if err := r.store.putCommit(
+			ctx,
+			fullName,
+			uploadExternalCommit,
+			bufmodule.ModuleReadBucketToStorageReadBucket(module),
+		); err != nil {
+			return nil, err
+		}
+		r.logger.DebugContext(
+			ctx,
+			"created commit",
+			slog.String("module", fullName.String()),
+			slog.String("commit", uploadExternalCommit.ID),
+		)
+	}
+	commitID, err := uuidutil.FromDashless(uploadExternalCommit.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range labels {
+		if err := r.store.putLabel(ctx, fullName, label, commitID); err != nil {
+			return nil, err
+		}
+	}
+	return uploadExternalCommit, nil
+}
+
+// getExistingExternalCommitForDigest returns the Commit at the first of the given labels
+// that has the given Digest, or nil if there is no such Commit.
+func (r *registry) getExistingExternalCommitForDigest(
+	ctx context.Context,
+	fullName bufparse.FullName,
+	labels []string,
+	digest bufmodule.Digest,
+) (*externalCommit, error) {
+	for _, label := range labels {
+		commitID, err := r.store.getCommitIDForLabel(ctx, fullName, label)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		externalCommit, err := r.store.getCommit(ctx, commitID)
+		if err != nil {
+			return nil, err
+		}
+		if externalCommit.Digest == digest.String() {
+			return externalCommit, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *registry) getExternalCommitDepForModuleDep(
+	ctx context.Context,
+	moduleDep bufmodule.ModuleDep,
+	fullNameStringToCommitID map[string]uuid.UUID,
+) (*externalCommitDep, error) {
+	depFullName := moduleDep.FullName()
+	if depFullName == nil {
+		return nil, fmt.Errorf("all dependencies must be named to push to a module registry, but %s is not", moduleDep.Description())
+	}
+	if moduleDep.IsLocal() {
+		commitID, ok := fullNameStringToCommitID[depFullName.String()]
+		if !ok {
+			return nil, fmt.Errorf("local dependency %s was not uploaded", depFullName.String())
+		}
+		return &externalCommitDep{
+			Module: depFullName.String(),
+			Commit: uuidutil.ToDashless(commitID),
+		}, nil
+	}
+	commitID := moduleDep.CommitID()
+	if commitID == uuid.Nil {
+		return nil, fmt.Errorf("dependency %s has no commit", depFullName.String())
+	}
+	externalCommit, err := r.store.getCommit(ctx, commitID)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf(
+				"dependency %s:%s is not present in the module registry, push it first",
+				depFullName.String(),
+				uuidutil.ToDashless(commitID),
+			)
+		}
+		return nil, err
+	}
+	if externalCommit.Module != depFullName.String() {
+		return nil, fmt.Errorf(
+			"dependency %s:%s is stored in the module registry as %s",
+			depFullName.String(),
+			uuidutil.ToDashless(commitID),
+			externalCommit.Module,
+		)
+	}
+	return &externalCommitDep{
+		Module: depFullName.String(),
+		Commit: externalCommit.ID,
+	}, nil
+}
+
+func (r *registry) getOrCreateModuleDefaultLabel(
+	ctx context.Context,
+	fullName bufparse.FullName,
+	uploadOptions bufmodule.UploadOptions,
+) (string, error) {
+	existingExternalModule, err := r.store.getModule(ctx, fullName)
+	if err == nil {
+		return existingExternalModule.DefaultLabel, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	if !uploadOptions.CreateIfNotExist() {
+		return "", fmt.Errorf("module %s does not exist in the module registry, use --create to create it", fullName.String())
+	}
+	label := uploadOptions.CreateDefaultLabel()
+	if label == "" {
+		label = defaultLabel
+	}
+	if err := validateLabel(label); err != nil {
+		return "", err
+	}
+	if err := r.store.putModule(
+		ctx,
+		fullName,
+		&externalModule{
+			Version:      externalVersion,
+			DefaultLabel: label,
+		},
+	); err != nil {
+		return "", err
+	}
+	r.logger.DebugContext(ctx, "created module", slog.String("module", fullName.String()))
+	return label, nil
+}
+
+// getExternalCommitForModuleRef resolves the ref of the ModuleRef.
+//
+// An empty ref resolves to the default label of the module. A ref that parses as a
+// commit ID resolves to that commit. Any other ref is treated as a label.
+func (r *registry) getExternalCommitForModuleRef(ctx context.Context, moduleRef bufparse.Ref) (*externalCommit, error) {
+	fullName := moduleRef.FullName()
+	ref := moduleRef.Ref()
+	if commitID, ok := parseCommitID(ref); ok {
+		return r.getExternalCommit(ctx, fullName, commitID)
+	}
+	label := ref
+	if label == "" {
+		externalModule, err := r.store.getModule(ctx, fullName)
+		if err != nil {
+			return nil, wrapNotExistError(err, moduleRef.String())
+		}
+		label = externalModule.DefaultLabel
+	}
+	if err := validateLabel(label); err != nil {
+		return nil, err
+	}
+	commitID, err := r.store.getCommitIDForLabel(ctx, fullName, label)
+	if err != nil {
+		return nil, wrapNotExistError(err, moduleRef.String())
+	}
+	return r.getExternalCommit(ctx, fullName, commitID)
+}
+
+func (r *registry) getExternalCommitForModuleKey(ctx context.Context, moduleKey bufmodule.ModuleKey) (*externalCommit, error) {
+	return r.getExternalCommit(ctx, moduleKey.FullName(), moduleKey.CommitID())
+}
+
+// getExternalCommit gets the Commit, verifying that it belongs to the given Module.
+func (r *registry) getExternalCommit(ctx context.Context, fullName bufparse.FullName, commitID uuid.UUID) (*externalCommit, error) {
+	description := fullName.String() + ":" + uuidutil.ToDashless(commitID)
+	externalCommit, err := r.store.getCommit(ctx, commitID)
+	if err != nil {
+		return nil, wrapNotExistError(err, description)
+	}
+	if externalCommit.Module != fullName.String() {
+		return nil, &fs.PathError{Op: "read", Path: description, Err: fs.ErrNotExist}
+	}
+	return externalCommit, nil
+}
+
+func (r *registry) addExternalCommitToGraph(
+	ctx context.Context,
+	graph *dag.Graph[bufmodule.RegistryCommitID, bufmodule.ModuleKey],
+	moduleKey bufmodule.ModuleKey,
+	externalCommit *externalCommit,
+	visited map[uuid.UUID]struct{},
+) error {
+	graph.AddNode(moduleKey)
+	if _, ok := visited[moduleKey.CommitID()]; ok {
+		return nil
+	}
+	visited[moduleKey.CommitID()] = struct{}{}
+	for _, externalCommitDep := range externalCommit.Deps {
+		depFullName, err := bufparse.ParseFullName(externalCommitDep.Module)
+		if err != nil {
+			return err
+		}
+		depCommitID, err := uuidutil.FromDashless(externalCommitDep.Commit)
+		if err != nil {
+			return err
+		}
+		depExternalCommit, err := r.getExternalCommit(ctx, depFullName, depCommitID)
+		if err != nil {
+			return err
+		}
+		depModuleKey, err := externalCommitToModuleKey(depExternalCommit)
+		if err != nil {
+			return err
+		}
+		graph.AddEdge(moduleKey, depModuleKey)
+		if err := r.addExternalCommitToGraph(ctx, graph, depModuleKey, depExternalCommit, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortModulesByDependencyOrder sorts the Modules so that every Module comes after
+// all of its local dependencies within the given Modules.
+func sortModulesByDependencyOrder(modules []bufmodule.Module) ([]bufmodule.Module, error) {
+	opaqueIDToModule := slicesext.ToValuesMap(modules, bufmodule.Module.OpaqueID)
+	sortedModules := make([]bufmodule.Module, 0, len(modules))
+	visited := make(map[string]struct{}, len(modules))
+	var visit func(bufmodule.Module) error
+	visit = func(module bufmodule.Module) error {
+		if _, ok := visited[module.OpaqueID()]; ok {
+			return nil
+		}
+		visited[module.OpaqueID()] = struct{}{}
+		moduleDeps, err := bufmodule.ModuleDirectModuleDeps(module)
+		if err != nil {
+			return err
+		}
+		for _, moduleDep := range moduleDeps {
+			if depModules, ok := opaqueIDToModule[moduleDep.OpaqueID()]; ok {
+				if err := visit(depModules[0]); err != nil {
+					return err
+				}
+			}
+		}
+		sortedModules = append(sortedModules, module)
+		return nil
+	}
+	for _, module := range modules {
+		if err := visit(module); err != nil {
+			return nil, err
+		}
+	}
+	return sortedModules, nil
+}
+
+func externalCommitToModuleKey(externalCommit *externalCommit) (bufmodule.ModuleKey, error) {
+	fullName, err := bufparse.ParseFullName(externalCommit.Module)
+	if err != nil {
+		return nil, err
+	}
+	commitID, err := uuidutil.FromDashless(externalCommit.ID)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := bufmodule.ParseDigest(externalCommit.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewModuleKey(
+		fullName,
+		commitID,
+		func() (bufmodule.Digest, error) {
+			return digest, nil
+		},
+	)
+}
+
+func externalCommitToCommit(externalCommit *externalCommit, options ...bufmodule.CommitOption) (bufmodule.Commit, error) {
+	moduleKey, err := externalCommitToModuleKey(externalCommit)
+	if err != nil {
+		return nil, err
+	}
+	signatures, err := slicesext.MapError(externalCommit.Signatures, externalCommitSignatureToSignature)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewCommit(
+		moduleKey,
+		func() (time.Time, error) {
+			return externalCommit.CreateTime, nil
+		},
+		append(options, bufmodule.CommitWithSignatures(signatures))...,
+	), nil
+}
+
+func externalCommitSignatureToSignature(externalCommitSignature *externalCommitSignature) (bufmodule.Signature, error) {
+	publicKey, err := bufmodule.ParsePublicKey(externalCommitSignature.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	value, err := base64.StdEncoding.DecodeString(externalCommitSignature.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature by %s: %w", externalCommitSignature.PublicKey, err)
+	}
+	return bufmodule.NewSignature(publicKey, value), nil
+}
+
+// isExternalCommitSignedBy returns true if the Commit has a signature by the PublicKey.
+//
+// The signature is not verified.
+func isExternalCommitSignedBy(externalCommit *externalCommit, publicKey bufmodule.PublicKey) bool {
+	for _, externalCommitSignature := range externalCommit.Signatures {
+		if externalCommitSignature.PublicKey == publicKey.String() {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCommitID(ref string) (uuid.UUID, bool) {
+	if commitID, err := uuidutil.FromDashless(ref); err == nil {
+		return commitID, true
+	}
+	if commitID, err := uuidutil.FromString(ref); err == nil {
+		return commitID, true
+	}
+	return uuid.Nil, false
+}
+
+func validateDigestType(digestType bufmodule.DigestType) error {
+	if digestType != bufmodule.DigestTypeB5 {
+		return fmt.Errorf("module registries only support %s digests, but %s was requested", bufmodule.DigestTypeB5, digestType)
+	}
+	return nil
+}
+
+// wrapNotExistError replaces not found errors with an error that describes what was not found.
+func wrapNotExistError(err error, description string) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return &fs.PathError{Op: "read", Path: description, Err: fs.ErrNotExist}
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmoduleregistry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/google/uuid"
+)
+
+const externalVersion = "v1"
+
+// Labels are used as path components and as parts of OCI tags, so we restrict them
+// to the characters that are valid in both.
+var labelRegexp = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,99}$`)
+
+// store is the storage backend of a registry.
+//
+// All get methods return an error with fs.ErrNotExist if the value is not found.
+type store interface {
+	getModule(ctx context.Context, fullName bufparse.FullName) (*externalModule, error)
+	putModule(ctx context.Context, fullName bufparse.FullName, externalModule *externalModule) error
+	getCommit(ctx context.Context, commitID uuid.UUID) (*externalCommit, error)
+	// putCommit puts the Commit and its files. The Commit is written last, so
+	// that a Commit is only visible once its files are present.
+	putCommit(ctx context.Context, fullName bufparse.FullName, externalCommit *externalCommit, files storage.ReadBucket) error
+	getFiles(ctx context.Context, fullName bufparse.FullName, commitID uuid.UUID) (storage.ReadBucket, error)
+	getCommitIDForLabel(ctx context.Context, fullName bufparse.FullName, label string) (uuid.UUID, error)
+	putLabel(ctx context.Context, fullName bufparse.FullName, label string, commitID uuid.UUID) error
+}
+
+// externalModule is the stored representation of a Module.
+type externalModule struct {
+	Version      string `json:"version,omitempty" yaml:"version,omitempty"`
+	DefaultLabel string `json:"default_label,omitempty" yaml:"default_label,omitempty"`
+}
+
+// externalCommit is the stored representation of a Commit.
+type externalCommit struct {
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Dashless.
+	ID               string               `json:"id,omitempty" yaml:"id,omitempty"`
+	Module           string               `json:"module,omitempty" yaml:"module,omitempty"`
+	CreateTime       time.Time            `json:"create_time,omitempty" yaml:"create_time,omitempty"`
+	Digest           string               `json:"digest,omitempty" yaml:"digest,omitempty"`
+	SourceControlURL string               `json:"source_control_url,omitempty" yaml:"source_control_url,omitempty"`
+	Deps             []*externalCommitDep `json:"deps,omitempty" yaml:"deps,omitempty"`
+	// Signatures are the signatures of the Digest, see bufmodule.SignModuleDigest.
+	Signatures []*externalCommitSignature `json:"signatures,omitempty" yaml:"signatures,omitempty"`
+}
+
+// externalCommitDep is a direct dependency of a Commit.
+type externalCommitDep struct {
+	Module string `json:"module,omitempty" yaml:"module,omitempty"`
+	// Dashless.
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+}
+
+// externalCommitSignature is a signature of the Digest of a Commit.
+type externalCommitSignature struct {
+	// In the form of bufmodule.PublicKey.String().
+	PublicKey string `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+	// Standard base64 encoding.
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty"`
+}
+
+// externalLabel is the stored representation of a label in a bucket.
+type externalLabel struct {
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Dashless.
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+}
+
+func (e *externalModule) isValid() bool {
+	return e.Version == externalVersion && e.DefaultLabel != ""
+}
+
+func (e *externalCommit) isValid() bool {
+	return e.Version == externalVersion && e.ID != "" && e.Module != "" && e.Digest != ""
+}
+
+func (e *externalLabel) isValid() bool {
+	return e.Version == externalVersion && e.Commit != ""
+}
+
+func validateLabel(label string) error {
+	if !labelRegexp.MatchString(label) {
+		return fmt.Errorf("invalid label %q: labels must match %s", label, labelRegexp.String())
+	}
+	return nil
+}
+
+func filesToTar(ctx context.Context, files storage.ReadBucket) ([]byte, error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := storagearchive.Tar(ctx, files, buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func tarToFiles(ctx context.Context, data []byte) (storage.ReadBucket, error) {
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	if err := storagearchive.Untar(ctx, bytes.NewReader(data), readWriteBucket); err != nil {
+		return nil, err
+	}
+	return readWriteBucket, nil
+}
@@ -16,23 +16,30 @@ package bufmodulestore
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufparse"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
 	"github.com/bufbuild/buf/private/pkg/slogext"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/syserror"
 	"github.com/bufbuild/buf/private/pkg/uuidutil"
 )
 
-var externalCommitVersion = "v1"
+var (
+	externalCommitVersion   = "v1"
+	externalCommitFileExt   = ".json"
+	externalCommitAccessExt = ".access"
+)
 
 // CommitStore reads and writes Commits.
 type CommitStore interface {
@@ -56,6 +63,12 @@ type CommitStore interface {
 	)
 	// Put puts the Commits to the store.
 	PutCommits(ctx context.Context, commits []bufmodule.Commit) error
+
+	// ListEntries lists all entries in the store.
+	//
+	// Entries are sorted by digest type, then FullName, then commit ID. Invalid entries
+	// are not included.
+	ListEntries(ctx context.Context) ([]CommitStoreEntry, error)
 }
 
 // NewCommitStore returns a new CommitStore for the given bucket.
@@ -75,6 +88,11 @@ func NewCommitStore(
 type commitStore struct {
 	logger *slog.Logger
 	bucket storage.ReadWriteBucket
+	now    func() time.Time
+
+	// Set once recording an access time fails, so that we do not attempt
+	// to write to a read-only store on every read.
+	accessTimeDisabled atomic.Bool
 }
 
 func newCommitStore(
@@ -84,6 +102,7 @@ func newCommitStore(
 	return &commitStore{
 		logger: logger,
 		bucket: bucket,
+		now:    time.Now,
 	}
 }
 
@@ -210,12 +229,19 @@ func (p *commitStore) getCommitForCommitKey(
 		invalidReason = "invalid module key"
 		return nil, err
 	}
+	signatures, err := slicesext.MapError(externalCommit.Signatures, externalCommitSignatureToSignature)
+	if err != nil {
+		invalidReason = "invalid signature"
+		return nil, err
+	}
+	p.putLastAccessTime(ctx, commitKey)
 	return bufmodule.NewCommit(
 		moduleKey,
 		func() (time.Time, error) {
 			return externalCommit.CreateTime, nil
 		},
 		bufmodule.CommitWithExpectedDigest(expectedDigest),
+		bufmodule.CommitWithSignatures(signatures),
 	), nil
 }
 
@@ -244,6 +270,7 @@ func (p *commitStore) putCommit(
 		Module:     moduleKey.FullName().Name(),
 		CreateTime: createTime,
 		Digest:     digest.String(),
+		Signatures: slicesext.Map(commit.Signatures(), signatureToExternalCommitSignature),
 	}
 	if !externalCommit.isValid() {
 		return syserror.Newf("external commit is invalid: %+v", externalCommit)
@@ -252,7 +279,11 @@ func (p *commitStore) putCommit(
 	if err != nil {
 		return err
 	}
-	return storage.PutPath(ctx, bucket, path, data, storage.PutWithAtomic())
+	if err := storage.PutPath(ctx, bucket, path, data, storage.PutWithAtomic()); err != nil {
+		return err
+	}
+	p.putLastAccessTime(ctx, commitKey)
+	return nil
 }
 
 func (p *commitStore) getReadWriteBucketForDir(ctx context.Context, commitKey bufmodule.CommitKey) storage.ReadWriteBucket {
@@ -290,6 +321,15 @@ func (p *commitStore) deleteInvalidCommitFile(
 			slogext.ErrorAttr(err),
 		)
 	}
+	if err := bucket.Delete(ctx, getCommitStoreAccessFilePath(commitKey)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// Otherwise ignore error.
+		p.logDebugCommitKey(
+			ctx,
+			commitKey,
+			"commit store could not delete last access time",
+			slogext.ErrorAttr(err),
+		)
+	}
 	// This will act as if the file is not found
 	return &fs.PathError{Op: "read", Path: path, Err: fs.ErrNotExist}
 }
@@ -314,7 +354,14 @@ func getCommitStoreDirPath(
 //
 // This is "dashlessCommitID.json", e.g. the commit "12345-abcde" will return "12345abcde.json".
 func getCommitStoreFilePath(commitKey bufmodule.CommitKey) string {
-	return uuidutil.ToDashless(commitKey.CommitID()) + ".json"
+	return uuidutil.ToDashless(commitKey.CommitID()) + externalCommitFileExt
+}
+
+// Returns the path of the file within the directory that records the last access time of the Commit.
+//
+// This is a sibling of the commit file, e.g. the commit "12345-abcde" will return "12345abcde.access".
+func getCommitStoreAccessFilePath(commitKey bufmodule.CommitKey) string {
+	return uuidutil.ToDashless(commitKey.CommitID()) + externalCommitAccessExt
 }
 
 // externalCommit is the store representation of a Commit.
@@ -330,6 +377,16 @@ type externalCommit struct {
 	Module     string    `json:"module,omitempty" yaml:"module,omitempty"`
 	CreateTime time.Time `json:"create_time,omitempty" yaml:"create_time,omitempty"`
 	Digest     string    `json:"digest,omitempty" yaml:"digest,omitempty"`
+	// Signatures are not verified by the store.
+	Signatures []*externalCommitSignature `json:"signatures,omitempty" yaml:"signatures,omitempty"`
+}
+
+// externalCommitSignature is the store representation of a Signature.
+type externalCommitSignature struct {
+	// In the form of bufmodule.PublicKey.String().
+	PublicKey string `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+	// Standard base64 encoding.
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty"`
 }
 
 // isValid returns true if all the information we currently expect to be on
@@ -344,3 +401,22 @@ func (e externalCommit) isValid() bool {
 		!e.CreateTime.IsZero() &&
 		e.Digest != ""
 }
+
+func signatureToExternalCommitSignature(signature bufmodule.Signature) *externalCommitSignature {
+	return &externalCommitSignature{
+		PublicKey: signature.PublicKey().String(),
+		Signature: base64.StdEncoding.EncodeToString(signature.Value()),
+	}
+}
+
+func externalCommitSignatureToSignature(externalCommitSignature *externalCommitSignature) (bufmodule.Signature, error) {
+	publicKey, err := bufmodule.ParsePublicKey(externalCommitSignature.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	value, err := base64.StdEncoding.DecodeString(externalCommitSignature.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewSignature(publicKey, value), nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulestore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+// CommitStoreEntry is a single commit stored in a CommitStore.
+type CommitStoreEntry interface {
+	// FullName is the FullName of the module.
+	FullName() bufparse.FullName
+	// CommitID is the ID of the commit.
+	CommitID() uuid.UUID
+	// DigestType is the type of Digest the entry was stored for.
+	DigestType() bufmodule.DigestType
+	// Size is the size of the entry in bytes.
+	Size() int64
+	// LastAccessTime is the last time the entry was read from or written to the store.
+	//
+	// This is the zero time if no access has been recorded, for example if the entry was
+	// written by an older version.
+	LastAccessTime() time.Time
+
+	isCommitStoreEntry()
+}
+
+// *** PRIVATE ***
+
+type commitStoreEntry struct {
+	fullName       bufparse.FullName
+	commitID       uuid.UUID
+	digestType     bufmodule.DigestType
+	size           int64
+	lastAccessTime time.Time
+}
+
+func (e *commitStoreEntry) FullName() bufparse.FullName {
+	return e.fullName
+}
+
+func (e *commitStoreEntry) CommitID() uuid.UUID {
+	return e.commitID
+}
+
+func (e *commitStoreEntry) DigestType() bufmodule.DigestType {
+	return e.digestType
+}
+
+func (e *commitStoreEntry) Size() int64 {
+	return e.size
+}
+
+func (e *commitStoreEntry) LastAccessTime() time.Time {
+	return e.lastAccessTime
+}
+
+func (*commitStoreEntry) isCommitStoreEntry() {}
+
+func (p *commitStore) ListEntries(ctx context.Context) ([]CommitStoreEntry, error) {
+	var entries []*commitStoreEntry
+	if err := p.bucket.Walk(
+		ctx,
+		"",
+		func(objectInfo storage.ObjectInfo) error {
+			entry, ok, err := p.readEntry(ctx, objectInfo.Path())
+			if err != nil || !ok {
+				return err
+			}
+			entries = append(entries, entry)
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	sort.Slice(
+		entries,
+		func(i int, j int) bool {
+			if entries[i].digestType != entries[j].digestType {
+				return entries[i].digestType.String() < entries[j].digestType.String()
+			}
+			if iFullName, jFullName := entries[i].fullName.String(), entries[j].fullName.String(); iFullName != jFullName {
+				return iFullName < jFullName
+			}
+			return entries[i].commitID.String() < entries[j].commitID.String()
+		},
+	)
+	commitStoreEntries := make([]CommitStoreEntry, len(entries))
+	for i, entry := range entries {
+		commitStoreEntries[i] = entry
+	}
+	return commitStoreEntries, nil
+}
+
+// putLastAccessTime records the current time as the last access time for the commit key.
+//
+// Errors are logged and otherwise ignored, as recording the last access time should never
+// fail reads or writes of the store. After the first error, for example if the store is
+// read-only, no further access times are recorded.
+func (p *commitStore) putLastAccessTime(ctx context.Context, commitKey bufmodule.CommitKey) {
+	if p.accessTimeDisabled.Load() {
+		return
+	}
+	if err := storage.PutPath(
+		ctx,
+		p.getReadWriteBucketForDir(ctx, commitKey),
+		getCommitStoreAccessFilePath(commitKey),
+		[]byte(p.now().UTC().Format(time.RFC3339Nano)),
+		storage.PutWithAtomic(),
+	); err != nil {
+		p.accessTimeDisabled.Store(true)
+		p.logDebugCommitKey(ctx, commitKey, "commit store could not put last access time", slogext.ErrorAttr(err))
+	}
+}
+
+// readEntry reads the entry for the commit file at the path.
+//
+// Returns false if the path is not a commit file, or the commit file is invalid. Invalid
+// commit files are deleted the next time they are read by the store.
+func (p *commitStore) readEntry(ctx context.Context, path string) (*commitStoreEntry, bool, error) {
+	// Commit file paths have the form "digestType/registry/dashlessCommitID.json".
+	components := strings.Split(path, "/")
+	if len(components) != 3 || !strings.HasSuffix(path, externalCommitFileExt) {
+		return nil, false, nil
+	}
+	digestType, err := bufmodule.ParseDigestType(components[0])
+	if err != nil {
+		return nil, false, nil
+	}
+	dashlessCommitID := strings.TrimSuffix(components[2], externalCommitFileExt)
+	commitID, err := uuidutil.FromDashless(dashlessCommitID)
+	if err != nil {
+		return nil, false, nil
+	}
+	data, err := storage.ReadPath(ctx, p.bucket, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// Deleted while listing.
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	var externalCommit externalCommit
+	if err := json.Unmarshal(data, &externalCommit); err != nil || !externalCommit.isValid() {
+		return nil, false, nil
+	}
+	fullName, err := bufparse.NewFullName(components[1], externalCommit.Owner, externalCommit.Module)
+	if err != nil {
+		return nil, false, nil
+	}
+	entry := &commitStoreEntry{
+		fullName:   fullName,
+		commitID:   commitID,
+		digestType: digestType,
+		size:       int64(len(data)),
+	}
+	accessPath := strings.TrimSuffix(path, externalCommitFileExt) + externalCommitAccessExt
+	if data, err := storage.ReadPath(ctx, p.bucket, accessPath); err == nil {
+		if lastAccessTime, err := time.Parse(time.RFC3339Nano, string(data)); err == nil {
+			entry.lastAccessTime = lastAccessTime
+		}
+	}
+	return entry, true, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulestore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommitStoreEntries(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket := storagemem.NewReadWriteBucket()
+	commitStore := newCommitStore(slogtestext.NewLogger(t), bucket)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	commitStore.now = func() time.Time {
+		now = now.Add(time.Hour)
+		return now
+	}
+	moduleKeys, _ := testGetModuleKeysAndModuleDatas(t, ctx)
+	// Put one at a time so that the last access times are in order mod1, mod3, mod2.
+	for _, moduleKey := range moduleKeys {
+		commit := bufmodule.NewCommit(
+			moduleKey,
+			func() (time.Time, error) {
+				return now, nil
+			},
+		)
+		require.NoError(t, commitStore.PutCommits(ctx, []bufmodule.Commit{commit}))
+	}
+
+	entries, err := commitStore.ListEntries(ctx)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	nameToModuleKey := make(map[string]bufmodule.ModuleKey)
+	for _, moduleKey := range moduleKeys {
+		nameToModuleKey[moduleKey.FullName().String()] = moduleKey
+	}
+	for i, entry := range entries {
+		require.Equal(t, []string{"buf.build/foo/mod1", "buf.build/foo/mod2", "buf.build/foo/mod3"}[i], entry.FullName().String())
+		require.Equal(t, nameToModuleKey[entry.FullName().String()].CommitID(), entry.CommitID())
+		require.Equal(t, bufmodule.DigestTypeB5, entry.DigestType())
+		require.Greater(t, entry.Size(), int64(0))
+		require.False(t, entry.LastAccessTime().IsZero())
+	}
+	require.True(t, entries[0].LastAccessTime().Before(entries[2].LastAccessTime()))
+	require.True(t, entries[2].LastAccessTime().Before(entries[1].LastAccessTime()))
+
+	// Reading updates the last access time.
+	foundCommits, _, err := commitStore.GetCommitsForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	require.Len(t, foundCommits, 1)
+	readEntries, err := commitStore.ListEntries(ctx)
+	require.NoError(t, err)
+	require.True(t, readEntries[0].LastAccessTime().After(readEntries[1].LastAccessTime()))
+
+	// Reading from a read-only store succeeds without recording the last access time.
+	readOnlyCommitStore := newCommitStore(slogtestext.NewLogger(t), testReadOnlyBucket{ReadWriteBucket: bucket})
+	foundCommits, _, err = readOnlyCommitStore.GetCommitsForModuleKeys(ctx, moduleKeys[1:2])
+	require.NoError(t, err)
+	require.Len(t, foundCommits, 1)
+	require.True(t, readOnlyCommitStore.accessTimeDisabled.Load())
+	readOnlyEntries, err := commitStore.ListEntries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, readEntries[2].LastAccessTime(), readOnlyEntries[2].LastAccessTime())
+}
@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"sync/atomic"
+	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufparse"
@@ -32,6 +34,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
 )
 
 var (
@@ -41,6 +44,7 @@ var (
 	externalModuleDataV1BufYAMLDir = "v1_buf_yaml"
 	externalModuleDataV1BufLockDir = "v1_buf_lock"
 	externalModuleDataLockFileExt  = ".lock"
+	externalModuleDataAccessExt    = ".access"
 )
 
 // ModuleDatasResult is a result for a get of ModuleDatas.
@@ -71,6 +75,21 @@ type ModuleDataStore interface {
 
 	// Put puts the ModuleDatas to the store.
 	PutModuleDatas(ctx context.Context, moduleDatas []bufmodule.ModuleData) error
+
+	// ListEntries lists all entries in the store.
+	//
+	// Entries are sorted by digest type, then FullName, then commit ID. Entries that were
+	// only partially written are included, so that they can be verified or deleted.
+	ListEntries(ctx context.Context) ([]ModuleDataStoreEntry, error)
+	// VerifyEntry re-computes the Digest of the entry and compares it to the expected Digest.
+	//
+	// If the entry cannot be read, or its Digest does not match, the entry is deleted
+	// and false is returned.
+	VerifyEntry(ctx context.Context, entry ModuleDataStoreEntry, expectedDigest bufmodule.Digest) (bool, error)
+	// DeleteEntry deletes the entry from the store.
+	//
+	// If the entry does not exist, this is a no-op.
+	DeleteEntry(ctx context.Context, entry ModuleDataStoreEntry) error
 }
 
 // NewModuleDataStore returns a new ModuleDataStore for the given bucket.
@@ -106,8 +125,12 @@ type moduleDataStore struct {
 	logger *slog.Logger
 	bucket storage.ReadWriteBucket
 	locker filelock.Locker
+	now    func() time.Time
 
 	tar bool
+	// Set once recording an access time fails, so that we do not attempt
+	// to write to a read-only store on every read.
+	accessTimeDisabled atomic.Bool
 }
 
 func newModuleDataStore(
@@ -120,6 +143,7 @@ func newModuleDataStore(
 		logger: logger,
 		bucket: bucket,
 		locker: locker,
+		now:    time.Now,
 	}
 	for _, option := range options {
 		option(moduleDataStore)
@@ -236,6 +260,22 @@ func (p *moduleDataStore) getModuleDataForModuleKey(
 			}
 		}()
 	}
+	moduleData, err := p.readModuleData(ctx, moduleKey, moduleCacheBucket)
+	if err != nil {
+		return nil, err
+	}
+	p.putLastAccessTime(ctx, moduleKey)
+	return moduleData, nil
+}
+
+// readModuleData reads the module data for the module key from the module cache bucket.
+//
+// The caller is responsible for any locking.
+func (p *moduleDataStore) readModuleData(
+	ctx context.Context,
+	moduleKey bufmodule.ModuleKey,
+	moduleCacheBucket storage.ReadBucket,
+) (bufmodule.ModuleData, error) {
 	// Attempt to read module.yaml from cache. The module.yaml file is always written last,
 	// so if a valid module.yaml file is present, then we proceed to read the rest of the
 	// the module data.
@@ -465,8 +505,13 @@ func (p *moduleDataStore) putModuleData(
 	if err != nil {
 		return err
 	}
+	moduleDigest, err := moduleKey.Digest()
+	if err != nil {
+		return err
+	}
 	externalModuleData := externalModuleData{
 		Version: externalModuleDataVersion,
+		Digest:  moduleDigest.String(),
 		Deps:    make([]externalModuleDataDep, len(depModuleKeys)),
 	}
 
@@ -526,13 +571,17 @@ func (p *moduleDataStore) putModuleData(
 	// Put the module.yaml last, so that we only have a module.yaml if the cache is finished writing.
 	// We can use the existence of the module.yaml file to say whether or not the cache contains a
 	// given ModuleKey, otherwise we overwrite any contents in the cache.
-	return storage.PutPath(
+	if err := storage.PutPath(
 		ctx,
 		moduleCacheBucket,
 		externalModuleDataFileName,
 		data,
 		storage.PutWithAtomic(),
-	)
+	); err != nil {
+		return err
+	}
+	p.putLastAccessTime(ctx, moduleKey)
+	return nil
 }
 
 // May return fs.ErrNotExist error if tar not found.
@@ -625,13 +674,17 @@ func getModuleDataStoreDirPath(moduleKey bufmodule.ModuleKey) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	return getModuleDataStoreDirPathForParts(digest.Type(), moduleKey.FullName(), moduleKey.CommitID()), nil
+}
+
+func getModuleDataStoreDirPathForParts(digestType bufmodule.DigestType, fullName bufparse.FullName, commitID uuid.UUID) string {
 	return normalpath.Join(
-		digest.Type().String(),
-		moduleKey.FullName().Registry(),
-		moduleKey.FullName().Owner(),
-		moduleKey.FullName().Name(),
-		uuidutil.ToDashless(moduleKey.CommitID()),
-	), nil
+		digestType.String(),
+		fullName.Registry(),
+		fullName.Owner(),
+		fullName.Name(),
+		uuidutil.ToDashless(commitID),
+	)
 }
 
 // Returns the module's path within the store if storing tar files.
@@ -640,17 +693,11 @@ func getModuleDataStoreDirPath(moduleKey bufmodule.ModuleKey) (string, error) {
 // e.g. the module "buf.build/acme/weather" with commit "12345-abcde" and digest
 // type "b5" will return "b5/buf.build/acme/weather/12345abcde.tar".
 func getModuleDataStoreTarPath(moduleKey bufmodule.ModuleKey) (string, error) {
-	digest, err := moduleKey.Digest()
+	moduleDataStoreDirPath, err := getModuleDataStoreDirPath(moduleKey)
 	if err != nil {
 		return "", err
 	}
-	return normalpath.Join(
-		digest.Type().String(),
-		moduleKey.FullName().Registry(),
-		moduleKey.FullName().Owner(),
-		moduleKey.FullName().Name(),
-		uuidutil.ToDashless(moduleKey.CommitID())+".tar",
-	), nil
+	return moduleDataStoreDirPath + ".tar", nil
 }
 
 func getDeclaredDepModuleKeyForExternalModuleDataDep(dep externalModuleDataDep) (bufmodule.ModuleKey, error) {
@@ -692,6 +739,17 @@ func getModuleDataStoreDirLockPath(moduleKey bufmodule.ModuleKey) (string, error
 	return moduleDataStoreDirPath + externalModuleDataLockFileExt, nil
 }
 
+// Returns the path of the file that records the last access time of the module within the store.
+//
+// This is a sibling of the module's directory or tar file, e.g. "b5/buf.build/acme/weather/12345abcde.access".
+func getModuleDataStoreAccessPath(moduleKey bufmodule.ModuleKey) (string, error) {
+	moduleDataStoreDirPath, err := getModuleDataStoreDirPath(moduleKey)
+	if err != nil {
+		return "", err
+	}
+	return moduleDataStoreDirPath + externalModuleDataAccessExt, nil
+}
+
 // externalModuleData is the store representation of a ModuleData.
 //
 // We could use a protobuf Message for this.
@@ -700,8 +758,11 @@ func getModuleDataStoreDirLockPath(moduleKey bufmodule.ModuleKey) (string, error
 // and persistence layers, and a bufconfig.BufLockFile does not have all the information that
 // a bufmodule.ModuleData has.
 type externalModuleData struct {
-	Version       string                  `json:"version,omitempty" yaml:"version,omitempty"`
-	FilesDir      string                  `json:"files_dir,omitempty" yaml:"files_dir,omitempty"`
+	Version  string `json:"version,omitempty" yaml:"version,omitempty"`
+	FilesDir string `json:"files_dir,omitempty" yaml:"files_dir,omitempty"`
+	// Digest is the Digest of the module. This was not written by older versions, so
+	// it is not required to be present.
+	Digest        string                  `json:"digest,omitempty" yaml:"digest,omitempty"`
 	Deps          []externalModuleDataDep `json:"deps,omitempty" yaml:"deps,omitempty"`
 	V1BufYAMLFile string                  `json:"v1_buf_yaml_file,omitempty" yaml:"v1_buf_yaml_file,omitempty"`
 	V1BufLockFile string                  `json:"v1_buf_lock_file,omitempty" yaml:"v1_buf_lock_file,omitempty"`
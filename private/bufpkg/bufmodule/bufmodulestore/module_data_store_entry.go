@@ -0,0 +1,390 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulestore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slogext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+// ModuleDataStoreEntry is a single module commit stored in a ModuleDataStore.
+type ModuleDataStoreEntry interface {
+	// FullName is the FullName of the module.
+	FullName() bufparse.FullName
+	// CommitID is the ID of the commit.
+	CommitID() uuid.UUID
+	// DigestType is the type of Digest the entry was stored for.
+	DigestType() bufmodule.DigestType
+	// Digest is the Digest of the module recorded when the entry was stored.
+	//
+	// This is nil if the entry was written by an older version, or was only partially written.
+	// This is not verified, use ModuleDataStore.VerifyEntry to verify the content of the entry.
+	Digest() bufmodule.Digest
+	// Size is the total size of the entry in bytes.
+	Size() int64
+	// LastAccessTime is the last time the entry was read from or written to the store.
+	//
+	// This is the zero time if no access has been recorded, for example if the entry was
+	// written by an older version.
+	LastAccessTime() time.Time
+
+	isModuleDataStoreEntry()
+}
+
+// *** PRIVATE ***
+
+type moduleDataStoreEntry struct {
+	fullName       bufparse.FullName
+	commitID       uuid.UUID
+	digestType     bufmodule.DigestType
+	digest         bufmodule.Digest
+	size           int64
+	lastAccessTime time.Time
+}
+
+func (e *moduleDataStoreEntry) FullName() bufparse.FullName {
+	return e.fullName
+}
+
+func (e *moduleDataStoreEntry) CommitID() uuid.UUID {
+	return e.commitID
+}
+
+func (e *moduleDataStoreEntry) DigestType() bufmodule.DigestType {
+	return e.digestType
+}
+
+func (e *moduleDataStoreEntry) Digest() bufmodule.Digest {
+	return e.digest
+}
+
+func (e *moduleDataStoreEntry) Size() int64 {
+	return e.size
+}
+
+func (e *moduleDataStoreEntry) LastAccessTime() time.Time {
+	return e.lastAccessTime
+}
+
+func (*moduleDataStoreEntry) isModuleDataStoreEntry() {}
+
+func (p *moduleDataStore) ListEntries(ctx context.Context) ([]ModuleDataStoreEntry, error) {
+	// First collect the entries by their directory path, then read each entry.
+	dirPathToEntry := make(map[string]*moduleDataStoreEntry)
+	if err := p.bucket.Walk(
+		ctx,
+		"",
+		func(objectInfo storage.ObjectInfo) error {
+			dirPath, ok := p.getEntryDirPathForPath(objectInfo.Path())
+			if !ok {
+				return nil
+			}
+			if _, ok := dirPathToEntry[dirPath]; ok {
+				return nil
+			}
+			entry, ok := getModuleDataStoreEntryForDirPath(dirPath)
+			if !ok {
+				// Not something this store wrote, ignore.
+				return nil
+			}
+			dirPathToEntry[dirPath] = entry
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	dirPaths := make([]string, 0, len(dirPathToEntry))
+	for dirPath := range dirPathToEntry {
+		dirPaths = append(dirPaths, dirPath)
+	}
+	sort.Strings(dirPaths)
+	entries := make([]ModuleDataStoreEntry, 0, len(dirPaths))
+	for _, dirPath := range dirPaths {
+		entry := dirPathToEntry[dirPath]
+		if err := p.readEntry(ctx, dirPath, entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (p *moduleDataStore) VerifyEntry(
+	ctx context.Context,
+	entry ModuleDataStoreEntry,
+	expectedDigest bufmodule.Digest,
+) (_ bool, retErr error) {
+	if expectedDigest.Type() != entry.DigestType() {
+		return false, fmt.Errorf(
+			"cannot verify %s:%s stored for digest type %s with a %s digest",
+			entry.FullName().String(),
+			uuidutil.ToDashless(entry.CommitID()),
+			entry.DigestType().String(),
+			expectedDigest.Type().String(),
+		)
+	}
+	moduleKey, err := bufmodule.NewModuleKey(
+		entry.FullName(),
+		entry.CommitID(),
+		func() (bufmodule.Digest, error) {
+			return expectedDigest, nil
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	dirPath := getModuleDataStoreDirPathForParts(entry.DigestType(), entry.FullName(), entry.CommitID())
+	// Verification may delete the entry, so we hold an exclusive lock for the duration,
+	// which also makes sure we never verify an entry that is being written.
+	unlocker, err := p.locker.Lock(ctx, dirPath+externalModuleDataLockFileExt)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, unlocker.Unlock())
+	}()
+	var moduleCacheBucket storage.ReadBucket
+	if p.tar {
+		moduleCacheBucket, _, err = p.getReadBucketAndSizeForTarPath(ctx, dirPath+".tar")
+	} else {
+		moduleCacheBucket = storage.MapReadBucket(p.bucket, storage.MapOnPrefix(dirPath))
+	}
+	if err == nil {
+		var moduleData bufmodule.ModuleData
+		moduleData, err = p.readModuleData(ctx, moduleKey, moduleCacheBucket)
+		if err == nil {
+			// This reads all the files and verifies the Digest.
+			_, err = moduleData.Bucket()
+		}
+	}
+	if err == nil {
+		return true, nil
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return false, ctxErr
+	}
+	p.logDebugModuleKey(
+		ctx,
+		moduleKey,
+		"module data store deleting invalid entry",
+		slogext.ErrorAttr(err),
+	)
+	if err := p.deleteEntry(ctx, dirPath); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+func (p *moduleDataStore) DeleteEntry(ctx context.Context, entry ModuleDataStoreEntry) (retErr error) {
+	dirPath := getModuleDataStoreDirPathForParts(entry.DigestType(), entry.FullName(), entry.CommitID())
+	unlocker, err := p.locker.Lock(ctx, dirPath+externalModuleDataLockFileExt)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, unlocker.Unlock())
+	}()
+	return p.deleteEntry(ctx, dirPath)
+}
+
+// putLastAccessTime records the current time as the last access time for the module key.
+//
+// Errors are logged and otherwise ignored, as recording the last access time should never
+// fail reads or writes of the store. After the first error, for example if the store is
+// read-only, no further access times are recorded.
+func (p *moduleDataStore) putLastAccessTime(ctx context.Context, moduleKey bufmodule.ModuleKey) {
+	if p.accessTimeDisabled.Load() {
+		return
+	}
+	accessPath, err := getModuleDataStoreAccessPath(moduleKey)
+	if err == nil {
+		err = storage.PutPath(
+			ctx,
+			p.bucket,
+			accessPath,
+			[]byte(p.now().UTC().Format(time.RFC3339Nano)),
+			storage.PutWithAtomic(),
+		)
+	}
+	if err != nil {
+		p.accessTimeDisabled.Store(true)
+		p.logDebugModuleKey(ctx, moduleKey, "module data store could not put last access time", slogext.ErrorAttr(err))
+	}
+}
+
+// readEntry populates the Digest, Size, and LastAccessTime of the entry.
+//
+// Entries that are corrupted, partially written, or only have a last access time are still
+// read, so that they can be verified or deleted.
+func (p *moduleDataStore) readEntry(
+	ctx context.Context,
+	dirPath string,
+	entry *moduleDataStoreEntry,
+) (retErr error) {
+	var moduleCacheBucket storage.ReadBucket
+	if p.tar {
+		readBucket, size, err := p.getReadBucketAndSizeForTarPath(ctx, dirPath+".tar")
+		if err != nil {
+			readBucket = storagemem.NewReadWriteBucket()
+		}
+		moduleCacheBucket = readBucket
+		entry.size = size
+	} else {
+		unlocker, err := p.locker.RLock(ctx, dirPath+externalModuleDataLockFileExt)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			retErr = errors.Join(retErr, unlocker.Unlock())
+		}()
+		moduleCacheBucket = storage.MapReadBucket(p.bucket, storage.MapOnPrefix(dirPath))
+		if err := moduleCacheBucket.Walk(
+			ctx,
+			"",
+			func(objectInfo storage.ObjectInfo) error {
+				size, err := getSize(ctx, moduleCacheBucket, objectInfo.Path())
+				if err != nil {
+					if errors.Is(err, fs.ErrNotExist) {
+						return nil
+					}
+					return err
+				}
+				entry.size += size
+				return nil
+			},
+		); err != nil {
+			return err
+		}
+	}
+	if data, err := storage.ReadPath(ctx, moduleCacheBucket, externalModuleDataFileName); err == nil {
+		var externalModuleData externalModuleData
+		if err := encoding.UnmarshalYAMLNonStrict(data, &externalModuleData); err == nil && externalModuleData.isValid() && externalModuleData.Digest != "" {
+			if digest, err := bufmodule.ParseDigest(externalModuleData.Digest); err == nil && digest.Type() == entry.digestType {
+				entry.digest = digest
+			}
+		}
+	}
+	if data, err := storage.ReadPath(ctx, p.bucket, dirPath+externalModuleDataAccessExt); err == nil {
+		if lastAccessTime, err := time.Parse(time.RFC3339Nano, string(data)); err == nil {
+			entry.lastAccessTime = lastAccessTime
+		}
+	}
+	return nil
+}
+
+// deleteEntry deletes the entry at the directory path. The caller is responsible for locking.
+func (p *moduleDataStore) deleteEntry(ctx context.Context, dirPath string) error {
+	if p.tar {
+		if err := p.bucket.Delete(ctx, dirPath+".tar"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	} else {
+		if err := p.bucket.DeleteAll(ctx, dirPath); err != nil {
+			return err
+		}
+	}
+	if err := p.bucket.Delete(ctx, dirPath+externalModuleDataAccessExt); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// getReadBucketAndSizeForTarPath reads the tar at the path.
+//
+// The size is returned even if the tar is corrupted.
+func (p *moduleDataStore) getReadBucketAndSizeForTarPath(
+	ctx context.Context,
+	tarPath string,
+) (storage.ReadBucket, int64, error) {
+	data, err := storage.ReadPath(ctx, p.bucket, tarPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	if err := storagearchive.Untar(ctx, bytes.NewReader(data), readWriteBucket); err != nil {
+		return nil, int64(len(data)), err
+	}
+	return readWriteBucket, int64(len(data)), nil
+}
+
+// getEntryDirPathForPath returns the directory path of the entry that the path belongs to.
+//
+// Entry directory paths have the form "digestType/registry/owner/name/dashlessCommitID".
+func (p *moduleDataStore) getEntryDirPathForPath(path string) (string, bool) {
+	components := strings.Split(path, "/")
+	switch {
+	case len(components) == 5 && strings.HasSuffix(path, externalModuleDataAccessExt):
+		return strings.TrimSuffix(path, externalModuleDataAccessExt), true
+	case p.tar && len(components) == 5 && strings.HasSuffix(path, ".tar"):
+		return strings.TrimSuffix(path, ".tar"), true
+	case !p.tar && len(components) > 5:
+		return normalpath.Join(components[:5]...), true
+	default:
+		return "", false
+	}
+}
+
+func getModuleDataStoreEntryForDirPath(dirPath string) (*moduleDataStoreEntry, bool) {
+	components := strings.Split(dirPath, "/")
+	if len(components) != 5 {
+		return nil, false
+	}
+	digestType, err := bufmodule.ParseDigestType(components[0])
+	if err != nil {
+		return nil, false
+	}
+	fullName, err := bufparse.NewFullName(components[1], components[2], components[3])
+	if err != nil {
+		return nil, false
+	}
+	commitID, err := uuidutil.FromDashless(components[4])
+	if err != nil {
+		return nil, false
+	}
+	return &moduleDataStoreEntry{
+		fullName:   fullName,
+		commitID:   commitID,
+		digestType: digestType,
+	}, true
+}
+
+func getSize(ctx context.Context, readBucket storage.ReadBucket, path string) (_ int64, retErr error) {
+	readObjectCloser, err := readBucket.Get(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, readObjectCloser.Close())
+	}()
+	return io.Copy(io.Discard, readObjectCloser)
+}
@@ -16,7 +16,9 @@ package bufmodulestore
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
@@ -46,6 +48,16 @@ func TestModuleDataStoreOS(t *testing.T) {
 	testModuleDataStoreOS(t)
 }
 
+func TestModuleDataStoreEntriesDir(t *testing.T) {
+	t.Parallel()
+	testModuleDataStoreEntries(t, false)
+}
+
+func TestModuleDataStoreEntriesTar(t *testing.T) {
+	t.Parallel()
+	testModuleDataStoreEntries(t, true)
+}
+
 func testModuleDataStoreBasic(t *testing.T, tar bool) {
 	bucket := storagemem.NewReadWriteBucket()
 	filelocker := filelock.NewNopLocker()
@@ -154,6 +166,111 @@ func testModuleDataStore(
 	)
 }
 
+func testModuleDataStoreEntries(t *testing.T, tar bool) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	bucket, err := storageos.NewProvider().NewReadWriteBucket(tempDir)
+	require.NoError(t, err)
+	filelocker, err := filelock.NewLocker(tempDir)
+	require.NoError(t, err)
+	var moduleDataStoreOptions []ModuleDataStoreOption
+	if tar {
+		moduleDataStoreOptions = append(moduleDataStoreOptions, ModuleDataStoreWithTar())
+	}
+	moduleDataStore := newModuleDataStore(slogtestext.NewLogger(t), bucket, filelocker, moduleDataStoreOptions...)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	moduleDataStore.now = func() time.Time {
+		now = now.Add(time.Hour)
+		return now
+	}
+	moduleKeys, moduleDatas := testGetModuleKeysAndModuleDatas(t, ctx)
+	// Put one at a time so that the last access times are in order mod1, mod3, mod2.
+	for _, moduleData := range moduleDatas {
+		require.NoError(t, moduleDataStore.PutModuleDatas(ctx, []bufmodule.ModuleData{moduleData}))
+	}
+
+	entries, err := moduleDataStore.ListEntries(ctx)
+	require.NoError(t, err)
+	testRequireEntryNamesEqual(t, []string{"buf.build/foo/mod1", "buf.build/foo/mod2", "buf.build/foo/mod3"}, entries)
+	nameToModuleKey := make(map[string]bufmodule.ModuleKey)
+	for _, moduleKey := range moduleKeys {
+		nameToModuleKey[moduleKey.FullName().String()] = moduleKey
+	}
+	for _, entry := range entries {
+		moduleKey := nameToModuleKey[entry.FullName().String()]
+		require.Equal(t, moduleKey.CommitID(), entry.CommitID())
+		require.Equal(t, bufmodule.DigestTypeB5, entry.DigestType())
+		expectedDigest, err := moduleKey.Digest()
+		require.NoError(t, err)
+		require.NotNil(t, entry.Digest())
+		require.True(t, bufmodule.DigestEqual(expectedDigest, entry.Digest()))
+		require.Greater(t, entry.Size(), int64(0))
+		require.False(t, entry.LastAccessTime().IsZero())
+		valid, err := moduleDataStore.VerifyEntry(ctx, entry, expectedDigest)
+		require.NoError(t, err)
+		require.True(t, valid)
+	}
+	require.True(t, entries[0].LastAccessTime().Before(entries[2].LastAccessTime()))
+	require.True(t, entries[2].LastAccessTime().Before(entries[1].LastAccessTime()))
+
+	// Reading updates the last access time.
+	_, _, err = moduleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	readEntries, err := moduleDataStore.ListEntries(ctx)
+	require.NoError(t, err)
+	require.True(t, readEntries[0].LastAccessTime().After(readEntries[1].LastAccessTime()))
+
+	// Reading from a read-only store succeeds without recording the last access time.
+	readOnlyModuleDataStore := newModuleDataStore(
+		slogtestext.NewLogger(t),
+		testReadOnlyBucket{ReadWriteBucket: bucket},
+		filelocker,
+		moduleDataStoreOptions...,
+	)
+	foundModuleDatas, _, err := readOnlyModuleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys[1:2])
+	require.NoError(t, err)
+	require.Len(t, foundModuleDatas, 1)
+	require.True(t, readOnlyModuleDataStore.accessTimeDisabled.Load())
+	readOnlyEntries, err := moduleDataStore.ListEntries(ctx)
+	require.NoError(t, err)
+	require.Equal(t, readEntries[1].LastAccessTime(), readOnlyEntries[1].LastAccessTime())
+
+	// Corrupted entries fail verification and are deleted.
+	if tar {
+		tarPath, err := getModuleDataStoreTarPath(moduleKeys[0])
+		require.NoError(t, err)
+		require.NoError(t, storage.PutPath(ctx, bucket, tarPath, []byte("invalid_tar")))
+	} else {
+		dirPath, err := getModuleDataStoreDirPath(moduleKeys[0])
+		require.NoError(t, err)
+		require.NoError(
+			t,
+			storage.PutPath(
+				ctx,
+				bucket,
+				normalpath.Join(dirPath, externalModuleDataFilesDir, "mod1.proto"),
+				[]byte("modified"),
+			),
+		)
+	}
+	expectedDigest, err := moduleKeys[0].Digest()
+	require.NoError(t, err)
+	valid, err := moduleDataStore.VerifyEntry(ctx, entries[0], expectedDigest)
+	require.NoError(t, err)
+	require.False(t, valid)
+	entries, err = moduleDataStore.ListEntries(ctx)
+	require.NoError(t, err)
+	testRequireEntryNamesEqual(t, []string{"buf.build/foo/mod2", "buf.build/foo/mod3"}, entries)
+
+	require.NoError(t, moduleDataStore.DeleteEntry(ctx, entries[1]))
+	entries, err = moduleDataStore.ListEntries(ctx)
+	require.NoError(t, err)
+	testRequireEntryNamesEqual(t, []string{"buf.build/foo/mod2"}, entries)
+	_, notFoundModuleKeys, err := moduleDataStore.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	testRequireModuleKeyNamesEqual(t, []string{"buf.build/foo/mod1", "buf.build/foo/mod3"}, notFoundModuleKeys)
+}
+
 func testGetModuleKeysAndModuleDatas(t *testing.T, ctx context.Context) ([]bufmodule.ModuleKey, []bufmodule.ModuleData) {
 	bsrProvider, err := bufmoduletesting.NewOmniProvider(
 		bufmoduletesting.ModuleData{
@@ -258,3 +375,25 @@ func testRequireModuleDataNamesEqual(t *testing.T, expected []string, actual []b
 		)
 	}
 }
+
+func testRequireEntryNamesEqual(t *testing.T, expected []string, actual []ModuleDataStoreEntry) {
+	require.Equal(
+		t,
+		expected,
+		slicesext.Map(
+			actual,
+			func(value ModuleDataStoreEntry) string {
+				return value.FullName().String()
+			},
+		),
+	)
+}
+
+// testReadOnlyBucket is a ReadWriteBucket that fails all writes.
+type testReadOnlyBucket struct {
+	storage.ReadWriteBucket
+}
+
+func (testReadOnlyBucket) Put(context.Context, string, ...storage.PutOption) (storage.WriteObjectCloser, error) {
+	return nil, errors.New("read-only")
+}
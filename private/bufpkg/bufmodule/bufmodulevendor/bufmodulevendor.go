@@ -0,0 +1,663 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufmodulevendor reads and writes vendored dependencies.
+//
+// Vendored dependencies live in a directory next to the buf.lock, with the layout:
+//
+//	modules.yaml
+//	<registry>/<owner>/<name>/buf.manifest
+//	<registry>/<owner>/<name>/<files...>
+//
+// modules.yaml lists the vendored ModuleKeys, their transitive dependencies, and their
+// Commits, and each buf.manifest is the bufcas Manifest of the files of a single Module.
+package bufmodulevendor
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/syserror"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+const (
+	// DirPath is the path of the vendor directory relative to the buf.lock.
+	DirPath = "vendor"
+	// ModulesFileName is the name of the file that lists the vendored Modules.
+	ModulesFileName = "modules.yaml"
+	// ManifestFileName is the name of the bufcas Manifest file for each vendored Module.
+	ManifestFileName = "buf.manifest"
+
+	externalModulesFileVersion = "v1"
+)
+
+// Exists returns true if the bucket contains vendored dependencies.
+func Exists(ctx context.Context, bucket storage.ReadBucket) (bool, error) {
+	return storage.Exists(ctx, bucket, ModulesFileName)
+}
+
+// Vendor writes the ModuleDatas and their Commits to the bucket.
+//
+// The Commits must be in the same order as the ModuleDatas. The Signatures of the Commits
+// are vendored, so that they can be verified without the cache or network access.
+//
+// Any existing content in the bucket is deleted first. Only b5 Digests are supported.
+func Vendor(
+	ctx context.Context,
+	bucket storage.ReadWriteBucket,
+	moduleDatas []bufmodule.ModuleData,
+	commits []bufmodule.Commit,
+) error {
+	if len(moduleDatas) != len(commits) {
+		return syserror.Newf("expected %d commits, got %d", len(moduleDatas), len(commits))
+	}
+	externalModulesFile := externalModulesFile{
+		Version: externalModulesFileVersion,
+	}
+	if err := bucket.DeleteAll(ctx, ""); err != nil {
+		return err
+	}
+	for i, moduleData := range moduleDatas {
+		moduleKey := moduleData.ModuleKey()
+		commit := commits[i]
+		if commit.ModuleKey().CommitID() != moduleKey.CommitID() {
+			return syserror.Newf("expected commit %s for %s, got %s", uuidutil.ToDashless(moduleKey.CommitID()), moduleKey.FullName(), uuidutil.ToDashless(commit.ModuleKey().CommitID()))
+		}
+		createTime, err := commit.CreateTime()
+		if err != nil {
+			return err
+		}
+		digest, err := moduleKey.Digest()
+		if err != nil {
+			return err
+		}
+		if digest.Type() != bufmodule.DigestTypeB5 {
+			return fmt.Errorf("%s has a %s digest, only %s digests can be vendored", moduleKey.String(), digest.Type(), bufmodule.DigestTypeB5)
+		}
+		declaredDepModuleKeys, err := moduleData.DeclaredDepModuleKeys()
+		if err != nil {
+			return err
+		}
+		filesBucket, err := moduleData.Bucket()
+		if err != nil {
+			return err
+		}
+		fileSet, err := bufcas.NewFileSetForBucket(ctx, filesBucket)
+		if err != nil {
+			return err
+		}
+		dirPath := getModuleDirPath(moduleKey.FullName())
+		if err := bufcas.PutFileSetToBucket(
+			ctx,
+			fileSet,
+			storage.MapWriteBucket(bucket, storage.MapOnPrefix(dirPath)),
+		); err != nil {
+			return err
+		}
+		if err := storage.PutPath(
+			ctx,
+			bucket,
+			normalpath.Join(dirPath, ManifestFileName),
+			[]byte(fileSet.Manifest().String()),
+		); err != nil {
+			return err
+		}
+		externalModulesFile.Modules = append(
+			externalModulesFile.Modules,
+			&externalModule{
+				Name:       moduleKey.FullName().String(),
+				Commit:     uuidutil.ToDashless(moduleKey.CommitID()),
+				Digest:     digest.String(),
+				CreateTime: createTime,
+				Signatures: slicesext.Map(commit.Signatures(), signatureToExternalSignature),
+				Deps: slicesext.Map(
+					declaredDepModuleKeys,
+					func(moduleKey bufmodule.ModuleKey) string {
+						return moduleKey.FullName().String()
+					},
+				),
+			},
+		)
+	}
+	sort.Slice(
+		externalModulesFile.Modules,
+		func(i int, j int) bool {
+			return externalModulesFile.Modules[i].Name < externalModulesFile.Modules[j].Name
+		},
+	)
+	data, err := encoding.MarshalYAML(externalModulesFile)
+	if err != nil {
+		return err
+	}
+	// Put the modules.yaml last, so that a partially written vendor directory is never used.
+	return storage.PutPath(ctx, bucket, ModulesFileName, data, storage.PutWithAtomic())
+}
+
+// Check verifies that the vendored dependencies in the bucket exactly match the ModuleKeys.
+//
+// The files of each vendored Module are verified against both its buf.manifest and its Digest.
+// All problems found are returned in a single error.
+func Check(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	moduleKeys []bufmodule.ModuleKey,
+) error {
+	nameToVendoredModule, err := getNameToVendoredModule(ctx, bucket)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("no vendored dependencies found, run buf dep vendor")
+		}
+		return err
+	}
+	var problems []string
+	nameToModuleKey := make(map[string]bufmodule.ModuleKey, len(moduleKeys))
+	for _, moduleKey := range moduleKeys {
+		name := moduleKey.FullName().String()
+		nameToModuleKey[name] = moduleKey
+		vendoredModule, ok := nameToVendoredModule[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is in buf.lock but is not vendored", name))
+			continue
+		}
+		matches, err := vendoredModule.matches(moduleKey)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			problems = append(problems, fmt.Sprintf("%s is vendored at %s but buf.lock has %s", name, vendoredModule.moduleKey.String(), moduleKey.String()))
+			continue
+		}
+		moduleProblems, err := checkVendoredModule(ctx, bucket, vendoredModule)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, moduleProblems...)
+	}
+	for _, name := range slicesext.MapKeysToSortedSlice(nameToVendoredModule) {
+		if _, ok := nameToModuleKey[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s is vendored but is not in buf.lock", name))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("vendored dependencies do not match buf.lock, run buf dep vendor:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// NewModuleDataProvider returns a new ModuleDataProvider that reads vendored ModuleDatas
+// from the bucket.
+//
+// ModuleKeys that are not vendored, or whose commit or Digest does not match the vendored
+// Module, are read from the delegate.
+func NewModuleDataProvider(
+	logger *slog.Logger,
+	bucket storage.ReadBucket,
+	delegate bufmodule.ModuleDataProvider,
+) bufmodule.ModuleDataProvider {
+	return newModuleDataProvider(logger, bucket, delegate)
+}
+
+// NewCommitProvider returns a new CommitProvider that reads vendored Commits from
+// the bucket.
+//
+// Commits that are not vendored, or whose Digest does not match the vendored Module, are
+// read from the delegate.
+func NewCommitProvider(
+	logger *slog.Logger,
+	bucket storage.ReadBucket,
+	delegate bufmodule.CommitProvider,
+) bufmodule.CommitProvider {
+	return newCommitProvider(logger, bucket, delegate)
+}
+
+// *** PRIVATE ***
+
+type moduleDataProvider struct {
+	logger   *slog.Logger
+	bucket   storage.ReadBucket
+	delegate bufmodule.ModuleDataProvider
+}
+
+func newModuleDataProvider(
+	logger *slog.Logger,
+	bucket storage.ReadBucket,
+	delegate bufmodule.ModuleDataProvider,
+) *moduleDataProvider {
+	return &moduleDataProvider{
+		logger:   logger,
+		bucket:   bucket,
+		delegate: delegate,
+	}
+}
+
+func (p *moduleDataProvider) GetModuleDatasForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.ModuleData, error) {
+	if len(moduleKeys) == 0 {
+		return nil, nil
+	}
+	nameToVendoredModule, err := getNameToVendoredModule(ctx, p.bucket)
+	if err != nil {
+		return nil, err
+	}
+	moduleDatas := make([]bufmodule.ModuleData, len(moduleKeys))
+	var notVendoredIndexedModuleKeys []slicesext.Indexed[bufmodule.ModuleKey]
+	for i, moduleKey := range moduleKeys {
+		vendoredModule, ok := nameToVendoredModule[moduleKey.FullName().String()]
+		if ok {
+			ok, err = vendoredModule.matches(moduleKey)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !ok {
+			p.logger.DebugContext(ctx, "dependency not vendored", slog.String("module", moduleKey.String()))
+			notVendoredIndexedModuleKeys = append(
+				notVendoredIndexedModuleKeys,
+				slicesext.Indexed[bufmodule.ModuleKey]{Value: moduleKey, Index: i},
+			)
+			continue
+		}
+		moduleDatas[i] = newModuleData(ctx, p.bucket, moduleKey, vendoredModule.depModuleKeys)
+	}
+	if len(notVendoredIndexedModuleKeys) > 0 {
+		delegateModuleDatas, err := p.delegate.GetModuleDatasForModuleKeys(
+			ctx,
+			slicesext.IndexedToValues(notVendoredIndexedModuleKeys),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for i, delegateModuleData := range delegateModuleDatas {
+			moduleDatas[notVendoredIndexedModuleKeys[i].Index] = delegateModuleData
+		}
+	}
+	return moduleDatas, nil
+}
+
+type commitProvider struct {
+	logger   *slog.Logger
+	bucket   storage.ReadBucket
+	delegate bufmodule.CommitProvider
+}
+
+func newCommitProvider(
+	logger *slog.Logger,
+	bucket storage.ReadBucket,
+	delegate bufmodule.CommitProvider,
+) *commitProvider {
+	return &commitProvider{
+		logger:   logger,
+		bucket:   bucket,
+		delegate: delegate,
+	}
+}
+
+func (p *commitProvider) GetCommitsForModuleKeys(
+	ctx context.Context,
+	moduleKeys []bufmodule.ModuleKey,
+) ([]bufmodule.Commit, error) {
+	if len(moduleKeys) == 0 {
+		return nil, nil
+	}
+	nameToVendoredModule, err := getNameToVendoredModule(ctx, p.bucket)
+	if err != nil {
+		return nil, err
+	}
+	return getCommitsForKeys(
+		ctx,
+		p.logger,
+		moduleKeys,
+		func(moduleKey bufmodule.ModuleKey) (*vendoredModule, error) {
+			vendoredModule, ok := nameToVendoredModule[moduleKey.FullName().String()]
+			if !ok {
+				return nil, nil
+			}
+			matches, err := vendoredModule.matches(moduleKey)
+			if err != nil || !matches {
+				return nil, err
+			}
+			return vendoredModule, nil
+		},
+		bufmodule.ModuleKey.String,
+		p.delegate.GetCommitsForModuleKeys,
+	)
+}
+
+func (p *commitProvider) GetCommitsForCommitKeys(
+	ctx context.Context,
+	commitKeys []bufmodule.CommitKey,
+) ([]bufmodule.Commit, error) {
+	if len(commitKeys) == 0 {
+		return nil, nil
+	}
+	nameToVendoredModule, err := getNameToVendoredModule(ctx, p.bucket)
+	if err != nil {
+		return nil, err
+	}
+	commitIDToVendoredModule := make(map[uuid.UUID]*vendoredModule, len(nameToVendoredModule))
+	for _, vendoredModule := range nameToVendoredModule {
+		commitIDToVendoredModule[vendoredModule.moduleKey.CommitID()] = vendoredModule
+	}
+	return getCommitsForKeys(
+		ctx,
+		p.logger,
+		commitKeys,
+		func(commitKey bufmodule.CommitKey) (*vendoredModule, error) {
+			vendoredModule, ok := commitIDToVendoredModule[commitKey.CommitID()]
+			if !ok ||
+				vendoredModule.moduleKey.FullName().Registry() != commitKey.Registry() ||
+				commitKey.DigestType() != bufmodule.DigestTypeB5 {
+				return nil, nil
+			}
+			return vendoredModule, nil
+		},
+		bufmodule.CommitKey.String,
+		p.delegate.GetCommitsForCommitKeys,
+	)
+}
+
+type vendoredModule struct {
+	moduleKey     bufmodule.ModuleKey
+	depModuleKeys []bufmodule.ModuleKey
+	createTime    time.Time
+	signatures    []bufmodule.Signature
+}
+
+// commit returns the vendored Commit of the vendoredModule.
+func (v *vendoredModule) commit() bufmodule.Commit {
+	return bufmodule.NewCommit(
+		v.moduleKey,
+		func() (time.Time, error) {
+			return v.createTime, nil
+		},
+		bufmodule.CommitWithSignatures(v.signatures),
+	)
+}
+
+// matches returns true if the vendoredModule has the same commit and Digest as the ModuleKey.
+func (v *vendoredModule) matches(moduleKey bufmodule.ModuleKey) (bool, error) {
+	if v.moduleKey.CommitID() != moduleKey.CommitID() {
+		return false, nil
+	}
+	vendoredDigest, err := v.moduleKey.Digest()
+	if err != nil {
+		return false, err
+	}
+	digest, err := moduleKey.Digest()
+	if err != nil {
+		return false, err
+	}
+	return bufmodule.DigestEqual(vendoredDigest, digest), nil
+}
+
+// checkVendoredModule returns the problems with the files of the vendoredModule.
+func checkVendoredModule(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	vendoredModule *vendoredModule,
+) ([]string, error) {
+	name := vendoredModule.moduleKey.FullName().String()
+	dirPath := getModuleDirPath(vendoredModule.moduleKey.FullName())
+	manifestData, err := storage.ReadPath(ctx, bucket, normalpath.Join(dirPath, ManifestFileName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return []string{fmt.Sprintf("%s has no %s", name, ManifestFileName)}, nil
+		}
+		return nil, err
+	}
+	expectedManifest, err := bufcas.ParseManifest(string(manifestData))
+	if err != nil {
+		return []string{fmt.Sprintf("%s has an invalid %s: %v", name, ManifestFileName, err)}, nil
+	}
+	fileSet, err := bufcas.NewFileSetForBucket(ctx, getModuleFilesBucket(bucket, dirPath))
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	actualManifest := fileSet.Manifest()
+	for _, fileNode := range expectedManifest.FileNodes() {
+		actualDigest := actualManifest.GetDigest(fileNode.Path())
+		if actualDigest == nil {
+			problems = append(problems, fmt.Sprintf("%s: %s is missing", name, fileNode.Path()))
+		} else if !bufcas.DigestEqual(actualDigest, fileNode.Digest()) {
+			problems = append(problems, fmt.Sprintf("%s: %s has been modified", name, fileNode.Path()))
+		}
+	}
+	for _, fileNode := range actualManifest.FileNodes() {
+		if expectedManifest.GetFileNode(fileNode.Path()) == nil {
+			problems = append(problems, fmt.Sprintf("%s: %s is not in %s", name, fileNode.Path(), ManifestFileName))
+		}
+	}
+	if len(problems) > 0 {
+		return problems, nil
+	}
+	// The files match the manifest, now make sure the manifest matches the Digest in buf.lock.
+	if _, err := newModuleData(ctx, bucket, vendoredModule.moduleKey, vendoredModule.depModuleKeys).Bucket(); err != nil {
+		var digestMismatchError *bufmodule.DigestMismatchError
+		if errors.As(err, &digestMismatchError) {
+			return []string{fmt.Sprintf("%s: %v", name, err)}, nil
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// getCommitsForKeys returns the vendored Commits for the keys, reading the keys that are
+// not vendored from the delegate.
+//
+// getVendoredModule returns nil if the key is not vendored.
+func getCommitsForKeys[K any](
+	ctx context.Context,
+	logger *slog.Logger,
+	keys []K,
+	getVendoredModule func(K) (*vendoredModule, error),
+	keyToString func(K) string,
+	delegateGetCommitsForKeys func(context.Context, []K) ([]bufmodule.Commit, error),
+) ([]bufmodule.Commit, error) {
+	commits := make([]bufmodule.Commit, len(keys))
+	var notVendoredIndexedKeys []slicesext.Indexed[K]
+	for i, key := range keys {
+		vendoredModule, err := getVendoredModule(key)
+		if err != nil {
+			return nil, err
+		}
+		if vendoredModule == nil {
+			logger.DebugContext(ctx, "commit not vendored", slog.String("key", keyToString(key)))
+			notVendoredIndexedKeys = append(
+				notVendoredIndexedKeys,
+				slicesext.Indexed[K]{Value: key, Index: i},
+			)
+			continue
+		}
+		commits[i] = vendoredModule.commit()
+	}
+	if len(notVendoredIndexedKeys) > 0 {
+		delegateCommits, err := delegateGetCommitsForKeys(
+			ctx,
+			slicesext.IndexedToValues(notVendoredIndexedKeys),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for i, delegateCommit := range delegateCommits {
+			commits[notVendoredIndexedKeys[i].Index] = delegateCommit
+		}
+	}
+	return commits, nil
+}
+
+func newModuleData(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	moduleKey bufmodule.ModuleKey,
+	depModuleKeys []bufmodule.ModuleKey,
+) bufmodule.ModuleData {
+	return bufmodule.NewModuleData(
+		ctx,
+		moduleKey,
+		func() (storage.ReadBucket, error) {
+			return getModuleFilesBucket(bucket, getModuleDirPath(moduleKey.FullName())), nil
+		},
+		func() ([]bufmodule.ModuleKey, error) { return depModuleKeys, nil },
+		// Only b5 Digests are vendored, which do not use v1 buf.yaml or buf.lock files.
+		func() (bufmodule.ObjectData, error) { return nil, nil },
+		func() (bufmodule.ObjectData, error) { return nil, nil },
+	)
+}
+
+// getNameToVendoredModule reads the modules.yaml in the bucket.
+//
+// Returns an error with fs.ErrNotExist if there is no modules.yaml.
+func getNameToVendoredModule(ctx context.Context, bucket storage.ReadBucket) (map[string]*vendoredModule, error) {
+	data, err := storage.ReadPath(ctx, bucket, ModulesFileName)
+	if err != nil {
+		return nil, err
+	}
+	var externalModulesFile externalModulesFile
+	if err := encoding.UnmarshalYAMLStrict(data, &externalModulesFile); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", normalpath.Join(DirPath, ModulesFileName), err)
+	}
+	if externalModulesFile.Version != externalModulesFileVersion {
+		return nil, fmt.Errorf("invalid %s: unknown version %q", normalpath.Join(DirPath, ModulesFileName), externalModulesFile.Version)
+	}
+	nameToModuleKey := make(map[string]bufmodule.ModuleKey, len(externalModulesFile.Modules))
+	for _, externalModule := range externalModulesFile.Modules {
+		moduleKey, err := externalModule.toModuleKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", normalpath.Join(DirPath, ModulesFileName), err)
+		}
+		nameToModuleKey[externalModule.Name] = moduleKey
+	}
+	nameToVendoredModule := make(map[string]*vendoredModule, len(externalModulesFile.Modules))
+	for _, externalModule := range externalModulesFile.Modules {
+		depModuleKeys, err := slicesext.MapError(
+			externalModule.Deps,
+			func(depName string) (bufmodule.ModuleKey, error) {
+				depModuleKey, ok := nameToModuleKey[depName]
+				if !ok {
+					return nil, fmt.Errorf("invalid %s: dependency %s of %s is not vendored", normalpath.Join(DirPath, ModulesFileName), depName, externalModule.Name)
+				}
+				return depModuleKey, nil
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		signatures, err := slicesext.MapError(externalModule.Signatures, externalSignatureToSignature)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s: %w", normalpath.Join(DirPath, ModulesFileName), externalModule.Name, err)
+		}
+		nameToVendoredModule[externalModule.Name] = &vendoredModule{
+			moduleKey:     nameToModuleKey[externalModule.Name],
+			depModuleKeys: depModuleKeys,
+			createTime:    externalModule.CreateTime,
+			signatures:    signatures,
+		}
+	}
+	return nameToVendoredModule, nil
+}
+
+func getModuleDirPath(fullName bufparse.FullName) string {
+	return normalpath.Join(fullName.Registry(), fullName.Owner(), fullName.Name())
+}
+
+func getModuleFilesBucket(bucket storage.ReadBucket, dirPath string) storage.ReadBucket {
+	return storage.FilterReadBucket(
+		storage.MapReadBucket(bucket, storage.MapOnPrefix(dirPath)),
+		storage.MatchNot(storage.MatchPathEqual(ManifestFileName)),
+	)
+}
+
+type externalModulesFile struct {
+	Version string            `json:"version,omitempty" yaml:"version,omitempty"`
+	Modules []*externalModule `json:"modules,omitempty" yaml:"modules,omitempty"`
+}
+
+type externalModule struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Dashless.
+	Commit     string    `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Digest     string    `json:"digest,omitempty" yaml:"digest,omitempty"`
+	CreateTime time.Time `json:"create_time,omitempty" yaml:"create_time,omitempty"`
+	// Signatures are not verified when read.
+	Signatures []*externalSignature `json:"signatures,omitempty" yaml:"signatures,omitempty"`
+	// The names of all transitive dependencies.
+	Deps []string `json:"deps,omitempty" yaml:"deps,omitempty"`
+}
+
+type externalSignature struct {
+	// In the form of bufmodule.PublicKey.String().
+	PublicKey string `json:"public_key,omitempty" yaml:"public_key,omitempty"`
+	// Standard base64 encoding.
+	Signature string `json:"signature,omitempty" yaml:"signature,omitempty"`
+}
+
+func (e *externalModule) toModuleKey() (bufmodule.ModuleKey, error) {
+	fullName, err := bufparse.ParseFullName(e.Name)
+	if err != nil {
+		return nil, err
+	}
+	commitID, err := uuidutil.FromDashless(e.Commit)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := bufmodule.ParseDigest(e.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewModuleKey(
+		fullName,
+		commitID,
+		func() (bufmodule.Digest, error) {
+			return digest, nil
+		},
+	)
+}
+
+func signatureToExternalSignature(signature bufmodule.Signature) *externalSignature {
+	return &externalSignature{
+		PublicKey: signature.PublicKey().String(),
+		Signature: base64.StdEncoding.EncodeToString(signature.Value()),
+	}
+}
+
+func externalSignatureToSignature(externalSignature *externalSignature) (bufmodule.Signature, error) {
+	publicKey, err := bufmodule.ParsePublicKey(externalSignature.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	value, err := base64.StdEncoding.DecodeString(externalSignature.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature by %s: %w", externalSignature.PublicKey, err)
+	}
+	return bufmodule.NewSignature(publicKey, value), nil
+}
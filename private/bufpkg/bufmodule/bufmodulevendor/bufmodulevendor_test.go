@@ -0,0 +1,238 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulevendor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/slogtestext"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVendorAndCheck(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	bsrProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/a",
+			PathToData: map[string][]byte{
+				"a.proto": []byte(`syntax = "proto3"; package a; message A {}`),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/b",
+			PathToData: map[string][]byte{
+				"b.proto": []byte(`syntax = "proto3"; package b; import "a.proto"; message B { a.A a = 1; }`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	moduleKeys := getModuleKeys(t, bsrProvider, "buf.build/foo/a", "buf.build/foo/b")
+	moduleDatas, err := bsrProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	commits, err := bsrProvider.GetCommitsForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+
+	bucket := storagemem.NewReadWriteBucket()
+	_, err = bucket.Put(ctx, "stale/file.proto")
+	require.NoError(t, err)
+	require.Error(t, Check(ctx, bucket, moduleKeys))
+	require.NoError(t, Vendor(ctx, bucket, moduleDatas, commits))
+	exists, err := storage.Exists(ctx, bucket, "stale/file.proto")
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.NoError(t, Check(ctx, bucket, moduleKeys))
+
+	// Vendored ModuleDatas are read from the bucket, and have the transitive dependencies.
+	emptyProvider, err := bufmoduletesting.NewOmniProvider()
+	require.NoError(t, err)
+	moduleDataProvider := NewModuleDataProvider(slogtestext.NewLogger(t), bucket, emptyProvider)
+	vendoredModuleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, vendoredModuleDatas, 2)
+	depModuleKeys, err := vendoredModuleDatas[1].DeclaredDepModuleKeys()
+	require.NoError(t, err)
+	require.Len(t, depModuleKeys, 1)
+	require.Equal(t, "buf.build/foo/a", depModuleKeys[0].FullName().String())
+	vendoredBucket, err := vendoredModuleDatas[1].Bucket()
+	require.NoError(t, err)
+	data, err := storage.ReadPath(ctx, vendoredBucket, "b.proto")
+	require.NoError(t, err)
+	require.Contains(t, string(data), "message B")
+	exists, err = storage.Exists(ctx, vendoredBucket, ManifestFileName)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// Only a subset of the vendored Modules is checked.
+	require.ErrorContains(t, Check(ctx, bucket, moduleKeys[:1]), "buf.build/foo/b is vendored but is not in buf.lock")
+
+	// Modified files are reported by path.
+	require.NoError(t, storage.PutPath(ctx, bucket, "buf.build/foo/a/a.proto", []byte(`syntax = "proto3"; package a;`)))
+	require.ErrorContains(t, Check(ctx, bucket, moduleKeys), "buf.build/foo/a: a.proto has been modified")
+	require.NoError(t, storage.PutPath(ctx, bucket, "buf.build/foo/a/extra.proto", []byte(`syntax = "proto3";`)))
+	require.ErrorContains(t, Check(ctx, bucket, moduleKeys), "buf.build/foo/a: extra.proto is not in buf.manifest")
+	_, err = moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	modifiedModuleDatas, err := moduleDataProvider.GetModuleDatasForModuleKeys(ctx, moduleKeys[:1])
+	require.NoError(t, err)
+	_, err = modifiedModuleDatas[0].Bucket()
+	require.Error(t, err)
+
+	// Revendoring fixes the modifications.
+	require.NoError(t, Vendor(ctx, bucket, moduleDatas, commits))
+	require.NoError(t, Check(ctx, bucket, moduleKeys))
+}
+
+func TestModuleDataProviderDelegates(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	bsrProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/a",
+			PathToData: map[string][]byte{
+				"a.proto": []byte(`syntax = "proto3"; package a;`),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/c",
+			PathToData: map[string][]byte{
+				"c.proto": []byte(`syntax = "proto3"; package c;`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	aModuleKeys := getModuleKeys(t, bsrProvider, "buf.build/foo/a")
+	aModuleDatas, err := bsrProvider.GetModuleDatasForModuleKeys(ctx, aModuleKeys)
+	require.NoError(t, err)
+	aCommits, err := bsrProvider.GetCommitsForModuleKeys(ctx, aModuleKeys)
+	require.NoError(t, err)
+	bucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, Vendor(ctx, bucket, aModuleDatas, aCommits))
+
+	moduleKeys := getModuleKeys(t, bsrProvider, "buf.build/foo/c", "buf.build/foo/a")
+	moduleDatas, err := NewModuleDataProvider(
+		slogtestext.NewLogger(t),
+		bucket,
+		bsrProvider,
+	).GetModuleDatasForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, moduleDatas, 2)
+	require.Equal(t, "buf.build/foo/c", moduleDatas[0].ModuleKey().FullName().String())
+	require.Equal(t, "buf.build/foo/a", moduleDatas[1].ModuleKey().FullName().String())
+	for _, moduleData := range moduleDatas {
+		_, err := moduleData.Bucket()
+		require.NoError(t, err)
+	}
+}
+
+func TestCommitProvider(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	bsrProvider, err := bufmoduletesting.NewOmniProvider(
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/a",
+			PathToData: map[string][]byte{
+				"a.proto": []byte(`syntax = "proto3"; package a;`),
+			},
+		},
+		bufmoduletesting.ModuleData{
+			Name: "buf.build/foo/c",
+			PathToData: map[string][]byte{
+				"c.proto": []byte(`syntax = "proto3"; package c;`),
+			},
+		},
+	)
+	require.NoError(t, err)
+	signingKey, err := bufmodule.NewSigningKey()
+	require.NoError(t, err)
+	aModuleKeys := getModuleKeys(t, bsrProvider, "buf.build/foo/a")
+	aModuleDatas, err := bsrProvider.GetModuleDatasForModuleKeys(ctx, aModuleKeys)
+	require.NoError(t, err)
+	aDigest, err := aModuleKeys[0].Digest()
+	require.NoError(t, err)
+	aSignature, err := bufmodule.SignModuleDigest(signingKey, aModuleKeys[0].FullName(), aDigest)
+	require.NoError(t, err)
+	aCreateTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	aCommit := bufmodule.NewCommit(
+		aModuleKeys[0],
+		func() (time.Time, error) {
+			return aCreateTime, nil
+		},
+		bufmodule.CommitWithSignatures([]bufmodule.Signature{aSignature}),
+	)
+	bucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, Vendor(ctx, bucket, aModuleDatas, []bufmodule.Commit{aCommit}))
+
+	// Vendored Commits are read from the bucket with their Signatures, without the delegate.
+	emptyProvider, err := bufmoduletesting.NewOmniProvider()
+	require.NoError(t, err)
+	commits, err := NewCommitProvider(
+		slogtestext.NewLogger(t),
+		bucket,
+		emptyProvider,
+	).GetCommitsForModuleKeys(ctx, aModuleKeys)
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	createTime, err := commits[0].CreateTime()
+	require.NoError(t, err)
+	require.Equal(t, aCreateTime, createTime)
+	require.NoError(t, bufmodule.VerifyCommitSignatures(commits, []bufmodule.PublicKey{signingKey.PublicKey()}))
+	commitKey, err := bufmodule.ModuleKeyToCommitKey(aModuleKeys[0])
+	require.NoError(t, err)
+	commits, err = NewCommitProvider(
+		slogtestext.NewLogger(t),
+		bucket,
+		emptyProvider,
+	).GetCommitsForCommitKeys(ctx, []bufmodule.CommitKey{commitKey})
+	require.NoError(t, err)
+	require.Len(t, commits, 1)
+	require.NoError(t, bufmodule.VerifyCommitSignatures(commits, []bufmodule.PublicKey{signingKey.PublicKey()}))
+
+	// Commits that are not vendored are read from the delegate.
+	moduleKeys := getModuleKeys(t, bsrProvider, "buf.build/foo/c", "buf.build/foo/a")
+	commits, err = NewCommitProvider(
+		slogtestext.NewLogger(t),
+		bucket,
+		bsrProvider,
+	).GetCommitsForModuleKeys(ctx, moduleKeys)
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	require.Equal(t, "buf.build/foo/c", commits[0].ModuleKey().FullName().String())
+	require.Empty(t, commits[0].Signatures())
+	require.Equal(t, "buf.build/foo/a", commits[1].ModuleKey().FullName().String())
+	require.Len(t, commits[1].Signatures(), 1)
+}
+
+func getModuleKeys(t *testing.T, moduleKeyProvider bufmodule.ModuleKeyProvider, refStrings ...string) []bufmodule.ModuleKey {
+	refs := make([]bufparse.Ref, len(refStrings))
+	for i, refString := range refStrings {
+		ref, err := bufparse.ParseRef(refString)
+		require.NoError(t, err)
+		refs[i] = ref
+	}
+	moduleKeys, err := moduleKeyProvider.GetModuleKeysForModuleRefs(context.Background(), refs, bufmodule.DigestTypeB5)
+	require.NoError(t, err)
+	return moduleKeys
+}
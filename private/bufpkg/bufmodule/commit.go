@@ -25,6 +25,11 @@ type Commit interface {
 	ModuleKey() ModuleKey
 	// CreateTime returns the time the Commit was created on the BSR.
 	CreateTime() (time.Time, error)
+	// Signatures returns the Signatures of the Digest of the Commit.
+	//
+	// The Signatures are not verified. Use VerifyCommitSignatures to verify them.
+	// Commits from the BSR never have Signatures.
+	Signatures() []Signature
 
 	isCommit()
 }
@@ -57,11 +62,19 @@ func CommitWithExpectedDigest(expectedDigest Digest) CommitOption {
 	}
 }
 
+// CommitWithSignatures returns a new CommitOption that sets the Signatures of the Commit.
+func CommitWithSignatures(signatures []Signature) CommitOption {
+	return func(commitOptions *commitOptions) {
+		commitOptions.signatures = signatures
+	}
+}
+
 // *** PRIVATE ***
 
 type commit struct {
 	moduleKey     ModuleKey
 	getCreateTime func() (time.Time, error)
+	signatures    []Signature
 }
 
 func newCommit(
@@ -102,6 +115,7 @@ func newCommit(
 	return &commit{
 		moduleKey:     moduleKey,
 		getCreateTime: sync.OnceValues(getCreateTime),
+		signatures:    commitOptions.signatures,
 	}
 }
 
@@ -117,10 +131,15 @@ func (c *commit) CreateTime() (time.Time, error) {
 	return c.getCreateTime()
 }
 
+func (c *commit) Signatures() []Signature {
+	return c.signatures
+}
+
 func (*commit) isCommit() {}
 
 type commitOptions struct {
 	expectedDigest Digest
+	signatures     []Signature
 }
 
 func newCommitOptions() *commitOptions {
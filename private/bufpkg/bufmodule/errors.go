@@ -204,6 +204,6 @@ func (m *DigestMismatchError) Error() string {
 	_, _ = builder.WriteString(`This may be the result of a hand-edited or corrupted buf.lock file, a corrupted local cache, and/or an attack.`)
 	_, _ = builder.WriteString("\n")
 	_, _ = builder.WriteString("\t")
-	_, _ = builder.WriteString(`To clear your local cache, run "buf registry cc".`)
+	_, _ = builder.WriteString(`To evict corrupted entries from your local cache, run "buf registry cache verify", or to clear it, run "buf registry cc".`)
 	return builder.String()
 }
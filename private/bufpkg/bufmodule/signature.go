@@ -0,0 +1,289 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodule
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufparse"
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+)
+
+const (
+	ed25519PublicKeyPrefix = "ed25519:"
+	privateKeyPEMBlockType = "PRIVATE KEY"
+	signaturePayloadPrefix = "buf-module-signature-v1"
+)
+
+// PublicKey is a public key that Signatures of Modules are verified with.
+//
+// Only ed25519 keys are supported.
+type PublicKey interface {
+	// String returns the string form of the PublicKey.
+	//
+	// This is "ed25519:" followed by the standard base64 encoding of the key, and is
+	// the form used in buf.yaml files.
+	String() string
+
+	isPublicKey()
+}
+
+// ParsePublicKey parses a PublicKey from its string form.
+func ParsePublicKey(publicKeyString string) (PublicKey, error) {
+	encoded, ok := strings.CutPrefix(publicKeyString, ed25519PublicKeyPrefix)
+	if !ok {
+		return nil, fmt.Errorf("invalid public key %q: must start with %q", publicKeyString, ed25519PublicKeyPrefix)
+	}
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %q: %w", publicKeyString, err)
+	}
+	if len(value) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key %q: must be %d bytes but was %d bytes", publicKeyString, ed25519.PublicKeySize, len(value))
+	}
+	return newPublicKey(value), nil
+}
+
+// PublicKeyEqual returns true if the PublicKeys are equal.
+func PublicKeyEqual(a PublicKey, b PublicKey) bool {
+	if (a == nil) != (b == nil) {
+		return false
+	}
+	if a == nil {
+		return true
+	}
+	return a.String() == b.String()
+}
+
+// SigningKey is a private key that Modules are signed with.
+//
+// Only ed25519 keys are supported.
+type SigningKey interface {
+	// PublicKey returns the PublicKey for the SigningKey.
+	PublicKey() PublicKey
+
+	isSigningKey()
+}
+
+// NewSigningKey generates a new SigningKey.
+func NewSigningKey() (SigningKey, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newSigningKey(privateKey), nil
+}
+
+// ParseSigningKeyPEM parses a SigningKey from a PEM-encoded PKCS #8 private key.
+//
+// This is the format written by SigningKeyToPEM and by "openssl genpkey -algorithm ed25519".
+func ParseSigningKeyPEM(data []byte) (SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != privateKeyPEMBlockType {
+		return nil, fmt.Errorf("signing key must be a PEM %q block", privateKeyPEMBlockType)
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing key: %w", err)
+	}
+	ed25519PrivateKey, ok := privateKey.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key must be an ed25519 key but was %T", privateKey)
+	}
+	return newSigningKey(ed25519PrivateKey), nil
+}
+
+// SigningKeyToPEM returns the SigningKey as a PEM-encoded PKCS #8 private key.
+func SigningKeyToPEM(signingKey SigningKey) ([]byte, error) {
+	signingKeyValue, err := getSigningKeyValue(signingKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := x509.MarshalPKCS8PrivateKey(signingKeyValue)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: privateKeyPEMBlockType, Bytes: data}), nil
+}
+
+// Signature is a signature of the Digest of a Module.
+type Signature interface {
+	// PublicKey returns the PublicKey of the SigningKey that created the Signature.
+	PublicKey() PublicKey
+	// Value returns the raw signature.
+	Value() []byte
+
+	isSignature()
+}
+
+// NewSignature returns a new Signature.
+func NewSignature(publicKey PublicKey, value []byte) Signature {
+	return newSignature(publicKey, value)
+}
+
+// SignModuleDigest signs the b5 Digest of the Module with the given FullName.
+//
+// The b5 Digest covers the bufcas manifest of the files of the Module and the Digests of
+// all of its dependencies, so the Signature covers the entire content of the Module.
+// The FullName is also signed, so that a Signature cannot be reused for another Module
+// with the same content.
+func SignModuleDigest(signingKey SigningKey, fullName bufparse.FullName, digest Digest) (Signature, error) {
+	if digest.Type() != DigestTypeB5 {
+		return nil, fmt.Errorf("can only sign %v digests but got a %v digest", DigestTypeB5, digest.Type())
+	}
+	signingKeyValue, err := getSigningKeyValue(signingKey)
+	if err != nil {
+		return nil, err
+	}
+	return newSignature(
+		signingKey.PublicKey(),
+		ed25519.Sign(signingKeyValue, getSignaturePayload(fullName, digest)),
+	), nil
+}
+
+// VerifyCommitSignatures verifies that each Commit has a valid Signature by one of the
+// trusted PublicKeys.
+//
+// An error is returned if a Commit has no Signature by a trusted PublicKey, or if any
+// Signature by a trusted PublicKey is invalid. Signatures by other PublicKeys are ignored.
+func VerifyCommitSignatures(commits []Commit, trustedPublicKeys []PublicKey) error {
+	if len(trustedPublicKeys) == 0 {
+		return errors.New("no trusted public keys given to verify signatures with")
+	}
+	trustedPublicKeyStrings := make(map[string]struct{}, len(trustedPublicKeys))
+	for _, trustedPublicKey := range trustedPublicKeys {
+		trustedPublicKeyStrings[trustedPublicKey.String()] = struct{}{}
+	}
+	for _, commit := range commits {
+		moduleKey := commit.ModuleKey()
+		description := moduleKey.FullName().String() + ":" + uuidutil.ToDashless(moduleKey.CommitID())
+		digest, err := moduleKey.Digest()
+		if err != nil {
+			return err
+		}
+		signatures := commit.Signatures()
+		if len(signatures) == 0 {
+			return fmt.Errorf("%s is not signed", description)
+		}
+		var verified bool
+		for _, signature := range signatures {
+			if _, ok := trustedPublicKeyStrings[signature.PublicKey().String()]; !ok {
+				continue
+			}
+			if !verifySignature(signature, moduleKey.FullName(), digest) {
+				return fmt.Errorf("%s has an invalid signature by %s", description, signature.PublicKey().String())
+			}
+			verified = true
+		}
+		if !verified {
+			return fmt.Errorf("%s is not signed by a trusted key", description)
+		}
+	}
+	return nil
+}
+
+// *** PRIVATE ***
+
+type publicKey struct {
+	value ed25519.PublicKey
+}
+
+func newPublicKey(value ed25519.PublicKey) *publicKey {
+	return &publicKey{
+		value: value,
+	}
+}
+
+func (p *publicKey) String() string {
+	return ed25519PublicKeyPrefix + base64.StdEncoding.EncodeToString(p.value)
+}
+
+func (*publicKey) isPublicKey() {}
+
+type signingKey struct {
+	value     ed25519.PrivateKey
+	publicKey PublicKey
+}
+
+func newSigningKey(value ed25519.PrivateKey) *signingKey {
+	return &signingKey{
+		value:     value,
+		publicKey: newPublicKey(value.Public().(ed25519.PublicKey)),
+	}
+}
+
+func (s *signingKey) PublicKey() PublicKey {
+	return s.publicKey
+}
+
+func (*signingKey) isSigningKey() {}
+
+type signature struct {
+	publicKey PublicKey
+	value     []byte
+}
+
+func newSignature(publicKey PublicKey, value []byte) *signature {
+	return &signature{
+		publicKey: publicKey,
+		value:     value,
+	}
+}
+
+func (s *signature) PublicKey() PublicKey {
+	return s.publicKey
+}
+
+func (s *signature) Value() []byte {
+	return s.value
+}
+
+func (*signature) isSignature() {}
+
+func verifySignature(signature Signature, fullName bufparse.FullName, digest Digest) bool {
+	publicKeyValue, err := getPublicKeyValue(signature.PublicKey())
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(publicKeyValue, getSignaturePayload(fullName, digest), signature.Value())
+}
+
+// getSignaturePayload returns the payload that is signed for a Module.
+func getSignaturePayload(fullName bufparse.FullName, digest Digest) []byte {
+	return []byte(signaturePayloadPrefix + "\n" + fullName.String() + "\n" + digest.String() + "\n")
+}
+
+func getPublicKeyValue(key PublicKey) (ed25519.PublicKey, error) {
+	p, ok := key.(*publicKey)
+	if !ok {
+		return nil, fmt.Errorf("unknown PublicKey type: %T", key)
+	}
+	return p.value, nil
+}
+
+func getSigningKeyValue(key SigningKey) (ed25519.PrivateKey, error) {
+	s, ok := key.(*signingKey)
+	if !ok {
+		return nil, fmt.Errorf("unknown SigningKey type: %T", key)
+	}
+	return s.value, nil
+}
@@ -85,6 +85,17 @@ func UploadWithExcludeUnnamed() UploadOption {
 	}
 }
 
+// UploadWithSigningKey returns a new UploadOption that signs the Digest of each
+// uploaded Module with the given SigningKey.
+//
+// Not all Uploaders support signing. Uploaders that do not support signing return
+// an error if this option is set.
+func UploadWithSigningKey(signingKey SigningKey) UploadOption {
+	return func(uploadOptions *uploadOptions) {
+		uploadOptions.signingKey = signingKey
+	}
+}
+
 // UploadOptions are the possible options for upload.
 //
 // This is used by Uploader implementations.
@@ -118,6 +129,10 @@ type UploadOptions interface {
 	SourceControlURL() string
 	// ExcludeUnnamed returns whether to exclude unnamed modules.
 	ExcludeUnnamed() bool
+	// SigningKey returns the SigningKey to sign the Digest of each uploaded Module with.
+	//
+	// May be nil, in which case Modules are not signed.
+	SigningKey() SigningKey
 
 	isUploadOptions()
 }
@@ -150,6 +165,7 @@ type uploadOptions struct {
 	createDefaultLabel     string
 	sourceControlURL       string
 	excludeUnnamed         bool
+	signingKey             SigningKey
 }
 
 func newUploadOptions() *uploadOptions {
@@ -184,6 +200,10 @@ func (u *uploadOptions) ExcludeUnnamed() bool {
 	return u.excludeUnnamed
 }
 
+func (u *uploadOptions) SigningKey() SigningKey {
+	return u.signingKey
+}
+
 func (u *uploadOptions) validate() error {
 	if u.createIfNotExist && u.createModuleVisibility == 0 {
 		return errors.New("must set a valid ModuleVisibility if CreateIfNotExist was specified")
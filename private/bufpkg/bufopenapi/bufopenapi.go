@@ -0,0 +1,231 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufopenapi generates OpenAPI documents for Protobuf services.
+//
+// The documents describe the Connect protocol with JSON, where every unary method
+// is a POST to "/<service full name>/<method name>" with the request message as
+// the body. The schemas of the messages are generated by bufjsonschema.
+package bufopenapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufjsonschema"
+	"github.com/bufbuild/protoplugin"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// Version is the OpenAPI version of the generated documents.
+	Version = "3.1.0"
+	// FileExtension is the file extension of the generated documents.
+	FileExtension = ".openapi.json"
+
+	connectErrorSchemaName = "connect.error"
+	refPrefix              = "#/components/schemas/"
+)
+
+// NewHandler returns a new protoplugin.Handler that generates an OpenAPI document
+// for every file to generate that defines services.
+//
+// Each document is written to a file with the path of the file without its
+// extension and FileExtension. Streaming methods are not supported by the
+// Connect protocol with unary POST requests, and are skipped.
+func NewHandler() protoplugin.Handler {
+	return protoplugin.HandlerFunc(handle)
+}
+
+// *** PRIVATE ***
+
+func handle(
+	_ context.Context,
+	_ protoplugin.PluginEnv,
+	responseWriter protoplugin.ResponseWriter,
+	request protoplugin.Request,
+) error {
+	if parameter := request.Parameter(); parameter != "" {
+		return fmt.Errorf("unknown parameter: %q", parameter)
+	}
+	fileDescriptors, err := request.FileDescriptorsToGenerate()
+	if err != nil {
+		return err
+	}
+	for _, fileDescriptor := range fileDescriptors {
+		if fileDescriptor.Services().Len() == 0 {
+			continue
+		}
+		data, err := bufjsonschema.MarshalJSON(newDocument(fileDescriptor))
+		if err != nil {
+			return err
+		}
+		responseWriter.AddFile(
+			strings.TrimSuffix(fileDescriptor.Path(), ".proto")+FileExtension,
+			string(data),
+		)
+	}
+	responseWriter.SetFeatureProto3Optional()
+	responseWriter.SetFeatureSupportsEditions(descriptorpb.Edition_EDITION_PROTO2, descriptorpb.Edition_EDITION_2023)
+	return nil
+}
+
+func newDocument(fileDescriptor protoreflect.FileDescriptor) map[string]any {
+	definitions := bufjsonschema.NewDefinitions(refPrefix)
+	paths := make(map[string]any)
+	var tags []map[string]any
+	services := fileDescriptor.Services()
+	for i := 0; i < services.Len(); i++ {
+		serviceDescriptor := services.Get(i)
+		tag := map[string]any{
+			"name": string(serviceDescriptor.FullName()),
+		}
+		if description := bufjsonschema.GetDescription(serviceDescriptor); description != "" {
+			tag["description"] = description
+		}
+		tags = append(tags, tag)
+		methods := serviceDescriptor.Methods()
+		for j := 0; j < methods.Len(); j++ {
+			methodDescriptor := methods.Get(j)
+			if methodDescriptor.IsStreamingClient() || methodDescriptor.IsStreamingServer() {
+				continue
+			}
+			paths["/"+string(serviceDescriptor.FullName())+"/"+string(methodDescriptor.Name())] = map[string]any{
+				"post": newOperation(definitions, serviceDescriptor, methodDescriptor),
+			}
+		}
+	}
+	schemas := make(map[string]any)
+	for name, schema := range definitions.Schemas() {
+		schemas[name] = schema
+	}
+	schemas[connectErrorSchemaName] = newConnectErrorSchema()
+	return map[string]any{
+		"openapi": Version,
+		"info": map[string]any{
+			"title":   fileDescriptor.Path(),
+			"version": "0.0.0",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+func newOperation(
+	definitions bufjsonschema.Definitions,
+	serviceDescriptor protoreflect.ServiceDescriptor,
+	methodDescriptor protoreflect.MethodDescriptor,
+) map[string]any {
+	operation := map[string]any{
+		"operationId": string(methodDescriptor.FullName()),
+		"tags":        []string{string(serviceDescriptor.FullName())},
+		"parameters": []map[string]any{
+			{
+				"name":     "Connect-Protocol-Version",
+				"in":       "header",
+				"required": false,
+				"schema": bufjsonschema.Schema{
+					"type":  "string",
+					"const": "1",
+				},
+			},
+		},
+		"requestBody": map[string]any{
+			"required": true,
+			"content":  newJSONContent(definitions.Ref(methodDescriptor.Input())),
+		},
+		"responses": map[string]any{
+			"200": map[string]any{
+				"description": "Success",
+				"content":     newJSONContent(definitions.Ref(methodDescriptor.Output())),
+			},
+			"default": map[string]any{
+				"description": "Error",
+				"content":     newJSONContent(bufjsonschema.Schema{"$ref": refPrefix + connectErrorSchemaName}),
+			},
+		},
+	}
+	if description := bufjsonschema.GetDescription(methodDescriptor); description != "" {
+		operation["description"] = description
+	}
+	if bufjsonschema.IsDeprecated(methodDescriptor) {
+		operation["deprecated"] = true
+	}
+	return operation
+}
+
+func newJSONContent(schema bufjsonschema.Schema) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{
+			"schema": schema,
+		},
+	}
+}
+
+// newConnectErrorSchema returns the schema of the JSON errors of the Connect protocol.
+func newConnectErrorSchema() bufjsonschema.Schema {
+	return bufjsonschema.Schema{
+		"type":        "object",
+		"description": "An error of the Connect protocol.",
+		"properties": map[string]bufjsonschema.Schema{
+			"code": {
+				"type": "string",
+				"enum": []string{
+					"canceled",
+					"unknown",
+					"invalid_argument",
+					"deadline_exceeded",
+					"not_found",
+					"already_exists",
+					"permission_denied",
+					"resource_exhausted",
+					"failed_precondition",
+					"aborted",
+					"out_of_range",
+					"unimplemented",
+					"internal",
+					"unavailable",
+					"data_loss",
+					"unauthenticated",
+				},
+			},
+			"message": {
+				"type": "string",
+			},
+			"details": {
+				"type": "array",
+				"items": bufjsonschema.Schema{
+					"type": "object",
+					"properties": map[string]bufjsonschema.Schema{
+						"type": {
+							"type": "string",
+						},
+						"value": {
+							"type":            "string",
+							"contentEncoding": "base64",
+						},
+						"debug": {},
+					},
+					"required": []string{"type", "value"},
+				},
+			},
+		},
+		"required": []string{"code"},
+	}
+}
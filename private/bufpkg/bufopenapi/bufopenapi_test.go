@@ -0,0 +1,236 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufopenapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/slicesext"
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protoplugin"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	response, err := testRunHandler(
+		t,
+		"",
+		"acme/v1/user_service.proto",
+		`syntax = "proto3";
+
+package acme.v1;
+
+// Manages users.
+service UserService {
+  // Gets a user.
+  rpc GetUser(GetUserRequest) returns (GetUserResponse) {
+    option deprecated = true;
+  }
+  rpc WatchUsers(GetUserRequest) returns (stream GetUserResponse);
+}
+
+message GetUserRequest {
+  string id = 1;
+}
+
+message GetUserResponse {
+  string name = 1;
+}
+
+message Unused {}
+`,
+	)
+	require.NoError(t, err)
+	require.Empty(t, response.GetError())
+	require.Equal(t, 1, len(response.GetFile()))
+	require.Equal(t, "acme/v1/user_service.openapi.json", response.GetFile()[0].GetName())
+	var document map[string]any
+	require.NoError(t, json.Unmarshal([]byte(response.GetFile()[0].GetContent()), &document))
+	require.Equal(t, "3.1.0", document["openapi"])
+	require.Equal(
+		t,
+		[]any{map[string]any{"name": "acme.v1.UserService", "description": "Manages users."}},
+		document["tags"],
+	)
+	paths, ok := document["paths"].(map[string]any)
+	require.True(t, ok)
+	// Streaming methods are skipped.
+	require.Equal(t, 1, len(paths))
+	require.JSONEq(
+		t,
+		`{
+  "post": {
+    "operationId": "acme.v1.UserService.GetUser",
+    "tags": ["acme.v1.UserService"],
+    "description": "Gets a user.",
+    "deprecated": true,
+    "parameters": [
+      {
+        "name": "Connect-Protocol-Version",
+        "in": "header",
+        "required": false,
+        "schema": {"type": "string", "const": "1"}
+      }
+    ],
+    "requestBody": {
+      "required": true,
+      "content": {
+        "application/json": {
+          "schema": {"$ref": "#/components/schemas/acme.v1.GetUserRequest"}
+        }
+      }
+    },
+    "responses": {
+      "200": {
+        "description": "Success",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/acme.v1.GetUserResponse"}
+          }
+        }
+      },
+      "default": {
+        "description": "Error",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/connect.error"}
+          }
+        }
+      }
+    }
+  }
+}`,
+		testMarshalJSON(t, paths["/acme.v1.UserService/GetUser"]),
+	)
+	components, ok := document["components"].(map[string]any)
+	require.True(t, ok)
+	schemas, ok := components["schemas"].(map[string]any)
+	require.True(t, ok)
+	// Only the messages that are used by methods are included.
+	require.Equal(
+		t,
+		[]string{"acme.v1.GetUserRequest", "acme.v1.GetUserResponse", "connect.error"},
+		slicesext.MapKeysToSortedSlice(schemas),
+	)
+	require.JSONEq(
+		t,
+		`{"type": "object", "properties": {"id": {"type": "string"}}}`,
+		testMarshalJSON(t, schemas["acme.v1.GetUserRequest"]),
+	)
+}
+
+func TestHandlerNoServices(t *testing.T) {
+	t.Parallel()
+	response, err := testRunHandler(
+		t,
+		"",
+		"acme/v1/user.proto",
+		`syntax = "proto3";
+package acme.v1;
+message User {}
+`,
+	)
+	require.NoError(t, err)
+	require.Empty(t, response.GetFile())
+}
+
+func testMarshalJSON(t *testing.T, value any) string {
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// testRunHandler compiles the file and runs the handler with a request to
+// generate the file, and returns the error of the handler.
+func testRunHandler(
+	t *testing.T,
+	parameter string,
+	path string,
+	content string,
+) (*pluginpb.CodeGeneratorResponse, error) {
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.CompositeResolver{
+			&protocompile.SourceResolver{
+				Accessor: protocompile.SourceAccessorFromMap(map[string]string{
+					path: content,
+				}),
+			},
+			protocompile.ResolverFunc(func(path string) (protocompile.SearchResult, error) {
+				fileDescriptor, err := protoregistry.GlobalFiles.FindFileByPath(path)
+				if err != nil {
+					return protocompile.SearchResult{}, err
+				}
+				return protocompile.SearchResult{Desc: fileDescriptor}, nil
+			}),
+		},
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	files, err := compiler.Compile(context.Background(), path)
+	require.NoError(t, err)
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{path},
+		Parameter:      proto.String(parameter),
+		ProtoFile:      testGetFileDescriptorProtos(files[0], make(map[string]struct{})),
+	}
+	requestData, err := proto.Marshal(request)
+	require.NoError(t, err)
+	stdout := bytes.NewBuffer(nil)
+	err = protoplugin.Run(
+		context.Background(),
+		protoplugin.Env{
+			Stdin:  bytes.NewReader(requestData),
+			Stdout: stdout,
+			Stderr: bytes.NewBuffer(nil),
+		},
+		NewHandler(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	response := &pluginpb.CodeGeneratorResponse{}
+	require.NoError(t, proto.Unmarshal(stdout.Bytes(), response))
+	return response, nil
+}
+
+// testGetFileDescriptorProtos returns the file and its imports, with imports
+// before the files that import them.
+func testGetFileDescriptorProtos(
+	fileDescriptor protoreflect.FileDescriptor,
+	seen map[string]struct{},
+) []*descriptorpb.FileDescriptorProto {
+	if _, ok := seen[fileDescriptor.Path()]; ok {
+		return nil
+	}
+	seen[fileDescriptor.Path()] = struct{}{}
+	var fileDescriptorProtos []*descriptorpb.FileDescriptorProto
+	imports := fileDescriptor.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		fileDescriptorProtos = append(
+			fileDescriptorProtos,
+			testGetFileDescriptorProtos(imports.Get(i).FileDescriptor, seen)...,
+		)
+	}
+	return append(fileDescriptorProtos, protodesc.ToFileDescriptorProto(fileDescriptor))
+}
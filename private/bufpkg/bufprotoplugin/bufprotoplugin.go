@@ -52,6 +52,7 @@ type Generator interface {
 		ctx context.Context,
 		container app.EnvStderrContainer,
 		requests []*pluginpb.CodeGeneratorRequest,
+		options ...GenerateOption,
 	) (*pluginpb.CodeGeneratorResponse, error)
 }
 
@@ -63,6 +64,19 @@ func NewGenerator(
 	return newGenerator(logger, handler)
 }
 
+// GenerateOption is an option for Generate.
+type GenerateOption func(*generateOptions)
+
+// GenerateWithMaxParallelism returns a new GenerateOption that limits the number
+// of requests that are executed at once.
+//
+// The default is to use thread.Parallelism(). A value of <1 has no meaning.
+func GenerateWithMaxParallelism(maxParallelism int) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.maxParallelism = maxParallelism
+	}
+}
+
 // ResponseWriter handles the response and writes it to the given storage.WriteBucket
 // without executing any plugins and handles insertion points as needed.
 type ResponseWriter interface {
@@ -67,6 +67,82 @@ func ResponseWriterWithCreateOutDirIfNotExists() ResponseWriterOption {
 	}
 }
 
+// ResponseWriterWithCheck returns a new ResponseWriterOption that results in
+// Close comparing the responses with the existing content of the output locations
+// instead of writing them to disk. A unified diff of every file that would be changed
+// or added is written to the given Writer, and nothing is written if the output
+// locations are up to date.
+//
+// Zip and jar files are compared by their entries. As they are replaced as a whole,
+// entries that would no longer be generated are reported as removed.
+func ResponseWriterWithCheck(writer io.Writer) ResponseWriterOption {
+	return func(responseWriterOptions *responseWriterOptions) {
+		responseWriterOptions.checkWriter = writer
+	}
+}
+
+// ResponseWriterWithCheckRemovedFiles returns a new ResponseWriterOption that results
+// in files that are recorded in the previous manifest of an output directory, but are
+// not part of any response, being reported as removed when checking. This should be
+// set if stale files would be deleted after generation.
+//
+// This has no effect if ResponseWriterWithCheck or ResponseWriterWithManifests is not set.
+func ResponseWriterWithCheckRemovedFiles() ResponseWriterOption {
+	return func(responseWriterOptions *responseWriterOptions) {
+		responseWriterOptions.checkRemovedFiles = true
+	}
+}
+
+// ResponseWriterWithManifests returns a new ResponseWriterOption that results in
+// every file written to an output directory being recorded in the given Manifests.
+func ResponseWriterWithManifests(manifests Manifests) ResponseWriterOption {
+	return func(responseWriterOptions *responseWriterOptions) {
+		responseWriterOptions.manifests = manifests
+	}
+}
+
+// ManifestFileName is the name of the file that records the files generated to an
+// output directory.
+const ManifestFileName = ".buf.gen.manifest.json"
+
+// Manifests records the files generated to output directories, along with their digests.
+//
+// A manifest is written to every output directory on every generation, and is read on the
+// next generation so that only the files that were previously generated, and are no longer
+// generated, are deleted when cleaning, instead of the entire output directory. Zip and jar
+// outputs do not have manifests.
+type Manifests interface {
+	// HasManifest returns true if the output directory had a manifest prior to generation.
+	//
+	// pluginOut will be unnormalized within this function.
+	HasManifest(pluginOut string) bool
+	// WriteManifests writes the manifests of all output directories that files were generated
+	// to, or that had a manifest.
+	//
+	// If deleteStaleFiles is set, files that were in a previous manifest but were not
+	// generated are deleted, unless they were modified after they were generated.
+	// A warning is logged for every generated file that was modified by hand.
+	WriteManifests(ctx context.Context, deleteStaleFiles bool) error
+	// CheckManifests compares the manifests that WriteManifests would write with the
+	// existing manifests, and writes a unified diff of every manifest that would be
+	// changed or added to the given Writer. Nothing is written to disk.
+	CheckManifests(ctx context.Context, writer io.Writer, deleteStaleFiles bool) error
+
+	addGeneratedFile(outDirPath string, path string, data []byte) error
+	getPreviousUnmodifiedPaths(outDirPath string) ([]string, bool)
+}
+
+// NewManifests returns a new Manifests, reading the existing manifests of the given
+// output locations.
+func NewManifests(
+	ctx context.Context,
+	logger *slog.Logger,
+	storageosProvider storageos.Provider,
+	pluginOuts []string,
+) (Manifests, error) {
+	return newManifests(ctx, logger, storageosProvider, pluginOuts)
+}
+
 // Cleaner deletes output locations prior to generation.
 //
 // This must be done before any interaction with  ResponseWriters, as multiple plugins may output to a single
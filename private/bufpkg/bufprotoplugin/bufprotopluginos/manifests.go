@@ -0,0 +1,427 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufprotopluginos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcas"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+)
+
+const manifestVersion = "v1"
+
+type manifests struct {
+	logger            *slog.Logger
+	storageosProvider storageos.Provider
+	// Keyed by absolute output directory path.
+	outDirPathToPreviousManifest map[string]*previousManifest
+	// Keyed by absolute output directory path, then by path within the output directory.
+	outDirPathToGeneratedPathToDigest map[string]map[string]string
+	lock                              sync.Mutex
+}
+
+func newManifests(
+	ctx context.Context,
+	logger *slog.Logger,
+	storageosProvider storageos.Provider,
+	pluginOuts []string,
+) (*manifests, error) {
+	manifests := &manifests{
+		logger:                            logger,
+		storageosProvider:                 storageosProvider,
+		outDirPathToPreviousManifest:      make(map[string]*previousManifest),
+		outDirPathToGeneratedPathToDigest: make(map[string]map[string]string),
+	}
+	for _, pluginOut := range pluginOuts {
+		switch filepath.Ext(pluginOut) {
+		case ".jar", ".zip":
+			// Archives are always replaced as a whole.
+			continue
+		}
+		outDirPath, err := filepath.Abs(normalpath.Unnormalize(pluginOut))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := manifests.outDirPathToPreviousManifest[outDirPath]; ok {
+			continue
+		}
+		previousManifest, err := manifests.readPreviousManifest(ctx, outDirPath)
+		if err != nil {
+			return nil, err
+		}
+		if previousManifest != nil {
+			manifests.outDirPathToPreviousManifest[outDirPath] = previousManifest
+		}
+	}
+	return manifests, nil
+}
+
+func (m *manifests) HasManifest(pluginOut string) bool {
+	outDirPath, err := filepath.Abs(normalpath.Unnormalize(pluginOut))
+	if err != nil {
+		return false
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, ok := m.outDirPathToPreviousManifest[outDirPath]
+	return ok
+}
+
+func (m *manifests) WriteManifests(ctx context.Context, deleteStaleFiles bool) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, outDirPath := range m.getOutDirPaths() {
+		if err := m.writeManifest(ctx, outDirPath, deleteStaleFiles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *manifests) CheckManifests(ctx context.Context, writer io.Writer, deleteStaleFiles bool) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, outDirPath := range m.getOutDirPaths() {
+		if err := m.checkManifest(ctx, writer, outDirPath, deleteStaleFiles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *manifests) addGeneratedFile(outDirPath string, path string, data []byte) error {
+	digest, err := bufcas.NewDigestForContent(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	generatedPathToDigest, ok := m.outDirPathToGeneratedPathToDigest[outDirPath]
+	if !ok {
+		generatedPathToDigest = make(map[string]string)
+		m.outDirPathToGeneratedPathToDigest[outDirPath] = generatedPathToDigest
+	}
+	generatedPathToDigest[path] = digest.String()
+	return nil
+}
+
+func (m *manifests) getPreviousUnmodifiedPaths(outDirPath string) ([]string, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	previousManifest, ok := m.outDirPathToPreviousManifest[outDirPath]
+	if !ok {
+		return nil, false
+	}
+	var paths []string
+	for _, previousFile := range previousManifest.files {
+		if !previousFile.modified {
+			paths = append(paths, previousFile.path)
+		}
+	}
+	return paths, true
+}
+
+// getOutDirPaths returns the sorted output directories that a manifest is written to.
+//
+// Manifests are written on every generation, so that a later clean can delete the files
+// that are no longer generated, even if no previous generation was a clean.
+//
+// Must be called with the lock held.
+func (m *manifests) getOutDirPaths() []string {
+	outDirPaths := make(map[string]struct{})
+	for outDirPath := range m.outDirPathToPreviousManifest {
+		outDirPaths[outDirPath] = struct{}{}
+	}
+	for outDirPath := range m.outDirPathToGeneratedPathToDigest {
+		outDirPaths[outDirPath] = struct{}{}
+	}
+	sortedOutDirPaths := make([]string, 0, len(outDirPaths))
+	for outDirPath := range outDirPaths {
+		sortedOutDirPaths = append(sortedOutDirPaths, outDirPath)
+	}
+	sort.Strings(sortedOutDirPaths)
+	return sortedOutDirPaths
+}
+
+func (m *manifests) writeManifest(ctx context.Context, outDirPath string, deleteStaleFiles bool) error {
+	data, stalePaths, err := m.getManifestData(outDirPath, deleteStaleFiles, true)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return nil
+	}
+	bucket, err := m.storageosProvider.NewReadWriteBucket(
+		outDirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	for _, stalePath := range stalePaths {
+		if err := bucket.Delete(ctx, stalePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return storage.PutPath(ctx, bucket, ManifestFileName, data)
+}
+
+// checkManifest writes a diff between the existing manifest of the output directory,
+// if any, and the manifest that would be written to the writer.
+func (m *manifests) checkManifest(ctx context.Context, writer io.Writer, outDirPath string, deleteStaleFiles bool) error {
+	data, _, err := m.getManifestData(outDirPath, deleteStaleFiles, false)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return nil
+	}
+	displayManifestPath := filepath.Join(m.getDisplayPath(outDirPath), ManifestFileName)
+	existingReadWriteBucket := storagemem.NewReadWriteBucket()
+	existingData, err := os.ReadFile(filepath.Join(outDirPath, ManifestFileName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err := putPathWithExternalPath(ctx, existingReadWriteBucket, ManifestFileName, existingData, displayManifestPath); err != nil {
+			return err
+		}
+	}
+	generatedReadWriteBucket := storagemem.NewReadWriteBucket()
+	if err := putPathWithExternalPath(ctx, generatedReadWriteBucket, ManifestFileName, data, displayManifestPath); err != nil {
+		return err
+	}
+	_, err = storage.DiffWithFilenames(
+		ctx,
+		writer,
+		existingReadWriteBucket,
+		generatedReadWriteBucket,
+		storage.DiffWithExternalPaths(),
+		storage.DiffWithSuppressTimestamps(),
+	)
+	return err
+}
+
+// getManifestData returns the content of the manifest to write to the output directory,
+// along with the paths of the stale files to delete.
+//
+// Returns nil data if nothing was ever generated to the output directory, in which case
+// the directory should not be created just for the manifest.
+func (m *manifests) getManifestData(
+	outDirPath string,
+	deleteStaleFiles bool,
+	logWarnings bool,
+) ([]byte, []string, error) {
+	displayOutDirPath := m.getDisplayPath(outDirPath)
+	warn := func(message string) {
+		if logWarnings {
+			m.logger.Warn(message)
+		}
+	}
+	generatedPathToDigest := m.outDirPathToGeneratedPathToDigest[outDirPath]
+	externalManifest := &externalManifest{
+		Version: manifestVersion,
+	}
+	for path, digest := range generatedPathToDigest {
+		externalManifest.Files = append(
+			externalManifest.Files,
+			&externalManifestFile{
+				Path:   path,
+				Digest: digest,
+			},
+		)
+	}
+	var stalePaths []string
+	if previousManifest, ok := m.outDirPathToPreviousManifest[outDirPath]; ok {
+		for _, previousFile := range previousManifest.files {
+			_, generated := generatedPathToDigest[previousFile.path]
+			displayPath := filepath.Join(displayOutDirPath, normalpath.Unnormalize(previousFile.path))
+			switch {
+			case generated && previousFile.modified:
+				warn(fmt.Sprintf("%s was modified after it was generated, and has been overwritten", displayPath))
+			case generated, previousFile.missing:
+				// Nothing to do, either the file was regenerated or it no longer exists.
+			case previousFile.modified && deleteStaleFiles:
+				// The file is no longer generated, but it was modified, so we consider it
+				// to be owned by the user from now on.
+				warn(fmt.Sprintf("%s is no longer generated, but was modified after it was generated, so it was not deleted", displayPath))
+			case previousFile.modified:
+				warn(fmt.Sprintf("%s was modified after it was generated", displayPath))
+				externalManifest.Files = append(externalManifest.Files, previousFile.externalManifestFile)
+			case deleteStaleFiles:
+				stalePaths = append(stalePaths, previousFile.path)
+			default:
+				// Keep track of the file so that it can be deleted by a later clean.
+				externalManifest.Files = append(externalManifest.Files, previousFile.externalManifestFile)
+			}
+		}
+	}
+	sort.Slice(
+		externalManifest.Files,
+		func(i int, j int) bool {
+			return externalManifest.Files[i].Path < externalManifest.Files[j].Path
+		},
+	)
+	if len(externalManifest.Files) == 0 && len(stalePaths) == 0 {
+		if _, err := os.Stat(outDirPath); errors.Is(err, fs.ErrNotExist) {
+			// Nothing was ever generated here, do not create the directory just for the manifest.
+			return nil, nil, nil
+		}
+	}
+	data, err := json.MarshalIndent(externalManifest, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(data, '\n'), stalePaths, nil
+}
+
+// readPreviousManifest reads the manifest in the output directory, if it exists.
+//
+// Returns nil if there is no manifest.
+func (m *manifests) readPreviousManifest(ctx context.Context, outDirPath string) (*previousManifest, error) {
+	// OK to use os.Stat instead of os.Lstat here.
+	if _, err := os.Stat(outDirPath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	bucket, err := m.storageosProvider.NewReadWriteBucket(
+		outDirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	data, err := storage.ReadPath(ctx, bucket, ManifestFileName)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	displayManifestPath := filepath.Join(m.getDisplayPath(outDirPath), ManifestFileName)
+	var externalManifest externalManifest
+	if err := json.Unmarshal(data, &externalManifest); err != nil {
+		return nil, fmt.Errorf("invalid generation manifest %s: %w", displayManifestPath, err)
+	}
+	if externalManifest.Version != manifestVersion {
+		return nil, fmt.Errorf("invalid generation manifest %s: unknown version %q", displayManifestPath, externalManifest.Version)
+	}
+	previousManifest := &previousManifest{}
+	for _, externalManifestFile := range externalManifest.Files {
+		path, err := normalpath.NormalizeAndValidate(externalManifestFile.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid generation manifest %s: %w", displayManifestPath, err)
+		}
+		if path == ManifestFileName {
+			return nil, fmt.Errorf("invalid generation manifest %s: cannot contain itself", displayManifestPath)
+		}
+		previousFile := &previousManifestFile{
+			externalManifestFile: externalManifestFile,
+			path:                 path,
+		}
+		data, err := storage.ReadPath(ctx, bucket, path)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			previousFile.missing = true
+		} else {
+			digest, err := bufcas.NewDigestForContent(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			previousFile.modified = digest.String() != externalManifestFile.Digest
+		}
+		previousManifest.files = append(previousManifest.files, previousFile)
+	}
+	return previousManifest, nil
+}
+
+// getDisplayPath returns the path relative to the current directory if it is
+// contained within the current directory, for printing.
+func (*manifests) getDisplayPath(outDirPath string) string {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return outDirPath
+	}
+	relPath, err := filepath.Rel(pwd, outDirPath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return outDirPath
+	}
+	return relPath
+}
+
+type previousManifest struct {
+	files []*previousManifestFile
+}
+
+type previousManifestFile struct {
+	externalManifestFile *externalManifestFile
+	// The normalized path.
+	path string
+	// True if the file no longer exists.
+	missing bool
+	// True if the digest of the file on disk does not match the digest in the manifest.
+	modified bool
+}
+
+// externalManifest is the JSON representation of a manifest.
+type externalManifest struct {
+	Version string                  `json:"version"`
+	Files   []*externalManifestFile `json:"files"`
+}
+
+type externalManifestFile struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+func putPathWithExternalPath(
+	ctx context.Context,
+	writeBucket storage.WriteBucket,
+	path string,
+	data []byte,
+	externalPath string,
+) (retErr error) {
+	writeObjectCloser, err := writeBucket.Put(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, writeObjectCloser.Close())
+	}()
+	if _, err := writeObjectCloser.Write(data); err != nil {
+		return err
+	}
+	return writeObjectCloser.SetExternalPath(externalPath)
+}
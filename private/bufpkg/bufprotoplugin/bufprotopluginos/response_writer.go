@@ -15,9 +15,11 @@
 package bufprotopluginos
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -47,6 +49,15 @@ type responseWriter struct {
 	responseWriter    bufprotoplugin.ResponseWriter
 	// If set, create directories if they don't already exist.
 	createOutDirIfNotExists bool
+	// If set, the responses are compared with the existing content of the
+	// output locations on Close instead of being written, and a diff is
+	// written to the checkWriter.
+	checkWriter io.Writer
+	// If set, files in the previous manifest of an output directory that are not
+	// in any response are reported as removed when checking.
+	checkRemovedFiles bool
+	// If set, files written to output directories are recorded in the manifests.
+	manifests Manifests
 	// Cache the readWriteBuckets by their respective output paths.
 	// These builders are transformed to storage.ReadBuckets and written
 	// to disk once the responseWriter is flushed.
@@ -84,6 +95,9 @@ func newResponseWriter(
 		storageosProvider:       storageosProvider,
 		responseWriter:          bufprotoplugin.NewResponseWriter(logger),
 		createOutDirIfNotExists: responseWriterOptions.createOutDirIfNotExists,
+		checkWriter:             responseWriterOptions.checkWriter,
+		checkRemovedFiles:       responseWriterOptions.checkRemovedFiles,
+		manifests:               responseWriterOptions.manifests,
 		readWriteBuckets:        make(map[string]storage.ReadWriteBucket),
 	}
 }
@@ -102,7 +116,8 @@ func (w *responseWriter) AddResponse(
 	// For example:
 	//
 	// --insertion-point-receiver_out=insertion --insertion-point-writer_out=./insertion/ --insertion-point_writer_out=/foo/insertion
-	absPluginOut, err := filepath.Abs(normalpath.Unnormalize(pluginOut))
+	unnormalizedPluginOut := normalpath.Unnormalize(pluginOut)
+	absPluginOut, err := filepath.Abs(unnormalizedPluginOut)
 	if err != nil {
 		return err
 	}
@@ -112,6 +127,7 @@ func (w *responseWriter) AddResponse(
 		ctx,
 		response,
 		absPluginOut,
+		unnormalizedPluginOut,
 		w.createOutDirIfNotExists,
 	)
 }
@@ -140,6 +156,7 @@ func (w *responseWriter) addResponse(
 	ctx context.Context,
 	response *pluginpb.CodeGeneratorResponse,
 	pluginOut string,
+	displayPluginOut string,
 	createOutDirIfNotExists bool,
 ) error {
 	switch filepath.Ext(pluginOut) {
@@ -148,6 +165,7 @@ func (w *responseWriter) addResponse(
 			ctx,
 			response,
 			pluginOut,
+			displayPluginOut,
 			true,
 			createOutDirIfNotExists,
 		)
@@ -156,6 +174,7 @@ func (w *responseWriter) addResponse(
 			ctx,
 			response,
 			pluginOut,
+			displayPluginOut,
 			false,
 			createOutDirIfNotExists,
 		)
@@ -164,6 +183,7 @@ func (w *responseWriter) addResponse(
 			ctx,
 			response,
 			pluginOut,
+			displayPluginOut,
 			createOutDirIfNotExists,
 		)
 	}
@@ -173,6 +193,7 @@ func (w *responseWriter) writeZip(
 	ctx context.Context,
 	response *pluginpb.CodeGeneratorResponse,
 	outFilePath string,
+	displayOutFilePath string,
 	includeManifest bool,
 	createOutDirIfNotExists bool,
 ) (retErr error) {
@@ -190,21 +211,24 @@ func (w *responseWriter) writeZip(
 		}
 		return nil
 	}
-	// OK to use os.Stat instead of os.Lstat here.
-	fileInfo, err := os.Stat(outDirPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			if createOutDirIfNotExists {
-				if err := os.MkdirAll(outDirPath, 0755); err != nil {
+	// When checking, nothing is written, so we do not need the directory to exist.
+	if w.checkWriter == nil {
+		// OK to use os.Stat instead of os.Lstat here.
+		fileInfo, err := os.Stat(outDirPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				if createOutDirIfNotExists {
+					if err := os.MkdirAll(outDirPath, 0755); err != nil {
+						return err
+					}
+				} else {
 					return err
 				}
-			} else {
-				return err
 			}
+			return err
+		} else if !fileInfo.IsDir() {
+			return fmt.Errorf("not a directory: %s", outDirPath)
 		}
-		return err
-	} else if !fileInfo.IsDir() {
-		return fmt.Errorf("not a directory: %s", outDirPath)
 	}
 	readWriteBucket := storagemem.NewReadWriteBucket()
 	if includeManifest {
@@ -223,6 +247,12 @@ func (w *responseWriter) writeZip(
 	// Add this readWriteBucket to the set so that other plugins
 	// can write to the same files (re: insertion points).
 	w.readWriteBuckets[outFilePath] = readWriteBucket
+	if w.checkWriter != nil {
+		w.closers = append(w.closers, func() error {
+			return w.checkZip(ctx, readWriteBucket, outFilePath, displayOutFilePath)
+		})
+		return nil
+	}
 	w.closers = append(w.closers, func() (retErr error) {
 		// We're done writing all of the content into this
 		// readWriteBucket, so we zip it when we flush.
@@ -243,6 +273,7 @@ func (w *responseWriter) writeDirectory(
 	ctx context.Context,
 	response *pluginpb.CodeGeneratorResponse,
 	outDirPath string,
+	displayOutDirPath string,
 	createOutDirIfNotExists bool,
 ) error {
 	if readWriteBucket, ok := w.readWriteBuckets[outDirPath]; ok {
@@ -270,6 +301,15 @@ func (w *responseWriter) writeDirectory(
 	// Add this readWriteBucket to the set so that other plugins
 	// can write to the same files (re: insertion points).
 	w.readWriteBuckets[outDirPath] = readWriteBucket
+	if w.checkWriter != nil {
+		w.closers = append(w.closers, func() error {
+			if err := w.checkDirectory(ctx, readWriteBucket, outDirPath, displayOutDirPath); err != nil {
+				return err
+			}
+			return w.addGeneratedFiles(ctx, readWriteBucket, outDirPath)
+		})
+		return nil
+	}
 	w.closers = append(w.closers, func() error {
 		if createOutDirIfNotExists {
 			if err := os.MkdirAll(outDirPath, 0755); err != nil {
@@ -287,13 +327,173 @@ func (w *responseWriter) writeDirectory(
 		if _, err := storage.Copy(ctx, readWriteBucket, osReadWriteBucket); err != nil {
 			return err
 		}
-		return nil
+		return w.addGeneratedFiles(ctx, readWriteBucket, outDirPath)
 	})
 	return nil
 }
 
+// addGeneratedFiles records the files generated to outDirPath in the manifests, if set.
+func (w *responseWriter) addGeneratedFiles(
+	ctx context.Context,
+	generatedReadBucket storage.ReadBucket,
+	outDirPath string,
+) error {
+	if w.manifests == nil {
+		return nil
+	}
+	return storage.WalkReadObjects(
+		ctx,
+		generatedReadBucket,
+		"",
+		func(readObject storage.ReadObject) error {
+			data, err := io.ReadAll(readObject)
+			if err != nil {
+				return err
+			}
+			return w.manifests.addGeneratedFile(outDirPath, readObject.Path(), data)
+		},
+	)
+}
+
+// checkZip compares the content that would be zipped to outFilePath with the
+// content of the existing file, if any. As the file is replaced as a whole, entries
+// in the existing file that are not generated are always reported as removed.
+func (w *responseWriter) checkZip(
+	ctx context.Context,
+	generatedReadBucket storage.ReadBucket,
+	outFilePath string,
+	displayOutFilePath string,
+) error {
+	existingReadWriteBucket := storagemem.NewReadWriteBucket()
+	data, err := os.ReadFile(outFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := storagearchive.Unzip(ctx, bytes.NewReader(data), int64(len(data)), existingReadWriteBucket); err != nil {
+			return fmt.Errorf("%s: %w", displayOutFilePath, err)
+		}
+	}
+	return w.checkReadBuckets(ctx, existingReadWriteBucket, generatedReadBucket, displayOutFilePath)
+}
+
+// checkDirectory compares the content that would be written to outDirPath with
+// the existing content of the directory, if any.
+func (w *responseWriter) checkDirectory(
+	ctx context.Context,
+	generatedReadBucket storage.ReadBucket,
+	outDirPath string,
+	displayOutDirPath string,
+) error {
+	// OK to use os.Stat instead of os.Lstat here.
+	if _, err := os.Stat(outDirPath); err != nil {
+		if os.IsNotExist(err) {
+			// Nothing exists yet, so every generated file is added.
+			return w.checkReadBuckets(ctx, storagemem.NewReadWriteBucket(), generatedReadBucket, displayOutDirPath)
+		}
+		return err
+	}
+	existingReadBucket, err := w.storageosProvider.NewReadWriteBucket(
+		outDirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	var previousPaths []string
+	if w.checkRemovedFiles && w.manifests != nil {
+		// Output directories without a manifest have nothing deleted.
+		previousPaths, _ = w.manifests.getPreviousUnmodifiedPaths(outDirPath)
+	}
+	// Files that are not generated are left alone by generation, so we only
+	// compare the files that would be written, and the files that would be
+	// deleted as they were previously generated.
+	filteredExistingReadWriteBucket := storagemem.NewReadWriteBucket()
+	copyIfExists := func(path string) error {
+		exists, err := storage.Exists(ctx, existingReadBucket, path)
+		if err != nil || !exists {
+			return err
+		}
+		return storage.CopyPath(ctx, existingReadBucket, path, filteredExistingReadWriteBucket, path)
+	}
+	if err := storage.WalkReadObjects(
+		ctx,
+		generatedReadBucket,
+		"",
+		func(readObject storage.ReadObject) error {
+			return copyIfExists(readObject.Path())
+		},
+	); err != nil {
+		return err
+	}
+	for _, previousPath := range previousPaths {
+		if err := copyIfExists(previousPath); err != nil {
+			return err
+		}
+	}
+	return w.checkReadBuckets(ctx, filteredExistingReadWriteBucket, generatedReadBucket, displayOutDirPath)
+}
+
+// checkReadBuckets writes a diff between the existing and generated buckets to the checkWriter.
+func (w *responseWriter) checkReadBuckets(
+	ctx context.Context,
+	existingReadBucket storage.ReadBucket,
+	generatedReadBucket storage.ReadBucket,
+	displayOutPath string,
+) error {
+	// We copy both buckets so that the diff is printed with paths that include the output location.
+	existingDiffReadWriteBucket := storagemem.NewReadWriteBucket()
+	if err := copyWithExternalPathPrefix(ctx, existingReadBucket, existingDiffReadWriteBucket, displayOutPath); err != nil {
+		return err
+	}
+	generatedDiffReadWriteBucket := storagemem.NewReadWriteBucket()
+	if err := copyWithExternalPathPrefix(ctx, generatedReadBucket, generatedDiffReadWriteBucket, displayOutPath); err != nil {
+		return err
+	}
+	_, err := storage.DiffWithFilenames(
+		ctx,
+		w.checkWriter,
+		existingDiffReadWriteBucket,
+		generatedDiffReadWriteBucket,
+		storage.DiffWithExternalPaths(),
+		storage.DiffWithSuppressTimestamps(),
+	)
+	return err
+}
+
+func copyWithExternalPathPrefix(
+	ctx context.Context,
+	from storage.ReadBucket,
+	to storage.WriteBucket,
+	externalPathPrefix string,
+) error {
+	return storage.WalkReadObjects(
+		ctx,
+		from,
+		"",
+		func(readObject storage.ReadObject) (retErr error) {
+			writeObjectCloser, err := to.Put(ctx, readObject.Path())
+			if err != nil {
+				return err
+			}
+			defer func() {
+				retErr = errors.Join(retErr, writeObjectCloser.Close())
+			}()
+			if _, err := io.Copy(writeObjectCloser, readObject); err != nil {
+				return err
+			}
+			return writeObjectCloser.SetExternalPath(
+				filepath.Join(externalPathPrefix, normalpath.Unnormalize(readObject.Path())),
+			)
+		},
+	)
+}
+
 type responseWriterOptions struct {
 	createOutDirIfNotExists bool
+	checkWriter             io.Writer
+	checkRemovedFiles       bool
+	manifests               Manifests
 }
 
 func newResponseWriterOptions() *responseWriterOptions {
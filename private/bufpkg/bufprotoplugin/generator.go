@@ -45,7 +45,12 @@ func (g *generator) Generate(
 	ctx context.Context,
 	container app.EnvStderrContainer,
 	codeGeneratorRequests []*pluginpb.CodeGeneratorRequest,
+	options ...GenerateOption,
 ) (*pluginpb.CodeGeneratorResponse, error) {
+	generateOptions := newGenerateOptions()
+	for _, option := range options {
+		option(generateOptions)
+	}
 	protopluginResponseWriter := protoplugin.NewResponseWriter(
 		protoplugin.ResponseWriterWithLenientValidation(
 			func(err error) {
@@ -72,7 +77,12 @@ func (g *generator) Generate(
 			)
 		}
 	}
-	if err := thread.Parallelize(ctx, jobs, thread.ParallelizeWithCancelOnFailure()); err != nil {
+	if err := thread.Parallelize(
+		ctx,
+		jobs,
+		thread.ParallelizeWithCancelOnFailure(),
+		thread.ParallelizeWithParallelism(generateOptions.maxParallelism),
+	); err != nil {
 		return nil, err
 	}
 	codeGeneratorResponse, err := protopluginResponseWriter.ToCodeGeneratorResponse()
@@ -84,3 +94,11 @@ func (g *generator) Generate(
 	}
 	return codeGeneratorResponse, nil
 }
+
+type generateOptions struct {
+	maxParallelism int
+}
+
+func newGenerateOptions() *generateOptions {
+	return &generateOptions{}
+}
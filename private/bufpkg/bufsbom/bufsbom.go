@@ -0,0 +1,244 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufsbom produces software bills of materials for Modules.
+//
+// Documents are produced in the CycloneDX and SPDX JSON formats. The license of
+// each Module is detected from its LICENSE file.
+package bufsbom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"buf.build/go/spdx"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/google/uuid"
+)
+
+const (
+	// CycloneDXSpecVersion is the CycloneDX specification version of the produced documents.
+	CycloneDXSpecVersion = "1.5"
+	// SPDXVersion is the SPDX specification version of the produced documents.
+	SPDXVersion = "SPDX-2.3"
+
+	licenseFilePath = "LICENSE"
+)
+
+var spdxLicenseIdentifierRegexp = regexp.MustCompile(`(?m)SPDX-License-Identifier:\s*([A-Za-z0-9.+-]+)`)
+
+// Module is a Module within a software bill of materials.
+type Module struct {
+	// Name is the FullName of the Module, or the OpaqueID if the Module has no FullName.
+	Name string
+	// CommitID is the ID of the commit of the Module, or uuid.Nil if the Module has no commit.
+	CommitID uuid.UUID
+	// Digest is the b5 Digest of the Module.
+	Digest bufmodule.Digest
+	// LicenseID is the SPDX ID of the license of the Module, or empty if the license
+	// could not be detected.
+	LicenseID string
+	// Local is true if the Module is local to the workspace.
+	Local bool
+	// Target is true if the Module is a target Module.
+	Target bool
+	// DepNames are the Names of the direct dependencies of the Module, sorted.
+	DepNames []string
+}
+
+// DocumentInfo contains the information about a document that is not derived from
+// the Modules.
+type DocumentInfo struct {
+	// Name is the name of the document, typically the input the document was produced for.
+	Name string
+	// ID is the unique ID of the document.
+	ID uuid.UUID
+	// Time is the time the document was produced.
+	Time time.Time
+	// ToolName is the name of the tool that produced the document.
+	ToolName string
+	// ToolVersion is the version of the tool that produced the document.
+	ToolVersion string
+}
+
+// ModulesForModuleSet returns the Modules for the target Modules of the ModuleSet and
+// all their transitive dependencies, sorted by Name.
+func ModulesForModuleSet(ctx context.Context, moduleSet bufmodule.ModuleSet) ([]*Module, error) {
+	graph, err := bufmodule.ModuleSetToDAG(moduleSet)
+	if err != nil {
+		return nil, err
+	}
+	var modules []*Module
+	if err := graph.WalkNodes(
+		func(module bufmodule.Module, _ []bufmodule.Module, deps []bufmodule.Module) error {
+			digest, err := module.Digest(bufmodule.DigestTypeB5)
+			if err != nil {
+				return err
+			}
+			licenseID, err := getLicenseIDForModule(ctx, module)
+			if err != nil {
+				return err
+			}
+			depNames := make([]string, len(deps))
+			for i, dep := range deps {
+				depNames[i] = getModuleName(dep)
+			}
+			slices.Sort(depNames)
+			modules = append(
+				modules,
+				&Module{
+					Name:      getModuleName(module),
+					CommitID:  module.CommitID(),
+					Digest:    digest,
+					LicenseID: licenseID,
+					Local:     module.IsLocal(),
+					Target:    module.IsTarget(),
+					DepNames:  depNames,
+				},
+			)
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(
+		modules,
+		func(a *Module, b *Module) int {
+			return strings.Compare(a.Name, b.Name)
+		},
+	)
+	return modules, nil
+}
+
+// DetectLicenseID detects the SPDX ID of the license with the given text.
+//
+// An SPDX-License-Identifier line takes precedence. Otherwise, the text is matched against
+// commonly used licenses. Returns empty if the license could not be detected.
+func DetectLicenseID(data []byte) string {
+	if matches := spdxLicenseIdentifierRegexp.FindSubmatch(data); len(matches) == 2 {
+		if license, ok := spdx.LicenseForID(string(matches[1])); ok {
+			return license.ID
+		}
+	}
+	text := strings.Join(strings.Fields(strings.ToLower(string(data))), " ")
+	for _, licenseMatcher := range licenseMatchers {
+		if licenseMatcher.matches(text) {
+			if license, ok := spdx.LicenseForID(licenseMatcher.id); ok {
+				return license.ID
+			}
+		}
+	}
+	return ""
+}
+
+// *** PRIVATE ***
+
+// licenseMatchers are matched in order, so more specific licenses must come first.
+var licenseMatchers = []licenseMatcher{
+	{
+		id:       "Apache-2.0",
+		contains: []string{"apache license", "version 2.0"},
+	},
+	{
+		id:       "MIT",
+		contains: []string{"permission is hereby granted, free of charge", "the above copyright notice and this permission notice shall be included"},
+	},
+	{
+		id:       "BSD-3-Clause",
+		contains: []string{"redistribution and use in source and binary forms", "neither the name"},
+	},
+	{
+		id:       "BSD-2-Clause",
+		contains: []string{"redistribution and use in source and binary forms"},
+	},
+	{
+		id:       "ISC",
+		contains: []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted"},
+	},
+	{
+		id:       "MPL-2.0",
+		contains: []string{"mozilla public license", "2.0"},
+	},
+	{
+		id:       "AGPL-3.0-only",
+		contains: []string{"gnu affero general public license", "version 3"},
+	},
+	{
+		id:       "LGPL-3.0-only",
+		contains: []string{"gnu lesser general public license", "version 3"},
+	},
+	{
+		id:       "GPL-3.0-only",
+		contains: []string{"gnu general public license", "version 3"},
+	},
+	{
+		id:       "GPL-2.0-only",
+		contains: []string{"gnu general public license", "version 2"},
+	},
+	{
+		id:       "Unlicense",
+		contains: []string{"this is free and unencumbered software released into the public domain"},
+	},
+	{
+		id:       "CC0-1.0",
+		contains: []string{"cc0 1.0 universal"},
+	},
+}
+
+type licenseMatcher struct {
+	id string
+	// contains are the normalized strings that must all be contained in the
+	// normalized license text.
+	contains []string
+}
+
+func (l licenseMatcher) matches(text string) bool {
+	for _, s := range l.contains {
+		if !strings.Contains(text, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func getLicenseIDForModule(ctx context.Context, module bufmodule.Module) (_ string, retErr error) {
+	file, err := module.GetFile(ctx, licenseFilePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer func() {
+		retErr = errors.Join(retErr, file.Close())
+	}()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return DetectLicenseID(data), nil
+}
+
+func getModuleName(module bufmodule.Module) string {
+	if moduleFullName := module.FullName(); moduleFullName != nil {
+		return moduleFullName.String()
+	}
+	return module.OpaqueID()
+}
@@ -0,0 +1,142 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectLicenseID(t *testing.T) {
+	t.Parallel()
+	testDetectLicenseID(t, "Apache-2.0", "                                 Apache License\n                           Version 2.0, January 2004\n")
+	testDetectLicenseID(t, "MIT", "MIT License\n\nPermission is hereby granted, free of charge, to any person obtaining a copy\nof this software...\n\nThe above copyright notice and this permission notice shall be included in all\ncopies.")
+	testDetectLicenseID(t, "BSD-3-Clause", "Redistribution and use in source and binary forms, with or without\nmodification, are permitted...\n3. Neither the name of the copyright holder")
+	testDetectLicenseID(t, "BSD-2-Clause", "Redistribution and use in source and binary forms, with or without\nmodification, are permitted")
+	testDetectLicenseID(t, "GPL-3.0-only", "GNU GENERAL PUBLIC LICENSE\n Version 3, 29 June 2007")
+	testDetectLicenseID(t, "LGPL-3.0-only", "GNU LESSER GENERAL PUBLIC LICENSE\n Version 3, 29 June 2007")
+	// SPDX-License-Identifier takes precedence, and is case-insensitive.
+	testDetectLicenseID(t, "MPL-2.0", "// SPDX-License-Identifier: mpl-2.0\nApache License Version 2.0")
+	testDetectLicenseID(t, "", "SPDX-License-Identifier: NotALicense")
+	testDetectLicenseID(t, "", "All rights reserved.")
+	testDetectLicenseID(t, "", "")
+}
+
+func TestWriteCycloneDX(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteCycloneDX(buffer, testNewDocumentInfo(), testNewModules(t)))
+	var document map[string]any
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &document))
+	require.Equal(t, "CycloneDX", document["bomFormat"])
+	require.Equal(t, CycloneDXSpecVersion, document["specVersion"])
+	require.Equal(t, "urn:uuid:"+testDocumentID.String(), document["serialNumber"])
+	components := document["components"].([]any)
+	require.Len(t, components, 2)
+	local := components[0].(map[string]any)
+	require.Equal(t, "local", local["name"])
+	require.Nil(t, local["version"])
+	require.Nil(t, local["licenses"])
+	remote := components[1].(map[string]any)
+	require.Equal(t, "buf.build/acme/weather", remote["name"])
+	require.Equal(t, strings.ReplaceAll(testCommitID.String(), "-", ""), remote["version"])
+	require.Equal(t, "buf.build/acme/weather:"+strings.ReplaceAll(testCommitID.String(), "-", ""), remote["bom-ref"])
+	require.Equal(
+		t,
+		[]any{map[string]any{"license": map[string]any{"id": "Apache-2.0"}}},
+		remote["licenses"],
+	)
+	require.Equal(
+		t,
+		[]any{
+			map[string]any{"ref": "local", "dependsOn": []any{remote["bom-ref"]}},
+			map[string]any{"ref": remote["bom-ref"], "dependsOn": []any{}},
+		},
+		document["dependencies"],
+	)
+}
+
+func TestWriteSPDX(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, WriteSPDX(buffer, testNewDocumentInfo(), testNewModules(t)))
+	var document map[string]any
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &document))
+	require.Equal(t, SPDXVersion, document["spdxVersion"])
+	require.Equal(t, "https://buf.build/spdxdocs/proto-"+testDocumentID.String(), document["documentNamespace"])
+	require.Equal(t, []any{"SPDXRef-Module-0"}, document["documentDescribes"])
+	packages := document["packages"].([]any)
+	require.Len(t, packages, 2)
+	local := packages[0].(map[string]any)
+	require.Equal(t, "NOASSERTION", local["licenseConcluded"])
+	require.Equal(t, "NOASSERTION", local["downloadLocation"])
+	remote := packages[1].(map[string]any)
+	require.Equal(t, "Apache-2.0", remote["licenseConcluded"])
+	require.Equal(t, "https://buf.build/acme/weather", remote["downloadLocation"])
+	require.Equal(
+		t,
+		[]any{
+			map[string]any{"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-Module-0"},
+			map[string]any{"spdxElementId": "SPDXRef-Module-0", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-Module-1"},
+		},
+		document["relationships"],
+	)
+}
+
+var (
+	testDocumentID = uuid.MustParse("6a9e8d3c-0b6b-4d1e-9b1f-2d3c4b5a6f70")
+	testCommitID   = uuid.MustParse("0d4f1e2a-3b5c-4d6e-8f70-8192a3b4c5d6")
+)
+
+func testDetectLicenseID(t *testing.T, expected string, text string) {
+	require.Equal(t, expected, DetectLicenseID([]byte(text)), text)
+}
+
+func testNewDocumentInfo() DocumentInfo {
+	return DocumentInfo{
+		Name:        "proto",
+		ID:          testDocumentID,
+		Time:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ToolName:    "buf",
+		ToolVersion: "1.0.0",
+	}
+}
+
+func testNewModules(t *testing.T) []*Module {
+	digest, err := bufmodule.ParseDigest("b5:" + strings.Repeat("ab", 64))
+	require.NoError(t, err)
+	return []*Module{
+		{
+			Name:     "local",
+			Digest:   digest,
+			Local:    true,
+			Target:   true,
+			DepNames: []string{"buf.build/acme/weather"},
+		},
+		{
+			Name:      "buf.build/acme/weather",
+			CommitID:  testCommitID,
+			Digest:    digest,
+			LicenseID: "Apache-2.0",
+		},
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsbom
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+const cycloneDXDigestPropertyName = "buf:digest"
+
+// WriteCycloneDX writes a CycloneDX JSON document for the Modules.
+//
+// Each Module is a component with the commit as its version. The b5 Digest is not a
+// CycloneDX hash algorithm, so it is written as the "buf:digest" property.
+//
+// https://cyclonedx.org/docs/1.5/json
+func WriteCycloneDX(writer io.Writer, documentInfo DocumentInfo, modules []*Module) error {
+	document := &externalCycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  CycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + documentInfo.ID.String(),
+		Version:      1,
+		Metadata: externalCycloneDXMetadata{
+			Timestamp: documentInfo.Time.UTC().Format(time.RFC3339),
+			Tools: externalCycloneDXTools{
+				Components: []externalCycloneDXComponent{
+					{
+						Type:    "application",
+						Name:    documentInfo.ToolName,
+						Version: documentInfo.ToolVersion,
+					},
+				},
+			},
+			Component: &externalCycloneDXComponent{
+				Type: "application",
+				Name: documentInfo.Name,
+			},
+		},
+		Components:   []externalCycloneDXComponent{},
+		Dependencies: []externalCycloneDXDependency{},
+	}
+	nameToBOMRef := make(map[string]string, len(modules))
+	for _, module := range modules {
+		nameToBOMRef[module.Name] = getModuleRef(module)
+	}
+	for _, module := range modules {
+		component := externalCycloneDXComponent{
+			Type:   "library",
+			BOMRef: nameToBOMRef[module.Name],
+			Name:   module.Name,
+			Properties: []externalCycloneDXProperty{
+				{
+					Name:  cycloneDXDigestPropertyName,
+					Value: module.Digest.String(),
+				},
+			},
+		}
+		if module.CommitID != uuid.Nil {
+			component.Version = uuidutil.ToDashless(module.CommitID)
+		}
+		if module.LicenseID != "" {
+			component.Licenses = []externalCycloneDXLicenseChoice{
+				{
+					License: externalCycloneDXLicense{
+						ID: module.LicenseID,
+					},
+				},
+			}
+		}
+		document.Components = append(document.Components, component)
+		dependency := externalCycloneDXDependency{
+			Ref:       nameToBOMRef[module.Name],
+			DependsOn: []string{},
+		}
+		for _, depName := range module.DepNames {
+			dependency.DependsOn = append(dependency.DependsOn, nameToBOMRef[depName])
+		}
+		document.Dependencies = append(document.Dependencies, dependency)
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// *** PRIVATE ***
+
+type externalCycloneDXDocument struct {
+	BOMFormat    string                        `json:"bomFormat"`
+	SpecVersion  string                        `json:"specVersion"`
+	SerialNumber string                        `json:"serialNumber"`
+	Version      int                           `json:"version"`
+	Metadata     externalCycloneDXMetadata     `json:"metadata"`
+	Components   []externalCycloneDXComponent  `json:"components"`
+	Dependencies []externalCycloneDXDependency `json:"dependencies"`
+}
+
+type externalCycloneDXMetadata struct {
+	Timestamp string                      `json:"timestamp"`
+	Tools     externalCycloneDXTools      `json:"tools"`
+	Component *externalCycloneDXComponent `json:"component,omitempty"`
+}
+
+type externalCycloneDXTools struct {
+	Components []externalCycloneDXComponent `json:"components"`
+}
+
+type externalCycloneDXComponent struct {
+	Type       string                           `json:"type"`
+	BOMRef     string                           `json:"bom-ref,omitempty"`
+	Name       string                           `json:"name"`
+	Version    string                           `json:"version,omitempty"`
+	Licenses   []externalCycloneDXLicenseChoice `json:"licenses,omitempty"`
+	Properties []externalCycloneDXProperty      `json:"properties,omitempty"`
+}
+
+type externalCycloneDXLicenseChoice struct {
+	License externalCycloneDXLicense `json:"license"`
+}
+
+type externalCycloneDXLicense struct {
+	ID string `json:"id"`
+}
+
+type externalCycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type externalCycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// getModuleRef returns the reference for the Module within a document, which is the
+// Name and the commit if present.
+func getModuleRef(module *Module) string {
+	if module.CommitID != uuid.Nil {
+		return module.Name + ":" + uuidutil.ToDashless(module.CommitID)
+	}
+	return module.Name
+}
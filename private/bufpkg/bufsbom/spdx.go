@@ -0,0 +1,145 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsbom
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/uuidutil"
+	"github.com/google/uuid"
+)
+
+const (
+	spdxNoAssertion   = "NOASSERTION"
+	spdxDocumentID    = "SPDXRef-DOCUMENT"
+	spdxNamespaceBase = "https://buf.build/spdxdocs/"
+)
+
+// WriteSPDX writes an SPDX JSON document for the Modules.
+//
+// Each Module is a package with the commit as its version. The target Modules are
+// described by the document. The b5 Digest is not an SPDX checksum algorithm, so it
+// is written in the comment of the package.
+//
+// https://spdx.github.io/spdx-spec/v2.3
+func WriteSPDX(writer io.Writer, documentInfo DocumentInfo, modules []*Module) error {
+	document := &externalSPDXDocument{
+		SPDXVersion:       SPDXVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            spdxDocumentID,
+		Name:              documentInfo.Name,
+		DocumentNamespace: spdxNamespaceBase + url.PathEscape(documentInfo.Name) + "-" + documentInfo.ID.String(),
+		CreationInfo: externalSPDXCreationInfo{
+			Created:  documentInfo.Time.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: " + documentInfo.ToolName + "-" + documentInfo.ToolVersion},
+		},
+		Packages:      []externalSPDXPackage{},
+		Relationships: []externalSPDXRelationship{},
+	}
+	nameToSPDXID := make(map[string]string, len(modules))
+	for i, module := range modules {
+		nameToSPDXID[module.Name] = "SPDXRef-Module-" + strconv.Itoa(i)
+	}
+	for _, module := range modules {
+		spdxID := nameToSPDXID[module.Name]
+		license := spdxNoAssertion
+		if module.LicenseID != "" {
+			license = module.LicenseID
+		}
+		downloadLocation := spdxNoAssertion
+		if !module.Local {
+			downloadLocation = "https://" + module.Name
+		}
+		spdxPackage := externalSPDXPackage{
+			Name:             module.Name,
+			SPDXID:           spdxID,
+			DownloadLocation: downloadLocation,
+			FilesAnalyzed:    false,
+			LicenseConcluded: license,
+			LicenseDeclared:  license,
+			CopyrightText:    spdxNoAssertion,
+			Comment:          "digest: " + module.Digest.String(),
+		}
+		if module.CommitID != uuid.Nil {
+			spdxPackage.VersionInfo = uuidutil.ToDashless(module.CommitID)
+		}
+		document.Packages = append(document.Packages, spdxPackage)
+		if module.Target {
+			document.DocumentDescribes = append(document.DocumentDescribes, spdxID)
+			document.Relationships = append(
+				document.Relationships,
+				externalSPDXRelationship{
+					SPDXElementID:      spdxDocumentID,
+					RelationshipType:   "DESCRIBES",
+					RelatedSPDXElement: spdxID,
+				},
+			)
+		}
+		for _, depName := range module.DepNames {
+			document.Relationships = append(
+				document.Relationships,
+				externalSPDXRelationship{
+					SPDXElementID:      spdxID,
+					RelationshipType:   "DEPENDS_ON",
+					RelatedSPDXElement: nameToSPDXID[depName],
+				},
+			)
+		}
+	}
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(document)
+}
+
+// *** PRIVATE ***
+
+type externalSPDXDocument struct {
+	SPDXVersion       string                     `json:"spdxVersion"`
+	DataLicense       string                     `json:"dataLicense"`
+	SPDXID            string                     `json:"SPDXID"`
+	Name              string                     `json:"name"`
+	DocumentNamespace string                     `json:"documentNamespace"`
+	CreationInfo      externalSPDXCreationInfo   `json:"creationInfo"`
+	DocumentDescribes []string                   `json:"documentDescribes,omitempty"`
+	Packages          []externalSPDXPackage      `json:"packages"`
+	Relationships     []externalSPDXRelationship `json:"relationships"`
+}
+
+type externalSPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type externalSPDXPackage struct {
+	Name             string `json:"name"`
+	SPDXID           string `json:"SPDXID"`
+	VersionInfo      string `json:"versionInfo,omitempty"`
+	DownloadLocation string `json:"downloadLocation"`
+	FilesAnalyzed    bool   `json:"filesAnalyzed"`
+	LicenseConcluded string `json:"licenseConcluded"`
+	LicenseDeclared  string `json:"licenseDeclared"`
+	CopyrightText    string `json:"copyrightText"`
+	Comment          string `json:"comment,omitempty"`
+}
+
+type externalSPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
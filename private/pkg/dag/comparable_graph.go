@@ -113,6 +113,13 @@ func (g *ComparableGraph[Value]) TopoSort(start Value) ([]Value, error) {
 	return g.Graph().TopoSort(start)
 }
 
+// AllPaths returns all paths in the Graph from the from node to the to node.
+//
+// Returns a *CycleError if there is a cycle in the graph reachable from the from node.
+func (g *ComparableGraph[Value]) AllPaths(from Value, to Value) ([][]Value, error) {
+	return g.Graph().AllPaths(from, to)
+}
+
 // DOTString returns a DOT representation of the graph.
 //
 // valueToString is used to print out the label for each node.
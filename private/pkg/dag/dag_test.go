@@ -125,6 +125,49 @@ func TestTopoSortCycleError3(t *testing.T) {
 	)
 }
 
+func TestAllPaths(t *testing.T) {
+	t.Parallel()
+	graph := dag.NewComparableGraph[string]()
+	graph.AddEdge("a", "b")
+	graph.AddEdge("a", "c")
+	graph.AddEdge("b", "d")
+	graph.AddEdge("c", "d")
+	graph.AddEdge("c", "e")
+	graph.AddEdge("e", "d")
+	paths, err := graph.AllPaths("a", "d")
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[][]string{
+			{"a", "b", "d"},
+			{"a", "c", "d"},
+			{"a", "c", "e", "d"},
+		},
+		paths,
+	)
+	paths, err = graph.AllPaths("d", "a")
+	require.NoError(t, err)
+	require.Empty(t, paths)
+	paths, err = graph.AllPaths("a", "a")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a"}}, paths)
+	_, err = graph.AllPaths("a", "f")
+	require.Error(t, err)
+}
+
+func TestAllPathsCycleError(t *testing.T) {
+	t.Parallel()
+	graph := dag.NewComparableGraph[string]()
+	graph.AddEdge("a", "b")
+	graph.AddEdge("b", "c")
+	graph.AddEdge("c", "b")
+	graph.AddEdge("c", "d")
+	_, err := graph.AllPaths("a", "d")
+	cycleError := &dag.CycleError[string]{}
+	require.ErrorAs(t, err, &cycleError)
+	require.Equal(t, []string{"b", "c", "b"}, cycleError.Keys)
+}
+
 func TestWalkEdges(t *testing.T) {
 	t.Parallel()
 	testWalkEdgesSuccess(
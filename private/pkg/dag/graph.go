@@ -235,6 +235,35 @@ func (g *Graph[Key, Value]) TopoSort(start Key) ([]Value, error) {
 	return g.getValuesForKeys(results.keys)
 }
 
+// AllPaths returns all paths in the Graph from the node for the from key to the node for the to key.
+//
+// Each path starts with the from node and ends with the to node. Paths are returned in
+// the order they are found by a depth-first traversal of outbound edges, which is deterministic.
+// Returns an empty slice if there is no path.
+//
+// Returns a *CycleError if there is a cycle in the graph reachable from the from key.
+func (g *Graph[Key, Value]) AllPaths(from Key, to Key) ([][]Value, error) {
+	if err := g.checkInit(); err != nil {
+		return nil, err
+	}
+	if _, ok := g.keyToNode[to]; !ok {
+		return nil, fmt.Errorf("key not present: %v", to)
+	}
+	var keyPaths [][]Key
+	if err := g.pathVisit(from, to, newOrderedSet[Key](), &keyPaths); err != nil {
+		return nil, err
+	}
+	paths := make([][]Value, len(keyPaths))
+	for i, keyPath := range keyPaths {
+		path, err := g.getValuesForKeys(keyPath)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = path
+	}
+	return paths, nil
+}
+
 // DOTString returns a DOT representation of the graph.
 //
 // valueToString is used to print out the label for each node.
@@ -435,6 +464,33 @@ func (g *Graph[Key, Value]) topoVisit(
 	return nil
 }
 
+func (g *Graph[Key, Value]) pathVisit(
+	from Key,
+	to Key,
+	visited *orderedSet[Key],
+	keyPaths *[][]Key,
+) error {
+	if !visited.add(from) {
+		index := visited.index(from)
+		cycle := append(visited.keys[index:], from)
+		return &CycleError[Key]{Keys: cycle}
+	}
+	if from == to {
+		*keyPaths = append(*keyPaths, visited.keys)
+		return nil
+	}
+	fromNode, ok := g.keyToNode[from]
+	if !ok {
+		return fmt.Errorf("key not present: %v", from)
+	}
+	for _, next := range fromNode.outboundEdges {
+		if err := g.pathVisit(next, to, visited.copy(), keyPaths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type node[Key comparable] struct {
 	outboundEdgeMap map[Key]struct{}
 	// need to store order for deterministic visits
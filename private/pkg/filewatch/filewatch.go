@@ -0,0 +1,95 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filewatch watches files for changes.
+//
+// Files are watched by polling their size and modification time, so that no
+// platform-specific notification mechanism is needed.
+package filewatch
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the default interval between polls of the files.
+	DefaultPollInterval = 250 * time.Millisecond
+	// DefaultDebounce is the default duration that the files must not change for
+	// after a change before the change is reported.
+	DefaultDebounce = 100 * time.Millisecond
+)
+
+// Snapshot is the state of the files within a set of paths at a point in time.
+type Snapshot interface {
+	// Paths returns the paths of the files in the Snapshot, sorted.
+	Paths() []string
+
+	isSnapshot()
+}
+
+// NewSnapshot returns a new Snapshot of the files within the paths.
+//
+// Directories are walked recursively, except for hidden directories such as ".git"
+// below them. Only regular files for which include returns true are part of the
+// Snapshot. If include is nil, all regular files are part of the Snapshot. Paths
+// that do not exist are ignored, so that they are part of the Snapshot once they
+// are created.
+func NewSnapshot(paths []string, include func(string) bool) (Snapshot, error) {
+	return newSnapshot(paths, include)
+}
+
+// ChangedPaths returns the paths of the files that were added, modified, or removed
+// between the Snapshots, sorted.
+func ChangedPaths(from Snapshot, to Snapshot) []string {
+	return changedPaths(from.(*snapshot), to.(*snapshot))
+}
+
+// WaitForChange polls the files within the paths of the Snapshot until they change, and returns the
+// paths of the changed files along with a new Snapshot of the files.
+//
+// After the first change, polling continues until the files have not changed for
+// the debounce duration, so that a burst of changes, such as when an editor saves
+// several files, is reported once.
+//
+// Returns the error of the context if the context is done before the files change.
+func WaitForChange(
+	ctx context.Context,
+	from Snapshot,
+	options ...WaitForChangeOption,
+) ([]string, Snapshot, error) {
+	return waitForChange(ctx, from.(*snapshot), options...)
+}
+
+// WaitForChangeOption is an option for WaitForChange.
+type WaitForChangeOption func(*waitForChangeOptions)
+
+// WaitForChangeWithPollInterval sets the interval between polls of the files.
+//
+// The default is DefaultPollInterval.
+func WaitForChangeWithPollInterval(pollInterval time.Duration) WaitForChangeOption {
+	return func(waitForChangeOptions *waitForChangeOptions) {
+		waitForChangeOptions.pollInterval = pollInterval
+	}
+}
+
+// WaitForChangeWithDebounce sets the duration that the files must not change for
+// after a change before the change is reported.
+//
+// The default is DefaultDebounce.
+func WaitForChangeWithDebounce(debounce time.Duration) WaitForChangeOption {
+	return func(waitForChangeOptions *waitForChangeOptions) {
+		waitForChangeOptions.debounce = debounce
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filewatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	testWriteFile(t, filepath.Join(dirPath, "a.proto"), "a")
+	testWriteFile(t, filepath.Join(dirPath, "b", "b.proto"), "b")
+	testWriteFile(t, filepath.Join(dirPath, "b", "b.txt"), "b")
+	testWriteFile(t, filepath.Join(dirPath, ".git", "c.proto"), "c")
+	from, err := NewSnapshot(
+		[]string{dirPath, filepath.Join(dirPath, "missing.proto")},
+		testIsProtoFile,
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "a.proto"),
+			filepath.Join(dirPath, "b", "b.proto"),
+		},
+		from.Paths(),
+	)
+	testWriteFile(t, filepath.Join(dirPath, "b", "b.proto"), "bb")
+	testWriteFile(t, filepath.Join(dirPath, "b", "b.txt"), "bb")
+	testWriteFile(t, filepath.Join(dirPath, "missing.proto"), "d")
+	require.NoError(t, os.Remove(filepath.Join(dirPath, "a.proto")))
+	to, err := NewSnapshot(
+		[]string{dirPath, filepath.Join(dirPath, "missing.proto")},
+		testIsProtoFile,
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "a.proto"),
+			filepath.Join(dirPath, "b", "b.proto"),
+			filepath.Join(dirPath, "missing.proto"),
+		},
+		ChangedPaths(from, to),
+	)
+	require.Empty(t, ChangedPaths(to, to))
+}
+
+func TestWaitForChange(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	testWriteFile(t, filepath.Join(dirPath, "a.proto"), "a")
+	from, err := NewSnapshot([]string{dirPath}, nil)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		assert.NoError(t, os.WriteFile(filepath.Join(dirPath, "b.proto"), []byte("b"), 0600))
+		time.Sleep(5 * time.Millisecond)
+		assert.NoError(t, os.WriteFile(filepath.Join(dirPath, "c.proto"), []byte("c"), 0600))
+	}()
+	changedPaths, to, err := WaitForChange(
+		ctx,
+		from,
+		WaitForChangeWithPollInterval(time.Millisecond),
+		WaitForChangeWithDebounce(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+	// Both changes are reported at once.
+	require.Equal(
+		t,
+		[]string{
+			filepath.Join(dirPath, "b.proto"),
+			filepath.Join(dirPath, "c.proto"),
+		},
+		changedPaths,
+	)
+	require.Len(t, to.Paths(), 3)
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = WaitForChange(cancelledCtx, to, WaitForChangeWithPollInterval(time.Millisecond))
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func testIsProtoFile(path string) bool {
+	return strings.HasSuffix(path, ".proto")
+}
+
+func testWriteFile(t *testing.T, path string, data string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+}
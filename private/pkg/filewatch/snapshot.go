@@ -0,0 +1,178 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filewatch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+type snapshot struct {
+	paths       []string
+	include     func(string) bool
+	pathToState map[string]fileState
+}
+
+func newSnapshot(paths []string, include func(string) bool) (*snapshot, error) {
+	if include == nil {
+		include = func(string) bool { return true }
+	}
+	pathToState := make(map[string]fileState)
+	for _, path := range paths {
+		if err := addFileStates(pathToState, path, include); err != nil {
+			return nil, err
+		}
+	}
+	return &snapshot{
+		paths:       paths,
+		include:     include,
+		pathToState: pathToState,
+	}, nil
+}
+
+func (s *snapshot) Paths() []string {
+	paths := make([]string, 0, len(s.pathToState))
+	for path := range s.pathToState {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+func (*snapshot) isSnapshot() {}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+func changedPaths(from *snapshot, to *snapshot) []string {
+	var changedPaths []string
+	for path, fromState := range from.pathToState {
+		if toState, ok := to.pathToState[path]; !ok || toState != fromState {
+			changedPaths = append(changedPaths, path)
+		}
+	}
+	for path := range to.pathToState {
+		if _, ok := from.pathToState[path]; !ok {
+			changedPaths = append(changedPaths, path)
+		}
+	}
+	slices.Sort(changedPaths)
+	return changedPaths
+}
+
+func waitForChange(
+	ctx context.Context,
+	from *snapshot,
+	options ...WaitForChangeOption,
+) ([]string, Snapshot, error) {
+	waitForChangeOptions := newWaitForChangeOptions()
+	for _, option := range options {
+		option(waitForChangeOptions)
+	}
+	ticker := time.NewTicker(waitForChangeOptions.pollInterval)
+	defer ticker.Stop()
+	var changed *snapshot
+	var lastChangeTime time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-ticker.C:
+		}
+		current, err := newSnapshot(from.paths, from.include)
+		if err != nil {
+			return nil, nil, err
+		}
+		if changed == nil {
+			if len(changedPaths(from, current)) > 0 {
+				changed = current
+				lastChangeTime = time.Now()
+			}
+			continue
+		}
+		if len(changedPaths(changed, current)) > 0 {
+			changed = current
+			lastChangeTime = time.Now()
+			continue
+		}
+		if time.Since(lastChangeTime) >= waitForChangeOptions.debounce {
+			if paths := changedPaths(from, changed); len(paths) > 0 {
+				return paths, changed, nil
+			}
+			// The files changed back to their original state.
+			changed = nil
+		}
+	}
+}
+
+type waitForChangeOptions struct {
+	pollInterval time.Duration
+	debounce     time.Duration
+}
+
+func newWaitForChangeOptions() *waitForChangeOptions {
+	return &waitForChangeOptions{
+		pollInterval: DefaultPollInterval,
+		debounce:     DefaultDebounce,
+	}
+}
+
+func addFileStates(pathToState map[string]fileState, rootPath string, include func(string) bool) error {
+	return filepath.WalkDir(
+		rootPath,
+		func(path string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					// The file was removed while walking, or the root does not exist yet.
+					return nil
+				}
+				return err
+			}
+			if dirEntry.IsDir() {
+				if path != rootPath && strings.HasPrefix(dirEntry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !include(path) {
+				return nil
+			}
+			// Follow symlinks, so that changes to the files they point to are seen.
+			fileInfo, err := os.Stat(path)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !fileInfo.Mode().IsRegular() {
+				return nil
+			}
+			pathToState[path] = fileState{
+				size:    fileInfo.Size(),
+				modTime: fileInfo.ModTime(),
+			}
+			return nil
+		},
+	)
+}
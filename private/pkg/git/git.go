@@ -140,6 +140,50 @@ type Lister interface {
 		envContainer app.EnvStdioContainer,
 		options ListFilesAndUnstagedFilesOptions,
 	) ([]string, error)
+	// ListTags lists the tags in the git repository of the current directory that
+	// match the given glob pattern, such as "v1.*".
+	//
+	// The returned tags are sorted in version order, oldest version first.
+	//
+	// This is the equivalent of doing:
+	//
+	//	git tag --list --sort=version:refname PATTERN
+	ListTags(
+		ctx context.Context,
+		envContainer app.EnvStdioContainer,
+		pattern string,
+	) ([]string, error)
+	// ListCommits lists the commits in the git repository of the current directory
+	// that are within the given revision range, such as "v1.0.0..v1.2.0".
+	//
+	// The returned commits are full hashes, oldest commit first.
+	//
+	// This is the equivalent of doing:
+	//
+	//	git rev-list --reverse REVISION_RANGE
+	ListCommits(
+		ctx context.Context,
+		envContainer app.EnvStdioContainer,
+		revisionRange string,
+	) ([]string, error)
+	// ListChangedFiles lists the files in the current directory that changed relative to
+	// the given git ref, such as "main" or "HEAD~1".
+	//
+	// This lists committed, staged, and unstaged changes, including deleted files, as well
+	// as untracked files that are not ignored. Renames are listed as a deletion and an addition.
+	//
+	// The returned paths are relative to the current directory, unnormalized, and sorted.
+	//
+	// This is the equivalent of doing:
+	//
+	//	sort -u \
+	//		<(git diff --name-only --relative --no-renames REF) \
+	//		<(git ls-files --others --exclude-standard)
+	ListChangedFiles(
+		ctx context.Context,
+		envContainer app.EnvStdioContainer,
+		ref string,
+	) ([]string, error)
 }
 
 // NewLister returns a new Lister.
@@ -75,6 +75,83 @@ func (l *lister) ListFilesAndUnstagedFiles(
 	), nil
 }
 
+func (l *lister) ListTags(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	pattern string,
+) ([]string, error) {
+	output, err := runStdout(
+		ctx,
+		container,
+		"git",
+		"tag",
+		"--list",
+		"--sort=version:refname",
+		pattern,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stringutil.SplitTrimLinesNoEmpty(string(output)), nil
+}
+
+func (l *lister) ListCommits(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	revisionRange string,
+) ([]string, error) {
+	output, err := runStdout(
+		ctx,
+		container,
+		"git",
+		"rev-list",
+		"--reverse",
+		revisionRange,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return stringutil.SplitTrimLinesNoEmpty(string(output)), nil
+}
+
+func (l *lister) ListChangedFiles(
+	ctx context.Context,
+	container app.EnvStdioContainer,
+	ref string,
+) ([]string, error) {
+	diffOutput, err := runStdout(
+		ctx,
+		container,
+		"git",
+		"diff",
+		"--name-only",
+		"--relative",
+		"--no-renames",
+		ref,
+		"--",
+	)
+	if err != nil {
+		return nil, err
+	}
+	untrackedFilesOutput, err := runStdout(
+		ctx,
+		container,
+		"git",
+		"ls-files",
+		"--others",
+		"--exclude-standard",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return slicesext.ToUniqueSorted(
+		append(
+			stringutil.SplitTrimLinesNoEmpty(string(diffOutput)),
+			stringutil.SplitTrimLinesNoEmpty(string(untrackedFilesOutput))...,
+		),
+	), nil
+}
+
 // stringSliceExcept returns all elements in source that are not in except.
 func stringSliceExcept(source []string, except []string) []string {
 	exceptMap := slicesext.ToStructMap(except)
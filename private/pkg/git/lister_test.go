@@ -0,0 +1,154 @@
+// Copyright 2020-2024 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/stretchr/testify/require"
+)
+
+// The Lister lists the git repository of the current directory, so these tests
+// change the working directory and cannot be run in parallel.
+
+func TestListTags(t *testing.T) {
+	ctx := context.Background()
+	container, err := app.NewContainerForOS()
+	require.NoError(t, err)
+	repoPath := createListerGitDir(ctx, t, container)
+	testChdir(t, repoPath)
+
+	tags, err := NewLister().ListTags(ctx, container, "v1.*")
+	require.NoError(t, err)
+	// Sorted by version, not lexically.
+	require.Equal(t, []string{"v1.0.0", "v1.2.0", "v1.10.0"}, tags)
+	tags, err = NewLister().ListTags(ctx, container, "v2.*")
+	require.NoError(t, err)
+	require.Empty(t, tags)
+}
+
+func TestListCommits(t *testing.T) {
+	ctx := context.Background()
+	container, err := app.NewContainerForOS()
+	require.NoError(t, err)
+	repoPath := createListerGitDir(ctx, t, container)
+	testChdir(t, repoPath)
+
+	commits, err := NewLister().ListCommits(ctx, container, "v1.0.0..v1.10.0")
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			revParse(ctx, t, container, repoPath, "v1.2.0"),
+			revParse(ctx, t, container, repoPath, "v1.10.0"),
+		},
+		commits,
+	)
+	commits, err = NewLister().ListCommits(ctx, container, "v1.10.0..v1.10.0")
+	require.NoError(t, err)
+	require.Empty(t, commits)
+	_, err = NewLister().ListCommits(ctx, container, "v1.0.0..nonexistent")
+	require.Error(t, err)
+}
+
+func TestListChangedFiles(t *testing.T) {
+	ctx := context.Background()
+	container, err := app.NewContainerForOS()
+	require.NoError(t, err)
+	repoPath := createListerGitDir(ctx, t, container)
+	// Committed changes since v1.0.0.
+	require.NoError(t, os.Remove(filepath.Join(repoPath, "proto", "b.proto")))
+	runCommand(ctx, t, container, "git", "-C", repoPath, "commit", "-a", "-m", "delete b")
+	// Unstaged, staged, and untracked changes.
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "proto", "a.proto"), []byte("// unstaged"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "proto", "c.proto"), []byte("// staged"), 0600))
+	runCommand(ctx, t, container, "git", "-C", repoPath, "add", filepath.Join("proto", "c.proto"))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "proto", "d.proto"), []byte("// untracked"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "proto", "ignored.proto"), []byte("// ignored"), 0600))
+
+	testChdir(t, repoPath)
+	changedFiles, err := NewLister().ListChangedFiles(ctx, container, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		[]string{
+			"proto/a.proto",
+			"proto/b.proto",
+			"proto/c.proto",
+			"proto/d.proto",
+			"version.txt",
+		},
+		changedFiles,
+	)
+	changedFiles, err = NewLister().ListChangedFiles(ctx, container, "HEAD")
+	require.NoError(t, err)
+	require.Equal(t, []string{"proto/a.proto", "proto/c.proto", "proto/d.proto"}, changedFiles)
+
+	// Paths are relative to the current directory.
+	testChdir(t, filepath.Join(repoPath, "proto"))
+	changedFiles, err = NewLister().ListChangedFiles(ctx, container, "HEAD")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a.proto", "c.proto", "d.proto"}, changedFiles)
+}
+
+// createListerGitDir creates a git repository with the tags v1.0.0, v1.2.0, and v1.10.0,
+// and returns its path.
+func createListerGitDir(
+	ctx context.Context,
+	t *testing.T,
+	container app.EnvStdioContainer,
+) string {
+	repoPath := t.TempDir()
+	runCommand(ctx, t, container, "git", "-C", repoPath, "init")
+	runCommand(ctx, t, container, "git", "-C", repoPath, "config", "user.email", "tests@buf.build")
+	runCommand(ctx, t, container, "git", "-C", repoPath, "config", "user.name", "Buf go tests")
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "proto"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".gitignore"), []byte("ignored.proto\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "proto", "a.proto"), []byte("// a"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "proto", "b.proto"), []byte("// b"), 0600))
+	for _, version := range []string{"v1.0.0", "v1.2.0", "v1.10.0"} {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "version.txt"), []byte(version), 0600))
+		runCommand(ctx, t, container, "git", "-C", repoPath, "add", ".")
+		runCommand(ctx, t, container, "git", "-C", repoPath, "commit", "-m", version)
+		runCommand(ctx, t, container, "git", "-C", repoPath, "tag", version)
+	}
+	return repoPath
+}
+
+func revParse(
+	ctx context.Context,
+	t *testing.T,
+	container app.EnvStdioContainer,
+	repoPath string,
+	ref string,
+) string {
+	output, err := runStdout(ctx, container, "git", "-C", repoPath, "rev-parse", ref+"^{commit}")
+	require.NoError(t, err)
+	return strings.TrimSpace(string(output))
+}
+
+func testChdir(t *testing.T, dirPath string) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dirPath))
+	t.Cleanup(func() {
+		require.NoError(t, os.Chdir(wd))
+	})
+}
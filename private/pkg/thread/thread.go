@@ -72,7 +72,11 @@ func Parallelize(ctx context.Context, jobs []func(context.Context) error, option
 		ctx, cancel = context.WithCancel(ctx)
 		defer cancel()
 	}
-	semaphoreC := make(chan struct{}, Parallelism()*multiplier)
+	parallelism := parallelizeOptions.parallelism
+	if parallelism < 1 {
+		parallelism = Parallelism()
+	}
+	semaphoreC := make(chan struct{}, parallelism*multiplier)
 	var errs []error
 	var lock sync.Mutex
 	addError := func(err error) {
@@ -143,6 +147,17 @@ func ParallelizeWithMultiplier(multiplier int) ParallelizeOption {
 	}
 }
 
+// ParallelizeWithParallelism returns a new ParallelizeOption that will use the
+// given parallelism instead of Parallelism() for the number of jobs that can be
+// run at once. The multiplier is still applied.
+//
+// A parallelism of <1 has no meaning.
+func ParallelizeWithParallelism(parallelism int) ParallelizeOption {
+	return func(parallelizeOptions *parallelizeOptions) {
+		parallelizeOptions.parallelism = parallelism
+	}
+}
+
 // ParallelizeWithCancelOnFailure returns a new ParallelizeOption that will attempt
 // to cancel all other jobs via context cancellation if any job fails.
 func ParallelizeWithCancelOnFailure() ParallelizeOption {
@@ -152,6 +167,7 @@ func ParallelizeWithCancelOnFailure() ParallelizeOption {
 }
 
 type parallelizeOptions struct {
+	parallelism     int
 	multiplier      int
 	cancelOnFailure bool
 }
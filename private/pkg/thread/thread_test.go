@@ -18,6 +18,7 @@ import (
 	"context"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -60,3 +61,30 @@ func TestParallelizeWithImmediateCancellation(t *testing.T) {
 		assert.Equal(t, int64(0), executed.Load(), "jobs executed")
 	})
 }
+
+func TestParallelizeWithParallelism(t *testing.T) {
+	t.Parallel()
+	const jobsToExecute = 10
+	var (
+		running    atomic.Int64
+		maxRunning atomic.Int64
+		jobs       = make([]func(context.Context) error, 0, jobsToExecute)
+	)
+	for i := 0; i < jobsToExecute; i++ {
+		jobs = append(jobs, func(_ context.Context) error {
+			current := running.Add(1)
+			for {
+				previous := maxRunning.Load()
+				if current <= previous || maxRunning.CompareAndSwap(previous, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	err := Parallelize(context.Background(), jobs, ParallelizeWithParallelism(2))
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, maxRunning.Load(), int64(2), "max jobs running at once")
+}